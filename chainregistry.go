@@ -461,6 +461,13 @@ func newChainControlFromConfig(cfg *config, chanDB *channeldb.DB,
 			homeChainConfig.Node)
 	}
 
+	// Coalesce the filter updates of the chain view, so that a burst of
+	// channel announcements doesn't result in a call to the chain backend
+	// for every single channel.
+	cc.chainView = chainview.NewBatchingChainView(
+		cc.chainView, chainview.DefaultFilterBatchWindow,
+	)
+
 	wc, err := btcwallet.New(*walletConfig)
 	if err != nil {
 		fmt.Printf("unable to create wallet controller: %v\n", err)
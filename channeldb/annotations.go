@@ -0,0 +1,297 @@
+package channeldb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/coreos/bbolt"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+var (
+	// annotationBucket is the top-level bucket that stores the operator
+	// annotations of channels and nodes.
+	annotationBucket = []byte("annotations")
+
+	// channelAnnotationBucket is a sub-bucket of the annotation bucket that
+	// stores the annotations of channels, keyed by channel id.
+	channelAnnotationBucket = []byte("channel")
+
+	// nodeAnnotationBucket is a sub-bucket of the annotation bucket that
+	// stores the annotations of nodes, keyed by public key.
+	nodeAnnotationBucket = []byte("node")
+
+	// ErrAnnotationNotFound is returned when no annotation is stored for a
+	// channel or node.
+	ErrAnnotationNotFound = errors.New("annotation not found")
+)
+
+// Annotation is operator knowledge about a channel or node of the graph, such
+// as a label or the trust tier it is assigned to.
+type Annotation struct {
+	// Label is a human readable name assigned by the operator.
+	Label string
+
+	// Tags are free-form tags, for example "tier3". Path finding can be
+	// instructed to avoid channels and nodes that carry a certain tag.
+	Tags []string
+
+	// Note is a free-form note.
+	Note string
+}
+
+// HasTag returns true if the annotation carries the given tag.
+func (a *Annotation) HasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AnnotationSet holds all annotations of the annotation store.
+type AnnotationSet struct {
+	// Channels maps channel ids to their annotation.
+	Channels map[uint64]*Annotation
+
+	// Nodes maps node public keys to their annotation.
+	Nodes map[route.Vertex]*Annotation
+}
+
+// AnnotationStore persists operator annotations of channels and nodes.
+type AnnotationStore struct {
+	db *DB
+}
+
+// NewAnnotationStore returns an annotation store backed by the given database.
+func NewAnnotationStore(db *DB) *AnnotationStore {
+	return &AnnotationStore{
+		db: db,
+	}
+}
+
+// SetChannelAnnotation stores the annotation of the given channel, replacing
+// any previous annotation.
+func (s *AnnotationStore) SetChannelAnnotation(chanID uint64,
+	a *Annotation) error {
+
+	var key [8]byte
+	byteOrder.PutUint64(key[:], chanID)
+
+	return s.put(channelAnnotationBucket, key[:], a)
+}
+
+// ChannelAnnotation returns the annotation of the given channel. If there is
+// none, ErrAnnotationNotFound is returned.
+func (s *AnnotationStore) ChannelAnnotation(chanID uint64) (*Annotation,
+	error) {
+
+	var key [8]byte
+	byteOrder.PutUint64(key[:], chanID)
+
+	return s.fetch(channelAnnotationBucket, key[:])
+}
+
+// DeleteChannelAnnotation removes the annotation of the given channel. It is
+// not an error if there is none.
+func (s *AnnotationStore) DeleteChannelAnnotation(chanID uint64) error {
+	var key [8]byte
+	byteOrder.PutUint64(key[:], chanID)
+
+	return s.delete(channelAnnotationBucket, key[:])
+}
+
+// SetNodeAnnotation stores the annotation of the given node, replacing any
+// previous annotation.
+func (s *AnnotationStore) SetNodeAnnotation(node route.Vertex,
+	a *Annotation) error {
+
+	return s.put(nodeAnnotationBucket, node[:], a)
+}
+
+// NodeAnnotation returns the annotation of the given node. If there is none,
+// ErrAnnotationNotFound is returned.
+func (s *AnnotationStore) NodeAnnotation(node route.Vertex) (*Annotation,
+	error) {
+
+	return s.fetch(nodeAnnotationBucket, node[:])
+}
+
+// DeleteNodeAnnotation removes the annotation of the given node. It is not an
+// error if there is none.
+func (s *AnnotationStore) DeleteNodeAnnotation(node route.Vertex) error {
+	return s.delete(nodeAnnotationBucket, node[:])
+}
+
+// FetchAnnotations returns all annotations of channels and nodes.
+func (s *AnnotationStore) FetchAnnotations() (*AnnotationSet, error) {
+	set := &AnnotationSet{
+		Channels: make(map[uint64]*Annotation),
+		Nodes:    make(map[route.Vertex]*Annotation),
+	}
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		annotations := tx.Bucket(annotationBucket)
+		if annotations == nil {
+			return nil
+		}
+
+		channels := annotations.Bucket(channelAnnotationBucket)
+		if channels != nil {
+			err := channels.ForEach(func(k, v []byte) error {
+				a, err := deserializeAnnotation(
+					bytes.NewReader(v),
+				)
+				if err != nil {
+					return err
+				}
+				set.Channels[byteOrder.Uint64(k)] = a
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		nodes := annotations.Bucket(nodeAnnotationBucket)
+		if nodes == nil {
+			return nil
+		}
+
+		return nodes.ForEach(func(k, v []byte) error {
+			a, err := deserializeAnnotation(bytes.NewReader(v))
+			if err != nil {
+				return err
+			}
+
+			var node route.Vertex
+			copy(node[:], k)
+			set.Nodes[node] = a
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// put stores the annotation under the given key of the given sub-bucket.
+func (s *AnnotationStore) put(subBucket, key []byte, a *Annotation) error {
+	var b bytes.Buffer
+	if err := serializeAnnotation(&b, a); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		annotations, err := tx.CreateBucketIfNotExists(
+			annotationBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		bucket, err := annotations.CreateBucketIfNotExists(subBucket)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(key, b.Bytes())
+	})
+}
+
+// fetch returns the annotation stored under the given key of the given
+// sub-bucket.
+func (s *AnnotationStore) fetch(subBucket, key []byte) (*Annotation, error) {
+	var a *Annotation
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		annotations := tx.Bucket(annotationBucket)
+		if annotations == nil {
+			return ErrAnnotationNotFound
+		}
+
+		bucket := annotations.Bucket(subBucket)
+		if bucket == nil {
+			return ErrAnnotationNotFound
+		}
+
+		v := bucket.Get(key)
+		if v == nil {
+			return ErrAnnotationNotFound
+		}
+
+		var err error
+		a, err = deserializeAnnotation(bytes.NewReader(v))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// delete removes the annotation stored under the given key of the given
+// sub-bucket.
+func (s *AnnotationStore) delete(subBucket, key []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		annotations := tx.Bucket(annotationBucket)
+		if annotations == nil {
+			return nil
+		}
+
+		bucket := annotations.Bucket(subBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.Delete(key)
+	})
+}
+
+func serializeAnnotation(w io.Writer, a *Annotation) error {
+	err := WriteElements(
+		w, []byte(a.Label), []byte(a.Note), uint16(len(a.Tags)),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range a.Tags {
+		if err := WriteElement(w, []byte(tag)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deserializeAnnotation(r io.Reader) (*Annotation, error) {
+	var (
+		label, note []byte
+		numTags     uint16
+	)
+	if err := ReadElements(r, &label, &note, &numTags); err != nil {
+		return nil, err
+	}
+
+	a := &Annotation{
+		Label: string(label),
+		Note:  string(note),
+	}
+	for i := uint16(0); i < numTags; i++ {
+		var tag []byte
+		if err := ReadElement(r, &tag); err != nil {
+			return nil, err
+		}
+		a.Tags = append(a.Tags, string(tag))
+	}
+
+	return a, nil
+}
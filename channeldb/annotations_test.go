@@ -0,0 +1,82 @@
+package channeldb
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestAnnotationStore tests that annotations of channels and nodes can be
+// stored, fetched, listed and deleted.
+func TestAnnotationStore(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := makeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test database: %v", err)
+	}
+	defer cleanUp()
+
+	store := NewAnnotationStore(cdb)
+	node := testHop.PubKeyBytes
+
+	// Nothing is stored yet.
+	if _, err := store.ChannelAnnotation(1); err != ErrAnnotationNotFound {
+		t.Fatalf("expected ErrAnnotationNotFound, got %v", err)
+	}
+	if _, err := store.NodeAnnotation(node); err != ErrAnnotationNotFound {
+		t.Fatalf("expected ErrAnnotationNotFound, got %v", err)
+	}
+
+	chanAnnotation := &Annotation{
+		Label: "backup link",
+		Tags:  []string{"tier3", "unstable"},
+		Note:  "goes offline at night",
+	}
+	if err := store.SetChannelAnnotation(1, chanAnnotation); err != nil {
+		t.Fatalf("unable to set channel annotation: %v", err)
+	}
+
+	nodeAnnotation := &Annotation{
+		Label: "exchange",
+		Tags:  []string{"tier1"},
+	}
+	if err := store.SetNodeAnnotation(node, nodeAnnotation); err != nil {
+		t.Fatalf("unable to set node annotation: %v", err)
+	}
+
+	a, err := store.ChannelAnnotation(1)
+	if err != nil {
+		t.Fatalf("unable to fetch channel annotation: %v", err)
+	}
+	if !reflect.DeepEqual(a, chanAnnotation) {
+		t.Fatalf("expected %v, got %v", chanAnnotation, a)
+	}
+	if !a.HasTag("tier3") || a.HasTag("tier1") {
+		t.Fatalf("unexpected tags: %v", a.Tags)
+	}
+
+	set, err := store.FetchAnnotations()
+	if err != nil {
+		t.Fatalf("unable to fetch annotations: %v", err)
+	}
+	if len(set.Channels) != 1 || len(set.Nodes) != 1 {
+		t.Fatalf("expected one channel and one node annotation, got "+
+			"%v and %v", len(set.Channels), len(set.Nodes))
+	}
+	if !reflect.DeepEqual(set.Nodes[node], nodeAnnotation) {
+		t.Fatalf("expected %v, got %v", nodeAnnotation, set.Nodes[node])
+	}
+
+	if err := store.DeleteChannelAnnotation(1); err != nil {
+		t.Fatalf("unable to delete channel annotation: %v", err)
+	}
+	if _, err := store.ChannelAnnotation(1); err != ErrAnnotationNotFound {
+		t.Fatalf("expected ErrAnnotationNotFound, got %v", err)
+	}
+	if err := store.DeleteNodeAnnotation(node); err != nil {
+		t.Fatalf("unable to delete node annotation: %v", err)
+	}
+	if _, err := store.NodeAnnotation(node); err != ErrAnnotationNotFound {
+		t.Fatalf("expected ErrAnnotationNotFound, got %v", err)
+	}
+}
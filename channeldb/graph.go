@@ -751,6 +751,75 @@ func (c *ChannelGraph) UpdateChannelEdge(edge *ChannelEdgeInfo) error {
 	})
 }
 
+// UpdateChannelCapacity replaces the funding outpoint and the capacity of an
+// existing channel, as happens when funds are spliced into or out of the
+// channel. The channel keeps its channel ID and policies. The edge info as it
+// was before the update is returned.
+func (c *ChannelGraph) UpdateChannelCapacity(chanID uint64,
+	chanPoint wire.OutPoint,
+	capacity btcutil.Amount) (*ChannelEdgeInfo, error) {
+
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	var chanKey [8]byte
+	byteOrder.PutUint64(chanKey[:], chanID)
+
+	var prevInfo ChannelEdgeInfo
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		edges := tx.Bucket(edgeBucket)
+		if edges == nil {
+			return ErrEdgeNotFound
+		}
+		edgeIndex := edges.Bucket(edgeIndexBucket)
+		if edgeIndex == nil {
+			return ErrEdgeNotFound
+		}
+		chanIndex := edges.Bucket(channelPointBucket)
+		if chanIndex == nil {
+			return ErrEdgeNotFound
+		}
+
+		info, err := fetchChanEdgeInfo(edgeIndex, chanKey[:])
+		if err != nil {
+			return err
+		}
+		prevInfo = info
+
+		// The channel is now identified by the new funding outpoint,
+		// so that a spend of the old one doesn't close it.
+		var prevPoint bytes.Buffer
+		err = writeOutpoint(&prevPoint, &info.ChannelPoint)
+		if err != nil {
+			return err
+		}
+		if err := chanIndex.Delete(prevPoint.Bytes()); err != nil {
+			return err
+		}
+
+		var newPoint bytes.Buffer
+		if err := writeOutpoint(&newPoint, &chanPoint); err != nil {
+			return err
+		}
+		if err := chanIndex.Put(newPoint.Bytes(), chanKey[:]); err != nil {
+			return err
+		}
+
+		info.ChannelPoint = chanPoint
+		info.Capacity = capacity
+
+		return putChanEdgeInfo(edgeIndex, &info, chanKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.rejectCache.remove(chanID)
+	c.chanCache.remove(chanID)
+
+	return &prevInfo, nil
+}
+
 const (
 	// pruneTipBytes is the total size of the value which stores a prune
 	// entry of the graph in the prune log. The "prune tip" is the last
@@ -1805,9 +1874,20 @@ func delChannelEdge(edges, edgeIndex, chanIndex, zombieIndex,
 		return nil
 	}
 
+	// We'll record the most recent update of either policy along with the
+	// zombie entry, as that is the timestamp that caused the channel to be
+	// deemed a zombie.
+	var lastUpdate time.Time
+	if edge1 != nil {
+		lastUpdate = edge1.LastUpdate
+	}
+	if edge2 != nil && edge2.LastUpdate.After(lastUpdate) {
+		lastUpdate = edge2.LastUpdate
+	}
+
 	return markEdgeZombie(
 		zombieIndex, byteOrder.Uint64(chanID), edgeInfo.NodeKey1Bytes,
-		edgeInfo.NodeKey2Bytes,
+		edgeInfo.NodeKey2Bytes, lastUpdate,
 	)
 }
 
@@ -3054,24 +3134,55 @@ func (c *ChannelGraph) NewChannelEdgePolicy() *ChannelEdgePolicy {
 	return &ChannelEdgePolicy{db: c.db}
 }
 
+// ZombieEdge describes an entry within the zombie index.
+type ZombieEdge struct {
+	// ChannelID is the unique channel ID of the zombie edge.
+	ChannelID uint64
+
+	// NodeKey1Bytes is the raw public key of the first node of the edge.
+	NodeKey1Bytes [33]byte
+
+	// NodeKey2Bytes is the raw public key of the second node of the edge.
+	NodeKey2Bytes [33]byte
+
+	// LastUpdate is the timestamp of the most recent channel update known
+	// for the edge at the time it was marked as a zombie. This will be
+	// the zero time for entries that were added before the timestamp was
+	// stored, or for edges that had no policies.
+	LastUpdate time.Time
+}
+
 // markEdgeZombie marks an edge as a zombie within our zombie index. The public
 // keys should represent the node public keys of the two parties involved in the
-// edge.
+// edge. The last update time of the edge is stored alongside them.
 func markEdgeZombie(zombieIndex *bbolt.Bucket, chanID uint64, pubKey1,
-	pubKey2 [33]byte) error {
+	pubKey2 [33]byte, lastUpdate time.Time) error {
 
 	var k [8]byte
 	byteOrder.PutUint64(k[:], chanID)
 
-	var v [66]byte
+	updateUnix := uint64(0)
+	if lastUpdate.Unix() > 0 {
+		updateUnix = uint64(lastUpdate.Unix())
+	}
+
+	var v [74]byte
 	copy(v[:33], pubKey1[:])
-	copy(v[33:], pubKey2[:])
+	copy(v[33:66], pubKey2[:])
+	byteOrder.PutUint64(v[66:], updateUnix)
 
 	return zombieIndex.Put(k[:], v[:])
 }
 
 // MarkEdgeLive clears an edge from our zombie index, deeming it as live.
 func (c *ChannelGraph) MarkEdgeLive(chanID uint64) error {
+	return c.MarkEdgesLive(chanID)
+}
+
+// MarkEdgesLive clears the given edges from our zombie index within a single
+// database transaction, deeming them as live. Channel IDs that are not found
+// within the zombie index are ignored.
+func (c *ChannelGraph) MarkEdgesLive(chanIDs ...uint64) error {
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
@@ -3086,19 +3197,74 @@ func (c *ChannelGraph) MarkEdgeLive(chanID uint64) error {
 		}
 
 		var k [8]byte
-		byteOrder.PutUint64(k[:], chanID)
-		return zombieIndex.Delete(k[:])
+		for _, chanID := range chanIDs {
+			byteOrder.PutUint64(k[:], chanID)
+			if err := zombieIndex.Delete(k[:]); err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 	if err != nil {
 		return err
 	}
 
-	c.rejectCache.remove(chanID)
-	c.chanCache.remove(chanID)
+	for _, chanID := range chanIDs {
+		c.rejectCache.remove(chanID)
+		c.chanCache.remove(chanID)
+	}
 
 	return nil
 }
 
+// ZombieEdges returns all edges currently within the zombie index.
+func (c *ChannelGraph) ZombieEdges() ([]ZombieEdge, error) {
+	var zombies []ZombieEdge
+
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		edges := tx.Bucket(edgeBucket)
+		if edges == nil {
+			return ErrGraphNoEdgesFound
+		}
+		zombieIndex := edges.Bucket(zombieBucket)
+		if zombieIndex == nil {
+			return nil
+		}
+
+		return zombieIndex.ForEach(func(k, v []byte) error {
+			if len(k) != 8 || len(v) < 66 {
+				return nil
+			}
+
+			zombie := ZombieEdge{
+				ChannelID: byteOrder.Uint64(k),
+			}
+			copy(zombie.NodeKey1Bytes[:], v[:33])
+			copy(zombie.NodeKey2Bytes[:], v[33:66])
+
+			// Entries written before the last update time was
+			// stored only hold the two public keys.
+			if len(v) >= 74 {
+				updateUnix := byteOrder.Uint64(v[66:74])
+				if updateUnix > 0 {
+					zombie.LastUpdate = time.Unix(
+						int64(updateUnix), 0,
+					)
+				}
+			}
+
+			zombies = append(zombies, zombie)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return zombies, nil
+}
+
 // IsZombieEdge returns whether the edge is considered zombie. If it is a
 // zombie, then the two node public keys corresponding to this edge are also
 // returned.
@@ -3144,7 +3310,7 @@ func isZombieEdge(zombieIndex *bbolt.Bucket,
 
 	var pubKey1, pubKey2 [33]byte
 	copy(pubKey1[:], v[:33])
-	copy(pubKey2[:], v[33:])
+	copy(pubKey2[:], v[33:66])
 
 	return true, pubKey1, pubKey2
 }
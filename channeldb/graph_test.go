@@ -2916,6 +2916,110 @@ func TestGraphZombieIndex(t *testing.T) {
 	}
 }
 
+// TestGraphZombieEdges asserts that the zombie index can be enumerated and
+// that edges can be resurrected in bulk.
+func TestGraphZombieEdges(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := makeTestDB()
+	defer cleanUp()
+	if err != nil {
+		t.Fatalf("unable to create test database: %v", err)
+	}
+	graph := db.ChannelGraph()
+
+	node1, err := createTestVertex(db)
+	if err != nil {
+		t.Fatalf("unable to create test vertex: %v", err)
+	}
+	if err := graph.AddLightningNode(node1); err != nil {
+		t.Fatalf("unable to add node: %v", err)
+	}
+	node2, err := createTestVertex(db)
+	if err != nil {
+		t.Fatalf("unable to create test vertex: %v", err)
+	}
+	if err := graph.AddLightningNode(node2); err != nil {
+		t.Fatalf("unable to add node: %v", err)
+	}
+	if bytes.Compare(node2.PubKeyBytes[:], node1.PubKeyBytes[:]) < 0 {
+		node1, node2 = node2, node1
+	}
+
+	// Add two edges to the graph, along with a policy for the first one
+	// so we can check that its update time is recorded once zombified.
+	edge1, policy1, _ := createChannelEdge(db, node1, node2)
+	if err := graph.AddChannelEdge(edge1); err != nil {
+		t.Fatalf("unable to create channel edge: %v", err)
+	}
+	if err := graph.UpdateEdgePolicy(policy1); err != nil {
+		t.Fatalf("unable to update edge: %v", err)
+	}
+	edge2, _, _ := createChannelEdge(db, node1, node2)
+	if err := graph.AddChannelEdge(edge2); err != nil {
+		t.Fatalf("unable to create channel edge: %v", err)
+	}
+
+	zombies, err := graph.ZombieEdges()
+	if err != nil {
+		t.Fatalf("unable to fetch zombie edges: %v", err)
+	}
+	if len(zombies) != 0 {
+		t.Fatalf("expected no zombies, got %v", len(zombies))
+	}
+
+	err = graph.DeleteChannelEdges(edge1.ChannelID, edge2.ChannelID)
+	if err != nil {
+		t.Fatalf("unable to mark edges as zombie: %v", err)
+	}
+
+	zombies, err = graph.ZombieEdges()
+	if err != nil {
+		t.Fatalf("unable to fetch zombie edges: %v", err)
+	}
+	if len(zombies) != 2 {
+		t.Fatalf("expected 2 zombies, got %v", len(zombies))
+	}
+	for _, zombie := range zombies {
+		if zombie.NodeKey1Bytes != node1.PubKeyBytes ||
+			zombie.NodeKey2Bytes != node2.PubKeyBytes {
+
+			t.Fatalf("unexpected zombie node keys: %x, %x",
+				zombie.NodeKey1Bytes, zombie.NodeKey2Bytes)
+		}
+
+		switch zombie.ChannelID {
+		case edge1.ChannelID:
+			if zombie.LastUpdate.Unix() != policy1.LastUpdate.Unix() {
+				t.Fatalf("expected last update %v, got %v",
+					policy1.LastUpdate, zombie.LastUpdate)
+			}
+
+		case edge2.ChannelID:
+			if !zombie.LastUpdate.IsZero() {
+				t.Fatalf("expected no last update, got %v",
+					zombie.LastUpdate)
+			}
+
+		default:
+			t.Fatalf("unexpected zombie %v", zombie.ChannelID)
+		}
+	}
+
+	// Resurrecting both edges at once should leave the index empty.
+	err = graph.MarkEdgesLive(edge1.ChannelID, edge2.ChannelID)
+	if err != nil {
+		t.Fatalf("unable to mark edges as live: %v", err)
+	}
+	zombies, err = graph.ZombieEdges()
+	if err != nil {
+		t.Fatalf("unable to fetch zombie edges: %v", err)
+	}
+	if len(zombies) != 0 {
+		t.Fatalf("expected no zombies, got %v", len(zombies))
+	}
+}
+
 // compareNodes is used to compare two LightningNodes while excluding the
 // Features struct, which cannot be compared as the semantics for reserializing
 // the featuresMap have not been defined.
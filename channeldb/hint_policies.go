@@ -0,0 +1,159 @@
+package channeldb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/coreos/bbolt"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+var (
+	// hintPolicyBucket is the name of the bucket that stores the policies
+	// of private channels that were learned from the channel updates
+	// returned by the nodes of route hints, keyed by short channel id.
+	hintPolicyBucket = []byte("hint-policies")
+
+	// ErrHintPolicyNotFound is returned when no policy has been learned
+	// for a channel.
+	ErrHintPolicyNotFound = errors.New("no hint policy found")
+)
+
+// HintPolicy is the policy of a private channel, as learned from a channel
+// update that was returned by the node at the start of the channel.
+type HintPolicy struct {
+	// Node is the node at the start of the channel that signed the
+	// update.
+	Node route.Vertex
+
+	// ChannelID is the short channel id of the channel.
+	ChannelID uint64
+
+	// Timestamp is the timestamp of the channel update.
+	Timestamp time.Time
+
+	// FeeBaseMSat is the base fee of the channel in millisatoshis.
+	FeeBaseMSat uint32
+
+	// FeeProportionalMillionths is the fee rate of the channel in
+	// millionths of the forwarded amount.
+	FeeProportionalMillionths uint32
+
+	// TimeLockDelta is the time lock delta of the channel.
+	TimeLockDelta uint16
+}
+
+// HintPolicyStore persists the policies of private channels that turned out
+// to differ from the route hints they were included in, so that subsequent
+// payments over the same channels can use the corrected values.
+type HintPolicyStore struct {
+	db *DB
+}
+
+// NewHintPolicyStore returns a hint policy store backed by the given
+// database.
+func NewHintPolicyStore(db *DB) *HintPolicyStore {
+	return &HintPolicyStore{
+		db: db,
+	}
+}
+
+// PutHintPolicy stores the given policy for its channel. A policy that is not
+// more recent than the policy that is already stored is ignored.
+func (s *HintPolicyStore) PutHintPolicy(policy *HintPolicy) error {
+	var key [8]byte
+	byteOrder.PutUint64(key[:], policy.ChannelID)
+
+	var b bytes.Buffer
+	if err := serializeHintPolicy(&b, policy); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(hintPolicyBucket)
+		if err != nil {
+			return err
+		}
+
+		if v := bucket.Get(key[:]); v != nil {
+			stored, err := deserializeHintPolicy(
+				bytes.NewReader(v), policy.ChannelID,
+			)
+			if err != nil {
+				return err
+			}
+
+			if !policy.Timestamp.After(stored.Timestamp) {
+				return nil
+			}
+		}
+
+		return bucket.Put(key[:], b.Bytes())
+	})
+}
+
+// FetchHintPolicy returns the policy that was learned for the given channel.
+// If there is none, ErrHintPolicyNotFound is returned.
+func (s *HintPolicyStore) FetchHintPolicy(chanID uint64) (*HintPolicy,
+	error) {
+
+	var key [8]byte
+	byteOrder.PutUint64(key[:], chanID)
+
+	var policy *HintPolicy
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(hintPolicyBucket)
+		if bucket == nil {
+			return ErrHintPolicyNotFound
+		}
+
+		v := bucket.Get(key[:])
+		if v == nil {
+			return ErrHintPolicyNotFound
+		}
+
+		var err error
+		policy, err = deserializeHintPolicy(bytes.NewReader(v), chanID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func serializeHintPolicy(w io.Writer, policy *HintPolicy) error {
+	if _, err := w.Write(policy.Node[:]); err != nil {
+		return err
+	}
+
+	return WriteElements(w,
+		uint64(policy.Timestamp.Unix()), policy.FeeBaseMSat,
+		policy.FeeProportionalMillionths, policy.TimeLockDelta,
+	)
+}
+
+func deserializeHintPolicy(r io.Reader, chanID uint64) (*HintPolicy, error) {
+	policy := &HintPolicy{
+		ChannelID: chanID,
+	}
+
+	if _, err := io.ReadFull(r, policy.Node[:]); err != nil {
+		return nil, err
+	}
+
+	var timestamp uint64
+	err := ReadElements(r,
+		&timestamp, &policy.FeeBaseMSat,
+		&policy.FeeProportionalMillionths, &policy.TimeLockDelta,
+	)
+	if err != nil {
+		return nil, err
+	}
+	policy.Timestamp = time.Unix(int64(timestamp), 0)
+
+	return policy, nil
+}
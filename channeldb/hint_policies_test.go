@@ -0,0 +1,79 @@
+package channeldb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestHintPolicyStore tests that learned hint policies can be stored and
+// fetched, and that outdated policies don't replace more recent ones.
+func TestHintPolicyStore(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := makeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test database: %v", err)
+	}
+	defer cleanUp()
+
+	store := NewHintPolicyStore(cdb)
+
+	// Nothing is stored yet.
+	_, err = store.FetchHintPolicy(12345)
+	if err != ErrHintPolicyNotFound {
+		t.Fatalf("expected ErrHintPolicyNotFound, got %v", err)
+	}
+
+	policy := &HintPolicy{
+		Node:                      testHop.PubKeyBytes,
+		ChannelID:                 12345,
+		Timestamp:                 time.Unix(1000, 0),
+		FeeBaseMSat:               1000,
+		FeeProportionalMillionths: 10,
+		TimeLockDelta:             40,
+	}
+	if err := store.PutHintPolicy(policy); err != nil {
+		t.Fatalf("unable to put hint policy: %v", err)
+	}
+
+	fetched, err := store.FetchHintPolicy(12345)
+	if err != nil {
+		t.Fatalf("unable to fetch hint policy: %v", err)
+	}
+	if !reflect.DeepEqual(fetched, policy) {
+		t.Fatalf("policy mismatch: expected %v, got %v", policy,
+			fetched)
+	}
+
+	// An older policy should be ignored.
+	older := *policy
+	older.Timestamp = time.Unix(900, 0)
+	older.FeeBaseMSat = 1
+	if err := store.PutHintPolicy(&older); err != nil {
+		t.Fatalf("unable to put hint policy: %v", err)
+	}
+	fetched, err = store.FetchHintPolicy(12345)
+	if err != nil {
+		t.Fatalf("unable to fetch hint policy: %v", err)
+	}
+	if !reflect.DeepEqual(fetched, policy) {
+		t.Fatalf("older policy replaced stored policy: %v", fetched)
+	}
+
+	// A newer policy replaces the stored one.
+	newer := *policy
+	newer.Timestamp = time.Unix(1100, 0)
+	newer.FeeBaseMSat = 2000
+	if err := store.PutHintPolicy(&newer); err != nil {
+		t.Fatalf("unable to put hint policy: %v", err)
+	}
+	fetched, err = store.FetchHintPolicy(12345)
+	if err != nil {
+		t.Fatalf("unable to fetch hint policy: %v", err)
+	}
+	if !reflect.DeepEqual(fetched, &newer) {
+		t.Fatalf("policy mismatch: expected %v, got %v", newer,
+			fetched)
+	}
+}
@@ -35,6 +35,11 @@ var (
 	// existing state of a payment.
 	ErrUnknownPaymentStatus = errors.New("unknown payment status")
 
+	// ErrPaymentLabelTooLarge is returned when the label or group id of a
+	// payment exceeds MaxPaymentLabelSize.
+	ErrPaymentLabelTooLarge = fmt.Errorf("payment label and group id "+
+		"must not exceed %v bytes", MaxPaymentLabelSize)
+
 	// errNoAttemptInfo is returned when no attempt info is stored yet.
 	errNoAttemptInfo = errors.New("unable to find attempt info for " +
 		"inflight payment")
@@ -59,6 +64,12 @@ func NewPaymentControl(db *DB) *PaymentControl {
 func (p *PaymentControl) InitPayment(paymentHash lntypes.Hash,
 	info *PaymentCreationInfo) error {
 
+	if len(info.Label) > MaxPaymentLabelSize ||
+		len(info.GroupID) > MaxPaymentLabelSize {
+
+		return ErrPaymentLabelTooLarge
+	}
+
 	var b bytes.Buffer
 	if err := serializePaymentCreationInfo(&b, info); err != nil {
 		return err
@@ -135,7 +146,13 @@ func (p *PaymentControl) InitPayment(paymentHash lntypes.Hash,
 
 		// Also delete any lingering failure info now that we are
 		// re-attempting.
-		return bucket.Delete(paymentFailInfoKey)
+		err = bucket.Delete(paymentFailInfoKey)
+		if err != nil {
+			return err
+		}
+
+		// A retried payment starts out unpaused.
+		return bucket.Delete(paymentPausedKey)
 	})
 	if err != nil {
 		return nil
@@ -279,6 +296,43 @@ func (p *PaymentControl) Fail(paymentHash lntypes.Hash,
 	return updateErr
 }
 
+// SetPaused marks an in-flight payment as paused or unpaused. The paused state
+// is kept until it is changed again or the payment is re-initialized.
+func (p *PaymentControl) SetPaused(paymentHash lntypes.Hash,
+	paused bool) error {
+
+	var updateErr error
+	err := p.db.Batch(func(tx *bbolt.Tx) error {
+		// Reset the update error, to avoid carrying over an error
+		// from a previous execution of the batched db transaction.
+		updateErr = nil
+
+		bucket, err := fetchPaymentBucket(tx, paymentHash)
+		if err == ErrPaymentNotInitiated {
+			updateErr = ErrPaymentNotInitiated
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// Only in-flight payments can be paused.
+		if err := ensureInFlight(bucket); err != nil {
+			updateErr = err
+			return nil
+		}
+
+		if !paused {
+			return bucket.Delete(paymentPausedKey)
+		}
+		return bucket.Put(paymentPausedKey, []byte{1})
+	})
+	if err != nil {
+		return err
+	}
+
+	return updateErr
+}
+
 // FetchPayment returns information about a payment from the database.
 func (p *PaymentControl) FetchPayment(paymentHash lntypes.Hash) (
 	*Payment, error) {
@@ -420,6 +474,15 @@ type InFlightPayment struct {
 	//
 	// NOTE: Might be nil.
 	Attempt *PaymentAttemptInfo
+
+	// Paused indicates that the payment was paused, and that no new
+	// attempts should be made for it until it is resumed.
+	Paused bool
+}
+
+// FetchPayments returns all payments found in the DB.
+func (p *PaymentControl) FetchPayments() ([]*Payment, error) {
+	return p.db.FetchPayments()
 }
 
 // FetchInFlightPayments returns all payments with status InFlight.
@@ -468,6 +531,8 @@ func (p *PaymentControl) FetchInFlightPayments() ([]*InFlightPayment, error) {
 				return err
 			}
 
+			inFlight.Paused = bucket.Get(paymentPausedKey) != nil
+
 			inFlights = append(inFlights, inFlight)
 			return nil
 		})
@@ -550,3 +550,136 @@ func assertPaymentInfo(t *testing.T, db *DB, hash lntypes.Hash,
 	}
 
 }
+
+// TestPaymentControlGroups checks that payments can be listed by their group
+// id, and that oversized labels are rejected.
+func TestPaymentControlGroups(t *testing.T) {
+	t.Parallel()
+
+	db, err := initDB()
+	if err != nil {
+		t.Fatalf("unable to init db: %v", err)
+	}
+
+	pControl := NewPaymentControl(db)
+
+	groups := []string{"shop", "", "shop", "refunds"}
+	for i, groupID := range groups {
+		info, _, _, err := genInfo()
+		if err != nil {
+			t.Fatalf("unable to generate htlc message: %v", err)
+		}
+		info.Label = fmt.Sprintf("order-%v", i)
+		info.GroupID = groupID
+
+		err = pControl.InitPayment(info.PaymentHash, info)
+		if err != nil {
+			t.Fatalf("unable to send htlc message: %v", err)
+		}
+	}
+
+	payments, err := db.FetchPaymentsInGroup("shop")
+	if err != nil {
+		t.Fatalf("unable to fetch payments: %v", err)
+	}
+	if len(payments) != 2 {
+		t.Fatalf("expected 2 payments, got %v", len(payments))
+	}
+	for i, p := range payments {
+		label := fmt.Sprintf("order-%v", 2*i)
+		if p.Info.Label != label {
+			t.Fatalf("expected label %v, got %v", label,
+				p.Info.Label)
+		}
+	}
+
+	info, _, _, err := genInfo()
+	if err != nil {
+		t.Fatalf("unable to generate htlc message: %v", err)
+	}
+	info.Label = string(make([]byte, MaxPaymentLabelSize+1))
+
+	err = pControl.InitPayment(info.PaymentHash, info)
+	if err != ErrPaymentLabelTooLarge {
+		t.Fatalf("expected ErrPaymentLabelTooLarge, got %v", err)
+	}
+}
+
+// TestPaymentControlPaused checks that the paused state of an in-flight
+// payment is persisted, and that it is cleared when the payment is retried.
+func TestPaymentControlPaused(t *testing.T) {
+	t.Parallel()
+
+	db, err := initDB()
+	if err != nil {
+		t.Fatalf("unable to init db: %v", err)
+	}
+
+	pControl := NewPaymentControl(db)
+
+	info, _, _, err := genInfo()
+	if err != nil {
+		t.Fatalf("unable to generate htlc message: %v", err)
+	}
+
+	// A payment that isn't initiated can't be paused.
+	err = pControl.SetPaused(info.PaymentHash, true)
+	if err != ErrPaymentNotInitiated {
+		t.Fatalf("expected ErrPaymentNotInitiated, got %v", err)
+	}
+
+	err = pControl.InitPayment(info.PaymentHash, info)
+	if err != nil {
+		t.Fatalf("unable to send htlc message: %v", err)
+	}
+
+	assertPaused := func(expected bool) {
+		t.Helper()
+
+		inFlights, err := pControl.FetchInFlightPayments()
+		if err != nil {
+			t.Fatalf("unable to fetch in-flight payments: %v", err)
+		}
+		if len(inFlights) != 1 {
+			t.Fatalf("expected 1 in-flight payment, got %v",
+				len(inFlights))
+		}
+		if inFlights[0].Paused != expected {
+			t.Fatalf("expected paused %v, got %v", expected,
+				inFlights[0].Paused)
+		}
+	}
+
+	assertPaused(false)
+
+	if err := pControl.SetPaused(info.PaymentHash, true); err != nil {
+		t.Fatalf("unable to pause payment: %v", err)
+	}
+	assertPaused(true)
+
+	if err := pControl.SetPaused(info.PaymentHash, false); err != nil {
+		t.Fatalf("unable to unpause payment: %v", err)
+	}
+	assertPaused(false)
+
+	// A failed payment can't be paused, and a retry of the payment starts
+	// out unpaused.
+	if err := pControl.SetPaused(info.PaymentHash, true); err != nil {
+		t.Fatalf("unable to pause payment: %v", err)
+	}
+	err = pControl.Fail(info.PaymentHash, FailureReasonNoRoute)
+	if err != nil {
+		t.Fatalf("unable to fail payment: %v", err)
+	}
+
+	err = pControl.SetPaused(info.PaymentHash, true)
+	if err != ErrPaymentAlreadyFailed {
+		t.Fatalf("expected ErrPaymentAlreadyFailed, got %v", err)
+	}
+
+	err = pControl.InitPayment(info.PaymentHash, info)
+	if err != nil {
+		t.Fatalf("unable to send htlc message: %v", err)
+	}
+	assertPaused(false)
+}
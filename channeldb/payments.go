@@ -80,8 +80,17 @@ var (
 	// paymentFailInfoKey is a key used in the payment's sub-bucket to
 	// store information about the reason a payment failed.
 	paymentFailInfoKey = []byte("payment-fail-info")
+
+	// paymentPausedKey is a key used in the payment's sub-bucket to mark
+	// an in-flight payment as paused. While present, no new attempts are
+	// made for the payment.
+	paymentPausedKey = []byte("payment-paused")
 )
 
+// MaxPaymentLabelSize is the maximum size in bytes of the label and of the
+// group id of a payment.
+const MaxPaymentLabelSize = 1024
+
 // FailureReason encodes the reason a payment ultimately failed.
 type FailureReason byte
 
@@ -94,6 +103,10 @@ const (
 	// destination was found during path finding.
 	FailureReasonNoRoute FailureReason = 1
 
+	// FailureReasonInvalidPreimage indicates that the payment was settled
+	// with a preimage that doesn't hash to the payment hash.
+	FailureReasonInvalidPreimage FailureReason = 2
+
 	// TODO(halseth): cancel state.
 
 	// TODO(joostjager): Add failure reasons for:
@@ -108,6 +121,8 @@ func (r FailureReason) String() string {
 		return "timeout"
 	case FailureReasonNoRoute:
 		return "no_route"
+	case FailureReasonInvalidPreimage:
+		return "invalid_preimage"
 	}
 
 	return "unknown"
@@ -185,6 +200,19 @@ type PaymentCreationInfo struct {
 
 	// PaymentRequest is the full payment request, if any.
 	PaymentRequest []byte
+
+	// TraceID is the correlation id under which the router logs its
+	// progress on this payment. It is zero for payments that were created
+	// before trace ids were introduced.
+	TraceID uint64
+
+	// Label is an optional external reference of the payment, such as the
+	// id of the order that it pays for.
+	Label string
+
+	// GroupID optionally groups related payments, so that they can be
+	// listed together.
+	GroupID string
 }
 
 // PaymentAttemptInfo contains information about a specific payment attempt for
@@ -238,6 +266,24 @@ type Payment struct {
 	Failure *FailureReason
 }
 
+// FetchPaymentsInGroup returns the sent payments with the given group id,
+// sorted by their sequence number.
+func (db *DB) FetchPaymentsInGroup(groupID string) ([]*Payment, error) {
+	payments, err := db.FetchPayments()
+	if err != nil {
+		return nil, err
+	}
+
+	var group []*Payment
+	for _, p := range payments {
+		if p.Info != nil && p.Info.GroupID == groupID {
+			group = append(group, p)
+		}
+	}
+
+	return group, nil
+}
+
 // FetchPayments returns all sent payments found in the DB.
 func (db *DB) FetchPayments() ([]*Payment, error) {
 	var payments []*Payment
@@ -430,6 +476,22 @@ func serializePaymentCreationInfo(w io.Writer, c *PaymentCreationInfo) error {
 		return err
 	}
 
+	byteOrder.PutUint64(scratch[:], c.TraceID)
+	if _, err := w.Write(scratch[:]); err != nil {
+		return err
+	}
+
+	for _, s := range []string{c.Label, c.GroupID} {
+		byteOrder.PutUint16(scratch[:2], uint16(len(s)))
+		if _, err := w.Write(scratch[:2]); err != nil {
+			return err
+		}
+
+		if _, err := w.Write([]byte(s)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -465,6 +527,44 @@ func deserializePaymentCreationInfo(r io.Reader) (*PaymentCreationInfo, error) {
 	}
 	c.PaymentRequest = payReq
 
+	// The trace id was added later on, so it is missing from the creation
+	// info of older payments.
+	_, err := io.ReadFull(r, scratch[:])
+	switch {
+	case err == io.EOF:
+		return c, nil
+
+	case err != nil:
+		return nil, err
+	}
+	c.TraceID = byteOrder.Uint64(scratch[:])
+
+	// The label and group id were added after the trace id, so they are
+	// missing from the creation info of older payments.
+	_, err = io.ReadFull(r, scratch[:2])
+	switch {
+	case err == io.EOF:
+		return c, nil
+
+	case err != nil:
+		return nil, err
+	}
+
+	label := make([]byte, byteOrder.Uint16(scratch[:2]))
+	if _, err := io.ReadFull(r, label); err != nil {
+		return nil, err
+	}
+	c.Label = string(label)
+
+	if _, err := io.ReadFull(r, scratch[:2]); err != nil {
+		return nil, err
+	}
+	groupID := make([]byte, byteOrder.Uint16(scratch[:2]))
+	if _, err := io.ReadFull(r, groupID); err != nil {
+		return nil, err
+	}
+	c.GroupID = string(groupID)
+
 	return c, nil
 }
 
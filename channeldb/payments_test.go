@@ -76,6 +76,9 @@ func makeFakeInfo() (*PaymentCreationInfo, *PaymentAttemptInfo) {
 		// failures due to the monotonic time component.
 		CreationDate:   time.Unix(time.Now().Unix(), 0),
 		PaymentRequest: []byte(""),
+		TraceID:        0xfeedcafe,
+		Label:          "order-1234",
+		GroupID:        "shop",
 	}
 
 	a := &PaymentAttemptInfo{
@@ -167,8 +170,11 @@ func TestSentPaymentSerialization(t *testing.T) {
 	if err := serializePaymentCreationInfo(&b, c); err != nil {
 		t.Fatalf("unable to serialize creation info: %v", err)
 	}
+	infoBytes := b.Bytes()
 
-	newCreationInfo, err := deserializePaymentCreationInfo(&b)
+	newCreationInfo, err := deserializePaymentCreationInfo(
+		bytes.NewReader(infoBytes),
+	)
 	if err != nil {
 		t.Fatalf("unable to deserialize creation info: %v", err)
 	}
@@ -180,6 +186,36 @@ func TestSentPaymentSerialization(t *testing.T) {
 		)
 	}
 
+	// The creation info of payments that were stored before labels were
+	// introduced lacks the label and group id.
+	labelsLen := 4 + len(c.Label) + len(c.GroupID)
+	legacyInfo, err := deserializePaymentCreationInfo(
+		bytes.NewReader(infoBytes[:len(infoBytes)-labelsLen]),
+	)
+	if err != nil {
+		t.Fatalf("unable to deserialize legacy creation info: %v", err)
+	}
+	if legacyInfo.TraceID != c.TraceID {
+		t.Fatalf("expected trace id %x, got %x", c.TraceID,
+			legacyInfo.TraceID)
+	}
+	if legacyInfo.Label != "" || legacyInfo.GroupID != "" {
+		t.Fatalf("expected no label and group id, got %q and %q",
+			legacyInfo.Label, legacyInfo.GroupID)
+	}
+
+	// The creation info of payments that were stored before trace ids
+	// were introduced lacks the trace id.
+	legacyInfo, err = deserializePaymentCreationInfo(
+		bytes.NewReader(infoBytes[:len(infoBytes)-labelsLen-8]),
+	)
+	if err != nil {
+		t.Fatalf("unable to deserialize legacy creation info: %v", err)
+	}
+	if legacyInfo.TraceID != 0 {
+		t.Fatalf("expected no trace id, got %x", legacyInfo.TraceID)
+	}
+
 	b.Reset()
 	if err := serializePaymentAttemptInfo(&b, s); err != nil {
 		t.Fatalf("unable to serialize info: %v", err)
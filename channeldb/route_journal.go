@@ -0,0 +1,143 @@
+package channeldb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/coreos/bbolt"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+var (
+	// routeJournalBucket is the name of the bucket that stores the routes
+	// that successfully settled a payment, keyed by destination and
+	// amount band.
+	routeJournalBucket = []byte("route-journal")
+
+	// ErrJournalRouteNotFound is returned when no route is stored in the
+	// route journal for a destination and amount band.
+	ErrJournalRouteNotFound = errors.New("no route in journal")
+)
+
+// JournalRoute is a route that successfully settled a payment.
+type JournalRoute struct {
+	// Route is the route over which the payment settled.
+	Route route.Route
+
+	// Settled is the time at which the payment settled.
+	Settled time.Time
+}
+
+// RouteJournal persists the last route that successfully settled a payment
+// for every destination and amount band, so that subsequent payments to the
+// same destination can try that route before falling back to path finding.
+type RouteJournal struct {
+	db *DB
+}
+
+// NewRouteJournal returns a route journal backed by the given database.
+func NewRouteJournal(db *DB) *RouteJournal {
+	return &RouteJournal{
+		db: db,
+	}
+}
+
+// routeJournalKey returns the key under which the route for the given
+// destination and amount band is stored.
+func routeJournalKey(target route.Vertex, band uint8) []byte {
+	var key [34]byte
+	copy(key[:], target[:])
+	key[33] = band
+
+	return key[:]
+}
+
+// PutRoute stores the given route as the last route that settled a payment to
+// its destination within the given amount band, replacing any previous route.
+func (j *RouteJournal) PutRoute(band uint8, rt *route.Route,
+	settled time.Time) error {
+
+	if len(rt.Hops) == 0 {
+		return errors.New("route has no hops")
+	}
+	target := rt.Hops[len(rt.Hops)-1].PubKeyBytes
+
+	var b bytes.Buffer
+	var scratch [8]byte
+	byteOrder.PutUint64(scratch[:], uint64(settled.Unix()))
+	if _, err := b.Write(scratch[:]); err != nil {
+		return err
+	}
+	if err := serializeRoute(&b, *rt); err != nil {
+		return err
+	}
+
+	return j.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(routeJournalBucket)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(routeJournalKey(target, band), b.Bytes())
+	})
+}
+
+// FetchRoute returns the last route that settled a payment to the given
+// destination within the given amount band. If there is none,
+// ErrJournalRouteNotFound is returned.
+func (j *RouteJournal) FetchRoute(target route.Vertex,
+	band uint8) (*JournalRoute, error) {
+
+	var entry *JournalRoute
+	err := j.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(routeJournalBucket)
+		if bucket == nil {
+			return ErrJournalRouteNotFound
+		}
+
+		v := bucket.Get(routeJournalKey(target, band))
+		if v == nil {
+			return ErrJournalRouteNotFound
+		}
+
+		r := bytes.NewReader(v)
+
+		var scratch [8]byte
+		if _, err := io.ReadFull(r, scratch[:]); err != nil {
+			return err
+		}
+		settled := time.Unix(int64(byteOrder.Uint64(scratch[:])), 0)
+
+		rt, err := deserializeRoute(r)
+		if err != nil {
+			return err
+		}
+
+		entry = &JournalRoute{
+			Route:   rt,
+			Settled: settled,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// DeleteRoute removes the route for the given destination and amount band
+// from the journal. It is not an error if no route is stored.
+func (j *RouteJournal) DeleteRoute(target route.Vertex, band uint8) error {
+	return j.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(routeJournalBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.Delete(routeJournalKey(target, band))
+	})
+}
@@ -0,0 +1,60 @@
+package channeldb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRouteJournal tests that routes can be stored, fetched and deleted per
+// destination and amount band.
+func TestRouteJournal(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := makeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test database: %v", err)
+	}
+	defer cleanUp()
+
+	journal := NewRouteJournal(cdb)
+	target := testHop.PubKeyBytes
+
+	// Nothing is stored yet.
+	_, err = journal.FetchRoute(target, 10)
+	if err != ErrJournalRouteNotFound {
+		t.Fatalf("expected ErrJournalRouteNotFound, got %v", err)
+	}
+
+	settled := time.Unix(time.Now().Unix(), 0)
+	if err := journal.PutRoute(10, &testRoute, settled); err != nil {
+		t.Fatalf("unable to put route: %v", err)
+	}
+
+	entry, err := journal.FetchRoute(target, 10)
+	if err != nil {
+		t.Fatalf("unable to fetch route: %v", err)
+	}
+	if !reflect.DeepEqual(entry.Route, testRoute) {
+		t.Fatalf("route mismatch: expected %v, got %v", testRoute,
+			entry.Route)
+	}
+	if !entry.Settled.Equal(settled) {
+		t.Fatalf("expected settle time %v, got %v", settled,
+			entry.Settled)
+	}
+
+	// Routes are stored per amount band.
+	_, err = journal.FetchRoute(target, 11)
+	if err != ErrJournalRouteNotFound {
+		t.Fatalf("expected ErrJournalRouteNotFound, got %v", err)
+	}
+
+	if err := journal.DeleteRoute(target, 10); err != nil {
+		t.Fatalf("unable to delete route: %v", err)
+	}
+	_, err = journal.FetchRoute(target, 10)
+	if err != ErrJournalRouteNotFound {
+		t.Fatalf("expected ErrJournalRouteNotFound, got %v", err)
+	}
+}
@@ -2093,7 +2093,8 @@ var sendPaymentCommand = cli.Command{
 		cli.StringFlag{
 			Name: "dest, d",
 			Usage: "the compressed identity pubkey of the " +
-				"payment recipient",
+				"payment recipient, or the alias or a pubkey " +
+				"prefix that uniquely identifies it",
 		},
 		cli.Int64Flag{
 			Name:  "amt, a",
@@ -2173,29 +2174,28 @@ func sendPayment(ctx *cli.Context) error {
 	}
 
 	var (
-		destNode []byte
-		amount   int64
-		err      error
+		dest   string
+		amount int64
+		err    error
 	)
 
 	args := ctx.Args()
 
 	switch {
 	case ctx.IsSet("dest"):
-		destNode, err = hex.DecodeString(ctx.String("dest"))
+		dest = ctx.String("dest")
 	case args.Present():
-		destNode, err = hex.DecodeString(args.First())
+		dest = args.First()
 		args = args.Tail()
 	default:
 		return fmt.Errorf("destination txid argument missing")
 	}
-	if err != nil {
-		return err
-	}
 
-	if len(destNode) != 33 {
-		return fmt.Errorf("dest node pubkey must be exactly 33 bytes, is "+
-			"instead: %v", len(destNode))
+	// A destination that isn't a full pubkey is passed on as a string, so
+	// that lnd can resolve it as the alias or pubkey prefix of a node.
+	destNode, err := hex.DecodeString(dest)
+	if err != nil || len(destNode) != 33 {
+		destNode = nil
 	}
 
 	if ctx.IsSet("amt") {
@@ -2212,6 +2212,9 @@ func sendPayment(ctx *cli.Context) error {
 		Dest: destNode,
 		Amt:  amount,
 	}
+	if destNode == nil {
+		req.DestString = dest
+	}
 
 	if ctx.Bool("debug_send") && (ctx.IsSet("payment_hash") || args.Present()) {
 		return fmt.Errorf("do not provide a payment hash with debug send")
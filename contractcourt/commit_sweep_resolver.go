@@ -104,8 +104,18 @@ func (c *commitSweepResolver) Resolve() (ContractResolver, error) {
 		// sweeper.
 		log.Infof("%T(%v): sweeping commit output", c, c.chanPoint)
 
-		feePref := sweep.FeePreference{ConfTarget: commitOutputConfTarget}
-		resultChan, err := c.Sweeper.SweepInput(&inp, feePref)
+		// The output pays directly to us, so there is no rush to
+		// sweep it.
+		feePref := sweep.FeePreference{
+			ConfTarget: commitOutputConfTarget,
+			Urgency:    sweep.UrgencyEconomic,
+		}
+		resultChan, err := c.Sweeper.SweepInputWithMetadata(
+			&inp, feePref, &sweep.InputMetadata{
+				ChanPoint: c.chanPoint,
+				Reason:    sweep.SweepReasonCommitment,
+			},
+		)
 		if err != nil {
 			log.Errorf("%T(%v): unable to sweep input: %v",
 				c, c.chanPoint, err)
@@ -20,6 +20,7 @@ import (
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/multimutex"
+	"github.com/lightningnetwork/lnd/netann"
 	"github.com/lightningnetwork/lnd/routing"
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/lightningnetwork/lnd/ticker"
@@ -182,6 +183,18 @@ type Config struct {
 	// here?
 	AnnSigner lnwallet.MessageSigner
 
+	// UpdateSigner is an optional external signer that is used to sign the
+	// channel updates we originate in place of AnnSigner, for deployments
+	// in which the node key is held outside of lnd. The updates of a
+	// policy change are signed in batches to limit the number of round
+	// trips to the signer.
+	UpdateSigner lnwallet.BatchMessageSigner
+
+	// UpdateSignBatchSize is the maximum number of channel updates that
+	// are signed in a single request to the UpdateSigner. If zero,
+	// netann.DefaultSignBatchSize is used.
+	UpdateSignBatchSize int
+
 	// NumActiveSyncers is the number of peers for which we should have
 	// active syncers with. After reaching NumActiveSyncers, any future
 	// gossip syncers will be passive.
@@ -1234,11 +1247,7 @@ func (d *AuthenticatedGossiper) isRecentlyRejectedMsg(msg lnwire.Message) bool {
 func (d *AuthenticatedGossiper) retransmitStaleChannels() error {
 	// Iterate over all of our channels and check if any of them fall
 	// within the prune interval or re-broadcast interval.
-	type updateTuple struct {
-		info *channeldb.ChannelEdgeInfo
-		edge *channeldb.ChannelEdgePolicy
-	}
-	var edgesToUpdate []updateTuple
+	var edgesToUpdate []edgeToUpdate
 	err := d.cfg.Router.ForAllOutgoingChannels(func(
 		info *channeldb.ChannelEdgeInfo,
 		edge *channeldb.ChannelEdgePolicy) error {
@@ -1258,7 +1267,7 @@ func (d *AuthenticatedGossiper) retransmitStaleChannels() error {
 		// introduction of the MaxHTLC field, then we'll update this
 		// edge to propagate this information in the network.
 		if !edge.MessageFlags.HasMaxHtlc() {
-			edgesToUpdate = append(edgesToUpdate, updateTuple{
+			edgesToUpdate = append(edgesToUpdate, edgeToUpdate{
 				info: info,
 				edge: edge,
 			})
@@ -1273,7 +1282,7 @@ func (d *AuthenticatedGossiper) retransmitStaleChannels() error {
 		// channel, add the channel to the set of edges we need to
 		// update.
 		if timeElapsed >= broadcastInterval {
-			edgesToUpdate = append(edgesToUpdate, updateTuple{
+			edgesToUpdate = append(edgesToUpdate, edgeToUpdate{
 				info: info,
 				edge: edge,
 			})
@@ -1286,21 +1295,19 @@ func (d *AuthenticatedGossiper) retransmitStaleChannels() error {
 			err)
 	}
 
-	var signedUpdates []lnwire.Message
-	for _, chanToUpdate := range edgesToUpdate {
-		// Re-sign and update the channel on disk and retrieve our
-		// ChannelUpdate to broadcast.
-		chanAnn, chanUpdate, err := d.updateChannel(
-			chanToUpdate.info, chanToUpdate.edge,
-		)
-		if err != nil {
-			return fmt.Errorf("unable to update channel: %v", err)
-		}
+	// Re-sign and update the channels on disk and retrieve our
+	// ChannelUpdates to broadcast.
+	chanAnns, chanUpdates, err := d.updateChannels(edgesToUpdate)
+	if err != nil {
+		return fmt.Errorf("unable to update channel: %v", err)
+	}
 
+	var signedUpdates []lnwire.Message
+	for i, chanUpdate := range chanUpdates {
 		// If we have a valid announcement to transmit, then we'll send
 		// that along with the update.
-		if chanAnn != nil {
-			signedUpdates = append(signedUpdates, chanAnn)
+		if chanAnns[i] != nil {
+			signedUpdates = append(signedUpdates, chanAnns[i])
 		}
 
 		signedUpdates = append(signedUpdates, chanUpdate)
@@ -1347,11 +1354,7 @@ func (d *AuthenticatedGossiper) processChanPolicyUpdate(
 		log.Infof("Updating routing policies for all chans")
 	}
 
-	type edgeWithInfo struct {
-		info *channeldb.ChannelEdgeInfo
-		edge *channeldb.ChannelEdgePolicy
-	}
-	var edgesToUpdate []edgeWithInfo
+	var edgesToUpdate []edgeToUpdate
 
 	// Next, we'll loop over all the outgoing channels the router knows of.
 	// If we have a filter then we'll only collected those channels,
@@ -1374,7 +1377,7 @@ func (d *AuthenticatedGossiper) processChanPolicyUpdate(
 		)
 		edge.TimeLockDelta = uint16(policyUpdate.newSchema.TimeLockDelta)
 
-		edgesToUpdate = append(edgesToUpdate, edgeWithInfo{
+		edgesToUpdate = append(edgesToUpdate, edgeToUpdate{
 			info: info,
 			edge: edge,
 		})
@@ -1386,18 +1389,16 @@ func (d *AuthenticatedGossiper) processChanPolicyUpdate(
 	}
 
 	// With the set of edges we need to update retrieved, we'll now re-sign
-	// them, and insert them into the database.
+	// them, and insert them into the database. Then we'll retrieve our
+	// ChannelUpdates to broadcast.
+	_, signedUpdates, err := d.updateChannels(edgesToUpdate)
+	if err != nil {
+		return nil, err
+	}
+
 	var chanUpdates []networkMsg
-	for _, edgeInfo := range edgesToUpdate {
-		// Now that we've collected all the channels we need to update,
-		// we'll Re-sign and update the backing ChannelGraphSource, and
-		// retrieve our ChannelUpdate to broadcast.
-		_, chanUpdate, err := d.updateChannel(
-			edgeInfo.info, edgeInfo.edge,
-		)
-		if err != nil {
-			return nil, err
-		}
+	for i, edgeInfo := range edgesToUpdate {
+		chanUpdate := signedUpdates[i]
 
 		// We'll avoid broadcasting any updates for private channels to
 		// avoid directly giving away their existence. Instead, we'll
@@ -2495,11 +2496,80 @@ func (d *AuthenticatedGossiper) isMsgStale(msg lnwire.Message) bool {
 	}
 }
 
-// updateChannel creates a new fully signed update for the channel, and updates
-// the underlying graph with the new state.
-func (d *AuthenticatedGossiper) updateChannel(info *channeldb.ChannelEdgeInfo,
-	edge *channeldb.ChannelEdgePolicy) (*lnwire.ChannelAnnouncement,
-	*lnwire.ChannelUpdate, error) {
+// edgeToUpdate is one of our channels of which the update is to be re-signed.
+type edgeToUpdate struct {
+	info *channeldb.ChannelEdgeInfo
+	edge *channeldb.ChannelEdgePolicy
+}
+
+// updateChannels creates new fully signed updates for the given channels, and
+// updates the underlying graph with the new state. The returned announcements
+// and updates are in the same order as the given edges. The announcement of a
+// channel is nil if we don't have a full announcement for it.
+func (d *AuthenticatedGossiper) updateChannels(edges []edgeToUpdate) (
+	[]*lnwire.ChannelAnnouncement, []*lnwire.ChannelUpdate, error) {
+
+	chanUpdates := make([]*lnwire.ChannelUpdate, 0, len(edges))
+	for _, e := range edges {
+		chanUpdate, err := prepareChannelUpdate(e.info, e.edge)
+		if err != nil {
+			return nil, nil, err
+		}
+		chanUpdates = append(chanUpdates, chanUpdate)
+	}
+
+	// With the updates applied, we'll generate new signatures over the
+	// digests of all of them at once.
+	if err := d.signChannelUpdates(chanUpdates); err != nil {
+		return nil, nil, err
+	}
+
+	chanAnns := make([]*lnwire.ChannelAnnouncement, 0, len(edges))
+	for i, e := range edges {
+		chanAnn, err := d.finalizeChannelUpdate(
+			e.info, e.edge, chanUpdates[i],
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		chanAnns = append(chanAnns, chanAnn)
+	}
+
+	return chanAnns, chanUpdates, nil
+}
+
+// signChannelUpdates signs the given channel updates. If an external update
+// signer is configured, the updates are signed in batches with it, otherwise
+// they are signed one by one with the announcement signer.
+func (d *AuthenticatedGossiper) signChannelUpdates(
+	chanUpdates []*lnwire.ChannelUpdate) error {
+
+	if d.cfg.UpdateSigner != nil {
+		return netann.SignChannelUpdateBatch(
+			d.cfg.UpdateSigner, d.selfKey, chanUpdates,
+			d.cfg.UpdateSignBatchSize,
+		)
+	}
+
+	for _, chanUpdate := range chanUpdates {
+		sig, err := SignAnnouncement(d.cfg.AnnSigner, d.selfKey, chanUpdate)
+		if err != nil {
+			return err
+		}
+
+		chanUpdate.Signature, err = lnwire.NewSigFromSignature(sig)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepareChannelUpdate creates a new unsigned update for the channel from our
+// policy. The policy is modified to reflect the new update.
+func prepareChannelUpdate(info *channeldb.ChannelEdgeInfo,
+	edge *channeldb.ChannelEdgePolicy) (*lnwire.ChannelUpdate, error) {
 
 	// We'll make sure we support the new max_htlc field if not already
 	// present.
@@ -2533,35 +2603,38 @@ func (d *AuthenticatedGossiper) updateChannel(info *channeldb.ChannelEdgeInfo,
 	var err error
 	chanUpdate.Signature, err = lnwire.NewSigFromRawSignature(edge.SigBytes)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	// With the update applied, we'll generate a new signature over a
-	// digest of the channel announcement itself.
-	sig, err := SignAnnouncement(d.cfg.AnnSigner, d.selfKey, chanUpdate)
-	if err != nil {
-		return nil, nil, err
-	}
+	return chanUpdate, nil
+}
 
-	// Next, we'll set the new signature in place, and update the reference
-	// in the backing slice.
-	edge.SigBytes = sig.Serialize()
-	chanUpdate.Signature, err = lnwire.NewSigFromSignature(sig)
+// finalizeChannelUpdate verifies the signature of the given channel update,
+// and writes the updated policy to the graph. It returns the original channel
+// announcement, if we have a full announcement for this channel.
+func (d *AuthenticatedGossiper) finalizeChannelUpdate(
+	info *channeldb.ChannelEdgeInfo, edge *channeldb.ChannelEdgePolicy,
+	chanUpdate *lnwire.ChannelUpdate) (*lnwire.ChannelAnnouncement, error) {
+
+	// We'll set the new signature in place, and update the reference in
+	// the backing slice.
+	sig, err := chanUpdate.Signature.ToSignature()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	edge.SigBytes = sig.Serialize()
 
 	// To ensure that our signature is valid, we'll verify it ourself
 	// before committing it to the slice returned.
 	err = routing.ValidateChannelUpdateAnn(d.selfKey, info.Capacity, chanUpdate)
 	if err != nil {
-		return nil, nil, fmt.Errorf("generated invalid channel "+
+		return nil, fmt.Errorf("generated invalid channel "+
 			"update sig: %v", err)
 	}
 
 	// Finally, we'll write the new edge policy to disk.
 	if err := d.cfg.Router.UpdateEdge(edge); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	// We'll also create the original channel announcement so the two can
@@ -2584,29 +2657,29 @@ func (d *AuthenticatedGossiper) updateChannel(info *channeldb.ChannelEdgeInfo,
 			info.AuthProof.NodeSig1Bytes,
 		)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		chanAnn.NodeSig2, err = lnwire.NewSigFromRawSignature(
 			info.AuthProof.NodeSig2Bytes,
 		)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		chanAnn.BitcoinSig1, err = lnwire.NewSigFromRawSignature(
 			info.AuthProof.BitcoinSig1Bytes,
 		)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		chanAnn.BitcoinSig2, err = lnwire.NewSigFromRawSignature(
 			info.AuthProof.BitcoinSig2Bytes,
 		)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 	}
 
-	return chanAnn, chanUpdate, err
+	return chanAnn, nil
 }
 
 // SyncManager returns the gossiper's SyncManager instance.
@@ -389,6 +389,37 @@ func (r *mockGraphSource) MarkEdgeLive(chanID lnwire.ShortChannelID) error {
 	return nil
 }
 
+// MarkEdgesLive clears the given edges from our zombie index, deeming them as
+// live.
+//
+// NOTE: This method is part of the ChannelGraphSource interface.
+func (r *mockGraphSource) MarkEdgesLive(chanIDs ...lnwire.ShortChannelID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, chanID := range chanIDs {
+		delete(r.zombies, chanID.ToUint64())
+	}
+	return nil
+}
+
+// ZombieEdges returns all edges within our zombie index.
+//
+// NOTE: This method is part of the ChannelGraphSource interface.
+func (r *mockGraphSource) ZombieEdges() ([]channeldb.ZombieEdge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	zombies := make([]channeldb.ZombieEdge, 0, len(r.zombies))
+	for chanID, pubKeys := range r.zombies {
+		zombies = append(zombies, channeldb.ZombieEdge{
+			ChannelID:     chanID,
+			NodeKey1Bytes: pubKeys[0],
+			NodeKey2Bytes: pubKeys[1],
+		})
+	}
+	return zombies, nil
+}
+
 // MarkEdgeZombie marks an edge as a zombie within our zombie index.
 func (r *mockGraphSource) MarkEdgeZombie(chanID lnwire.ShortChannelID, pubKey1,
 	pubKey2 [33]byte) error {
@@ -0,0 +1,167 @@
+package eventbus
+
+import (
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+)
+
+// EventBus is a subsystem through which other subsystems publish significant
+// events, such as settled payments or confirmed sweeps. It takes
+// subscriptions for these events, and whenever it receives a new event it
+// notifies all of its subscribers. Subscribers are expected to switch on the
+// type of the events they receive, and to ignore the ones they aren't
+// interested in.
+type EventBus struct {
+	started uint32
+	stopped uint32
+
+	ntfnServer *subscribe.Server
+}
+
+// PaymentSettledEvent is published when an outgoing payment has settled.
+type PaymentSettledEvent struct {
+	// PaymentHash is the hash of the payment.
+	PaymentHash lntypes.Hash
+
+	// Preimage is the preimage that was revealed by the recipient.
+	Preimage lntypes.Preimage
+
+	// Route is the route over which the payment settled.
+	Route *route.Route
+
+	// TraceID is the correlation id of the payment in the router's logs.
+	TraceID uint64
+}
+
+// PaymentFailedEvent is published when the router has given up on an
+// outgoing payment.
+type PaymentFailedEvent struct {
+	// PaymentHash is the hash of the payment.
+	PaymentHash lntypes.Hash
+
+	// Reason is the reason why the payment failed.
+	Reason channeldb.FailureReason
+
+	// TraceID is the correlation id of the payment in the router's logs.
+	TraceID uint64
+}
+
+// SweepBroadcastEvent is published when the sweeper has broadcast a sweep
+// transaction.
+type SweepBroadcastEvent struct {
+	// Tx is the sweep transaction.
+	Tx *wire.MsgTx
+
+	// FeeRate is the fee rate of the sweep transaction.
+	FeeRate lnwallet.SatPerKWeight
+
+	// Height is the height at which the transaction was broadcast.
+	Height int32
+}
+
+// SweepConfirmedEvent is published when a sweep transaction of the sweeper
+// has confirmed.
+type SweepConfirmedEvent struct {
+	// Tx is the confirmed sweep transaction.
+	Tx *wire.MsgTx
+
+	// Height is the height at which the transaction confirmed.
+	Height int32
+}
+
+// PruneReason describes why a channel was pruned from the graph.
+type PruneReason uint8
+
+const (
+	// PruneReasonClosed indicates that the funding output of the channel
+	// was spent on chain.
+	PruneReasonClosed PruneReason = iota
+
+	// PruneReasonZombie indicates that the channel was considered a
+	// zombie, because neither of its policies was updated for a long time.
+	PruneReasonZombie
+)
+
+// String returns a human readable representation of the prune reason.
+func (r PruneReason) String() string {
+	switch r {
+	case PruneReasonClosed:
+		return "closed"
+
+	case PruneReasonZombie:
+		return "zombie"
+
+	default:
+		return "unknown"
+	}
+}
+
+// ChannelPrunedEvent is published when a channel is removed from the graph.
+type ChannelPrunedEvent struct {
+	// ChannelID is the short channel id of the pruned channel.
+	ChannelID uint64
+
+	// ChannelPoint is the funding outpoint of the pruned channel.
+	ChannelPoint wire.OutPoint
+
+	// Capacity is the capacity of the pruned channel.
+	Capacity btcutil.Amount
+
+	// Reason is the reason why the channel was pruned.
+	Reason PruneReason
+}
+
+// New creates a new event bus.
+func New() *EventBus {
+	return &EventBus{
+		ntfnServer: subscribe.NewServer(),
+	}
+}
+
+// Start starts the EventBus and all goroutines it needs to carry out its task.
+func (b *EventBus) Start() error {
+	if !atomic.CompareAndSwapUint32(&b.started, 0, 1) {
+		return nil
+	}
+
+	log.Tracef("EventBus starting")
+
+	return b.ntfnServer.Start()
+}
+
+// Stop signals the event bus for a graceful shutdown.
+func (b *EventBus) Stop() {
+	if !atomic.CompareAndSwapUint32(&b.stopped, 0, 1) {
+		return
+	}
+
+	b.ntfnServer.Stop()
+}
+
+// SubscribeEvents returns a subscribe.Client that will receive all events that
+// are published after the subscription was made.
+func (b *EventBus) SubscribeEvents() (*subscribe.Client, error) {
+	return b.ntfnServer.Subscribe()
+}
+
+// Publish sends the given event to all subscribers. Publishing on a nil event
+// bus is a no-op, so that subsystems don't need to check whether one is
+// configured.
+func (b *EventBus) Publish(event interface{}) {
+	if b == nil {
+		return
+	}
+
+	log.Tracef("Publishing event %T", event)
+
+	if err := b.ntfnServer.SendUpdate(event); err != nil {
+		log.Warnf("Unable to publish event %T: %v", event, err)
+	}
+}
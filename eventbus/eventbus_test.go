@@ -0,0 +1,62 @@
+package eventbus
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEventBus tests that subscribers receive the events that are published
+// after they subscribe, and that publishing on a nil bus is a no-op.
+func TestEventBus(t *testing.T) {
+	t.Parallel()
+
+	// Publishing on a nil bus must not panic.
+	var nilBus *EventBus
+	nilBus.Publish(ChannelPrunedEvent{})
+
+	bus := New()
+	if err := bus.Start(); err != nil {
+		t.Fatalf("unable to start event bus: %v", err)
+	}
+	defer bus.Stop()
+
+	client, err := bus.SubscribeEvents()
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+	defer client.Cancel()
+
+	bus.Publish(ChannelPrunedEvent{
+		ChannelID: 1,
+		Reason:    PruneReasonZombie,
+	})
+	bus.Publish(SweepConfirmedEvent{Height: 100})
+
+	select {
+	case event := <-client.Updates():
+		pruned, ok := event.(ChannelPrunedEvent)
+		if !ok {
+			t.Fatalf("unexpected event %T", event)
+		}
+		if pruned.ChannelID != 1 || pruned.Reason != PruneReasonZombie {
+			t.Fatalf("unexpected event: %v", pruned)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatalf("event not received")
+	}
+
+	select {
+	case event := <-client.Updates():
+		confirmed, ok := event.(SweepConfirmedEvent)
+		if !ok {
+			t.Fatalf("unexpected event %T", event)
+		}
+		if confirmed.Height != 100 {
+			t.Fatalf("unexpected height %v", confirmed.Height)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatalf("event not received")
+	}
+}
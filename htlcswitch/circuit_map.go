@@ -115,6 +115,10 @@ type CircuitMap interface {
 	// circuits that use the given payment hash.
 	LookupByPaymentHash(hash [32]byte) []*PaymentCircuit
 
+	// LookupByIncomingChannel queries the circuit map and returns all
+	// active circuits whose incoming HTLC arrived over the given channel.
+	LookupByIncomingChannel(chanID lnwire.ShortChannelID) []*PaymentCircuit
+
 	// NumPending returns the total number of active circuits added by
 	// CommitCircuits.
 	NumPending() int
@@ -518,6 +522,24 @@ func (cm *circuitMap) LookupByPaymentHash(hash [32]byte) []*PaymentCircuit {
 	return circuits
 }
 
+// LookupByIncomingChannel looks up and returns all active payment circuits
+// whose incoming HTLC arrived over the given channel.
+func (cm *circuitMap) LookupByIncomingChannel(
+	chanID lnwire.ShortChannelID) []*PaymentCircuit {
+
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+
+	var circuits []*PaymentCircuit
+	for inKey, circuit := range cm.pending {
+		if inKey.ChanID == chanID {
+			circuits = append(circuits, circuit)
+		}
+	}
+
+	return circuits
+}
+
 // CommitCircuits accepts any number of circuits and persistently adds them to
 // the switch's circuit map. The method returns a list of circuits that had not
 // been seen prior by the switch. A link should only forward HTLCs corresponding
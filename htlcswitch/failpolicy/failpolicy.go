@@ -0,0 +1,271 @@
+package failpolicy
+
+import (
+	"sync"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// Penalty describes which part of the route is held responsible for a
+// failure that was reported while forwarding an htlc.
+type Penalty uint8
+
+const (
+	// PenaltyNone indicates that no part of the route is penalized.
+	PenaltyNone Penalty = iota
+
+	// PenaltyNode indicates that the node that reported the failure is
+	// penalized.
+	PenaltyNode
+
+	// PenaltyOutgoingChannel indicates that the outgoing channel of the
+	// node that reported the failure is penalized, regardless of the
+	// amount that was sent over it.
+	PenaltyOutgoingChannel
+
+	// PenaltyOutgoingChannelAmt indicates that the outgoing channel of the
+	// node that reported the failure is penalized, but only for amounts
+	// equal to or larger than the amount that was sent over it.
+	PenaltyOutgoingChannelAmt
+
+	// PenaltyOutgoingChannelBoth indicates that the outgoing channel of
+	// the node that reported the failure is penalized in both directions.
+	PenaltyOutgoingChannelBoth
+
+	// PenaltyChannelPolicy indicates that the policy of the outgoing
+	// channel of the node that reported the failure was out of date. The
+	// enclosed channel update is applied and the channel is only
+	// penalized if the node keeps reporting policy failures.
+	PenaltyChannelPolicy
+)
+
+// String returns a human readable representation of the penalty.
+func (p Penalty) String() string {
+	switch p {
+	case PenaltyNone:
+		return "none"
+
+	case PenaltyNode:
+		return "node"
+
+	case PenaltyOutgoingChannel:
+		return "outgoing_channel"
+
+	case PenaltyOutgoingChannelAmt:
+		return "outgoing_channel_amt"
+
+	case PenaltyOutgoingChannelBoth:
+		return "outgoing_channel_both"
+
+	case PenaltyChannelPolicy:
+		return "channel_policy"
+
+	default:
+		return "unknown"
+	}
+}
+
+// Policy describes how the sender of a payment should react to a particular
+// failure.
+type Policy struct {
+	// Terminal indicates that the payment can't succeed over any route, so
+	// no further attempts should be made.
+	Terminal bool
+
+	// Penalty describes which part of the route is held responsible for
+	// the failure. It is only relevant for non-terminal failures.
+	Penalty Penalty
+
+	// Permanent indicates that the failure is not expected to be resolved
+	// over time, so the penalty should be remembered for longer.
+	Permanent bool
+
+	// ApplyUpdate indicates that the channel update enclosed in the
+	// failure, if any, should be applied to the graph.
+	ApplyUpdate bool
+}
+
+// terminalPolicy is the policy of failures after which a payment can't be
+// retried.
+var terminalPolicy = Policy{Terminal: true}
+
+// defaultPolicies is the set of policies for the failures defined by the
+// current version of the Lightning protocol.
+var defaultPolicies = map[lnwire.FailCode]Policy{
+	// If the end destination didn't know the payment hash, or we sent the
+	// wrong amount or time-lock to it, another route won't help.
+	lnwire.CodeUnknownPaymentHash:       terminalPolicy,
+	lnwire.CodeIncorrectPaymentAmount:   terminalPolicy,
+	lnwire.CodeFinalIncorrectCltvExpiry: terminalPolicy,
+	lnwire.CodeFinalIncorrectHtlcAmount: terminalPolicy,
+
+	// TODO(roasbeef): can happen to to race condition, try again with
+	// recent block height
+	lnwire.CodeFinalExpiryTooSoon: terminalPolicy,
+
+	// If we erroneously attempted to cross a chain border, or we hit an
+	// instance of onion payload corruption, we'll exit early as this
+	// shouldn't happen in the typical case.
+	lnwire.CodeInvalidRealm:        terminalPolicy,
+	lnwire.CodeInvalidOnionVersion: terminalPolicy,
+	lnwire.CodeInvalidOnionHmac:    terminalPolicy,
+	lnwire.CodeInvalidOnionKey:     terminalPolicy,
+
+	// If the expiry was too soon for an intermediate node, the node
+	// doesn't know the correct block height.
+	lnwire.CodeExpiryTooSoon: {
+		Penalty:     PenaltyNode,
+		ApplyUpdate: true,
+	},
+
+	// If the node disagrees with the fee, time lock or minimum amount
+	// we used, we apply its new policy and try again.
+	lnwire.CodeAmountBelowMinimum: {
+		Penalty:     PenaltyChannelPolicy,
+		ApplyUpdate: true,
+	},
+	lnwire.CodeFeeInsufficient: {
+		Penalty:     PenaltyChannelPolicy,
+		ApplyUpdate: true,
+	},
+	lnwire.CodeIncorrectCltvExpiry: {
+		Penalty:     PenaltyChannelPolicy,
+		ApplyUpdate: true,
+	},
+
+	// The outgoing channel is currently disabled.
+	lnwire.CodeChannelDisabled: {
+		Penalty:     PenaltyOutgoingChannel,
+		ApplyUpdate: true,
+	},
+
+	// It's likely that the outgoing channel didn't have sufficient
+	// capacity for the amount we sent.
+	lnwire.CodeTemporaryChannelFailure: {
+		Penalty:     PenaltyOutgoingChannelAmt,
+		ApplyUpdate: true,
+	},
+
+	// The node doesn't have the features required to forward.
+	lnwire.CodeRequiredNodeFeatureMissing: {
+		Penalty:   PenaltyNode,
+		Permanent: true,
+	},
+	lnwire.CodeRequiredChannelFeatureMissing: {
+		Penalty:   PenaltyNode,
+		Permanent: true,
+	},
+
+	// If the next hop wasn't known or offline, we only penalize the
+	// channel which we attempted to route over. This guards against
+	// routing nodes returning errors in order to black list another node.
+	lnwire.CodeUnknownNextPeer: {
+		Penalty: PenaltyOutgoingChannel,
+	},
+
+	lnwire.CodeTemporaryNodeFailure: {
+		Penalty: PenaltyNode,
+	},
+	lnwire.CodePermanentNodeFailure: {
+		Penalty:   PenaltyNode,
+		Permanent: true,
+	},
+
+	// As there currently is no way of knowing a node's maximum acceptable
+	// cltv, we cannot take this constraint into account during routing.
+	lnwire.CodeExpiryTooFar: {
+		Penalty:   PenaltyNode,
+		Permanent: true,
+	},
+
+	lnwire.CodePermanentChannelFailure: {
+		Penalty:   PenaltyOutgoingChannelBoth,
+		Permanent: true,
+	},
+}
+
+// Table maps failure codes to the policy that applies to them. Failure codes
+// that aren't part of the table are treated as terminal.
+type Table struct {
+	policies map[lnwire.FailCode]Policy
+
+	mu sync.RWMutex
+}
+
+// NewTable returns a table that is populated with the default policies.
+func NewTable() *Table {
+	policies := make(map[lnwire.FailCode]Policy, len(defaultPolicies))
+	for code, policy := range defaultPolicies {
+		policies[code] = policy
+	}
+
+	return &Table{
+		policies: policies,
+	}
+}
+
+// DefaultTable is the table that is used by the router to decide how to react
+// to payment failures, unless it is configured otherwise.
+var DefaultTable = NewTable()
+
+// Register sets the policy for the given failure code, replacing any policy
+// that was set previously. This allows callers to add policies for failure
+// codes that aren't known to this package.
+func (t *Table) Register(code lnwire.FailCode, policy Policy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.policies[code] = policy
+}
+
+// Lookup returns the policy for the given failure message.
+func (t *Table) Lookup(msg lnwire.FailureMessage) Policy {
+	if msg == nil {
+		return terminalPolicy
+	}
+
+	// The switch reports an htlc that was resolved on chain before it got
+	// past the first hop as a permanent channel failure by value, rather
+	// than by pointer like decoded failures. Such a failure has always
+	// ended the payment, so it bypasses the table.
+	if _, ok := msg.(lnwire.FailPermanentChannelFailure); ok {
+		return terminalPolicy
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	policy, ok := t.policies[msg.Code()]
+	if !ok {
+		return terminalPolicy
+	}
+
+	return policy
+}
+
+// ChannelUpdate returns the channel update that is enclosed in the given
+// failure message, or nil if the message doesn't carry one.
+func ChannelUpdate(msg lnwire.FailureMessage) *lnwire.ChannelUpdate {
+	switch onionErr := msg.(type) {
+	case *lnwire.FailExpiryTooSoon:
+		return &onionErr.Update
+
+	case *lnwire.FailAmountBelowMinimum:
+		return &onionErr.Update
+
+	case *lnwire.FailFeeInsufficient:
+		return &onionErr.Update
+
+	case *lnwire.FailIncorrectCltvExpiry:
+		return &onionErr.Update
+
+	case *lnwire.FailChannelDisabled:
+		return &onionErr.Update
+
+	case *lnwire.FailTemporaryChannelFailure:
+		return onionErr.Update
+
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+package failpolicy
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// TestLookup asserts that failures are mapped to the expected policies, and
+// that failures without a known policy are treated as terminal.
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	table := NewTable()
+
+	tests := []struct {
+		name   string
+		msg    lnwire.FailureMessage
+		policy Policy
+	}{
+		{
+			name:   "nil failure",
+			msg:    nil,
+			policy: Policy{Terminal: true},
+		},
+		{
+			name:   "unknown payment hash",
+			msg:    &lnwire.FailUnknownPaymentHash{},
+			policy: Policy{Terminal: true},
+		},
+		{
+			name: "temporary channel failure",
+			msg:  lnwire.NewTemporaryChannelFailure(nil),
+			policy: Policy{
+				Penalty:     PenaltyOutgoingChannelAmt,
+				ApplyUpdate: true,
+			},
+		},
+		{
+			name: "fee insufficient",
+			msg:  &lnwire.FailFeeInsufficient{},
+			policy: Policy{
+				Penalty:     PenaltyChannelPolicy,
+				ApplyUpdate: true,
+			},
+		},
+		{
+			name: "permanent channel failure",
+			msg:  &lnwire.FailPermanentChannelFailure{},
+			policy: Policy{
+				Penalty:   PenaltyOutgoingChannelBoth,
+				Permanent: true,
+			},
+		},
+		{
+			name:   "permanent channel failure by value",
+			msg:    lnwire.FailPermanentChannelFailure{},
+			policy: Policy{Terminal: true},
+		},
+		{
+			name: "permanent node failure",
+			msg:  &lnwire.FailPermanentNodeFailure{},
+			policy: Policy{
+				Penalty:   PenaltyNode,
+				Permanent: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		policy := table.Lookup(test.msg)
+		if policy != test.policy {
+			t.Fatalf("%v: expected policy %+v, got %+v", test.name,
+				test.policy, policy)
+		}
+	}
+}
+
+// TestRegister asserts that registered policies override the defaults without
+// affecting other tables.
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	table := NewTable()
+
+	retry := Policy{Penalty: PenaltyNode}
+	table.Register(lnwire.CodeFinalExpiryTooSoon, retry)
+
+	msg := &lnwire.FailFinalExpiryTooSoon{}
+	if policy := table.Lookup(msg); policy != retry {
+		t.Fatalf("expected registered policy, got %+v", policy)
+	}
+
+	if !NewTable().Lookup(msg).Terminal {
+		t.Fatalf("expected default policy to be terminal")
+	}
+}
+
+// TestChannelUpdate asserts that enclosed channel updates are extracted from
+// failure messages.
+func TestChannelUpdate(t *testing.T) {
+	t.Parallel()
+
+	update := &lnwire.ChannelUpdate{Timestamp: 1}
+	if ChannelUpdate(lnwire.NewTemporaryChannelFailure(update)) != update {
+		t.Fatalf("expected enclosed update")
+	}
+
+	feeErr := &lnwire.FailFeeInsufficient{Update: *update}
+	if ChannelUpdate(feeErr) != &feeErr.Update {
+		t.Fatalf("expected enclosed update")
+	}
+
+	if ChannelUpdate(&lnwire.FailUnknownNextPeer{}) != nil {
+		t.Fatalf("expected no update")
+	}
+}
@@ -964,6 +964,12 @@ func (m *mockCircuitMap) LookupByPaymentHash(hash [32]byte) []*PaymentCircuit {
 	return nil
 }
 
+func (m *mockCircuitMap) LookupByIncomingChannel(
+	chanID lnwire.ShortChannelID) []*PaymentCircuit {
+
+	return nil
+}
+
 func (m *mockCircuitMap) NumPending() int {
 	return 0
 }
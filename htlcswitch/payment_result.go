@@ -41,6 +41,41 @@ type PaymentResult struct {
 	Error error
 }
 
+// AttemptStatus describes what the switch knows about a locally initiated
+// payment attempt.
+type AttemptStatus uint8
+
+const (
+	// AttemptStatusUnknown indicates that the switch has no record of the
+	// payment attempt, meaning it was never forwarded.
+	AttemptStatusUnknown AttemptStatus = iota
+
+	// AttemptStatusInFlight indicates that the payment attempt has been
+	// forwarded, and its result is not yet known.
+	AttemptStatusInFlight
+
+	// AttemptStatusResolved indicates that the result of the payment
+	// attempt is available.
+	AttemptStatusResolved
+)
+
+// String returns a human readable representation of the attempt status.
+func (a AttemptStatus) String() string {
+	switch a {
+	case AttemptStatusUnknown:
+		return "unknown"
+
+	case AttemptStatusInFlight:
+		return "in-flight"
+
+	case AttemptStatusResolved:
+		return "resolved"
+
+	default:
+		return "invalid"
+	}
+}
+
 // networkResult is the raw result received from the network after a payment
 // attempt has been made. Since the switch doesn't always have the necessary
 // data to decode the raw message, we store it together with some meta data,
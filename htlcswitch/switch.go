@@ -326,6 +326,45 @@ func (s *Switch) ProcessContractResolution(msg contractcourt.ResolutionMsg) erro
 	return nil
 }
 
+// PaymentAttemptStatus returns what the switch knows about the locally
+// initiated payment attempt with the given paymentID. It allows the caller to
+// reconcile its own record of the attempt with the switch after a restart.
+func (s *Switch) PaymentAttemptStatus(paymentID uint64) (AttemptStatus,
+	error) {
+
+	inKey := CircuitKey{
+		ChanID: sourceHop,
+		HtlcID: paymentID,
+	}
+	if s.circuits.LookupCircuit(inKey) != nil {
+		return AttemptStatusInFlight, nil
+	}
+
+	_, err := s.networkResults.getResult(paymentID)
+	switch {
+	case err == ErrPaymentIDNotFound:
+		return AttemptStatusUnknown, nil
+
+	case err != nil:
+		return 0, err
+	}
+
+	return AttemptStatusResolved, nil
+}
+
+// LocalPaymentIDs returns the payment IDs of all locally initiated payment
+// attempts that are currently in flight within the switch.
+func (s *Switch) LocalPaymentIDs() []uint64 {
+	circuits := s.circuits.LookupByIncomingChannel(sourceHop)
+
+	paymentIDs := make([]uint64, 0, len(circuits))
+	for _, circuit := range circuits {
+		paymentIDs = append(paymentIDs, circuit.Incoming.HtlcID)
+	}
+
+	return paymentIDs
+}
+
 // GetPaymentResult returns the the result of the payment attempt with the
 // given paymentID. The method returns a channel where the payment result will
 // be sent when available, or an error is encountered during forwarding. When a
@@ -409,6 +448,11 @@ func (s *Switch) GetPaymentResult(paymentID uint64, paymentHash lntypes.Hash,
 func (s *Switch) SendHTLC(firstHop lnwire.ShortChannelID, paymentID uint64,
 	htlc *lnwire.UpdateAddHTLC) error {
 
+	// The router logs the payment id alongside the trace id of the
+	// payment, so that the switch's logs for it can be joined.
+	log.Debugf("Dispatching local htlc (pid=%v, hash=%x) over %v",
+		paymentID, htlc.PaymentHash[:], firstHop)
+
 	// Generate and send new update packet, if error will be received on
 	// this stage it means that packet haven't left boundaries of our
 	// system and something wrong happened.
@@ -422,6 +466,87 @@ func (s *Switch) SendHTLC(firstHop lnwire.ShortChannelID, paymentID uint64,
 	return s.forward(packet)
 }
 
+// CheckLocalHtlc checks whether a locally initiated htlc could be dispatched
+// over the given first hop, without actually sending it. It performs the same
+// checks as SendHTLC does before handing the htlc to the link, and returns the
+// ForwardingError that SendHTLC would return if the htlc violates our own
+// outgoing policy or exceeds the bandwidth of the channel.
+func (s *Switch) CheckLocalHtlc(firstHop lnwire.ShortChannelID,
+	htlc *lnwire.UpdateAddHTLC) error {
+
+	_, err := s.checkLocalHtlc(firstHop, htlc)
+	return err
+}
+
+// checkLocalHtlc returns the link of the given outgoing channel if a locally
+// initiated htlc can be dispatched over it. Otherwise, a ForwardingError
+// sourced by us is returned.
+func (s *Switch) checkLocalHtlc(outgoingChanID lnwire.ShortChannelID,
+	htlc *lnwire.UpdateAddHTLC) (ChannelLink, error) {
+
+	// Try to find links by node destination.
+	s.indexMtx.RLock()
+	link, err := s.getLinkByShortID(outgoingChanID)
+	s.indexMtx.RUnlock()
+	if err != nil {
+		log.Errorf("Link %v not found", outgoingChanID)
+		return nil, &ForwardingError{
+			ErrorSource:    s.cfg.SelfKey,
+			FailureMessage: &lnwire.FailUnknownNextPeer{},
+		}
+	}
+
+	if !link.EligibleToForward() {
+		err := fmt.Errorf("Link %v is not available to forward",
+			outgoingChanID)
+		log.Error(err)
+
+		// The update does not need to be populated as the error
+		// will be returned back to the router.
+		htlcErr := lnwire.NewTemporaryChannelFailure(nil)
+		return nil, &ForwardingError{
+			ErrorSource:    s.cfg.SelfKey,
+			ExtraMsg:       err.Error(),
+			FailureMessage: htlcErr,
+		}
+	}
+
+	// Ensure that the htlc satisfies the outgoing channel policy.
+	currentHeight := atomic.LoadUint32(&s.bestHeight)
+	htlcErr := link.HtlcSatifiesPolicyLocal(
+		htlc.PaymentHash,
+		htlc.Amount,
+		htlc.Expiry, currentHeight,
+	)
+	if htlcErr != nil {
+		log.Errorf("Link %v policy for local forward not "+
+			"satisfied", outgoingChanID)
+
+		return nil, &ForwardingError{
+			ErrorSource:    s.cfg.SelfKey,
+			FailureMessage: htlcErr,
+		}
+	}
+
+	if link.Bandwidth() < htlc.Amount {
+		err := fmt.Errorf("Link %v has insufficient capacity: "+
+			"need %v, has %v", outgoingChanID,
+			htlc.Amount, link.Bandwidth())
+		log.Error(err)
+
+		// The update does not need to be populated as the error
+		// will be returned back to the router.
+		htlcErr := lnwire.NewTemporaryChannelFailure(nil)
+		return nil, &ForwardingError{
+			ErrorSource:    s.cfg.SelfKey,
+			ExtraMsg:       err.Error(),
+			FailureMessage: htlcErr,
+		}
+	}
+
+	return link, nil
+}
+
 // UpdateForwardingPolicies sends a message to the switch to update the
 // forwarding policies for the set of target channels. If the set of targeted
 // channels is nil, then the forwarding policies for all active channels with
@@ -769,64 +894,9 @@ func (s *Switch) handleLocalDispatch(pkt *htlcPacket) error {
 	// User have created the htlc update therefore we should find the
 	// appropriate channel link and send the payment over this link.
 	if htlc, ok := pkt.htlc.(*lnwire.UpdateAddHTLC); ok {
-		// Try to find links by node destination.
-		s.indexMtx.RLock()
-		link, err := s.getLinkByShortID(pkt.outgoingChanID)
-		s.indexMtx.RUnlock()
+		link, err := s.checkLocalHtlc(pkt.outgoingChanID, htlc)
 		if err != nil {
-			log.Errorf("Link %v not found", pkt.outgoingChanID)
-			return &ForwardingError{
-				ErrorSource:    s.cfg.SelfKey,
-				FailureMessage: &lnwire.FailUnknownNextPeer{},
-			}
-		}
-
-		if !link.EligibleToForward() {
-			err := fmt.Errorf("Link %v is not available to forward",
-				pkt.outgoingChanID)
-			log.Error(err)
-
-			// The update does not need to be populated as the error
-			// will be returned back to the router.
-			htlcErr := lnwire.NewTemporaryChannelFailure(nil)
-			return &ForwardingError{
-				ErrorSource:    s.cfg.SelfKey,
-				ExtraMsg:       err.Error(),
-				FailureMessage: htlcErr,
-			}
-		}
-
-		// Ensure that the htlc satisfies the outgoing channel policy.
-		currentHeight := atomic.LoadUint32(&s.bestHeight)
-		htlcErr := link.HtlcSatifiesPolicyLocal(
-			htlc.PaymentHash,
-			htlc.Amount,
-			htlc.Expiry, currentHeight,
-		)
-		if htlcErr != nil {
-			log.Errorf("Link %v policy for local forward not "+
-				"satisfied", pkt.outgoingChanID)
-
-			return &ForwardingError{
-				ErrorSource:    s.cfg.SelfKey,
-				FailureMessage: htlcErr,
-			}
-		}
-
-		if link.Bandwidth() < htlc.Amount {
-			err := fmt.Errorf("Link %v has insufficient capacity: "+
-				"need %v, has %v", pkt.outgoingChanID,
-				htlc.Amount, link.Bandwidth())
-			log.Error(err)
-
-			// The update does not need to be populated as the error
-			// will be returned back to the router.
-			htlcErr := lnwire.NewTemporaryChannelFailure(nil)
-			return &ForwardingError{
-				ErrorSource:    s.cfg.SelfKey,
-				ExtraMsg:       err.Error(),
-				FailureMessage: htlcErr,
-			}
+			return err
 		}
 
 		return link.HandleSwitchPacket(pkt)
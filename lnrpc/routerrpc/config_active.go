@@ -34,11 +34,42 @@ type Config struct {
 	// channel is back at 50% probability.
 	PenaltyHalfLife time.Duration `long:"penaltyhalflife" description:"Defines the duration after which a penalized node or channel is back at 50% probability"`
 
+	// PermanentPenaltyHalfLife defines the half-life of permanent failures,
+	// such as closed channels or missing features.
+	PermanentPenaltyHalfLife time.Duration `long:"permanentpenaltyhalflife" description:"Defines the duration after which a node or channel penalized for a permanent failure is back at 50% probability"`
+
+	// PolicyPenaltyHalfLife defines the half-life of channels that
+	// repeatedly fail due to their routing policy.
+	PolicyPenaltyHalfLife time.Duration `long:"policypenaltyhalflife" description:"Defines the duration after which a channel penalized for repeated policy failures is back at 50% probability"`
+
 	// AttemptCost is the virtual cost in path finding weight units of
 	// executing a payment attempt that fails. It is used to trade off
 	// potentially better routes against their probability of succeeding.
 	AttemptCost int64 `long:"attemptcost" description:"The (virtual) cost in sats of a failed payment attempt"`
 
+	// LiquidityAdBias is the fraction by which path finding discounts the
+	// weight of channels of nodes that advertise inbound liquidity.
+	LiquidityAdBias float64 `long:"liquidityadbias" description:"Fraction in [0, 1) by which path finding favors channels of nodes advertising inbound liquidity. Zero disables the bias"`
+
+	// TieBreaker is the name of the criterion by which path finding
+	// chooses between routes of equal cost.
+	TieBreaker string `long:"tiebreaker" description:"The criterion by which path finding chooses between routes of equal cost" choice:"none" choice:"probability" choice:"hops" choice:"capacity" choice:"random"`
+
+	// ProbabilityEstimator is the name of the model that mission control
+	// uses to estimate the success probability of a channel.
+	ProbabilityEstimator string `long:"estimator" description:"The model used to estimate the success probability of a channel" choice:"apriori" choice:"bimodal" choice:"historical"`
+
+	// BimodalScale is the scale of the balance distribution that is
+	// assumed by the bimodal estimator.
+	BimodalScale int64 `long:"bimodalscale" description:"The scale in sats over which the balance distribution assumed by the bimodal estimator decays away from the channel ends"`
+
+	// HourPenalty, DayPenalty and WeekPenalty are the fractions by which
+	// each failure of a node or channel in the respective recency bucket
+	// reduces its success probability.
+	HourPenalty float64 `long:"hourpenalty" description:"Fraction by which each failure in the last hour reduces the success probability of a node or channel. Zero disables the bucket"`
+	DayPenalty  float64 `long:"daypenalty" description:"Fraction by which each failure older than an hour but within the last day reduces the success probability of a node or channel. Zero disables the bucket"`
+	WeekPenalty float64 `long:"weekpenalty" description:"Fraction by which each failure older than a day but within the last week reduces the success probability of a node or channel. Zero disables the bucket"`
+
 	// NetworkDir is the main network directory wherein the router rpc
 	// server will find the macaroon named DefaultRouterMacFilename.
 	NetworkDir string
@@ -63,24 +94,61 @@ type Config struct {
 // DefaultConfig defines the config defaults.
 func DefaultConfig() *Config {
 	return &Config{
-		AprioriHopProbability: routing.DefaultAprioriHopProbability,
-		MinRouteProbability:   routing.DefaultMinRouteProbability,
-		PenaltyHalfLife:       routing.DefaultPenaltyHalfLife,
+		AprioriHopProbability:    routing.DefaultAprioriHopProbability,
+		MinRouteProbability:      routing.DefaultMinRouteProbability,
+		PenaltyHalfLife:          routing.DefaultPenaltyHalfLife,
+		PermanentPenaltyHalfLife: routing.DefaultPermanentPenaltyHalfLife,
+		PolicyPenaltyHalfLife:    routing.DefaultPolicyPenaltyHalfLife,
 		AttemptCost: int64(
 			routing.DefaultPaymentAttemptPenalty.ToSatoshis(),
 		),
+		ProbabilityEstimator: routing.AprioriEstimatorName,
+		BimodalScale:         int64(routing.DefaultBimodalScale),
 	}
 }
 
 // GetMissionControlConfig returns the mission control config based on this sub
-// server config.
-func GetMissionControlConfig(cfg *Config) *routing.MissionControlConfig {
+// server config. An error is returned if the configured probability estimator
+// is unknown.
+func GetMissionControlConfig(cfg *Config) (*routing.MissionControlConfig,
+	error) {
+
+	estimator, err := routing.NewProbabilityEstimator(
+		cfg.ProbabilityEstimator, cfg.AprioriHopProbability,
+		btcutil.Amount(cfg.BimodalScale),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tieBreaker, err := routing.ParseTieBreaker(cfg.TieBreaker)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recency decay is only enabled if at least one of the buckets has a
+	// penalty configured.
+	var recencyDecay *routing.RecencyDecayConfig
+	if cfg.HourPenalty != 0 || cfg.DayPenalty != 0 || cfg.WeekPenalty != 0 {
+		recencyDecay = &routing.RecencyDecayConfig{
+			HourPenalty: cfg.HourPenalty,
+			DayPenalty:  cfg.DayPenalty,
+			WeekPenalty: cfg.WeekPenalty,
+		}
+	}
+
 	return &routing.MissionControlConfig{
 		AprioriHopProbability: cfg.AprioriHopProbability,
 		MinRouteProbability:   cfg.MinRouteProbability,
 		PaymentAttemptPenalty: lnwire.NewMSatFromSatoshis(
 			btcutil.Amount(cfg.AttemptCost),
 		),
-		PenaltyHalfLife: cfg.PenaltyHalfLife,
-	}
+		PenaltyHalfLife:          cfg.PenaltyHalfLife,
+		PermanentPenaltyHalfLife: cfg.PermanentPenaltyHalfLife,
+		PolicyPenaltyHalfLife:    cfg.PolicyPenaltyHalfLife,
+		LiquidityAdBias:          cfg.LiquidityAdBias,
+		TieBreaker:               tieBreaker,
+		Estimator:                estimator,
+		RecencyDecay:             recencyDecay,
+	}, nil
 }
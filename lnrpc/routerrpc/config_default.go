@@ -16,11 +16,15 @@ func DefaultConfig() *Config {
 
 // GetMissionControlConfig returns the mission control config based on this sub
 // server config.
-func GetMissionControlConfig(cfg *Config) *routing.MissionControlConfig {
+func GetMissionControlConfig(cfg *Config) (*routing.MissionControlConfig,
+	error) {
+
 	return &routing.MissionControlConfig{
-		AprioriHopProbability: routing.DefaultAprioriHopProbability,
-		MinRouteProbability:   routing.DefaultMinRouteProbability,
-		PaymentAttemptPenalty: routing.DefaultPaymentAttemptPenalty,
-		PenaltyHalfLife:       routing.DefaultPenaltyHalfLife,
-	}
+		AprioriHopProbability:    routing.DefaultAprioriHopProbability,
+		MinRouteProbability:      routing.DefaultMinRouteProbability,
+		PaymentAttemptPenalty:    routing.DefaultPaymentAttemptPenalty,
+		PenaltyHalfLife:          routing.DefaultPenaltyHalfLife,
+		PermanentPenaltyHalfLife: routing.DefaultPermanentPenaltyHalfLife,
+		PolicyPenaltyHalfLife:    routing.DefaultPolicyPenaltyHalfLife,
+	}, nil
 }
@@ -138,8 +138,8 @@ func (r *RouterBackend) QueryRoutes(ctx context.Context,
 	restrictions := &routing.RestrictParams{
 		FeeLimit: feeLimit,
 		ProbabilitySource: func(node route.Vertex,
-			edge routing.EdgeLocator,
-			amt lnwire.MilliSatoshi) float64 {
+			edge routing.EdgeLocator, amt lnwire.MilliSatoshi,
+			capacity btcutil.Amount) float64 {
 
 			if _, ok := ignoredNodes[node]; ok {
 				return 0
@@ -360,7 +360,9 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 
 	// Pass along an outgoing channel restriction if specified.
 	if rpcPayReq.OutgoingChanId != 0 {
-		payIntent.OutgoingChannelID = &rpcPayReq.OutgoingChanId
+		payIntent.OutgoingChannelIDs = []uint64{
+			rpcPayReq.OutgoingChanId,
+		}
 	}
 
 	// Take cltv limit from request if set.
@@ -414,6 +416,13 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 			return nil, err
 		}
 
+		// We'll also make sure that we support all features that the
+		// payreq requires.
+		err = routing.ValidateInvoiceFeatures(payReq)
+		if err != nil {
+			return nil, err
+		}
+
 		// If the amount was not included in the invoice, then we let
 		// the payee specify the amount of satoshis they wish to send.
 		// We override the amount to pay with the amount provided from
@@ -444,6 +453,7 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 
 		payIntent.FinalCLTVDelta = uint16(payReq.MinFinalCLTVExpiry())
 		payIntent.RouteHints = payReq.RouteHints
+		payIntent.InvoiceExpiry = payReq.Timestamp.Add(payReq.Expiry())
 	} else {
 		// Otherwise, If the payment request field was not specified
 		// (and a custom route wasn't specified), construct the payment
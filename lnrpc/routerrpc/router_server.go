@@ -547,6 +547,13 @@ func (s *Server) trackPayment(paymentHash lntypes.Hash,
 			case channeldb.FailureReasonNoRoute:
 				status.State = PaymentState_FAILED_NO_ROUTE
 
+			// There is no dedicated state for a payment that was
+			// settled with an invalid preimage, so we'll report
+			// it as an error to the client.
+			case channeldb.FailureReasonInvalidPreimage:
+				return errors.New("payment settled with " +
+					"invalid preimage")
+
 			default:
 				return errors.New("unknown failure reason")
 			}
@@ -965,7 +965,9 @@ func (*FeeLimit) XXX_OneofWrappers() []interface{} {
 type SendRequest struct {
 	/// The identity pubkey of the payment recipient
 	Dest []byte `protobuf:"bytes,1,opt,name=dest,proto3" json:"dest,omitempty"`
-	/// The hex-encoded identity pubkey of the payment recipient
+	//*
+	//The hex-encoded identity pubkey of the payment recipient. Alternatively,
+	//the alias or a pubkey prefix that uniquely identifies a node in the graph.
 	DestString string `protobuf:"bytes,2,opt,name=dest_string,json=destString,proto3" json:"dest_string,omitempty"`
 	/// Number of satoshis to send.
 	Amt int64 `protobuf:"varint,3,opt,name=amt,proto3" json:"amt,omitempty"`
@@ -102,6 +102,16 @@ type FeeEstimator interface {
 	RelayFeePerKW() SatPerKWeight
 }
 
+// MempoolFeeSource is implemented by fee estimators whose backend is able to
+// report the minimum fee rate that it currently requires for transactions to
+// be accepted into its mempool. When the mempool is full, this fee rate rises
+// above the relay fee.
+type MempoolFeeSource interface {
+	// MempoolMinFeePerKW returns the minimum fee rate that transactions
+	// currently need to pay to be accepted into the backend's mempool.
+	MempoolMinFeePerKW() (SatPerKWeight, error)
+}
+
 // StaticFeeEstimator will return a static value for all fee calculation
 // requests. It is designed to be replaced by a proper fee calculation
 // implementation. The fees are not accessible directly, because changing them
@@ -439,6 +449,38 @@ func (b *BitcoindFeeEstimator) RelayFeePerKW() SatPerKWeight {
 	return b.minFeePerKW
 }
 
+// MempoolMinFeePerKW returns the minimum fee rate that transactions currently
+// need to pay to be accepted into the mempool of the bitcoind node. It is never
+// lower than the minimum relay fee.
+//
+// NOTE: This method is part of the MempoolFeeSource interface.
+func (b *BitcoindFeeEstimator) MempoolMinFeePerKW() (SatPerKWeight, error) {
+	resp, err := b.bitcoindConn.RawRequest("getmempoolinfo", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Parse the response to retrieve the mempool minimum fee in sat/KB.
+	info := struct {
+		MempoolMinFee float64 `json:"mempoolminfee"`
+	}{}
+	if err := json.Unmarshal(resp, &info); err != nil {
+		return 0, err
+	}
+
+	mempoolMinFee, err := btcutil.NewAmount(info.MempoolMinFee)
+	if err != nil {
+		return 0, err
+	}
+
+	minFeePerKW := SatPerKVByte(mempoolMinFee).FeePerKWeight()
+	if minFeePerKW < b.minFeePerKW {
+		minFeePerKW = b.minFeePerKW
+	}
+
+	return minFeePerKW, nil
+}
+
 // fetchEstimate returns a fee estimate for a transaction to be confirmed in
 // confTarget blocks. The estimate is returned in sat/kw.
 func (b *BitcoindFeeEstimator) fetchEstimate(confTarget uint32) (SatPerKWeight, error) {
@@ -495,6 +537,10 @@ func (b *BitcoindFeeEstimator) fetchEstimate(confTarget uint32) (SatPerKWeight,
 // FeeEstimator interface.
 var _ FeeEstimator = (*BitcoindFeeEstimator)(nil)
 
+// A compile-time assertion to ensure that BitcoindFeeEstimator implements the
+// MempoolFeeSource interface.
+var _ MempoolFeeSource = (*BitcoindFeeEstimator)(nil)
+
 // WebAPIFeeSource is an interface allows the WebAPIFeeEstimator to query an
 // arbitrary HTTP-based fee estimator. Each new set/network will gain an
 // implementation of this interface in order to allow the WebAPIFeeEstimator to
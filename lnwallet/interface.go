@@ -296,6 +296,17 @@ type MessageSigner interface {
 	SignMessage(pubKey *btcec.PublicKey, msg []byte) (*btcec.Signature, error)
 }
 
+// BatchMessageSigner represents an abstract object capable of signing multiple
+// messages under the same key in a single request. It is intended for external
+// signers, for which every request incurs a round trip.
+type BatchMessageSigner interface {
+	// SignMessages signs the double SHA-256 of each of the passed messages
+	// with the private key that corresponds to the passed public key. The
+	// returned signatures are in the same order as the messages.
+	SignMessages(pubKey *btcec.PublicKey,
+		msgs [][]byte) ([]*btcec.Signature, error)
+}
+
 // WalletDriver represents a "driver" for a particular concrete
 // WalletController implementation. A driver is identified by a globally unique
 // string identifier along with a 'New()' method which is responsible for
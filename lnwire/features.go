@@ -46,6 +46,42 @@ const (
 	// efficient network view reconciliation.
 	GossipQueriesOptional FeatureBit = 7
 
+	// TLVOnionPayloadRequired is a feature bit that indicates that the
+	// recipient requires the variable length onion payload format.
+	TLVOnionPayloadRequired FeatureBit = 8
+
+	// TLVOnionPayloadOptional is an optional feature bit that indicates
+	// that the recipient supports the variable length onion payload
+	// format.
+	TLVOnionPayloadOptional FeatureBit = 9
+
+	// PaymentAddrRequired is a feature bit that indicates that the
+	// recipient requires the payment secret of an invoice to be included
+	// in the final hop payload.
+	PaymentAddrRequired FeatureBit = 14
+
+	// PaymentAddrOptional is an optional feature bit that indicates that
+	// the recipient supports payment secrets.
+	PaymentAddrOptional FeatureBit = 15
+
+	// MPPRequired is a feature bit that indicates that the recipient
+	// requires the payment to be split into multiple parts.
+	MPPRequired FeatureBit = 16
+
+	// MPPOptional is an optional feature bit that indicates that the
+	// recipient accepts payments that are split into multiple parts.
+	MPPOptional FeatureBit = 17
+
+	// WumboChannelsRequired is a feature bit that indicates that the
+	// node requires channels larger than the historical maximum channel
+	// size.
+	WumboChannelsRequired FeatureBit = 18
+
+	// WumboChannelsOptional is an optional feature bit that indicates
+	// that the node supports channels larger than the historical maximum
+	// channel size.
+	WumboChannelsOptional FeatureBit = 19
+
 	// maxAllowedSize is a maximum allowed size of feature vector.
 	//
 	// NOTE: Within the protocol, the maximum allowed message size is 65535
@@ -72,6 +108,20 @@ var LocalFeatures = map[FeatureBit]string{
 	GossipQueriesOptional:   "gossip-queries",
 }
 
+// InvoiceFeatures is a mapping of known invoice feature bits to a descriptive
+// name. Invoice features are those which are signaled by the recipient of a
+// payment in its BOLT-11 invoice.
+var InvoiceFeatures = map[FeatureBit]string{
+	TLVOnionPayloadRequired: "tlv-onion",
+	TLVOnionPayloadOptional: "tlv-onion",
+	PaymentAddrRequired:     "payment-addr",
+	PaymentAddrOptional:     "payment-addr",
+	MPPRequired:             "multi-path-payments",
+	MPPOptional:             "multi-path-payments",
+	WumboChannelsRequired:   "wumbo-channels",
+	WumboChannelsOptional:   "wumbo-channels",
+}
+
 // GlobalFeatures is a mapping of known global feature bits to a descriptive
 // name. All known global feature bits must be assigned a name in this mapping.
 // Global features are those which are advertised to the entire network. A full
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/lightningnetwork/lnd/monitoring"
+	"github.com/lightningnetwork/lnd/routing"
+)
+
+// registerMissionControlMetrics exports the penalty statistics of mission
+// control as Prometheus metrics. They allow operators to see when mission
+// control has penalized large parts of the graph and needs to be reset.
+func registerMissionControlMetrics(mc *routing.MissionControl) error {
+	const subsystem = "missioncontrol"
+
+	gauges := []struct {
+		name  string
+		help  string
+		value func(*routing.MissionControlPenaltyStats) float64
+	}{
+		{
+			name: "penalized_nodes",
+			help: "Number of nodes with an active node level penalty",
+			value: func(s *routing.MissionControlPenaltyStats) float64 {
+				return float64(s.PenalizedNodes)
+			},
+		},
+		{
+			name: "penalized_pairs",
+			help: "Number of node and channel pairs with an " +
+				"active channel level penalty",
+			value: func(s *routing.MissionControlPenaltyStats) float64 {
+				return float64(s.PenalizedPairs)
+			},
+		},
+		{
+			name: "average_penalty_age_seconds",
+			help: "Average age of the active penalties",
+			value: func(s *routing.MissionControlPenaltyStats) float64 {
+				return s.AveragePenaltyAge.Seconds()
+			},
+		},
+	}
+
+	for _, g := range gauges {
+		value := g.value
+		err := monitoring.RegisterGaugeFunc(
+			subsystem, g.name, g.help, func() float64 {
+				return value(mc.PenaltyStats())
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return monitoring.RegisterCounterFunc(
+		subsystem, "penalties_applied_total",
+		"Total number of penalties applied by mission control",
+		func() float64 {
+			return float64(mc.PenaltyStats().PenaltiesApplied)
+		},
+	)
+}
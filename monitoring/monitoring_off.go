@@ -23,3 +23,13 @@ func ExportPrometheusMetrics(_ *grpc.Server, _ lncfg.Prometheus) error {
 	return fmt.Errorf("lnd must be built with the monitoring tag to " +
 		"enable exporting Prometheus metrics")
 }
+
+// RegisterGaugeFunc is a no-op, as monitoring is currently disabled.
+func RegisterGaugeFunc(_, _, _ string, _ func() float64) error {
+	return nil
+}
+
+// RegisterCounterFunc is a no-op, as monitoring is currently disabled.
+func RegisterCounterFunc(_, _, _ string, _ func() float64) error {
+	return nil
+}
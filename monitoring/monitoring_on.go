@@ -10,6 +10,7 @@ import (
 
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/lightningnetwork/lnd/lncfg"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -43,3 +44,34 @@ func ExportPrometheusMetrics(grpcServer *grpc.Server, cfg lncfg.Prometheus) erro
 
 	return nil
 }
+
+// RegisterGaugeFunc registers a gauge in the lnd namespace whose value is
+// obtained by calling valueFunc whenever the metrics are collected.
+func RegisterGaugeFunc(subsystem, name, help string,
+	valueFunc func() float64) error {
+
+	return prometheus.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: "lnd",
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+		}, valueFunc,
+	))
+}
+
+// RegisterCounterFunc registers a counter in the lnd namespace whose value is
+// obtained by calling valueFunc whenever the metrics are collected. The value
+// must never decrease.
+func RegisterCounterFunc(subsystem, name, help string,
+	valueFunc func() float64) error {
+
+	return prometheus.Register(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Namespace: "lnd",
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+		}, valueFunc,
+	))
+}
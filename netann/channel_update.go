@@ -75,6 +75,60 @@ func SignChannelUpdate(signer lnwallet.MessageSigner, pubKey *btcec.PublicKey,
 	return nil
 }
 
+// DefaultSignBatchSize is the default maximum number of channel updates that
+// are signed in a single request to a BatchMessageSigner.
+const DefaultSignBatchSize = 50
+
+// SignChannelUpdateBatch signs the given channel updates with the batch
+// signer, requesting at most batchSize signatures at a time. A batchSize of
+// zero selects DefaultSignBatchSize. Unlike SignChannelUpdate, the updates
+// aren't modified other than setting their signatures.
+func SignChannelUpdateBatch(signer lnwallet.BatchMessageSigner,
+	pubKey *btcec.PublicKey, updates []*lnwire.ChannelUpdate,
+	batchSize int) error {
+
+	if batchSize <= 0 {
+		batchSize = DefaultSignBatchSize
+	}
+
+	for start := 0; start < len(updates); start += batchSize {
+		end := start + batchSize
+		if end > len(updates) {
+			end = len(updates)
+		}
+		batch := updates[start:end]
+
+		msgs := make([][]byte, 0, len(batch))
+		for _, update := range batch {
+			msg, err := update.DataToSign()
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, msg)
+		}
+
+		sigs, err := signer.SignMessages(pubKey, msgs)
+		if err != nil {
+			return err
+		}
+		if len(sigs) != len(batch) {
+			return fmt.Errorf("expected %v signatures, got %v",
+				len(batch), len(sigs))
+		}
+
+		for i, update := range batch {
+			update.Signature, err = lnwire.NewSigFromSignature(
+				sigs[i],
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // ExtractChannelUpdate attempts to retrieve a lnwire.ChannelUpdate message from
 // an edge's info and a set of routing policies.
 //
@@ -187,3 +187,62 @@ func TestUpdateDisableFlag(t *testing.T) {
 		})
 	}
 }
+
+// countingBatchSigner wraps a NodeSigner and records the size of each batch
+// it is asked to sign.
+type countingBatchSigner struct {
+	*netann.NodeSigner
+
+	batches []int
+}
+
+func (c *countingBatchSigner) SignMessages(pk *btcec.PublicKey,
+	msgs [][]byte) ([]*btcec.Signature, error) {
+
+	c.batches = append(c.batches, len(msgs))
+	return c.NodeSigner.SignMessages(pk, msgs)
+}
+
+var _ lnwallet.BatchMessageSigner = (*countingBatchSigner)(nil)
+
+// TestSignChannelUpdateBatch asserts that channel updates are signed in
+// batches of the requested size, and that all signatures are valid.
+func TestSignChannelUpdateBatch(t *testing.T) {
+	t.Parallel()
+
+	var updates []*lnwire.ChannelUpdate
+	for i := 0; i < 5; i++ {
+		updates = append(updates, &lnwire.ChannelUpdate{
+			ShortChannelID: lnwire.NewShortChanIDFromInt(uint64(i)),
+			Timestamp:      uint32(time.Now().Unix()),
+		})
+	}
+
+	signer := &countingBatchSigner{
+		NodeSigner: netann.NewNodeSigner(privKey),
+	}
+	err := netann.SignChannelUpdateBatch(signer, pubKey, updates, 2)
+	if err != nil {
+		t.Fatalf("unable to sign updates: %v", err)
+	}
+
+	expectedBatches := []int{2, 2, 1}
+	if len(signer.batches) != len(expectedBatches) {
+		t.Fatalf("expected batches %v, got %v", expectedBatches,
+			signer.batches)
+	}
+	for i, size := range expectedBatches {
+		if signer.batches[i] != size {
+			t.Fatalf("expected batches %v, got %v",
+				expectedBatches, signer.batches)
+		}
+	}
+
+	for _, update := range updates {
+		err := routing.ValidateChannelUpdateAnn(pubKey, 0, update)
+		if err != nil {
+			t.Fatalf("channel update %v failed to validate: %v",
+				update.ShortChannelID, err)
+		}
+	}
+}
@@ -49,6 +49,23 @@ func (n *NodeSigner) SignMessage(pubKey *btcec.PublicKey,
 	return sign, nil
 }
 
+// SignMessages signs a double-sha256 digest of each of the passed msgs under
+// the resident node's private key.
+func (n *NodeSigner) SignMessages(pubKey *btcec.PublicKey,
+	msgs [][]byte) ([]*btcec.Signature, error) {
+
+	sigs := make([]*btcec.Signature, 0, len(msgs))
+	for _, msg := range msgs {
+		sig, err := n.SignMessage(pubKey, msg)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+
+	return sigs, nil
+}
+
 // SignCompact signs a double-sha256 digest of the msg parameter under the
 // resident node's private key. The returned signature is a pubkey-recoverable
 // signature.
@@ -80,3 +97,7 @@ func (n *NodeSigner) SignDigestCompact(hash []byte) ([]byte, error) {
 // A compile time check to ensure that NodeSigner implements the MessageSigner
 // interface.
 var _ lnwallet.MessageSigner = (*NodeSigner)(nil)
+
+// A compile time check to ensure that NodeSigner implements the
+// BatchMessageSigner interface.
+var _ lnwallet.BatchMessageSigner = (*NodeSigner)(nil)
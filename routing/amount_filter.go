@@ -0,0 +1,167 @@
+package routing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+const (
+	// amountFilterTTL is the duration for which an amount filter is reused
+	// before it is rebuilt from the graph.
+	amountFilterTTL = 30 * time.Second
+
+	// amountFilterMinAmt is the minimum payment amount for which path
+	// finding uses an amount filter. Smaller payments can be carried by
+	// nearly all channels, so building a filter for them doesn't pay off.
+	amountFilterMinAmt = lnwire.MilliSatoshi(100000000)
+)
+
+// amountFilter is the set of channel directions of the graph that are unable
+// to carry any amount within an amount band, because either the capacity of
+// the channel or the max htlc of the direction is below the lower bound of the
+// band. As the amount that is forwarded over an edge only grows towards the
+// source of a route, these edges can be skipped by path finding without
+// evaluating them.
+type amountFilter struct {
+	// excluded maps the ids of the filtered channels to a bit mask of the
+	// excluded directions. The lowest bit denotes the direction from node
+	// 1 to node 2, the next bit the direction from node 2 to node 1.
+	excluded map[uint64]uint8
+
+	created time.Time
+}
+
+// bandFloor returns the lowest amount within the given amount band.
+func bandFloor(band uint8) lnwire.MilliSatoshi {
+	if band == 0 {
+		return 0
+	}
+
+	return lnwire.MilliSatoshi(1) << (band - 1)
+}
+
+// newAmountFilter builds the amount filter for the given amount band from the
+// channel graph.
+func newAmountFilter(graph *channeldb.ChannelGraph, band uint8,
+	now time.Time) (*amountFilter, error) {
+
+	minAmt := bandFloor(band)
+
+	tooSmall := func(policy *channeldb.ChannelEdgePolicy) bool {
+		return policy != nil && policy.MaxHTLC != 0 &&
+			policy.MaxHTLC < minAmt
+	}
+
+	filter := &amountFilter{
+		excluded: make(map[uint64]uint8),
+		created:  now,
+	}
+	err := graph.ForEachChannel(func(info *channeldb.ChannelEdgeInfo,
+		policy1, policy2 *channeldb.ChannelEdgePolicy) error {
+
+		// The capacity of a channel may be unknown when operating
+		// under a light client, in which case it isn't filtered.
+		capacity := lnwire.NewMSatFromSatoshis(info.Capacity)
+		if info.Capacity != 0 && capacity < minAmt {
+			filter.excluded[info.ChannelID] = 3
+			return nil
+		}
+
+		var mask uint8
+		if tooSmall(policy1) {
+			mask |= 1
+		}
+		if tooSmall(policy2) {
+			mask |= 2
+		}
+		if mask != 0 {
+			filter.excluded[info.ChannelID] = mask
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Built amount filter for amounts of at least %v, "+
+		"excluding %v channels", minAmt, len(filter.excluded))
+
+	return filter, nil
+}
+
+// excludes returns true if the given edge is unable to carry the amounts the
+// filter was built for. A nil filter doesn't exclude any edge.
+func (f *amountFilter) excludes(edge *channeldb.ChannelEdgePolicy) bool {
+	if f == nil {
+		return false
+	}
+
+	mask, ok := f.excluded[edge.ChannelID]
+	if !ok {
+		return false
+	}
+
+	direction := uint8(1)
+	if edge.ChannelFlags&lnwire.ChanUpdateDirection != 0 {
+		direction = 2
+	}
+
+	return mask&direction != 0
+}
+
+// amountFilterCache holds the amount filters per amount band, so that they
+// can be reused by subsequent payments for a short while.
+type amountFilterCache struct {
+	graph   *channeldb.ChannelGraph
+	filters map[uint8]*amountFilter
+
+	// now is expected to return the current time. It is supplied as an
+	// external function to enable deterministic unit tests.
+	now func() time.Time
+
+	sync.Mutex
+}
+
+// newAmountFilterCache returns an empty amount filter cache for the given
+// graph.
+func newAmountFilterCache(graph *channeldb.ChannelGraph) *amountFilterCache {
+	return &amountFilterCache{
+		graph:   graph,
+		filters: make(map[uint8]*amountFilter),
+		now:     time.Now,
+	}
+}
+
+// get returns the amount filter for payments of the given amount. The filter is
+// built if there is no recent filter for the amount band of the payment. Nil is
+// returned for small payments, or if the filter can't be built.
+func (c *amountFilterCache) get(amt lnwire.MilliSatoshi) *amountFilter {
+	if c == nil || amt < amountFilterMinAmt {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	now := c.now()
+	band := amountBand(amt)
+
+	filter, ok := c.filters[band]
+	if ok && now.Sub(filter.created) < amountFilterTTL {
+		return filter
+	}
+
+	filter, err := newAmountFilter(c.graph, band, now)
+	if err != nil {
+		log.Errorf("Unable to build amount filter: %v", err)
+		delete(c.filters, band)
+		return nil
+	}
+	c.filters[band] = filter
+
+	return filter
+}
@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// TestAmountFilter asserts that the amount filter excludes the channels that
+// are too small for the amount band, and that filters are cached briefly.
+func TestAmountFilter(t *testing.T) {
+	t.Parallel()
+
+	graph, err := parseTestGraph(basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer graph.cleanUp()
+
+	now := testTime
+	cache := newAmountFilterCache(graph.graph)
+	cache.now = func() time.Time {
+		return now
+	}
+
+	// Small payments aren't filtered.
+	if cache.get(lnwire.NewMSatFromSatoshis(1000)) != nil {
+		t.Fatalf("expected no filter for small payment")
+	}
+
+	amt := lnwire.NewMSatFromSatoshis(50000)
+	filter := cache.get(amt)
+	if filter == nil {
+		t.Fatalf("expected filter")
+	}
+
+	// The channel between roasbeef and satoshi has a capacity of 10k
+	// sats, so it is excluded in both directions. Larger channels aren't.
+	for _, flags := range []lnwire.ChanUpdateChanFlags{
+		0, lnwire.ChanUpdateDirection,
+	} {
+		small := &channeldb.ChannelEdgePolicy{
+			ChannelID:    2340213491,
+			ChannelFlags: flags,
+		}
+		if !filter.excludes(small) {
+			t.Fatalf("expected small channel to be excluded")
+		}
+
+		large := &channeldb.ChannelEdgePolicy{
+			ChannelID:    689530843,
+			ChannelFlags: flags,
+		}
+		if filter.excludes(large) {
+			t.Fatalf("expected large channel to be included")
+		}
+	}
+
+	// Within the same band and ttl, the filter is reused.
+	if cache.get(amt+1) != filter {
+		t.Fatalf("expected cached filter")
+	}
+
+	// Once the ttl has passed, the filter is rebuilt.
+	now = now.Add(amountFilterTTL)
+	if cache.get(amt) == filter {
+		t.Fatalf("expected filter to be rebuilt")
+	}
+}
@@ -0,0 +1,80 @@
+package routing
+
+import (
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// AnnotationSource provides the operator annotations of the channels and nodes
+// of the graph.
+type AnnotationSource interface {
+	// FetchAnnotations returns all annotations of channels and nodes.
+	FetchAnnotations() (*channeldb.AnnotationSet, error)
+}
+
+// tagFilter is the set of channels and nodes that carry at least one of the
+// tags that path finding is instructed to avoid.
+type tagFilter struct {
+	channels map[uint64]struct{}
+	nodes    map[route.Vertex]struct{}
+}
+
+// newTagFilter returns the filter of the channels and nodes of the given
+// annotations that carry one of the given tags. If there is nothing to avoid,
+// nil is returned.
+func newTagFilter(annotations *channeldb.AnnotationSet,
+	avoidTags []string) *tagFilter {
+
+	if annotations == nil || len(avoidTags) == 0 {
+		return nil
+	}
+
+	hasAvoidedTag := func(a *channeldb.Annotation) bool {
+		for _, tag := range avoidTags {
+			if a.HasTag(tag) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	filter := &tagFilter{
+		channels: make(map[uint64]struct{}),
+		nodes:    make(map[route.Vertex]struct{}),
+	}
+	for chanID, a := range annotations.Channels {
+		if hasAvoidedTag(a) {
+			filter.channels[chanID] = struct{}{}
+		}
+	}
+	for node, a := range annotations.Nodes {
+		if hasAvoidedTag(a) {
+			filter.nodes[node] = struct{}{}
+		}
+	}
+
+	return filter
+}
+
+// excludesChannel returns true if the given channel carries an avoided tag. A
+// nil filter doesn't exclude any channel.
+func (f *tagFilter) excludesChannel(chanID uint64) bool {
+	if f == nil {
+		return false
+	}
+
+	_, ok := f.channels[chanID]
+	return ok
+}
+
+// excludesNode returns true if the given node carries an avoided tag. A nil
+// filter doesn't exclude any node.
+func (f *tagFilter) excludesNode(node route.Vertex) bool {
+	if f == nil {
+		return false
+	}
+
+	_, ok := f.nodes[node]
+	return ok
+}
@@ -0,0 +1,160 @@
+package routing
+
+import (
+	"github.com/coreos/bbolt"
+	"github.com/go-errors/errors"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// BuildRoute constructs a route to the last of the given hops that delivers
+// amt to it. The route starts at our own node and visits the hops in the given
+// order. For every pair of consecutive nodes, the channel that is able to
+// carry the payment at the lowest fee is selected, based on the current
+// policies in the graph. If outgoingChan is set, the route leaves our node
+// over that channel. The fees and time locks of the route are calculated from
+// the policies of the selected channels.
+func (r *ChannelRouter) BuildRoute(amt lnwire.MilliSatoshi,
+	hops []route.Vertex, outgoingChan *uint64,
+	finalCltvDelta uint16) (*route.Route, error) {
+
+	if len(hops) == 0 {
+		return nil, route.ErrNoRouteHopsProvided
+	}
+
+	// Our own channels are checked against their actual bandwidth.
+	bandwidthHints, err := generateBandwidthHints(
+		r.selfNode, r.cfg.QueryBandwidth,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	source := route.Vertex(r.selfNode.PubKeyBytes)
+
+	// Select the channels walking backwards from the final hop, as the
+	// amount that is sent over a channel depends on the fees of all
+	// channels that follow it.
+	pathEdges := make([]*channeldb.ChannelEdgePolicy, len(hops))
+	amtToSend := amt
+	for i := len(hops) - 1; i >= 0; i-- {
+		fromNode := source
+		if i > 0 {
+			fromNode = hops[i-1]
+		}
+
+		var chanRestriction *uint64
+		if i == 0 {
+			chanRestriction = outgoingChan
+		}
+
+		edge, err := r.selectHopEdge(
+			fromNode, hops[i], amtToSend, chanRestriction,
+			bandwidthHints,
+		)
+		if err != nil {
+			return nil, err
+		}
+		pathEdges[i] = edge
+
+		// The node the channel originates from charges a fee for
+		// forwarding over it, unless it is our own node.
+		if i > 0 {
+			amtToSend += computeFee(amtToSend, edge)
+		}
+	}
+
+	_, currentHeight, err := r.cfg.Chain.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return newRoute(
+		amt, source, pathEdges, uint32(currentHeight), finalCltvDelta,
+	)
+}
+
+// selectHopEdge returns the policy of the channel from fromNode to toNode that
+// is able to carry amt at the lowest fee. If chanRestriction is set, only that
+// channel is considered.
+func (r *ChannelRouter) selectHopEdge(fromNode, toNode route.Vertex,
+	amt lnwire.MilliSatoshi, chanRestriction *uint64,
+	bandwidthHints map[uint64]lnwire.MilliSatoshi) (
+	*channeldb.ChannelEdgePolicy, error) {
+
+	node, err := r.FetchLightningNode(toNode)
+	if err != nil {
+		return nil, errors.Errorf("unable to fetch node %x: %v",
+			toNode[:], err)
+	}
+
+	isSourceChan := fromNode == route.Vertex(r.selfNode.PubKeyBytes)
+
+	var (
+		bestEdge *channeldb.ChannelEdgePolicy
+		bestFee  lnwire.MilliSatoshi
+	)
+	err = node.ForEachChannel(nil, func(_ *bbolt.Tx,
+		edgeInfo *channeldb.ChannelEdgeInfo,
+		_, inEdge *channeldb.ChannelEdgePolicy) error {
+
+		// The policy of the other node for the channel describes
+		// forwarding from it to toNode.
+		if inEdge == nil {
+			return nil
+		}
+		if edgeInfo.NodeKey1Bytes != fromNode &&
+			edgeInfo.NodeKey2Bytes != fromNode {
+
+			return nil
+		}
+
+		if chanRestriction != nil &&
+			*chanRestriction != edgeInfo.ChannelID {
+
+			return nil
+		}
+
+		// Our own disabled channels may still be usable, like in
+		// path finding.
+		isDisabled := inEdge.ChannelFlags&lnwire.ChanUpdateDisabled != 0
+		if !isSourceChan && isDisabled {
+			return nil
+		}
+
+		bandwidth, ok := bandwidthHints[edgeInfo.ChannelID]
+		if !ok {
+			bandwidth = inEdge.MaxHTLC
+			if bandwidth == 0 {
+				bandwidth = lnwire.NewMSatFromSatoshis(
+					edgeInfo.Capacity,
+				)
+			}
+		}
+		if bandwidth < amt || amt < inEdge.MinHTLC {
+			return nil
+		}
+		if inEdge.MaxHTLC != 0 && inEdge.MaxHTLC < amt {
+			return nil
+		}
+
+		fee := computeFee(amt, inEdge)
+		if bestEdge == nil || fee < bestFee {
+			bestEdge = inEdge
+			bestFee = fee
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if bestEdge == nil {
+		return nil, newErrf(ErrNoPathFound, "no channel from %x to %x "+
+			"can carry %v", fromNode[:], toNode[:], amt)
+	}
+
+	return bestEdge, nil
+}
@@ -0,0 +1,93 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// TestBuildRoute tests that a route built from a list of hops matches the
+// route found by path finding over the same hops, and that channel
+// restrictions are honored.
+func TestBuildRoute(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	// The cheapest path from roasbeef to sophon goes through songoku.
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+	restrictions := &RestrictParams{
+		FeeLimit:          lnwire.NewMSatFromSatoshis(10),
+		ProbabilitySource: noProbabilitySource,
+	}
+	expectedRoute, err := ctx.router.FindRoute(
+		ctx.router.selfNode.PubKeyBytes, ctx.aliases["sophon"],
+		paymentAmt, restrictions, zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to find route: %v", err)
+	}
+
+	hops := []route.Vertex{ctx.aliases["songoku"], ctx.aliases["sophon"]}
+	rt, err := ctx.router.BuildRoute(
+		paymentAmt, hops, nil, zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to build route: %v", err)
+	}
+	if !reflect.DeepEqual(rt, expectedRoute) {
+		t.Fatalf("expected route %v, got %v",
+			spew.Sdump(expectedRoute), spew.Sdump(rt))
+	}
+
+	// Restricting the outgoing channel to the one that is selected anyway
+	// results in the same route.
+	outgoingChan := rt.Hops[0].ChannelID
+	rt, err = ctx.router.BuildRoute(
+		paymentAmt, hops, &outgoingChan, zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to build route: %v", err)
+	}
+	if !reflect.DeepEqual(rt, expectedRoute) {
+		t.Fatalf("expected route %v, got %v",
+			spew.Sdump(expectedRoute), spew.Sdump(rt))
+	}
+
+	// A channel to another node can't be used to reach the first hop.
+	outgoingChan = 999991
+	_, err = ctx.router.BuildRoute(
+		paymentAmt, hops, &outgoingChan, zpay32.DefaultFinalCLTVDelta,
+	)
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
+	}
+
+	// Hops that don't share a channel can't be part of a route.
+	hops = []route.Vertex{ctx.aliases["songoku"], ctx.aliases["roasbeef"],
+		ctx.aliases["sophon"]}
+	_, err = ctx.router.BuildRoute(
+		paymentAmt, hops, nil, zpay32.DefaultFinalCLTVDelta,
+	)
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
+	}
+
+	_, err = ctx.router.BuildRoute(
+		paymentAmt, nil, nil, zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != route.ErrNoRouteHopsProvided {
+		t.Fatalf("expected ErrNoRouteHopsProvided, got %v", err)
+	}
+}
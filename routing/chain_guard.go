@@ -0,0 +1,135 @@
+package routing
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ChainMismatchStats contains the number of network messages and payments
+// that were rejected by the router because they belong to a different chain
+// than the one the router is configured for.
+type ChainMismatchStats struct {
+	// ChannelAnnouncements is the number of rejected channel
+	// announcements.
+	ChannelAnnouncements uint64
+
+	// ChannelUpdates is the number of rejected channel updates that were
+	// received as part of a payment failure.
+	ChannelUpdates uint64
+
+	// Payments is the number of rejected payments.
+	Payments uint64
+}
+
+// chainGuard rejects network messages and payments that belong to a different
+// chain than the one the router is configured for, and counts the number of
+// rejections.
+type chainGuard struct {
+	chanAnnMismatches    uint64 // To be used atomically.
+	chanUpdateMismatches uint64 // To be used atomically.
+	paymentMismatches    uint64 // To be used atomically.
+}
+
+// chainHash returns the genesis hash of the router's chain, or nil if the
+// router isn't configured with chain parameters.
+func (r *ChannelRouter) chainHash() *chainhash.Hash {
+	if r.cfg.ChainParams == nil {
+		return nil
+	}
+
+	return r.cfg.ChainParams.GenesisHash
+}
+
+// checkChannelAnnChain returns an ErrChainMismatch error if the given chain
+// hash of a channel announcement doesn't match the router's chain.
+func (r *ChannelRouter) checkChannelAnnChain(chanID uint64,
+	annChain chainhash.Hash) error {
+
+	chain := r.chainHash()
+	if chain == nil || annChain == *chain {
+		return nil
+	}
+
+	atomic.AddUint64(&r.chainGuard.chanAnnMismatches, 1)
+
+	return newErrf(ErrChainMismatch, "channel announcement for "+
+		"chan_id=%v is for chain %v, expected %v", chanID, annChain,
+		chain)
+}
+
+// checkChannelUpdateChain returns an ErrChainMismatch error if the given chain
+// hash of a channel update doesn't match the router's chain.
+func (r *ChannelRouter) checkChannelUpdateChain(chanID uint64,
+	updateChain chainhash.Hash) error {
+
+	chain := r.chainHash()
+	if chain == nil || updateChain == *chain {
+		return nil
+	}
+
+	atomic.AddUint64(&r.chainGuard.chanUpdateMismatches, 1)
+
+	return newErrf(ErrChainMismatch, "channel update for chan_id=%v is "+
+		"for chain %v, expected %v", chanID, updateChain, chain)
+}
+
+// checkPaymentChain returns an ErrChainMismatch error if the payment request
+// of the given payment is for a different chain than the router's.
+func (r *ChannelRouter) checkPaymentChain(payment *LightningPayment) error {
+	params := r.cfg.ChainParams
+	if params == nil || len(payment.PaymentRequest) == 0 {
+		return nil
+	}
+
+	if paymentRequestMatchesChain(
+		string(payment.PaymentRequest), params.Bech32HRPSegwit,
+	) {
+		return nil
+	}
+
+	atomic.AddUint64(&r.chainGuard.paymentMismatches, 1)
+
+	return newErrf(ErrChainMismatch, "payment request for payment %x is "+
+		"not for chain %v", payment.PaymentHash, params.Name)
+}
+
+// paymentRequestMatchesChain returns true if the human-readable part of the
+// given payment request designates the chain with the passed segwit HRP. The
+// human-readable part consists of "ln", the chain's HRP and an optional
+// amount, which always starts with a digit. This allows us to tell apart
+// chains whose HRPs share a prefix, such as "bc" and "bcrt".
+func paymentRequestMatchesChain(payReq, chainHRP string) bool {
+	payReq = strings.ToLower(payReq)
+
+	// The human-readable part is separated from the data part by the last
+	// occurrence of '1'.
+	sep := strings.LastIndex(payReq, "1")
+	if sep < 0 {
+		return false
+	}
+	hrp := payReq[:sep]
+
+	prefix := "ln" + chainHRP
+	if !strings.HasPrefix(hrp, prefix) {
+		return false
+	}
+
+	amount := hrp[len(prefix):]
+	return len(amount) == 0 || (amount[0] >= '0' && amount[0] <= '9')
+}
+
+// ChainMismatchStats returns the number of network messages and payments that
+// were rejected because they belong to a different chain.
+func (r *ChannelRouter) ChainMismatchStats() *ChainMismatchStats {
+	return &ChainMismatchStats{
+		ChannelAnnouncements: atomic.LoadUint64(
+			&r.chainGuard.chanAnnMismatches,
+		),
+		ChannelUpdates: atomic.LoadUint64(
+			&r.chainGuard.chanUpdateMismatches,
+		),
+		Payments: atomic.LoadUint64(&r.chainGuard.paymentMismatches),
+	}
+}
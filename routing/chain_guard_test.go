@@ -0,0 +1,88 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// TestPaymentRequestMatchesChain asserts that payment requests are only
+// matched with the chain designated by their human-readable part.
+func TestPaymentRequestMatchesChain(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		payReq   string
+		chainHRP string
+		match    bool
+	}{
+		{"lnbc1pvjluez", "bc", true},
+		{"lnbc2500u1pvjluez", "bc", true},
+		{"LNBC2500U1PVJLUEZ", "bc", true},
+		{"lntb20m1pvjluez", "bc", false},
+		{"lnbcrt1pvjluez", "bc", false},
+		{"lnbcrt500u1pvjluez", "bcrt", true},
+		{"lnbc500u1pvjluez", "bcrt", false},
+		{"lnbcpvjluez", "bc", false},
+	}
+
+	for _, test := range tests {
+		match := paymentRequestMatchesChain(test.payReq, test.chainHRP)
+		if match != test.match {
+			t.Fatalf("expected match=%v for %v on chain %v, got %v",
+				test.match, test.payReq, test.chainHRP, match)
+		}
+	}
+}
+
+// TestChainGuard asserts that announcements, channel updates and payments for
+// other chains are rejected with a typed error and counted.
+func TestChainGuard(t *testing.T) {
+	t.Parallel()
+
+	r := &ChannelRouter{
+		cfg: &Config{
+			ChainParams: &chaincfg.MainNetParams,
+		},
+	}
+
+	mainChain := *chaincfg.MainNetParams.GenesisHash
+	testChain := *chaincfg.TestNet3Params.GenesisHash
+
+	if err := r.checkChannelAnnChain(1, mainChain); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := r.checkChannelAnnChain(1, testChain)
+	if !IsError(err, ErrChainMismatch) {
+		t.Fatalf("expected ErrChainMismatch, got %v", err)
+	}
+
+	err = r.checkChannelUpdateChain(1, testChain)
+	if !IsError(err, ErrChainMismatch) {
+		t.Fatalf("expected ErrChainMismatch, got %v", err)
+	}
+
+	// Payments without a payment request can't be checked.
+	if err := r.checkPaymentChain(&LightningPayment{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = r.checkPaymentChain(&LightningPayment{
+		PaymentRequest: []byte("lntb20m1pvjluez"),
+	})
+	if !IsError(err, ErrChainMismatch) {
+		t.Fatalf("expected ErrChainMismatch, got %v", err)
+	}
+
+	stats := r.ChainMismatchStats()
+	if stats.ChannelAnnouncements != 1 || stats.ChannelUpdates != 1 ||
+		stats.Payments != 1 {
+
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	// Without chain parameters, no checks are performed.
+	r = &ChannelRouter{cfg: &Config{}}
+	if err := r.checkChannelAnnChain(1, testChain); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
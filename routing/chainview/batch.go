@@ -0,0 +1,188 @@
+package chainview
+
+import (
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+// DefaultFilterBatchWindow is the default duration over which filter updates
+// are coalesced before they are applied to the chain view.
+const DefaultFilterBatchWindow = 500 * time.Millisecond
+
+// BatchingChainView wraps a FilteredChainView and coalesces the filter updates
+// that are made within a short window into a single update. Outpoints that are
+// already part of the filter, or already pending, are skipped. This reduces
+// the number of calls to the chain backend during bursts of channel
+// announcements, as the router updates the filter for every new channel.
+type BatchingChainView struct {
+	FilteredChainView
+
+	window time.Duration
+
+	// pending holds the edge points that haven't been applied to the
+	// filter yet, and pendingHeight the lowest update height requested
+	// for them.
+	pending       []channeldb.EdgePoint
+	pendingHeight uint32
+
+	// filtered is the set of outpoints that have been handed to the
+	// wrapped chain view, or are pending.
+	filtered map[wire.OutPoint]struct{}
+
+	timer *time.Timer
+	mtx   sync.Mutex
+
+	// flushMtx serializes flushes, so that batches are applied to the
+	// wrapped chain view in order.
+	flushMtx sync.Mutex
+}
+
+// A compile time check to ensure BatchingChainView implements the
+// FilteredChainView interface.
+var _ FilteredChainView = (*BatchingChainView)(nil)
+
+// NewBatchingChainView returns a chain view that batches the filter updates
+// for the given chain view within the given window. A zero window applies
+// updates immediately, but still skips known outpoints.
+func NewBatchingChainView(view FilteredChainView,
+	window time.Duration) *BatchingChainView {
+
+	return &BatchingChainView{
+		FilteredChainView: view,
+		window:            window,
+		filtered:          make(map[wire.OutPoint]struct{}),
+	}
+}
+
+// UpdateFilter queues the given edge points to be added to the filter of the
+// wrapped chain view. The update is applied once the batch window expires,
+// with the lowest update height of the batch. Errors that occur while
+// applying a batch are logged, and the batch is retried.
+//
+// NOTE: This is part of the FilteredChainView interface.
+func (b *BatchingChainView) UpdateFilter(ops []channeldb.EdgePoint,
+	updateHeight uint32) error {
+
+	b.mtx.Lock()
+	b.queue(ops, updateHeight)
+
+	if b.window == 0 {
+		b.mtx.Unlock()
+		return b.flush()
+	}
+
+	if len(b.pending) > 0 && b.timer == nil {
+		b.timer = time.AfterFunc(b.window, func() {
+			if err := b.flush(); err != nil {
+				log.Errorf("Unable to update chain filter: %v",
+					err)
+			}
+		})
+	}
+	b.mtx.Unlock()
+
+	return nil
+}
+
+// queue adds the edge points that aren't known yet to the pending batch.
+//
+// NOTE: The caller must hold the mutex.
+func (b *BatchingChainView) queue(ops []channeldb.EdgePoint,
+	updateHeight uint32) {
+
+	for _, op := range ops {
+		if _, ok := b.filtered[op.OutPoint]; ok {
+			continue
+		}
+		b.filtered[op.OutPoint] = struct{}{}
+
+		if len(b.pending) == 0 || updateHeight < b.pendingHeight {
+			b.pendingHeight = updateHeight
+		}
+		b.pending = append(b.pending, op)
+	}
+}
+
+// flush applies the pending batch to the wrapped chain view. If that fails,
+// the batch is queued again and retried after the batch window.
+func (b *BatchingChainView) flush() error {
+	b.flushMtx.Lock()
+	defer b.flushMtx.Unlock()
+
+	b.mtx.Lock()
+	ops, height := b.pending, b.pendingHeight
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mtx.Unlock()
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	log.Debugf("Adding %v outpoints to chain filter at height %v",
+		len(ops), height)
+
+	err := b.FilteredChainView.UpdateFilter(ops, height)
+	if err == nil {
+		return nil
+	}
+
+	// Forget about the outpoints of the failed batch, so that they are
+	// queued again.
+	b.mtx.Lock()
+	for _, op := range ops {
+		delete(b.filtered, op.OutPoint)
+	}
+	b.mtx.Unlock()
+
+	if b.window == 0 {
+		return err
+	}
+
+	if retryErr := b.UpdateFilter(ops, height); retryErr != nil {
+		return retryErr
+	}
+
+	return err
+}
+
+// FilterBlock applies any pending filter updates, and then returns the result
+// of filtering the block with the given hash.
+//
+// NOTE: This is part of the FilteredChainView interface.
+func (b *BatchingChainView) FilterBlock(
+	blockHash *chainhash.Hash) (*FilteredBlock, error) {
+
+	if err := b.flush(); err != nil {
+		return nil, err
+	}
+
+	return b.FilteredChainView.FilterBlock(blockHash)
+}
+
+// Stop applies any pending filter updates, and stops the wrapped chain view.
+//
+// NOTE: This is part of the FilteredChainView interface.
+func (b *BatchingChainView) Stop() error {
+	if err := b.flush(); err != nil {
+		log.Errorf("Unable to update chain filter: %v", err)
+	}
+
+	// A failed flush schedules a retry, which is pointless once the
+	// wrapped chain view is stopped.
+	b.mtx.Lock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mtx.Unlock()
+
+	return b.FilteredChainView.Stop()
+}
@@ -0,0 +1,159 @@
+package chainview
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+// mockFilterUpdate is a filter update received by the mockChainView.
+type mockFilterUpdate struct {
+	ops    []channeldb.EdgePoint
+	height uint32
+}
+
+// mockChainView records the filter updates it receives.
+type mockChainView struct {
+	FilteredChainView
+
+	updates chan mockFilterUpdate
+
+	fail bool
+	sync.Mutex
+}
+
+func newMockChainView() *mockChainView {
+	return &mockChainView{
+		updates: make(chan mockFilterUpdate, 10),
+	}
+}
+
+func (m *mockChainView) UpdateFilter(ops []channeldb.EdgePoint,
+	updateHeight uint32) error {
+
+	m.Lock()
+	defer m.Unlock()
+
+	if m.fail {
+		m.fail = false
+		return errors.New("backend unavailable")
+	}
+
+	m.updates <- mockFilterUpdate{ops: ops, height: updateHeight}
+	return nil
+}
+
+func (m *mockChainView) FilterBlock(
+	blockHash *chainhash.Hash) (*FilteredBlock, error) {
+
+	return &FilteredBlock{Hash: *blockHash}, nil
+}
+
+// expectUpdate asserts that the next filter update contains the outpoints
+// with the given indexes at the given height.
+func (m *mockChainView) expectUpdate(t *testing.T, height uint32,
+	indexes ...uint32) {
+
+	t.Helper()
+
+	select {
+	case update := <-m.updates:
+		if update.height != height {
+			t.Fatalf("expected height %v, got %v", height,
+				update.height)
+		}
+		if len(update.ops) != len(indexes) {
+			t.Fatalf("expected %v outpoints, got %v",
+				len(indexes), len(update.ops))
+		}
+		for i, op := range update.ops {
+			if op.OutPoint.Index != indexes[i] {
+				t.Fatalf("expected outpoint %v, got %v",
+					indexes[i], op.OutPoint.Index)
+			}
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no filter update received")
+	}
+}
+
+// expectNoUpdate asserts that no filter update is received.
+func (m *mockChainView) expectNoUpdate(t *testing.T) {
+	t.Helper()
+
+	select {
+	case update := <-m.updates:
+		t.Fatalf("unexpected filter update %v", update)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func edgePoints(indexes ...uint32) []channeldb.EdgePoint {
+	ops := make([]channeldb.EdgePoint, 0, len(indexes))
+	for _, i := range indexes {
+		ops = append(ops, channeldb.EdgePoint{
+			OutPoint: wire.OutPoint{Index: i},
+		})
+	}
+	return ops
+}
+
+// TestBatchingChainView asserts that filter updates are coalesced within the
+// batch window, and that known outpoints are skipped.
+func TestBatchingChainView(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockChainView()
+	view := NewBatchingChainView(mock, 100*time.Millisecond)
+
+	// Updates within the window are coalesced into a single update at the
+	// lowest height, without duplicates.
+	for _, update := range []mockFilterUpdate{
+		{ops: edgePoints(1, 2), height: 110},
+		{ops: edgePoints(2, 3), height: 100},
+		{ops: edgePoints(1), height: 120},
+	} {
+		if err := view.UpdateFilter(update.ops, update.height); err != nil {
+			t.Fatalf("unable to update filter: %v", err)
+		}
+	}
+	mock.expectUpdate(t, 100, 1, 2, 3)
+
+	// Outpoints that are already part of the filter are not added again.
+	if err := view.UpdateFilter(edgePoints(2, 3), 130); err != nil {
+		t.Fatalf("unable to update filter: %v", err)
+	}
+	mock.expectNoUpdate(t)
+
+	// Filtering a block applies the pending updates first.
+	if err := view.UpdateFilter(edgePoints(4), 140); err != nil {
+		t.Fatalf("unable to update filter: %v", err)
+	}
+	if _, err := view.FilterBlock(&chainhash.Hash{}); err != nil {
+		t.Fatalf("unable to filter block: %v", err)
+	}
+	select {
+	case update := <-mock.updates:
+		if len(update.ops) != 1 || update.ops[0].OutPoint.Index != 4 {
+			t.Fatalf("unexpected filter update %v", update)
+		}
+	default:
+		t.Fatalf("expected update to be applied before filtering")
+	}
+
+	// A failed update is retried.
+	mock.Lock()
+	mock.fail = true
+	mock.Unlock()
+
+	if err := view.UpdateFilter(edgePoints(5), 150); err != nil {
+		t.Fatalf("unable to update filter: %v", err)
+	}
+	mock.expectUpdate(t, 150, 5)
+}
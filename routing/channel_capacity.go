@@ -0,0 +1,113 @@
+package routing
+
+import (
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/go-errors/errors"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// UpdateChannelCapacity replaces the funding outpoint and the capacity of an
+// existing channel, for instance after funds were spliced into or out of it.
+// Unlike a close followed by a new announcement, the channel keeps its channel
+// ID and policies, and topology clients are notified with a
+// ChannelCapacityChange.
+//
+// Unless the router is configured to assume channels valid, the new funding
+// output must be unspent, locked to the keys of the channel and hold the given
+// capacity.
+func (r *ChannelRouter) UpdateChannelCapacity(chanID lnwire.ShortChannelID,
+	chanPoint wire.OutPoint, capacity btcutil.Amount) error {
+
+	id := chanID.ToUint64()
+
+	// We make sure to hold the mutex for this channel ID, such that no
+	// other goroutine is concurrently doing database accesses for the
+	// same channel ID.
+	r.channelEdgeMtx.Lock(id)
+	defer r.channelEdgeMtx.Unlock(id)
+
+	info, _, _, err := r.cfg.Graph.FetchChannelEdgesByID(id)
+	if err != nil {
+		return err
+	}
+
+	// The funding output of the channel is still locked to the same
+	// multisig script, only its value and location changed.
+	witnessScript, err := input.GenMultiSigScript(
+		info.BitcoinKey1Bytes[:], info.BitcoinKey2Bytes[:],
+	)
+	if err != nil {
+		return err
+	}
+	fundingPkScript, err := input.WitnessScriptHash(witnessScript)
+	if err != nil {
+		return err
+	}
+
+	if !r.cfg.AssumeChannelValid {
+		chanUtxo, err := r.cfg.Chain.GetUtxo(
+			&chanPoint, fundingPkScript, chanID.BlockHeight,
+			r.quit,
+		)
+		if err != nil {
+			return errors.Errorf("unable to fetch utxo for "+
+				"chan_id=%v, chan_point=%v: %v", id,
+				chanPoint, err)
+		}
+
+		if btcutil.Amount(chanUtxo.Value) != capacity {
+			return errors.Errorf("capacity mismatch: expected %v, "+
+				"got %v", capacity,
+				btcutil.Amount(chanUtxo.Value))
+		}
+	}
+
+	var prevInfo *channeldb.ChannelEdgeInfo
+	err = r.graphWrites.write("update channel capacity", func() error {
+		prevInfo, err = r.cfg.Graph.UpdateChannelCapacity(
+			id, chanPoint, capacity,
+		)
+		return err
+	})
+	if err != nil {
+		return errors.Errorf("unable to update capacity: %v", err)
+	}
+
+	log.Infof("Capacity of chan_id=%v changed from %v to %v, "+
+		"ChannelPoint(%v) replaced by ChannelPoint(%v)", id,
+		prevInfo.Capacity, capacity, prevInfo.ChannelPoint, chanPoint)
+
+	// We'll need to be notified when the new funding output is spent, in
+	// order to detect the close of the channel.
+	filterUpdate := []channeldb.EdgePoint{
+		{
+			FundingPkScript: fundingPkScript,
+			OutPoint:        chanPoint,
+		},
+	}
+	err = r.cfg.ChainView.UpdateFilter(
+		filterUpdate, atomic.LoadUint32(&r.bestHeight),
+	)
+	if err != nil {
+		return errors.Errorf("unable to update chain view: %v", err)
+	}
+
+	r.notifyTopologyChange(&TopologyChange{
+		ChannelCapacityChanges: []*ChannelCapacityChange{
+			{
+				ChanID:        id,
+				PrevChanPoint: prevInfo.ChannelPoint,
+				ChanPoint:     chanPoint,
+				PrevCapacity:  prevInfo.Capacity,
+				Capacity:      capacity,
+			},
+		},
+	})
+
+	return nil
+}
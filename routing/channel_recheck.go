@@ -0,0 +1,233 @@
+package routing
+
+import (
+	"bytes"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/go-errors/errors"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/eventbus"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// ChannelRecheckResult describes how the graph was corrected by
+// RecheckChannel.
+type ChannelRecheckResult uint8
+
+const (
+	// ChannelRecheckValid indicates that the channel is part of the graph
+	// and that its funding output matches the graph.
+	ChannelRecheckValid ChannelRecheckResult = iota
+
+	// ChannelRecheckCapacityCorrected indicates that the channel is part
+	// of the graph, but its capacity didn't match the value of its funding
+	// output and was corrected.
+	ChannelRecheckCapacityCorrected
+
+	// ChannelRecheckPruned indicates that the funding output of the
+	// channel is spent or invalid, so the channel was removed from the
+	// graph.
+	ChannelRecheckPruned
+
+	// ChannelRecheckResurrected indicates that the channel was marked as a
+	// zombie, but its funding output is still unspent. The channel was
+	// removed from the zombie index, so that it is added to the graph
+	// again once it is announced.
+	ChannelRecheckResurrected
+
+	// ChannelRecheckZombie indicates that the channel is marked as a
+	// zombie and that its funding output is spent or invalid, so it
+	// remains a zombie.
+	ChannelRecheckZombie
+)
+
+// String returns a human readable representation of the result.
+func (c ChannelRecheckResult) String() string {
+	switch c {
+	case ChannelRecheckValid:
+		return "valid"
+
+	case ChannelRecheckCapacityCorrected:
+		return "capacity_corrected"
+
+	case ChannelRecheckPruned:
+		return "pruned"
+
+	case ChannelRecheckResurrected:
+		return "resurrected"
+
+	case ChannelRecheckZombie:
+		return "zombie"
+
+	default:
+		return "unknown"
+	}
+}
+
+// RecheckChannel validates a single channel against the chain and corrects
+// the graph accordingly. It is meant for support and debugging, when the
+// graph seems to be wrong about a channel.
+//
+// The funding output of the channel is fetched from the block and position
+// encoded in its channel ID, unless the channel was moved to another funding
+// output since. If that output is spent, or isn't locked to the keys of the
+// channel, the channel is pruned. If its value doesn't match the capacity of
+// the channel, the capacity is corrected. A channel that is marked as a zombie
+// is resurrected if its funding output is unspent.
+func (r *ChannelRouter) RecheckChannel(
+	chanID lnwire.ShortChannelID) (ChannelRecheckResult, error) {
+
+	if r.cfg.AssumeChannelValid {
+		return 0, errors.New("channels can't be rechecked if they " +
+			"are assumed to be valid")
+	}
+
+	id := chanID.ToUint64()
+
+	// We make sure to hold the mutex for this channel ID, such that no
+	// other goroutine is concurrently doing database accesses for the
+	// same channel ID.
+	r.channelEdgeMtx.Lock(id)
+	defer r.channelEdgeMtx.Unlock(id)
+
+	info, _, _, err := r.cfg.Graph.FetchChannelEdgesByID(id)
+	switch {
+	case err == channeldb.ErrZombieEdge:
+		return r.recheckZombieChannel(chanID)
+
+	case err != nil:
+		return 0, err
+	}
+
+	witnessScript, err := input.GenMultiSigScript(
+		info.BitcoinKey1Bytes[:], info.BitcoinKey2Bytes[:],
+	)
+	if err != nil {
+		return 0, err
+	}
+	fundingPkScript, err := input.WitnessScriptHash(witnessScript)
+	if err != nil {
+		return 0, err
+	}
+
+	// If the channel still uses the funding output encoded in its channel
+	// ID, that output must be locked to the keys of the channel.
+	chanPoint, txOut, err := r.fetchChanPoint(&chanID)
+	if err != nil {
+		return 0, errors.Errorf("unable to fetch chan point for "+
+			"chan_id=%v: %v", id, err)
+	}
+	valid := *chanPoint != info.ChannelPoint ||
+		bytes.Equal(txOut.PkScript, fundingPkScript)
+
+	// The current funding output must be unspent.
+	var capacity btcutil.Amount
+	if valid {
+		chanUtxo, err := r.cfg.Chain.GetUtxo(
+			&info.ChannelPoint, fundingPkScript,
+			chanID.BlockHeight, r.quit,
+		)
+		if err != nil {
+			log.Debugf("Funding output of chan_id=%v not "+
+				"available: %v", id, err)
+
+			valid = false
+		} else {
+			capacity = btcutil.Amount(chanUtxo.Value)
+		}
+	}
+
+	if !valid {
+		log.Infof("Pruning chan_id=%v, ChannelPoint(%v) after recheck",
+			id, info.ChannelPoint)
+
+		err := r.graphWrites.write("delete edge", func() error {
+			return r.cfg.Graph.DeleteChannelEdges(id)
+		})
+		if err != nil {
+			return 0, errors.Errorf("unable to delete edge: %v", err)
+		}
+
+		r.publishPrunedChannels(eventbus.PruneReasonClosed, info)
+		r.notifyTopologyChange(&TopologyChange{
+			ClosedChannels: createCloseSummaries(
+				atomic.LoadUint32(&r.bestHeight), info,
+			),
+		})
+
+		return ChannelRecheckPruned, nil
+	}
+
+	if capacity == info.Capacity {
+		return ChannelRecheckValid, nil
+	}
+
+	log.Infof("Correcting capacity of chan_id=%v from %v to %v after "+
+		"recheck", id, info.Capacity, capacity)
+
+	err = r.graphWrites.write("update channel capacity", func() error {
+		_, err := r.cfg.Graph.UpdateChannelCapacity(
+			id, info.ChannelPoint, capacity,
+		)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Errorf("unable to update capacity: %v", err)
+	}
+
+	r.notifyTopologyChange(&TopologyChange{
+		ChannelCapacityChanges: []*ChannelCapacityChange{
+			{
+				ChanID:        id,
+				PrevChanPoint: info.ChannelPoint,
+				ChanPoint:     info.ChannelPoint,
+				PrevCapacity:  info.Capacity,
+				Capacity:      capacity,
+			},
+		},
+	})
+
+	return ChannelRecheckCapacityCorrected, nil
+}
+
+// recheckZombieChannel resurrects the zombie channel if its funding output is
+// unspent. As the keys of a zombie channel aren't known anymore, the funding
+// output can only be checked to be a P2WSH output.
+func (r *ChannelRouter) recheckZombieChannel(
+	chanID lnwire.ShortChannelID) (ChannelRecheckResult, error) {
+
+	chanPoint, txOut, err := r.fetchChanPoint(&chanID)
+	if err != nil {
+		return 0, errors.Errorf("unable to fetch chan point for "+
+			"chan_id=%v: %v", chanID, err)
+	}
+
+	if !txscript.IsPayToWitnessScriptHash(txOut.PkScript) {
+		return ChannelRecheckZombie, nil
+	}
+
+	_, err = r.cfg.Chain.GetUtxo(
+		chanPoint, txOut.PkScript, chanID.BlockHeight, r.quit,
+	)
+	if err != nil {
+		log.Debugf("Funding output of zombie chan_id=%v not "+
+			"available: %v", chanID, err)
+
+		return ChannelRecheckZombie, nil
+	}
+
+	log.Infof("Resurrecting zombie chan_id=%v, ChannelPoint(%v) after "+
+		"recheck", chanID, chanPoint)
+
+	err = r.graphWrites.write("mark edge live", func() error {
+		return r.cfg.Graph.MarkEdgeLive(chanID.ToUint64())
+	})
+	if err != nil {
+		return 0, errors.Errorf("unable to mark edge live: %v", err)
+	}
+
+	return ChannelRecheckResurrected, nil
+}
@@ -0,0 +1,115 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+// TestRecheckChannel tests that rechecking a channel corrects its capacity,
+// prunes it once its funding output is spent and resurrects it if the funding
+// output turns out to be unspent after all.
+func TestRecheckChannel(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxSingleNode(startingBlockHeight)
+	defer cleanUp()
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+
+	const chanValue = 10000
+	fundingTx, chanUtxo, chanID, err := createChannelEdge(ctx,
+		bitcoinKey1.SerializeCompressed(), bitcoinKey2.SerializeCompressed(),
+		chanValue, startingBlockHeight)
+	if err != nil {
+		t.Fatalf("unable create channel edge: %v", err)
+	}
+	fundingBlock := &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{fundingTx},
+	}
+	ctx.chain.addBlock(fundingBlock, chanID.BlockHeight, chanID.BlockHeight)
+
+	node1, err := createTestNode()
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+	node2, err := createTestNode()
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+
+	edge := &channeldb.ChannelEdgeInfo{
+		ChannelID:     chanID.ToUint64(),
+		NodeKey1Bytes: node1.PubKeyBytes,
+		NodeKey2Bytes: node2.PubKeyBytes,
+		AuthProof: &channeldb.ChannelAuthProof{
+			NodeSig1Bytes:    testSig.Serialize(),
+			NodeSig2Bytes:    testSig.Serialize(),
+			BitcoinSig1Bytes: testSig.Serialize(),
+			BitcoinSig2Bytes: testSig.Serialize(),
+		},
+	}
+	copy(edge.BitcoinKey1Bytes[:], bitcoinKey1.SerializeCompressed())
+	copy(edge.BitcoinKey2Bytes[:], bitcoinKey2.SerializeCompressed())
+	if err := ctx.router.AddEdge(edge); err != nil {
+		t.Fatalf("unable to add edge: %v", err)
+	}
+
+	assertRecheck := func(expected ChannelRecheckResult) {
+		t.Helper()
+
+		result, err := ctx.router.RecheckChannel(*chanID)
+		if err != nil {
+			t.Fatalf("unable to recheck channel: %v", err)
+		}
+		if result != expected {
+			t.Fatalf("expected result %v, got %v", expected, result)
+		}
+	}
+
+	assertRecheck(ChannelRecheckValid)
+
+	// Change the value of the funding output, as if the graph had stored
+	// the wrong capacity. The recheck corrects it.
+	const newChanValue = 20000
+	fundingOut := *fundingTx.TxOut[0]
+	fundingOut.Value = newChanValue
+	ctx.chain.addUtxo(*chanUtxo, &fundingOut)
+
+	assertRecheck(ChannelRecheckCapacityCorrected)
+
+	info, _, _, err := ctx.graph.FetchChannelEdgesByID(chanID.ToUint64())
+	if err != nil {
+		t.Fatalf("unable to fetch channel: %v", err)
+	}
+	if info.Capacity != newChanValue {
+		t.Fatalf("expected capacity %v, got %v", newChanValue,
+			info.Capacity)
+	}
+
+	// Once the funding output is spent, the channel is pruned and marked
+	// as a zombie.
+	ctx.chain.delUtxo(*chanUtxo)
+
+	assertRecheck(ChannelRecheckPruned)
+	assertRecheck(ChannelRecheckZombie)
+
+	_, _, _, err = ctx.graph.FetchChannelEdgesByID(chanID.ToUint64())
+	if err != channeldb.ErrZombieEdge {
+		t.Fatalf("expected ErrZombieEdge, got %v", err)
+	}
+
+	// If the funding output shows up as unspent again, the zombie is
+	// resurrected, so that the channel can be announced again.
+	ctx.chain.addUtxo(*chanUtxo, &fundingOut)
+
+	assertRecheck(ChannelRecheckResurrected)
+
+	_, _, _, err = ctx.graph.FetchChannelEdgesByID(chanID.ToUint64())
+	if err != channeldb.ErrEdgeNotFound {
+		t.Fatalf("expected ErrEdgeNotFound, got %v", err)
+	}
+}
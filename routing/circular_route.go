@@ -0,0 +1,185 @@
+package routing
+
+import (
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// scrubRouteHints removes the redundant segments of route hints that traverse
+// our own node. This is common for invoices that are paid to rebalance our own
+// channels. As every route starts at our own node, the hops of a hint up to
+// and including the last hop that starts at our own node are dropped. Our own
+// channels are known from the graph. Hints that are left empty are dropped as
+// well.
+func scrubRouteHints(routeHints [][]zpay32.HopHint,
+	self route.Vertex) [][]zpay32.HopHint {
+
+	scrubbed := make([][]zpay32.HopHint, 0, len(routeHints))
+	for _, routeHint := range routeHints {
+		start := 0
+		for i, hopHint := range routeHint {
+			if route.NewVertex(hopHint.NodeID) == self {
+				start = i + 1
+			}
+		}
+
+		if start > 0 {
+			log.Debugf("Dropping %v hops of route hint that "+
+				"traverse our own node", start)
+		}
+
+		if start == len(routeHint) {
+			continue
+		}
+		scrubbed = append(scrubbed, routeHint[start:])
+	}
+
+	return scrubbed
+}
+
+// findCircularPath finds a path for a payment to our own node. Such a path
+// must end with one of the hint edges that lead to our own node, as path
+// finding can't search for a path from our own node to itself. For each of
+// these edges, a path to the node at its start is searched for, and the
+// cheapest of the resulting paths is returned. If no hint edge leads to our
+// own node, a nil path is returned.
+func (p *paymentSession) findCircularPath(g *graphParams,
+	restrictions *RestrictParams,
+	amt lnwire.MilliSatoshi) ([]*channeldb.ChannelEdgePolicy, error) {
+
+	self := route.Vertex(p.mc.selfNode.PubKeyBytes)
+
+	var (
+		bestPath []*channeldb.ChannelEdgePolicy
+		bestFee  lnwire.MilliSatoshi
+		lastErr  error
+	)
+	for lastHop, edges := range p.additionalEdges {
+		if lastHop == self {
+			continue
+		}
+
+		for _, lastEdge := range edges {
+			if lastEdge.Node.PubKeyBytes != self {
+				continue
+			}
+
+			path, err := p.findCircularPathVia(
+				g, restrictions, lastHop, lastEdge, amt,
+			)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			fee := circularPathFee(path, amt)
+			if bestPath == nil || fee < bestFee {
+				bestPath = path
+				bestFee = fee
+			}
+		}
+	}
+
+	if bestPath == nil && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return bestPath, nil
+}
+
+// findCircularPathVia finds a path to our own node that ends with the given
+// hint edge from lastHop.
+func (p *paymentSession) findCircularPathVia(g *graphParams,
+	restrictions *RestrictParams, lastHop route.Vertex,
+	lastEdge *channeldb.ChannelEdgePolicy,
+	amt lnwire.MilliSatoshi) ([]*channeldb.ChannelEdgePolicy, error) {
+
+	// The last hop charges a fee for forwarding back to us, which is part
+	// of the fee limit of the payment.
+	fee := computeFee(amt, lastEdge)
+	if fee > restrictions.FeeLimit {
+		return nil, newErrf(ErrFeeLimitExceeded, "fee of last hop "+
+			"exceeds fee limit")
+	}
+
+	// The start of the hint edge is the last hop of the circular route.
+	if restrictions.LastHop != nil && *restrictions.LastHop != lastHop {
+		return nil, newErrf(ErrNoPathFound, "hint edge doesn't start "+
+			"at last hop")
+	}
+
+	// The hint edge must not be excluded by the ignore lists.
+	if hopIgnored(
+		restrictions.IgnoredNodes, restrictions.IgnoredPairs, lastHop,
+		p.mc.selfNode.PubKeyBytes, false,
+	) {
+
+		return nil, newErrf(ErrNoPathFound, "hint edge is ignored")
+	}
+
+	// The hint edge is one of the hops of the route.
+	if restrictions.MaxHops == 1 {
+		return nil, newErrf(ErrMaxHopsExceeded, "circular route "+
+			"exceeds max hops")
+	}
+
+	r := *restrictions
+	r.FeeLimit -= fee
+	r.LastHop = nil
+	if restrictions.MaxHops != 0 {
+		r.MaxHops--
+	}
+
+	if restrictions.CltvLimit != nil {
+		delta := uint32(lastEdge.TimeLockDelta)
+		if delta > *restrictions.CltvLimit {
+			return nil, newErrf(ErrNoPathFound, "cltv delta of "+
+				"last hop exceeds cltv limit")
+		}
+
+		limit := *restrictions.CltvLimit - delta
+		r.CltvLimit = &limit
+	}
+
+	// The channel of the last hop can't also be used to reach the last
+	// hop.
+	r.ProbabilitySource = func(node route.Vertex, edge EdgeLocator,
+		amt lnwire.MilliSatoshi, capacity btcutil.Amount) float64 {
+
+		if edge.ChannelID == lastEdge.ChannelID {
+			return 0
+		}
+		if restrictions.ProbabilitySource == nil {
+			return 1
+		}
+
+		return restrictions.ProbabilitySource(
+			node, edge, amt, capacity,
+		)
+	}
+
+	path, err := p.pathFinder(
+		g, &r, p.mc.selfNode.PubKeyBytes, lastHop, amt+fee,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(path, lastEdge), nil
+}
+
+// circularPathFee returns the total fee of sending amt along the path.
+func circularPathFee(path []*channeldb.ChannelEdgePolicy,
+	amt lnwire.MilliSatoshi) lnwire.MilliSatoshi {
+
+	// The first edge leaves our own node, which doesn't charge a fee.
+	total := amt
+	for i := len(path) - 1; i > 0; i-- {
+		total += computeFee(total, path[i])
+	}
+
+	return total - amt
+}
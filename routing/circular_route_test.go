@@ -0,0 +1,152 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// TestScrubRouteHints asserts that the hops of route hints up to our own node
+// are dropped.
+func TestScrubRouteHints(t *testing.T) {
+	t.Parallel()
+
+	var keys [3]*btcec.PublicKey
+	for i := range keys {
+		priv, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+		keys[i] = priv.PubKey()
+	}
+	self := route.NewVertex(keys[0])
+
+	routeHints := [][]zpay32.HopHint{
+		// A hint that doesn't traverse our node is kept.
+		{
+			{NodeID: keys[1], ChannelID: 1},
+		},
+
+		// A hint that traverses our node is collapsed to the part
+		// after it.
+		{
+			{NodeID: keys[1], ChannelID: 2},
+			{NodeID: keys[0], ChannelID: 3},
+			{NodeID: keys[2], ChannelID: 4},
+		},
+
+		// A hint that ends at our node is dropped.
+		{
+			{NodeID: keys[2], ChannelID: 5},
+			{NodeID: keys[0], ChannelID: 6},
+		},
+	}
+
+	scrubbed := scrubRouteHints(routeHints, self)
+	if len(scrubbed) != 2 {
+		t.Fatalf("expected 2 route hints, got %v", len(scrubbed))
+	}
+	if len(scrubbed[0]) != 1 || scrubbed[0][0].ChannelID != 1 {
+		t.Fatalf("expected first hint to be kept, got %v", scrubbed[0])
+	}
+	if len(scrubbed[1]) != 1 || scrubbed[1][0].ChannelID != 4 {
+		t.Fatalf("expected second hint to be collapsed, got %v",
+			scrubbed[1])
+	}
+}
+
+// TestRequestRouteCircular asserts that a payment to our own node is routed
+// through the hint edge that leads back to us.
+func TestRequestRouteCircular(t *testing.T) {
+	t.Parallel()
+
+	const (
+		height         = 10
+		finalCltvDelta = 8
+		amt            = lnwire.MilliSatoshi(100000)
+		hintChan       = 5
+	)
+
+	self := &channeldb.LightningNode{PubKeyBytes: route.Vertex{1}}
+	lastHop := route.Vertex{2}
+
+	hintEdge := &channeldb.ChannelEdgePolicy{
+		Node:          self,
+		ChannelID:     hintChan,
+		FeeBaseMSat:   1000,
+		TimeLockDelta: 40,
+	}
+
+	findPath := func(g *graphParams, r *RestrictParams,
+		source, target route.Vertex, pathAmt lnwire.MilliSatoshi) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		// Path finding must search for a path to the start of the
+		// hint edge, including its fee, without taking the hint
+		// channel.
+		if source != self.PubKeyBytes || target != lastHop {
+			t.Fatalf("unexpected path finding from %v to %v",
+				source, target)
+		}
+		if pathAmt != amt+hintEdge.FeeBaseMSat {
+			t.Fatalf("unexpected amount %v", pathAmt)
+		}
+		p := r.ProbabilitySource(
+			self.PubKeyBytes, EdgeLocator{ChannelID: hintChan},
+			pathAmt, 0,
+		)
+		if p != 0 {
+			t.Fatalf("expected hint channel to be excluded")
+		}
+
+		return []*channeldb.ChannelEdgePolicy{
+			{
+				Node: &channeldb.LightningNode{
+					PubKeyBytes: lastHop,
+				},
+				ChannelID: 1,
+			},
+		}, nil
+	}
+
+	session := &paymentSession{
+		additionalEdges: map[route.Vertex][]*channeldb.ChannelEdgePolicy{
+			lastHop: {hintEdge},
+		},
+		mc: &MissionControl{
+			selfNode: self,
+			cfg:      &MissionControlConfig{},
+		},
+		pathFinder: findPath,
+	}
+
+	payment := &LightningPayment{
+		Target:   self.PubKeyBytes,
+		Amount:   amt,
+		FeeLimit: noFeeLimit,
+	}
+
+	rt, err := session.RequestRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatalf("unable to request route: %v", err)
+	}
+
+	if len(rt.Hops) != 2 {
+		t.Fatalf("expected 2 hops, got %v", len(rt.Hops))
+	}
+	if rt.Hops[1].PubKeyBytes != self.PubKeyBytes ||
+		rt.Hops[1].ChannelID != hintChan {
+
+		t.Fatalf("expected route to end with hint channel")
+	}
+	if rt.TotalAmount != amt+hintEdge.FeeBaseMSat {
+		t.Fatalf("unexpected total amount %v", rt.TotalAmount)
+	}
+	if rt.TotalTimeLock != height+finalCltvDelta+40 {
+		t.Fatalf("unexpected total time lock %v", rt.TotalTimeLock)
+	}
+}
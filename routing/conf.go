@@ -2,11 +2,24 @@
 
 package routing
 
-// Conf provides the command line routing configuration. There are no fields in
-// the production build so that this section is hidden by default.
-type Conf struct{}
+// Conf provides the command line routing configuration. Only the options that
+// are available in all builds are included in the production build, the
+// experimental ones are hidden.
+type Conf struct {
+	PacingConf
+}
 
 // UseAssumeChannelValid always returns false when not in experimental builds.
 func (c *Conf) UseAssumeChannelValid() bool {
 	return false
 }
+
+// UseDeferGraphSync always returns false when not in experimental builds.
+func (c *Conf) UseDeferGraphSync() bool {
+	return false
+}
+
+// UseRouteJournal always returns false when not in experimental builds.
+func (c *Conf) UseRouteJournal() bool {
+	return false
+}
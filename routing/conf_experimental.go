@@ -2,9 +2,16 @@
 
 package routing
 
-// Conf exposes the experimental command line routing configurations.
+// Conf exposes the experimental command line routing configurations, along
+// with the options that are available in all builds.
 type Conf struct {
+	PacingConf
+
 	AssumeChannelValid bool `long:"assumechanvalid" description:"Skip checking channel spentness during graph validation. (default: false)"`
+
+	DeferGraphSync bool `long:"defergraphsync" description:"Make the router available for payments before the channel graph has been synced with the chain at startup. (default: false)"`
+
+	RouteJournal bool `long:"routejournal" description:"Persist the routes of settled payments and try them first for subsequent payments to the same destination. (default: false)"`
 }
 
 // UseAssumeChannelValid returns true if the router should skip checking for
@@ -12,3 +19,15 @@ type Conf struct {
 func (c *Conf) UseAssumeChannelValid() bool {
 	return c.AssumeChannelValid
 }
+
+// UseDeferGraphSync returns true if the router should sync the channel graph
+// with the chain in the background at startup.
+func (c *Conf) UseDeferGraphSync() bool {
+	return c.DeferGraphSync
+}
+
+// UseRouteJournal returns true if the router should persist the routes of
+// settled payments and try them first for subsequent payments.
+func (c *Conf) UseRouteJournal() bool {
+	return c.RouteJournal
+}
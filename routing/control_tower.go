@@ -36,6 +36,10 @@ type ControlTower interface {
 	// the switch to make a subsequent payment.
 	Fail(lntypes.Hash, channeldb.FailureReason) error
 
+	// SetPaused marks an in-flight payment as paused or unpaused. The
+	// paused state is persisted, so that it survives restarts.
+	SetPaused(lntypes.Hash, bool) error
+
 	// FetchInFlightPayments returns all payments with status InFlight.
 	FetchInFlightPayments() ([]*channeldb.InFlightPayment, error)
 
@@ -44,6 +48,11 @@ type ControlTower interface {
 	// flight and a channel that provides the final outcome of the payment.
 	SubscribePayment(paymentHash lntypes.Hash) (bool, chan PaymentResult,
 		error)
+
+	// DestinationStats returns the aggregated statistics of the payments
+	// made to the given destination, or nil if no payments to it have
+	// been completed.
+	DestinationStats(target route.Vertex) *DestinationStats
 }
 
 // PaymentResult is the struct describing the events received by payment
@@ -72,13 +81,28 @@ type controlTower struct {
 
 	subscribers    map[lntypes.Hash][]chan PaymentResult
 	subscribersMtx sync.Mutex
+
+	// destStats aggregates the payment outcomes per destination.
+	destStats *destStatsTracker
 }
 
-// NewControlTower creates a new instance of the controlTower.
+// NewControlTower creates a new instance of the controlTower. The destination
+// statistics are initialized from the payments that are stored in the DB.
 func NewControlTower(db *channeldb.PaymentControl) ControlTower {
+	destStats := newDestStatsTracker()
+
+	payments, err := db.FetchPayments()
+	if err != nil {
+		log.Warnf("Unable to load payment history for destination "+
+			"stats: %v", err)
+	} else {
+		destStats.load(payments)
+	}
+
 	return &controlTower{
 		db:          db,
 		subscribers: make(map[lntypes.Hash][]chan PaymentResult),
+		destStats:   destStats,
 	}
 }
 
@@ -97,7 +121,14 @@ func (p *controlTower) InitPayment(paymentHash lntypes.Hash,
 func (p *controlTower) RegisterAttempt(paymentHash lntypes.Hash,
 	attempt *channeldb.PaymentAttemptInfo) error {
 
-	return p.db.RegisterAttempt(paymentHash, attempt)
+	err := p.db.RegisterAttempt(paymentHash, attempt)
+	if err != nil {
+		return err
+	}
+
+	p.destStats.recordAttempt(paymentHash, &attempt.Route)
+
+	return nil
 }
 
 // Success transitions a payment into the Succeeded state. After invoking this
@@ -112,6 +143,8 @@ func (p *controlTower) Success(paymentHash lntypes.Hash,
 		return err
 	}
 
+	p.destStats.recordSuccess(paymentHash, route)
+
 	// Notify subscribers of success event.
 	p.notifyFinalEvent(
 		paymentHash, PaymentResult{
@@ -136,6 +169,8 @@ func (p *controlTower) Fail(paymentHash lntypes.Hash,
 		return err
 	}
 
+	p.destStats.recordFailure(paymentHash)
+
 	// Notify subscribers of fail event.
 	p.notifyFinalEvent(
 		paymentHash, PaymentResult{
@@ -147,11 +182,23 @@ func (p *controlTower) Fail(paymentHash lntypes.Hash,
 	return nil
 }
 
+// SetPaused marks an in-flight payment as paused or unpaused. The paused state
+// is persisted, so that it survives restarts.
+func (p *controlTower) SetPaused(paymentHash lntypes.Hash, paused bool) error {
+	return p.db.SetPaused(paymentHash, paused)
+}
+
 // FetchInFlightPayments returns all payments with status InFlight.
 func (p *controlTower) FetchInFlightPayments() ([]*channeldb.InFlightPayment, error) {
 	return p.db.FetchInFlightPayments()
 }
 
+// DestinationStats returns the aggregated statistics of the payments made to
+// the given destination, or nil if no payments to it have been completed.
+func (p *controlTower) DestinationStats(target route.Vertex) *DestinationStats {
+	return p.destStats.stats(target)
+}
+
 // SubscribePayment subscribes to updates for the payment with the given hash.
 // It returns a boolean indicating whether the payment is still in flight and a
 // channel that provides the final outcome of the payment.
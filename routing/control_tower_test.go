@@ -237,6 +237,89 @@ func TestPaymentControlSubscribeFail(t *testing.T) {
 	}
 }
 
+// TestControlTowerDestinationStats asserts that payment outcomes are
+// aggregated per destination, and that the statistics are restored from the
+// payment history.
+func TestControlTowerDestinationStats(t *testing.T) {
+	t.Parallel()
+
+	db, err := initDB()
+	if err != nil {
+		t.Fatalf("unable to init db: %v", err)
+	}
+
+	pControl := NewControlTower(channeldb.NewPaymentControl(db))
+
+	target := route.NewVertex(pub)
+	if stats := pControl.DestinationStats(target); stats != nil {
+		t.Fatalf("expected no stats, got %v", stats)
+	}
+
+	// Make a payment that succeeds on the second attempt.
+	info1, attempt1, preimg1, err := genInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pControl.InitPayment(info1.PaymentHash, info1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		err := pControl.RegisterAttempt(info1.PaymentHash, attempt1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pControl.Success(info1.PaymentHash, preimg1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make a payment that fails after a single attempt.
+	info2, attempt2, _, err := genInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pControl.InitPayment(info2.PaymentHash, info2); err != nil {
+		t.Fatal(err)
+	}
+	err = pControl.RegisterAttempt(info2.PaymentHash, attempt2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pControl.Fail(info2.PaymentHash, channeldb.FailureReasonNoRoute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feePPM := float64(testRoute.TotalFees()) * 1e6 /
+		float64(testRoute.TotalAmount-testRoute.TotalFees())
+
+	stats := pControl.DestinationStats(target)
+	if stats == nil {
+		t.Fatalf("expected stats for destination")
+	}
+	if stats.Payments != 2 || stats.Successes != 1 ||
+		stats.SuccessRate != 0.5 || stats.AvgAttempts != 1.5 ||
+		stats.AvgFeePPM != feePPM || stats.LastSuccess.IsZero() {
+
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// A new control tower restores the stats from the payment history, in
+	// which only the last attempt of each payment is known.
+	pControl = NewControlTower(channeldb.NewPaymentControl(db))
+
+	stats = pControl.DestinationStats(target)
+	if stats == nil {
+		t.Fatalf("expected stats for destination")
+	}
+	if stats.Payments != 2 || stats.Successes != 1 ||
+		stats.AvgAttempts != 1 || stats.AvgFeePPM != feePPM ||
+		!stats.LastSuccess.Equal(info1.CreationDate) {
+
+		t.Fatalf("unexpected restored stats %+v", stats)
+	}
+}
+
 func initDB() (*channeldb.DB, error) {
 	tempPath, err := ioutil.TempDir("", "routingdb")
 	if err != nil {
@@ -0,0 +1,253 @@
+package routing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// DestinationStats aggregates the outcomes of the payments that were made to
+// a single destination.
+type DestinationStats struct {
+	// Payments is the number of completed payments to the destination,
+	// both successful and failed.
+	Payments int
+
+	// Successes is the number of successful payments to the destination.
+	Successes int
+
+	// SuccessRate is the fraction of payments that succeeded.
+	SuccessRate float64
+
+	// AvgFeePPM is the average fee that was paid for successful payments,
+	// expressed in parts per million of the amount that was delivered.
+	AvgFeePPM float64
+
+	// AvgAttempts is the average number of attempts that were made per
+	// payment. For payments that were completed before the tracker was
+	// started only the last attempt is known, so they count as a single
+	// attempt.
+	AvgAttempts float64
+
+	// LastSuccess is the time of the last successful payment. For payments
+	// that were completed before the tracker was started, the creation
+	// time of the payment is used instead.
+	LastSuccess time.Time
+}
+
+// destStats holds the running totals for a single destination.
+type destStats struct {
+	payments    int
+	successes   int
+	attempts    int
+	feePPMSum   float64
+	lastSuccess time.Time
+}
+
+// stats converts the running totals into the exported statistics.
+func (d *destStats) stats() *DestinationStats {
+	s := &DestinationStats{
+		Payments:    d.payments,
+		Successes:   d.successes,
+		LastSuccess: d.lastSuccess,
+	}
+	if d.payments > 0 {
+		s.SuccessRate = float64(d.successes) / float64(d.payments)
+		s.AvgAttempts = float64(d.attempts) / float64(d.payments)
+	}
+	if d.successes > 0 {
+		s.AvgFeePPM = d.feePPMSum / float64(d.successes)
+	}
+
+	return s
+}
+
+// pendingStats tracks a payment that is in flight, until its outcome is
+// known.
+type pendingStats struct {
+	attempts int
+	target   route.Vertex
+}
+
+// destStatsTracker incrementally aggregates payment outcomes per destination.
+type destStatsTracker struct {
+	dests   map[route.Vertex]*destStats
+	pending map[lntypes.Hash]*pendingStats
+
+	// now is expected to return the current time. It is supplied as an
+	// external function to enable deterministic unit tests.
+	now func() time.Time
+
+	sync.Mutex
+}
+
+// newDestStatsTracker returns a new, empty destination stats tracker.
+func newDestStatsTracker() *destStatsTracker {
+	return &destStatsTracker{
+		dests:   make(map[route.Vertex]*destStats),
+		pending: make(map[lntypes.Hash]*pendingStats),
+		now:     time.Now,
+	}
+}
+
+// routeTarget returns the destination of the given route.
+func routeTarget(rt *route.Route) (route.Vertex, bool) {
+	if rt == nil || len(rt.Hops) == 0 {
+		return route.Vertex{}, false
+	}
+
+	return rt.Hops[len(rt.Hops)-1].PubKeyBytes, true
+}
+
+// routeFeePPM returns the fee of the route in parts per million of the amount
+// that is delivered to the destination.
+func routeFeePPM(rt *route.Route) float64 {
+	fees := rt.TotalFees()
+	amt := rt.TotalAmount - fees
+	if amt == 0 {
+		return 0
+	}
+
+	return float64(fees) * 1e6 / float64(amt)
+}
+
+// dest returns the running totals for the given destination, creating them if
+// needed.
+//
+// NOTE: The caller must hold the tracker's lock.
+func (d *destStatsTracker) dest(target route.Vertex) *destStats {
+	stats, ok := d.dests[target]
+	if !ok {
+		stats = &destStats{}
+		d.dests[target] = stats
+	}
+
+	return stats
+}
+
+// load populates the tracker with the payments that were completed in the
+// past.
+func (d *destStatsTracker) load(payments []*channeldb.Payment) {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, p := range payments {
+		if p.Attempt == nil {
+			continue
+		}
+
+		target, ok := routeTarget(&p.Attempt.Route)
+		if !ok {
+			continue
+		}
+
+		switch p.Status {
+		case channeldb.StatusSucceeded:
+			stats := d.dest(target)
+			stats.payments++
+			stats.successes++
+			stats.attempts++
+			stats.feePPMSum += routeFeePPM(&p.Attempt.Route)
+			if p.Info.CreationDate.After(stats.lastSuccess) {
+				stats.lastSuccess = p.Info.CreationDate
+			}
+
+		case channeldb.StatusFailed:
+			stats := d.dest(target)
+			stats.payments++
+			stats.attempts++
+		}
+	}
+}
+
+// recordAttempt records a new attempt for the given payment.
+func (d *destStatsTracker) recordAttempt(paymentHash lntypes.Hash,
+	rt *route.Route) {
+
+	target, ok := routeTarget(rt)
+	if !ok {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	p, ok := d.pending[paymentHash]
+	if !ok {
+		p = &pendingStats{}
+		d.pending[paymentHash] = p
+	}
+	p.attempts++
+	p.target = target
+}
+
+// recordSuccess records that the given payment succeeded over the given route.
+func (d *destStatsTracker) recordSuccess(paymentHash lntypes.Hash,
+	rt *route.Route) {
+
+	target, ok := routeTarget(rt)
+	if !ok {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	// A payment that was resumed after a restart has no pending record,
+	// in which case only the final attempt is known.
+	attempts := 1
+	if p, ok := d.pending[paymentHash]; ok {
+		attempts = p.attempts
+		delete(d.pending, paymentHash)
+	}
+
+	stats := d.dest(target)
+	stats.payments++
+	stats.successes++
+	stats.attempts += attempts
+	stats.feePPMSum += routeFeePPM(rt)
+	stats.lastSuccess = d.now()
+}
+
+// recordFailure records that the given payment failed. Payments for which no
+// attempt was made are not attributed to a destination, as the destination
+// isn't known to the control tower.
+func (d *destStatsTracker) recordFailure(paymentHash lntypes.Hash) {
+	d.Lock()
+	defer d.Unlock()
+
+	p, ok := d.pending[paymentHash]
+	if !ok {
+		return
+	}
+	delete(d.pending, paymentHash)
+
+	stats := d.dest(p.target)
+	stats.payments++
+	stats.attempts += p.attempts
+}
+
+// stats returns the statistics for the given destination, or nil if no
+// payments to it have been completed.
+func (d *destStatsTracker) stats(target route.Vertex) *DestinationStats {
+	d.Lock()
+	defer d.Unlock()
+
+	stats, ok := d.dests[target]
+	if !ok {
+		return nil
+	}
+
+	return stats.stats()
+}
+
+// DestinationStats returns the aggregated payment statistics for the given
+// destination, or nil if no payments to it have been completed.
+func (r *ChannelRouter) DestinationStats(
+	target route.Vertex) *DestinationStats {
+
+	return r.cfg.Control.DestinationStats(target)
+}
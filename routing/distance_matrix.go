@@ -0,0 +1,301 @@
+package routing
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// MaxDistanceMatrixNodes is the maximum number of nodes for which a distance
+// matrix can be computed in a single call.
+const MaxDistanceMatrixNodes = 50
+
+// DistanceMatrix holds the pairwise distances among a set of nodes.
+type DistanceMatrix struct {
+	// Nodes is the set of nodes the matrix was computed for. The rows and
+	// columns of Hops and Fees are indexed in the same order.
+	Nodes []route.Vertex
+
+	// Hops holds the number of hops of the shortest path from Nodes[i] to
+	// Nodes[j]. If Nodes[j] isn't reachable from Nodes[i], the entry is
+	// -1.
+	Hops [][]int
+
+	// Fees holds an estimate of the lowest total fee that is paid to
+	// intermediate nodes when sending the matrix amount from Nodes[i] to
+	// Nodes[j]. The path with the lowest fee may differ from the path with
+	// the lowest number of hops. The entry is only valid if Nodes[j] is
+	// reachable from Nodes[i].
+	Fees [][]lnwire.MilliSatoshi
+}
+
+// matrixEdge is a directed channel within the in-memory adjacency list that is
+// used to compute a distance matrix.
+type matrixEdge struct {
+	to  int
+	fee lnwire.MilliSatoshi
+}
+
+// matrixGraph is an in-memory adjacency list of the channel graph, containing
+// only the channels that are able to carry a particular amount. It is shared
+// among all searches that make up a distance matrix, so the graph only needs
+// to be read once.
+type matrixGraph struct {
+	index map[route.Vertex]int
+	edges [][]matrixEdge
+}
+
+// vertex returns the index of the given node, adding it to the graph if it
+// isn't known yet.
+func (g *matrixGraph) vertex(v route.Vertex) int {
+	if i, ok := g.index[v]; ok {
+		return i
+	}
+
+	i := len(g.edges)
+	g.index[v] = i
+	g.edges = append(g.edges, nil)
+
+	return i
+}
+
+// addEdge adds the channel described by the given policy to the graph, if it
+// is able to carry the amount.
+func (g *matrixGraph) addEdge(from, to route.Vertex,
+	policy *channeldb.ChannelEdgePolicy, amt lnwire.MilliSatoshi) {
+
+	if policy == nil || policy.IsDisabled() {
+		return
+	}
+	if amt < policy.MinHTLC {
+		return
+	}
+	if policy.MaxHTLC != 0 && policy.MaxHTLC < amt {
+		return
+	}
+
+	fromIdx := g.vertex(from)
+	g.edges[fromIdx] = append(g.edges[fromIdx], matrixEdge{
+		to:  g.vertex(to),
+		fee: computeFee(amt, policy),
+	})
+}
+
+// newMatrixGraph reads the channel graph into memory, keeping only the
+// channels that are able to carry the given amount.
+func newMatrixGraph(graph *channeldb.ChannelGraph,
+	amt lnwire.MilliSatoshi) (*matrixGraph, error) {
+
+	g := &matrixGraph{
+		index: make(map[route.Vertex]int),
+	}
+
+	err := graph.ForEachChannel(func(info *channeldb.ChannelEdgeInfo,
+		policy1, policy2 *channeldb.ChannelEdgePolicy) error {
+
+		if lnwire.NewMSatFromSatoshis(info.Capacity) < amt {
+			return nil
+		}
+
+		node1 := route.Vertex(info.NodeKey1Bytes)
+		node2 := route.Vertex(info.NodeKey2Bytes)
+
+		g.addEdge(node1, node2, policy1, amt)
+		g.addEdge(node2, node1, policy2, amt)
+
+		return nil
+	})
+	switch {
+	case err == channeldb.ErrGraphNoEdgesFound:
+	case err == channeldb.ErrGraphNotFound:
+	case err != nil:
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// hopDistances returns the number of hops from the source to every vertex in
+// the graph, using a breadth-first search. Unreachable vertices have a
+// distance of -1. The search terminates as soon as all targets are reached.
+func (g *matrixGraph) hopDistances(source int,
+	targets map[int]struct{}) []int {
+
+	dist := make([]int, len(g.edges))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[source] = 0
+
+	remaining := len(targets)
+	if _, ok := targets[source]; ok {
+		remaining--
+	}
+
+	queue := []int{source}
+	for len(queue) > 0 && remaining > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, e := range g.edges[current] {
+			if dist[e.to] != -1 {
+				continue
+			}
+
+			dist[e.to] = dist[current] + 1
+			queue = append(queue, e.to)
+
+			if _, ok := targets[e.to]; ok {
+				remaining--
+			}
+		}
+	}
+
+	return dist
+}
+
+// feeDistances returns the lowest fee paid to intermediate nodes when sending
+// from the source to every vertex in the graph, using Dijkstra's algorithm.
+// The source doesn't charge a fee for its own channels. The search terminates
+// as soon as the fees to all targets are known.
+func (g *matrixGraph) feeDistances(source int,
+	targets map[int]struct{}) []lnwire.MilliSatoshi {
+
+	var (
+		fees    = make([]lnwire.MilliSatoshi, len(g.edges))
+		reached = make([]bool, len(g.edges))
+		settled = make([]bool, len(g.edges))
+	)
+	reached[source] = true
+
+	remaining := len(targets)
+	queue := &feeHeap{}
+	heap.Push(queue, vertexFee{vertex: source})
+	for queue.Len() > 0 && remaining > 0 {
+		current := heap.Pop(queue).(vertexFee)
+		if settled[current.vertex] {
+			continue
+		}
+		settled[current.vertex] = true
+
+		if _, ok := targets[current.vertex]; ok {
+			remaining--
+		}
+
+		for _, e := range g.edges[current.vertex] {
+			fee := current.fee
+			if current.vertex != source {
+				fee += e.fee
+			}
+
+			if reached[e.to] && fees[e.to] <= fee {
+				continue
+			}
+
+			fees[e.to] = fee
+			reached[e.to] = true
+			heap.Push(queue, vertexFee{vertex: e.to, fee: fee})
+		}
+	}
+
+	return fees
+}
+
+// vertexFee couples a vertex index with the fee paid to reach it.
+type vertexFee struct {
+	vertex int
+	fee    lnwire.MilliSatoshi
+}
+
+// feeHeap is a min-fee heap used to compute the fee distances of a distance
+// matrix.
+type feeHeap struct {
+	items []vertexFee
+}
+
+// Len returns the number of items in the priority queue.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (h *feeHeap) Len() int { return len(h.items) }
+
+// Less returns whether the item in the priority queue with index i should sort
+// before the item with index j.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (h *feeHeap) Less(i, j int) bool {
+	return h.items[i].fee < h.items[j].fee
+}
+
+// Swap swaps the items at the passed indices in the priority queue.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (h *feeHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+// Push pushes the passed item onto the priority queue.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (h *feeHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(vertexFee))
+}
+
+// Pop removes the highest priority item (according to Less) from the priority
+// queue and returns it.
+//
+// NOTE: This is part of the heap.Interface implementation.
+func (h *feeHeap) Pop() interface{} {
+	n := len(h.items)
+	x := h.items[n-1]
+	h.items = h.items[0 : n-1]
+	return x
+}
+
+// DistanceMatrix computes the pairwise hop distances and fee estimates among
+// the given set of nodes, for channels that are able to carry the passed
+// amount. The channel graph is read only once and shared among the searches,
+// which each compute the distances from one node to all others in the set.
+func (r *ChannelRouter) DistanceMatrix(nodes []route.Vertex,
+	amt lnwire.MilliSatoshi) (*DistanceMatrix, error) {
+
+	if len(nodes) > MaxDistanceMatrixNodes {
+		return nil, fmt.Errorf("distance matrix limited to %v nodes, "+
+			"got %v", MaxDistanceMatrixNodes, len(nodes))
+	}
+
+	g, err := newMatrixGraph(r.cfg.Graph, amt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure every requested node is part of the graph, even if it has
+	// no usable channels.
+	targets := make([]int, len(nodes))
+	targetSet := make(map[int]struct{}, len(nodes))
+	for i, node := range nodes {
+		targets[i] = g.vertex(node)
+		targetSet[targets[i]] = struct{}{}
+	}
+
+	matrix := &DistanceMatrix{
+		Nodes: nodes,
+		Hops:  make([][]int, len(nodes)),
+		Fees:  make([][]lnwire.MilliSatoshi, len(nodes)),
+	}
+	for i, source := range targets {
+		hops := g.hopDistances(source, targetSet)
+		fees := g.feeDistances(source, targetSet)
+
+		matrix.Hops[i] = make([]int, len(nodes))
+		matrix.Fees[i] = make([]lnwire.MilliSatoshi, len(nodes))
+		for j, target := range targets {
+			matrix.Hops[i][j] = hops[target]
+			matrix.Fees[i][j] = fees[target]
+		}
+	}
+
+	return matrix, nil
+}
@@ -0,0 +1,69 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// TestDistanceMatrix asserts that the pairwise hop distances and fees among a
+// set of nodes are computed correctly.
+func TestDistanceMatrix(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	unknown := route.Vertex{1}
+	nodes := []route.Vertex{
+		ctx.aliases["roasbeef"],
+		ctx.aliases["sophon"],
+		ctx.aliases["satoshi"],
+		unknown,
+	}
+
+	matrix, err := ctx.router.DistanceMatrix(
+		nodes, lnwire.NewMSatFromSatoshis(100),
+	)
+	if err != nil {
+		t.Fatalf("unable to compute distance matrix: %v", err)
+	}
+
+	expectedHops := [][]int{
+		{0, 2, 1, -1},
+		{2, 0, 3, -1},
+		{1, 3, 0, -1},
+		{-1, -1, -1, 0},
+	}
+	expectedFees := [][]lnwire.MilliSatoshi{
+		{0, 110, 0, 0},
+		{110, 0, 220, 0},
+		{0, 220, 0, 0},
+		{0, 0, 0, 0},
+	}
+	for i := range nodes {
+		for j := range nodes {
+			if matrix.Hops[i][j] != expectedHops[i][j] {
+				t.Fatalf("hops %v->%v: expected %v, got %v", i,
+					j, expectedHops[i][j], matrix.Hops[i][j])
+			}
+			if matrix.Fees[i][j] != expectedFees[i][j] {
+				t.Fatalf("fees %v->%v: expected %v, got %v", i,
+					j, expectedFees[i][j], matrix.Fees[i][j])
+			}
+		}
+	}
+
+	// Requests for too many nodes should be rejected.
+	tooMany := make([]route.Vertex, MaxDistanceMatrixNodes+1)
+	if _, err := ctx.router.DistanceMatrix(tooMany, 1000); err == nil {
+		t.Fatalf("expected error for too many nodes")
+	}
+}
@@ -51,6 +51,22 @@ const (
 	// ErrFeeLimitExceeded is returned when the total fees of a route exceed
 	// the user-specified fee limit.
 	ErrFeeLimitExceeded
+
+	// ErrInvoiceExpired is returned when the invoice that a payment is
+	// attempting to pay expires before the payment completes.
+	ErrInvoiceExpired
+
+	// ErrChainMismatch is returned when an announcement or payment is for
+	// a different chain than the one the router is configured for.
+	ErrChainMismatch
+
+	// ErrInvalidPreimage is returned when a payment is settled with a
+	// preimage that doesn't hash to the payment hash.
+	ErrInvalidPreimage
+
+	// ErrDestinationOffline is returned when the destination of a payment
+	// looks offline and the payment requests to fail fast in that case.
+	ErrDestinationOffline
 )
 
 // routerError is a structure that represent the error inside the routing package,
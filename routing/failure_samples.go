@@ -0,0 +1,250 @@
+package routing
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// maxFailureSamples is the maximum number of failed payment attempts that we
+// retain samples of. Once this limit is reached, the oldest sample is
+// replaced.
+const maxFailureSamples = 1000
+
+// AmountBand is a coarse classification of the amount of a payment attempt.
+type AmountBand uint8
+
+const (
+	// AmountBandMicro is the band of amounts below 1,000 satoshis.
+	AmountBandMicro AmountBand = iota
+
+	// AmountBandSmall is the band of amounts from 1,000 up to 100,000
+	// satoshis.
+	AmountBandSmall
+
+	// AmountBandMedium is the band of amounts from 100,000 up to 1,000,000
+	// satoshis.
+	AmountBandMedium
+
+	// AmountBandLarge is the band of amounts of 1,000,000 satoshis and
+	// above.
+	AmountBandLarge
+)
+
+// String returns a human readable representation of the amount band.
+func (b AmountBand) String() string {
+	switch b {
+	case AmountBandMicro:
+		return "micro"
+
+	case AmountBandSmall:
+		return "small"
+
+	case AmountBandMedium:
+		return "medium"
+
+	case AmountBandLarge:
+		return "large"
+
+	default:
+		return "unknown"
+	}
+}
+
+// failureAmountBand returns the band that the amount belongs to.
+func failureAmountBand(amt lnwire.MilliSatoshi) AmountBand {
+	switch sat := amt.ToSatoshis(); {
+	case sat < 1000:
+		return AmountBandMicro
+
+	case sat < 100000:
+		return AmountBandSmall
+
+	case sat < 1000000:
+		return AmountBandMedium
+
+	default:
+		return AmountBandLarge
+	}
+}
+
+// FailureSample describes a single failed payment attempt.
+type FailureSample struct {
+	// Time is the time at which the failure was processed.
+	Time time.Time
+
+	// FailureCode is the code of the failure message. It is zero if the
+	// failure message couldn't be decoded.
+	FailureCode lnwire.FailCode
+
+	// HopDistance is the distance of the node that reported the failure
+	// from our own node. A distance of zero means that our own node
+	// reported the failure.
+	HopDistance int
+
+	// ChannelAge is the age in blocks of the channel that the failure is
+	// attributed to, derived from its short channel id. It is zero if the
+	// age is unknown, for example for private channels.
+	ChannelAge uint32
+
+	// Amount is the amount that was to be forwarded over the failed
+	// channel.
+	Amount lnwire.MilliSatoshi
+
+	// AmountBand is the band that Amount belongs to.
+	AmountBand AmountBand
+}
+
+// FailureStats aggregates the recent failed payment attempts across all
+// payments. It gives a view of the conditions of the network from the
+// vantage point of our node.
+type FailureStats struct {
+	// Samples contains the recent failure samples, ordered from oldest to
+	// newest.
+	Samples []FailureSample
+
+	// ByCode is the number of samples per failure code.
+	ByCode map[lnwire.FailCode]int
+
+	// ByHopDistance is the number of samples per hop distance of the node
+	// that reported the failure.
+	ByHopDistance map[int]int
+
+	// ByAmountBand is the number of samples per amount band.
+	ByAmountBand map[AmountBand]int
+
+	// MeanHopDistance is the average hop distance of the nodes that
+	// reported the failures.
+	MeanHopDistance float64
+
+	// MeanChannelAge is the average age in blocks of the failed channels,
+	// over the samples for which the age is known.
+	MeanChannelAge uint32
+}
+
+// failureSampler is a fixed size ring buffer of failure samples.
+type failureSampler struct {
+	samples []FailureSample
+	next    int
+	limit   int
+
+	// now is expected to return the current time. It is supplied as an
+	// external function to enable deterministic unit tests.
+	now func() time.Time
+
+	sync.Mutex
+}
+
+// newFailureSampler returns a new sampler that holds up to limit samples.
+func newFailureSampler(limit int) *failureSampler {
+	return &failureSampler{
+		samples: make([]FailureSample, 0, limit),
+		limit:   limit,
+		now:     time.Now,
+	}
+}
+
+// add records a new sample, replacing the oldest one if the buffer is full.
+func (f *failureSampler) add(sample FailureSample) {
+	f.Lock()
+	defer f.Unlock()
+
+	sample.Time = f.now()
+
+	if len(f.samples) < f.limit {
+		f.samples = append(f.samples, sample)
+		return
+	}
+
+	f.samples[f.next] = sample
+	f.next = (f.next + 1) % f.limit
+}
+
+// stats computes the aggregate statistics of the samples currently held.
+func (f *failureSampler) stats() *FailureStats {
+	f.Lock()
+	defer f.Unlock()
+
+	stats := &FailureStats{
+		Samples:       make([]FailureSample, 0, len(f.samples)),
+		ByCode:        make(map[lnwire.FailCode]int),
+		ByHopDistance: make(map[int]int),
+		ByAmountBand:  make(map[AmountBand]int),
+	}
+
+	// Once the buffer is full, the oldest sample is the one that will be
+	// replaced next.
+	stats.Samples = append(stats.Samples, f.samples[f.next:]...)
+	stats.Samples = append(stats.Samples, f.samples[:f.next]...)
+
+	var (
+		totalDistance int
+		totalAge      uint64
+		agedCount     uint64
+	)
+	for _, sample := range stats.Samples {
+		stats.ByCode[sample.FailureCode]++
+		stats.ByHopDistance[sample.HopDistance]++
+		stats.ByAmountBand[sample.AmountBand]++
+
+		totalDistance += sample.HopDistance
+		if sample.ChannelAge > 0 {
+			totalAge += uint64(sample.ChannelAge)
+			agedCount++
+		}
+	}
+
+	if len(stats.Samples) > 0 {
+		stats.MeanHopDistance = float64(totalDistance) /
+			float64(len(stats.Samples))
+	}
+	if agedCount > 0 {
+		stats.MeanChannelAge = uint32(totalAge / agedCount)
+	}
+
+	return stats
+}
+
+// sampleFailure records a sample of the failure of an attempt along the given
+// route.
+func (r *ChannelRouter) sampleFailure(rt *route.Route, errSource route.Vertex,
+	failedEdge edge, failedAmt lnwire.MilliSatoshi,
+	fErr *htlcswitch.ForwardingError) {
+
+	sample := FailureSample{
+		Amount:     failedAmt,
+		AmountBand: failureAmountBand(failedAmt),
+	}
+
+	if fErr.FailureMessage != nil {
+		sample.FailureCode = fErr.FailureMessage.Code()
+	}
+
+	for i, hop := range rt.Hops {
+		if hop.PubKeyBytes == errSource {
+			sample.HopDistance = i + 1
+			break
+		}
+	}
+
+	// Private channels may not be confirmed at the height that their short
+	// channel id indicates, in which case the age is left unknown.
+	chanID := lnwire.NewShortChanIDFromInt(failedEdge.channel)
+	chanHeight := chanID.BlockHeight
+	bestHeight := atomic.LoadUint32(&r.bestHeight)
+	if chanHeight > 0 && chanHeight <= bestHeight {
+		sample.ChannelAge = bestHeight - chanHeight
+	}
+
+	r.failureSampler.add(sample)
+}
+
+// FailureStats returns aggregate statistics of the recent failed payment
+// attempts across all payments.
+func (r *ChannelRouter) FailureStats() *FailureStats {
+	return r.failureSampler.stats()
+}
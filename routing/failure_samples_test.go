@@ -0,0 +1,86 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// TestFailureSamplerStats asserts that the failure sampler retains the most
+// recent samples in order and aggregates them.
+func TestFailureSamplerStats(t *testing.T) {
+	t.Parallel()
+
+	sampler := newFailureSampler(3)
+
+	samples := []FailureSample{
+		{
+			FailureCode: lnwire.CodeTemporaryChannelFailure,
+			HopDistance: 1,
+			ChannelAge:  100,
+			Amount:      lnwire.NewMSatFromSatoshis(500),
+		},
+		{
+			FailureCode: lnwire.CodeTemporaryChannelFailure,
+			HopDistance: 2,
+			ChannelAge:  200,
+			Amount:      lnwire.NewMSatFromSatoshis(5000),
+		},
+		{
+			FailureCode: lnwire.CodeFeeInsufficient,
+			HopDistance: 3,
+			Amount:      lnwire.NewMSatFromSatoshis(500000),
+		},
+		{
+			FailureCode: lnwire.CodeUnknownNextPeer,
+			HopDistance: 4,
+			ChannelAge:  400,
+			Amount:      lnwire.NewMSatFromSatoshis(5000000),
+		},
+	}
+	for _, sample := range samples {
+		sample.AmountBand = failureAmountBand(sample.Amount)
+		sampler.add(sample)
+	}
+
+	stats := sampler.stats()
+
+	// The first sample is replaced by the last one.
+	if len(stats.Samples) != 3 {
+		t.Fatalf("expected 3 samples, got %v", len(stats.Samples))
+	}
+	for i, sample := range stats.Samples {
+		if sample.HopDistance != i+2 {
+			t.Fatalf("expected sample %v at distance %v, got %v",
+				i, i+2, sample.HopDistance)
+		}
+	}
+
+	if stats.ByCode[lnwire.CodeTemporaryChannelFailure] != 1 ||
+		stats.ByCode[lnwire.CodeFeeInsufficient] != 1 ||
+		stats.ByCode[lnwire.CodeUnknownNextPeer] != 1 {
+
+		t.Fatalf("unexpected counts by code: %v", stats.ByCode)
+	}
+
+	if stats.ByAmountBand[AmountBandMicro] != 0 ||
+		stats.ByAmountBand[AmountBandSmall] != 1 ||
+		stats.ByAmountBand[AmountBandMedium] != 1 ||
+		stats.ByAmountBand[AmountBandLarge] != 1 {
+
+		t.Fatalf("unexpected counts by amount band: %v",
+			stats.ByAmountBand)
+	}
+
+	if stats.MeanHopDistance != 3 {
+		t.Fatalf("expected mean hop distance 3, got %v",
+			stats.MeanHopDistance)
+	}
+
+	// The sample without a known channel age doesn't count towards the
+	// mean age.
+	if stats.MeanChannelAge != 300 {
+		t.Fatalf("expected mean channel age 300, got %v",
+			stats.MeanChannelAge)
+	}
+}
@@ -0,0 +1,149 @@
+package routing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// ForwardingHistory provides access to the forwarding events of our node. It
+// is implemented by channeldb.ForwardingLog.
+type ForwardingHistory interface {
+	// Query returns the forwarding events within the time slice of the
+	// query, starting at its index offset.
+	Query(q channeldb.ForwardingEventQuery) (
+		channeldb.ForwardingLogTimeSlice, error)
+}
+
+// FeeRevenueEstimate is the projected routing revenue of one of our channels
+// under a candidate fee schema, based on the volume that was forwarded over
+// the channel in a historical period. The projection assumes that the
+// forwarded volume doesn't change with the fees, so it is an upper bound for
+// fee increases and a lower bound for fee decreases.
+type FeeRevenueEstimate struct {
+	// ChannelID is the short channel id of the channel.
+	ChannelID uint64
+
+	// Forwards is the number of htlcs that were forwarded out over the
+	// channel in the period.
+	Forwards int
+
+	// Volume is the total amount that was forwarded out over the channel
+	// in the period.
+	Volume lnwire.MilliSatoshi
+
+	// Earned is the fee revenue that was actually earned in the period,
+	// under the policies that were active at the time.
+	Earned lnwire.MilliSatoshi
+
+	// CurrentSchema is the fee schema that the channel currently has.
+	CurrentSchema FeeSchema
+
+	// Current is the fee revenue that the forwards of the period would
+	// have earned under the current fee schema.
+	Current lnwire.MilliSatoshi
+
+	// ProjectedSchema is the candidate fee schema of the channel.
+	ProjectedSchema FeeSchema
+
+	// Projected is the fee revenue that the forwards of the period would
+	// have earned under the candidate fee schema.
+	Projected lnwire.MilliSatoshi
+}
+
+// Change returns the projected change in revenue of the candidate fee schema
+// compared to the current fee schema.
+func (e *FeeRevenueEstimate) Change() int64 {
+	return int64(e.Projected) - int64(e.Current)
+}
+
+// fee returns the fee that the schema charges for forwarding the amount.
+func (f FeeSchema) fee(amt lnwire.MilliSatoshi) lnwire.MilliSatoshi {
+	return f.BaseFee + amt*lnwire.MilliSatoshi(f.FeeRate)/1000000
+}
+
+// forEachForward calls the callback for every forwarding event between start
+// and end, paging through the forwarding history.
+func (r *ChannelRouter) forEachForward(start, end time.Time,
+	cb func(*channeldb.ForwardingEvent)) error {
+
+	query := channeldb.ForwardingEventQuery{
+		StartTime:    start,
+		EndTime:      end,
+		NumMaxEvents: channeldb.MaxResponseEvents,
+	}
+	for {
+		timeSlice, err := r.cfg.ForwardingHistory.Query(query)
+		if err != nil {
+			return err
+		}
+
+		for i := range timeSlice.ForwardingEvents {
+			cb(&timeSlice.ForwardingEvents[i])
+		}
+
+		if len(timeSlice.ForwardingEvents) < int(query.NumMaxEvents) {
+			return nil
+		}
+		query.IndexOffset = timeSlice.LastIndexOffset
+	}
+}
+
+// EstimateFeeRevenue projects the routing revenue of our channels under the
+// given candidate fee schemas, keyed by short channel id, based on the volume
+// that was forwarded between start and end. Channels without a candidate
+// schema are projected under their current schema. This allows operators to
+// preview the revenue impact of policy changes before applying them.
+func (r *ChannelRouter) EstimateFeeRevenue(start, end time.Time,
+	schemas map[uint64]FeeSchema) (map[uint64]*FeeRevenueEstimate, error) {
+
+	if r.cfg.ForwardingHistory == nil {
+		return nil, fmt.Errorf("no forwarding history available")
+	}
+
+	estimates := make(map[uint64]*FeeRevenueEstimate)
+	err := r.ForAllOutgoingChannels(func(c *channeldb.ChannelEdgeInfo,
+		e *channeldb.ChannelEdgePolicy) error {
+
+		current := FeeSchema{
+			BaseFee: e.FeeBaseMSat,
+			FeeRate: uint32(e.FeeProportionalMillionths),
+		}
+		projected, ok := schemas[c.ChannelID]
+		if !ok {
+			projected = current
+		}
+
+		estimates[c.ChannelID] = &FeeRevenueEstimate{
+			ChannelID:       c.ChannelID,
+			CurrentSchema:   current,
+			ProjectedSchema: projected,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.forEachForward(start, end, func(f *channeldb.ForwardingEvent) {
+		estimate, ok := estimates[f.OutgoingChanID.ToUint64()]
+		if !ok {
+			return
+		}
+
+		estimate.Forwards++
+		estimate.Volume += f.AmtOut
+		if f.AmtIn > f.AmtOut {
+			estimate.Earned += f.AmtIn - f.AmtOut
+		}
+		estimate.Current += estimate.CurrentSchema.fee(f.AmtOut)
+		estimate.Projected += estimate.ProjectedSchema.fee(f.AmtOut)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return estimates, nil
+}
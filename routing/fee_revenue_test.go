@@ -0,0 +1,132 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// mockForwardingHistory is a ForwardingHistory that returns a fixed set of
+// events.
+type mockForwardingHistory struct {
+	events []channeldb.ForwardingEvent
+}
+
+func (m *mockForwardingHistory) Query(q channeldb.ForwardingEventQuery) (
+	channeldb.ForwardingLogTimeSlice, error) {
+
+	var events []channeldb.ForwardingEvent
+	offset := q.IndexOffset
+	for _, event := range m.events[q.IndexOffset:] {
+		if uint32(len(events)) == q.NumMaxEvents {
+			break
+		}
+		events = append(events, event)
+		offset++
+	}
+
+	return channeldb.ForwardingLogTimeSlice{
+		ForwardingEventQuery: q,
+		ForwardingEvents:     events,
+		LastIndexOffset:      offset,
+	}, nil
+}
+
+// TestEstimateFeeRevenue asserts that the revenue of our channels is projected
+// from the forwarded volume under the current and candidate fee schemas.
+func TestEstimateFeeRevenue(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	_, err = ctx.router.EstimateFeeRevenue(time.Time{}, time.Now(), nil)
+	if err == nil {
+		t.Fatal("expected error without forwarding history")
+	}
+
+	// Channel 12345 of the source node has a base fee of 10 msat and a fee
+	// rate of 1000 ppm. Channel 777 isn't ours, so its event is ignored.
+	const chanID = 12345
+	ctx.router.cfg.ForwardingHistory = &mockForwardingHistory{
+		events: []channeldb.ForwardingEvent{
+			{
+				OutgoingChanID: lnwire.NewShortChanIDFromInt(chanID),
+				AmtIn:          1000020,
+				AmtOut:         1000000,
+			},
+			{
+				OutgoingChanID: lnwire.NewShortChanIDFromInt(chanID),
+				AmtIn:          3000040,
+				AmtOut:         3000000,
+			},
+			{
+				OutgoingChanID: lnwire.NewShortChanIDFromInt(777),
+				AmtIn:          1000000,
+				AmtOut:         900000,
+			},
+		},
+	}
+
+	candidate := FeeSchema{
+		BaseFee: 0,
+		FeeRate: 2000,
+	}
+	estimates, err := ctx.router.EstimateFeeRevenue(
+		time.Time{}, time.Now(), map[uint64]FeeSchema{
+			chanID: candidate,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to estimate revenue: %v", err)
+	}
+
+	if _, ok := estimates[777]; ok {
+		t.Fatal("unexpected estimate for foreign channel")
+	}
+
+	estimate, ok := estimates[chanID]
+	if !ok {
+		t.Fatalf("no estimate for channel %v", chanID)
+	}
+	if estimate.Forwards != 2 || estimate.Volume != 4000000 ||
+		estimate.Earned != 60 {
+
+		t.Fatalf("unexpected history: %v forwards, volume %v, "+
+			"earned %v", estimate.Forwards, estimate.Volume,
+			estimate.Earned)
+	}
+	if estimate.ProjectedSchema != candidate {
+		t.Fatalf("unexpected projected schema: %v",
+			estimate.ProjectedSchema)
+	}
+	if estimate.Current != 4020 || estimate.Projected != 8000 {
+		t.Fatalf("expected current revenue 4020 and projected "+
+			"revenue 8000, got %v and %v", estimate.Current,
+			estimate.Projected)
+	}
+	if estimate.Change() != 3980 {
+		t.Fatalf("expected change of 3980, got %v", estimate.Change())
+	}
+
+	// Channels without a candidate schema keep their current schema.
+	for id, estimate := range estimates {
+		if id == chanID {
+			continue
+		}
+		if estimate.ProjectedSchema != estimate.CurrentSchema ||
+			estimate.Change() != 0 {
+
+			t.Fatalf("unexpected estimate for channel %v: %v", id,
+				estimate)
+		}
+	}
+}
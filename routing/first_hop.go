@@ -0,0 +1,219 @@
+package routing
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// maxFirstHopCandidates is the maximum number of first hop channels for which
+// path finding is attempted before falling back to unrestricted path finding.
+// This bounds the number of path finding runs per route request.
+const maxFirstHopCandidates = 5
+
+// FirstHopCandidate describes one of our channels that is able to carry a
+// payment as its first hop.
+type FirstHopCandidate struct {
+	// ChannelID is the short channel ID of the channel.
+	ChannelID uint64
+
+	// Peer is the node at the other end of the channel.
+	Peer route.Vertex
+
+	// Bandwidth is the amount that can currently be sent over the channel.
+	Bandwidth lnwire.MilliSatoshi
+
+	// Probability is the success probability that mission control
+	// assigns to the channel based on recent failures.
+	Probability float64
+}
+
+// FirstHopStrategy selects which of our channels should be used as the first
+// hop of a route, if multiple channels are able to carry the payment.
+type FirstHopStrategy interface {
+	// Rank returns the candidates in the order in which they should be
+	// tried as the first hop. Candidates that shouldn't be tried at all
+	// may be omitted.
+	Rank(candidates []FirstHopCandidate,
+		amt lnwire.MilliSatoshi) []FirstHopCandidate
+}
+
+// HeadroomFirstHopStrategy prefers the channels that have the most bandwidth
+// left after the payment has been sent over them.
+type HeadroomFirstHopStrategy struct{}
+
+// A compile time assertion to ensure HeadroomFirstHopStrategy meets the
+// FirstHopStrategy interface.
+var _ FirstHopStrategy = (*HeadroomFirstHopStrategy)(nil)
+
+// Rank orders the candidates by decreasing bandwidth.
+//
+// NOTE: Part of the FirstHopStrategy interface.
+func (s *HeadroomFirstHopStrategy) Rank(candidates []FirstHopCandidate,
+	_ lnwire.MilliSatoshi) []FirstHopCandidate {
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Bandwidth > candidates[j].Bandwidth
+	})
+
+	return candidates
+}
+
+// ReliabilityFirstHopStrategy prefers the channels with the lowest recent
+// failure rate, as reflected by the success probability that mission control
+// assigns to them. Channels with an equal probability are ordered by
+// decreasing bandwidth.
+type ReliabilityFirstHopStrategy struct{}
+
+// A compile time assertion to ensure ReliabilityFirstHopStrategy meets the
+// FirstHopStrategy interface.
+var _ FirstHopStrategy = (*ReliabilityFirstHopStrategy)(nil)
+
+// Rank orders the candidates by decreasing success probability.
+//
+// NOTE: Part of the FirstHopStrategy interface.
+func (s *ReliabilityFirstHopStrategy) Rank(candidates []FirstHopCandidate,
+	_ lnwire.MilliSatoshi) []FirstHopCandidate {
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Probability != candidates[j].Probability {
+			return candidates[i].Probability >
+				candidates[j].Probability
+		}
+
+		return candidates[i].Bandwidth > candidates[j].Bandwidth
+	})
+
+	return candidates
+}
+
+// RoundRobinFirstHopStrategy rotates the preferred first hop among the
+// candidates with every route request. This spreads our payments over all of
+// our channels, so that an observer at one of our peers learns less about our
+// payment activity.
+type RoundRobinFirstHopStrategy struct {
+	next uint64
+	mu   sync.Mutex
+}
+
+// A compile time assertion to ensure RoundRobinFirstHopStrategy meets the
+// FirstHopStrategy interface.
+var _ FirstHopStrategy = (*RoundRobinFirstHopStrategy)(nil)
+
+// Rank orders the candidates by channel ID, and rotates them so that a
+// different candidate comes first on every call.
+//
+// NOTE: Part of the FirstHopStrategy interface.
+func (s *RoundRobinFirstHopStrategy) Rank(candidates []FirstHopCandidate,
+	_ lnwire.MilliSatoshi) []FirstHopCandidate {
+
+	if len(candidates) == 0 {
+		return candidates
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ChannelID < candidates[j].ChannelID
+	})
+
+	s.mu.Lock()
+	start := int(s.next % uint64(len(candidates)))
+	s.next++
+	s.mu.Unlock()
+
+	ranked := make([]FirstHopCandidate, 0, len(candidates))
+	ranked = append(ranked, candidates[start:]...)
+	return append(ranked, candidates[:start]...)
+}
+
+// firstHopCandidates returns the channels of ours that are able to carry the
+// given amount as the first hop of a route. If a set of allowed channels is
+// given, only those channels are returned.
+func (p *paymentSession) firstHopCandidates(amt lnwire.MilliSatoshi,
+	allowed []uint64) []FirstHopCandidate {
+
+	source := route.Vertex(p.mc.selfNode.PubKeyBytes)
+
+	var candidates []FirstHopCandidate
+	for _, edge := range p.localChans {
+		if !outgoingChannelAllowed(allowed, edge.ChannelID) {
+			continue
+		}
+
+		bandwidth, ok := p.bandwidthHints[edge.ChannelID]
+		if !ok || bandwidth < amt {
+			continue
+		}
+
+		if amt < edge.MinHTLC {
+			continue
+		}
+		if edge.MaxHTLC != 0 && edge.MaxHTLC < amt {
+			continue
+		}
+
+		probability := p.edgeProbability(
+			source, *newEdgeLocator(edge), amt, 0,
+		)
+		if probability == 0 {
+			continue
+		}
+
+		candidates = append(candidates, FirstHopCandidate{
+			ChannelID:   edge.ChannelID,
+			Peer:        route.Vertex(edge.Node.PubKeyBytes),
+			Bandwidth:   bandwidth,
+			Probability: probability,
+		})
+	}
+
+	return candidates
+}
+
+// findPathWithFirstHop attempts to find a path over the first hop candidates
+// in the order given by the configured strategy. If a path is found, it is
+// returned. Otherwise nil is returned, so that the caller can fall back to
+// unrestricted path finding.
+func (p *paymentSession) findPathWithFirstHop(g *graphParams,
+	r RestrictParams, target route.Vertex,
+	amt lnwire.MilliSatoshi) ([]*channeldb.ChannelEdgePolicy, error) {
+
+	strategy := p.mc.cfg.FirstHopStrategy
+
+	candidates := p.firstHopCandidates(amt, r.OutgoingChannelIDs)
+
+	// With less than two candidates, there is nothing to choose from.
+	if len(candidates) < 2 {
+		return nil, nil
+	}
+
+	ranked := strategy.Rank(candidates, amt)
+	if len(ranked) > maxFirstHopCandidates {
+		ranked = ranked[:maxFirstHopCandidates]
+	}
+
+	source := route.Vertex(p.mc.selfNode.PubKeyBytes)
+	for _, candidate := range ranked {
+		chanID := candidate.ChannelID
+		r.OutgoingChannelIDs = []uint64{chanID}
+
+		path, err := p.pathFinder(g, &r, source, target, amt)
+		switch {
+		case IsError(err, ErrNoPathFound, ErrMaxHopsExceeded):
+			log.Tracef("No path found over first hop %v", chanID)
+			continue
+
+		case err != nil:
+			return nil, err
+		}
+
+		log.Debugf("Selected first hop %v to %x", chanID,
+			candidate.Peer[:])
+
+		return path, nil
+	}
+
+	return nil, nil
+}
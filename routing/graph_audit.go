@@ -0,0 +1,370 @@
+package routing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/eventbus"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// DefaultGraphAuditSize is the default number of graph mutations that are
+// retained in the audit log.
+const DefaultGraphAuditSize = 10000
+
+// GraphMutationType describes the kind of change that was made to the graph.
+type GraphMutationType uint8
+
+const (
+	// GraphMutationNodeAnn is a node announcement that was added to the
+	// graph.
+	GraphMutationNodeAnn GraphMutationType = iota
+
+	// GraphMutationChannelAnn is a channel announcement that was added to
+	// the graph.
+	GraphMutationChannelAnn
+
+	// GraphMutationChannelUpdate is a channel update that replaced the
+	// policy of one direction of a channel.
+	GraphMutationChannelUpdate
+
+	// GraphMutationChannelClosed is a channel that was removed from the
+	// graph because its funding output was spent.
+	GraphMutationChannelClosed
+
+	// GraphMutationChannelZombie is a channel that was removed from the
+	// graph because it wasn't updated for too long.
+	GraphMutationChannelZombie
+)
+
+// String returns a human readable representation of the mutation type.
+func (t GraphMutationType) String() string {
+	switch t {
+	case GraphMutationNodeAnn:
+		return "node_announcement"
+
+	case GraphMutationChannelAnn:
+		return "channel_announcement"
+
+	case GraphMutationChannelUpdate:
+		return "channel_update"
+
+	case GraphMutationChannelClosed:
+		return "channel_closed"
+
+	case GraphMutationChannelZombie:
+		return "channel_zombie"
+
+	default:
+		return "unknown"
+	}
+}
+
+// GraphMutationSource describes where a graph mutation originates from.
+type GraphMutationSource uint8
+
+const (
+	// GraphSourceGossip is a message that was received from the network,
+	// or announced by our own node.
+	GraphSourceGossip GraphMutationSource = iota
+
+	// GraphSourcePaymentFailure is a channel update that was returned in
+	// the failure of a payment attempt.
+	GraphSourcePaymentFailure
+
+	// GraphSourceChain is a channel that was closed on chain.
+	GraphSourceChain
+
+	// GraphSourcePruning is a channel that was pruned by the router.
+	GraphSourcePruning
+)
+
+// String returns a human readable representation of the mutation source.
+func (s GraphMutationSource) String() string {
+	switch s {
+	case GraphSourceGossip:
+		return "gossip"
+
+	case GraphSourcePaymentFailure:
+		return "payment_failure"
+
+	case GraphSourceChain:
+		return "chain"
+
+	case GraphSourcePruning:
+		return "pruning"
+
+	default:
+		return "unknown"
+	}
+}
+
+// AuditPolicy contains the routing policy values of one direction of a channel
+// at the time of a mutation.
+type AuditPolicy struct {
+	// LastUpdate is the timestamp of the channel update.
+	LastUpdate time.Time
+
+	// TimeLockDelta is the time lock delta of the policy.
+	TimeLockDelta uint16
+
+	// MinHTLC is the minimum htlc amount of the policy.
+	MinHTLC lnwire.MilliSatoshi
+
+	// MaxHTLC is the maximum htlc amount of the policy.
+	MaxHTLC lnwire.MilliSatoshi
+
+	// FeeBaseMSat is the base fee of the policy.
+	FeeBaseMSat lnwire.MilliSatoshi
+
+	// FeeProportionalMillionths is the proportional fee of the policy.
+	FeeProportionalMillionths lnwire.MilliSatoshi
+
+	// Disabled indicates that the direction was disabled.
+	Disabled bool
+}
+
+// newAuditPolicy returns the audit record of the given policy, or nil if the
+// policy is unknown.
+func newAuditPolicy(policy *channeldb.ChannelEdgePolicy) *AuditPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	return &AuditPolicy{
+		LastUpdate:                policy.LastUpdate,
+		TimeLockDelta:             policy.TimeLockDelta,
+		MinHTLC:                   policy.MinHTLC,
+		MaxHTLC:                   policy.MaxHTLC,
+		FeeBaseMSat:               policy.FeeBaseMSat,
+		FeeProportionalMillionths: policy.FeeProportionalMillionths,
+		Disabled: policy.ChannelFlags&
+			lnwire.ChanUpdateDisabled != 0,
+	}
+}
+
+// GraphMutation is an entry of the graph audit log.
+type GraphMutation struct {
+	// Time is the time at which the mutation was applied.
+	Time time.Time
+
+	// Type is the kind of mutation.
+	Type GraphMutationType
+
+	// Source is where the mutation originates from.
+	Source GraphMutationSource
+
+	// ChannelID is the short channel id of the affected channel. It is
+	// zero for node announcements.
+	ChannelID uint64
+
+	// Node is the node that signed the message. For channel announcements
+	// and removed channels, it is the first node of the channel.
+	Node route.Vertex
+
+	// Direction is the direction of the channel that a channel update
+	// applies to.
+	Direction uint8
+
+	// OldPolicy is the policy that a channel update replaced. It is nil if
+	// the direction had no policy yet, or for other mutation types.
+	OldPolicy *AuditPolicy
+
+	// NewPolicy is the policy of a channel update. It is nil for other
+	// mutation types.
+	NewPolicy *AuditPolicy
+}
+
+// GraphAuditQuery selects entries of the graph audit log. Unset fields don't
+// restrict the result.
+type GraphAuditQuery struct {
+	// ChannelID restricts the result to mutations of the given channel.
+	ChannelID uint64
+
+	// Node restricts the result to mutations signed by the given node.
+	Node *route.Vertex
+
+	// Types restricts the result to the given mutation types.
+	Types []GraphMutationType
+
+	// Since restricts the result to mutations applied at or after the
+	// given time.
+	Since time.Time
+
+	// MaxEntries restricts the result to the most recent entries.
+	MaxEntries int
+}
+
+// matches returns true if the mutation is selected by the query.
+func (q *GraphAuditQuery) matches(m *GraphMutation) bool {
+	if q.ChannelID != 0 && m.ChannelID != q.ChannelID {
+		return false
+	}
+	if q.Node != nil && m.Node != *q.Node {
+		return false
+	}
+	if m.Time.Before(q.Since) {
+		return false
+	}
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if m.Type == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// graphAuditLog is a fixed size ring buffer of graph mutations.
+type graphAuditLog struct {
+	entries []GraphMutation
+	next    int
+	limit   int
+
+	// now is expected to return the current time. It is supplied as an
+	// external function to enable deterministic unit tests.
+	now func() time.Time
+
+	sync.Mutex
+}
+
+// newGraphAuditLog returns a new audit log that holds up to limit entries.
+func newGraphAuditLog(limit int) *graphAuditLog {
+	return &graphAuditLog{
+		entries: make([]GraphMutation, 0, limit),
+		limit:   limit,
+		now:     time.Now,
+	}
+}
+
+// add records a new mutation, replacing the oldest one if the log is full.
+func (g *graphAuditLog) add(m GraphMutation) {
+	g.Lock()
+	defer g.Unlock()
+
+	m.Time = g.now()
+
+	if len(g.entries) < g.limit {
+		g.entries = append(g.entries, m)
+		return
+	}
+
+	g.entries[g.next] = m
+	g.next = (g.next + 1) % g.limit
+}
+
+// query returns the mutations selected by the query, ordered from oldest to
+// newest.
+func (g *graphAuditLog) query(q *GraphAuditQuery) []GraphMutation {
+	g.Lock()
+	defer g.Unlock()
+
+	// Once the log is full, the oldest entry is the one that will be
+	// replaced next.
+	ordered := append([]GraphMutation(nil), g.entries[g.next:]...)
+	ordered = append(ordered, g.entries[:g.next]...)
+
+	var result []GraphMutation
+	for i := range ordered {
+		if q.matches(&ordered[i]) {
+			result = append(result, ordered[i])
+		}
+	}
+
+	if q.MaxEntries > 0 && len(result) > q.MaxEntries {
+		result = result[len(result)-q.MaxEntries:]
+	}
+
+	return result
+}
+
+// auditMutation records the mutation in the audit log, if it is enabled.
+func (r *ChannelRouter) auditMutation(m GraphMutation) {
+	if r.graphAudit == nil {
+		return
+	}
+
+	r.graphAudit.add(m)
+}
+
+// auditPrunedChannels records the removal of the given channels in the audit
+// log.
+func (r *ChannelRouter) auditPrunedChannels(reason eventbus.PruneReason,
+	chans ...*channeldb.ChannelEdgeInfo) {
+
+	mutationType := GraphMutationChannelClosed
+	source := GraphSourceChain
+	if reason == eventbus.PruneReasonZombie {
+		mutationType = GraphMutationChannelZombie
+		source = GraphSourcePruning
+	}
+
+	for _, info := range chans {
+		r.auditMutation(GraphMutation{
+			Type:      mutationType,
+			Source:    source,
+			ChannelID: info.ChannelID,
+			Node:      info.NodeKey1Bytes,
+		})
+	}
+}
+
+// GraphAudit returns the entries of the graph audit log that are selected by
+// the query, ordered from oldest to newest. It allows operators to determine
+// when and through which message the graph changed, for instance when the
+// fee of a channel was raised. Nil is returned if the audit log is disabled.
+func (r *ChannelRouter) GraphAudit(q *GraphAuditQuery) []GraphMutation {
+	if r.graphAudit == nil {
+		return nil
+	}
+
+	return r.graphAudit.query(q)
+}
+
+// auditChannelAnn records the addition of the given channel in the audit log.
+func (r *ChannelRouter) auditChannelAnn(info *channeldb.ChannelEdgeInfo,
+	source GraphMutationSource) {
+
+	r.auditMutation(GraphMutation{
+		Type:      GraphMutationChannelAnn,
+		Source:    source,
+		ChannelID: info.ChannelID,
+		Node:      info.NodeKey1Bytes,
+	})
+}
+
+// auditChannelUpdate records the replacement of the old policy of a channel
+// direction by the given policy in the audit log. The node that signed the
+// update is looked up in the graph.
+func (r *ChannelRouter) auditChannelUpdate(policy,
+	oldPolicy *channeldb.ChannelEdgePolicy, source GraphMutationSource) {
+
+	direction := uint8(policy.ChannelFlags & lnwire.ChanUpdateDirection)
+
+	m := GraphMutation{
+		Type:      GraphMutationChannelUpdate,
+		Source:    source,
+		ChannelID: policy.ChannelID,
+		Direction: direction,
+		OldPolicy: newAuditPolicy(oldPolicy),
+		NewPolicy: newAuditPolicy(policy),
+	}
+
+	info, _, _, err := r.cfg.Graph.FetchChannelEdgesByID(policy.ChannelID)
+	if err != nil {
+		log.Debugf("Unable to fetch chan_id=%v for audit: %v",
+			policy.ChannelID, err)
+	} else {
+		m.Node = info.NodeKey1Bytes
+		if direction == 1 {
+			m.Node = info.NodeKey2Bytes
+		}
+	}
+
+	r.auditMutation(m)
+}
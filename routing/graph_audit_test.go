@@ -0,0 +1,129 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// TestGraphAuditLog asserts that the audit log retains the most recent
+// mutations and filters them by query.
+func TestGraphAuditLog(t *testing.T) {
+	t.Parallel()
+
+	auditLog := newGraphAuditLog(3)
+
+	now := time.Unix(1000, 0)
+	auditLog.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	auditLog.add(GraphMutation{Type: GraphMutationNodeAnn})
+	auditLog.add(GraphMutation{
+		Type:      GraphMutationChannelAnn,
+		ChannelID: 1,
+	})
+	auditLog.add(GraphMutation{
+		Type:      GraphMutationChannelUpdate,
+		ChannelID: 1,
+	})
+	auditLog.add(GraphMutation{
+		Type:      GraphMutationChannelUpdate,
+		ChannelID: 2,
+	})
+
+	// The node announcement is replaced by the last mutation.
+	all := auditLog.query(&GraphAuditQuery{})
+	if len(all) != 3 {
+		t.Fatalf("expected 3 entries, got %v", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if !all[i-1].Time.Before(all[i].Time) {
+			t.Fatalf("entries not ordered by time: %v", all)
+		}
+	}
+
+	chanEntries := auditLog.query(&GraphAuditQuery{ChannelID: 1})
+	if len(chanEntries) != 2 {
+		t.Fatalf("expected 2 entries of channel 1, got %v",
+			len(chanEntries))
+	}
+
+	updates := auditLog.query(&GraphAuditQuery{
+		Types:      []GraphMutationType{GraphMutationChannelUpdate},
+		MaxEntries: 1,
+	})
+	if len(updates) != 1 || updates[0].ChannelID != 2 {
+		t.Fatalf("expected most recent update, got %v", updates)
+	}
+
+	recent := auditLog.query(&GraphAuditQuery{Since: all[2].Time})
+	if len(recent) != 1 || recent[0].ChannelID != 2 {
+		t.Fatalf("expected mutation since last time, got %v", recent)
+	}
+}
+
+// TestGraphAuditChannelUpdate asserts that an accepted channel update is
+// audited with the policy that it replaced.
+func TestGraphAuditChannelUpdate(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	ctx.router.graphAudit = newGraphAuditLog(DefaultGraphAuditSize)
+
+	const chanID = 12345
+	info, oldPolicy, _, err := ctx.graph.FetchChannelEdgesByID(chanID)
+	if err != nil {
+		t.Fatalf("unable to fetch channel: %v", err)
+	}
+
+	newPolicy := *oldPolicy
+	newPolicy.LastUpdate = oldPolicy.LastUpdate.Add(time.Hour)
+	newPolicy.FeeBaseMSat = oldPolicy.FeeBaseMSat + 1000
+	if err := ctx.router.UpdateEdge(&newPolicy); err != nil {
+		t.Fatalf("unable to update edge: %v", err)
+	}
+
+	// An outdated update isn't accepted, so it isn't audited either.
+	err = ctx.router.UpdateEdge(oldPolicy)
+	if !IsError(err, ErrOutdated) {
+		t.Fatalf("expected ErrOutdated, got %v", err)
+	}
+
+	entries := ctx.router.GraphAudit(&GraphAuditQuery{ChannelID: chanID})
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %v", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Type != GraphMutationChannelUpdate ||
+		entry.Source != GraphSourceGossip {
+
+		t.Fatalf("unexpected mutation %v from %v", entry.Type,
+			entry.Source)
+	}
+	if entry.Direction != 0 || entry.Node != info.NodeKey1Bytes {
+		t.Fatalf("unexpected direction %v or node %x",
+			entry.Direction, entry.Node)
+	}
+	if entry.OldPolicy == nil ||
+		entry.OldPolicy.FeeBaseMSat != oldPolicy.FeeBaseMSat {
+
+		t.Fatalf("unexpected old policy %v", entry.OldPolicy)
+	}
+	if entry.NewPolicy == nil || entry.NewPolicy.FeeBaseMSat !=
+		oldPolicy.FeeBaseMSat+lnwire.MilliSatoshi(1000) {
+
+		t.Fatalf("unexpected new policy %v", entry.NewPolicy)
+	}
+}
@@ -0,0 +1,107 @@
+package routing
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// slowGraphWriteThreshold is the duration after which a graph mutation is
+// considered to be slow. Such mutations are reported by the watchdog, as they
+// stall the entire validation pipeline.
+const slowGraphWriteThreshold = 5 * time.Second
+
+// GraphWriteStats contains statistics about the graph mutations carried out
+// by the router while processing network updates.
+type GraphWriteStats struct {
+	// PendingUpdates is the number of network updates that have been
+	// accepted by the router, but haven't been fully processed yet.
+	PendingUpdates int64
+
+	// PendingWrites is the number of graph mutations that are currently
+	// waiting for or holding a write transaction.
+	PendingWrites int64
+
+	// SlowWrites is the total number of graph mutations that took longer
+	// than the slow write threshold to complete.
+	SlowWrites uint64
+}
+
+// graphWriteTracker keeps track of the queue depth of graph mutations, and
+// reports mutations that stall the processing of network updates. Write
+// contention doesn't result in errors that could be retried, as a bolt write
+// transaction blocks until the database lock is available. Contention
+// therefore shows up as slow writes.
+type graphWriteTracker struct {
+	slowWrites uint64 // To be used atomically.
+
+	pendingUpdates int64 // To be used atomically.
+	pendingWrites  int64 // To be used atomically.
+
+	// slowThreshold is the duration after which a mutation is reported as
+	// being slow.
+	slowThreshold time.Duration
+}
+
+// newGraphWriteTracker returns a new graph write tracker.
+func newGraphWriteTracker() *graphWriteTracker {
+	return &graphWriteTracker{
+		slowThreshold: slowGraphWriteThreshold,
+	}
+}
+
+// updateQueued records that a new network update has entered the processing
+// pipeline.
+func (g *graphWriteTracker) updateQueued() {
+	atomic.AddInt64(&g.pendingUpdates, 1)
+}
+
+// updateDone records that a network update has left the processing pipeline.
+func (g *graphWriteTracker) updateDone() {
+	atomic.AddInt64(&g.pendingUpdates, -1)
+}
+
+// write executes the passed graph mutation. While the mutation is in
+// progress, a watchdog reports it if it is held for too long.
+func (g *graphWriteTracker) write(desc string, mutate func() error) error {
+	atomic.AddInt64(&g.pendingWrites, 1)
+	defer atomic.AddInt64(&g.pendingWrites, -1)
+
+	start := time.Now()
+
+	// The watchdog fires once the mutation has been running for longer
+	// than the threshold, which allows us to spot stalls while they are
+	// still ongoing.
+	watchdog := time.AfterFunc(g.slowThreshold, func() {
+		log.Warnf("Graph write (%v) has been running for more than "+
+			"%v, pending_updates=%v, pending_writes=%v", desc,
+			g.slowThreshold, atomic.LoadInt64(&g.pendingUpdates),
+			atomic.LoadInt64(&g.pendingWrites))
+	})
+	defer func() {
+		watchdog.Stop()
+
+		elapsed := time.Since(start)
+		if elapsed > g.slowThreshold {
+			atomic.AddUint64(&g.slowWrites, 1)
+			log.Warnf("Graph write (%v) completed after %v", desc,
+				elapsed)
+		}
+	}()
+
+	return mutate()
+}
+
+// stats returns a snapshot of the current graph write statistics.
+func (g *graphWriteTracker) stats() *GraphWriteStats {
+	return &GraphWriteStats{
+		PendingUpdates: atomic.LoadInt64(&g.pendingUpdates),
+		PendingWrites:  atomic.LoadInt64(&g.pendingWrites),
+		SlowWrites:     atomic.LoadUint64(&g.slowWrites),
+	}
+}
+
+// GraphWriteStats returns statistics about the queue depth of network updates
+// and the graph mutations carried out while processing them.
+func (r *ChannelRouter) GraphWriteStats() *GraphWriteStats {
+	return r.graphWrites.stats()
+}
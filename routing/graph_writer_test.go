@@ -0,0 +1,55 @@
+package routing
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestGraphWriteStats asserts that graph mutations are executed once, that
+// their error is returned, and that slow mutations are accounted for.
+func TestGraphWriteStats(t *testing.T) {
+	t.Parallel()
+
+	tracker := newGraphWriteTracker()
+	tracker.slowThreshold = 10 * time.Millisecond
+
+	// A failing mutation shouldn't be retried.
+	errInvalid := errors.New("invalid")
+	attempts := 0
+	err := tracker.write("test", func() error {
+		attempts++
+		return errInvalid
+	})
+	if err != errInvalid {
+		t.Fatalf("expected errInvalid, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %v", attempts)
+	}
+
+	// A mutation that holds the write for longer than the threshold is
+	// reported as slow.
+	err = tracker.write("test", func() error {
+		stats := tracker.stats()
+		if stats.PendingWrites != 1 {
+			t.Fatalf("expected 1 pending write, got %v",
+				stats.PendingWrites)
+		}
+
+		time.Sleep(2 * tracker.slowThreshold)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := tracker.stats()
+	if stats.SlowWrites != 1 {
+		t.Fatalf("expected 1 slow write, got %v", stats.SlowWrites)
+	}
+	if stats.PendingWrites != 0 {
+		t.Fatalf("expected no pending writes, got %v",
+			stats.PendingWrites)
+	}
+}
@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"github.com/btcsuite/btcutil"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnwire"
 )
@@ -33,6 +34,19 @@ type nodeWithDist struct {
 	// Includes the routing fees and a virtual cost factor to account for
 	// time locks.
 	weight int64
+
+	// hops is the number of hops of the route from this node to the
+	// destination.
+	hops int
+
+	// minCapacity is the smallest known channel capacity on the route
+	// from this node to the destination. It is zero if none of the
+	// capacities is known.
+	minCapacity btcutil.Amount
+
+	// ties is the number of candidate routes from this node that were
+	// found to have the same distance as the current one.
+	ties int
 }
 
 // distanceHeap is a min-distance heap that's used within our path finding
@@ -79,6 +93,63 @@ func (d *distanceHeap) Pop() interface{} {
 	return x
 }
 
+// pushNode pushes the node onto the heap. Unlike heap.Push, it doesn't need to
+// box the node into an interface, which would allocate.
+func (d *distanceHeap) pushNode(n nodeWithDist) {
+	d.nodes = append(d.nodes, n)
+
+	// Move the node up until its parent is closer.
+	j := len(d.nodes) - 1
+	for j > 0 {
+		i := (j - 1) / 2
+		if !d.Less(j, i) {
+			break
+		}
+		d.Swap(i, j)
+		j = i
+	}
+}
+
+// popNode removes the closest node from the heap and returns it. Unlike
+// heap.Pop, it doesn't need to box the node into an interface, which would
+// allocate.
+func (d *distanceHeap) popNode() nodeWithDist {
+	n := len(d.nodes) - 1
+	d.Swap(0, n)
+
+	// Move the node that was swapped to the root down until its children
+	// are further away.
+	i := 0
+	for {
+		j := 2*i + 1
+		if j >= n {
+			break
+		}
+		if j2 := j + 1; j2 < n && d.Less(j2, j) {
+			j = j2
+		}
+		if !d.Less(j, i) {
+			break
+		}
+		d.Swap(i, j)
+		i = j
+	}
+
+	x := d.nodes[n]
+	d.nodes[n] = nodeWithDist{}
+	d.nodes = d.nodes[:n]
+
+	return x
+}
+
+// reset empties the heap, keeping its capacity.
+func (d *distanceHeap) reset() {
+	for i := range d.nodes {
+		d.nodes[i] = nodeWithDist{}
+	}
+	d.nodes = d.nodes[:0]
+}
+
 // path represents an ordered set of edges which forms an available path from a
 // given source node to our destination. During the process of computing the
 // KSP's from a source to destination, several path swill be considered in the
@@ -54,3 +54,51 @@ func TestHeapOrdering(t *testing.T) {
 			poppedEntries)
 	}
 }
+
+// TestDistanceHeapPushPopNode ensures that nodes pushed with pushNode are
+// popped with popNode in minimum order of distance, and that the heap can be
+// reused after a reset.
+func TestDistanceHeapPushPopNode(t *testing.T) {
+	t.Parallel()
+
+	var nodeHeap distanceHeap
+
+	for round := 0; round < 2; round++ {
+		const numEntries = 100
+		sortedEntries := make([]nodeWithDist, 0, numEntries)
+		for i := 0; i < numEntries; i++ {
+			entry := nodeWithDist{
+				dist: prand.Int63(),
+			}
+
+			nodeHeap.pushNode(entry)
+			sortedEntries = append(sortedEntries, entry)
+		}
+
+		sort.Slice(sortedEntries, func(i, j int) bool {
+			return sortedEntries[i].dist < sortedEntries[j].dist
+		})
+
+		// Pop half of the entries and reset the heap in the first
+		// round, so that the second round starts with a used heap.
+		numPopped := numEntries
+		if round == 0 {
+			numPopped = numEntries / 2
+		}
+
+		poppedEntries := make([]nodeWithDist, 0, numPopped)
+		for i := 0; i < numPopped; i++ {
+			poppedEntries = append(poppedEntries, nodeHeap.popNode())
+		}
+
+		if !reflect.DeepEqual(poppedEntries, sortedEntries[:numPopped]) {
+			t.Fatalf("items don't match: expected %v, got %v",
+				sortedEntries[:numPopped], poppedEntries)
+		}
+
+		nodeHeap.reset()
+		if nodeHeap.Len() != 0 {
+			t.Fatalf("expected empty heap after reset")
+		}
+	}
+}
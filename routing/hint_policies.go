@@ -0,0 +1,128 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// HintPolicyStore persists the policies of private channels that were learned
+// from the channel updates that are returned by the nodes of route hints.
+type HintPolicyStore interface {
+	// PutHintPolicy stores the given policy for its channel, unless a more
+	// recent policy is already stored.
+	PutHintPolicy(policy *channeldb.HintPolicy) error
+
+	// FetchHintPolicy returns the policy that was learned for the given
+	// channel. If there is none, channeldb.ErrHintPolicyNotFound is
+	// returned.
+	FetchHintPolicy(chanID uint64) (*channeldb.HintPolicy, error)
+}
+
+// learnHintPolicy stores the policy of the channel update that was returned
+// for a channel that isn't part of our graph, which is the case for the
+// private channels of route hints. It returns true if the policy was learned.
+func (r *ChannelRouter) learnHintPolicy(msg *lnwire.ChannelUpdate,
+	pubKey *btcec.PublicKey, failedEdge edge) bool {
+
+	if r.cfg.HintPolicies == nil || msg == nil {
+		return false
+	}
+
+	// The update must be for the channel that we attempted to use, and
+	// signed by the node at its start.
+	chanID := msg.ShortChannelID.ToUint64()
+	if chanID != failedEdge.channel ||
+		route.NewVertex(pubKey) != failedEdge.from {
+
+		return false
+	}
+
+	err := r.checkChannelUpdateChain(chanID, msg.ChainHash)
+	if err != nil {
+		log.Debugf("Not learning hint policy: %v", err)
+		return false
+	}
+
+	// Updates for channels that are part of our graph are handled by
+	// applying them to the graph instead.
+	_, _, _, err = r.GetChannelByID(msg.ShortChannelID)
+	if err != channeldb.ErrEdgeNotFound {
+		return false
+	}
+
+	if err := VerifyChannelUpdateSignature(msg, pubKey); err != nil {
+		log.Debugf("Not learning hint policy: %v", err)
+		return false
+	}
+
+	policy := &channeldb.HintPolicy{
+		Node:                      failedEdge.from,
+		ChannelID:                 chanID,
+		Timestamp:                 time.Unix(int64(msg.Timestamp), 0),
+		FeeBaseMSat:               msg.BaseFee,
+		FeeProportionalMillionths: msg.FeeRate,
+		TimeLockDelta:             msg.TimeLockDelta,
+	}
+	if err := r.cfg.HintPolicies.PutHintPolicy(policy); err != nil {
+		log.Errorf("Unable to store hint policy of chan_id=%v: %v",
+			chanID, err)
+		return false
+	}
+
+	log.Debugf("Learned policy of private chan_id=%v: base_fee=%v, "+
+		"fee_rate=%v, time_lock_delta=%v", chanID, msg.BaseFee,
+		msg.FeeRate, msg.TimeLockDelta)
+
+	return true
+}
+
+// applyHintPolicies returns a copy of the given route hints in which the fees
+// and time lock deltas of the hops are replaced by the policies that were
+// learned for their channels. The hints that are passed in aren't modified.
+func (r *ChannelRouter) applyHintPolicies(
+	routeHints [][]zpay32.HopHint) ([][]zpay32.HopHint, error) {
+
+	if r.cfg.HintPolicies == nil || len(routeHints) == 0 {
+		return routeHints, nil
+	}
+
+	hints := make([][]zpay32.HopHint, len(routeHints))
+	for i, routeHint := range routeHints {
+		hints[i] = make([]zpay32.HopHint, len(routeHint))
+		copy(hints[i], routeHint)
+
+		for j := range hints[i] {
+			hopHint := &hints[i][j]
+
+			policy, err := r.cfg.HintPolicies.FetchHintPolicy(
+				hopHint.ChannelID,
+			)
+			switch {
+			case err == channeldb.ErrHintPolicyNotFound:
+				continue
+
+			case err != nil:
+				return nil, err
+			}
+
+			// Only apply the policy if it was signed by the node
+			// that the hint claims to be at the start of the
+			// channel.
+			if policy.Node != route.NewVertex(hopHint.NodeID) {
+				continue
+			}
+
+			hopHint.FeeBaseMSat = policy.FeeBaseMSat
+			hopHint.FeeProportionalMillionths =
+				policy.FeeProportionalMillionths
+			hopHint.CLTVExpiryDelta = policy.TimeLockDelta
+		}
+	}
+
+	return hints, nil
+}
@@ -0,0 +1,167 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// mockHintPolicyStore is an in-memory implementation of HintPolicyStore.
+type mockHintPolicyStore struct {
+	policies map[uint64]*channeldb.HintPolicy
+}
+
+func (m *mockHintPolicyStore) PutHintPolicy(
+	policy *channeldb.HintPolicy) error {
+
+	stored, ok := m.policies[policy.ChannelID]
+	if ok && !policy.Timestamp.After(stored.Timestamp) {
+		return nil
+	}
+	m.policies[policy.ChannelID] = policy
+
+	return nil
+}
+
+func (m *mockHintPolicyStore) FetchHintPolicy(
+	chanID uint64) (*channeldb.HintPolicy, error) {
+
+	policy, ok := m.policies[chanID]
+	if !ok {
+		return nil, channeldb.ErrHintPolicyNotFound
+	}
+
+	return policy, nil
+}
+
+// TestHintPolicyLearning asserts that the policy of a private channel is
+// learned from a signed channel update, and applied to the route hints of
+// subsequent payments.
+func TestHintPolicyLearning(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	store := &mockHintPolicyStore{
+		policies: make(map[uint64]*channeldb.HintPolicy),
+	}
+	ctx.router.cfg.HintPolicies = store
+
+	hintKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hintNode := route.NewVertex(hintKey.PubKey())
+
+	const privateChanID = 999
+	update := &lnwire.ChannelUpdate{
+		ShortChannelID: lnwire.NewShortChanIDFromInt(privateChanID),
+		Timestamp:      1000,
+		BaseFee:        2000,
+		FeeRate:        300,
+		TimeLockDelta:  60,
+	}
+	data, err := update.DataToSign()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := hintKey.Sign(chainhash.DoubleHashB(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	update.Signature, err = lnwire.NewSigFromSignature(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failedEdge := edge{
+		from:    hintNode,
+		to:      ctx.aliases["sophon"],
+		channel: privateChanID,
+	}
+
+	// An update that doesn't belong to the failed edge isn't learned.
+	otherEdge := failedEdge
+	otherEdge.channel = privateChanID + 1
+	if ctx.router.learnHintPolicy(update, hintKey.PubKey(), otherEdge) {
+		t.Fatalf("expected update for other channel to be ignored")
+	}
+
+	if !ctx.router.learnHintPolicy(update, hintKey.PubKey(), failedEdge) {
+		t.Fatalf("expected hint policy to be learned")
+	}
+
+	// Updates of channels in our graph aren't learned as hint policies.
+	graphEdge := edge{
+		from:    ctx.aliases["songoku"],
+		to:      ctx.aliases["roasbeef"],
+		channel: 12345,
+	}
+	graphUpdate := *update
+	graphUpdate.ShortChannelID = lnwire.NewShortChanIDFromInt(12345)
+	songokuKey, err := btcec.ParsePubKey(
+		graphEdge.from[:], btcec.S256(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.router.learnHintPolicy(&graphUpdate, songokuKey, graphEdge) {
+		t.Fatalf("expected update of public channel to be ignored")
+	}
+
+	// The learned policy replaces the policy of the matching hint, but
+	// not of a hint that claims a different node for the channel.
+	otherKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	routeHints := [][]zpay32.HopHint{
+		{{
+			NodeID:                    hintKey.PubKey(),
+			ChannelID:                 privateChanID,
+			FeeBaseMSat:               1,
+			FeeProportionalMillionths: 1,
+			CLTVExpiryDelta:           10,
+		}},
+		{{
+			NodeID:                    otherKey.PubKey(),
+			ChannelID:                 privateChanID,
+			FeeBaseMSat:               1,
+			FeeProportionalMillionths: 1,
+			CLTVExpiryDelta:           10,
+		}},
+	}
+
+	hints, err := ctx.router.applyHintPolicies(routeHints)
+	if err != nil {
+		t.Fatalf("unable to apply hint policies: %v", err)
+	}
+
+	learned := hints[0][0]
+	if learned.FeeBaseMSat != 2000 ||
+		learned.FeeProportionalMillionths != 300 ||
+		learned.CLTVExpiryDelta != 60 {
+
+		t.Fatalf("learned policy not applied: %v", learned)
+	}
+	if hints[1][0] != routeHints[1][0] {
+		t.Fatalf("policy applied to hint of other node")
+	}
+
+	// The hints of the caller are left untouched.
+	if routeHints[0][0].FeeBaseMSat != 1 {
+		t.Fatalf("route hints of caller modified")
+	}
+}
@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"errors"
+
+	"github.com/coreos/bbolt"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// HubPenalty discourages path finding from routing through highly connected
+// nodes, so that payments rely less on a few large hubs at the cost of
+// slightly higher fees.
+type HubPenalty struct {
+	// MinChannels is the number of channels from which on a node is
+	// considered a hub.
+	MinChannels int
+
+	// Penalty is the virtual cost in path finding weight units that is
+	// added for every hub that the route passes through as an
+	// intermediate hop. Like the fee, it is expressed in msat, so a hub is
+	// only used if the alternatives are more expensive by at least this
+	// amount.
+	Penalty lnwire.MilliSatoshi
+}
+
+// errHubThreshold is used to stop counting the channels of a node once the
+// hub threshold is reached.
+var errHubThreshold = errors.New("hub threshold reached")
+
+// hubCache lazily determines and caches which nodes encountered during a
+// single path finding run are hubs.
+type hubCache map[route.Vertex]bool
+
+// isHub returns true if the given node has at least minChannels channels in
+// the graph. Counting stops as soon as the threshold is reached, so that the
+// channels of large hubs aren't all loaded.
+func (h hubCache) isHub(tx *bbolt.Tx, node *channeldb.LightningNode,
+	minChannels int) (bool, error) {
+
+	vertex := route.Vertex(node.PubKeyBytes)
+	if isHub, ok := h[vertex]; ok {
+		return isHub, nil
+	}
+
+	var numChannels int
+	err := node.ForEachChannel(tx, func(_ *bbolt.Tx,
+		_ *channeldb.ChannelEdgeInfo,
+		_, _ *channeldb.ChannelEdgePolicy) error {
+
+		numChannels++
+		if numChannels >= minChannels {
+			return errHubThreshold
+		}
+		return nil
+	})
+	if err != nil && err != errHubThreshold {
+		return false, err
+	}
+
+	isHub := numChannels >= minChannels
+	h[vertex] = isHub
+
+	return isHub, nil
+}
@@ -0,0 +1,105 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// TestHubPenalty asserts that path finding avoids nodes with many channels
+// if a hub penalty is set, unless the alternatives are too expensive.
+func TestHubPenalty(t *testing.T) {
+	t.Parallel()
+
+	// The route through the hub is cheaper than the route through b. The
+	// hub has four channels.
+	testChannels := []*testChannel{
+		symmetricTestChannel("roasbeef", "hub", 100000, &testChannelPolicy{}, 1),
+		symmetricTestChannel("hub", "target", 100000, &testChannelPolicy{
+			Expiry:      144,
+			FeeBaseMsat: 1000,
+			MinHTLC:     1,
+		}),
+		symmetricTestChannel("hub", "x", 100000, &testChannelPolicy{}),
+		symmetricTestChannel("hub", "y", 100000, &testChannelPolicy{}),
+		symmetricTestChannel("roasbeef", "b", 100000, &testChannelPolicy{}, 2),
+		symmetricTestChannel("b", "target", 100000, &testChannelPolicy{
+			Expiry:      144,
+			FeeBaseMsat: 5000,
+			MinHTLC:     1,
+		}),
+	}
+
+	testGraphInstance, err := createTestGraphFromChannels(testChannels)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer testGraphInstance.cleanUp()
+
+	sourceNode, err := testGraphInstance.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+	sourceVertex := route.Vertex(sourceNode.PubKeyBytes)
+
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+	target := testGraphInstance.aliasMap["target"]
+
+	testCases := []struct {
+		name         string
+		hubPenalty   *HubPenalty
+		expectedChan uint64
+	}{
+		{
+			name:         "no penalty",
+			expectedChan: 1,
+		},
+		{
+			name: "hub avoided",
+			hubPenalty: &HubPenalty{
+				MinChannels: 4,
+				Penalty:     10000,
+			},
+			expectedChan: 2,
+		},
+		{
+			name: "penalty below fee difference",
+			hubPenalty: &HubPenalty{
+				MinChannels: 4,
+				Penalty:     1000,
+			},
+			expectedChan: 1,
+		},
+		{
+			name: "below threshold",
+			hubPenalty: &HubPenalty{
+				MinChannels: 5,
+				Penalty:     10000,
+			},
+			expectedChan: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		path, err := findPath(
+			&graphParams{
+				graph: testGraphInstance.graph,
+			},
+			&RestrictParams{
+				FeeLimit:          noFeeLimit,
+				ProbabilitySource: noProbabilitySource,
+				HubPenalty:        test.hubPenalty,
+			},
+			sourceVertex, target, paymentAmt,
+		)
+		if err != nil {
+			t.Fatalf("%v: unable to find path: %v", test.name, err)
+		}
+
+		if path[0].ChannelID != test.expectedChan {
+			t.Fatalf("%v: expected route through channel %v, got %v",
+				test.name, test.expectedChan, path[0].ChannelID)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// supportedInvoiceFeatures are the invoice features that our payment
+// construction satisfies. Payments are currently sent as a single shard using
+// the legacy onion payload, so none of the invoice features that change the
+// payment construction are supported.
+var supportedInvoiceFeatures = map[lnwire.FeatureBit]string{}
+
+// UnsupportedFeaturesError is returned when an invoice requires features that
+// our payment construction doesn't satisfy.
+type UnsupportedFeaturesError struct {
+	// Missing are the required feature bits of the invoice that aren't
+	// supported, in ascending order.
+	Missing []lnwire.FeatureBit
+}
+
+// Error returns a human readable description of the missing features.
+func (e *UnsupportedFeaturesError) Error() string {
+	names := lnwire.NewFeatureVector(nil, lnwire.InvoiceFeatures)
+
+	missing := make([]string, 0, len(e.Missing))
+	for _, bit := range e.Missing {
+		missing = append(missing, names.Name(bit))
+	}
+
+	return fmt.Sprintf("invoice requires unsupported features: %v",
+		strings.Join(missing, ", "))
+}
+
+// ValidateInvoiceFeatures checks that all of the features that the invoice
+// requires are supported, such that a payment to it can succeed. If any of
+// them aren't, an UnsupportedFeaturesError is returned.
+func ValidateInvoiceFeatures(invoice *zpay32.Invoice) error {
+	if invoice.Features == nil {
+		return nil
+	}
+
+	supported := lnwire.NewFeatureVector(
+		invoice.Features.RawFeatureVector, supportedInvoiceFeatures,
+	)
+	missing := supported.UnknownRequiredFeatures()
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i] < missing[j]
+	})
+
+	return &UnsupportedFeaturesError{Missing: missing}
+}
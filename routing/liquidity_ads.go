@@ -0,0 +1,212 @@
+package routing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// liquidityAdRecordType is the type of the record within the extra
+	// opaque data of a node or channel announcement that carries a
+	// liquidity advertisement.
+	liquidityAdRecordType = 1
+
+	// liquidityAdMinLen is the minimum length of an encoded liquidity
+	// advertisement. The final field is a truncated integer, so it may be
+	// omitted entirely if it is zero.
+	liquidityAdMinLen = 10
+
+	// liquidityAdMaxLen is the maximum length of an encoded liquidity
+	// advertisement.
+	liquidityAdMaxLen = 14
+)
+
+var (
+	// ErrMalformedLiquidityAd is returned when the extra opaque data of an
+	// announcement contains a liquidity advertisement that can't be
+	// decoded.
+	ErrMalformedLiquidityAd = errors.New("malformed liquidity ad")
+)
+
+// LiquidityAd describes the terms under which a node is willing to lease
+// inbound liquidity to other nodes in the network. Liquidity ads are carried
+// within the extra opaque data of node and channel announcements, which are
+// persisted as is within the channel graph.
+type LiquidityAd struct {
+	// FundingWeight is the weight the lessor will contribute to the
+	// funding transaction.
+	FundingWeight uint16
+
+	// LeaseFeeBasis is the proportional fee charged for the leased
+	// amount, expressed in basis points.
+	LeaseFeeBasis uint16
+
+	// ChannelFeeMaxProportional is the maximum proportional fee, in
+	// thousandths, that the lessor commits to charging on the leased
+	// channel.
+	ChannelFeeMaxProportional uint16
+
+	// LeaseFeeBase is the fixed fee charged for a lease.
+	LeaseFeeBase uint32
+
+	// ChannelFeeMaxBase is the maximum base fee that the lessor commits to
+	// charging on the leased channel.
+	ChannelFeeMaxBase lnwire.MilliSatoshi
+}
+
+// readBigSize reads a BigSize encoded integer from the start of the passed
+// buffer, returning the value and the number of bytes consumed.
+func readBigSize(b []byte) (uint64, int, error) {
+	if len(b) < 1 {
+		return 0, 0, ErrMalformedLiquidityAd
+	}
+
+	var size int
+	switch b[0] {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return uint64(b[0]), 1, nil
+	}
+
+	if len(b) < 1+size {
+		return 0, 0, ErrMalformedLiquidityAd
+	}
+
+	var v uint64
+	for _, c := range b[1 : 1+size] {
+		v = v<<8 | uint64(c)
+	}
+
+	return v, 1 + size, nil
+}
+
+// ParseLiquidityAd extracts a liquidity advertisement from the extra opaque
+// data of a node or channel announcement. The data is interpreted as a stream
+// of type-length-value records, of which all but the liquidity ad record are
+// ignored. If the data doesn't contain a liquidity ad, nil is returned.
+func ParseLiquidityAd(extraData []byte) (*LiquidityAd, error) {
+	for len(extraData) > 0 {
+		recordType, n, err := readBigSize(extraData)
+		if err != nil {
+			return nil, err
+		}
+		extraData = extraData[n:]
+
+		length, n, err := readBigSize(extraData)
+		if err != nil {
+			return nil, err
+		}
+		extraData = extraData[n:]
+
+		if uint64(len(extraData)) < length {
+			return nil, ErrMalformedLiquidityAd
+		}
+		value := extraData[:length]
+		extraData = extraData[length:]
+
+		if recordType != liquidityAdRecordType {
+			continue
+		}
+
+		if len(value) < liquidityAdMinLen ||
+			len(value) > liquidityAdMaxLen {
+
+			return nil, fmt.Errorf("%v: invalid length %v",
+				ErrMalformedLiquidityAd, len(value))
+		}
+
+		ad := &LiquidityAd{
+			FundingWeight:             binary.BigEndian.Uint16(value[0:2]),
+			LeaseFeeBasis:             binary.BigEndian.Uint16(value[2:4]),
+			ChannelFeeMaxProportional: binary.BigEndian.Uint16(value[4:6]),
+			LeaseFeeBase:              binary.BigEndian.Uint32(value[6:10]),
+		}
+
+		var maxBase uint32
+		for _, c := range value[10:] {
+			maxBase = maxBase<<8 | uint32(c)
+		}
+		ad.ChannelFeeMaxBase = lnwire.MilliSatoshi(maxBase)
+
+		return ad, nil
+	}
+
+	return nil, nil
+}
+
+// liquidityAdCache lazily parses and caches the liquidity ads of the nodes
+// encountered during a single path finding run.
+type liquidityAdCache map[route.Vertex]bool
+
+// hasAd returns true if the given node advertises a liquidity ad. Malformed
+// ads are treated as if no ad was present.
+func (l liquidityAdCache) hasAd(node *channeldb.LightningNode) bool {
+	vertex := route.Vertex(node.PubKeyBytes)
+	if hasAd, ok := l[vertex]; ok {
+		return hasAd
+	}
+
+	ad, err := ParseLiquidityAd(node.ExtraOpaqueData)
+	hasAd := err == nil && ad != nil
+	l[vertex] = hasAd
+
+	return hasAd
+}
+
+// NodeLiquidityAd returns the liquidity ad advertised by the target node, if
+// any. If the node doesn't advertise liquidity, nil is returned.
+func (r *ChannelRouter) NodeLiquidityAd(node route.Vertex) (*LiquidityAd,
+	error) {
+
+	n, err := r.FetchLightningNode(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseLiquidityAd(n.ExtraOpaqueData)
+}
+
+// ChannelLiquidityAd returns the liquidity ad included in the announcement of
+// the target channel, if any. If the channel doesn't carry an ad, nil is
+// returned.
+func (r *ChannelRouter) ChannelLiquidityAd(
+	chanID lnwire.ShortChannelID) (*LiquidityAd, error) {
+
+	info, _, _, err := r.GetChannelByID(chanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseLiquidityAd(info.ExtraOpaqueData)
+}
+
+// ForEachLiquidityAd iterates over all nodes within the graph that advertise
+// inbound liquidity, invoking the passed callback for each of them. Nodes
+// with malformed ads are skipped.
+func (r *ChannelRouter) ForEachLiquidityAd(
+	cb func(route.Vertex, *LiquidityAd) error) error {
+
+	return r.ForEachNode(func(node *channeldb.LightningNode) error {
+		ad, err := ParseLiquidityAd(node.ExtraOpaqueData)
+		if err != nil {
+			log.Debugf("Skipping malformed liquidity ad of node "+
+				"%x: %v", node.PubKeyBytes, err)
+			return nil
+		}
+		if ad == nil {
+			return nil
+		}
+
+		return cb(route.Vertex(node.PubKeyBytes), ad)
+	})
+}
@@ -0,0 +1,76 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseLiquidityAd asserts that liquidity ads are correctly extracted from
+// the extra opaque data of an announcement.
+func TestParseLiquidityAd(t *testing.T) {
+	t.Parallel()
+
+	adValue := []byte{
+		0x00, 0x10, // funding weight
+		0x00, 0x20, // lease fee basis
+		0x00, 0x30, // channel fee max proportional
+		0x00, 0x00, 0x01, 0x00, // lease fee base
+		0x03, 0xe8, // truncated channel fee max base
+	}
+
+	testCases := []struct {
+		name      string
+		extraData []byte
+		expected  *LiquidityAd
+		expectErr bool
+	}{
+		{
+			name:      "empty",
+			extraData: nil,
+		},
+		{
+			name:      "unknown record only",
+			extraData: []byte{0x03, 0x01, 0xff},
+		},
+		{
+			name: "ad after unknown record",
+			extraData: append(
+				[]byte{0x00, 0x01, 0xff, 0x01, 12}, adValue...,
+			),
+			expected: &LiquidityAd{
+				FundingWeight:             0x10,
+				LeaseFeeBasis:             0x20,
+				ChannelFeeMaxProportional: 0x30,
+				LeaseFeeBase:              0x100,
+				ChannelFeeMaxBase:         1000,
+			},
+		},
+		{
+			name:      "truncated record",
+			extraData: []byte{0x01, 12, 0x00},
+			expectErr: true,
+		},
+		{
+			name:      "ad too short",
+			extraData: []byte{0x01, 0x02, 0x00, 0x01},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		ad, err := ParseLiquidityAd(test.extraData)
+		if test.expectErr {
+			if err == nil {
+				t.Fatalf("%v: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(ad, test.expected) {
+			t.Fatalf("%v: expected %v, got %v", test.name,
+				test.expected, ad)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/coreos/bbolt"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// LivenessCheck determines whether the destination of a payment is checked
+// for signs of being offline before the first attempt is made, and how a
+// destination that looks offline is handled.
+type LivenessCheck uint8
+
+const (
+	// LivenessCheckOff skips the liveness check.
+	LivenessCheckOff LivenessCheck = iota
+
+	// LivenessCheckWarn logs a warning if the destination looks offline,
+	// but attempts the payment anyway.
+	LivenessCheckWarn
+
+	// LivenessCheckFail fails the payment with ErrDestinationOffline if
+	// the destination looks offline, before any fee-bearing attempt is
+	// made.
+	LivenessCheckFail
+)
+
+// nodeLastSeen returns the most recent time at which the given node updated
+// its node announcement or the policy of one of its channels. False is
+// returned if the node isn't part of the graph.
+func (r *ChannelRouter) nodeLastSeen(node route.Vertex) (time.Time, bool,
+	error) {
+
+	lastSeen, exists, err := r.cfg.Graph.HasLightningNode(node)
+	if err != nil || !exists {
+		return time.Time{}, false, err
+	}
+
+	dbNode, err := r.FetchLightningNode(node)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	// Only the policies of the node's own direction of its channels are
+	// signed by the node.
+	err = dbNode.ForEachChannel(nil, func(_ *bbolt.Tx,
+		_ *channeldb.ChannelEdgeInfo,
+		outEdge, _ *channeldb.ChannelEdgePolicy) error {
+
+		if outEdge != nil && outEdge.LastUpdate.After(lastSeen) {
+			lastSeen = outEdge.LastUpdate
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return lastSeen, true, nil
+}
+
+// nodeLooksOnline returns true if the given node updated its announcements
+// within the channel prune expiry, or if it was successfully probed. Nodes
+// that aren't part of the graph can only be probed.
+func (r *ChannelRouter) nodeLooksOnline(node route.Vertex,
+	now time.Time) (bool, error) {
+
+	lastSeen, exists, err := r.nodeLastSeen(node)
+	if err != nil {
+		return false, err
+	}
+	if exists && now.Sub(lastSeen) <= r.cfg.ChannelPruneExpiry {
+		return true, nil
+	}
+
+	return r.cfg.ProbeNode != nil && r.cfg.ProbeNode(node), nil
+}
+
+// checkDestinationLiveness checks whether the destination of the payment looks
+// offline, based on how recently it updated its announcements. The liveness
+// of a destination that isn't part of the graph is derived from the nodes
+// that its route hints enter the graph at. If the destination looks offline,
+// a warning is logged or ErrDestinationOffline is returned, depending on the
+// liveness check of the payment.
+func (r *ChannelRouter) checkDestinationLiveness(payment *LightningPayment,
+	now time.Time) error {
+
+	if payment.LivenessCheck == LivenessCheckOff {
+		return nil
+	}
+
+	online, err := r.nodeLooksOnline(payment.Target, now)
+	if err != nil {
+		return err
+	}
+	if online {
+		return nil
+	}
+
+	_, exists, err := r.cfg.Graph.HasLightningNode(payment.Target)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		// Without route hints, there is nothing to base the check on.
+		// Path finding will report that the destination is unknown.
+		if len(payment.RouteHints) == 0 {
+			return nil
+		}
+
+		checked := make(map[route.Vertex]struct{})
+		for _, hint := range payment.RouteHints {
+			if len(hint) == 0 {
+				continue
+			}
+
+			entryNode := route.NewVertex(hint[0].NodeID)
+			if _, ok := checked[entryNode]; ok {
+				continue
+			}
+			checked[entryNode] = struct{}{}
+
+			online, err := r.nodeLooksOnline(entryNode, now)
+			if err != nil {
+				return err
+			}
+			if online {
+				return nil
+			}
+		}
+	}
+
+	if payment.LivenessCheck == LivenessCheckWarn {
+		log.Warnf("Destination %x of payment %x looks offline, "+
+			"attempting payment anyway", payment.Target[:],
+			payment.PaymentHash)
+
+		return nil
+	}
+
+	return newErrf(ErrDestinationOffline, "destination %x of payment %x "+
+		"looks offline", payment.Target[:], payment.PaymentHash)
+}
@@ -0,0 +1,61 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// TestDestinationLiveness asserts that payments to destinations that haven't
+// recently updated their announcements fail fast, unless a probe shows that
+// the destination is online.
+func TestDestinationLiveness(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	target := route.Vertex(ctx.aliases["sophon"])
+	payment := &LightningPayment{
+		Target:        target,
+		LivenessCheck: LivenessCheckFail,
+	}
+
+	// All announcements of the test graph were made at testTime, so the
+	// destination looks online shortly after.
+	err = ctx.router.checkDestinationLiveness(
+		payment, testTime.Add(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("expected destination to look online: %v", err)
+	}
+
+	// Once the prune expiry has passed, the destination looks offline.
+	now := testTime.Add(ctx.router.cfg.ChannelPruneExpiry + time.Hour)
+	err = ctx.router.checkDestinationLiveness(payment, now)
+	if !IsError(err, ErrDestinationOffline) {
+		t.Fatalf("expected ErrDestinationOffline, got %v", err)
+	}
+
+	// In warn mode, the payment is attempted anyway.
+	payment.LivenessCheck = LivenessCheckWarn
+	if err := ctx.router.checkDestinationLiveness(payment, now); err != nil {
+		t.Fatalf("expected no error in warn mode: %v", err)
+	}
+
+	// A successful probe overrides the stale announcements.
+	payment.LivenessCheck = LivenessCheckFail
+	ctx.router.cfg.ProbeNode = func(node route.Vertex) bool {
+		return node == target
+	}
+	if err := ctx.router.checkDestinationLiveness(payment, now); err != nil {
+		t.Fatalf("expected probed destination to look online: %v", err)
+	}
+}
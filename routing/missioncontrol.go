@@ -1,11 +1,11 @@
 package routing
 
 import (
-	"math"
 	"sync"
 	"time"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
 	"github.com/coreos/bbolt"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -18,8 +18,53 @@ const (
 	// half-life duration defines after how much time a penalized node or
 	// channel is back at 50% probability.
 	DefaultPenaltyHalfLife = time.Hour
+
+	// DefaultPermanentPenaltyHalfLife is the default half-life duration for
+	// failures that indicate that a node or channel is unlikely to become
+	// usable again soon.
+	DefaultPermanentPenaltyHalfLife = 24 * time.Hour
+
+	// DefaultPolicyPenaltyHalfLife is the default half-life duration for
+	// channels that repeatedly fail due to their routing policy, even after
+	// the policy has been updated.
+	DefaultPolicyPenaltyHalfLife = time.Minute
+)
+
+// FailureClass classifies the failures reported to mission control. Each class
+// decays with its own half-life, which controls how quickly a failed node or
+// channel becomes usable again.
+type FailureClass uint8
+
+const (
+	// FailureClassTemporary is the class of failures that are likely to be
+	// resolved soon, such as insufficient balance or an offline peer.
+	FailureClassTemporary FailureClass = iota
+
+	// FailureClassPermanent is the class of failures that are unlikely to
+	// be resolved soon, such as a closed channel or missing features.
+	FailureClassPermanent
+
+	// FailureClassPolicy is the class of failures caused by a channel's
+	// routing policy. The first policy failure of a channel within a
+	// payment isn't penalized at all, as the attached channel update allows
+	// an immediate retry.
+	FailureClassPolicy
 )
 
+// String returns a human-readable representation of the failure class.
+func (c FailureClass) String() string {
+	switch c {
+	case FailureClassTemporary:
+		return "temporary"
+	case FailureClassPermanent:
+		return "permanent"
+	case FailureClassPolicy:
+		return "policy"
+	default:
+		return "unknown"
+	}
+}
+
 // MissionControl contains state which summarizes the past attempts of HTLC
 // routing by external callers when sending payments throughout the network. It
 // acts as a shared memory during routing attempts with the goal to optimize the
@@ -38,12 +83,20 @@ type MissionControl struct {
 
 	queryBandwidth func(*channeldb.ChannelEdgeInfo) lnwire.MilliSatoshi
 
+	// amountFilters caches the graph filters that path finding uses to
+	// skip edges that can't carry large payments.
+	amountFilters *amountFilterCache
+
 	// now is expected to return the current time. It is supplied as an
 	// external function to enable deterministic unit tests.
 	now func() time.Time
 
 	cfg *MissionControlConfig
 
+	// penaltiesApplied is the total number of penalties that were applied
+	// since mission control was created.
+	penaltiesApplied uint64
+
 	sync.Mutex
 
 	// TODO(roasbeef): further counters, if vertex continually unavailable,
@@ -60,9 +113,19 @@ var _ PaymentSessionSource = (*MissionControl)(nil)
 // behaviour.
 type MissionControlConfig struct {
 	// PenaltyHalfLife defines after how much time a penalized node or
-	// channel is back at 50% probability.
+	// channel is back at 50% probability. It applies to temporary failures,
+	// and to the other failure classes if no specific half-life is set for
+	// them.
 	PenaltyHalfLife time.Duration
 
+	// PermanentPenaltyHalfLife defines the half-life of permanent failures.
+	// If zero, PenaltyHalfLife is used.
+	PermanentPenaltyHalfLife time.Duration
+
+	// PolicyPenaltyHalfLife defines the half-life of repeated policy
+	// failures. If zero, PenaltyHalfLife is used.
+	PolicyPenaltyHalfLife time.Duration
+
 	// PaymentAttemptPenalty is the virtual cost in path finding weight
 	// units of executing a payment attempt that fails. It is used to trade
 	// off potentially better routes against their probability of
@@ -76,6 +139,47 @@ type MissionControlConfig struct {
 	// AprioriHopProbability is the assumed success probability of a hop in
 	// a route when no other information is available.
 	AprioriHopProbability float64
+
+	// LiquidityAdBias is the fraction by which path finding reduces the
+	// weight of edges belonging to nodes that advertise inbound liquidity.
+	// A value of zero disables the bias.
+	LiquidityAdBias float64
+
+	// TieBreaker determines which route path finding chooses if multiple
+	// routes have the same cost.
+	TieBreaker TieBreaker
+
+	// FirstHopStrategy optionally selects the first hop of a route if
+	// multiple of our channels are able to carry the payment. If nil, the
+	// first hop is whatever path finding picks.
+	FirstHopStrategy FirstHopStrategy
+
+	// Estimator estimates the success probability of an edge from its
+	// history. If nil, an AprioriEstimator with AprioriHopProbability is
+	// used.
+	Estimator ProbabilityEstimator
+
+	// RecencyDecay optionally weights failures by how recently they
+	// occurred, so that frequently failing nodes and channels are
+	// penalized more. If nil, only the last failure is taken into account.
+	RecencyDecay *RecencyDecayConfig
+
+	// Annotations optionally provides the operator annotations of channels
+	// and nodes, so that payments can avoid channels and nodes with
+	// certain tags. If nil, tags to avoid are ignored.
+	Annotations AnnotationSource
+}
+
+// estimator returns the configured probability estimator, or an
+// AprioriEstimator if none is configured.
+func (c *MissionControlConfig) estimator() ProbabilityEstimator {
+	if c.Estimator != nil {
+		return c.Estimator
+	}
+
+	return &AprioriEstimator{
+		AprioriHopProbability: c.AprioriHopProbability,
+	}
 }
 
 // nodeHistory contains a summary of payment attempt outcomes involving a
@@ -84,9 +188,21 @@ type nodeHistory struct {
 	// lastFail is the last time a node level failure occurred, if any.
 	lastFail *time.Time
 
+	// lastFailClass is the class of the last node level failure.
+	lastFailClass FailureClass
+
 	// channelLastFail tracks history per channel, if available for that
 	// channel.
 	channelLastFail map[uint64]*channelHistory
+
+	// recentFails holds the times of the recent node level failures. It is
+	// only populated if recency decay is configured.
+	recentFails []time.Time
+
+	// channelRecentFails holds the times of the recent channel level
+	// failures per channel. It is only populated if recency decay is
+	// configured.
+	channelRecentFails map[uint64][]time.Time
 }
 
 // channelHistory contains a summary of payment attempt outcomes involving a
@@ -95,6 +211,9 @@ type channelHistory struct {
 	// lastFail is the last time a channel level failure occurred.
 	lastFail time.Time
 
+	// class is the class of the last channel level failure.
+	class FailureClass
+
 	// minPenalizeAmt is the minimum amount for which to take this failure
 	// into account.
 	minPenalizeAmt lnwire.MilliSatoshi
@@ -134,6 +253,9 @@ type MissionControlChannelSnapshot struct {
 	// LastFail is the time of last failure.
 	LastFail time.Time
 
+	// Class is the class of the last failure.
+	Class FailureClass
+
 	// MinPenalizeAmt is the minimum amount for which the channel will be
 	// penalized.
 	MinPenalizeAmt lnwire.MilliSatoshi
@@ -150,16 +272,21 @@ func NewMissionControl(g *channeldb.ChannelGraph, selfNode *channeldb.LightningN
 	cfg *MissionControlConfig) *MissionControl {
 
 	log.Debugf("Instantiating mission control with config: "+
-		"PenaltyHalfLife=%v, PaymentAttemptPenalty=%v, "+
-		"MinRouteProbability=%v, AprioriHopProbability=%v",
-		cfg.PenaltyHalfLife,
+		"PenaltyHalfLife=%v, PermanentPenaltyHalfLife=%v, "+
+		"PolicyPenaltyHalfLife=%v, PaymentAttemptPenalty=%v, "+
+		"MinRouteProbability=%v, AprioriHopProbability=%v, "+
+		"Estimator=%v",
+		cfg.PenaltyHalfLife, cfg.PermanentPenaltyHalfLife,
+		cfg.PolicyPenaltyHalfLife,
 		int64(cfg.PaymentAttemptPenalty.ToSatoshis()),
-		cfg.MinRouteProbability, cfg.AprioriHopProbability)
+		cfg.MinRouteProbability, cfg.AprioriHopProbability,
+		cfg.estimator())
 
 	return &MissionControl{
 		history:        make(map[route.Vertex]*nodeHistory),
 		selfNode:       selfNode,
 		queryBandwidth: qb,
+		amountFilters:  newAmountFilterCache(g),
 		graph:          g,
 		now:            time.Now,
 		cfg:            cfg,
@@ -175,6 +302,15 @@ func (m *MissionControl) NewPaymentSession(routeHints [][]zpay32.HopHint,
 
 	edges := make(map[route.Vertex][]*channeldb.ChannelEdgePolicy)
 
+	// Hints that lead through our own node, for example those of an
+	// invoice that rebalances our channels, are collapsed to the part
+	// after our own node.
+	if m.selfNode != nil {
+		routeHints = scrubRouteHints(
+			routeHints, route.Vertex(m.selfNode.PubKeyBytes),
+		)
+	}
+
 	// Traverse through all of the available hop hints and include them in
 	// our edges map, indexed by the public key of the channel's starting
 	// node.
@@ -235,9 +371,29 @@ func (m *MissionControl) NewPaymentSession(routeHints [][]zpay32.HopHint,
 		return nil, err
 	}
 
+	// In order to allow the payment session to attempt paying a direct
+	// peer without path finding, we'll collect our channels with the
+	// target.
+	directChans, err := fetchDirectChannels(sourceNode, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// If a first hop strategy is configured, we'll need the outgoing
+	// policies of all of our channels to select among them.
+	var localChans []*channeldb.ChannelEdgePolicy
+	if m.cfg.FirstHopStrategy != nil {
+		localChans, err = fetchLocalChannels(sourceNode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &paymentSession{
 		additionalEdges:      edges,
 		bandwidthHints:       bandwidthHints,
+		directChans:          directChans,
+		localChans:           localChans,
 		errFailedPolicyChans: make(map[nodeChannel]struct{}),
 		mc:                   m,
 		pathFinder:           findPath,
@@ -272,18 +428,25 @@ func (m *MissionControl) NewPaymentSessionEmpty() PaymentSession {
 // bandwidth hints for the edges we directly have open ourselves. Obtaining
 // these hints allows us to reduce the number of extraneous attempts as we can
 // skip channels that are inactive, or just don't have enough bandwidth to
-// carry the payment.
+// carry the payment. Channels that we have disabled ourselves are hinted with
+// zero bandwidth, even if their link is online.
 func generateBandwidthHints(sourceNode *channeldb.LightningNode,
 	queryBandwidth func(*channeldb.ChannelEdgeInfo) lnwire.MilliSatoshi) (map[uint64]lnwire.MilliSatoshi, error) {
 
 	// First, we'll collect the set of outbound edges from the target
 	// source node.
-	var localChans []*channeldb.ChannelEdgeInfo
+	var (
+		localChans       []*channeldb.ChannelEdgeInfo
+		disabledChannels = make(map[uint64]struct{})
+	)
 	err := sourceNode.ForEachChannel(nil, func(tx *bbolt.Tx,
 		edgeInfo *channeldb.ChannelEdgeInfo,
-		_, _ *channeldb.ChannelEdgePolicy) error {
+		outPolicy, _ *channeldb.ChannelEdgePolicy) error {
 
 		localChans = append(localChans, edgeInfo)
+		if isLocallyDisabled(outPolicy) {
+			disabledChannels[edgeInfo.ChannelID] = struct{}{}
+		}
 		return nil
 	})
 	if err != nil {
@@ -295,12 +458,86 @@ func generateBandwidthHints(sourceNode *channeldb.LightningNode,
 	// to date values.
 	bandwidthHints := make(map[uint64]lnwire.MilliSatoshi)
 	for _, localChan := range localChans {
+		if _, ok := disabledChannels[localChan.ChannelID]; ok {
+			bandwidthHints[localChan.ChannelID] = 0
+			continue
+		}
+
 		bandwidthHints[localChan.ChannelID] = queryBandwidth(localChan)
 	}
 
 	return bandwidthHints, nil
 }
 
+// isLocallyDisabled returns true if the given outgoing policy of one of our
+// own channels has the disabled bit set. Our policies are disabled by the
+// channel status manager while the peer is offline, and are re-enabled by it
+// through a new channel update once the peer has been back for a while. As
+// that update is applied to the graph, the channel becomes eligible again
+// without any further action of the router.
+func isLocallyDisabled(outPolicy *channeldb.ChannelEdgePolicy) bool {
+	return outPolicy != nil &&
+		outPolicy.ChannelFlags&lnwire.ChanUpdateDisabled != 0
+}
+
+// fetchDirectChannels returns the outgoing policies of all channels between
+// the source node and the target. Channels for which we don't know our own
+// policy, or that we have disabled ourselves, are skipped.
+func fetchDirectChannels(sourceNode *channeldb.LightningNode,
+	target route.Vertex) ([]*channeldb.ChannelEdgePolicy, error) {
+
+	var directChans []*channeldb.ChannelEdgePolicy
+	err := sourceNode.ForEachChannel(nil, func(tx *bbolt.Tx,
+		_ *channeldb.ChannelEdgeInfo,
+		outPolicy, _ *channeldb.ChannelEdgePolicy) error {
+
+		if outPolicy == nil || outPolicy.Node == nil {
+			return nil
+		}
+		if route.Vertex(outPolicy.Node.PubKeyBytes) != target {
+			return nil
+		}
+		if isLocallyDisabled(outPolicy) {
+			return nil
+		}
+
+		directChans = append(directChans, outPolicy)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return directChans, nil
+}
+
+// fetchLocalChannels returns the outgoing policies of all channels of the
+// source node. Channels that we have disabled ourselves are skipped.
+func fetchLocalChannels(sourceNode *channeldb.LightningNode) (
+	[]*channeldb.ChannelEdgePolicy, error) {
+
+	var localChans []*channeldb.ChannelEdgePolicy
+	err := sourceNode.ForEachChannel(nil, func(tx *bbolt.Tx,
+		_ *channeldb.ChannelEdgeInfo,
+		outPolicy, _ *channeldb.ChannelEdgePolicy) error {
+
+		if outPolicy == nil || outPolicy.Node == nil {
+			return nil
+		}
+		if isLocallyDisabled(outPolicy) {
+			return nil
+		}
+
+		localChans = append(localChans, outPolicy)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return localChans, nil
+}
+
 // ResetHistory resets the history of MissionControl returning it to a state as
 // if no payment attempts have been made.
 func (m *MissionControl) ResetHistory() {
@@ -313,42 +550,78 @@ func (m *MissionControl) ResetHistory() {
 }
 
 // getEdgeProbability is expected to return the success probability of a payment
-// from fromNode along edge.
+// from fromNode along edge. The capacity of the edge is zero if it is unknown.
 func (m *MissionControl) getEdgeProbability(fromNode route.Vertex,
-	edge EdgeLocator, amt lnwire.MilliSatoshi) float64 {
+	edge EdgeLocator, amt lnwire.MilliSatoshi,
+	capacity btcutil.Amount) float64 {
 
 	m.Lock()
 	defer m.Unlock()
 
 	// Get the history for this node. If there is no history available,
-	// assume that it's success probability is a constant a priori
-	// probability. After the attempt new information becomes available to
+	// the estimate is only based on the a priori knowledge of the
+	// estimator. After the attempt new information becomes available to
 	// adjust this probability.
 	nodeHistory, ok := m.history[fromNode]
 	if !ok {
-		return m.cfg.AprioriHopProbability
+		return m.cfg.estimator().EdgeProbability(
+			m.now(), &EdgeHistory{}, amt, capacity,
+		)
 	}
 
-	return m.getEdgeProbabilityForNode(nodeHistory, edge.ChannelID, amt)
+	return m.getEdgeProbabilityForNode(
+		nodeHistory, edge.ChannelID, amt, capacity,
+	)
 }
 
 // getEdgeProbabilityForNode estimates the probability of successfully
 // traversing a channel based on the node history.
 func (m *MissionControl) getEdgeProbabilityForNode(nodeHistory *nodeHistory,
-	channelID uint64, amt lnwire.MilliSatoshi) float64 {
+	channelID uint64, amt lnwire.MilliSatoshi,
+	capacity btcutil.Amount) float64 {
+
+	lastFailure, lastFailureClass, channelRecentFails :=
+		nodeHistory.lastChannelFailure(channelID, amt)
+
+	history := &EdgeHistory{
+		LastFailure:      lastFailure,
+		LastFailureClass: lastFailureClass,
+		HalfLife:         m.penaltyHalfLife(lastFailureClass),
+	}
+
+	now := m.now()
+	probability := m.cfg.estimator().EdgeProbability(
+		now, history, amt, capacity,
+	)
+
+	// Reduce the probability further for every recent failure, weighted
+	// by the recency bucket that it falls in.
+	recencyFactor := m.cfg.RecencyDecay.factor(
+		now, nodeHistory.recentFails, channelRecentFails,
+	)
+
+	return probability * recencyFactor
+}
+
+// lastChannelFailure returns the last failure that applies to sending the
+// given amount over the channel, along with its class and the recent channel
+// level failures. A node failure is considered a failure that would have
+// affected every channel of the node.
+func (h *nodeHistory) lastChannelFailure(channelID uint64,
+	amt lnwire.MilliSatoshi) (*time.Time, FailureClass, []time.Time) {
 
-	// Calculate the last failure of the given edge. A node failure is
-	// considered a failure that would have affected every edge. Therefore
-	// we insert a node level failure into the history of every channel.
-	lastFailure := nodeHistory.lastFail
+	lastFailure := h.lastFail
+	lastFailureClass := h.lastFailClass
 
 	// Take into account a minimum penalize amount. For balance errors, a
 	// failure may be reported with such a minimum to prevent too aggresive
 	// penalization. We only take into account a previous failure if the
 	// amount that we currently get the probability for is greater or equal
 	// than the minPenalizeAmt of the previous failure.
-	channelHistory, ok := nodeHistory.channelLastFail[channelID]
+	var channelRecentFails []time.Time
+	channelHistory, ok := h.channelLastFail[channelID]
 	if ok && channelHistory.minPenalizeAmt <= amt {
+		channelRecentFails = h.channelRecentFails[channelID]
 
 		// If there is both a node level failure recorded and a channel
 		// level failure is applicable too, we take the most recent of
@@ -357,23 +630,27 @@ func (m *MissionControl) getEdgeProbabilityForNode(nodeHistory *nodeHistory,
 			channelHistory.lastFail.After(*lastFailure) {
 
 			lastFailure = &channelHistory.lastFail
+			lastFailureClass = channelHistory.class
 		}
 	}
 
-	if lastFailure == nil {
-		return m.cfg.AprioriHopProbability
-	}
+	return lastFailure, lastFailureClass, channelRecentFails
+}
+
+// penaltyHalfLife returns the half-life of failures of the given class.
+func (m *MissionControl) penaltyHalfLife(class FailureClass) time.Duration {
+	switch {
+	case class == FailureClassPermanent &&
+		m.cfg.PermanentPenaltyHalfLife != 0:
 
-	timeSinceLastFailure := m.now().Sub(*lastFailure)
+		return m.cfg.PermanentPenaltyHalfLife
 
-	// Calculate success probability. It is an exponential curve that brings
-	// the probability down to zero when a failure occurs. From there it
-	// recovers asymptotically back to the a priori probability. The rate at
-	// which this happens is controlled by the penaltyHalfLife parameter.
-	exp := -timeSinceLastFailure.Hours() / m.cfg.PenaltyHalfLife.Hours()
-	probability := m.cfg.AprioriHopProbability * (1 - math.Pow(2, exp))
+	case class == FailureClassPolicy && m.cfg.PolicyPenaltyHalfLife != 0:
+		return m.cfg.PolicyPenaltyHalfLife
 
-	return probability
+	default:
+		return m.cfg.PenaltyHalfLife
+	}
 }
 
 // createHistoryIfNotExists returns the history for the given node. If the node
@@ -384,16 +661,20 @@ func (m *MissionControl) createHistoryIfNotExists(vertex route.Vertex) *nodeHist
 	}
 
 	node := &nodeHistory{
-		channelLastFail: make(map[uint64]*channelHistory),
+		channelLastFail:    make(map[uint64]*channelHistory),
+		channelRecentFails: make(map[uint64][]time.Time),
 	}
 	m.history[vertex] = node
 
 	return node
 }
 
-// reportVertexFailure reports a node level failure.
-func (m *MissionControl) reportVertexFailure(v route.Vertex) {
-	log.Debugf("Reporting vertex %v failure to Mission Control", v)
+// reportVertexFailure reports a node level failure of the given class.
+func (m *MissionControl) reportVertexFailure(v route.Vertex,
+	class FailureClass) {
+
+	log.Debugf("Reporting vertex %v %v failure to Mission Control", v,
+		class)
 
 	now := m.now()
 
@@ -402,16 +683,24 @@ func (m *MissionControl) reportVertexFailure(v route.Vertex) {
 
 	history := m.createHistoryIfNotExists(v)
 	history.lastFail = &now
+	history.lastFailClass = class
+	m.penaltiesApplied++
+
+	if m.cfg.RecencyDecay != nil {
+		history.recentFails = addRecentFailure(
+			history.recentFails, now,
+		)
+	}
 }
 
-// reportEdgeFailure reports a channel level failure.
+// reportEdgeFailure reports a channel level failure of the given class.
 //
 // TODO(roasbeef): also add value attempted to send and capacity of channel
 func (m *MissionControl) reportEdgeFailure(failedEdge edge,
-	minPenalizeAmt lnwire.MilliSatoshi) {
+	minPenalizeAmt lnwire.MilliSatoshi, class FailureClass) {
 
-	log.Debugf("Reporting channel %v failure to Mission Control",
-		failedEdge.channel)
+	log.Debugf("Reporting channel %v %v failure to Mission Control",
+		failedEdge.channel, class)
 
 	now := m.now()
 
@@ -421,8 +710,16 @@ func (m *MissionControl) reportEdgeFailure(failedEdge edge,
 	history := m.createHistoryIfNotExists(failedEdge.from)
 	history.channelLastFail[failedEdge.channel] = &channelHistory{
 		lastFail:       now,
+		class:          class,
 		minPenalizeAmt: minPenalizeAmt,
 	}
+	m.penaltiesApplied++
+
+	if m.cfg.RecencyDecay != nil {
+		history.channelRecentFails[failedEdge.channel] = addRecentFailure(
+			history.channelRecentFails[failedEdge.channel], now,
+		)
+	}
 }
 
 // GetHistorySnapshot takes a snapshot from the current mission control state
@@ -445,20 +742,21 @@ func (m *MissionControl) GetHistorySnapshot() *MissionControlSnapshot {
 			// Show probability assuming amount meets min
 			// penalization amount.
 			prob := m.getEdgeProbabilityForNode(
-				h, id, lastFail.minPenalizeAmt,
+				h, id, lastFail.minPenalizeAmt, 0,
 			)
 
 			channelSnapshot = append(channelSnapshot,
 				MissionControlChannelSnapshot{
 					ChannelID:      id,
 					LastFail:       lastFail.lastFail,
+					Class:          lastFail.class,
 					MinPenalizeAmt: lastFail.minPenalizeAmt,
 					SuccessProb:    prob,
 				},
 			)
 		}
 
-		otherProb := m.getEdgeProbabilityForNode(h, 0, 0)
+		otherProb := m.getEdgeProbabilityForNode(h, 0, 0, 0)
 
 		nodes = append(nodes,
 			MissionControlNodeSnapshot{
@@ -0,0 +1,102 @@
+package routing
+
+import (
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// MissionControlMode defines how a payment interacts with mission control.
+type MissionControlMode uint8
+
+const (
+	// MissionControlDefault lets path finding take the penalties of
+	// mission control into account, and reports the failures of the
+	// payment back to mission control.
+	MissionControlDefault MissionControlMode = iota
+
+	// MissionControlBypass ignores mission control entirely. Path finding
+	// starts with a fresh view of the network, in which only the failures
+	// of the payment itself are penalized. These failures aren't reported
+	// back to mission control.
+	MissionControlBypass
+
+	// MissionControlRecordOnly ignores the penalties of mission control
+	// like MissionControlBypass does, but still reports the failures of
+	// the payment back to mission control.
+	MissionControlRecordOnly
+)
+
+// String returns a human readable representation of the mode.
+func (m MissionControlMode) String() string {
+	switch m {
+	case MissionControlDefault:
+		return "default"
+
+	case MissionControlBypass:
+		return "bypass"
+
+	case MissionControlRecordOnly:
+		return "record-only"
+
+	default:
+		return "unknown"
+	}
+}
+
+// usePenalties returns true if path finding should take the penalties of
+// mission control into account.
+func (m MissionControlMode) usePenalties() bool {
+	return m == MissionControlDefault
+}
+
+// recordFailures returns true if failures should be reported back to mission
+// control.
+func (m MissionControlMode) recordFailures() bool {
+	return m != MissionControlBypass
+}
+
+// edgeProbability returns the success probability of the edge as seen by
+// this session. Unless the payment ignores mission control, it is the
+// probability that mission control estimates.
+func (p *paymentSession) edgeProbability(fromNode route.Vertex,
+	edge EdgeLocator, amt lnwire.MilliSatoshi,
+	capacity btcutil.Amount) float64 {
+
+	if p.mcMode.usePenalties() {
+		return p.mc.getEdgeProbability(fromNode, edge, amt, capacity)
+	}
+
+	// Otherwise only the failures that were reported during this session
+	// apply. Everything else is estimated as if no history is available.
+	if p.sessionFailed(fromNode, edge.ChannelID, amt) {
+		return 0
+	}
+
+	return p.mc.cfg.estimator().EdgeProbability(
+		p.mc.now(), &EdgeHistory{}, amt, capacity,
+	)
+}
+
+// sessionFailed returns true if a failure of the node or of its channel was
+// reported during this session that applies to the given amount.
+func (p *paymentSession) sessionFailed(node route.Vertex, channel uint64,
+	amt lnwire.MilliSatoshi) bool {
+
+	p.prunedMtx.Lock()
+	defer p.prunedMtx.Unlock()
+
+	if _, ok := p.prunedVertexIndex[node]; ok {
+		return true
+	}
+
+	i, ok := p.prunedEdgeIndex[nodeChannel{
+		node:    node,
+		channel: channel,
+	}]
+	if !ok {
+		return false
+	}
+
+	return p.prunedEdges[i].MinPenalizeAmt <= amt
+}
@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"time"
+)
+
+const (
+	// recencyHour, recencyDay and recencyWeek are the upper bounds of the
+	// age of the failures in the respective recency buckets.
+	recencyHour = time.Hour
+	recencyDay  = 24 * time.Hour
+	recencyWeek = 7 * 24 * time.Hour
+
+	// maxRecentFailures is the maximum number of recent failures that are
+	// retained per node and per channel. Once this limit is reached, the
+	// oldest failure is dropped.
+	maxRecentFailures = 100
+)
+
+// RecencyDecayConfig weights the failures of a node or channel by how recently
+// they occurred. The last failure of a node or channel already decays with the
+// configured half-life, which lets a temporary outage stop affecting the
+// probability soon after recovery. The recency buckets additionally take into
+// account how often failures occurred, so that nodes and channels that fail
+// chronically keep a lower probability.
+//
+// Each failure in a bucket reduces the success probability by the penalty of
+// that bucket. A penalty of zero disables a bucket.
+type RecencyDecayConfig struct {
+	// HourPenalty is the fraction by which each failure in the last hour
+	// reduces the success probability.
+	HourPenalty float64
+
+	// DayPenalty is the fraction by which each failure that is older than
+	// an hour, but occurred within the last day, reduces the success
+	// probability.
+	DayPenalty float64
+
+	// WeekPenalty is the fraction by which each failure that is older
+	// than a day, but occurred within the last week, reduces the success
+	// probability. Older failures aren't taken into account.
+	WeekPenalty float64
+}
+
+// penalty returns the penalty that applies to a failure of the given age.
+func (c *RecencyDecayConfig) penalty(age time.Duration) float64 {
+	switch {
+	case age < recencyHour:
+		return c.HourPenalty
+
+	case age < recencyDay:
+		return c.DayPenalty
+
+	case age < recencyWeek:
+		return c.WeekPenalty
+
+	default:
+		return 0
+	}
+}
+
+// factor returns the factor in [0, 1] by which the success probability is
+// reduced because of the given recent failures. A nil config always returns
+// one.
+func (c *RecencyDecayConfig) factor(now time.Time,
+	failures ...[]time.Time) float64 {
+
+	if c == nil {
+		return 1
+	}
+
+	factor := 1.0
+	for _, list := range failures {
+		for _, t := range list {
+			factor *= 1 - c.penalty(now.Sub(t))
+		}
+	}
+
+	return factor
+}
+
+// addRecentFailure appends a failure at the given time to the list of recent
+// failures. Failures that have aged out of all buckets are dropped, as well as
+// the oldest failures in excess of maxRecentFailures.
+func addRecentFailure(failures []time.Time, now time.Time) []time.Time {
+	var expired int
+	for expired < len(failures) &&
+		now.Sub(failures[expired]) >= recencyWeek {
+
+		expired++
+	}
+
+	if len(failures)-expired >= maxRecentFailures {
+		expired = len(failures) - maxRecentFailures + 1
+	}
+
+	// Copy the retained failures into a new slice, so that the memory of
+	// the dropped failures is released.
+	recent := make([]time.Time, 0, len(failures)-expired+1)
+	recent = append(recent, failures[expired:]...)
+
+	return append(recent, now)
+}
@@ -0,0 +1,84 @@
+package routing
+
+import (
+	"time"
+)
+
+// activePenaltyHalfLives is the number of half-lives after which a penalty is
+// no longer considered active for the purpose of penalty statistics. After
+// three half-lives, the probability of the penalized node or channel has
+// recovered to within 12.5% of its a priori value.
+const activePenaltyHalfLives = 3
+
+// MissionControlPenaltyStats summarizes the penalties that mission control
+// currently applies. It allows operators to detect when mission control has
+// penalized large parts of the graph and may need to be reset.
+type MissionControlPenaltyStats struct {
+	// PenalizedNodes is the number of nodes with an active node level
+	// penalty.
+	PenalizedNodes int
+
+	// PenalizedPairs is the number of node and channel pairs with an
+	// active channel level penalty.
+	PenalizedPairs int
+
+	// PenaltiesApplied is the total number of penalties that were applied
+	// since mission control was created. It isn't affected by a reset of
+	// the history and only ever increases, so that the rate at which
+	// penalties are applied can be derived from it.
+	PenaltiesApplied uint64
+
+	// AveragePenaltyAge is the average age of all active penalties. It is
+	// zero if there are none.
+	AveragePenaltyAge time.Duration
+}
+
+// PenaltyStats returns statistics about the penalties that mission control
+// currently applies.
+func (m *MissionControl) PenaltyStats() *MissionControlPenaltyStats {
+	m.Lock()
+	defer m.Unlock()
+
+	now := m.now()
+	stats := &MissionControlPenaltyStats{
+		PenaltiesApplied: m.penaltiesApplied,
+	}
+
+	var (
+		totalAge    time.Duration
+		activeCount int64
+	)
+	for _, h := range m.history {
+		if h.lastFail != nil &&
+			m.penaltyActive(now, *h.lastFail, h.lastFailClass) {
+
+			stats.PenalizedNodes++
+			totalAge += now.Sub(*h.lastFail)
+			activeCount++
+		}
+
+		for _, c := range h.channelLastFail {
+			if !m.penaltyActive(now, c.lastFail, c.class) {
+				continue
+			}
+
+			stats.PenalizedPairs++
+			totalAge += now.Sub(c.lastFail)
+			activeCount++
+		}
+	}
+
+	if activeCount > 0 {
+		stats.AveragePenaltyAge = totalAge / time.Duration(activeCount)
+	}
+
+	return stats
+}
+
+// penaltyActive returns true if a failure of the given class that occurred at
+// lastFail is still considered an active penalty.
+func (m *MissionControl) penaltyActive(now, lastFail time.Time,
+	class FailureClass) bool {
+
+	return now.Sub(lastFail) < activePenaltyHalfLives*m.penaltyHalfLife(class)
+}
@@ -1,9 +1,11 @@
 package routing
 
 import (
+	"math"
 	"testing"
 	"time"
 
+	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing/route"
 )
@@ -32,7 +34,7 @@ func TestMissionControl(t *testing.T) {
 
 		p := mc.getEdgeProbability(
 			testNode, EdgeLocator{ChannelID: testEdge.channel},
-			amt,
+			amt, 0,
 		)
 		if p != expected {
 			t.Fatalf("unexpected probability %v", p)
@@ -43,7 +45,7 @@ func TestMissionControl(t *testing.T) {
 	expectP(1000, 0.8)
 
 	// Expect probability to be zero after reporting the edge as failed.
-	mc.reportEdgeFailure(testEdge, 1000)
+	mc.reportEdgeFailure(testEdge, 1000, FailureClassTemporary)
 	expectP(1000, 0)
 
 	// As we reported with a min penalization amt, a lower amt than reported
@@ -56,7 +58,7 @@ func TestMissionControl(t *testing.T) {
 
 	// Edge fails again, this time without a min penalization amt. The edge
 	// should be penalized regardless of amount.
-	mc.reportEdgeFailure(testEdge, 0)
+	mc.reportEdgeFailure(testEdge, 0, FailureClassTemporary)
 	expectP(1000, 0)
 	expectP(500, 0)
 
@@ -66,7 +68,7 @@ func TestMissionControl(t *testing.T) {
 
 	// A node level failure should bring probability of every channel back
 	// to zero.
-	mc.reportVertexFailure(testNode)
+	mc.reportVertexFailure(testNode, FailureClassTemporary)
 	expectP(1000, 0)
 
 	// Check whether history snapshot looks sane.
@@ -79,3 +81,266 @@ func TestMissionControl(t *testing.T) {
 		t.Fatal("unexpected number of channels")
 	}
 }
+
+// TestMissionControlPenaltyStats asserts that the penalty statistics count the
+// active penalties and their age, and that penalties expire after a number of
+// half-lives.
+func TestMissionControlPenaltyStats(t *testing.T) {
+	now := testTime
+
+	mc := NewMissionControl(
+		nil, nil, nil, &MissionControlConfig{
+			PenaltyHalfLife:       time.Hour,
+			AprioriHopProbability: 0.8,
+		},
+	)
+	mc.now = func() time.Time { return now }
+
+	expectStats := func(nodes, pairs int, applied uint64,
+		age time.Duration) {
+
+		t.Helper()
+
+		stats := mc.PenaltyStats()
+		expected := MissionControlPenaltyStats{
+			PenalizedNodes:    nodes,
+			PenalizedPairs:    pairs,
+			PenaltiesApplied:  applied,
+			AveragePenaltyAge: age,
+		}
+		if *stats != expected {
+			t.Fatalf("expected stats %+v, got %+v", expected,
+				*stats)
+		}
+	}
+
+	expectStats(0, 0, 0, 0)
+
+	node1 := route.Vertex{1}
+	node2 := route.Vertex{2}
+	mc.reportEdgeFailure(
+		edge{from: node1, channel: 1}, 0, FailureClassTemporary,
+	)
+
+	now = testTime.Add(time.Hour)
+	mc.reportVertexFailure(node2, FailureClassTemporary)
+	mc.reportEdgeFailure(
+		edge{from: node2, channel: 2}, 0, FailureClassTemporary,
+	)
+
+	// Two penalties have no age, the first one is an hour old.
+	expectStats(1, 2, 3, 20*time.Minute)
+
+	// After three half-lives, the first penalty is no longer active.
+	now = testTime.Add(3 * time.Hour)
+	expectStats(1, 1, 3, 2*time.Hour)
+
+	// A reset clears the active penalties, but not the total count.
+	mc.ResetHistory()
+	expectStats(0, 0, 3, 0)
+}
+
+// TestMissionControlFailureClasses asserts that failures decay with the
+// half-life of their failure class.
+func TestMissionControlFailureClasses(t *testing.T) {
+	now := testTime
+
+	mc := NewMissionControl(
+		nil, nil, nil, &MissionControlConfig{
+			PenaltyHalfLife:          time.Hour,
+			PermanentPenaltyHalfLife: 4 * time.Hour,
+			PolicyPenaltyHalfLife:    time.Minute,
+			AprioriHopProbability:    0.8,
+		},
+	)
+	mc.now = func() time.Time { return now }
+
+	testNode := route.Vertex{}
+	expectP := func(channel uint64, expected float64) {
+		t.Helper()
+
+		p := mc.getEdgeProbability(
+			testNode, EdgeLocator{ChannelID: channel}, 1000, 0,
+		)
+		if p != expected {
+			t.Fatalf("unexpected probability %v for channel %v, "+
+				"expected %v", p, channel, expected)
+		}
+	}
+
+	mc.reportEdgeFailure(edge{channel: 1}, 0, FailureClassTemporary)
+	mc.reportEdgeFailure(edge{channel: 2}, 0, FailureClassPermanent)
+	mc.reportEdgeFailure(edge{channel: 3}, 0, FailureClassPolicy)
+
+	// After a minute, only the policy failure should have decayed to half
+	// of the a priori probability.
+	now = testTime.Add(time.Minute)
+	expectP(3, 0.4)
+
+	// After an hour, the temporary failure should have decayed to half of
+	// the a priori probability, while the permanent failure is still
+	// heavily penalized.
+	now = testTime.Add(time.Hour)
+	expectP(1, 0.4)
+
+	now = testTime.Add(4 * time.Hour)
+	expectP(2, 0.4)
+
+	// If no half-life is configured for a class, the default penalty
+	// half-life should be used.
+	mc.cfg.PermanentPenaltyHalfLife = 0
+	now = testTime.Add(time.Hour)
+	expectP(2, 0.4)
+}
+
+// TestMissionControlRecencyDecay asserts that recent failures are weighted by
+// their recency bucket, so that a single outage stops affecting the
+// probability while repeated failures keep penalizing a channel.
+func TestMissionControlRecencyDecay(t *testing.T) {
+	now := testTime
+
+	mc := NewMissionControl(
+		nil, nil, nil, &MissionControlConfig{
+			PenaltyHalfLife:       time.Minute,
+			AprioriHopProbability: 1,
+			RecencyDecay: &RecencyDecayConfig{
+				HourPenalty: 0.5,
+				DayPenalty:  0.2,
+				WeekPenalty: 0.1,
+			},
+		},
+	)
+	mc.now = func() time.Time { return now }
+
+	testNode := route.Vertex{}
+	expectP := func(channel uint64, expected float64) {
+		t.Helper()
+
+		p := mc.getEdgeProbability(
+			testNode, EdgeLocator{ChannelID: channel}, 1000, 0,
+		)
+		if math.Abs(p-expected) > 1e-6 {
+			t.Fatalf("unexpected probability %v for channel %v, "+
+				"expected %v", p, channel, expected)
+		}
+	}
+
+	// Channel 1 fails once, while channel 2 fails repeatedly.
+	mc.reportEdgeFailure(edge{channel: 1}, 0, FailureClassTemporary)
+	for i := 0; i < 3; i++ {
+		mc.reportEdgeFailure(edge{channel: 2}, 0, FailureClassTemporary)
+	}
+
+	// The last failure has decayed after half an hour, but every failure
+	// in the hour bucket still halves the probability.
+	now = testTime.Add(30 * time.Minute)
+	expectP(1, 0.5)
+	expectP(2, 0.125)
+
+	// In the day bucket, every failure reduces the probability by 20%.
+	now = testTime.Add(2 * time.Hour)
+	expectP(1, 0.8)
+	expectP(2, 0.512)
+
+	// In the week bucket, every failure reduces the probability by 10%.
+	now = testTime.Add(48 * time.Hour)
+	expectP(1, 0.9)
+	expectP(2, 0.729)
+
+	// After a week, the failures are no longer taken into account.
+	now = testTime.Add(8 * 24 * time.Hour)
+	expectP(1, 1)
+	expectP(2, 1)
+
+	// Only a limited number of failures is retained, and failures that
+	// are older than a week are dropped.
+	var failures []time.Time
+	for i := 0; i < maxRecentFailures+10; i++ {
+		failures = addRecentFailure(failures, testTime)
+	}
+	if len(failures) != maxRecentFailures {
+		t.Fatalf("expected %v failures, got %v", maxRecentFailures,
+			len(failures))
+	}
+	failures = addRecentFailure(failures, now)
+	if len(failures) != 1 {
+		t.Fatalf("expected expired failures to be dropped, got %v",
+			len(failures))
+	}
+}
+
+// TestBandwidthHintsLocalDisable asserts that channels that we have disabled
+// ourselves are hinted with zero bandwidth, and become eligible again once our
+// policy is re-enabled.
+func TestBandwidthHintsLocalDisable(t *testing.T) {
+	t.Parallel()
+
+	graph, err := parseTestGraph(basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer graph.cleanUp()
+
+	sourceNode, err := graph.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+
+	const bandwidth = lnwire.MilliSatoshi(100000000)
+	queryBandwidth := func(*channeldb.ChannelEdgeInfo) lnwire.MilliSatoshi {
+		return bandwidth
+	}
+
+	// setDisabled updates both policies of the channel between roasbeef
+	// and phamnuwen, so that our own policy is updated regardless of the
+	// direction.
+	roasToPham := uint64(999991)
+	setDisabled := func(disabled bool) {
+		_, e1, e2, err := graph.graph.FetchChannelEdgesByID(roasToPham)
+		if err != nil {
+			t.Fatalf("unable to fetch edge: %v", err)
+		}
+		for _, e := range []*channeldb.ChannelEdgePolicy{e1, e2} {
+			e.ChannelFlags &^= lnwire.ChanUpdateDisabled
+			if disabled {
+				e.ChannelFlags |= lnwire.ChanUpdateDisabled
+			}
+			if err := graph.graph.UpdateEdgePolicy(e); err != nil {
+				t.Fatalf("unable to update edge: %v", err)
+			}
+		}
+	}
+
+	expectHint := func(expected lnwire.MilliSatoshi) {
+		t.Helper()
+
+		hints, err := generateBandwidthHints(sourceNode, queryBandwidth)
+		if err != nil {
+			t.Fatalf("unable to generate hints: %v", err)
+		}
+		if len(hints) < 2 {
+			t.Fatalf("expected hints for all local channels")
+		}
+		for chanID, hint := range hints {
+			switch {
+			case chanID == roasToPham && hint != expected:
+				t.Fatalf("expected hint %v, got %v", expected,
+					hint)
+
+			case chanID != roasToPham && hint != bandwidth:
+				t.Fatalf("expected hint %v for channel %v, "+
+					"got %v", bandwidth, chanID, hint)
+			}
+		}
+	}
+
+	expectHint(bandwidth)
+
+	setDisabled(true)
+	expectHint(0)
+
+	// Once the channel status manager re-enables the channel, its
+	// bandwidth is reported again.
+	setDisabled(false)
+	expectHint(bandwidth)
+}
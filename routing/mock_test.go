@@ -16,6 +16,7 @@ import (
 type mockPaymentAttemptDispatcher struct {
 	onPayment func(firstHop lnwire.ShortChannelID) ([32]byte, error)
 	results   map[uint64]*htlcswitch.PaymentResult
+	inFlight  map[uint64]struct{}
 }
 
 var _ PaymentAttemptDispatcher = (*mockPaymentAttemptDispatcher)(nil)
@@ -66,6 +67,28 @@ func (m *mockPaymentAttemptDispatcher) GetPaymentResult(paymentID uint64,
 
 }
 
+func (m *mockPaymentAttemptDispatcher) PaymentAttemptStatus(
+	paymentID uint64) (htlcswitch.AttemptStatus, error) {
+
+	if _, ok := m.inFlight[paymentID]; ok {
+		return htlcswitch.AttemptStatusInFlight, nil
+	}
+	if _, ok := m.results[paymentID]; ok {
+		return htlcswitch.AttemptStatusResolved, nil
+	}
+
+	return htlcswitch.AttemptStatusUnknown, nil
+}
+
+func (m *mockPaymentAttemptDispatcher) LocalPaymentIDs() []uint64 {
+	var paymentIDs []uint64
+	for paymentID := range m.inFlight {
+		paymentIDs = append(paymentIDs, paymentID)
+	}
+
+	return paymentIDs
+}
+
 func (m *mockPaymentAttemptDispatcher) setPaymentResult(
 	f func(firstHop lnwire.ShortChannelID) ([32]byte, error)) {
 
@@ -112,12 +135,30 @@ func (m *mockPaymentSession) RequestRoute(payment *LightningPayment,
 	return r, nil
 }
 
-func (m *mockPaymentSession) ReportVertexFailure(v route.Vertex) {}
+func (m *mockPaymentSession) ReportVertexFailure(v route.Vertex,
+	class FailureClass) {
+}
 
-func (m *mockPaymentSession) ReportEdgeFailure(failedEdge edge, minPenalizeAmt lnwire.MilliSatoshi) {}
+func (m *mockPaymentSession) ReportEdgeFailure(failedEdge edge,
+	minPenalizeAmt lnwire.MilliSatoshi, class FailureClass) {
+}
 
 func (m *mockPaymentSession) ReportEdgePolicyFailure(failedEdge edge) {}
 
+func (m *mockPaymentSession) PrunedState() ([]PrunedEdge, []PrunedVertex) {
+	return nil, nil
+}
+
+func (m *mockPaymentSession) ReportLocalChannelFailure(channel uint64) error {
+	return nil
+}
+
+func (m *mockPaymentSession) AdoptRoute(payment *LightningPayment,
+	rt *route.Route, height uint32) bool {
+
+	return false
+}
+
 type mockPayer struct {
 	sendResult       chan error
 	paymentResultErr chan error
@@ -155,6 +196,16 @@ func (m *mockPayer) GetPaymentResult(paymentID uint64, _ lntypes.Hash,
 	}
 }
 
+func (m *mockPayer) PaymentAttemptStatus(
+	paymentID uint64) (htlcswitch.AttemptStatus, error) {
+
+	return htlcswitch.AttemptStatusInFlight, nil
+}
+
+func (m *mockPayer) LocalPaymentIDs() []uint64 {
+	return nil
+}
+
 type initArgs struct {
 	c *channeldb.PaymentCreationInfo
 }
@@ -269,6 +320,21 @@ func (m *mockControlTower) Fail(phash lntypes.Hash,
 	return nil
 }
 
+func (m *mockControlTower) SetPaused(phash lntypes.Hash, paused bool) error {
+	m.Lock()
+	defer m.Unlock()
+
+	p, ok := m.inflights[phash]
+	if !ok {
+		return fmt.Errorf("not in flight")
+	}
+
+	p.Paused = paused
+	m.inflights[phash] = p
+
+	return nil
+}
+
 func (m *mockControlTower) FetchInFlightPayments() (
 	[]*channeldb.InFlightPayment, error) {
 
@@ -292,3 +358,21 @@ func (m *mockControlTower) SubscribePayment(paymentHash lntypes.Hash) (
 
 	return false, nil, errors.New("not implemented")
 }
+
+func (m *mockControlTower) DestinationStats(
+	target route.Vertex) *DestinationStats {
+
+	return nil
+}
+
+type mockAnnotationSource struct {
+	annotations *channeldb.AnnotationSet
+}
+
+var _ AnnotationSource = (*mockAnnotationSource)(nil)
+
+func (m *mockAnnotationSource) FetchAnnotations() (*channeldb.AnnotationSet,
+	error) {
+
+	return m.annotations, nil
+}
@@ -0,0 +1,151 @@
+package routing
+
+import (
+	"errors"
+	"image/color"
+	"net"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
+)
+
+// ErrNodeNotAnnounced is returned when the metadata of a node is requested
+// that is known from its channels, but hasn't sent a node announcement yet.
+var ErrNodeNotAnnounced = errors.New("node has not been announced")
+
+// NodeInfo is the metadata of a node, as contained in its most recent node
+// announcement.
+type NodeInfo struct {
+	// PubKey is the identity public key of the node.
+	PubKey route.Vertex
+
+	// Alias is the alias or nick name of the node.
+	Alias string
+
+	// Color is the color of the node.
+	Color color.RGBA
+
+	// Addresses are the addresses at which the node accepts incoming
+	// connections.
+	Addresses []net.Addr
+
+	// Features are the features that are supported by the node.
+	Features *lnwire.FeatureVector
+
+	// LastUpdate is the time of the node announcement.
+	LastUpdate time.Time
+}
+
+// NodeAddressUpdate is sent to the subscribers of node address updates when
+// an announcement changes the set of addresses of a node.
+type NodeAddressUpdate struct {
+	// Node is the node whose addresses changed.
+	Node route.Vertex
+
+	// Addresses are the new addresses of the node.
+	Addresses []net.Addr
+
+	// PrevAddresses are the addresses of the node before the update. It
+	// is empty for nodes that weren't announced before.
+	PrevAddresses []net.Addr
+}
+
+// FetchNodeInfo returns the metadata of the given node. If the node isn't
+// known, channeldb.ErrGraphNodeNotFound is returned. If it is known, but
+// hasn't been announced, ErrNodeNotAnnounced is returned.
+func (r *ChannelRouter) FetchNodeInfo(node route.Vertex) (*NodeInfo, error) {
+	pubKey, err := btcec.ParsePubKey(node[:], btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+
+	dbNode, err := r.cfg.Graph.FetchLightningNode(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dbNode.HaveNodeAnnouncement {
+		return nil, ErrNodeNotAnnounced
+	}
+
+	features := dbNode.Features
+	if features == nil {
+		features = lnwire.NewFeatureVector(nil, lnwire.GlobalFeatures)
+	}
+
+	return &NodeInfo{
+		PubKey:     node,
+		Alias:      dbNode.Alias,
+		Color:      dbNode.Color,
+		Addresses:  dbNode.Addresses,
+		Features:   features,
+		LastUpdate: dbNode.LastUpdate,
+	}, nil
+}
+
+// SubscribeNodeAddressUpdates returns a client that receives a
+// NodeAddressUpdate whenever the addresses of a node change.
+func (r *ChannelRouter) SubscribeNodeAddressUpdates() (*subscribe.Client,
+	error) {
+
+	return r.nodeAddrNtfns.Subscribe()
+}
+
+// announcedAddresses returns the addresses of the given node as currently
+// stored in the graph. If the node isn't known or hasn't been announced, nil
+// is returned.
+func (r *ChannelRouter) announcedAddresses(node route.Vertex) []net.Addr {
+	info, err := r.FetchNodeInfo(node)
+	if err != nil {
+		return nil
+	}
+
+	return info.Addresses
+}
+
+// notifyAddressChange notifies the subscribers of node address updates if the
+// addresses of the node differ from the previous ones.
+func (r *ChannelRouter) notifyAddressChange(node route.Vertex,
+	prevAddrs, addrs []net.Addr) {
+
+	if sameAddresses(prevAddrs, addrs) {
+		return
+	}
+
+	log.Debugf("Addresses of node %x changed from %v to %v", node,
+		prevAddrs, addrs)
+
+	err := r.nodeAddrNtfns.SendUpdate(&NodeAddressUpdate{
+		Node:          node,
+		Addresses:     addrs,
+		PrevAddresses: prevAddrs,
+	})
+	if err != nil {
+		log.Warnf("Unable to send node address update: %v", err)
+	}
+}
+
+// sameAddresses returns true if both lists contain the same set of addresses,
+// regardless of their order.
+func sameAddresses(a, b []net.Addr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	addrs := make(map[string]int, len(a))
+	for _, addr := range a {
+		addrs[addr.Network()+"|"+addr.String()]++
+	}
+	for _, addr := range b {
+		key := addr.Network() + "|" + addr.String()
+		if addrs[key] == 0 {
+			return false
+		}
+		addrs[key]--
+	}
+
+	return true
+}
@@ -0,0 +1,93 @@
+package routing
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// ErrNodeNotResolved is returned when no node in the graph matches the alias
+// or pubkey prefix that is to be resolved.
+var ErrNodeNotResolved = errors.New("no node matches the alias or pubkey " +
+	"prefix")
+
+// AmbiguousNodeError is returned when multiple nodes in the graph match the
+// alias or pubkey prefix that is to be resolved.
+type AmbiguousNodeError struct {
+	// Identifier is the alias or pubkey prefix that was to be resolved.
+	Identifier string
+
+	// Matches are the pubkeys of the nodes that match the identifier.
+	Matches []route.Vertex
+}
+
+// Error returns a human readable description of the ambiguity.
+func (e *AmbiguousNodeError) Error() string {
+	return fmt.Sprintf("%v matches %v nodes", e.Identifier, len(e.Matches))
+}
+
+// ResolveNode maps a node identifier to the pubkey of the node. The
+// identifier is either a full hex encoded pubkey, the alias of a node or a
+// prefix of the hex encoded pubkey of a node. A full pubkey is returned as is,
+// even if the node isn't part of the graph. Otherwise the graph is searched,
+// with aliases taking precedence over pubkey prefixes. If the identifier
+// matches multiple nodes, an AmbiguousNodeError is returned.
+func (r *ChannelRouter) ResolveNode(identifier string) (route.Vertex, error) {
+	if identifier == "" {
+		return route.Vertex{}, ErrNodeNotResolved
+	}
+
+	if len(identifier) == 2*len(route.Vertex{}) {
+		pubKey, err := hex.DecodeString(identifier)
+		if err == nil {
+			var vertex route.Vertex
+			copy(vertex[:], pubKey)
+			return vertex, nil
+		}
+	}
+
+	// Pubkey prefixes are only matched if the identifier consists of hex
+	// characters. Node aliases are matched case sensitively.
+	prefix := strings.ToLower(identifier)
+	matchPrefix := strings.TrimLeft(prefix, "0123456789abcdef") == ""
+
+	var aliasMatches, prefixMatches []route.Vertex
+	err := r.ForEachNode(func(node *channeldb.LightningNode) error {
+		if node.HaveNodeAnnouncement && node.Alias == identifier {
+			aliasMatches = append(aliasMatches, node.PubKeyBytes)
+		}
+
+		pubKey := hex.EncodeToString(node.PubKeyBytes[:])
+		if matchPrefix && strings.HasPrefix(pubKey, prefix) {
+			prefixMatches = append(prefixMatches, node.PubKeyBytes)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return route.Vertex{}, err
+	}
+
+	matches := aliasMatches
+	if len(matches) == 0 {
+		matches = prefixMatches
+	}
+
+	switch len(matches) {
+	case 0:
+		return route.Vertex{}, ErrNodeNotResolved
+
+	case 1:
+		return matches[0], nil
+
+	default:
+		return route.Vertex{}, &AmbiguousNodeError{
+			Identifier: identifier,
+			Matches:    matches,
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package routing
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// TestResolveNode tests that nodes can be resolved by their pubkey, alias or
+// pubkey prefix, and that ambiguous identifiers are rejected.
+func TestResolveNode(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	songoku := ctx.aliases["songoku"]
+	songokuHex := hex.EncodeToString(songoku[:])
+
+	// The full pubkey, the alias and a pubkey prefix all resolve to the
+	// node. Prefixes are matched case insensitively.
+	identifiers := []string{
+		songokuHex,
+		"songoku",
+		songokuHex[:len(songokuHex)-2],
+		strings.ToUpper(songokuHex[:20]),
+	}
+	for _, identifier := range identifiers {
+		node, err := ctx.router.ResolveNode(identifier)
+		if err != nil {
+			t.Fatalf("unable to resolve %v: %v", identifier, err)
+		}
+		if node != songoku {
+			t.Fatalf("expected %v to resolve to %v, got %v",
+				identifier, songoku, node)
+		}
+	}
+
+	// All compressed pubkeys start with a zero, so this prefix is
+	// ambiguous.
+	_, err = ctx.router.ResolveNode("0")
+	ambiguousErr, ok := err.(*AmbiguousNodeError)
+	if !ok {
+		t.Fatalf("expected AmbiguousNodeError, got %v", err)
+	}
+	if len(ambiguousErr.Matches) < 2 {
+		t.Fatalf("expected multiple matches, got %v",
+			ambiguousErr.Matches)
+	}
+
+	for _, identifier := range []string{"doge", ""} {
+		_, err = ctx.router.ResolveNode(identifier)
+		if err != ErrNodeNotResolved {
+			t.Fatalf("expected ErrNodeNotResolved for %q, got %v",
+				identifier, err)
+		}
+	}
+}
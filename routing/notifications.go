@@ -14,8 +14,22 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/queue"
+	"github.com/lightningnetwork/lnd/routing/route"
 )
 
+// topologyHistorySize is the number of most recent topology changes that are
+// retained, such that clients can resume their subscription after a brief
+// disconnect.
+const topologyHistorySize = 1000
+
+// ErrTopologySeqUnavailable is returned when a client attempts to resume its
+// topology subscription from a sequence number for which the missed changes
+// are no longer, or were never, available. The client should fully resync its
+// view of the graph in this case.
+var ErrTopologySeqUnavailable = fmt.Errorf("topology changes since " +
+	"sequence number unavailable")
+
 // TopologyClient represents an intent to receive notifications from the
 // channel router regarding changes to the topology of the channel graph. The
 // TopologyChanges channel will be sent upon with new updates to the channel
@@ -49,6 +63,15 @@ type topologyClientUpdate struct {
 	// ntfnChan is a *send-only* channel in which notifications should be
 	// sent over from router -> client.
 	ntfnChan chan<- *TopologyChange
+
+	// resumeFrom, if non-nil, indicates that the client requests strictly
+	// ordered notifications, starting with all retained changes that have
+	// a sequence number greater than the one pointed to.
+	resumeFrom *uint64
+
+	// errChan, if non-nil, is used to report the outcome of the
+	// registration back to the client.
+	errChan chan error
 }
 
 // SubscribeTopology returns a new topology client which can be used by the
@@ -84,19 +107,76 @@ func (r *ChannelRouter) SubscribeTopology() (*TopologyClient, error) {
 
 	return &TopologyClient{
 		TopologyChanges: ntfnChan,
-		Cancel: func() {
-			select {
-			case r.ntfnClientUpdates <- &topologyClientUpdate{
-				cancel:   true,
-				clientID: clientID,
-			}:
-			case <-r.quit:
-				return
-			}
-		},
+		Cancel:          r.cancelTopologyClient(clientID),
+	}, nil
+}
+
+// SubscribeTopologyFrom returns a new topology client that receives topology
+// changes strictly ordered by their sequence number. The client first receives
+// all retained changes with a sequence number greater than seqNum, followed by
+// all subsequent changes. This allows a client that disconnected briefly to
+// resync only the changes it missed. A seqNum of zero requests all retained
+// changes. If the missed changes are no longer available,
+// ErrTopologySeqUnavailable is returned and the client should fully resync.
+func (r *ChannelRouter) SubscribeTopologyFrom(
+	seqNum uint64) (*TopologyClient, error) {
+
+	// If the router is not yet started, return an error to avoid a
+	// deadlock waiting for it to handle the subscription request.
+	if atomic.LoadUint32(&r.started) == 0 {
+		return nil, fmt.Errorf("router not started")
+	}
+
+	clientID := atomic.AddUint64(&r.ntfnClientCounter, 1)
+
+	log.Debugf("New ordered graph topology client subscription, client "+
+		"%v, resuming from sequence number %v", clientID, seqNum)
+
+	ntfnChan := make(chan *TopologyChange, 10)
+	errChan := make(chan error, 1)
+
+	select {
+	case r.ntfnClientUpdates <- &topologyClientUpdate{
+		cancel:     false,
+		clientID:   clientID,
+		ntfnChan:   ntfnChan,
+		resumeFrom: &seqNum,
+		errChan:    errChan,
+	}:
+	case <-r.quit:
+		return nil, errors.New("ChannelRouter shutting down")
+	}
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			return nil, err
+		}
+	case <-r.quit:
+		return nil, errors.New("ChannelRouter shutting down")
+	}
+
+	return &TopologyClient{
+		TopologyChanges: ntfnChan,
+		Cancel:          r.cancelTopologyClient(clientID),
 	}, nil
 }
 
+// cancelTopologyClient returns a closure that cancels the notifications of the
+// client with the given ID.
+func (r *ChannelRouter) cancelTopologyClient(clientID uint64) func() {
+	return func() {
+		select {
+		case r.ntfnClientUpdates <- &topologyClientUpdate{
+			cancel:   true,
+			clientID: clientID,
+		}:
+		case <-r.quit:
+			return
+		}
+	}
+}
+
 // topologyClient is a data-structure use by the channel router to couple the
 // client's notification channel along with a special "exit" channel that can
 // be used to cancel all lingering goroutines blocked on a send to the
@@ -111,17 +191,128 @@ type topologyClient struct {
 	// cancel any active un-consumed goroutine notifications.
 	exit chan struct{}
 
+	// ordered, if non-nil, is the queue through which notifications are
+	// delivered to a client that requested strict ordering.
+	ordered *queue.ConcurrentQueue
+
 	wg sync.WaitGroup
 }
 
+// deliverOrdered forwards the notifications of an ordered client from its
+// queue to its notification channel, preserving their order.
+//
+// NOTE: This MUST be run as a goroutine.
+func (r *ChannelRouter) deliverOrdered(c *topologyClient) {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case item := <-c.ordered.ChanOut():
+			select {
+			case c.ntfnChan <- item.(*TopologyChange):
+			case <-c.exit:
+				return
+			case <-r.quit:
+				return
+			}
+
+		case <-c.exit:
+			return
+
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// registerTopologyClient registers the client described by the passed update.
+// If the client requested strict ordering, the retained changes it missed are
+// queued for delivery first.
+func (r *ChannelRouter) registerTopologyClient(
+	ntfnUpdate *topologyClientUpdate) error {
+
+	r.Lock()
+	defer r.Unlock()
+
+	client := &topologyClient{
+		ntfnChan: ntfnUpdate.ntfnChan,
+		exit:     make(chan struct{}),
+	}
+
+	if ntfnUpdate.resumeFrom != nil {
+		missed, err := r.topologyChangesSince(*ntfnUpdate.resumeFrom)
+		if err != nil {
+			return err
+		}
+
+		// As the queue is unbounded, queueing the missed changes
+		// won't block.
+		client.ordered = queue.NewConcurrentQueue(10)
+		client.ordered.Start()
+		for _, change := range missed {
+			client.ordered.ChanIn() <- change
+		}
+
+		client.wg.Add(1)
+		go r.deliverOrdered(client)
+	}
+
+	r.topologyClients[ntfnUpdate.clientID] = client
+
+	return nil
+}
+
+// topologyChangesSince returns the retained topology changes with a sequence
+// number greater than seqNum. ErrTopologySeqUnavailable is returned if some of
+// these changes are no longer retained, or if seqNum lies in the future.
+//
+// NOTE: The router's lock MUST be held when calling this method.
+func (r *ChannelRouter) topologyChangesSince(
+	seqNum uint64) ([]*TopologyChange, error) {
+
+	switch {
+	// A sequence number beyond the latest one may have been issued by a
+	// previous instance of the router, so the client can't be resumed.
+	case seqNum > r.topologySeq:
+		return nil, ErrTopologySeqUnavailable
+
+	case seqNum == r.topologySeq:
+		return nil, nil
+	}
+
+	// The history always contains the latest change, so it can't be empty
+	// at this point.
+	oldest := r.topologyHistory[0].SeqNum
+	if seqNum+1 < oldest {
+		return nil, ErrTopologySeqUnavailable
+	}
+
+	missed := r.topologyHistory[seqNum+1-oldest:]
+	changes := make([]*TopologyChange, len(missed))
+	copy(changes, missed)
+
+	return changes, nil
+}
+
 // notifyTopologyChange notifies all registered clients of a new change in
 // graph topology in a non-blocking.
 func (r *ChannelRouter) notifyTopologyChange(topologyDiff *TopologyChange) {
-	r.RLock()
-	numClients := len(r.topologyClients)
-	r.RUnlock()
+	// Assign the next sequence number to the change and retain it, such
+	// that clients are able to resume from it. The lock is held while
+	// dispatching, which ensures that ordered clients receive the changes
+	// in sequence order.
+	r.Lock()
+	defer r.Unlock()
+
+	r.topologySeq++
+	topologyDiff.SeqNum = r.topologySeq
+
+	if len(r.topologyHistory) >= topologyHistorySize {
+		r.topologyHistory = r.topologyHistory[1:]
+	}
+	r.topologyHistory = append(r.topologyHistory, topologyDiff)
 
-	// Do not reacquire the lock twice unnecessarily.
+	numClients := len(r.topologyClients)
 	if numClients == 0 {
 		return
 	}
@@ -133,8 +324,19 @@ func (r *ChannelRouter) notifyTopologyChange(topologyDiff *TopologyChange) {
 		}),
 	)
 
-	r.RLock()
 	for _, client := range r.topologyClients {
+		// Notifications for ordered clients are queued, as the queue
+		// preserves their order. As the queue is unbounded, this won't
+		// block.
+		if client.ordered != nil {
+			select {
+			case client.ordered.ChanIn() <- topologyDiff:
+			case <-client.exit:
+			case <-r.quit:
+			}
+			continue
+		}
+
 		client.wg.Add(1)
 
 		go func(c *topologyClient) {
@@ -157,13 +359,17 @@ func (r *ChannelRouter) notifyTopologyChange(topologyDiff *TopologyChange) {
 			}
 		}(client)
 	}
-	r.RUnlock()
 }
 
 // TopologyChange represents a new set of modifications to the channel graph.
 // Topology changes will be dispatched in real-time as the ChannelGraph
 // validates and process modifications to the authenticated channel graph.
 type TopologyChange struct {
+	// SeqNum is the sequence number of this change. Sequence numbers are
+	// monotonically increasing, which allows clients to detect missed
+	// changes. They are reset when the router restarts.
+	SeqNum uint64
+
 	// NodeUpdates is a slice of nodes which are either new to the channel
 	// graph, or have had their attributes updated in an authenticated
 	// manner.
@@ -178,13 +384,65 @@ type TopologyChange struct {
 	// described which block a channel was closed at, and also carry
 	// supplemental information such as the capacity of the former channel.
 	ClosedChannels []*ClosedChanSummary
+
+	// NewChannels contains a slice of summaries of channels that were
+	// newly announced. The routing policies of these channels are sent
+	// out separately as ChannelEdgeUpdates, once they are known.
+	NewChannels []*NewChanSummary
+
+	// ChannelCapacityChanges contains a slice of summaries of channels
+	// whose funding output was replaced, for instance by splicing funds
+	// into or out of the channel. The channels remain open.
+	ChannelCapacityChanges []*ChannelCapacityChange
 }
 
 // isEmpty returns true if the TopologyChange is empty. A TopologyChange is
 // considered empty, if it contains no *new* updates of any type.
 func (t *TopologyChange) isEmpty() bool {
 	return len(t.NodeUpdates) == 0 && len(t.ChannelEdgeUpdates) == 0 &&
-		len(t.ClosedChannels) == 0
+		len(t.ClosedChannels) == 0 && len(t.NewChannels) == 0 &&
+		len(t.ChannelCapacityChanges) == 0
+}
+
+// ChannelCapacityChange is a summary of a channel whose funding output and
+// capacity changed, while the channel itself remained open.
+type ChannelCapacityChange struct {
+	// ChanID is the short-channel ID which uniquely identifies the
+	// channel. It doesn't change along with the funding output.
+	ChanID uint64
+
+	// PrevChanPoint is the funding point of the channel before the
+	// change.
+	PrevChanPoint wire.OutPoint
+
+	// ChanPoint is the new funding point of the channel.
+	ChanPoint wire.OutPoint
+
+	// PrevCapacity is the capacity of the channel before the change.
+	PrevCapacity btcutil.Amount
+
+	// Capacity is the new capacity of the channel.
+	Capacity btcutil.Amount
+}
+
+// NewChanSummary is a summary of a channel that was newly added to the channel
+// graph.
+type NewChanSummary struct {
+	// ChanID is the short-channel ID which uniquely identifies the
+	// channel.
+	ChanID uint64
+
+	// ChanPoint is the funding point of the channel.
+	ChanPoint wire.OutPoint
+
+	// Capacity is the total capacity of the channel.
+	Capacity btcutil.Amount
+
+	// Node1 is the node with the lexicographically smaller public key.
+	Node1 route.Vertex
+
+	// Node2 is the node with the lexicographically larger public key.
+	Node2 route.Vertex
 }
 
 // ClosedChanSummary is a summary of a channel that was detected as being
@@ -250,6 +508,11 @@ type NetworkNodeUpdate struct {
 
 	// Color is the node's color in hex code format.
 	Color string
+
+	// ExtraOpaqueData is the set of data that was appended to the node's
+	// announcement, but isn't understood by us. It allows future protocol
+	// features to be analyzed without a schema migration.
+	ExtraOpaqueData []byte
 }
 
 // ChannelEdgeUpdate is an update for a new channel within the ChannelGraph.
@@ -302,6 +565,11 @@ type ChannelEdgeUpdate struct {
 	// Disabled, if true, signals that the channel is unavailable to relay
 	// payments.
 	Disabled bool
+
+	// ExtraOpaqueData is the set of data that was appended to the channel
+	// update, but isn't understood by us. It allows future protocol
+	// features to be analyzed without a schema migration.
+	ExtraOpaqueData []byte
 }
 
 // appendTopologyChange appends the passed update message to the passed
@@ -322,19 +590,28 @@ func addToTopologyChange(graph *channeldb.ChannelGraph, update *TopologyChange,
 			return err
 		}
 		nodeUpdate := &NetworkNodeUpdate{
-			Addresses:   m.Addresses,
-			IdentityKey: pubKey,
-			Alias:       m.Alias,
-			Color:       EncodeHexColor(m.Color),
+			Addresses:       m.Addresses,
+			IdentityKey:     pubKey,
+			Alias:           m.Alias,
+			Color:           EncodeHexColor(m.Color),
+			ExtraOpaqueData: m.ExtraOpaqueData,
 		}
 		nodeUpdate.IdentityKey.Curve = nil
 
 		update.NodeUpdates = append(update.NodeUpdates, nodeUpdate)
 		return nil
 
-	// We ignore initial channel announcements as we'll only send out
-	// updates once the individual edges themselves have been updated.
+	// Initial channel announcements are only summarized, as the routing
+	// policies are sent out once the individual edges themselves have
+	// been updated.
 	case *channeldb.ChannelEdgeInfo:
+		update.NewChannels = append(update.NewChannels, &NewChanSummary{
+			ChanID:    m.ChannelID,
+			ChanPoint: m.ChannelPoint,
+			Capacity:  m.Capacity,
+			Node1:     m.NodeKey1Bytes,
+			Node2:     m.NodeKey2Bytes,
+		})
 		return nil
 
 	// Any new ChannelUpdateAnnouncements will generate a corresponding
@@ -379,6 +656,7 @@ func addToTopologyChange(graph *channeldb.ChannelGraph, update *TopologyChange,
 			AdvertisingNode: aNode,
 			ConnectingNode:  cNode,
 			Disabled:        m.ChannelFlags&lnwire.ChanUpdateDisabled != 0,
+			ExtraOpaqueData: m.ExtraOpaqueData,
 		}
 		edgeUpdate.AdvertisingNode.Curve = nil
 		edgeUpdate.ConnectingNode.Curve = nil
@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"bytes"
 	"fmt"
 	"image/color"
 	"net"
@@ -181,6 +182,13 @@ func (m *mockChain) addUtxo(op wire.OutPoint, out *wire.TxOut) {
 	m.utxos[op] = *out
 	m.Unlock()
 }
+
+func (m *mockChain) delUtxo(op wire.OutPoint) {
+	m.Lock()
+	delete(m.utxos, op)
+	m.Unlock()
+}
+
 func (m *mockChain) GetUtxo(op *wire.OutPoint, _ []byte, _ uint32,
 	_ <-chan struct{}) (*wire.TxOut, error) {
 	m.RLock()
@@ -410,6 +418,10 @@ func TestEdgeUpdateNotification(t *testing.T) {
 	edge2 := randEdgePolicy(chanID, node2)
 	edge2.ChannelFlags = 1
 
+	// Unknown trailing data of the updates should be retained.
+	edge1.ExtraOpaqueData = []byte{0x01, 0x02, 0x03}
+	edge2.ExtraOpaqueData = []byte{0x04, 0x05}
+
 	if err := ctx.router.UpdateEdge(edge1); err != nil {
 		t.Fatalf("unable to add edge update: %v", err)
 	}
@@ -417,6 +429,21 @@ func TestEdgeUpdateNotification(t *testing.T) {
 		t.Fatalf("unable to add edge update: %v", err)
 	}
 
+	// The extra data should have been written to the graph along with the
+	// rest of the policies.
+	_, dbEdge1, dbEdge2, err := ctx.router.GetChannelByID(*chanID)
+	if err != nil {
+		t.Fatalf("unable to fetch channel: %v", err)
+	}
+	if !bytes.Equal(dbEdge1.ExtraOpaqueData, edge1.ExtraOpaqueData) {
+		t.Fatalf("stored extra data doesn't match: expected %x, "+
+			"got %x", edge1.ExtraOpaqueData, dbEdge1.ExtraOpaqueData)
+	}
+	if !bytes.Equal(dbEdge2.ExtraOpaqueData, edge2.ExtraOpaqueData) {
+		t.Fatalf("stored extra data doesn't match: expected %x, "+
+			"got %x", edge2.ExtraOpaqueData, dbEdge2.ExtraOpaqueData)
+	}
+
 	assertEdgeCorrect := func(t *testing.T, edgeUpdate *ChannelEdgeUpdate,
 		edgeAnn *channeldb.ChannelEdgePolicy) {
 		if edgeUpdate.ChanID != edgeAnn.ChannelID {
@@ -458,6 +485,11 @@ func TestEdgeUpdateNotification(t *testing.T) {
 				"expected %v, got %v", edgeAnn.TimeLockDelta,
 				edgeUpdate.TimeLockDelta)
 		}
+		if !bytes.Equal(edgeUpdate.ExtraOpaqueData, edgeAnn.ExtraOpaqueData) {
+			t.Fatalf("extra data of edge doesn't match: "+
+				"expected %x, got %x", edgeAnn.ExtraOpaqueData,
+				edgeUpdate.ExtraOpaqueData)
+		}
 	}
 
 	// Create lookup map for notifications we are intending to receive. Entries
@@ -573,6 +605,9 @@ func TestNodeUpdateNotification(t *testing.T) {
 		t.Fatalf("unable to create test node: %v", err)
 	}
 
+	// Unknown trailing data of the announcement should be retained.
+	node1.ExtraOpaqueData = []byte{0x01, 0x02, 0x03}
+
 	edge := &channeldb.ChannelEdgeInfo{
 		ChannelID:     chanID.ToUint64(),
 		NodeKey1Bytes: node1.PubKeyBytes,
@@ -632,6 +667,11 @@ func TestNodeUpdateNotification(t *testing.T) {
 			t.Fatalf("node color doesn't match: expected %v, got %v",
 				EncodeHexColor(ann.Color), nodeUpdate.Color)
 		}
+		if !bytes.Equal(nodeUpdate.ExtraOpaqueData, ann.ExtraOpaqueData) {
+			t.Fatalf("node extra data doesn't match: expected %x, "+
+				"got %x", ann.ExtraOpaqueData,
+				nodeUpdate.ExtraOpaqueData)
+		}
 	}
 
 	// Create lookup map for notifications we are intending to receive. Entries
@@ -931,6 +971,150 @@ func TestChannelCloseNotification(t *testing.T) {
 	}
 }
 
+// TestChannelCapacityChangeNotification tests that a change of the funding
+// output of a channel updates the channel in place, notifies topology clients
+// and isn't mistaken for a close once the old funding output is spent.
+func TestChannelCapacityChangeNotification(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxSingleNode(startingBlockHeight)
+	defer cleanUp()
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+
+	const chanValue = 10000
+	fundingTx, chanUtxo, chanID, err := createChannelEdge(ctx,
+		bitcoinKey1.SerializeCompressed(), bitcoinKey2.SerializeCompressed(),
+		chanValue, startingBlockHeight)
+	if err != nil {
+		t.Fatalf("unable create channel edge: %v", err)
+	}
+	fundingBlock := &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{fundingTx},
+	}
+	ctx.chain.addBlock(fundingBlock, chanID.BlockHeight, chanID.BlockHeight)
+
+	node1, err := createTestNode()
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+	node2, err := createTestNode()
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+
+	edge := &channeldb.ChannelEdgeInfo{
+		ChannelID:     chanID.ToUint64(),
+		NodeKey1Bytes: node1.PubKeyBytes,
+		NodeKey2Bytes: node2.PubKeyBytes,
+		AuthProof: &channeldb.ChannelAuthProof{
+			NodeSig1Bytes:    testSig.Serialize(),
+			NodeSig2Bytes:    testSig.Serialize(),
+			BitcoinSig1Bytes: testSig.Serialize(),
+			BitcoinSig2Bytes: testSig.Serialize(),
+		},
+	}
+	copy(edge.BitcoinKey1Bytes[:], bitcoinKey1.SerializeCompressed())
+	copy(edge.BitcoinKey2Bytes[:], bitcoinKey2.SerializeCompressed())
+	if err := ctx.router.AddEdge(edge); err != nil {
+		t.Fatalf("unable to add edge: %v", err)
+	}
+
+	ntfnClient, err := ctx.router.SubscribeTopology()
+	if err != nil {
+		t.Fatalf("unable to subscribe for channel notifications: %v", err)
+	}
+
+	// Create the splice tx, which spends the old funding output into a
+	// new one with a larger capacity.
+	const newChanValue = 25000
+	_, newFundingOut, err := input.GenFundingPkScript(
+		bitcoinKey1.SerializeCompressed(),
+		bitcoinKey2.SerializeCompressed(), newChanValue,
+	)
+	if err != nil {
+		t.Fatalf("unable to create funding output: %v", err)
+	}
+	spliceTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{
+			{
+				PreviousOutPoint: *chanUtxo,
+			},
+		},
+		TxOut: []*wire.TxOut{newFundingOut},
+	}
+	newChanPoint := wire.OutPoint{
+		Hash:  spliceTx.TxHash(),
+		Index: 0,
+	}
+	ctx.chain.addUtxo(newChanPoint, newFundingOut)
+
+	// A capacity that doesn't match the new funding output is rejected.
+	err = ctx.router.UpdateChannelCapacity(*chanID, newChanPoint, chanValue)
+	if err == nil {
+		t.Fatalf("expected capacity mismatch to be rejected")
+	}
+
+	err = ctx.router.UpdateChannelCapacity(
+		*chanID, newChanPoint, newChanValue,
+	)
+	if err != nil {
+		t.Fatalf("unable to update channel capacity: %v", err)
+	}
+
+	select {
+	case ntfn := <-ntfnClient.TopologyChanges:
+		if len(ntfn.ChannelCapacityChanges) != 1 {
+			t.Fatalf("expected one capacity change, got %v",
+				len(ntfn.ChannelCapacityChanges))
+		}
+
+		change := ntfn.ChannelCapacityChanges[0]
+		expected := ChannelCapacityChange{
+			ChanID:        chanID.ToUint64(),
+			PrevChanPoint: *chanUtxo,
+			ChanPoint:     newChanPoint,
+			PrevCapacity:  chanValue,
+			Capacity:      newChanValue,
+		}
+		if *change != expected {
+			t.Fatalf("expected capacity change %v, got %v",
+				expected, change)
+		}
+
+	case <-time.After(time.Second * 5):
+		t.Fatal("notification not sent")
+	}
+
+	// Confirm the splice tx, which spends the old funding output. This
+	// must not be interpreted as a close of the channel.
+	blockHeight := uint32(102)
+	newBlock := &wire.MsgBlock{
+		Transactions: []*wire.MsgTx{spliceTx},
+	}
+	ctx.chain.addBlock(newBlock, blockHeight, blockHeight)
+	ctx.chainView.notifyBlock(newBlock.Header.BlockHash(), blockHeight,
+		newBlock.Transactions)
+
+	select {
+	case ntfn := <-ntfnClient.TopologyChanges:
+		t.Fatalf("unexpected notification: %v", ntfn)
+
+	case <-time.After(time.Second):
+	}
+
+	info, _, _, err := ctx.router.GetChannelByID(*chanID)
+	if err != nil {
+		t.Fatalf("unable to fetch channel: %v", err)
+	}
+	if info.Capacity != newChanValue || info.ChannelPoint != newChanPoint {
+		t.Fatalf("channel not updated: capacity=%v, chan_point=%v",
+			info.Capacity, info.ChannelPoint)
+	}
+}
+
 // TestEncodeHexColor tests that the string used to represent a node color is
 // correctly encoded.
 func TestEncodeHexColor(t *testing.T) {
@@ -957,3 +1141,177 @@ func TestEncodeHexColor(t *testing.T) {
 		}
 	}
 }
+
+// TestTopologyClientResume asserts that topology changes are assigned
+// increasing sequence numbers, and that ordered clients can resume their
+// subscription from a sequence number to receive only the changes they
+// missed.
+func TestTopologyClientResume(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxSingleNode(startingBlockHeight)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	// Dispatch a number of changes before any client is subscribed.
+	const numChanges = 5
+	for i := uint64(1); i <= numChanges; i++ {
+		ctx.router.notifyTopologyChange(&TopologyChange{
+			ClosedChannels: []*ClosedChanSummary{{ChanID: i}},
+		})
+	}
+
+	expectChanges := func(client *TopologyClient, from, to uint64) {
+		t.Helper()
+
+		for seqNum := from; seqNum <= to; seqNum++ {
+			select {
+			case change := <-client.TopologyChanges:
+				if change.SeqNum != seqNum {
+					t.Fatalf("expected sequence number "+
+						"%v, got %v", seqNum,
+						change.SeqNum)
+				}
+				chanID := change.ClosedChannels[0].ChanID
+				if chanID != seqNum {
+					t.Fatalf("expected channel %v, got %v",
+						seqNum, chanID)
+				}
+			case <-time.After(time.Second * 5):
+				t.Fatalf("notification %v not received",
+					seqNum)
+			}
+		}
+	}
+
+	// A client resuming from the second change should first receive the
+	// changes it missed, and then new changes in order.
+	client, err := ctx.router.SubscribeTopologyFrom(2)
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+	defer client.Cancel()
+
+	expectChanges(client, 3, numChanges)
+
+	for i := uint64(numChanges + 1); i <= 2*numChanges; i++ {
+		ctx.router.notifyTopologyChange(&TopologyChange{
+			ClosedChannels: []*ClosedChanSummary{{ChanID: i}},
+		})
+	}
+	expectChanges(client, numChanges+1, 2*numChanges)
+
+	// Resuming from a sequence number that lies in the future isn't
+	// possible, as it was issued by another router instance.
+	_, err = ctx.router.SubscribeTopologyFrom(3 * numChanges)
+	if err != ErrTopologySeqUnavailable {
+		t.Fatalf("expected ErrTopologySeqUnavailable, got %v", err)
+	}
+
+	// Once changes are no longer retained, resuming should fail as well.
+	for i := 0; i < topologyHistorySize; i++ {
+		ctx.router.notifyTopologyChange(&TopologyChange{})
+	}
+	_, err = ctx.router.SubscribeTopologyFrom(1)
+	if err != ErrTopologySeqUnavailable {
+		t.Fatalf("expected ErrTopologySeqUnavailable, got %v", err)
+	}
+}
+
+// TestNodeAddressUpdates asserts that subscribers are notified when the
+// addresses of a node change, and that the node's metadata can be queried.
+func TestNodeAddressUpdates(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxSingleNode(startingBlockHeight)
+	defer cleanUp()
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+
+	client, err := ctx.router.SubscribeNodeAddressUpdates()
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+	defer client.Cancel()
+
+	assertUpdate := func(node route.Vertex, prev, addrs []net.Addr) {
+		t.Helper()
+
+		select {
+		case ntfn := <-client.Updates():
+			update := ntfn.(*NodeAddressUpdate)
+			if update.Node != node {
+				t.Fatalf("expected update for %x, got %x",
+					node, update.Node)
+			}
+			if !sameAddresses(update.PrevAddresses, prev) {
+				t.Fatalf("expected previous addresses %v, "+
+					"got %v", prev, update.PrevAddresses)
+			}
+			if !sameAddresses(update.Addresses, addrs) {
+				t.Fatalf("expected addresses %v, got %v",
+					addrs, update.Addresses)
+			}
+
+		case <-time.After(time.Second * 5):
+			t.Fatal("address update not received")
+		}
+	}
+
+	node, err := createTestNode()
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+	vertex := route.Vertex(node.PubKeyBytes)
+
+	// Before the node is announced, no metadata is available.
+	if _, err := ctx.router.FetchNodeInfo(vertex); err == nil {
+		t.Fatalf("expected error for unknown node")
+	}
+
+	// The first announcement of the node adds its addresses.
+	if err := ctx.router.AddNode(node); err != nil {
+		t.Fatalf("unable to add node: %v", err)
+	}
+	assertUpdate(vertex, nil, testAddrs)
+
+	info, err := ctx.router.FetchNodeInfo(vertex)
+	if err != nil {
+		t.Fatalf("unable to fetch node info: %v", err)
+	}
+	if info.Alias != node.Alias || info.Color != node.Color ||
+		!sameAddresses(info.Addresses, testAddrs) {
+
+		t.Fatalf("unexpected node info: %v", info)
+	}
+	if info.Features == nil {
+		t.Fatalf("expected features to be set")
+	}
+
+	// A newer announcement with the same addresses doesn't trigger a
+	// notification.
+	sameAddrAnn := *node
+	sameAddrAnn.LastUpdate = node.LastUpdate.Add(time.Second)
+	sameAddrAnn.Alias = "renamed"
+	if err := ctx.router.AddNode(&sameAddrAnn); err != nil {
+		t.Fatalf("unable to add node: %v", err)
+	}
+
+	// An announcement with a new address does.
+	newAddrs := []net.Addr{&net.TCPAddr{
+		IP:   net.IP{0xA, 0x0, 0x0, 0x2},
+		Port: 9000,
+	}}
+	newAddrAnn := sameAddrAnn
+	newAddrAnn.LastUpdate = sameAddrAnn.LastUpdate.Add(time.Second)
+	newAddrAnn.Addresses = newAddrs
+	if err := ctx.router.AddNode(&newAddrAnn); err != nil {
+		t.Fatalf("unable to add node: %v", err)
+	}
+	assertUpdate(vertex, testAddrs, newAddrs)
+}
@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+// PacingConf holds the command line options for the pacing of payment
+// attempts. They are available in all builds.
+type PacingConf struct {
+	MinAttemptInterval time.Duration `long:"minattemptinterval" description:"The minimum time between consecutive attempts of a payment. (default: 0s)"`
+
+	AttemptIntervalJitter time.Duration `long:"attemptintervaljitter" description:"The upper bound of a random delay added to the interval between consecutive attempts of a payment. (default: 0s)"`
+}
+
+// attemptDelay returns how long the next attempt of a payment needs to wait,
+// given the time of its previous attempt. Consecutive attempts are spaced by
+// at least the configured minimum interval plus a random jitter, so that a
+// burst of failures doesn't result in a storm of attempts over the same peers,
+// and the timing of attempts is harder to correlate by intermediaries. The
+// first attempt of a payment isn't delayed.
+func (r *ChannelRouter) attemptDelay(lastAttempt, now time.Time) time.Duration {
+	if lastAttempt.IsZero() {
+		return 0
+	}
+
+	interval := r.cfg.MinAttemptInterval
+	if r.cfg.AttemptJitter > 0 {
+		interval += time.Duration(
+			rand.Int63n(int64(r.cfg.AttemptJitter)),
+		)
+	}
+
+	return lastAttempt.Add(interval).Sub(now)
+}
+
+// waitForPacing blocks until the next attempt of the payment may be made
+// according to the attempt pacing of the router. The payment is failed if
+// its attempt timeout expires in the meantime.
+func (p *paymentLifecycle) waitForPacing() error {
+	delay := p.router.attemptDelay(p.lastAttempt, time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	log.Debugf("[trace=%v] Delaying next attempt of payment %x by %v",
+		p.payment.TraceID, p.payment.PaymentHash, delay)
+
+	select {
+	case <-time.After(delay):
+		return nil
+
+	case <-p.timeoutChan:
+		return p.failTimeout()
+
+	case <-p.router.quit:
+		return ErrRouterShuttingDown
+	}
+}
+
+// failTimeout marks the payment as failed because its attempt timeout
+// expired, and returns the corresponding error.
+func (p *paymentLifecycle) failTimeout() error {
+	err := p.failPayment(channeldb.FailureReasonTimeout)
+	if err != nil {
+		return err
+	}
+
+	return newErr(ErrPaymentAttemptTimeout, "payment attempt not "+
+		"completed before timeout")
+}
@@ -0,0 +1,48 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAttemptDelay asserts that consecutive payment attempts are spaced by the
+// minimum interval plus a bounded jitter, and that the first attempt isn't
+// delayed.
+func TestAttemptDelay(t *testing.T) {
+	t.Parallel()
+
+	router := &ChannelRouter{
+		cfg: &Config{
+			MinAttemptInterval: time.Second,
+		},
+	}
+
+	now := time.Unix(1000, 0)
+
+	if delay := router.attemptDelay(time.Time{}, now); delay != 0 {
+		t.Fatalf("expected first attempt without delay, got %v", delay)
+	}
+
+	// The previous attempt was half a second ago, so the next one needs
+	// to wait for the remainder of the interval.
+	lastAttempt := now.Add(-500 * time.Millisecond)
+	delay := router.attemptDelay(lastAttempt, now)
+	if delay != 500*time.Millisecond {
+		t.Fatalf("expected delay of 500ms, got %v", delay)
+	}
+
+	// Once the interval has passed, no delay is needed.
+	lastAttempt = now.Add(-2 * time.Second)
+	if delay := router.attemptDelay(lastAttempt, now); delay > 0 {
+		t.Fatalf("expected no delay, got %v", delay)
+	}
+
+	// With jitter, the delay stays within the bounds.
+	router.cfg.AttemptJitter = time.Second
+	for i := 0; i < 100; i++ {
+		delay := router.attemptDelay(now, now)
+		if delay < time.Second || delay >= 2*time.Second {
+			t.Fatalf("delay %v out of bounds", delay)
+		}
+	}
+}
@@ -1,9 +1,10 @@
 package routing
 
 import (
-	"container/heap"
+	"fmt"
 	"math"
 
+	"github.com/btcsuite/btcutil"
 	"github.com/coreos/bbolt"
 
 	"github.com/lightningnetwork/lnd/channeldb"
@@ -239,29 +240,89 @@ type graphParams struct {
 	// set to the current available sending bandwidth for active local
 	// channels, and 0 for inactive channels.
 	bandwidthHints map[uint64]lnwire.MilliSatoshi
+
+	// amountFilter is an optional filter of the edges that are unable to
+	// carry the payment amount. These edges are skipped without being
+	// evaluated.
+	amountFilter *amountFilter
+
+	// annotations is an optional set of operator annotations of channels
+	// and nodes. It is only required if the restrictions contain tags to
+	// avoid.
+	annotations *channeldb.AnnotationSet
+
+	// selfNode is our own node. If set, paths that traverse our own node
+	// as an intermediate hop are excluded, unless the restrictions allow
+	// circular routes. This can only happen if the path finding source
+	// isn't our own node, or through route hints.
+	selfNode *route.Vertex
+}
+
+// withSnapshot runs f with the graph parameters bound to a single read
+// transaction. All path finding that f performs sees the same consistent view
+// of the graph, even while the router applies gossip updates concurrently. The
+// transaction is only kept open while f runs, as a long-lived read
+// transaction prevents the database from growing its memory map. If the
+// parameters are already bound to a transaction, that transaction is used.
+func (g *graphParams) withSnapshot(f func() error) error {
+	if g.tx != nil || g.graph == nil {
+		return f()
+	}
+
+	tx, err := g.graph.Database().Begin(false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		g.tx = nil
+		tx.Rollback()
+	}()
+
+	g.tx = tx
+
+	return f()
 }
 
 // RestrictParams wraps the set of restrictions passed to findPath that the
 // found path must adhere to.
 type RestrictParams struct {
 	// ProbabilitySource is a callback that is expected to return the
-	// success probability of traversing the channel from the node.
+	// success probability of traversing the channel from the node. The
+	// capacity of the channel is zero if it is unknown.
 	ProbabilitySource func(route.Vertex, EdgeLocator,
-		lnwire.MilliSatoshi) float64
+		lnwire.MilliSatoshi, btcutil.Amount) float64
 
 	// FeeLimit is a maximum fee amount allowed to be used on the path from
 	// the source to the target.
 	FeeLimit lnwire.MilliSatoshi
 
-	// OutgoingChannelID is the channel that needs to be taken to the first
-	// hop. If nil, any channel may be used.
-	OutgoingChannelID *uint64
+	// OutgoingChannelIDs is the set of channels of which one needs to be
+	// taken to the first hop. If empty, any channel may be used.
+	OutgoingChannelIDs []uint64
+
+	// LastHop is the node that needs to be the last hop before the target.
+	// If nil, any node may be the last hop.
+	LastHop *route.Vertex
 
 	// CltvLimit is the maximum time lock of the route excluding the final
 	// ctlv. After path finding is complete, the caller needs to increase
 	// all cltv expiry heights with the required final cltv delta.
 	CltvLimit *uint32
 
+	// IgnoredNodes is an optional set of nodes that must not be used as
+	// intermediate hops of the route.
+	IgnoredNodes map[route.Vertex]struct{}
+
+	// IgnoredPairs is an optional set of directed node pairs. For each
+	// pair, the channels from the first to the second node must not be
+	// used.
+	IgnoredPairs map[DirectedNodePair]struct{}
+
+	// MaxHops is the maximum number of hops of the route. It can be used
+	// to limit the length of routes below HopLimit, for example to reduce
+	// latency. If zero, HopLimit applies.
+	MaxHops uint32
+
 	// PaymentAttemptPenalty is the virtual cost in path finding weight
 	// units of executing a payment attempt that fails. It is used to trade
 	// off potentially better routes against their probability of
@@ -271,6 +332,55 @@ type RestrictParams struct {
 	// MinProbability defines the minimum success probability of the
 	// returned route.
 	MinProbability float64
+
+	// LiquidityAdBias is the fraction by which the weight of an edge is
+	// reduced if its source node advertises inbound liquidity through a
+	// liquidity ad. It should be within [0, 1). A value of zero disables
+	// the bias.
+	LiquidityAdBias float64
+
+	// HopHintBandwidths is an optional map from the channel ids of hop
+	// hint channels to their usable bandwidth, for example as reported by
+	// the node at the other end of a private channel. Without a hint, path
+	// finding assumes that a hop hint channel is able to carry the full
+	// amount.
+	HopHintBandwidths map[uint64]lnwire.MilliSatoshi
+
+	// AvoidTags is an optional list of annotation tags. Channels and
+	// intermediate nodes that are annotated with one of these tags, for
+	// example "tier3", are not used.
+	AvoidTags []string
+
+	// AllowCircularRoute allows paths to traverse our own node as an
+	// intermediate hop, which is only required for circular payments that
+	// rebalance our own channels.
+	AllowCircularRoute bool
+
+	// TieBreaker determines which path is chosen if multiple paths have
+	// the same cost.
+	TieBreaker TieBreaker
+
+	// HubPenalty is an optional penalty for routing through highly
+	// connected nodes.
+	HubPenalty *HubPenalty
+}
+
+// DirectedNodePair stores a directed pair of nodes.
+type DirectedNodePair struct {
+	From, To route.Vertex
+}
+
+// NewDirectedNodePair instantiates a new DirectedNodePair struct.
+func NewDirectedNodePair(from, to route.Vertex) DirectedNodePair {
+	return DirectedNodePair{
+		From: from,
+		To:   to,
+	}
+}
+
+// String converts a node pair to its human readable representation.
+func (d DirectedNodePair) String() string {
+	return fmt.Sprintf("%v -> %v", d.From, d.To)
 }
 
 // findPath attempts to find a path from the source node within the
@@ -297,44 +407,30 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 		defer tx.Rollback()
 	}
 
+	// The data structures of the search are taken from a pool, so that
+	// they don't need to be allocated for every search.
+	state := getPathFindingState()
+	defer state.release()
+
 	// First we'll initialize an empty heap which'll help us to quickly
 	// locate the next edge we should visit next during our graph
 	// traversal.
-	var nodeHeap distanceHeap
-
-	// For each node in the graph, we create an entry in the distance map
-	// for the node set with a distance of "infinity". graph.ForEachNode
-	// also returns the source node, so there is no need to add the source
-	// node explicitly.
-	distance := make(map[route.Vertex]nodeWithDist)
-	if err := g.graph.ForEachNode(tx, func(_ *bbolt.Tx,
-		node *channeldb.LightningNode) error {
-		// TODO(roasbeef): with larger graph can just use disk seeks
-		// with a visited map
-		distance[route.Vertex(node.PubKeyBytes)] = nodeWithDist{
-			dist: infinity,
-			node: node,
-		}
-		return nil
-	}); err != nil {
-		return nil, err
-	}
+	nodeHeap := &state.nodeHeap
+
+	// The distance map holds the nodes that have been reached. Nodes that
+	// are not part of it are at a distance of "infinity". This saves us
+	// from loading every node of the graph up front.
+	distance := state.distance
 
-	additionalEdgesWithSrc := make(map[route.Vertex][]*edgePolicyWithSource)
+	additionalEdgesWithSrc := state.additionalEdgesWithSrc
 	for vertex, outgoingEdgePolicies := range g.additionalEdges {
-		// We'll also include all the nodes found within the additional
-		// edges that are not known to us yet in the distance map.
 		node := &channeldb.LightningNode{PubKeyBytes: vertex}
-		distance[vertex] = nodeWithDist{
-			dist: infinity,
-			node: node,
-		}
 
 		// Build reverse lookup to find incoming edges. Needed because
 		// search is taken place from target to source.
 		for _, outgoingEdgePolicy := range outgoingEdgePolicies {
 			toVertex := outgoingEdgePolicy.Node.PubKeyBytes
-			incomingEdgePolicy := &edgePolicyWithSource{
+			incomingEdgePolicy := edgePolicyWithSource{
 				sourceNode: node,
 				edge:       outgoingEdgePolicy,
 			}
@@ -352,7 +448,8 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 	// point of the graph traversal. We are searching backwards to get the
 	// fees first time right and correctly match channel bandwidth.
 	targetNode := &channeldb.LightningNode{PubKeyBytes: target}
-	distance[target] = nodeWithDist{
+	targetLabel := pathLabel{node: target}
+	distance[targetLabel] = nodeWithDist{
 		dist:            0,
 		weight:          0,
 		node:            targetNode,
@@ -364,15 +461,77 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 	// We'll use this map as a series of "next" hop pointers. So to get
 	// from `Vertex` to the target node, we'll take the edge that it's
 	// mapped to within `next`.
-	next := make(map[route.Vertex]*channeldb.ChannelEdgePolicy)
+	next := state.next
+
+	// visited holds the nodes that have been explored already. Their path
+	// to the target is final.
+	visited := state.visited
+
+	// liquidityAds caches which nodes advertise liquidity, so that we only
+	// need to parse the announcement of each node once.
+	liquidityAds := state.liquidityAds
+
+	// hubs caches which nodes are hubs, so that the channels of each node
+	// only need to be counted once.
+	hubs := state.hubs
+
+	// If we have an outgoing channel restriction, we'll index the allowed
+	// channels for quick lookups.
+	var outgoingChans map[uint64]struct{}
+	if len(r.OutgoingChannelIDs) > 0 {
+		outgoingChans = make(map[uint64]struct{})
+		for _, chanID := range r.OutgoingChannelIDs {
+			outgoingChans[chanID] = struct{}{}
+		}
+	}
+
+	// maxHops is the maximum length of the route, which can't exceed the
+	// hop limit of the onion.
+	maxHops := HopLimit
+	if r.MaxHops != 0 && r.MaxHops < HopLimit {
+		maxHops = int(r.MaxHops)
+	}
+
+	// A plain search keeps a single best route per node. If the route
+	// length is restricted, the cheapest route from a node may be too
+	// long to be extended to the source, while a more expensive but
+	// shorter one could still be. In that case the search keeps the best
+	// route per node and number of hops, so that a shorter route isn't
+	// lost to a cheaper but longer one.
+	hopAware := maxHops < HopLimit
+	labelOf := func(v route.Vertex, hops int) pathLabel {
+		if !hopAware {
+			hops = 0
+		}
+		return pathLabel{node: v, hops: hops}
+	}
+
+	// onPath returns whether the node is part of the best known route
+	// from the given state to the target. The hop-aware search uses it
+	// to prevent routes that visit a node twice.
+	onPath := func(l pathLabel, v route.Vertex) bool {
+		for l.node != target {
+			if l.node == v {
+				return true
+			}
+			edge := next[l]
+			l = labelOf(route.Vertex(edge.Node.PubKeyBytes), l.hops-1)
+		}
+		return v == target
+	}
+
+	// avoided holds the channels and nodes that carry a tag that we were
+	// instructed to avoid.
+	avoided := newTagFilter(g.annotations, r.AvoidTags)
 
 	// processEdge is a helper closure that will be used to make sure edges
 	// satisfy our specific requirements.
 	processEdge := func(fromNode *channeldb.LightningNode,
-		edge *channeldb.ChannelEdgePolicy,
-		bandwidth lnwire.MilliSatoshi, toNode route.Vertex) {
+		edge *channeldb.ChannelEdgePolicy, capacity btcutil.Amount,
+		bandwidth lnwire.MilliSatoshi, toLabel pathLabel) {
 
 		fromVertex := route.Vertex(fromNode.PubKeyBytes)
+		toNode := toLabel.node
 
 		// If this is not a local channel and it is disabled, we will
 		// skip it.
@@ -387,23 +546,62 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 			return
 		}
 
+		// Don't route through our own node, unless it is the source of
+		// the path or a circular route was explicitly requested.
+		if !isSourceChan && g.selfNode != nil &&
+			fromVertex == *g.selfNode && !r.AllowCircularRoute {
+
+			return
+		}
+
 		// If we have an outgoing channel restriction and this is not
-		// the specified channel, skip it.
-		if isSourceChan && r.OutgoingChannelID != nil &&
-			*r.OutgoingChannelID != edge.ChannelID {
+		// one of the specified channels, skip it.
+		if isSourceChan && outgoingChans != nil {
+			if _, ok := outgoingChans[edge.ChannelID]; !ok {
+				return
+			}
+		}
+
+		// If we have a last hop restriction, only edges from the
+		// specified node may lead to the target.
+		if r.LastHop != nil && toNode == target &&
+			fromVertex != *r.LastHop {
+
+			return
+		}
+
+		// Skip intermediate nodes and node pairs that the caller
+		// explicitly asked to ignore.
+		if fromVertex != source {
+			if _, ok := r.IgnoredNodes[fromVertex]; ok {
+				return
+			}
+		}
+		if _, ok := r.IgnoredPairs[DirectedNodePair{
+			From: fromVertex,
+			To:   toNode,
+		}]; ok {
+
+			return
+		}
+
+		// Skip channels and intermediate nodes that are annotated with
+		// a tag to avoid.
+		if avoided.excludesChannel(edge.ChannelID) ||
+			(!isSourceChan && avoided.excludesNode(fromVertex)) {
 
 			return
 		}
 
 		// Calculate amount that the candidate node would have to sent
 		// out.
-		toNodeDist := distance[toNode]
+		toNodeDist := distance[toLabel]
 		amountToSend := toNodeDist.amountToReceive
 
 		// Request the success probability for this edge.
 		locator := newEdgeLocator(edge)
 		edgeProbability := r.ProbabilitySource(
-			fromVertex, *locator, amountToSend,
+			fromVertex, *locator, amountToSend, capacity,
 		)
 
 		log.Tracef("path finding probability: fromnode=%v, chanid=%v, "+
@@ -459,6 +657,22 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 			return
 		}
 
+		// Adding fromNode must not make the route longer than allowed.
+		// As each additional node only makes the route longer, this
+		// direction can be abandoned.
+		hops := toNodeDist.hops + 1
+		if hops > maxHops {
+			return
+		}
+
+		// Routes of the hop-aware search may reach a node more than
+		// once. Such routes are never needed, because skipping the
+		// loop yields a shorter route that is at least as cheap.
+		if hopAware && onPath(toLabel, fromVertex) {
+			return
+		}
+		fromLabel := labelOf(fromVertex, hops)
+
 		// amountToReceive is the amount that the node that is added to
 		// the distance map needs to receive from a (to be found)
 		// previous node in the route. That previous node will need to
@@ -491,6 +705,26 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 		// the HTLC that is handed out to fromNode.
 		weight := edgeWeight(amountToReceive, fee, timeLockDelta)
 
+		// If requested, favor edges of nodes that advertise inbound
+		// liquidity by discounting their weight.
+		if r.LiquidityAdBias > 0 && liquidityAds.hasAd(fromNode) {
+			weight -= int64(float64(weight) * r.LiquidityAdBias)
+		}
+
+		// If requested, add a penalty for routing through a hub to
+		// diversify the routes.
+		if r.HubPenalty != nil && fromVertex != source {
+			isHub, err := hubs.isHub(
+				tx, fromNode, r.HubPenalty.MinChannels,
+			)
+			if err != nil {
+				log.Debugf("Unable to count channels of %v: %v",
+					fromVertex, err)
+			} else if isHub {
+				weight += int64(r.HubPenalty.Penalty)
+			}
+		}
+
 		// Compute the tentative weight to this new channel/edge
 		// which is the weight from our toNode to the target node
 		// plus the weight of this edge.
@@ -502,13 +736,55 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 			tempWeight, probability, int64(r.PaymentAttemptPenalty),
 		)
 
+		// Keep track of the properties of the candidate route that the
+		// tie breaker may need.
+		pathCapacity := minCapacity(toNodeDist.minCapacity, capacity)
+
+		candidate := nodeWithDist{
+			dist:            tempDist,
+			weight:          tempWeight,
+			node:            fromNode,
+			amountToReceive: amountToReceive,
+			incomingCltv:    incomingCltv,
+			probability:     probability,
+			hops:            hops,
+			minCapacity:     pathCapacity,
+		}
+
 		// If the current best route is better than this candidate
-		// route, return. It is important to also return if the distance
-		// is equal, because otherwise the algorithm could run into an
-		// endless loop.
-		if tempDist >= distance[fromVertex].dist {
+		// route, return. If the distance is equal, the tie breaker
+		// decides. Ties are only broken for nodes that haven't been
+		// explored yet, and never in favor of the channel that the
+		// current route already takes. Otherwise the algorithm could
+		// run into an endless loop.
+		current, ok := distance[fromLabel]
+		if !ok {
+			current.dist = infinity
+		}
+		if tempDist > current.dist {
 			return
 		}
+		if tempDist == current.dist {
+			currentEdge := next[fromLabel]
+			if currentEdge == nil ||
+				currentEdge.ChannelID == edge.ChannelID {
+
+				return
+			}
+			if _, ok := visited[fromLabel]; ok {
+				return
+			}
+
+			current.ties++
+			distance[fromLabel] = current
+
+			if !r.TieBreaker.prefers(
+				&candidate, &current, edge, currentEdge,
+			) {
+				return
+			}
+			candidate.ties = current.ties
+		}
 
 		// Every edge should have a positive time lock delta. If we
 		// encounter a zero delta, log a warning line.
@@ -521,20 +797,13 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 		// better than the current best known distance to this node.
 		// The new better distance is recorded, and also our "next hop"
 		// map is populated with this edge.
-		distance[fromVertex] = nodeWithDist{
-			dist:            tempDist,
-			weight:          tempWeight,
-			node:            fromNode,
-			amountToReceive: amountToReceive,
-			incomingCltv:    incomingCltv,
-			probability:     probability,
-		}
+		distance[fromLabel] = candidate
 
-		next[fromVertex] = edge
+		next[fromLabel] = edge
 
 		// Add this new node to our heap as we'd like to further
 		// explore backwards through this edge.
-		heap.Push(&nodeHeap, distance[fromVertex])
+		nodeHeap.pushNode(candidate)
 	}
 
 	// TODO(roasbeef): also add path caching
@@ -542,25 +811,32 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 
 	// To start, our target node will the sole item within our distance
 	// heap.
-	heap.Push(&nodeHeap, distance[target])
+	nodeHeap.pushNode(distance[targetLabel])
+
+	// sourceLabel is the state of the source node from which the best
+	// route departs, once it has been found.
+	var sourceLabel *pathLabel
 
 	for nodeHeap.Len() != 0 {
 		// Fetch the node within the smallest distance from our source
 		// from the heap.
-		partialPath := heap.Pop(&nodeHeap).(nodeWithDist)
+		partialPath := nodeHeap.popNode()
 		bestNode := partialPath.node
+		pivot := route.Vertex(bestNode.PubKeyBytes)
+		pivotLabel := labelOf(pivot, partialPath.hops)
 
 		// If we've reached our source (or we don't have any incoming
 		// edges), then we're done here and can exit the graph
 		// traversal early.
-		if bestNode.PubKeyBytes == source {
+		if pivot == source {
+			sourceLabel = &pivotLabel
 			break
 		}
 
 		// Now that we've found the next potential step to take we'll
 		// examine all the incoming edges (channels) from this node to
 		// further our graph traversal.
-		pivot := route.Vertex(bestNode.PubKeyBytes)
+		visited[pivotLabel] = struct{}{}
 		err := bestNode.ForEachChannel(tx, func(tx *bbolt.Tx,
 			edgeInfo *channeldb.ChannelEdgeInfo,
 			_, inEdge *channeldb.ChannelEdgePolicy) error {
@@ -573,6 +849,13 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 				return nil
 			}
 
+			// Skip edges that are known to be unable to carry the
+			// payment amount, before fetching the node on the
+			// other end of the channel.
+			if g.amountFilter.excludes(inEdge) {
+				return nil
+			}
+
 			// We'll query the lower layer to see if we can obtain
 			// any more up to date information concerning the
 			// bandwidth of this edge.
@@ -604,7 +887,10 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 
 			// Check if this candidate node is better than what we
 			// already have.
-			processEdge(channelSource, inEdge, edgeBandwidth, pivot)
+			processEdge(
+				channelSource, inEdge, edgeInfo.Capacity,
+				edgeBandwidth, pivotLabel,
+			)
 			return nil
 		})
 		if err != nil {
@@ -612,20 +898,25 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 		}
 
 		// Then, we'll examine all the additional edges from the node
-		// we're currently visiting. Since we don't know the capacity
-		// of the private channel, we'll assume it was selected as a
+		// we're currently visiting. Unless a bandwidth hint is
+		// provided for the private channel, we don't know its
+		// capacity. In that case we'll assume it was selected as a
 		// routing hint due to having enough capacity for the payment
 		// and use the payment amount as its capacity.
-		bandWidth := partialPath.amountToReceive
 		for _, reverseEdge := range additionalEdgesWithSrc[bestNode.PubKeyBytes] {
+			bandWidth, ok := r.HopHintBandwidths[reverseEdge.edge.ChannelID]
+			if !ok {
+				bandWidth = partialPath.amountToReceive
+			}
+
 			processEdge(reverseEdge.sourceNode, reverseEdge.edge,
-				bandWidth, pivot)
+				0, bandWidth, pivotLabel)
 		}
 	}
 
-	// If the source node isn't found in the next hop map, then a path
-	// doesn't exist, so we terminate in an error.
-	if _, ok := next[source]; !ok {
+	// If the source node hasn't been reached, then a path doesn't exist,
+	// so we terminate in an error.
+	if sourceLabel == nil {
 		return nil, newErrf(ErrNoPathFound, "unable to find a path to "+
 			"destination")
 	}
@@ -633,16 +924,19 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 	// Use the nextHop map to unravel the forward path from source to
 	// target.
 	pathEdges := make([]*channeldb.ChannelEdgePolicy, 0, len(next))
-	currentNode := source
-	for currentNode != target { // TODO(roasbeef): assumes no cycles
+	currentLabel := *sourceLabel
+	for currentLabel.node != target { // TODO(roasbeef): assumes no cycles
 		// Determine the next hop forward using the next map.
-		nextNode := next[currentNode]
+		nextNode := next[currentLabel]
 
 		// Add the next hop to the list of path edges.
 		pathEdges = append(pathEdges, nextNode)
 
 		// Advance current node.
-		currentNode = route.Vertex(nextNode.Node.PubKeyBytes)
+		currentLabel = labelOf(
+			route.Vertex(nextNode.Node.PubKeyBytes),
+			currentLabel.hops-1,
+		)
 	}
 
 	// The route is invalid if it spans more than 20 hops. The current
@@ -650,14 +944,14 @@ func findPath(g *graphParams, r *RestrictParams, source, target route.Vertex,
 	// as the entire packet is fixed size. If this route is more than 20
 	// hops, then it's invalid.
 	numEdges := len(pathEdges)
-	if numEdges > HopLimit {
+	if numEdges > maxHops {
 		return nil, newErr(ErrMaxHopsExceeded, "potential path has "+
 			"too many hops")
 	}
 
 	log.Debugf("Found route: probability=%v, hops=%v, fee=%v\n",
-		distance[source].probability, numEdges,
-		distance[source].amountToReceive-amt)
+		distance[*sourceLabel].probability, numEdges,
+		distance[*sourceLabel].amountToReceive-amt)
 
 	return pathEdges, nil
 }
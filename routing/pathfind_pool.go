@@ -0,0 +1,100 @@
+package routing
+
+import (
+	"sync"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// pathLabel identifies a state of the search: a node together with the
+// number of hops of the route from that node to the target. Unless the search
+// is hop-aware, the number of hops is always zero and there is a single state
+// per node.
+type pathLabel struct {
+	node route.Vertex
+	hops int
+}
+
+// pathFindingState holds the data structures that findPath needs during a
+// single search. The structures are pooled and reused across searches, so
+// that nodes that find many paths don't allocate and grow them from scratch
+// for every search.
+type pathFindingState struct {
+	// distance holds the best known route to the target from each of the
+	// states that were reached so far. States that haven't been reached
+	// are at an infinite distance.
+	distance map[pathLabel]nodeWithDist
+
+	// next holds the first edge of the best known route to the target
+	// from each of the states that were reached so far.
+	next map[pathLabel]*channeldb.ChannelEdgePolicy
+
+	// visited holds the states that have been explored already.
+	visited map[pathLabel]struct{}
+
+	// additionalEdgesWithSrc indexes the additional edges by the node
+	// they lead to.
+	additionalEdgesWithSrc map[route.Vertex][]edgePolicyWithSource
+
+	// liquidityAds caches which nodes advertise liquidity.
+	liquidityAds liquidityAdCache
+
+	// hubs caches which nodes are hubs.
+	hubs hubCache
+
+	// nodeHeap holds the nodes that still need to be explored.
+	nodeHeap distanceHeap
+}
+
+// pathFindingStatePool holds the path finding states that are currently
+// unused.
+var pathFindingStatePool = sync.Pool{
+	New: func() interface{} {
+		return &pathFindingState{
+			distance: make(map[pathLabel]nodeWithDist),
+			next: make(
+				map[pathLabel]*channeldb.ChannelEdgePolicy,
+			),
+			visited: make(map[pathLabel]struct{}),
+			additionalEdgesWithSrc: make(
+				map[route.Vertex][]edgePolicyWithSource,
+			),
+			liquidityAds: make(liquidityAdCache),
+			hubs:         make(hubCache),
+		}
+	},
+}
+
+// getPathFindingState returns an empty path finding state from the pool.
+func getPathFindingState() *pathFindingState {
+	return pathFindingStatePool.Get().(*pathFindingState)
+}
+
+// release empties the state and returns it to the pool. The state must not be
+// used afterwards. Emptying the maps keeps their buckets allocated, and drops
+// all references to nodes and edges of the graph, so that they can be garbage
+// collected.
+func (s *pathFindingState) release() {
+	for v := range s.distance {
+		delete(s.distance, v)
+	}
+	for v := range s.next {
+		delete(s.next, v)
+	}
+	for v := range s.visited {
+		delete(s.visited, v)
+	}
+	for v := range s.additionalEdgesWithSrc {
+		delete(s.additionalEdgesWithSrc, v)
+	}
+	for v := range s.liquidityAds {
+		delete(s.liquidityAds, v)
+	}
+	for v := range s.hubs {
+		delete(s.hubs, v)
+	}
+	s.nodeHeap.reset()
+
+	pathFindingStatePool.Put(s)
+}
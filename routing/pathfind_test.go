@@ -75,7 +75,9 @@ var (
 
 // noProbabilitySource is used in testing to return the same probability 1 for
 // all edges.
-func noProbabilitySource(route.Vertex, EdgeLocator, lnwire.MilliSatoshi) float64 {
+func noProbabilitySource(route.Vertex, EdgeLocator, lnwire.MilliSatoshi,
+	btcutil.Amount) float64 {
+
 	return 1
 }
 
@@ -664,6 +666,118 @@ func TestFindLowestFeePath(t *testing.T) {
 	}
 }
 
+// TestTieBreaker tests that path finding chooses between routes of equal cost
+// according to the configured tie breaker.
+func TestTieBreaker(t *testing.T) {
+	t.Parallel()
+
+	// Set up a test graph with three routes from first to target that all
+	// charge the same fee. The direct route has the fewest hops, the route
+	// through a the largest capacity and the route through b the highest
+	// success probability.
+	policy := func(feeBase lnwire.MilliSatoshi) *testChannelPolicy {
+		return &testChannelPolicy{
+			Expiry:      144,
+			FeeBaseMsat: feeBase,
+			MinHTLC:     1,
+			MaxHTLC:     100000000,
+		}
+	}
+	testChannels := []*testChannel{
+		symmetricTestChannel("roasbeef", "first", 100000, policy(0), 1),
+		symmetricTestChannel("first", "target", 50000, policy(2000), 2),
+		symmetricTestChannel("first", "a", 300000, policy(1000), 3),
+		symmetricTestChannel("a", "target", 200000, policy(1000), 4),
+		symmetricTestChannel("first", "b", 100000, policy(1000), 5),
+		symmetricTestChannel("b", "target", 100000, policy(1000), 6),
+	}
+
+	testGraphInstance, err := createTestGraphFromChannels(testChannels)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer testGraphInstance.cleanUp()
+
+	sourceNode, err := testGraphInstance.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+	sourceVertex := route.Vertex(sourceNode.PubKeyBytes)
+
+	// The channels of the direct route and the route through a are
+	// less likely to succeed. Without an attempt penalty, this doesn't
+	// change the cost of the routes.
+	probabilitySource := func(_ route.Vertex, edge EdgeLocator,
+		_ lnwire.MilliSatoshi, _ btcutil.Amount) float64 {
+
+		switch edge.ChannelID {
+		case 2, 3, 4:
+			return 0.5
+		default:
+			return 1
+		}
+	}
+
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+	target := testGraphInstance.aliasMap["target"]
+
+	// findSecondHop returns the alias of the node after first on the route
+	// that path finding picks.
+	findSecondHop := func(tieBreaker TieBreaker) string {
+		t.Helper()
+
+		path, err := findPath(
+			&graphParams{
+				graph: testGraphInstance.graph,
+			},
+			&RestrictParams{
+				FeeLimit:          noFeeLimit,
+				ProbabilitySource: probabilitySource,
+				TieBreaker:        tieBreaker,
+			},
+			sourceNode.PubKeyBytes, target, paymentAmt,
+		)
+		if err != nil {
+			t.Fatalf("unable to find path: %v", err)
+		}
+		route, err := newRoute(
+			paymentAmt, sourceVertex, path, 100, 1,
+		)
+		if err != nil {
+			t.Fatalf("unable to create path: %v", err)
+		}
+
+		return getAliasFromPubKey(
+			route.Hops[1].PubKeyBytes, testGraphInstance.aliasMap,
+		)
+	}
+
+	tests := []struct {
+		tieBreaker TieBreaker
+		secondHop  string
+	}{
+		{TieBreakHops, "target"},
+		{TieBreakCapacity, "a"},
+		{TieBreakProbability, "b"},
+	}
+	for _, test := range tests {
+		secondHop := findSecondHop(test.tieBreaker)
+		if secondHop != test.secondHop {
+			t.Fatalf("%v: expected route through %v, got %v",
+				test.tieBreaker, test.secondHop, secondHop)
+		}
+	}
+
+	// Random tie breaking should not always pick the same route.
+	secondHops := make(map[string]struct{})
+	for i := 0; i < 30; i++ {
+		secondHops[findSecondHop(TieBreakRandom)] = struct{}{}
+	}
+	if len(secondHops) < 2 {
+		t.Fatalf("expected random routes, got %v", secondHops)
+	}
+}
+
 func getAliasFromPubKey(pubKey route.Vertex,
 	aliases map[string]route.Vertex) string {
 
@@ -960,6 +1074,40 @@ func TestPathFindingWithAdditionalEdges(t *testing.T) {
 	// The path should represent the following hops:
 	//	roasbeef -> songoku -> doge
 	assertExpectedPath(t, graph.aliasMap, path, "songoku", "doge")
+
+	// If a bandwidth hint for the private channel indicates that it can't
+	// carry the payment, no path should be found.
+	restrictions := *noRestrictions
+	restrictions.HopHintBandwidths = map[uint64]lnwire.MilliSatoshi{
+		songokuToDoge.ChannelID: paymentAmt - 1,
+	}
+	_, err = findPath(
+		&graphParams{
+			graph:           graph.graph,
+			additionalEdges: additionalEdges,
+		},
+		&restrictions,
+		sourceNode.PubKeyBytes, doge.PubKeyBytes, paymentAmt,
+	)
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
+	}
+
+	// With sufficient bandwidth, the private channel should be used
+	// again.
+	restrictions.HopHintBandwidths[songokuToDoge.ChannelID] = paymentAmt
+	path, err = findPath(
+		&graphParams{
+			graph:           graph.graph,
+			additionalEdges: additionalEdges,
+		},
+		&restrictions,
+		sourceNode.PubKeyBytes, doge.PubKeyBytes, paymentAmt,
+	)
+	if err != nil {
+		t.Fatalf("unable to find private path to doge: %v", err)
+	}
+	assertExpectedPath(t, graph.aliasMap, path, "songoku", "doge")
 }
 
 // TestNewRoute tests whether the construction of hop payloads by newRoute
@@ -1881,37 +2029,154 @@ func TestRestrictOutgoingChannel(t *testing.T) {
 
 	paymentAmt := lnwire.NewMSatFromSatoshis(100)
 	target := testGraphInstance.aliasMap["target"]
-	outgoingChannelID := uint64(2)
 
-	// Find the best path given the restriction to only use channel 2 as the
-	// outgoing channel.
-	path, err := findPath(
-		&graphParams{
-			graph: testGraphInstance.graph,
+	testCases := []struct {
+		outgoingChannelIDs []uint64
+		expectedChannel    uint64
+	}{
+		// Restricting the outgoing channels to channel 2 forces the
+		// highest cost path.
+		{
+			outgoingChannelIDs: []uint64{2},
+			expectedChannel:    2,
 		},
-		&RestrictParams{
-			FeeLimit:          noFeeLimit,
-			OutgoingChannelID: &outgoingChannelID,
-			ProbabilitySource: noProbabilitySource,
+
+		// Of channels 2 and 3, channel 3 is the cheapest.
+		{
+			outgoingChannelIDs: []uint64{2, 3},
+			expectedChannel:    3,
 		},
-		sourceVertex, target, paymentAmt,
-	)
+
+		// Without a restriction, the cheapest path is taken.
+		{
+			expectedChannel: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		// Find the best path given the restriction to only use the
+		// specified outgoing channels.
+		path, err := findPath(
+			&graphParams{
+				graph: testGraphInstance.graph,
+			},
+			&RestrictParams{
+				FeeLimit:           noFeeLimit,
+				OutgoingChannelIDs: test.outgoingChannelIDs,
+				ProbabilitySource:  noProbabilitySource,
+			},
+			sourceVertex, target, paymentAmt,
+		)
+		if err != nil {
+			t.Fatalf("unable to find path: %v", err)
+		}
+		route, err := newRoute(
+			paymentAmt, sourceVertex, path, startingHeight,
+			finalHopCLTV,
+		)
+		if err != nil {
+			t.Fatalf("unable to create path: %v", err)
+		}
+
+		// Assert that the route starts with the expected channel, in
+		// line with the specified restriction.
+		if route.Hops[0].ChannelID != test.expectedChannel {
+			t.Fatalf("expected route to pass through channel %v, "+
+				"but channel %v was selected instead",
+				test.expectedChannel, route.Hops[0].ChannelID)
+		}
+	}
+}
+
+// TestRestrictLastHop asserts that a last hop restriction is obeyed by the
+// path finding algorithm.
+func TestRestrictLastHop(t *testing.T) {
+	t.Parallel()
+
+	// Set up a test graph with two paths from roasbeef to target. The path
+	// through b is the highest cost path.
+	testChannels := []*testChannel{
+		symmetricTestChannel("roasbeef", "a", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 1),
+		symmetricTestChannel("a", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 2),
+		symmetricTestChannel("roasbeef", "b", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 800,
+			MinHTLC: 1,
+		}, 3),
+		symmetricTestChannel("b", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 800,
+			MinHTLC: 1,
+		}, 4),
+		symmetricTestChannel("b", "c", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 5),
+	}
+
+	testGraphInstance, err := createTestGraphFromChannels(testChannels)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer testGraphInstance.cleanUp()
+
+	sourceNode, err := testGraphInstance.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+	sourceVertex := route.Vertex(sourceNode.PubKeyBytes)
+
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+	target := testGraphInstance.aliasMap["target"]
+
+	findPathWithLastHop := func(alias string) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		lastHop := testGraphInstance.aliasMap[alias]
+		return findPath(
+			&graphParams{
+				graph: testGraphInstance.graph,
+			},
+			&RestrictParams{
+				FeeLimit:          noFeeLimit,
+				LastHop:           &lastHop,
+				ProbabilitySource: noProbabilitySource,
+			},
+			sourceVertex, target, paymentAmt,
+		)
+	}
+
+	// Restricting the last hop to b forces the more expensive path.
+	path, err := findPathWithLastHop("b")
 	if err != nil {
 		t.Fatalf("unable to find path: %v", err)
 	}
-	route, err := newRoute(
-		paymentAmt, sourceVertex, path, startingHeight,
-		finalHopCLTV,
-	)
+	if len(path) != 2 || path[1].ChannelID != 4 {
+		t.Fatalf("expected path through b")
+	}
+
+	// The cheapest path is found if it satisfies the restriction.
+	path, err = findPathWithLastHop("a")
 	if err != nil {
-		t.Fatalf("unable to create path: %v", err)
+		t.Fatalf("unable to find path: %v", err)
+	}
+	if len(path) != 2 || path[1].ChannelID != 2 {
+		t.Fatalf("expected path through a")
 	}
 
-	// Assert that the route starts with channel 2, in line with the
-	// specified restriction.
-	if route.Hops[0].ChannelID != 2 {
-		t.Fatalf("expected route to pass through channel 2, "+
-			"but channel %v was selected instead", route.Hops[0].ChannelID)
+	// No path exists if the last hop has no channel with the target.
+	_, err = findPathWithLastHop("c")
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
 	}
 }
 
@@ -2138,7 +2403,7 @@ func testProbabilityRouting(t *testing.T, p10, p11, p20, minProbability float64,
 
 	// Configure a probability source with the test parameters.
 	probabilitySource := func(node route.Vertex, edge EdgeLocator,
-		amt lnwire.MilliSatoshi) float64 {
+		amt lnwire.MilliSatoshi, _ btcutil.Amount) float64 {
 
 		if amt == 0 {
 			t.Fatal("expected non-zero amount")
@@ -2185,3 +2450,651 @@ func testProbabilityRouting(t *testing.T, p10, p11, p20, minProbability float64,
 			path[1].ChannelID)
 	}
 }
+
+// TestPathFindAvoidTags asserts that path finding doesn't use channels and
+// nodes that are annotated with a tag to avoid.
+func TestPathFindAvoidTags(t *testing.T) {
+	t.Parallel()
+
+	graph, err := parseTestGraph(basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer graph.cleanUp()
+
+	sourceNode, err := graph.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+
+	pham := graph.aliasMap["phamnuwen"]
+	annotations := &channeldb.AnnotationSet{
+		Channels: make(map[uint64]*channeldb.Annotation),
+		Nodes: map[route.Vertex]*channeldb.Annotation{
+			pham: {Tags: []string{"tier3"}},
+		},
+	}
+
+	// The route from roasbeef to sophon goes through phamnuwen.
+	target := graph.aliasMap["sophon"]
+	payAmt := lnwire.NewMSatFromSatoshis(105000)
+	path, err := findPath(
+		&graphParams{
+			graph:       graph.graph,
+			annotations: annotations,
+		},
+		noRestrictions,
+		sourceNode.PubKeyBytes, target, payAmt,
+	)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	if route.Vertex(path[0].Node.PubKeyBytes) != pham {
+		t.Fatalf("expected path through phamnuwen")
+	}
+
+	// When instructed to avoid nodes in tier 3, phamnuwen must not be
+	// used as an intermediate hop.
+	restrictions := *noRestrictions
+	restrictions.AvoidTags = []string{"tier3"}
+	path, err = findPath(
+		&graphParams{
+			graph:       graph.graph,
+			annotations: annotations,
+		},
+		&restrictions,
+		sourceNode.PubKeyBytes, target, payAmt,
+	)
+	switch {
+	case IsError(err, ErrNoPathFound):
+
+	case err != nil:
+		t.Fatalf("unable to find path: %v", err)
+
+	default:
+		for _, edge := range path {
+			if route.Vertex(edge.Node.PubKeyBytes) == pham {
+				t.Fatalf("path uses avoided node")
+			}
+		}
+	}
+
+	// Tagging a channel excludes it as well, so phamnuwen can only be
+	// reached through other nodes, if at all.
+	annotations.Nodes = nil
+	annotations.Channels[999991] = &channeldb.Annotation{
+		Tags: []string{"tier3"},
+	}
+	path, err = findPath(
+		&graphParams{
+			graph:       graph.graph,
+			annotations: annotations,
+		},
+		&restrictions,
+		sourceNode.PubKeyBytes, pham, payAmt,
+	)
+	if err == nil {
+		for _, edge := range path {
+			if edge.ChannelID == 999991 {
+				t.Fatalf("path uses avoided channel")
+			}
+		}
+	} else if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestPathFindExcludeSelf asserts that paths don't traverse our own node as an
+// intermediate hop, neither through public channels nor through route hints,
+// unless a circular route is explicitly allowed.
+func TestPathFindExcludeSelf(t *testing.T) {
+	t.Parallel()
+
+	graph, err := parseTestGraph(basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer graph.cleanUp()
+
+	self := graph.aliasMap["roasbeef"]
+	source := graph.aliasMap["luoji"]
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+
+	// All paths from luoji to songoku go through roasbeef. Without
+	// knowledge of our own node, path finding uses it.
+	target := graph.aliasMap["songoku"]
+	path, err := findPath(
+		&graphParams{
+			graph: graph.graph,
+		},
+		noRestrictions, source, target, paymentAmt,
+	)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	assertExpectedPath(t, graph.aliasMap, path, "roasbeef", "songoku")
+
+	// With our own node set, no path should be found.
+	_, err = findPath(
+		&graphParams{
+			graph:    graph.graph,
+			selfNode: &self,
+		},
+		noRestrictions, source, target, paymentAmt,
+	)
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
+	}
+
+	// Unless a circular route is explicitly requested.
+	restrictions := *noRestrictions
+	restrictions.AllowCircularRoute = true
+	path, err = findPath(
+		&graphParams{
+			graph:    graph.graph,
+			selfNode: &self,
+		},
+		&restrictions, source, target, paymentAmt,
+	)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	assertExpectedPath(t, graph.aliasMap, path, "roasbeef", "songoku")
+
+	// A route hint from our own node to a private node must not cause
+	// our node to be used as an intermediate hop either.
+	doge := &channeldb.LightningNode{PubKeyBytes: route.Vertex{2, 1}}
+	additionalEdges := map[route.Vertex][]*channeldb.ChannelEdgePolicy{
+		self: {{
+			Node:                      doge,
+			ChannelID:                 1337,
+			FeeBaseMSat:               1,
+			FeeProportionalMillionths: 1000,
+			TimeLockDelta:             9,
+		}},
+	}
+	_, err = findPath(
+		&graphParams{
+			graph:           graph.graph,
+			additionalEdges: additionalEdges,
+			selfNode:        &self,
+		},
+		noRestrictions, source, doge.PubKeyBytes, paymentAmt,
+	)
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
+	}
+
+	// A path that starts at our own node may still use the hint.
+	path, err = findPath(
+		&graphParams{
+			graph:           graph.graph,
+			additionalEdges: additionalEdges,
+			selfNode:        &self,
+		},
+		noRestrictions, self, doge.PubKeyBytes, paymentAmt,
+	)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	if len(path) != 1 || path[0].ChannelID != 1337 {
+		t.Fatalf("expected direct path over hint channel")
+	}
+}
+
+// benchmarkFindPath runs path finding from roasbeef to the target in the given
+// graph, reporting the allocations per search.
+func benchmarkFindPath(b *testing.B, graph *testGraphInstance, target string) {
+	sourceNode, err := graph.graph.SourceNode()
+	if err != nil {
+		b.Fatalf("unable to fetch source node: %v", err)
+	}
+
+	restrictions := &RestrictParams{
+		FeeLimit:          noFeeLimit,
+		ProbabilitySource: noProbabilitySource,
+	}
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := findPath(
+			&graphParams{
+				graph: graph.graph,
+			},
+			restrictions, sourceNode.PubKeyBytes,
+			graph.aliasMap[target], paymentAmt,
+		)
+		if err != nil {
+			b.Fatalf("unable to find path: %v", err)
+		}
+	}
+}
+
+// BenchmarkFindPathBasicGraph benchmarks path finding in the basic graph.
+func BenchmarkFindPathBasicGraph(b *testing.B) {
+	graph, err := parseTestGraph(basicGraphFilePath)
+	if err != nil {
+		b.Fatalf("unable to create graph: %v", err)
+	}
+	defer graph.cleanUp()
+
+	benchmarkFindPath(b, graph, "sophon")
+}
+
+// BenchmarkFindPathRingGraph benchmarks path finding in a ring of 100 nodes
+// with chords, in which most of the nodes are explored before the source is
+// reached.
+func BenchmarkFindPathRingGraph(b *testing.B) {
+	const numNodes = 100
+
+	policy := &testChannelPolicy{
+		Expiry:  144,
+		FeeRate: 400,
+		MinHTLC: 1,
+	}
+	alias := func(i int) string {
+		if i%numNodes == 0 {
+			return "roasbeef"
+		}
+		return fmt.Sprintf("node%v", i%numNodes)
+	}
+
+	var testChannels []*testChannel
+	for i := 0; i < numNodes; i++ {
+		testChannels = append(testChannels,
+			symmetricTestChannel(alias(i), alias(i+1), 100000, policy),
+			symmetricTestChannel(alias(i), alias(i+7), 100000, policy),
+		)
+	}
+
+	graph, err := createTestGraphFromChannels(testChannels)
+	if err != nil {
+		b.Fatalf("unable to create graph: %v", err)
+	}
+	defer graph.cleanUp()
+
+	benchmarkFindPath(b, graph, alias(numNodes/2))
+}
+
+// TestRestrictMaxHops asserts that a max hops restriction is obeyed by the
+// path finding algorithm.
+func TestRestrictMaxHops(t *testing.T) {
+	t.Parallel()
+
+	// Set up a test graph with a cheap path of three hops and an
+	// expensive path of two hops from roasbeef to target.
+	testChannels := []*testChannel{
+		symmetricTestChannel("roasbeef", "a", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 1),
+		symmetricTestChannel("a", "b", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 2),
+		symmetricTestChannel("b", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 3),
+		symmetricTestChannel("roasbeef", "c", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 4000,
+			MinHTLC: 1,
+		}, 4),
+		symmetricTestChannel("c", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 4000,
+			MinHTLC: 1,
+		}, 5),
+	}
+
+	testGraphInstance, err := createTestGraphFromChannels(testChannels)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer testGraphInstance.cleanUp()
+
+	sourceNode, err := testGraphInstance.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+	sourceVertex := route.Vertex(sourceNode.PubKeyBytes)
+
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+	target := testGraphInstance.aliasMap["target"]
+
+	findPathWithMaxHops := func(maxHops uint32) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		return findPath(
+			&graphParams{
+				graph: testGraphInstance.graph,
+			},
+			&RestrictParams{
+				FeeLimit:          noFeeLimit,
+				MaxHops:           maxHops,
+				ProbabilitySource: noProbabilitySource,
+			},
+			sourceVertex, target, paymentAmt,
+		)
+	}
+
+	testCases := []struct {
+		maxHops      uint32
+		expectedHops int
+	}{
+		// Without a restriction, the cheapest path is taken.
+		{maxHops: 0, expectedHops: 3},
+		{maxHops: 3, expectedHops: 3},
+
+		// A limit of two hops forces the expensive path.
+		{maxHops: 2, expectedHops: 2},
+
+		// No path of a single hop exists.
+		{maxHops: 1, expectedHops: 0},
+	}
+
+	for _, tc := range testCases {
+		path, err := findPathWithMaxHops(tc.maxHops)
+		if tc.expectedHops == 0 {
+			if !IsError(err, ErrNoPathFound) {
+				t.Fatalf("max hops %v: expected no path, got %v",
+					tc.maxHops, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("max hops %v: unable to find path: %v",
+				tc.maxHops, err)
+		}
+		if len(path) != tc.expectedHops {
+			t.Fatalf("max hops %v: expected %v hops, got %v",
+				tc.maxHops, tc.expectedHops, len(path))
+		}
+	}
+}
+
+// TestRestrictMaxHopsCheaperLongerPath asserts that a max hops restriction
+// doesn't hide a short route to a node of which the cheapest route to the
+// target is too long.
+func TestRestrictMaxHopsCheaperLongerPath(t *testing.T) {
+	t.Parallel()
+
+	// Set up a test graph in which the cheapest path from roasbeef to
+	// target is roasbeef->a->b->c->target. Node a also has a direct, but
+	// expensive, channel to target.
+	testChannels := []*testChannel{
+		symmetricTestChannel("roasbeef", "a", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 1),
+		symmetricTestChannel("a", "b", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 2),
+		symmetricTestChannel("b", "c", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 3),
+		symmetricTestChannel("c", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 4),
+		symmetricTestChannel("a", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 4000,
+			MinHTLC: 1,
+		}, 5),
+	}
+
+	testGraphInstance, err := createTestGraphFromChannels(testChannels)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer testGraphInstance.cleanUp()
+
+	sourceNode, err := testGraphInstance.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+	sourceVertex := route.Vertex(sourceNode.PubKeyBytes)
+
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+	target := testGraphInstance.aliasMap["target"]
+
+	findPathWithMaxHops := func(maxHops uint32) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		return findPath(
+			&graphParams{
+				graph: testGraphInstance.graph,
+			},
+			&RestrictParams{
+				FeeLimit:          noFeeLimit,
+				MaxHops:           maxHops,
+				ProbabilitySource: noProbabilitySource,
+			},
+			sourceVertex, target, paymentAmt,
+		)
+	}
+
+	// Without a restriction, the cheapest path of four hops is taken.
+	path, err := findPathWithMaxHops(0)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	assertExpectedPath(
+		t, testGraphInstance.aliasMap, path, "a", "b", "c", "target",
+	)
+
+	// With a limit of three hops, the cheapest route from a can't be
+	// used anymore. The expensive direct channel from a to target must
+	// be taken instead.
+	path, err = findPathWithMaxHops(3)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	assertExpectedPath(t, testGraphInstance.aliasMap, path, "a", "target")
+}
+
+// TestIgnoredNodesAndPairs asserts that path finding doesn't use ignored nodes
+// and node pairs.
+func TestIgnoredNodesAndPairs(t *testing.T) {
+	t.Parallel()
+
+	// Set up a test graph with three paths from roasbeef to target, of
+	// which the path through a is the cheapest and the path through c
+	// the most expensive.
+	testChannels := []*testChannel{
+		symmetricTestChannel("roasbeef", "a", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 1),
+		symmetricTestChannel("a", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 400,
+			MinHTLC: 1,
+		}, 2),
+		symmetricTestChannel("roasbeef", "b", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 800,
+			MinHTLC: 1,
+		}, 3),
+		symmetricTestChannel("b", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 800,
+			MinHTLC: 1,
+		}, 4),
+		symmetricTestChannel("roasbeef", "c", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 1600,
+			MinHTLC: 1,
+		}, 5),
+		symmetricTestChannel("c", "target", 100000, &testChannelPolicy{
+			Expiry:  144,
+			FeeRate: 1600,
+			MinHTLC: 1,
+		}, 6),
+	}
+
+	testGraphInstance, err := createTestGraphFromChannels(testChannels)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer testGraphInstance.cleanUp()
+
+	sourceNode, err := testGraphInstance.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+	sourceVertex := route.Vertex(sourceNode.PubKeyBytes)
+
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+	aliases := testGraphInstance.aliasMap
+	target := aliases["target"]
+
+	findPathWithIgnored := func(ignoredNodes map[route.Vertex]struct{},
+		ignoredPairs map[DirectedNodePair]struct{}) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		return findPath(
+			&graphParams{
+				graph: testGraphInstance.graph,
+			},
+			&RestrictParams{
+				FeeLimit:          noFeeLimit,
+				IgnoredNodes:      ignoredNodes,
+				IgnoredPairs:      ignoredPairs,
+				ProbabilitySource: noProbabilitySource,
+			},
+			sourceVertex, target, paymentAmt,
+		)
+	}
+
+	// Ignoring a forces the path through b. Ignoring the source node
+	// itself has no effect.
+	path, err := findPathWithIgnored(
+		map[route.Vertex]struct{}{
+			aliases["a"]: {},
+			sourceVertex: {},
+		}, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	if path[0].ChannelID != 3 {
+		t.Fatalf("expected path through b, got channel %v",
+			path[0].ChannelID)
+	}
+
+	// Ignoring the pair from b to target in addition forces the path
+	// through c.
+	path, err = findPathWithIgnored(
+		map[route.Vertex]struct{}{
+			aliases["a"]: {},
+		},
+		map[DirectedNodePair]struct{}{
+			NewDirectedNodePair(aliases["b"], target): {},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	if path[0].ChannelID != 5 {
+		t.Fatalf("expected path through c, got channel %v",
+			path[0].ChannelID)
+	}
+
+	// Ignoring the pair in the opposite direction has no effect.
+	path, err = findPathWithIgnored(
+		nil, map[DirectedNodePair]struct{}{
+			NewDirectedNodePair(aliases["a"], sourceVertex): {},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	if path[0].ChannelID != 1 {
+		t.Fatalf("expected path through a, got channel %v",
+			path[0].ChannelID)
+	}
+
+	// Ignoring all intermediate nodes leaves no path.
+	_, err = findPathWithIgnored(
+		map[route.Vertex]struct{}{
+			aliases["a"]: {},
+			aliases["b"]: {},
+			aliases["c"]: {},
+		}, nil,
+	)
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected no path, got %v", err)
+	}
+}
+
+// TestGraphParamsSnapshot asserts that path finding within a snapshot uses a
+// single read transaction, which is closed once the snapshot ends.
+func TestGraphParamsSnapshot(t *testing.T) {
+	t.Parallel()
+
+	testGraphInstance, err := parseTestGraph(basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create graph: %v", err)
+	}
+	defer testGraphInstance.cleanUp()
+
+	sourceNode, err := testGraphInstance.graph.SourceNode()
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+
+	g := &graphParams{
+		graph: testGraphInstance.graph,
+	}
+
+	err = g.withSnapshot(func() error {
+		tx := g.tx
+		if tx == nil {
+			t.Fatalf("expected snapshot transaction")
+		}
+
+		// A nested snapshot reuses the transaction.
+		err := g.withSnapshot(func() error {
+			if g.tx != tx {
+				t.Fatalf("expected transaction to be reused")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if g.tx != tx {
+			t.Fatalf("expected transaction to remain open")
+		}
+
+		_, err = findPath(
+			g, noRestrictions, sourceNode.PubKeyBytes,
+			testGraphInstance.aliasMap["sophon"],
+			lnwire.NewMSatFromSatoshis(100),
+		)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+
+	if g.tx != nil {
+		t.Fatalf("expected snapshot transaction to be closed")
+	}
+}
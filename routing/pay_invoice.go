@@ -0,0 +1,195 @@
+package routing
+
+import (
+	"errors"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// PayInvoiceOptions contains the optional parameters of a payment made with
+// PayInvoice. The zero value results in sensible defaults for all fields.
+type PayInvoiceOptions struct {
+	// Amount is the amount to pay. It must be set for invoices that don't
+	// specify an amount, and must be zero for those that do.
+	Amount lnwire.MilliSatoshi
+
+	// FeeLimit is the maximum fee that may be paid. If nil, the fee limit
+	// defaults to the payment amount, to prevent fees from exceeding the
+	// amount being paid.
+	FeeLimit *lnwire.MilliSatoshi
+
+	// CltvLimit is the maximum time lock that is allowed for attempts to
+	// complete this payment.
+	CltvLimit *uint32
+
+	// OutgoingChannelIDs is the set of channels of which one needs to be
+	// taken to the first hop. If empty, any channel may be used.
+	OutgoingChannelIDs []uint64
+
+	// LastHop is the node that needs to be the last hop before the
+	// destination of the invoice. If nil, any node may be the last hop.
+	LastHop *route.Vertex
+
+	// MaxHops is the maximum number of hops of the routes that are
+	// attempted. If zero, HopLimit applies.
+	MaxHops uint32
+
+	// IgnoredNodes is an optional set of nodes that must not be used as
+	// intermediate hops.
+	IgnoredNodes map[route.Vertex]struct{}
+
+	// IgnoredPairs is an optional set of directed node pairs. For each
+	// pair, the channels from the first to the second node aren't used.
+	IgnoredPairs map[DirectedNodePair]struct{}
+
+	// LivenessCheck determines whether the destination of the invoice is
+	// checked for signs of being offline before the first attempt.
+	LivenessCheck LivenessCheck
+
+	// HubPenalty is an optional penalty for routing through nodes with
+	// many channels.
+	HubPenalty *HubPenalty
+
+	// TimePref shifts the trade-off of path finding between cheap (-1)
+	// and reliable (1) routes.
+	TimePref float64
+
+	// PayAttemptTimeout is the time after which no further payment
+	// attempts are made. If zero, DefaultPayAttemptTimeout is used.
+	PayAttemptTimeout time.Duration
+}
+
+// InvoicePaymentResult is the outcome of a successful PayInvoice call.
+type InvoicePaymentResult struct {
+	// PaymentHash is the payment hash of the paid invoice.
+	PaymentHash [32]byte
+
+	// Preimage is the preimage that was revealed by the recipient.
+	Preimage [32]byte
+
+	// Route is the route that the successful payment attempt took.
+	Route *route.Route
+
+	// Invoice is the decoded invoice that was paid.
+	Invoice *zpay32.Invoice
+}
+
+// newInvoicePayment decodes the passed payment request and builds the
+// LightningPayment paying it, applying the defaults for all options that
+// weren't specified.
+func newInvoicePayment(payReq string, opts *PayInvoiceOptions,
+	params *chaincfg.Params, now time.Time) (*LightningPayment,
+	*zpay32.Invoice, error) {
+
+	if params == nil {
+		return nil, nil, errors.New("chain params required to decode " +
+			"payment request")
+	}
+	if opts == nil {
+		opts = &PayInvoiceOptions{}
+	}
+
+	invoice, err := zpay32.Decode(payReq, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Ensure that the invoice hasn't already expired.
+	invoiceExpiry := invoice.Timestamp.Add(invoice.Expiry())
+	if now.After(invoiceExpiry) {
+		return nil, nil, newErrf(ErrInvoiceExpired, "invoice expired. "+
+			"Valid until %v", invoiceExpiry)
+	}
+
+	// Ensure that we are able to satisfy all features that the invoice
+	// requires, as an attempt would otherwise fail at the recipient.
+	if err := ValidateInvoiceFeatures(invoice); err != nil {
+		return nil, nil, err
+	}
+
+	// Determine the amount to pay, which must be specified by either the
+	// invoice or the caller, but not both.
+	var amount lnwire.MilliSatoshi
+	switch {
+	case invoice.MilliSat == nil && opts.Amount == 0:
+		return nil, nil, errors.New("amount must be specified when " +
+			"paying a zero amount invoice")
+
+	case invoice.MilliSat == nil:
+		amount = opts.Amount
+
+	case opts.Amount != 0:
+		return nil, nil, errors.New("amount must not be specified " +
+			"when paying a non-zero amount invoice")
+
+	default:
+		amount = *invoice.MilliSat
+	}
+
+	// If no fee limit was specified, we'll use the payment's amount as an
+	// upper bound in order to avoid payment attempts from incurring fees
+	// higher than the payment amount itself.
+	feeLimit := amount
+	if opts.FeeLimit != nil {
+		feeLimit = *opts.FeeLimit
+	}
+
+	payAttemptTimeout := opts.PayAttemptTimeout
+	if payAttemptTimeout == 0 {
+		payAttemptTimeout = DefaultPayAttemptTimeout
+	}
+
+	payment := &LightningPayment{
+		Target:             route.NewVertex(invoice.Destination),
+		Amount:             amount,
+		FeeLimit:           feeLimit,
+		CltvLimit:          opts.CltvLimit,
+		PaymentHash:        *invoice.PaymentHash,
+		FinalCLTVDelta:     uint16(invoice.MinFinalCLTVExpiry()),
+		PayAttemptTimeout:  payAttemptTimeout,
+		RouteHints:         invoice.RouteHints,
+		OutgoingChannelIDs: opts.OutgoingChannelIDs,
+		LastHop:            opts.LastHop,
+		MaxHops:            opts.MaxHops,
+		IgnoredNodes:       opts.IgnoredNodes,
+		IgnoredPairs:       opts.IgnoredPairs,
+		PaymentRequest:     []byte(payReq),
+		InvoiceExpiry:      invoiceExpiry,
+		LivenessCheck:      opts.LivenessCheck,
+		HubPenalty:         opts.HubPenalty,
+		TimePref:           opts.TimePref,
+	}
+
+	return payment, invoice, nil
+}
+
+// PayInvoice decodes the passed BOLT 11 payment request and pays it. Amount
+// and fee limit defaults are applied, and the route hints and expiry of the
+// invoice are taken into account while attempting the payment. This method
+// blocks until the payment either succeeds or fails.
+func (r *ChannelRouter) PayInvoice(payReq string,
+	opts *PayInvoiceOptions) (*InvoicePaymentResult, error) {
+
+	payment, invoice, err := newInvoicePayment(
+		payReq, opts, r.cfg.ChainParams, time.Now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	preimage, rt, err := r.SendPayment(payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InvoicePaymentResult{
+		PaymentHash: payment.PaymentHash,
+		Preimage:    preimage,
+		Route:       rt,
+		Invoice:     invoice,
+	}, nil
+}
@@ -0,0 +1,142 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// TestNewInvoicePayment asserts that payment requests are correctly converted
+// into payments, applying the expected defaults.
+func TestNewInvoicePayment(t *testing.T) {
+	t.Parallel()
+
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	signer := zpay32.MessageSigner{
+		SignCompact: func(hash []byte) ([]byte, error) {
+			return btcec.SignCompact(btcec.S256(), privKey, hash, true)
+		},
+	}
+
+	now := time.Unix(1500000000, 0)
+	params := &chaincfg.TestNet3Params
+	payHash := [32]byte{1, 2, 3}
+
+	encode := func(options ...func(*zpay32.Invoice)) string {
+		options = append(options, zpay32.Description("test"))
+		invoice, err := zpay32.NewInvoice(params, payHash, now, options...)
+		if err != nil {
+			t.Fatalf("unable to create invoice: %v", err)
+		}
+		payReq, err := invoice.Encode(signer)
+		if err != nil {
+			t.Fatalf("unable to encode invoice: %v", err)
+		}
+		return payReq
+	}
+
+	amt := lnwire.MilliSatoshi(100000)
+	payReq := encode(zpay32.Amount(amt), zpay32.Expiry(time.Hour))
+
+	// An invoice with an amount should default the fee limit to the
+	// amount, and carry the invoice's expiry.
+	payment, _, err := newInvoicePayment(payReq, nil, params, now)
+	if err != nil {
+		t.Fatalf("unable to create payment: %v", err)
+	}
+	if payment.Amount != amt || payment.FeeLimit != amt {
+		t.Fatalf("unexpected amount %v or fee limit %v",
+			payment.Amount, payment.FeeLimit)
+	}
+	if payment.PaymentHash != payHash {
+		t.Fatalf("unexpected payment hash %x", payment.PaymentHash)
+	}
+	if payment.Target != route.NewVertex(privKey.PubKey()) {
+		t.Fatalf("unexpected target %v", payment.Target)
+	}
+	if !payment.InvoiceExpiry.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected invoice expiry %v", payment.InvoiceExpiry)
+	}
+	if payment.PayAttemptTimeout != DefaultPayAttemptTimeout {
+		t.Fatalf("unexpected timeout %v", payment.PayAttemptTimeout)
+	}
+
+	// Specifying an amount for an invoice that already has one should
+	// fail.
+	_, _, err = newInvoicePayment(
+		payReq, &PayInvoiceOptions{Amount: amt}, params, now,
+	)
+	if err == nil {
+		t.Fatal("expected error when overriding invoice amount")
+	}
+
+	// Paying after the invoice has expired should fail with a typed
+	// error.
+	_, _, err = newInvoicePayment(
+		payReq, nil, params, now.Add(2*time.Hour),
+	)
+	if !IsError(err, ErrInvoiceExpired) {
+		t.Fatalf("expected ErrInvoiceExpired, got %v", err)
+	}
+
+	// A zero amount invoice requires the amount to be specified.
+	zeroAmtPayReq := encode()
+	_, _, err = newInvoicePayment(zeroAmtPayReq, nil, params, now)
+	if err == nil {
+		t.Fatal("expected error for missing amount")
+	}
+
+	feeLimit := lnwire.MilliSatoshi(10)
+	payment, _, err = newInvoicePayment(
+		zeroAmtPayReq, &PayInvoiceOptions{
+			Amount:   amt,
+			FeeLimit: &feeLimit,
+		}, params, now,
+	)
+	if err != nil {
+		t.Fatalf("unable to create payment: %v", err)
+	}
+	if payment.Amount != amt || payment.FeeLimit != feeLimit {
+		t.Fatalf("unexpected amount %v or fee limit %v",
+			payment.Amount, payment.FeeLimit)
+	}
+
+	// Optional features of the invoice can be ignored.
+	optFeatures := lnwire.NewRawFeatureVector(
+		lnwire.PaymentAddrOptional, lnwire.MPPOptional,
+	)
+	optPayReq := encode(zpay32.Amount(amt), zpay32.Features(optFeatures))
+	_, _, err = newInvoicePayment(optPayReq, nil, params, now)
+	if err != nil {
+		t.Fatalf("unable to create payment: %v", err)
+	}
+
+	// Required features that we don't support should be rejected with a
+	// typed error that lists them.
+	reqFeatures := lnwire.NewRawFeatureVector(
+		lnwire.MPPRequired, lnwire.PaymentAddrRequired,
+		lnwire.TLVOnionPayloadOptional,
+	)
+	reqPayReq := encode(zpay32.Amount(amt), zpay32.Features(reqFeatures))
+	_, _, err = newInvoicePayment(reqPayReq, nil, params, now)
+	featuresErr, ok := err.(*UnsupportedFeaturesError)
+	if !ok {
+		t.Fatalf("expected UnsupportedFeaturesError, got %v", err)
+	}
+	expectedMissing := []lnwire.FeatureBit{
+		lnwire.PaymentAddrRequired, lnwire.MPPRequired,
+	}
+	if !reflect.DeepEqual(featuresErr.Missing, expectedMissing) {
+		t.Fatalf("expected missing features %v, got %v",
+			expectedMissing, featuresErr.Missing)
+	}
+}
@@ -0,0 +1,184 @@
+package routing
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// ErrPaymentNotInFlight is returned when inspecting a payment that isn't
+// currently being attempted by the router.
+var ErrPaymentNotInFlight = errors.New("payment not in flight")
+
+// PrunedEdge describes a directed channel that was reported as failed during a
+// payment session.
+type PrunedEdge struct {
+	// From is the node at the start of the failed channel.
+	From route.Vertex
+
+	// To is the node at the end of the failed channel.
+	To route.Vertex
+
+	// ChannelID is the short channel id of the failed channel.
+	ChannelID uint64
+
+	// MinPenalizeAmt is the minimum amount for which the channel is
+	// penalized. Payments of a lower amount may still use the channel.
+	MinPenalizeAmt lnwire.MilliSatoshi
+
+	// Class is the class of the reported failure.
+	Class FailureClass
+}
+
+// PrunedVertex describes a node that was reported as failed during a payment
+// session.
+type PrunedVertex struct {
+	// Node is the public key of the failed node.
+	Node route.Vertex
+
+	// Class is the class of the reported failure.
+	Class FailureClass
+}
+
+// PaymentSessionState is a snapshot of the live state of an in-flight
+// payment. It allows callers to determine why a payment is taking long to
+// complete.
+type PaymentSessionState struct {
+	// PaymentHash is the hash of the payment.
+	PaymentHash [32]byte
+
+	// Attempts is the number of routes that have been attempted so far,
+	// including the attempt that is currently in flight.
+	Attempts int
+
+	// CurrentRoute is the route of the most recent attempt, or nil if no
+	// attempt has been made yet.
+	CurrentRoute *route.Route
+
+	// PrunedEdges contains the channels that were reported as failed
+	// during this payment.
+	PrunedEdges []PrunedEdge
+
+	// PrunedVertices contains the nodes that were reported as failed
+	// during this payment.
+	PrunedVertices []PrunedVertex
+
+	// Started is the time at which the router started working on the
+	// payment.
+	Started time.Time
+
+	// Deadline is the time after which no further attempts are made. It
+	// is the zero time if the payment has no attempt timeout.
+	Deadline time.Time
+
+	// TimeRemaining is the time left until the deadline is reached. It is
+	// zero if the payment has no attempt timeout, or if the deadline has
+	// already passed.
+	TimeRemaining time.Duration
+
+	// Paused indicates that the payment was paused with PausePayment, and
+	// that no new attempts are made until it is resumed.
+	Paused bool
+}
+
+// activePayments keeps track of the payment lifecycles that are currently
+// executed by the router, so that they can be inspected.
+type activePayments struct {
+	payments map[[32]byte]*paymentLifecycle
+
+	sync.Mutex
+}
+
+// newActivePayments returns an empty set of active payments.
+func newActivePayments() *activePayments {
+	return &activePayments{
+		payments: make(map[[32]byte]*paymentLifecycle),
+	}
+}
+
+// add registers the lifecycle of a payment that is about to be executed.
+func (a *activePayments) add(p *paymentLifecycle) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.payments[p.payment.PaymentHash] = p
+}
+
+// remove unregisters the lifecycle of a payment that is no longer executed.
+func (a *activePayments) remove(p *paymentLifecycle) {
+	a.Lock()
+	defer a.Unlock()
+
+	// Only remove the entry if it still refers to this lifecycle.
+	if a.payments[p.payment.PaymentHash] == p {
+		delete(a.payments, p.payment.PaymentHash)
+	}
+}
+
+// get returns the lifecycle of the payment with the given hash, if it is
+// currently executed.
+func (a *activePayments) get(paymentHash [32]byte) (*paymentLifecycle, bool) {
+	a.Lock()
+	defer a.Unlock()
+
+	p, ok := a.payments[paymentHash]
+	return p, ok
+}
+
+// InspectPayment returns a snapshot of the live state of the in-flight payment
+// with the given hash. ErrPaymentNotInFlight is returned if the router isn't
+// currently attempting the payment.
+func (r *ChannelRouter) InspectPayment(
+	paymentHash [32]byte) (*PaymentSessionState, error) {
+
+	p, ok := r.activePayments.get(paymentHash)
+	if !ok {
+		return nil, ErrPaymentNotInFlight
+	}
+
+	return p.state(time.Now()), nil
+}
+
+// PausePayment temporarily halts the retries of the in-flight payment with the
+// given hash, without cancelling it. An attempt that is already in flight runs
+// to completion, but no new attempts are made until the payment is resumed
+// with ResumePayment. The paused state is persisted, so that the payment stays
+// paused across restarts. ErrPaymentNotInFlight is returned if the router isn't
+// currently attempting the payment.
+func (r *ChannelRouter) PausePayment(paymentHash [32]byte) error {
+	return r.setPaymentPaused(paymentHash, true)
+}
+
+// ResumePayment lets the in-flight payment with the given hash make new
+// attempts again after it was paused with PausePayment.
+func (r *ChannelRouter) ResumePayment(paymentHash [32]byte) error {
+	return r.setPaymentPaused(paymentHash, false)
+}
+
+// setPaymentPaused persists the paused state of the in-flight payment with the
+// given hash and applies it to its lifecycle.
+func (r *ChannelRouter) setPaymentPaused(paymentHash [32]byte,
+	paused bool) error {
+
+	p, ok := r.activePayments.get(paymentHash)
+	if !ok {
+		return ErrPaymentNotInFlight
+	}
+
+	err := r.cfg.Control.SetPaused(paymentHash, paused)
+	if err != nil {
+		return err
+	}
+	p.setPaused(paused)
+
+	if paused {
+		log.Infof("Paused payment %x", paymentHash)
+	} else {
+		log.Infof("Resumed payment %x", paymentHash)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// TestPaymentSessionState asserts that the live state of a payment reflects
+// the failures reported to its session, the attempts made and the time left
+// until the attempt timeout.
+func TestPaymentSessionState(t *testing.T) {
+	t.Parallel()
+
+	mc := NewMissionControl(
+		nil, nil, nil, &MissionControlConfig{
+			PenaltyHalfLife:       time.Hour,
+			AprioriHopProbability: 0.6,
+		},
+	)
+	session := &paymentSession{
+		errFailedPolicyChans: make(map[nodeChannel]struct{}),
+		mc:                   mc,
+	}
+
+	nodeA := route.Vertex{1}
+	nodeB := route.Vertex{2}
+	failedEdge := edge{from: nodeA, to: nodeB, channel: 1}
+
+	// The first policy failure shouldn't prune the edge, the second one
+	// should.
+	session.ReportEdgePolicyFailure(failedEdge)
+	edges, _ := session.PrunedState()
+	if len(edges) != 0 {
+		t.Fatalf("expected no pruned edges, got %v", len(edges))
+	}
+	session.ReportEdgePolicyFailure(failedEdge)
+
+	// A subsequent failure of the same edge replaces the previous one.
+	session.ReportEdgeFailure(failedEdge, 100, FailureClassTemporary)
+	session.ReportVertexFailure(nodeB, FailureClassPermanent)
+
+	started := time.Unix(1000, 0)
+	rt := &route.Route{TotalAmount: 1000}
+	p := &paymentLifecycle{
+		payment: &LightningPayment{
+			PaymentHash: [32]byte{1},
+		},
+		paySession: session,
+		started:    started,
+		deadline:   started.Add(time.Minute),
+	}
+	p.recordAttempt(rt)
+
+	state := p.state(started.Add(20 * time.Second))
+	if state.PaymentHash != p.payment.PaymentHash {
+		t.Fatalf("unexpected payment hash %x", state.PaymentHash)
+	}
+	if state.Attempts != 1 || state.CurrentRoute != rt {
+		t.Fatalf("unexpected attempts %v or route %v", state.Attempts,
+			state.CurrentRoute)
+	}
+	if len(state.PrunedEdges) != 1 {
+		t.Fatalf("expected 1 pruned edge, got %v",
+			len(state.PrunedEdges))
+	}
+	prunedEdge := state.PrunedEdges[0]
+	if prunedEdge.From != nodeA || prunedEdge.To != nodeB ||
+		prunedEdge.ChannelID != 1 || prunedEdge.MinPenalizeAmt != 100 ||
+		prunedEdge.Class != FailureClassTemporary {
+
+		t.Fatalf("unexpected pruned edge %v", prunedEdge)
+	}
+	if len(state.PrunedVertices) != 1 ||
+		state.PrunedVertices[0].Node != nodeB ||
+		state.PrunedVertices[0].Class != FailureClassPermanent {
+
+		t.Fatalf("unexpected pruned vertices %v", state.PrunedVertices)
+	}
+	if state.TimeRemaining != 40*time.Second {
+		t.Fatalf("unexpected time remaining %v", state.TimeRemaining)
+	}
+
+	// Once the deadline has passed, no time should remain.
+	state = p.state(started.Add(2 * time.Minute))
+	if state.TimeRemaining != 0 {
+		t.Fatalf("expected no time remaining, got %v",
+			state.TimeRemaining)
+	}
+}
+
+// TestPaymentPauseResume asserts that a paused payment holds back new attempts
+// until it is resumed.
+func TestPaymentPauseResume(t *testing.T) {
+	t.Parallel()
+
+	p := &paymentLifecycle{
+		router: &ChannelRouter{
+			quit: make(chan struct{}),
+		},
+		payment: &LightningPayment{
+			PaymentHash: [32]byte{1},
+		},
+		paySession: &mockPaymentSession{},
+		resumeChan: make(chan struct{}),
+	}
+
+	p.setPaused(true)
+	if !p.state(time.Now()).Paused {
+		t.Fatalf("expected payment to be paused")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.waitWhilePaused()
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("paused payment continued: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	p.setPaused(false)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unable to resume payment: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("payment not resumed")
+	}
+
+	if p.state(time.Now()).Paused {
+		t.Fatalf("expected payment not to be paused")
+	}
+
+	// A paused payment stops waiting when the router shuts down.
+	p.setPaused(true)
+	close(p.router.quit)
+	if err := p.waitWhilePaused(); err != ErrRouterShuttingDown {
+		t.Fatalf("expected ErrRouterShuttingDown, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package routing
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// maxLatencySamples is the maximum number of latency samples that we
+	// retain for the overall latency summary. Once this limit is reached,
+	// the oldest sample is replaced.
+	maxLatencySamples = 1000
+
+	// maxDestLatencySamples is the maximum number of latency samples that
+	// we retain per destination.
+	maxDestLatencySamples = 100
+
+	// maxLatencyDestinations is the maximum number of destinations that
+	// we keep a latency breakdown for. If this limit is reached, the
+	// destination that has been paid least recently is evicted.
+	maxLatencyDestinations = 1000
+)
+
+// LatencySummary summarizes a set of observed payment latencies. A latency is
+// measured from the moment the first attempt of a payment is dispatched to the
+// switch until the moment the payment settles.
+type LatencySummary struct {
+	// Count is the number of samples the summary is based on.
+	Count int
+
+	// Mean is the average latency of the samples.
+	Mean time.Duration
+
+	// P50 is the median latency.
+	P50 time.Duration
+
+	// P90 is the 90th percentile latency.
+	P90 time.Duration
+
+	// P99 is the 99th percentile latency.
+	P99 time.Duration
+
+	// Max is the highest latency observed.
+	Max time.Duration
+}
+
+// PaymentLatencySnapshot contains the current latency statistics of
+// successful payments made by the router.
+type PaymentLatencySnapshot struct {
+	// Overall summarizes the latency across all destinations.
+	Overall LatencySummary
+
+	// Destinations contains a latency summary for each destination that
+	// was paid recently.
+	Destinations map[route.Vertex]LatencySummary
+}
+
+// latencySamples is a fixed size ring buffer of latency samples.
+type latencySamples struct {
+	samples []time.Duration
+	next    int
+	limit   int
+}
+
+// newLatencySamples returns a new ring buffer that holds up to limit samples.
+func newLatencySamples(limit int) *latencySamples {
+	return &latencySamples{
+		samples: make([]time.Duration, 0, limit),
+		limit:   limit,
+	}
+}
+
+// add records a new sample, replacing the oldest one if the buffer is full.
+func (l *latencySamples) add(latency time.Duration) {
+	if len(l.samples) < l.limit {
+		l.samples = append(l.samples, latency)
+		return
+	}
+
+	l.samples[l.next] = latency
+	l.next = (l.next + 1) % l.limit
+}
+
+// summary computes the latency summary for the samples currently held.
+func (l *latencySamples) summary() LatencySummary {
+	count := len(l.samples)
+	if count == 0 {
+		return LatencySummary{}
+	}
+
+	sorted := make([]time.Duration, count)
+	copy(sorted, l.samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	var total time.Duration
+	for _, s := range sorted {
+		total += s
+	}
+
+	// percentile uses the nearest-rank method to select the sample at the
+	// given percentile.
+	percentile := func(p int) time.Duration {
+		rank := (p*count + 99) / 100
+		if rank < 1 {
+			rank = 1
+		}
+		return sorted[rank-1]
+	}
+
+	return LatencySummary{
+		Count: count,
+		Mean:  total / time.Duration(count),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+		Max:   sorted[count-1],
+	}
+}
+
+// destLatency holds the samples for a single destination together with the
+// time the last sample was added, which is used for eviction.
+type destLatency struct {
+	samples    *latencySamples
+	lastUpdate time.Time
+}
+
+// latencyTracker records end-to-end latencies of successful payments, both
+// overall and broken down per destination.
+type latencyTracker struct {
+	overall      *latencySamples
+	destinations map[route.Vertex]*destLatency
+
+	// now is expected to return the current time. It is supplied as an
+	// external function to enable deterministic unit tests.
+	now func() time.Time
+
+	sync.Mutex
+}
+
+// newLatencyTracker returns a new, empty latency tracker.
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{
+		overall:      newLatencySamples(maxLatencySamples),
+		destinations: make(map[route.Vertex]*destLatency),
+		now:          time.Now,
+	}
+}
+
+// recordSuccess records the latency of a successful payment to the given
+// destination.
+func (l *latencyTracker) recordSuccess(target route.Vertex,
+	latency time.Duration) {
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.overall.add(latency)
+
+	dest, ok := l.destinations[target]
+	if !ok {
+		// Make room for the new destination by evicting the one that
+		// hasn't been paid for the longest time.
+		if len(l.destinations) >= maxLatencyDestinations {
+			l.evictOldestDest()
+		}
+
+		dest = &destLatency{
+			samples: newLatencySamples(maxDestLatencySamples),
+		}
+		l.destinations[target] = dest
+	}
+
+	dest.samples.add(latency)
+	dest.lastUpdate = l.now()
+}
+
+// evictOldestDest removes the destination with the oldest last update from
+// the tracker.
+//
+// NOTE: The caller must hold the tracker's lock.
+func (l *latencyTracker) evictOldestDest() {
+	var (
+		oldest     route.Vertex
+		oldestTime time.Time
+		found      bool
+	)
+	for v, dest := range l.destinations {
+		if !found || dest.lastUpdate.Before(oldestTime) {
+			oldest = v
+			oldestTime = dest.lastUpdate
+			found = true
+		}
+	}
+
+	if found {
+		delete(l.destinations, oldest)
+	}
+}
+
+// snapshot returns the current latency statistics.
+func (l *latencyTracker) snapshot() *PaymentLatencySnapshot {
+	l.Lock()
+	defer l.Unlock()
+
+	snapshot := &PaymentLatencySnapshot{
+		Overall:      l.overall.summary(),
+		Destinations: make(map[route.Vertex]LatencySummary),
+	}
+	for v, dest := range l.destinations {
+		snapshot.Destinations[v] = dest.samples.summary()
+	}
+
+	return snapshot
+}
+
+// PaymentLatencyStats returns percentile summaries of the end-to-end latency
+// of recent successful payments, both overall and per destination. Payments
+// that were resumed after a restart are not included, as their first dispatch
+// time is unknown.
+func (r *ChannelRouter) PaymentLatencyStats() *PaymentLatencySnapshot {
+	return r.latencyTracker.snapshot()
+}
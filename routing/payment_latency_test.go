@@ -0,0 +1,82 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// TestLatencyTracker asserts that the latency tracker computes the expected
+// percentile summaries, both overall and per destination.
+func TestLatencyTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := newLatencyTracker()
+
+	destA := route.Vertex{1}
+	destB := route.Vertex{2}
+
+	// Record latencies of 1..100 ms to destination A, and a single slow
+	// payment to destination B.
+	for i := 1; i <= 100; i++ {
+		tracker.recordSuccess(destA, time.Duration(i)*time.Millisecond)
+	}
+	tracker.recordSuccess(destB, time.Second)
+
+	snapshot := tracker.snapshot()
+
+	summaryA, ok := snapshot.Destinations[destA]
+	if !ok {
+		t.Fatal("expected summary for destination A")
+	}
+	if summaryA.Count != 100 {
+		t.Fatalf("expected 100 samples, got %v", summaryA.Count)
+	}
+	if summaryA.P50 != 50*time.Millisecond {
+		t.Fatalf("unexpected p50: %v", summaryA.P50)
+	}
+	if summaryA.P90 != 90*time.Millisecond {
+		t.Fatalf("unexpected p90: %v", summaryA.P90)
+	}
+	if summaryA.P99 != 99*time.Millisecond {
+		t.Fatalf("unexpected p99: %v", summaryA.P99)
+	}
+	if summaryA.Max != 100*time.Millisecond {
+		t.Fatalf("unexpected max: %v", summaryA.Max)
+	}
+
+	summaryB := snapshot.Destinations[destB]
+	if summaryB.Count != 1 || summaryB.P50 != time.Second {
+		t.Fatalf("unexpected summary for destination B: %+v",
+			summaryB)
+	}
+
+	if snapshot.Overall.Count != 101 {
+		t.Fatalf("expected 101 overall samples, got %v",
+			snapshot.Overall.Count)
+	}
+	if snapshot.Overall.Max != time.Second {
+		t.Fatalf("unexpected overall max: %v", snapshot.Overall.Max)
+	}
+}
+
+// TestLatencySamplesRing asserts that the sample buffer only retains the most
+// recent samples once its limit is reached.
+func TestLatencySamplesRing(t *testing.T) {
+	t.Parallel()
+
+	samples := newLatencySamples(3)
+	for i := 1; i <= 5; i++ {
+		samples.add(time.Duration(i))
+	}
+
+	summary := samples.summary()
+	if summary.Count != 3 {
+		t.Fatalf("expected 3 samples, got %v", summary.Count)
+	}
+	if summary.Mean != 4 {
+		t.Fatalf("expected mean of most recent samples, got %v",
+			summary.Mean)
+	}
+}
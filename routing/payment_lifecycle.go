@@ -2,12 +2,15 @@ package routing
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	sphinx "github.com/lightningnetwork/lightning-onion"
 	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/eventbus"
 	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing/route"
 )
@@ -38,6 +41,118 @@ type paymentLifecycle struct {
 	attempt        *channeldb.PaymentAttemptInfo
 	circuit        *sphinx.Circuit
 	lastError      *htlcswitch.ForwardingError
+
+	// firstDispatch is the time the first attempt of this payment was
+	// handed to the switch. It is left at its zero value for payments
+	// that are resumed after a restart.
+	firstDispatch time.Time
+
+	// started is the time the router started working on the payment, and
+	// deadline the time after which no further attempts are made. The
+	// deadline is left at its zero value if there is no attempt timeout.
+	started  time.Time
+	deadline time.Time
+
+	// attempts and currentRoute track the progress of the payment, so
+	// that it can be inspected while in flight. They are guarded by
+	// stateMtx.
+	attempts     int
+	currentRoute *route.Route
+	stateMtx     sync.Mutex
+
+	// paused indicates that no new attempts are made for the payment.
+	// resumeChan is closed and replaced when the payment is unpaused.
+	// Both are guarded by stateMtx.
+	paused     bool
+	resumeChan chan struct{}
+
+	// lastAttempt is the time at which the previous attempt was handed
+	// to the switch. It is used to pace the attempts.
+	lastAttempt time.Time
+
+	// journalTried indicates whether the journaled route to the
+	// destination has been requested already, and journalAttempt whether
+	// the current attempt uses it.
+	journalTried   bool
+	journalAttempt bool
+}
+
+// recordAttempt records that a new attempt is made along the given route.
+func (p *paymentLifecycle) recordAttempt(rt *route.Route) {
+	p.stateMtx.Lock()
+	defer p.stateMtx.Unlock()
+
+	p.attempts++
+	p.currentRoute = rt
+}
+
+// setPaused pauses or unpauses the payment. Unpausing wakes up the payment
+// loop if it is waiting to make a new attempt.
+func (p *paymentLifecycle) setPaused(paused bool) {
+	p.stateMtx.Lock()
+	defer p.stateMtx.Unlock()
+
+	if p.paused == paused {
+		return
+	}
+	p.paused = paused
+
+	if !paused {
+		close(p.resumeChan)
+		p.resumeChan = make(chan struct{})
+	}
+}
+
+// waitWhilePaused blocks until the payment is unpaused. An in-flight attempt
+// isn't affected by a pause, only new attempts are held back.
+func (p *paymentLifecycle) waitWhilePaused() error {
+	for {
+		p.stateMtx.Lock()
+		paused := p.paused
+		resumeChan := p.resumeChan
+		p.stateMtx.Unlock()
+
+		if !paused {
+			return nil
+		}
+
+		log.Debugf("[trace=%v] Payment %x paused, holding back new "+
+			"attempts", p.payment.TraceID, p.payment.PaymentHash)
+
+		select {
+		case <-resumeChan:
+		case <-p.router.quit:
+			return ErrRouterShuttingDown
+		}
+	}
+}
+
+// state returns a snapshot of the live state of the payment.
+func (p *paymentLifecycle) state(now time.Time) *PaymentSessionState {
+	p.stateMtx.Lock()
+	attempts := p.attempts
+	currentRoute := p.currentRoute
+	paused := p.paused
+	p.stateMtx.Unlock()
+
+	prunedEdges, prunedVertices := p.paySession.PrunedState()
+
+	var timeRemaining time.Duration
+	if !p.deadline.IsZero() && p.deadline.After(now) {
+		timeRemaining = p.deadline.Sub(now)
+	}
+
+	return &PaymentSessionState{
+		PaymentHash:    p.payment.PaymentHash,
+		Attempts:       attempts,
+		CurrentRoute:   currentRoute,
+		PrunedEdges:    prunedEdges,
+		PrunedVertices: prunedVertices,
+		Started:        p.started,
+		Deadline:       p.deadline,
+		TimeRemaining:  timeRemaining,
+		Paused:         paused,
+	}
 }
 
 // resumePayment resumes the paymentLifecycle from the current state.
@@ -56,6 +171,9 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 
 			// Now that the attempt is created and checkpointed to
 			// the DB, we send it.
+			if p.firstDispatch.IsZero() {
+				p.firstDispatch = time.Now()
+			}
 			sendErr := p.sendPaymentAttempt(firstHop, htlcAdd)
 			if sendErr != nil {
 				// We must inspect the error to know whether it
@@ -104,8 +222,9 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		// restart. In this case we can safely send a new payment
 		// attempt, and wait for its result to be available.
 		case err == htlcswitch.ErrPaymentIDNotFound:
-			log.Debugf("Payment ID %v for hash %x not found in "+
-				"the Switch, retrying.", p.attempt.PaymentID,
+			log.Debugf("[trace=%v] Payment ID %v for hash %x not "+
+				"found in the Switch, retrying.",
+				p.payment.TraceID, p.attempt.PaymentID,
 				p.payment.PaymentHash)
 
 			// Reset the attempt to indicate we want to make a new
@@ -115,8 +234,9 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 
 		// A critical, unexpected error was encountered.
 		case err != nil:
-			log.Errorf("Failed getting result for paymentID %d "+
-				"from switch: %v", p.attempt.PaymentID, err)
+			log.Errorf("[trace=%v] Failed getting result for "+
+				"paymentID %d from switch: %v",
+				p.payment.TraceID, p.attempt.PaymentID, err)
 
 			return [32]byte{}, nil, err
 		}
@@ -141,8 +261,10 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		// In case of a payment failure, we use the error to decide
 		// whether we should retry.
 		if result.Error != nil {
-			log.Errorf("Attempt to send payment %x failed: %v",
-				p.payment.PaymentHash, result.Error)
+			log.Errorf("[trace=%v] Attempt to send payment %x "+
+				"(pid=%v) failed: %v", p.payment.TraceID,
+				p.payment.PaymentHash, p.attempt.PaymentID,
+				result.Error)
 
 			// We must inspect the error to know whether it was
 			// critical or not, to decide whether we should
@@ -157,19 +279,61 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 			continue
 		}
 
+		// A preimage that doesn't match the payment hash doesn't prove
+		// that the payment was received, so we can't consider the
+		// payment succeeded.
+		preimage := lntypes.Preimage(result.Preimage)
+		if !preimage.Matches(p.payment.PaymentHash) {
+			log.Errorf("[trace=%v] Payment %x (pid=%v) settled "+
+				"with invalid preimage %v", p.payment.TraceID,
+				p.payment.PaymentHash, p.attempt.PaymentID,
+				preimage)
+
+			err := p.failPayment(
+				channeldb.FailureReasonInvalidPreimage,
+			)
+			if err != nil {
+				return [32]byte{}, nil, err
+			}
+
+			return [32]byte{}, nil, newErrf(ErrInvalidPreimage,
+				"preimage %v doesn't match payment hash %x",
+				preimage, p.payment.PaymentHash)
+		}
+
 		// We successfully got a payment result back from the switch.
-		log.Debugf("Payment %x succeeded with pid=%v",
-			p.payment.PaymentHash, p.attempt.PaymentID)
+		log.Debugf("[trace=%v] Payment %x succeeded with pid=%v",
+			p.payment.TraceID, p.payment.PaymentHash,
+			p.attempt.PaymentID)
 
 		// In case of success we atomically store the db payment and
 		// move the payment to the success state.
 		err = p.router.cfg.Control.Success(p.payment.PaymentHash, result.Preimage)
 		if err != nil {
-			log.Errorf("Unable to succeed payment "+
-				"attempt: %v", err)
+			log.Errorf("[trace=%v] Unable to succeed payment "+
+				"attempt: %v", p.payment.TraceID, err)
 			return [32]byte{}, nil, err
 		}
 
+		// Journal the route of the payment, so that the next payment
+		// to the same destination can try it first.
+		p.router.recordJournalRoute(&p.attempt.Route)
+
+		p.router.cfg.EventBus.Publish(eventbus.PaymentSettledEvent{
+			PaymentHash: p.payment.PaymentHash,
+			Preimage:    result.Preimage,
+			Route:       &p.attempt.Route,
+			TraceID:     uint64(p.payment.TraceID),
+		})
+
+		// Record the end-to-end latency of this payment, if we know
+		// when it was first dispatched.
+		if !p.firstDispatch.IsZero() {
+			p.router.latencyTracker.recordSuccess(
+				p.payment.Target, time.Since(p.firstDispatch),
+			)
+		}
+
 		// Terminal state, return the preimage and the route
 		// taken.
 		return result.Preimage, &p.attempt.Route, nil
@@ -182,48 +346,63 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 func (p *paymentLifecycle) createNewPaymentAttempt() (lnwire.ShortChannelID,
 	*lnwire.UpdateAddHTLC, error) {
 
+	// A paused payment doesn't make new attempts until it is resumed. The
+	// attempt timeout keeps running in the meantime, so a payment that is
+	// resumed after its deadline fails right away.
+	if err := p.waitWhilePaused(); err != nil {
+		return lnwire.ShortChannelID{}, nil, err
+	}
+
+	// Space the attempt from the previous one, if pacing is configured.
+	if err := p.waitForPacing(); err != nil {
+		return lnwire.ShortChannelID{}, nil, err
+	}
+
 	// Before we attempt this next payment, we'll check to see if either
 	// we've gone past the payment attempt timeout, or the router is
-	// exiting. In either case, we'll stop this payment attempt short. If a
-	// timeout is not applicable, timeoutChan will be nil.
-	select {
-	case <-p.timeoutChan:
-		// Mark the payment as failed because of the
-		// timeout.
-		err := p.router.cfg.Control.Fail(
-			p.payment.PaymentHash, channeldb.FailureReasonTimeout,
-		)
+	// exiting. In either case, we'll stop this payment attempt short.
+	if err := p.checkTimeout(); err != nil {
+		return lnwire.ShortChannelID{}, nil, err
+	}
+
+	// If the invoice we're paying has expired in the meantime, there is
+	// no point in making another attempt, as the recipient will reject
+	// it.
+	if !p.payment.InvoiceExpiry.IsZero() &&
+		time.Now().After(p.payment.InvoiceExpiry) {
+
+		// The invoice expiry is a timeout imposed by the recipient,
+		// so we'll mark the payment as timed out.
+		err := p.failPayment(channeldb.FailureReasonTimeout)
 		if err != nil {
 			return lnwire.ShortChannelID{}, nil, err
 		}
 
-		errStr := fmt.Sprintf("payment attempt not completed " +
-			"before timeout")
-
-		return lnwire.ShortChannelID{}, nil,
-			newErr(ErrPaymentAttemptTimeout, errStr)
+		return lnwire.ShortChannelID{}, nil, newErrf(
+			ErrInvoiceExpired, "invoice expired at %v before "+
+				"payment completed", p.payment.InvoiceExpiry,
+		)
+	}
 
-	case <-p.router.quit:
-		// The payment will be resumed from the current state
-		// after restart.
-		return lnwire.ShortChannelID{}, nil, ErrRouterShuttingDown
+	// Create a new payment attempt from the given payment session. If the
+	// route violates our own outgoing policy, our switch would reject it
+	// right away. Rather than making an attempt that is bound to fail, we
+	// exclude the first hop channel and request a new route. As every
+	// rejection excludes a channel from the session, this ends once the
+	// session runs out of routes, or when the payment times out.
+	route, err := p.requestRoute()
+	for err == nil && !p.satisfiesLocalPolicy(route) {
+		if err := p.checkTimeout(); err != nil {
+			return lnwire.ShortChannelID{}, nil, err
+		}
 
-	default:
-		// Fall through if we haven't hit our time limit, or
-		// are expiring.
+		route, err = p.requestRoute()
 	}
-
-	// Create a new payment attempt from the given payment session.
-	route, err := p.paySession.RequestRoute(
-		p.payment, uint32(p.currentHeight), p.finalCLTVDelta,
-	)
 	if err != nil {
 		// If we're unable to successfully make a payment using
 		// any of the routes we've found, then mark the payment
 		// as permanently failed.
-		saveErr := p.router.cfg.Control.Fail(
-			p.payment.PaymentHash, channeldb.FailureReasonNoRoute,
-		)
+		saveErr := p.failPayment(channeldb.FailureReasonNoRoute)
 		if saveErr != nil {
 			return lnwire.ShortChannelID{}, nil, saveErr
 		}
@@ -238,6 +417,8 @@ func (p *paymentLifecycle) createNewPaymentAttempt() (lnwire.ShortChannelID,
 		return lnwire.ShortChannelID{}, nil, err
 	}
 
+	p.recordAttempt(route)
+
 	// Generate a new key to be used for this attempt.
 	sessionKey, err := generateNewSessionKey()
 	if err != nil {
@@ -304,13 +485,85 @@ func (p *paymentLifecycle) createNewPaymentAttempt() (lnwire.ShortChannelID,
 	return firstHop, htlcAdd, nil
 }
 
+// checkTimeout returns an error if the payment attempt timeout has expired, in
+// which case the payment is marked as failed, or if the router is exiting. If a
+// timeout is not applicable, timeoutChan will be nil.
+func (p *paymentLifecycle) checkTimeout() error {
+	select {
+	case <-p.timeoutChan:
+		return p.failTimeout()
+
+	case <-p.router.quit:
+		// The payment will be resumed from the current state
+		// after restart.
+		return ErrRouterShuttingDown
+
+	default:
+		return nil
+	}
+}
+
+// requestRoute returns the route for the next attempt. The first attempt uses
+// the journaled route to the destination if there is one that the payment
+// session accepts, otherwise the route is requested from the payment session.
+func (p *paymentLifecycle) requestRoute() (*route.Route, error) {
+	if !p.journalTried {
+		p.journalTried = true
+
+		height := uint32(p.currentHeight)
+		rt := p.router.journalRoute(p.payment, height, p.finalCLTVDelta)
+		if rt != nil && p.paySession.AdoptRoute(p.payment, rt, height) {
+			p.journalAttempt = true
+			return rt, nil
+		}
+	}
+	p.journalAttempt = false
+
+	return p.paySession.RequestRoute(
+		p.payment, uint32(p.currentHeight), p.finalCLTVDelta,
+	)
+}
+
+// satisfiesLocalPolicy checks the first hop of the given route against our own
+// outgoing policy, using the configured local policy check. If the route
+// violates it, the first hop channel is excluded from the payment session,
+// and false is returned. As the failure is self-inflicted, it isn't reported
+// to mission control.
+func (p *paymentLifecycle) satisfiesLocalPolicy(rt *route.Route) bool {
+	checkPolicy := p.router.cfg.CheckLocalPolicy
+	if checkPolicy == nil {
+		return true
+	}
+
+	firstHop := lnwire.NewShortChanIDFromInt(rt.Hops[0].ChannelID)
+	err := checkPolicy(firstHop, &lnwire.UpdateAddHTLC{
+		Amount:      rt.TotalAmount,
+		Expiry:      rt.TotalTimeLock,
+		PaymentHash: p.payment.PaymentHash,
+	})
+	if err == nil {
+		return true
+	}
+
+	log.Debugf("[trace=%v] Route for payment %x violates local policy "+
+		"of first hop %v, requesting new route: %v", p.payment.TraceID,
+		p.payment.PaymentHash, firstHop, err)
+
+	err = p.paySession.ReportLocalChannelFailure(rt.Hops[0].ChannelID)
+	if err != nil {
+		log.Warnf("Unable to refresh bandwidth hints: %v", err)
+	}
+
+	return false
+}
+
 // sendPaymentAttempt attempts to send the current attempt to the switch.
 func (p *paymentLifecycle) sendPaymentAttempt(firstHop lnwire.ShortChannelID,
 	htlcAdd *lnwire.UpdateAddHTLC) error {
 
-	log.Tracef("Attempting to send payment %x (pid=%v), "+
-		"using route: %v", p.payment.PaymentHash, p.attempt.PaymentID,
-		newLogClosure(func() string {
+	log.Tracef("[trace=%v] Attempting to send payment %x (pid=%v), "+
+		"using route: %v", p.payment.TraceID, p.payment.PaymentHash,
+		p.attempt.PaymentID, newLogClosure(func() string {
 			return spew.Sdump(p.attempt.Route)
 		}),
 	)
@@ -319,18 +572,37 @@ func (p *paymentLifecycle) sendPaymentAttempt(firstHop lnwire.ShortChannelID,
 	// the Switch successfully has persisted the payment attempt,
 	// such that we can resume waiting for the result after a
 	// restart.
+	p.lastAttempt = time.Now()
 	err := p.router.cfg.Payer.SendHTLC(
 		firstHop, p.attempt.PaymentID, htlcAdd,
 	)
 	if err != nil {
-		log.Errorf("Failed sending attempt %d for payment "+
-			"%x to switch: %v", p.attempt.PaymentID,
-			p.payment.PaymentHash, err)
+		log.Errorf("[trace=%v] Failed sending attempt %d for payment "+
+			"%x to switch: %v", p.payment.TraceID,
+			p.attempt.PaymentID, p.payment.PaymentHash, err)
 		return err
 	}
 
-	log.Debugf("Payment %x (pid=%v) successfully sent to switch",
-		p.payment.PaymentHash, p.attempt.PaymentID)
+	log.Debugf("[trace=%v] Payment %x (pid=%v) successfully sent to "+
+		"switch", p.payment.TraceID, p.payment.PaymentHash,
+		p.attempt.PaymentID)
+
+	return nil
+}
+
+// failPayment marks the payment as failed for the given reason, and publishes
+// the failure on the event bus.
+func (p *paymentLifecycle) failPayment(reason channeldb.FailureReason) error {
+	err := p.router.cfg.Control.Fail(p.payment.PaymentHash, reason)
+	if err != nil {
+		return err
+	}
+
+	p.router.cfg.EventBus.Publish(eventbus.PaymentFailedEvent{
+		PaymentHash: p.payment.PaymentHash,
+		Reason:      reason,
+		TraceID:     uint64(p.payment.TraceID),
+	})
 
 	return nil
 }
@@ -340,13 +612,23 @@ func (p *paymentLifecycle) sendPaymentAttempt(firstHop lnwire.ShortChannelID,
 func (p *paymentLifecycle) handleSendError(sendErr error) error {
 	var finalOutcome bool
 
+	// A journaled route that fails is no longer trusted.
+	if p.journalAttempt {
+		p.journalAttempt = false
+		p.router.invalidateJournalRoute(
+			p.payment.Target, amountBand(p.payment.Amount),
+			"attempt failed",
+		)
+	}
+
 	// If an internal, non-forwarding error occurred, we can stop trying.
 	fErr, ok := sendErr.(*htlcswitch.ForwardingError)
 	if !ok {
 		finalOutcome = true
 	} else {
 		finalOutcome = p.router.processSendError(
-			p.paySession, &p.attempt.Route, fErr,
+			p.payment.TraceID, p.paySession, &p.attempt.Route,
+			fErr,
 		)
 
 		// Save the forwarding error so it can be returned if this turns
@@ -355,15 +637,13 @@ func (p *paymentLifecycle) handleSendError(sendErr error) error {
 	}
 
 	if finalOutcome {
-		log.Errorf("Payment %x failed with final outcome: %v",
-			p.payment.PaymentHash, sendErr)
+		log.Errorf("[trace=%v] Payment %x failed with final outcome: "+
+			"%v", p.payment.TraceID, p.payment.PaymentHash, sendErr)
 
 		// Mark the payment failed with no route.
 		// TODO(halseth): make payment codes for the actual reason we
 		// don't continue path finding.
-		err := p.router.cfg.Control.Fail(
-			p.payment.PaymentHash, channeldb.FailureReasonNoRoute,
-		)
+		err := p.failPayment(channeldb.FailureReasonNoRoute)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,170 @@
+package routing
+
+import (
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// AttemptOutcome describes the result of reconciling a payment attempt
+// recorded by the ControlTower with the state of the switch.
+type AttemptOutcome uint8
+
+const (
+	// AttemptOutcomeNone indicates that no attempt was recorded for the
+	// in-flight payment, so there is nothing to reconcile.
+	AttemptOutcomeNone AttemptOutcome = iota
+
+	// AttemptOutcomeInFlight indicates that the switch is still
+	// forwarding the attempt, and its result needs to be awaited.
+	AttemptOutcomeInFlight
+
+	// AttemptOutcomeResolved indicates that the switch already has the
+	// result of the attempt.
+	AttemptOutcomeResolved
+
+	// AttemptOutcomeNotDispatched indicates that the attempt was recorded
+	// by the ControlTower, but never reached the switch before a restart.
+	// It is safe to dispatch the exact same attempt again.
+	AttemptOutcomeNotDispatched
+
+	// AttemptOutcomeOrphaned indicates that the switch is forwarding an
+	// attempt of which the ControlTower has no record.
+	AttemptOutcomeOrphaned
+)
+
+// String returns a human readable representation of the attempt outcome.
+func (a AttemptOutcome) String() string {
+	switch a {
+	case AttemptOutcomeNone:
+		return "none"
+
+	case AttemptOutcomeInFlight:
+		return "in-flight"
+
+	case AttemptOutcomeResolved:
+		return "resolved"
+
+	case AttemptOutcomeNotDispatched:
+		return "not-dispatched"
+
+	case AttemptOutcomeOrphaned:
+		return "orphaned"
+
+	default:
+		return "unknown"
+	}
+}
+
+// AttemptReconciliation is the outcome of reconciling a single payment attempt
+// with the switch.
+type AttemptReconciliation struct {
+	// PaymentHash is the hash of the payment the attempt belongs to. It is
+	// left at its zero value for orphaned attempts.
+	PaymentHash lntypes.Hash
+
+	// PaymentID is the switch payment ID of the attempt.
+	PaymentID uint64
+
+	// Outcome describes how the state of the ControlTower and the switch
+	// relate for this attempt.
+	Outcome AttemptOutcome
+}
+
+// reconcileAttempts compares the attempts of the given in-flight payments with
+// the state of the switch, instead of assuming that both are consistent after
+// a restart. An entry is returned for each of the payments in the same order,
+// followed by an entry for each attempt that the switch is forwarding without
+// the ControlTower knowing about it.
+func (r *ChannelRouter) reconcileAttempts(
+	payments []*channeldb.InFlightPayment) ([]*AttemptReconciliation,
+	error) {
+
+	var (
+		reconciliations = make(
+			[]*AttemptReconciliation, 0, len(payments),
+		)
+		knownIDs = make(map[uint64]struct{}, len(payments))
+	)
+	for _, payment := range payments {
+		reconciliation := &AttemptReconciliation{
+			PaymentHash: payment.Info.PaymentHash,
+		}
+		reconciliations = append(reconciliations, reconciliation)
+
+		if payment.Attempt == nil {
+			reconciliation.Outcome = AttemptOutcomeNone
+			continue
+		}
+
+		paymentID := payment.Attempt.PaymentID
+		reconciliation.PaymentID = paymentID
+		knownIDs[paymentID] = struct{}{}
+
+		status, err := r.cfg.Payer.PaymentAttemptStatus(paymentID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch status {
+		case htlcswitch.AttemptStatusInFlight:
+			reconciliation.Outcome = AttemptOutcomeInFlight
+
+		case htlcswitch.AttemptStatusResolved:
+			reconciliation.Outcome = AttemptOutcomeResolved
+
+		default:
+			reconciliation.Outcome = AttemptOutcomeNotDispatched
+		}
+
+		log.Debugf("Reconciled attempt pid=%v of payment %v: %v",
+			paymentID, payment.Info.PaymentHash,
+			reconciliation.Outcome)
+	}
+
+	for _, paymentID := range r.cfg.Payer.LocalPaymentIDs() {
+		if _, ok := knownIDs[paymentID]; ok {
+			continue
+		}
+
+		log.Warnf("Switch is forwarding payment attempt pid=%v "+
+			"which is unknown to the ControlTower", paymentID)
+
+		reconciliations = append(reconciliations, &AttemptReconciliation{
+			PaymentID: paymentID,
+			Outcome:   AttemptOutcomeOrphaned,
+		})
+	}
+
+	return reconciliations, nil
+}
+
+// redispatchAttempt sends the exact same attempt that was recorded for the
+// payment with the given hash to the switch once more. This must only be done
+// if the switch has no record of the attempt.
+func (r *ChannelRouter) redispatchAttempt(paymentHash lntypes.Hash,
+	attempt *channeldb.PaymentAttemptInfo) error {
+
+	rt := &attempt.Route
+	onionBlob, _, err := generateSphinxPacket(
+		rt, paymentHash[:], attempt.SessionKey,
+	)
+	if err != nil {
+		return err
+	}
+
+	htlcAdd := &lnwire.UpdateAddHTLC{
+		Amount:      rt.TotalAmount,
+		Expiry:      rt.TotalTimeLock,
+		PaymentHash: paymentHash,
+	}
+	copy(htlcAdd.OnionBlob[:], onionBlob)
+
+	firstHop := lnwire.NewShortChanIDFromInt(rt.Hops[0].ChannelID)
+
+	log.Infof("Redispatching attempt pid=%v of payment %v",
+		attempt.PaymentID, paymentHash)
+
+	return r.cfg.Payer.SendHTLC(firstHop, attempt.PaymentID, htlcAdd)
+}
@@ -0,0 +1,90 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/lntypes"
+)
+
+// TestReconcileAttempts asserts that the attempts recorded by the
+// ControlTower are correctly reconciled with the state of the switch.
+func TestReconcileAttempts(t *testing.T) {
+	t.Parallel()
+
+	payer := &mockPaymentAttemptDispatcher{
+		results: map[uint64]*htlcswitch.PaymentResult{
+			2: {},
+		},
+		inFlight: map[uint64]struct{}{
+			1: {},
+			4: {},
+		},
+	}
+	r := &ChannelRouter{
+		cfg: &Config{
+			Payer: payer,
+		},
+	}
+
+	newPayment := func(hash byte,
+		attempt *channeldb.PaymentAttemptInfo) *channeldb.InFlightPayment {
+
+		return &channeldb.InFlightPayment{
+			Info: &channeldb.PaymentCreationInfo{
+				PaymentHash: lntypes.Hash{hash},
+			},
+			Attempt: attempt,
+		}
+	}
+
+	payments := []*channeldb.InFlightPayment{
+		newPayment(1, &channeldb.PaymentAttemptInfo{PaymentID: 1}),
+		newPayment(2, &channeldb.PaymentAttemptInfo{PaymentID: 2}),
+		newPayment(3, &channeldb.PaymentAttemptInfo{PaymentID: 3}),
+		newPayment(4, nil),
+	}
+
+	reconciliations, err := r.reconcileAttempts(payments)
+	if err != nil {
+		t.Fatalf("unable to reconcile attempts: %v", err)
+	}
+
+	expected := []AttemptReconciliation{
+		{
+			PaymentHash: lntypes.Hash{1},
+			PaymentID:   1,
+			Outcome:     AttemptOutcomeInFlight,
+		},
+		{
+			PaymentHash: lntypes.Hash{2},
+			PaymentID:   2,
+			Outcome:     AttemptOutcomeResolved,
+		},
+		{
+			PaymentHash: lntypes.Hash{3},
+			PaymentID:   3,
+			Outcome:     AttemptOutcomeNotDispatched,
+		},
+		{
+			PaymentHash: lntypes.Hash{4},
+			Outcome:     AttemptOutcomeNone,
+		},
+		{
+			PaymentID: 4,
+			Outcome:   AttemptOutcomeOrphaned,
+		},
+	}
+
+	if len(reconciliations) != len(expected) {
+		t.Fatalf("expected %v reconciliations, got %v",
+			len(expected), len(reconciliations))
+	}
+	for i, reconciliation := range reconciliations {
+		if *reconciliation != expected[i] {
+			t.Fatalf("reconciliation %v: expected %+v, got %+v", i,
+				expected[i], *reconciliation)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package routing
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
+	"github.com/btcsuite/btcutil"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing/route"
@@ -18,17 +22,20 @@ type PaymentSession interface {
 		height uint32, finalCltvDelta uint16) (*route.Route, error)
 
 	// ReportVertexFailure reports to the PaymentSession that the passsed
-	// vertex failed to route the previous payment attempt. The
+	// vertex failed to route the previous payment attempt. The failure
+	// class determines how long the vertex remains penalized. The
 	// PaymentSession will use this information to produce a better next
 	// route.
-	ReportVertexFailure(v route.Vertex)
+	ReportVertexFailure(v route.Vertex, class FailureClass)
 
 	// ReportEdgeFailure reports to the PaymentSession that the passed edge
 	// failed to route the previous payment attempt. A minimum penalization
 	// amount is included to attenuate the failure. This is set to a
-	// non-zero value for channel balance failures. The PaymentSession will
-	// use this information to produce a better next route.
-	ReportEdgeFailure(failedEdge edge, minPenalizeAmt lnwire.MilliSatoshi)
+	// non-zero value for channel balance failures. The failure class
+	// determines how long the edge remains penalized. The PaymentSession
+	// will use this information to produce a better next route.
+	ReportEdgeFailure(failedEdge edge, minPenalizeAmt lnwire.MilliSatoshi,
+		class FailureClass)
 
 	// ReportEdgePolicyFailure reports to the PaymentSession that we
 	// received a failure message that relates to a channel policy. For
@@ -37,6 +44,25 @@ type PaymentSession interface {
 	// PaymentSession will use this information to produce a better next
 	// route.
 	ReportEdgePolicyFailure(failedEdge edge)
+
+	// PrunedState returns the edges and vertices that were reported as
+	// failed during this session.
+	PrunedState() ([]PrunedEdge, []PrunedVertex)
+
+	// ReportLocalChannelFailure reports to the PaymentSession that one of
+	// our own channels turned out to be unavailable while dispatching the
+	// previous payment attempt. The PaymentSession refreshes its view of
+	// the bandwidth of our channels and excludes the failed channel from
+	// the next attempted route, without penalizing it in mission control.
+	ReportLocalChannelFailure(channel uint64) error
+
+	// AdoptRoute checks whether a route that was obtained outside of the
+	// PaymentSession, such as a journaled route, satisfies all
+	// restrictions that the PaymentSession applies to the routes it finds
+	// itself. If it does, the route is recorded as attempted and true is
+	// returned.
+	AdoptRoute(payment *LightningPayment, rt *route.Route,
+		height uint32) bool
 }
 
 // paymentSession is used during an HTLC routings session to prune the local
@@ -52,6 +78,11 @@ type paymentSession struct {
 
 	bandwidthHints map[uint64]lnwire.MilliSatoshi
 
+	// excludedLocalChans holds our own channels that failed during this
+	// session. They are excluded from the bandwidth hints every time the
+	// hints are refreshed.
+	excludedLocalChans map[uint64]struct{}
+
 	// errFailedFeeChans is a map of the short channel IDs that were the
 	// source of policy related routing failures during this payment attempt.
 	// We'll use this map to prune out channels when the first error may not
@@ -63,7 +94,41 @@ type paymentSession struct {
 	preBuiltRoute      *route.Route
 	preBuiltRouteTried bool
 
+	// directChans holds the outgoing policies of our channels that lead
+	// directly to the payment target. They are used to attempt a single
+	// hop route before falling back to full path finding.
+	directChans []*channeldb.ChannelEdgePolicy
+
+	// directTried indicates whether the direct channel fast path has
+	// already been attempted.
+	directTried bool
+
+	// localChans holds the outgoing policies of all of our channels. It is
+	// only populated if a first hop strategy is configured.
+	localChans []*channeldb.ChannelEdgePolicy
+
 	pathFinder pathFinder
+
+	// prunedEdges and prunedVertices record the failures reported during
+	// this session in the order they were reported, so that they can be
+	// inspected while the payment is in flight. They are indexed by the
+	// failed node and channel in prunedEdgeIndex and prunedVertexIndex.
+	// All four are guarded by prunedMtx.
+	prunedEdges       []PrunedEdge
+	prunedVertices    []PrunedVertex
+	prunedEdgeIndex   map[nodeChannel]int
+	prunedVertexIndex map[route.Vertex]int
+	prunedMtx         sync.Mutex
+
+	// attemptedRoutes holds the keys of the routes that were returned by
+	// this session since the last policy update. It is used to make sure
+	// that the same failing route isn't attempted over and over.
+	attemptedRoutes map[string]struct{}
+
+	// mcMode is the mission control mode of the payment that this session
+	// produces routes for. It is taken from the payment on every route
+	// request.
+	mcMode MissionControlMode
 }
 
 // A compile time assertion to ensure paymentSession meets the PaymentSession
@@ -77,31 +142,139 @@ var _ PaymentSession = (*paymentSession)(nil)
 // This ensures we don't retry this vertex during the payment attempt.
 //
 // NOTE: Part of the PaymentSession interface.
-func (p *paymentSession) ReportVertexFailure(v route.Vertex) {
-	p.mc.reportVertexFailure(v)
+func (p *paymentSession) ReportVertexFailure(v route.Vertex,
+	class FailureClass) {
+
+	if p.mcMode.recordFailures() {
+		p.mc.reportVertexFailure(v, class)
+	}
+
+	p.prunedMtx.Lock()
+	defer p.prunedMtx.Unlock()
+
+	// If the vertex was already reported, only update its failure class.
+	if i, ok := p.prunedVertexIndex[v]; ok {
+		p.prunedVertices[i].Class = class
+		return
+	}
+
+	if p.prunedVertexIndex == nil {
+		p.prunedVertexIndex = make(map[route.Vertex]int)
+	}
+	p.prunedVertexIndex[v] = len(p.prunedVertices)
+	p.prunedVertices = append(p.prunedVertices, PrunedVertex{
+		Node:  v,
+		Class: class,
+	})
 }
 
 // ReportEdgeFailure adds a channel to the graph prune view. The time the
-// channel was added is noted, as it'll be pruned from the global view after a
-// period of edgeDecay. However, the edge will remain pruned for the duration
-// of the *local* session. This ensures that we don't flap by continually
-// retrying an edge after its pruning has expired.
+// channel was added is noted, as its penalty decays with the half-life of the
+// failure's class.
 //
 // TODO(roasbeef): also add value attempted to send and capacity of channel
 //
 // NOTE: Part of the PaymentSession interface.
 func (p *paymentSession) ReportEdgeFailure(failedEdge edge,
-	minPenalizeAmt lnwire.MilliSatoshi) {
+	minPenalizeAmt lnwire.MilliSatoshi, class FailureClass) {
+
+	if p.mcMode.recordFailures() {
+		p.mc.reportEdgeFailure(failedEdge, minPenalizeAmt, class)
+	}
+
+	p.prunedMtx.Lock()
+	defer p.prunedMtx.Unlock()
+
+	pruned := PrunedEdge{
+		From:           failedEdge.from,
+		To:             failedEdge.to,
+		ChannelID:      failedEdge.channel,
+		MinPenalizeAmt: minPenalizeAmt,
+		Class:          class,
+	}
+
+	// If this direction of the channel was already reported, replace the
+	// previous failure.
+	key := nodeChannel{
+		node:    pruned.From,
+		channel: pruned.ChannelID,
+	}
+	if i, ok := p.prunedEdgeIndex[key]; ok {
+		p.prunedEdges[i] = pruned
+		return
+	}
+
+	if p.prunedEdgeIndex == nil {
+		p.prunedEdgeIndex = make(map[nodeChannel]int)
+	}
+	p.prunedEdgeIndex[key] = len(p.prunedEdges)
+	p.prunedEdges = append(p.prunedEdges, pruned)
+}
+
+// PrunedState returns the edges and vertices that were reported as failed
+// during this session.
+//
+// NOTE: Part of the PaymentSession interface.
+func (p *paymentSession) PrunedState() ([]PrunedEdge, []PrunedVertex) {
+	p.prunedMtx.Lock()
+	defer p.prunedMtx.Unlock()
 
-	p.mc.reportEdgeFailure(failedEdge, minPenalizeAmt)
+	edges := make([]PrunedEdge, len(p.prunedEdges))
+	copy(edges, p.prunedEdges)
+
+	vertices := make([]PrunedVertex, len(p.prunedVertices))
+	copy(vertices, p.prunedVertices)
+
+	return edges, vertices
+}
+
+// ReportLocalChannelFailure refreshes the bandwidth hints of our channels, as
+// the failure indicates that they may be out of date, and excludes the failed
+// channel for the remainder of this session.
+//
+// NOTE: Part of the PaymentSession interface.
+func (p *paymentSession) ReportLocalChannelFailure(channel uint64) error {
+	// Sessions for a pre-built route don't use bandwidth hints.
+	if p.bandwidthHints == nil {
+		return nil
+	}
+
+	if p.excludedLocalChans == nil {
+		p.excludedLocalChans = make(map[uint64]struct{})
+	}
+	p.excludedLocalChans[channel] = struct{}{}
+
+	// Exclude all failed channels, including those that failed before,
+	// even if the hints can't be refreshed.
+	defer func() {
+		for excluded := range p.excludedLocalChans {
+			p.bandwidthHints[excluded] = 0
+		}
+	}()
+
+	sourceNode, err := p.mc.graph.SourceNode()
+	if err != nil {
+		return err
+	}
+	bandwidthHints, err := generateBandwidthHints(
+		sourceNode, p.mc.queryBandwidth,
+	)
+	if err != nil {
+		return err
+	}
+
+	p.bandwidthHints = bandwidthHints
+
+	return nil
 }
 
 // ReportEdgePolicyFailure handles a failure message that relates to a
 // channel policy. For these types of failures, the policy is updated and we
 // want to keep it included during path finding. This function does mark the
-// edge as 'policy failed once'. The next time it fails, the whole node will be
-// pruned. This is to prevent nodes from keeping us busy by continuously sending
-// new channel updates.
+// edge as 'policy failed once'. The next time it fails, the edge is penalized
+// with a policy failure. This is to prevent nodes from keeping us busy by
+// continuously sending new channel updates, while allowing the channel to
+// become usable again quickly.
 //
 // NOTE: Part of the PaymentSession interface.
 //
@@ -113,19 +286,21 @@ func (p *paymentSession) ReportEdgePolicyFailure(failedEdge edge) {
 	}
 
 	// Check to see if we've already reported a policy related failure for
-	// this channel. If so, then we'll prune out the vertex.
+	// this channel. If so, then we'll penalize the edge.
 	_, ok := p.errFailedPolicyChans[key]
 	if ok {
-		// TODO(joostjager): is this aggressive pruning still necessary?
-		// Just pruning edges may also work unless there is a huge
-		// number of failing channels from that node?
-		p.ReportVertexFailure(key.node)
+		p.ReportEdgeFailure(failedEdge, 0, FailureClassPolicy)
 
 		return
 	}
 
 	// Finally, we'll record a policy failure from this node and move on.
 	p.errFailedPolicyChans[key] = struct{}{}
+
+	// As the policy of the channel was updated, a route that was attempted
+	// before may now succeed. We'll therefore allow previous routes to be
+	// attempted again.
+	p.attemptedRoutes = nil
 }
 
 // RequestRoute returns a route which is likely to be capable for successfully
@@ -154,40 +329,301 @@ func (p *paymentSession) RequestRoute(payment *LightningPayment,
 		return nil, fmt.Errorf("pre-built route already tried")
 	}
 
+	p.mcMode = payment.MissionControlMode
+
 	// If a route cltv limit was specified, we need to subtract the final
 	// delta before passing it into path finding. The optimal path is
 	// independent of the final cltv delta and the path finding algorithm is
 	// unaware of this value.
 	var cltvLimit *uint32
 	if payment.CltvLimit != nil {
+		// No route can satisfy a limit below the final delta.
+		if *payment.CltvLimit < uint32(finalCltvDelta) {
+			return nil, newErrf(ErrNoPathFound, "cltv limit %v "+
+				"below final cltv delta %v", *payment.CltvLimit,
+				finalCltvDelta)
+		}
+
 		limit := *payment.CltvLimit - uint32(finalCltvDelta)
 		cltvLimit = &limit
 	}
 
+	// If the target is a direct peer, we'll first try to pay it over one
+	// of our channels with it, without involving path finding at all.
+	if !p.directTried {
+		p.directTried = true
+
+		route, err := p.requestDirectRoute(payment, height, finalCltvDelta)
+		if err != nil {
+			return nil, err
+		}
+		if route != nil {
+			p.recordAttemptedRoute(route)
+			return route, nil
+		}
+	}
+
 	// TODO(roasbeef): sync logic amongst dist sys
 
+	selfNode := route.Vertex(p.mc.selfNode.PubKeyBytes)
+	g := &graphParams{
+		graph:           p.mc.graph,
+		additionalEdges: p.additionalEdges,
+		bandwidthHints:  p.bandwidthHints,
+		amountFilter:    p.mc.amountFilters.get(payment.Amount),
+		selfNode:        &selfNode,
+	}
+
+	annotations, err := p.fetchAnnotations(payment)
+	if err != nil {
+		return nil, err
+	}
+	g.annotations = annotations
+
+	restrictions := &RestrictParams{
+		ProbabilitySource:     p.edgeProbability,
+		FeeLimit:              payment.FeeLimit,
+		OutgoingChannelIDs:    payment.OutgoingChannelIDs,
+		LastHop:               payment.LastHop,
+		CltvLimit:             cltvLimit,
+		MaxHops:               payment.MaxHops,
+		IgnoredNodes:          payment.IgnoredNodes,
+		IgnoredPairs:          payment.IgnoredPairs,
+		PaymentAttemptPenalty: p.attemptPenalty(payment),
+		MinProbability:        p.minProbability(payment),
+		LiquidityAdBias:       p.mc.cfg.LiquidityAdBias,
+		TieBreaker:            p.mc.cfg.TieBreaker,
+		HopHintBandwidths:     payment.HopHintBandwidths,
+		AvoidTags:             payment.AvoidTags,
+		HubPenalty:            payment.HubPenalty,
+	}
+
+	// All paths of this request are searched for in the same snapshot of
+	// the graph, so that the route isn't affected by concurrent updates.
+	var route *route.Route
+	err = g.withSnapshot(func() error {
+		var err error
+		route, err = p.findRoute(
+			g, restrictions, payment, height, finalCltvDelta,
+		)
+		if err != nil {
+			return err
+		}
+
+		// If the exact same route was attempted before and no policy
+		// was updated since, it is bound to fail again. In that case
+		// we'll look for a route that differs in at least one hop.
+		if !p.routeAttempted(route) {
+			return nil
+		}
+
+		log.Debugf("Route %v was already attempted, searching for an "+
+			"alternative", routeKey(route))
+
+		route, err = p.findAlternativeRoute(
+			g, restrictions, payment, height, finalCltvDelta, route,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.recordAttemptedRoute(route)
+
+	return route, nil
+}
+
+// AdoptRoute checks whether the given route, which was obtained outside of
+// the session, satisfies the restrictions of the payment and the failures
+// that were reported during the session. If so, it is recorded as attempted,
+// so that the session doesn't return it again.
+//
+// NOTE: Part of the PaymentSession interface.
+func (p *paymentSession) AdoptRoute(payment *LightningPayment,
+	rt *route.Route, height uint32) bool {
+
+	// Sessions for a pre-built route and the empty sessions of resumed
+	// payments don't make any attempts of their own.
+	if p.preBuiltRoute != nil || len(rt.Hops) == 0 {
+		return false
+	}
+
+	p.mcMode = payment.MissionControlMode
+
+	if p.routeAttempted(rt) {
+		return false
+	}
+
+	if err := p.checkRoute(payment, rt, height); err != nil {
+		log.Debugf("Not adopting route %v: %v", routeKey(rt), err)
+		return false
+	}
+
+	p.recordAttemptedRoute(rt)
+
+	return true
+}
+
+// checkRoute returns an error if the route violates one of the restrictions
+// that path finding applies to the routes of the payment.
+func (p *paymentSession) checkRoute(payment *LightningPayment,
+	rt *route.Route, height uint32) error {
+
+	hops := rt.Hops
+	source := route.Vertex(p.mc.selfNode.PubKeyBytes)
+
+	if !outgoingChannelAllowed(
+		payment.OutgoingChannelIDs, hops[0].ChannelID,
+	) {
+
+		return errors.New("outgoing channel not allowed")
+	}
+
+	if payment.MaxHops != 0 && len(hops) > int(payment.MaxHops) {
+		return errors.New("too many hops")
+	}
+
+	if payment.LastHop != nil {
+		lastHop := source
+		if len(hops) > 1 {
+			lastHop = hops[len(hops)-2].PubKeyBytes
+		}
+		if lastHop != *payment.LastHop {
+			return errors.New("last hop not allowed")
+		}
+	}
+
+	if rt.TotalFees() > payment.FeeLimit {
+		return fmt.Errorf("fee %v exceeds limit %v", rt.TotalFees(),
+			payment.FeeLimit)
+	}
+
+	if payment.CltvLimit != nil &&
+		rt.TotalTimeLock-height > *payment.CltvLimit {
+
+		return fmt.Errorf("time lock %v exceeds limit %v",
+			rt.TotalTimeLock-height, *payment.CltvLimit)
+	}
+
+	// The first hop is one of our own channels. Its bandwidth hint
+	// reflects its current balance, and is zero if the channel was
+	// excluded during this session.
+	if p.bandwidthHints != nil {
+		bandwidth, ok := p.bandwidthHints[hops[0].ChannelID]
+		if !ok || bandwidth < rt.TotalAmount {
+			return errors.New("insufficient bandwidth of first hop")
+		}
+	}
+
+	annotations, err := p.fetchAnnotations(payment)
+	if err != nil {
+		return err
+	}
+	avoided := newTagFilter(annotations, payment.AvoidTags)
+
+	// Walk the route to check every channel and intermediate node. The
+	// amount is the amount that is sent over the channel of the current
+	// hop.
+	var (
+		from        = source
+		amt         = rt.TotalAmount
+		probability = 1.0
+	)
+	for i, hop := range hops {
+		to := hop.PubKeyBytes
+
+		if hopIgnored(
+			payment.IgnoredNodes, payment.IgnoredPairs, from, to,
+			i == 0,
+		) {
+
+			return fmt.Errorf("hop %v ignored", i)
+		}
+
+		if avoided.excludesChannel(hop.ChannelID) ||
+			(i > 0 && avoided.excludesNode(from)) {
+
+			return fmt.Errorf("hop %v carries an avoided tag", i)
+		}
+
+		bandwidth, ok := payment.HopHintBandwidths[hop.ChannelID]
+		if ok && bandwidth < amt {
+			return fmt.Errorf("insufficient bandwidth of hop %v", i)
+		}
+
+		locator := newEdgeLocatorByPubkeys(hop.ChannelID, &from, &to)
+		edgeProbability := p.edgeProbability(from, *locator, amt, 0)
+		if edgeProbability == 0 {
+			return fmt.Errorf("hop %v penalized", i)
+		}
+		probability *= edgeProbability
+
+		from = to
+		amt = hop.AmtToForward
+	}
+
+	if probability < p.minProbability(payment) {
+		return fmt.Errorf("probability %v below minimum", probability)
+	}
+
+	return nil
+}
+
+// fetchAnnotations returns the annotations of channels and nodes, if the
+// payment needs to avoid certain tags. Otherwise nil is returned.
+func (p *paymentSession) fetchAnnotations(
+	payment *LightningPayment) (*channeldb.AnnotationSet, error) {
+
+	if len(payment.AvoidTags) == 0 || p.mc.cfg.Annotations == nil {
+		return nil, nil
+	}
+
+	return p.mc.cfg.Annotations.FetchAnnotations()
+}
+
+// findRoute finds a path to the payment target, respecting the given
+// restrictions, and turns it into a route.
+func (p *paymentSession) findRoute(g *graphParams, restrictions *RestrictParams,
+	payment *LightningPayment, height uint32,
+	finalCltvDelta uint16) (*route.Route, error) {
+
+	// If a first hop strategy is configured, we'll let the strategy
+	// decide which of the allowed outgoing channels to try first.
+	var (
+		path []*channeldb.ChannelEdgePolicy
+		err  error
+	)
+	if p.mc.cfg.FirstHopStrategy != nil {
+		path, err = p.findPathWithFirstHop(
+			g, *restrictions, payment.Target,
+			payment.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A payment to our own node can only be routed through a hint that
+	// leads back to us.
+	if path == nil && payment.Target == p.mc.selfNode.PubKeyBytes {
+		path, err = p.findCircularPath(g, restrictions, payment.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Taking into account this prune view, we'll attempt to locate a path
 	// to our destination, respecting the recommendations from
 	// MissionControl.
-	path, err := p.pathFinder(
-		&graphParams{
-			graph:           p.mc.graph,
-			additionalEdges: p.additionalEdges,
-			bandwidthHints:  p.bandwidthHints,
-		},
-		&RestrictParams{
-			ProbabilitySource:     p.mc.getEdgeProbability,
-			FeeLimit:              payment.FeeLimit,
-			OutgoingChannelID:     payment.OutgoingChannelID,
-			CltvLimit:             cltvLimit,
-			PaymentAttemptPenalty: p.mc.cfg.PaymentAttemptPenalty,
-			MinProbability:        p.mc.cfg.MinRouteProbability,
-		},
-		p.mc.selfNode.PubKeyBytes, payment.Target,
-		payment.Amount,
-	)
-	if err != nil {
-		return nil, err
+	if path == nil {
+		path, err = p.pathFinder(
+			g, restrictions, p.mc.selfNode.PubKeyBytes,
+			payment.Target, payment.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// With the next candidate path found, we'll attempt to turn this into
@@ -205,6 +641,247 @@ func (p *paymentSession) RequestRoute(payment *LightningPayment,
 	return route, err
 }
 
+// findAlternativeRoute searches for a route that differs from the given,
+// already attempted, route in at least one hop. It does so by excluding one of
+// the channels of the attempted route at a time.
+func (p *paymentSession) findAlternativeRoute(g *graphParams,
+	restrictions *RestrictParams, payment *LightningPayment, height uint32,
+	finalCltvDelta uint16, attempted *route.Route) (*route.Route, error) {
+
+	for _, hop := range attempted.Hops {
+		excludedChan := hop.ChannelID
+
+		r := *restrictions
+		r.ProbabilitySource = func(node route.Vertex, edge EdgeLocator,
+			amt lnwire.MilliSatoshi, capacity btcutil.Amount) float64 {
+
+			if edge.ChannelID == excludedChan {
+				return 0
+			}
+
+			return restrictions.ProbabilitySource(
+				node, edge, amt, capacity,
+			)
+		}
+
+		rt, err := p.findRoute(g, &r, payment, height, finalCltvDelta)
+		switch {
+		case IsError(err, ErrNoPathFound, ErrMaxHopsExceeded):
+			continue
+
+		case err != nil:
+			return nil, err
+
+		case p.routeAttempted(rt):
+			continue
+		}
+
+		return rt, nil
+	}
+
+	return nil, newErrf(ErrNoPathFound, "all routes to destination "+
+		"were already attempted")
+}
+
+// outgoingChannelAllowed returns true if the channel may be taken to the first
+// hop, given the set of allowed outgoing channels. An empty set allows any
+// channel.
+func outgoingChannelAllowed(allowed []uint64, chanID uint64) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, allowedID := range allowed {
+		if allowedID == chanID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hopIgnored returns true if the hop from one node to the next is excluded by
+// the ignored nodes or node pairs. The source of a route can't be ignored as
+// a node.
+func hopIgnored(ignoredNodes map[route.Vertex]struct{},
+	ignoredPairs map[DirectedNodePair]struct{}, from, to route.Vertex,
+	fromSource bool) bool {
+
+	if !fromSource {
+		if _, ok := ignoredNodes[from]; ok {
+			return true
+		}
+	}
+
+	_, ok := ignoredPairs[NewDirectedNodePair(from, to)]
+	return ok
+}
+
+// routeKey returns a key that identifies the route by the channels and the
+// amounts forwarded over them.
+func routeKey(rt *route.Route) string {
+	var b strings.Builder
+	for i, hop := range rt.Hops {
+		if i > 0 {
+			b.WriteString("->")
+		}
+		fmt.Fprintf(&b, "%v:%v", hop.ChannelID, hop.AmtToForward)
+	}
+
+	return b.String()
+}
+
+// routeAttempted returns true if the exact route was already returned by this
+// session since the last policy update.
+func (p *paymentSession) routeAttempted(rt *route.Route) bool {
+	_, ok := p.attemptedRoutes[routeKey(rt)]
+	return ok
+}
+
+// recordAttemptedRoute records that the route is about to be attempted.
+func (p *paymentSession) recordAttemptedRoute(rt *route.Route) {
+	if p.attemptedRoutes == nil {
+		p.attemptedRoutes = make(map[string]struct{})
+	}
+
+	p.attemptedRoutes[routeKey(rt)] = struct{}{}
+}
+
+// requestDirectRoute attempts to construct a single hop route to the payment
+// target over one of our direct channels with it. Of the channels that are
+// able to carry the payment, the one with the highest bandwidth is selected.
+// If no suitable channel exists, a nil route is returned.
+func (p *paymentSession) requestDirectRoute(payment *LightningPayment,
+	height uint32, finalCltvDelta uint16) (*route.Route, error) {
+
+	// A direct route has our own node as the last hop.
+	if payment.LastHop != nil &&
+		*payment.LastHop != p.mc.selfNode.PubKeyBytes {
+
+		return nil, nil
+	}
+
+	if hopIgnored(
+		payment.IgnoredNodes, payment.IgnoredPairs,
+		p.mc.selfNode.PubKeyBytes, payment.Target, true,
+	) {
+
+		return nil, nil
+	}
+
+	// The time lock of a direct route only consists of the final delta,
+	// as our own channel policy doesn't apply. It must not exceed the
+	// limit of the payment.
+	if payment.CltvLimit != nil &&
+		uint32(finalCltvDelta) > *payment.CltvLimit {
+
+		return nil, nil
+	}
+
+	// Like path finding, skip the channels that carry a tag that the
+	// payment needs to avoid.
+	annotations, err := p.fetchAnnotations(payment)
+	if err != nil {
+		return nil, err
+	}
+	avoided := newTagFilter(annotations, payment.AvoidTags)
+
+	var (
+		bestChan      *channeldb.ChannelEdgePolicy
+		bestBandwidth lnwire.MilliSatoshi
+	)
+	for _, edge := range p.directChans {
+		if !outgoingChannelAllowed(
+			payment.OutgoingChannelIDs, edge.ChannelID,
+		) {
+
+			continue
+		}
+
+		if avoided.excludesChannel(edge.ChannelID) {
+			continue
+		}
+
+		bandwidth, ok := p.bandwidthHints[edge.ChannelID]
+		if !ok || bandwidth < payment.Amount {
+			continue
+		}
+
+		if payment.Amount < edge.MinHTLC {
+			continue
+		}
+		if edge.MaxHTLC != 0 && edge.MaxHTLC < payment.Amount {
+			continue
+		}
+
+		// Respect any failures that mission control has recorded for
+		// this channel during this or previous payments.
+		source := route.Vertex(p.mc.selfNode.PubKeyBytes)
+		probability := p.edgeProbability(
+			source, *newEdgeLocator(edge), payment.Amount, 0,
+		)
+		if probability == 0 || probability < p.minProbability(payment) {
+			continue
+		}
+
+		if bestChan == nil || bandwidth > bestBandwidth {
+			bestChan = edge
+			bestBandwidth = bandwidth
+		}
+	}
+
+	if bestChan == nil {
+		return nil, nil
+	}
+
+	log.Debugf("Attempting direct route to %x over chan_id=%v",
+		payment.Target, bestChan.ChannelID)
+
+	sourceVertex := route.Vertex(p.mc.selfNode.PubKeyBytes)
+	return newRoute(
+		payment.Amount, sourceVertex,
+		[]*channeldb.ChannelEdgePolicy{bestChan}, height,
+		finalCltvDelta,
+	)
+}
+
+// minProbability returns the minimum success probability of the routes that
+// are attempted for the given payment. It is the higher of the minimum that is
+// configured for mission control and the minimum of the payment itself.
+func (p *paymentSession) minProbability(payment *LightningPayment) float64 {
+	if payment.MinSuccessProbability > p.mc.cfg.MinRouteProbability {
+		return payment.MinSuccessProbability
+	}
+
+	return p.mc.cfg.MinRouteProbability
+}
+
+// maxTimePrefFactor is the factor by which the payment attempt penalty is
+// scaled for payments with the maximum time preference of 1.
+const maxTimePrefFactor = 10
+
+// attemptPenalty returns the payment attempt penalty that path finding uses for
+// the given payment. It is scaled by the time preference of the payment. A
+// preference of -1 disables the penalty, so that the cheapest route is taken
+// regardless of its probability, while a preference of 1 raises it by
+// maxTimePrefFactor, so that the most reliable route is taken unless it is
+// much more expensive.
+func (p *paymentSession) attemptPenalty(
+	payment *LightningPayment) lnwire.MilliSatoshi {
+
+	penalty := float64(p.mc.cfg.PaymentAttemptPenalty)
+
+	switch {
+	case payment.TimePref < 0:
+		penalty *= 1 + payment.TimePref
+
+	case payment.TimePref > 0:
+		penalty *= 1 + payment.TimePref*(maxTimePrefFactor-1)
+	}
+
+	return lnwire.MilliSatoshi(penalty)
+}
+
 // nodeChannel is a combination of the node pubkey and one of its channels.
 type nodeChannel struct {
 	node    route.Vertex
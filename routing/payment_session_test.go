@@ -2,7 +2,9 @@ package routing
 
 import (
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcutil"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing/route"
@@ -59,3 +61,905 @@ func TestRequestRoute(t *testing.T) {
 			route.TotalTimeLock)
 	}
 }
+
+// TestRequestRouteDirect asserts that a payment to a direct peer is first
+// attempted over the direct channel with the most bandwidth, without invoking
+// path finding.
+func TestRequestRouteDirect(t *testing.T) {
+	const (
+		height         = 10
+		finalCltvDelta = 8
+	)
+
+	pathFindCalled := false
+	findPath := func(g *graphParams, r *RestrictParams,
+		source, target route.Vertex, amt lnwire.MilliSatoshi) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		pathFindCalled = true
+		return []*channeldb.ChannelEdgePolicy{
+			{
+				Node: &channeldb.LightningNode{},
+			},
+		}, nil
+	}
+
+	target := route.Vertex{1}
+	peer := &channeldb.LightningNode{PubKeyBytes: target}
+
+	session := &paymentSession{
+		mc: &MissionControl{
+			selfNode: &channeldb.LightningNode{},
+			cfg: &MissionControlConfig{
+				AprioriHopProbability: 0.95,
+			},
+			history: make(map[route.Vertex]*nodeHistory),
+			now:     time.Now,
+		},
+		directChans: []*channeldb.ChannelEdgePolicy{
+			{ChannelID: 1, Node: peer},
+			{ChannelID: 2, Node: peer},
+			{ChannelID: 3, Node: peer},
+		},
+		bandwidthHints: map[uint64]lnwire.MilliSatoshi{
+			1: 1000,
+			2: 5000,
+			3: 50,
+		},
+		pathFinder: findPath,
+	}
+
+	payment := &LightningPayment{
+		Target:         target,
+		Amount:         100,
+		FinalCLTVDelta: finalCltvDelta,
+	}
+
+	rt, err := session.RequestRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pathFindCalled {
+		t.Fatal("expected path finding to be skipped")
+	}
+	if len(rt.Hops) != 1 || rt.Hops[0].ChannelID != 2 {
+		t.Fatalf("expected direct route over channel 2, got %v", rt)
+	}
+
+	// The direct route is only attempted once, so the next request should
+	// fall back to path finding.
+	_, err = session.RequestRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pathFindCalled {
+		t.Fatal("expected path finding to be invoked")
+	}
+
+	// A direct route is still possible if the cltv limit equals the final
+	// delta.
+	cltvLimit := uint32(finalCltvDelta)
+	payment.CltvLimit = &cltvLimit
+	rt, err = session.requestDirectRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt == nil {
+		t.Fatal("expected direct route within cltv limit")
+	}
+
+	// A lower limit excludes the direct route, and any other route.
+	cltvLimit = finalCltvDelta - 1
+	rt, err = session.requestDirectRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt != nil {
+		t.Fatalf("expected no direct route, got %v", rt)
+	}
+
+	session.directTried = false
+	pathFindCalled = false
+	_, err = session.RequestRoute(payment, height, finalCltvDelta)
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
+	}
+	if pathFindCalled {
+		t.Fatal("expected path finding to be skipped")
+	}
+}
+
+// TestRequestRouteDirectAvoidTags asserts that the direct route skips channels
+// that carry a tag that the payment needs to avoid.
+func TestRequestRouteDirectAvoidTags(t *testing.T) {
+	t.Parallel()
+
+	const (
+		height         = 10
+		finalCltvDelta = 8
+	)
+
+	target := route.Vertex{1}
+	peer := &channeldb.LightningNode{PubKeyBytes: target}
+
+	// Channel 2 is tagged as a channel to avoid.
+	annotations := &channeldb.AnnotationSet{
+		Channels: map[uint64]*channeldb.Annotation{
+			2: {Tags: []string{"tier3"}},
+		},
+	}
+
+	session := &paymentSession{
+		mc: &MissionControl{
+			selfNode: &channeldb.LightningNode{},
+			cfg: &MissionControlConfig{
+				AprioriHopProbability: 0.95,
+				Annotations: &mockAnnotationSource{
+					annotations: annotations,
+				},
+			},
+			history: make(map[route.Vertex]*nodeHistory),
+			now:     time.Now,
+		},
+		directChans: []*channeldb.ChannelEdgePolicy{
+			{ChannelID: 1, Node: peer},
+			{ChannelID: 2, Node: peer},
+		},
+		bandwidthHints: map[uint64]lnwire.MilliSatoshi{
+			1: 1000,
+			2: 5000,
+		},
+	}
+
+	payment := &LightningPayment{
+		Target:         target,
+		Amount:         100,
+		FinalCLTVDelta: finalCltvDelta,
+	}
+
+	// Without tags to avoid, the channel with the most bandwidth is used.
+	rt, err := session.requestDirectRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt == nil || rt.Hops[0].ChannelID != 2 {
+		t.Fatalf("expected direct route over channel 2, got %v", rt)
+	}
+
+	// The tagged channel is skipped if the payment avoids its tag.
+	payment.AvoidTags = []string{"tier3"}
+	rt, err = session.requestDirectRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt == nil || rt.Hops[0].ChannelID != 1 {
+		t.Fatalf("expected direct route over channel 1, got %v", rt)
+	}
+
+	// If all direct channels are tagged, there is no direct route.
+	delete(session.bandwidthHints, 1)
+	rt, err = session.requestDirectRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt != nil {
+		t.Fatalf("expected no direct route, got %v", rt)
+	}
+}
+
+// TestRequestRouteFirstHop asserts that path finding is restricted to the
+// first hop candidates in the order of the configured strategy, and that
+// candidates over which no path exists are skipped.
+func TestRequestRouteFirstHop(t *testing.T) {
+	const (
+		height         = 10
+		finalCltvDelta = 8
+	)
+
+	var tried []uint64
+	findPath := func(g *graphParams, r *RestrictParams,
+		source, target route.Vertex, amt lnwire.MilliSatoshi) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		if len(r.OutgoingChannelIDs) != 1 {
+			t.Fatal("expected outgoing channel restriction")
+		}
+		chanID := r.OutgoingChannelIDs[0]
+		tried = append(tried, chanID)
+
+		if chanID == 2 {
+			return nil, newErrf(ErrNoPathFound, "no path")
+		}
+
+		return []*channeldb.ChannelEdgePolicy{
+			{
+				ChannelID: chanID,
+				Node:      &channeldb.LightningNode{},
+			},
+		}, nil
+	}
+
+	session := &paymentSession{
+		mc: &MissionControl{
+			selfNode: &channeldb.LightningNode{},
+			cfg: &MissionControlConfig{
+				AprioriHopProbability: 0.95,
+				FirstHopStrategy:      &HeadroomFirstHopStrategy{},
+			},
+			history: make(map[route.Vertex]*nodeHistory),
+			now:     time.Now,
+		},
+		localChans: []*channeldb.ChannelEdgePolicy{
+			{ChannelID: 1, Node: &channeldb.LightningNode{
+				PubKeyBytes: route.Vertex{1},
+			}},
+			{ChannelID: 2, Node: &channeldb.LightningNode{
+				PubKeyBytes: route.Vertex{2},
+			}},
+			{ChannelID: 3, Node: &channeldb.LightningNode{
+				PubKeyBytes: route.Vertex{3},
+			}},
+		},
+		bandwidthHints: map[uint64]lnwire.MilliSatoshi{
+			1: 1000,
+			2: 5000,
+			3: 50,
+		},
+		directTried: true,
+		pathFinder:  findPath,
+	}
+
+	payment := &LightningPayment{
+		Target:         route.Vertex{9},
+		Amount:         100,
+		FinalCLTVDelta: finalCltvDelta,
+	}
+
+	rt, err := session.RequestRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Channel 3 can't carry the amount. Of the others, channel 2 has the
+	// most headroom, but doesn't lead to the target.
+	if len(tried) != 2 || tried[0] != 2 || tried[1] != 1 {
+		t.Fatalf("unexpected first hops tried: %v", tried)
+	}
+	if rt.Hops[0].ChannelID != 1 {
+		t.Fatalf("expected route over channel 1, got %v",
+			rt.Hops[0].ChannelID)
+	}
+}
+
+// TestRoundRobinFirstHopStrategy asserts that the round robin strategy
+// prefers a different candidate on every call.
+func TestRoundRobinFirstHopStrategy(t *testing.T) {
+	t.Parallel()
+
+	strategy := &RoundRobinFirstHopStrategy{}
+
+	for _, expected := range []uint64{1, 2, 3, 1} {
+		candidates := []FirstHopCandidate{
+			{ChannelID: 3}, {ChannelID: 1}, {ChannelID: 2},
+		}
+
+		ranked := strategy.Rank(candidates, 0)
+		if len(ranked) != 3 {
+			t.Fatalf("expected 3 candidates, got %v", len(ranked))
+		}
+		if ranked[0].ChannelID != expected {
+			t.Fatalf("expected channel %v first, got %v",
+				expected, ranked[0].ChannelID)
+		}
+	}
+}
+
+// TestRequestRouteNoRepeat asserts that the session doesn't return a route
+// that it returned before, unless a policy was updated in the mean time.
+func TestRequestRouteNoRepeat(t *testing.T) {
+	const (
+		height         = 10
+		finalCltvDelta = 8
+	)
+
+	target := route.Vertex{1}
+	targetNode := &channeldb.LightningNode{PubKeyBytes: target}
+
+	// The path finder prefers channel 1 and falls back to channel 2 if
+	// channel 1 is excluded.
+	findPath := func(g *graphParams, r *RestrictParams,
+		source, target route.Vertex, amt lnwire.MilliSatoshi) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		for _, chanID := range []uint64{1, 2} {
+			p := r.ProbabilitySource(
+				source, EdgeLocator{ChannelID: chanID}, amt, 0,
+			)
+			if p == 0 {
+				continue
+			}
+
+			return []*channeldb.ChannelEdgePolicy{
+				{ChannelID: chanID, Node: targetNode},
+			}, nil
+		}
+
+		return nil, newErr(ErrNoPathFound, "no path")
+	}
+
+	session := &paymentSession{
+		mc: &MissionControl{
+			selfNode: &channeldb.LightningNode{},
+			cfg: &MissionControlConfig{
+				AprioriHopProbability: 0.95,
+			},
+			history: make(map[route.Vertex]*nodeHistory),
+			now:     time.Now,
+		},
+		errFailedPolicyChans: make(map[nodeChannel]struct{}),
+		directTried:          true,
+		pathFinder:           findPath,
+	}
+
+	payment := &LightningPayment{
+		Target:         target,
+		Amount:         1000,
+		FinalCLTVDelta: finalCltvDelta,
+	}
+
+	expectRoute := func(chanID uint64) {
+		t.Helper()
+
+		rt, err := session.RequestRoute(payment, height, finalCltvDelta)
+		if err != nil {
+			t.Fatalf("unable to request route: %v", err)
+		}
+		if rt.Hops[0].ChannelID != chanID {
+			t.Fatalf("expected route over channel %v, got %v",
+				chanID, rt.Hops[0].ChannelID)
+		}
+	}
+
+	// The preferred route is returned first. As it was attempted already,
+	// the alternative route is returned next.
+	expectRoute(1)
+	expectRoute(2)
+
+	// With both routes attempted, no route should be returned.
+	_, err := session.RequestRoute(payment, height, finalCltvDelta)
+	if !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
+	}
+
+	// After a policy update, the previous routes may succeed again.
+	session.ReportEdgePolicyFailure(edge{
+		to:      target,
+		channel: 1,
+	})
+	expectRoute(1)
+}
+
+// TestRequestRouteMinProbability asserts that the minimum success probability
+// of a payment is passed to path finding if it exceeds the configured minimum,
+// and that direct channels that are less likely to succeed are skipped.
+func TestRequestRouteMinProbability(t *testing.T) {
+	const (
+		height         = 10
+		finalCltvDelta = 8
+	)
+
+	var minProbability float64
+	findPath := func(g *graphParams, r *RestrictParams,
+		source, target route.Vertex, amt lnwire.MilliSatoshi) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		minProbability = r.MinProbability
+		return []*channeldb.ChannelEdgePolicy{
+			{
+				Node: &channeldb.LightningNode{},
+			},
+		}, nil
+	}
+
+	target := route.Vertex{1}
+	peer := &channeldb.LightningNode{PubKeyBytes: target}
+
+	newSession := func() *paymentSession {
+		return &paymentSession{
+			mc: &MissionControl{
+				selfNode: &channeldb.LightningNode{},
+				cfg: &MissionControlConfig{
+					AprioriHopProbability: 0.6,
+					MinRouteProbability:   0.01,
+				},
+				history: make(map[route.Vertex]*nodeHistory),
+				now:     time.Now,
+			},
+			directChans: []*channeldb.ChannelEdgePolicy{
+				{ChannelID: 1, Node: peer},
+			},
+			bandwidthHints: map[uint64]lnwire.MilliSatoshi{
+				1: 1000,
+			},
+			pathFinder: findPath,
+		}
+	}
+
+	payment := &LightningPayment{
+		Target:         target,
+		Amount:         100,
+		FinalCLTVDelta: finalCltvDelta,
+	}
+
+	// Without a minimum of its own, the payment uses the direct channel
+	// and the configured minimum applies to path finding.
+	session := newSession()
+	rt, err := session.RequestRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.Hops) != 1 || rt.Hops[0].ChannelID != 1 {
+		t.Fatalf("expected direct route, got %v", rt)
+	}
+	if _, err := session.RequestRoute(
+		payment, height, finalCltvDelta,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if minProbability != 0.01 {
+		t.Fatalf("expected min probability 0.01, got %v",
+			minProbability)
+	}
+
+	// With a minimum above the a priori probability of the direct
+	// channel, the direct route is skipped and the minimum of the payment
+	// is passed to path finding.
+	payment.MinSuccessProbability = 0.8
+	session = newSession()
+	minProbability = 0
+	if _, err := session.RequestRoute(
+		payment, height, finalCltvDelta,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if minProbability != 0.8 {
+		t.Fatalf("expected min probability 0.8, got %v",
+			minProbability)
+	}
+}
+
+// TestAttemptPenaltyTimePref asserts that the payment attempt penalty is
+// scaled by the time preference of the payment.
+func TestAttemptPenaltyTimePref(t *testing.T) {
+	t.Parallel()
+
+	session := &paymentSession{
+		mc: &MissionControl{
+			cfg: &MissionControlConfig{
+				PaymentAttemptPenalty: 1000,
+			},
+		},
+	}
+
+	testCases := []struct {
+		timePref float64
+		expected lnwire.MilliSatoshi
+	}{
+		{timePref: -1, expected: 0},
+		{timePref: -0.5, expected: 500},
+		{timePref: 0, expected: 1000},
+		{timePref: 0.5, expected: 5500},
+		{timePref: 1, expected: 10000},
+	}
+
+	for _, test := range testCases {
+		penalty := session.attemptPenalty(&LightningPayment{
+			TimePref: test.timePref,
+		})
+		if penalty != test.expected {
+			t.Fatalf("time preference %v: expected penalty %v, "+
+				"got %v", test.timePref, test.expected, penalty)
+		}
+	}
+}
+
+// TestRequestRouteMissionControlMode asserts that a payment that bypasses
+// mission control ignores its penalties, only takes the failures of its own
+// session into account and reports them back to mission control in
+// record-only mode.
+func TestRequestRouteMissionControlMode(t *testing.T) {
+	const (
+		height         = 10
+		finalCltvDelta = 8
+	)
+
+	var probabilitySource func(route.Vertex, EdgeLocator,
+		lnwire.MilliSatoshi, btcutil.Amount) float64
+	findPath := func(g *graphParams, r *RestrictParams,
+		source, target route.Vertex, amt lnwire.MilliSatoshi) (
+		[]*channeldb.ChannelEdgePolicy, error) {
+
+		probabilitySource = r.ProbabilitySource
+		return []*channeldb.ChannelEdgePolicy{
+			{
+				Node: &channeldb.LightningNode{},
+			},
+		}, nil
+	}
+
+	penalizedNode := route.Vertex{1}
+	sessionNode := route.Vertex{2}
+
+	mc := &MissionControl{
+		selfNode: &channeldb.LightningNode{},
+		cfg: &MissionControlConfig{
+			AprioriHopProbability: 0.6,
+			PenaltyHalfLife:       time.Hour,
+		},
+		history: make(map[route.Vertex]*nodeHistory),
+		now:     time.Now,
+	}
+	mc.reportVertexFailure(penalizedNode, FailureClassTemporary)
+
+	probability := func(node route.Vertex) float64 {
+		return probabilitySource(node, EdgeLocator{ChannelID: 1}, 100, 0)
+	}
+
+	tests := []struct {
+		mode     MissionControlMode
+		recorded bool
+	}{
+		{mode: MissionControlBypass, recorded: false},
+		{mode: MissionControlRecordOnly, recorded: true},
+	}
+
+	for _, test := range tests {
+		session := &paymentSession{
+			mc:         mc,
+			pathFinder: findPath,
+		}
+		payment := &LightningPayment{
+			Amount:             100,
+			FinalCLTVDelta:     finalCltvDelta,
+			MissionControlMode: test.mode,
+		}
+
+		_, err := session.RequestRoute(payment, height, finalCltvDelta)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The penalty of mission control should be ignored.
+		if probability(penalizedNode) != 0.6 {
+			t.Fatalf("%v: expected penalty to be ignored, got "+
+				"probability %v", test.mode,
+				probability(penalizedNode))
+		}
+
+		// A failure in this session should be taken into account.
+		session.ReportVertexFailure(sessionNode, FailureClassTemporary)
+		if probability(sessionNode) != 0 {
+			t.Fatalf("%v: expected session failure to apply, got "+
+				"probability %v", test.mode,
+				probability(sessionNode))
+		}
+
+		_, recorded := mc.history[sessionNode]
+		if recorded != test.recorded {
+			t.Fatalf("%v: expected failure recorded=%v, got %v",
+				test.mode, test.recorded, recorded)
+		}
+	}
+
+	// In the default mode, the penalty of mission control applies.
+	session := &paymentSession{
+		mc:         mc,
+		pathFinder: findPath,
+	}
+	payment := &LightningPayment{
+		Amount:         100,
+		FinalCLTVDelta: finalCltvDelta,
+	}
+	_, err := session.RequestRoute(payment, height, finalCltvDelta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if probability(penalizedNode) >= 0.6 {
+		t.Fatalf("expected penalty to apply, got probability %v",
+			probability(penalizedNode))
+	}
+}
+
+// TestReportLocalChannelFailure asserts that our channels that failed during a
+// session remain excluded when the bandwidth hints are refreshed after a
+// subsequent failure.
+func TestReportLocalChannelFailure(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	mc := ctx.router.cfg.MissionControl.(*MissionControl)
+	session, err := mc.NewPaymentSession(nil, ctx.aliases["sophon"])
+	if err != nil {
+		t.Fatalf("unable to create session: %v", err)
+	}
+	ps := session.(*paymentSession)
+
+	var localChans []uint64
+	for channel, bandwidth := range ps.bandwidthHints {
+		if bandwidth > 0 {
+			localChans = append(localChans, channel)
+		}
+	}
+	if len(localChans) < 2 {
+		t.Fatalf("expected at least 2 local channels, got %v",
+			len(localChans))
+	}
+
+	// Report a failure of each channel in turn. Every report refreshes
+	// the hints, which must not re-enable the channels that failed
+	// before.
+	for i, channel := range localChans[:2] {
+		if err := session.ReportLocalChannelFailure(channel); err != nil {
+			t.Fatalf("unable to report failure: %v", err)
+		}
+
+		for _, excluded := range localChans[:i+1] {
+			if ps.bandwidthHints[excluded] != 0 {
+				t.Fatalf("expected channel %v to be excluded",
+					excluded)
+			}
+		}
+		for _, included := range localChans[i+1:] {
+			if ps.bandwidthHints[included] == 0 {
+				t.Fatalf("expected channel %v to be included",
+					included)
+			}
+		}
+	}
+}
+
+// TestReportEdgePolicyFailureRepeat asserts that only a repeated policy
+// failure of the same channel penalizes it, and that the penalty applies to
+// the channel rather than to the node that reported the failure.
+func TestReportEdgePolicyFailureRepeat(t *testing.T) {
+	t.Parallel()
+
+	mc := NewMissionControl(
+		nil, nil, nil, &MissionControlConfig{
+			PenaltyHalfLife:       time.Hour,
+			AprioriHopProbability: 0.6,
+		},
+	)
+
+	// Freeze time, so that a penalty doesn't start to recover.
+	now := time.Unix(1000, 0)
+	mc.now = func() time.Time { return now }
+
+	session := &paymentSession{
+		errFailedPolicyChans: make(map[nodeChannel]struct{}),
+		mc:                   mc,
+	}
+
+	nodeA := route.Vertex{1}
+	nodeB := route.Vertex{2}
+	failedEdge := edge{from: nodeA, to: nodeB, channel: 1}
+
+	probability := func(channel uint64) float64 {
+		return session.edgeProbability(
+			nodeA, EdgeLocator{ChannelID: channel}, 1000, 0,
+		)
+	}
+
+	// The first policy failure only records the failure.
+	session.ReportEdgePolicyFailure(failedEdge)
+	edges, vertices := session.PrunedState()
+	if len(edges) != 0 || len(vertices) != 0 {
+		t.Fatalf("expected nothing pruned, got %v edges and %v "+
+			"vertices", len(edges), len(vertices))
+	}
+	if probability(1) != 0.6 {
+		t.Fatalf("expected channel not to be penalized, got "+
+			"probability %v", probability(1))
+	}
+
+	// The second failure of the same channel penalizes the edge.
+	session.ReportEdgePolicyFailure(failedEdge)
+	edges, vertices = session.PrunedState()
+	if len(edges) != 1 || edges[0].ChannelID != 1 ||
+		edges[0].From != nodeA || edges[0].Class != FailureClassPolicy {
+
+		t.Fatalf("unexpected pruned edges %v", edges)
+	}
+	if len(vertices) != 0 {
+		t.Fatalf("expected no pruned vertices, got %v", vertices)
+	}
+	if probability(1) != 0 {
+		t.Fatalf("expected channel to be penalized, got "+
+			"probability %v", probability(1))
+	}
+
+	// Other channels of the node aren't affected.
+	if probability(2) != 0.6 {
+		t.Fatalf("expected other channel not to be penalized, got "+
+			"probability %v", probability(2))
+	}
+}
+
+// TestAdoptRoute asserts that a route that was obtained outside of the
+// session, such as a journaled route, is only adopted if it satisfies the
+// restrictions of the payment and the failures of the session.
+func TestAdoptRoute(t *testing.T) {
+	t.Parallel()
+
+	const height = 10
+
+	nodeA := route.Vertex{2}
+	target := route.Vertex{1}
+
+	// The route leads from our own node over node a to the target.
+	rt := &route.Route{
+		TotalTimeLock: height + 48,
+		TotalAmount:   1100,
+		Hops: []*route.Hop{
+			{
+				PubKeyBytes:      nodeA,
+				ChannelID:        1,
+				AmtToForward:     1000,
+				OutgoingTimeLock: height + 8,
+			},
+			{
+				PubKeyBytes:      target,
+				ChannelID:        2,
+				AmtToForward:     1000,
+				OutgoingTimeLock: height + 8,
+			},
+		},
+	}
+
+	newSession := func(annotations *channeldb.AnnotationSet) *paymentSession {
+		mc := NewMissionControl(
+			nil, &channeldb.LightningNode{}, nil,
+			&MissionControlConfig{
+				PenaltyHalfLife:       time.Hour,
+				AprioriHopProbability: 0.6,
+				MinRouteProbability:   0.01,
+				Annotations: &mockAnnotationSource{
+					annotations: annotations,
+				},
+			},
+		)
+
+		return &paymentSession{
+			errFailedPolicyChans: make(map[nodeChannel]struct{}),
+			mc:                   mc,
+			bandwidthHints: map[uint64]lnwire.MilliSatoshi{
+				1: 5000,
+			},
+		}
+	}
+
+	newPayment := func() *LightningPayment {
+		return &LightningPayment{
+			Target:   target,
+			Amount:   1000,
+			FeeLimit: 1000,
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		annotations *channeldb.AnnotationSet
+		prepare     func(*paymentSession, *LightningPayment)
+		adopted     bool
+	}{
+		{
+			name:    "no restrictions",
+			adopted: true,
+		},
+		{
+			name: "fee limit",
+			prepare: func(_ *paymentSession, p *LightningPayment) {
+				p.FeeLimit = 50
+			},
+		},
+		{
+			name: "avoided node",
+			annotations: &channeldb.AnnotationSet{
+				Nodes: map[route.Vertex]*channeldb.Annotation{
+					nodeA: {Tags: []string{"tier3"}},
+				},
+			},
+			prepare: func(_ *paymentSession, p *LightningPayment) {
+				p.AvoidTags = []string{"tier3"}
+			},
+		},
+		{
+			name: "avoided channel",
+			annotations: &channeldb.AnnotationSet{
+				Channels: map[uint64]*channeldb.Annotation{
+					2: {Tags: []string{"tier3"}},
+				},
+			},
+			prepare: func(_ *paymentSession, p *LightningPayment) {
+				p.AvoidTags = []string{"tier3"}
+			},
+		},
+		{
+			name: "penalized channel",
+			prepare: func(s *paymentSession, _ *LightningPayment) {
+				s.ReportEdgeFailure(edge{
+					from:    nodeA,
+					to:      target,
+					channel: 2,
+				}, 0, FailureClassTemporary)
+			},
+		},
+		{
+			name: "below min probability",
+			prepare: func(_ *paymentSession, p *LightningPayment) {
+				p.MinSuccessProbability = 0.5
+			},
+		},
+		{
+			name: "insufficient hop hint bandwidth",
+			prepare: func(_ *paymentSession, p *LightningPayment) {
+				p.HopHintBandwidths = map[uint64]lnwire.MilliSatoshi{
+					2: 500,
+				}
+			},
+		},
+		{
+			name: "excluded local channel",
+			prepare: func(s *paymentSession, _ *LightningPayment) {
+				s.bandwidthHints[1] = 0
+			},
+		},
+		{
+			name: "already attempted",
+			prepare: func(s *paymentSession, _ *LightningPayment) {
+				s.recordAttemptedRoute(rt)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		session := newSession(tc.annotations)
+		payment := newPayment()
+		if tc.prepare != nil {
+			tc.prepare(session, payment)
+		}
+
+		adopted := session.AdoptRoute(payment, rt, height)
+		if adopted != tc.adopted {
+			t.Fatalf("%v: expected adopted=%v, got %v", tc.name,
+				tc.adopted, adopted)
+		}
+	}
+
+	// An adopted route is recorded as attempted, so that it isn't adopted
+	// or returned by the session again.
+	session := newSession(nil)
+	if !session.AdoptRoute(newPayment(), rt, height) {
+		t.Fatal("expected route to be adopted")
+	}
+	if !session.routeAttempted(rt) {
+		t.Fatal("expected adopted route to be recorded as attempted")
+	}
+	if session.AdoptRoute(newPayment(), rt, height) {
+		t.Fatal("expected route not to be adopted twice")
+	}
+
+	// The empty session of a resumed payment doesn't adopt any route.
+	empty := session.mc.NewPaymentSessionEmpty()
+	if empty.AdoptRoute(newPayment(), rt, height) {
+		t.Fatal("expected empty session not to adopt route")
+	}
+}
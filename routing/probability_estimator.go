@@ -0,0 +1,217 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+const (
+	// AprioriEstimatorName is the name of the apriori probability
+	// estimator.
+	AprioriEstimatorName = "apriori"
+
+	// BimodalEstimatorName is the name of the bimodal probability
+	// estimator.
+	BimodalEstimatorName = "bimodal"
+
+	// HistoricalEstimatorName is the name of the purely historical
+	// probability estimator.
+	HistoricalEstimatorName = "historical"
+
+	// DefaultBimodalScale is the default scale of the balance distribution
+	// that the bimodal estimator assumes.
+	DefaultBimodalScale = btcutil.Amount(300000)
+)
+
+// EdgeHistory summarizes what mission control knows about the outcomes of
+// previous payment attempts over an edge. It is the input to a
+// ProbabilityEstimator.
+type EdgeHistory struct {
+	// LastFailure is the time of the last failure that applies to the
+	// edge, either on the channel or on the node level. It is nil if no
+	// failure applies.
+	LastFailure *time.Time
+
+	// LastFailureClass is the class of the last failure.
+	LastFailureClass FailureClass
+
+	// HalfLife is the configured half-life of the class of the last
+	// failure.
+	HalfLife time.Duration
+}
+
+// recoveryFactor returns the factor in [0, 1] by which the success
+// probability is reduced because of the last failure. It is an exponential
+// curve that drops to zero when a failure occurs, and recovers asymptotically
+// back to one at a rate given by the half-life of the failure.
+func (h *EdgeHistory) recoveryFactor(now time.Time) float64 {
+	if h.LastFailure == nil {
+		return 1
+	}
+
+	timeSinceLastFailure := now.Sub(*h.LastFailure)
+	exp := -timeSinceLastFailure.Hours() / h.HalfLife.Hours()
+
+	return 1 - math.Pow(2, exp)
+}
+
+// ProbabilityEstimator estimates the success probability of forwarding a
+// payment over an edge. Mission control delegates the estimation to the
+// configured estimator, which allows experimenting with different models
+// without modifying the payment logic.
+type ProbabilityEstimator interface {
+	// EdgeProbability returns the probability in [0, 1] that the given
+	// amount can be forwarded over an edge with the given history and
+	// capacity. A capacity of zero indicates that the capacity is
+	// unknown.
+	EdgeProbability(now time.Time, history *EdgeHistory,
+		amt lnwire.MilliSatoshi, capacity btcutil.Amount) float64
+
+	// String returns the name of the estimator.
+	String() string
+}
+
+// AprioriEstimator assumes a fixed a priori success probability for every
+// edge. After a failure, the probability drops to zero and recovers back to
+// the a priori probability.
+type AprioriEstimator struct {
+	// AprioriHopProbability is the assumed success probability of an
+	// edge without recent failures.
+	AprioriHopProbability float64
+}
+
+// A compile time assertion to ensure AprioriEstimator meets the
+// ProbabilityEstimator interface.
+var _ ProbabilityEstimator = (*AprioriEstimator)(nil)
+
+// EdgeProbability returns the a priori probability, reduced by the last
+// failure.
+//
+// NOTE: Part of the ProbabilityEstimator interface.
+func (e *AprioriEstimator) EdgeProbability(now time.Time, history *EdgeHistory,
+	_ lnwire.MilliSatoshi, _ btcutil.Amount) float64 {
+
+	return e.AprioriHopProbability * history.recoveryFactor(now)
+}
+
+// String returns the name of the estimator.
+//
+// NOTE: Part of the ProbabilityEstimator interface.
+func (e *AprioriEstimator) String() string {
+	return AprioriEstimatorName
+}
+
+// BimodalEstimator models the balance of a channel as being concentrated near
+// either of its ends, as is commonly observed for channels that are mostly
+// used in one direction. The probability that a payment succeeds is the
+// probability that the local balance of the channel is at least the payment
+// amount, reduced by the last failure.
+type BimodalEstimator struct {
+	// Scale is the characteristic distance from the channel ends over
+	// which the balance probability density decays.
+	Scale btcutil.Amount
+
+	// AprioriHopProbability is the success probability that is assumed
+	// for edges of which the capacity is unknown.
+	AprioriHopProbability float64
+}
+
+// A compile time assertion to ensure BimodalEstimator meets the
+// ProbabilityEstimator interface.
+var _ ProbabilityEstimator = (*BimodalEstimator)(nil)
+
+// EdgeProbability returns the probability that the balance of the channel is
+// sufficient to forward the amount, reduced by the last failure.
+//
+// NOTE: Part of the ProbabilityEstimator interface.
+func (e *BimodalEstimator) EdgeProbability(now time.Time, history *EdgeHistory,
+	amt lnwire.MilliSatoshi, capacity btcutil.Amount) float64 {
+
+	return e.balanceProbability(amt, capacity) *
+		history.recoveryFactor(now)
+}
+
+// balanceProbability returns the probability that the balance of a channel
+// with the given capacity is at least the given amount. The balance density is
+// taken to be proportional to exp(-x/s) + exp((x-c)/s), of which the integral
+// from amt to c is normalized by the integral over the full capacity.
+func (e *BimodalEstimator) balanceProbability(amt lnwire.MilliSatoshi,
+	capacity btcutil.Amount) float64 {
+
+	if capacity == 0 || e.Scale == 0 {
+		return e.AprioriHopProbability
+	}
+
+	c := float64(lnwire.NewMSatFromSatoshis(capacity))
+	s := float64(lnwire.NewMSatFromSatoshis(e.Scale))
+	a := float64(amt)
+
+	if a > c {
+		return 0
+	}
+
+	norm := 2 * (1 - math.Exp(-c/s))
+	mass := math.Exp(-a/s) - math.Exp(-c/s) + 1 - math.Exp((a-c)/s)
+
+	return mass / norm
+}
+
+// String returns the name of the estimator.
+//
+// NOTE: Part of the ProbabilityEstimator interface.
+func (e *BimodalEstimator) String() string {
+	return BimodalEstimatorName
+}
+
+// HistoricalEstimator only takes observed failures into account. Edges
+// without recent failures are assumed to always succeed.
+type HistoricalEstimator struct{}
+
+// A compile time assertion to ensure HistoricalEstimator meets the
+// ProbabilityEstimator interface.
+var _ ProbabilityEstimator = (*HistoricalEstimator)(nil)
+
+// EdgeProbability returns the probability implied by the last failure only.
+//
+// NOTE: Part of the ProbabilityEstimator interface.
+func (e *HistoricalEstimator) EdgeProbability(now time.Time,
+	history *EdgeHistory, _ lnwire.MilliSatoshi, _ btcutil.Amount) float64 {
+
+	return history.recoveryFactor(now)
+}
+
+// String returns the name of the estimator.
+//
+// NOTE: Part of the ProbabilityEstimator interface.
+func (e *HistoricalEstimator) String() string {
+	return HistoricalEstimatorName
+}
+
+// NewProbabilityEstimator returns the estimator with the given name. An empty
+// name selects the apriori estimator.
+func NewProbabilityEstimator(name string, aprioriHopProbability float64,
+	bimodalScale btcutil.Amount) (ProbabilityEstimator, error) {
+
+	switch name {
+	case "", AprioriEstimatorName:
+		return &AprioriEstimator{
+			AprioriHopProbability: aprioriHopProbability,
+		}, nil
+
+	case BimodalEstimatorName:
+		return &BimodalEstimator{
+			Scale:                 bimodalScale,
+			AprioriHopProbability: aprioriHopProbability,
+		}, nil
+
+	case HistoricalEstimatorName:
+		return &HistoricalEstimator{}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown probability estimator %q", name)
+	}
+}
@@ -0,0 +1,164 @@
+package routing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// TestProbabilityEstimators tests the success probabilities returned by the
+// available estimators.
+func TestProbabilityEstimators(t *testing.T) {
+	t.Parallel()
+
+	now := testTime
+	lastFailure := now.Add(-time.Hour)
+
+	noHistory := &EdgeHistory{}
+	failedHistory := &EdgeHistory{
+		LastFailure: &lastFailure,
+		HalfLife:    time.Hour,
+	}
+
+	const capacity = btcutil.Amount(100000)
+	capacityMsat := lnwire.NewMSatFromSatoshis(capacity)
+
+	tests := []struct {
+		name      string
+		estimator ProbabilityEstimator
+		history   *EdgeHistory
+		amt       lnwire.MilliSatoshi
+		capacity  btcutil.Amount
+		expected  float64
+	}{
+		{
+			name:      "apriori no history",
+			estimator: &AprioriEstimator{AprioriHopProbability: 0.6},
+			history:   noHistory,
+			expected:  0.6,
+		},
+		{
+			name:      "apriori failure",
+			estimator: &AprioriEstimator{AprioriHopProbability: 0.6},
+			history:   failedHistory,
+			expected:  0.3,
+		},
+		{
+			name:      "historical no history",
+			estimator: &HistoricalEstimator{},
+			history:   noHistory,
+			expected:  1,
+		},
+		{
+			name:      "historical failure",
+			estimator: &HistoricalEstimator{},
+			history:   failedHistory,
+			expected:  0.5,
+		},
+		{
+			name: "bimodal unknown capacity",
+			estimator: &BimodalEstimator{
+				Scale:                 10000,
+				AprioriHopProbability: 0.6,
+			},
+			history:  failedHistory,
+			amt:      1000,
+			expected: 0.3,
+		},
+		{
+			name:      "bimodal zero amount",
+			estimator: &BimodalEstimator{Scale: 10000},
+			history:   noHistory,
+			capacity:  capacity,
+			expected:  1,
+		},
+		{
+			name:      "bimodal half capacity",
+			estimator: &BimodalEstimator{Scale: 10000},
+			history:   noHistory,
+			amt:       capacityMsat / 2,
+			capacity:  capacity,
+			expected:  0.5,
+		},
+		{
+			name:      "bimodal half capacity failure",
+			estimator: &BimodalEstimator{Scale: 10000},
+			history:   failedHistory,
+			amt:       capacityMsat / 2,
+			capacity:  capacity,
+			expected:  0.25,
+		},
+		{
+			name:      "bimodal full capacity",
+			estimator: &BimodalEstimator{Scale: 10000},
+			history:   noHistory,
+			amt:       capacityMsat,
+			capacity:  capacity,
+			expected:  0,
+		},
+		{
+			name:      "bimodal exceeds capacity",
+			estimator: &BimodalEstimator{Scale: 10000},
+			history:   noHistory,
+			amt:       capacityMsat + 1,
+			capacity:  capacity,
+			expected:  0,
+		},
+	}
+
+	for _, test := range tests {
+		p := test.estimator.EdgeProbability(
+			now, test.history, test.amt, test.capacity,
+		)
+		if math.Abs(p-test.expected) > 1e-9 {
+			t.Fatalf("%v: expected probability %v, got %v",
+				test.name, test.expected, p)
+		}
+	}
+
+	// A small amount over a bimodal channel is more likely to succeed than
+	// an amount that requires most of the balance.
+	bimodal := &BimodalEstimator{Scale: 10000}
+	small := bimodal.EdgeProbability(now, noHistory, 1000000, capacity)
+	large := bimodal.EdgeProbability(
+		now, noHistory, capacityMsat-1000000, capacity,
+	)
+	if small <= large {
+		t.Fatalf("expected small amount probability %v to exceed "+
+			"large amount probability %v", small, large)
+	}
+}
+
+// TestNewProbabilityEstimator asserts that estimators are selected by name.
+func TestNewProbabilityEstimator(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{
+		AprioriEstimatorName, BimodalEstimatorName,
+		HistoricalEstimatorName,
+	} {
+		estimator, err := NewProbabilityEstimator(name, 0.6, 10000)
+		if err != nil {
+			t.Fatalf("unable to create estimator %v: %v", name, err)
+		}
+		if estimator.String() != name {
+			t.Fatalf("expected estimator %v, got %v", name,
+				estimator)
+		}
+	}
+
+	estimator, err := NewProbabilityEstimator("", 0.6, 10000)
+	if err != nil {
+		t.Fatalf("unable to create default estimator: %v", err)
+	}
+	if _, ok := estimator.(*AprioriEstimator); !ok {
+		t.Fatalf("expected apriori estimator, got %v", estimator)
+	}
+
+	if _, err := NewProbabilityEstimator("unknown", 0.6, 10000); err == nil {
+		t.Fatalf("expected error for unknown estimator")
+	}
+}
@@ -0,0 +1,177 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// RouteAnalysis is a report on the quality of a prospective route. It allows
+// users that hand-pick routes to judge them before attempting a payment.
+type RouteAnalysis struct {
+	// Hops contains the diagnostics of every hop of the route, in the
+	// order of the route.
+	Hops []*HopAnalysis
+
+	// Probability is the estimated success probability of the route, as
+	// the product of the success probabilities of its hops.
+	Probability float64
+}
+
+// HopAnalysis contains the diagnostics of a single hop of a prospective route.
+// The hop consists of the channel from the previous node of the route to the
+// node of the hop.
+type HopAnalysis struct {
+	// ChannelID is the id of the channel that the hop is made over.
+	ChannelID uint64
+
+	// From is the node that sends the payment over the channel.
+	From route.Vertex
+
+	// To is the node that receives the payment over the channel.
+	To route.Vertex
+
+	// ChannelKnown indicates whether the channel is part of the graph.
+	// Private channels, such as those of hop hints, aren't.
+	ChannelKnown bool
+
+	// Capacity is the capacity of the channel. It is zero if the channel
+	// isn't known.
+	Capacity btcutil.Amount
+
+	// Amount is the amount that is sent over the channel.
+	Amount lnwire.MilliSatoshi
+
+	// Headroom is the capacity of the channel minus the amount that is
+	// sent over it, in millisatoshis. It is negative if the amount exceeds
+	// the capacity, and only meaningful if the channel is known.
+	Headroom int64
+
+	// PolicyKnown indicates whether the policy of From for the channel is
+	// part of the graph.
+	PolicyKnown bool
+
+	// PolicyAge is the time since the policy of From for the channel was
+	// last updated. It is only set if the policy is known.
+	PolicyAge time.Duration
+
+	// Fee is the fee that To charges for forwarding the payment. For the
+	// last hop, it is zero.
+	Fee lnwire.MilliSatoshi
+
+	// CltvDelta is the number of blocks that the time lock is decreased by
+	// at To. For the last hop, it is the final cltv delta of the payment.
+	CltvDelta uint32
+
+	// LastFailure is the time of the last failure that mission control
+	// recorded for the channel or its sending node, if any.
+	LastFailure *time.Time
+
+	// LastFailureClass is the class of the last failure. It is only
+	// meaningful if LastFailure is set.
+	LastFailureClass FailureClass
+
+	// Probability is the estimated success probability of the hop.
+	Probability float64
+}
+
+// AnalyzeRoute reports diagnostics for every hop of the given route: the
+// staleness of the policies, the recorded failure history, the estimated
+// success probability, the capacity headroom and the cltv contribution. The
+// route isn't validated, so routes that can't carry the payment can be
+// analyzed as well.
+func (m *MissionControl) AnalyzeRoute(rt *route.Route) (*RouteAnalysis, error) {
+	now := m.now()
+
+	incomingAmt := rt.TotalAmount
+	incomingTimeLock := rt.TotalTimeLock
+	from := rt.SourcePubKey
+
+	hops := make([]*HopAnalysis, 0, len(rt.Hops))
+	for i, hop := range rt.Hops {
+		analysis := &HopAnalysis{
+			ChannelID: hop.ChannelID,
+			From:      from,
+			To:        hop.PubKeyBytes,
+			Amount:    incomingAmt,
+			Fee:       rt.HopFee(i),
+			CltvDelta: incomingTimeLock - hop.OutgoingTimeLock,
+		}
+
+		err := m.analyzeChannel(analysis, now)
+		if err != nil {
+			return nil, err
+		}
+
+		hops = append(hops, analysis)
+
+		incomingAmt = hop.AmtToForward
+		incomingTimeLock = hop.OutgoingTimeLock
+		from = hop.PubKeyBytes
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	probability := 1.0
+	for _, hop := range hops {
+		nodeHistory, ok := m.history[hop.From]
+		if !ok {
+			hop.Probability = m.cfg.estimator().EdgeProbability(
+				now, &EdgeHistory{}, hop.Amount, hop.Capacity,
+			)
+		} else {
+			hop.LastFailure, hop.LastFailureClass, _ =
+				nodeHistory.lastChannelFailure(
+					hop.ChannelID, hop.Amount,
+				)
+
+			hop.Probability = m.getEdgeProbabilityForNode(
+				nodeHistory, hop.ChannelID, hop.Amount,
+				hop.Capacity,
+			)
+		}
+
+		probability *= hop.Probability
+	}
+
+	return &RouteAnalysis{
+		Hops:        hops,
+		Probability: probability,
+	}, nil
+}
+
+// analyzeChannel fills in the diagnostics of the hop that are taken from the
+// graph. Channels that aren't part of the graph are left unknown.
+func (m *MissionControl) analyzeChannel(hop *HopAnalysis, now time.Time) error {
+	info, policy1, policy2, err := m.graph.FetchChannelEdgesByID(
+		hop.ChannelID,
+	)
+	switch {
+	case err == channeldb.ErrEdgeNotFound || err == channeldb.ErrZombieEdge:
+		return nil
+
+	case err != nil:
+		return err
+	}
+
+	hop.ChannelKnown = true
+	hop.Capacity = info.Capacity
+	hop.Headroom = int64(lnwire.NewMSatFromSatoshis(info.Capacity)) -
+		int64(hop.Amount)
+
+	// The first policy is the one of the first node of the channel.
+	policy := policy2
+	if info.NodeKey1Bytes == hop.From {
+		policy = policy1
+	}
+	if policy != nil {
+		hop.PolicyKnown = true
+		hop.PolicyAge = now.Sub(policy.LastUpdate)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// TestAnalyzeRoute tests that the route analysis reports the amounts, fees,
+// cltv deltas, capacities and failure history of every hop.
+func TestAnalyzeRoute(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	mc := ctx.router.cfg.MissionControl.(*MissionControl)
+
+	restrictions := &RestrictParams{
+		FeeLimit:          noFeeLimit,
+		ProbabilitySource: noProbabilitySource,
+	}
+	rt, err := ctx.router.FindRoute(
+		ctx.router.selfNode.PubKeyBytes, ctx.aliases["sophon"],
+		lnwire.NewMSatFromSatoshis(100), restrictions,
+		zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to find route: %v", err)
+	}
+	if len(rt.Hops) != 2 {
+		t.Fatalf("expected 2 hops, got %v", len(rt.Hops))
+	}
+
+	analysis, err := mc.AnalyzeRoute(rt)
+	if err != nil {
+		t.Fatalf("unable to analyze route: %v", err)
+	}
+	if len(analysis.Hops) != len(rt.Hops) {
+		t.Fatalf("expected %v hops, got %v", len(rt.Hops),
+			len(analysis.Hops))
+	}
+
+	var cltvDelta uint32
+	for i, hop := range analysis.Hops {
+		if hop.To != rt.Hops[i].PubKeyBytes {
+			t.Fatalf("hop %v: unexpected node %v", i,
+				getAliasFromPubKey(hop.To, ctx.aliases))
+		}
+		if !hop.ChannelKnown || !hop.PolicyKnown {
+			t.Fatalf("hop %v: expected known channel and policy", i)
+		}
+		if hop.Fee != rt.HopFee(i) {
+			t.Fatalf("hop %v: expected fee %v, got %v", i,
+				rt.HopFee(i), hop.Fee)
+		}
+		expectedHeadroom := int64(
+			lnwire.NewMSatFromSatoshis(hop.Capacity),
+		) - int64(hop.Amount)
+		if hop.Headroom != expectedHeadroom {
+			t.Fatalf("hop %v: expected headroom %v, got %v", i,
+				expectedHeadroom, hop.Headroom)
+		}
+		if hop.LastFailure != nil {
+			t.Fatalf("hop %v: unexpected failure", i)
+		}
+
+		cltvDelta += hop.CltvDelta
+	}
+
+	if analysis.Hops[0].From != ctx.router.selfNode.PubKeyBytes {
+		t.Fatalf("expected first hop to start at the source")
+	}
+	if analysis.Hops[0].Amount != rt.TotalAmount {
+		t.Fatalf("expected first hop to carry %v, got %v",
+			rt.TotalAmount, analysis.Hops[0].Amount)
+	}
+	if analysis.Hops[1].Fee != 0 {
+		t.Fatalf("expected no fee for the last hop")
+	}
+	lastHop := rt.Hops[len(rt.Hops)-1]
+	if cltvDelta != rt.TotalTimeLock-lastHop.OutgoingTimeLock {
+		t.Fatalf("expected cltv deltas to add up to %v, got %v",
+			rt.TotalTimeLock-lastHop.OutgoingTimeLock, cltvDelta)
+	}
+
+	// After a failure of the second channel, the failure must be reported
+	// and the probability of the route must drop.
+	hop := analysis.Hops[1]
+	mc.reportEdgeFailure(
+		edge{from: hop.From, to: hop.To, channel: hop.ChannelID}, 0,
+		FailureClassTemporary,
+	)
+
+	failedAnalysis, err := mc.AnalyzeRoute(rt)
+	if err != nil {
+		t.Fatalf("unable to analyze route: %v", err)
+	}
+	failedHop := failedAnalysis.Hops[1]
+	if failedHop.LastFailure == nil ||
+		failedHop.LastFailureClass != FailureClassTemporary {
+
+		t.Fatalf("expected temporary failure to be reported")
+	}
+	if failedAnalysis.Hops[0].LastFailure != nil {
+		t.Fatalf("unexpected failure of first hop")
+	}
+	if failedAnalysis.Probability >= analysis.Probability {
+		t.Fatalf("expected probability to drop below %v, got %v",
+			analysis.Probability, failedAnalysis.Probability)
+	}
+}
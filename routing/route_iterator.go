@@ -0,0 +1,403 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// routeCandidate is a path that was discovered by a RouteIterator, but that
+// hasn't been returned yet.
+type routeCandidate struct {
+	path  []*channeldb.ChannelEdgePolicy
+	route *route.Route
+}
+
+// RouteIterator yields routes from a source to a target one at a time, on
+// demand. The first route is the one that FindRoute would return. Every
+// following call returns the cheapest of the not yet returned alternatives
+// that were discovered so far, so that a caller that browses through routes
+// doesn't need to restart path finding for every route it requests.
+//
+// Alternatives are discovered using Yen's k-shortest paths algorithm on top of
+// findPath. The state of the search, including the bandwidth hints and the
+// block height, is captured when the iterator is created and kept between
+// calls. Changes to the graph that happen in between calls are picked up by
+// the paths that are discovered afterwards, but previously discovered
+// candidates are not revalidated.
+type RouteIterator struct {
+	router         *ChannelRouter
+	source         route.Vertex
+	target         route.Vertex
+	amt            lnwire.MilliSatoshi
+	restrictions   RestrictParams
+	finalCLTVDelta uint16
+	bandwidthHints map[uint64]lnwire.MilliSatoshi
+	height         uint32
+
+	// found holds the paths that have been returned so far, in the order
+	// in which they were returned.
+	found [][]*channeldb.ChannelEdgePolicy
+
+	// candidates holds the paths that have been discovered, but not yet
+	// returned. It is kept sorted by fee and time lock.
+	candidates []*routeCandidate
+
+	// seen contains the keys of all paths in found and candidates, so that
+	// the same path isn't discovered twice.
+	seen map[string]struct{}
+
+	// started indicates whether the initial path has been searched for.
+	started bool
+
+	// expanded is the number of returned paths whose alternatives have
+	// been discovered.
+	expanded int
+
+	sync.Mutex
+}
+
+// NewRouteIterator returns an iterator that yields routes from the source to
+// the target that are able to carry the given amount. The restrictions apply
+// to every route that is returned. The final cltv delta is optional, if not
+// set the default of zpay32 is used.
+func (r *ChannelRouter) NewRouteIterator(source, target route.Vertex,
+	amt lnwire.MilliSatoshi, restrictions *RestrictParams,
+	finalExpiry ...uint16) (*RouteIterator, error) {
+
+	finalCLTVDelta := uint16(zpay32.DefaultFinalCLTVDelta)
+	if len(finalExpiry) > 0 {
+		finalCLTVDelta = finalExpiry[0]
+	}
+
+	if _, exists, err := r.cfg.Graph.HasLightningNode(target); err != nil {
+		return nil, err
+	} else if !exists {
+		log.Debugf("Target %x is not in known graph", target)
+		return nil, newErrf(ErrTargetNotInNetwork, "target not found")
+	}
+
+	bandwidthHints, err := r.sourceBandwidthHints(source)
+	if err != nil {
+		return nil, err
+	}
+
+	_, currentHeight, err := r.cfg.Chain.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RouteIterator{
+		router:         r,
+		source:         source,
+		target:         target,
+		amt:            amt,
+		restrictions:   *restrictions,
+		finalCLTVDelta: finalCLTVDelta,
+		bandwidthHints: bandwidthHints,
+		height:         uint32(currentHeight),
+		seen:           make(map[string]struct{}),
+	}, nil
+}
+
+// FindRoutes returns up to numRoutes routes from the source to the target that
+// are able to carry the given amount, ordered from cheapest to most expensive.
+// The first route is the one that FindRoute would return, the others are the
+// next cheapest alternatives that a RouteIterator yields. Fewer routes are
+// returned if no more routes exist. The final cltv delta is optional, if not
+// set the default of zpay32 is used.
+func (r *ChannelRouter) FindRoutes(source, target route.Vertex,
+	amt lnwire.MilliSatoshi, restrictions *RestrictParams, numRoutes int,
+	finalExpiry ...uint16) ([]*route.Route, error) {
+
+	if numRoutes < 1 {
+		return nil, fmt.Errorf("invalid number of routes %v", numRoutes)
+	}
+
+	it, err := r.NewRouteIterator(
+		source, target, amt, restrictions, finalExpiry...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]*route.Route, 0, numRoutes)
+	for len(routes) < numRoutes {
+		rt, err := it.Next()
+		switch {
+		// If at least one route was found, running out of alternatives
+		// isn't an error.
+		case IsError(err, ErrNoPathFound) && len(routes) > 0:
+			return routes, nil
+
+		case err != nil:
+			return nil, err
+		}
+
+		routes = append(routes, rt)
+	}
+
+	return routes, nil
+}
+
+// Next returns the next route. Once all routes have been returned, an error
+// with code ErrNoPathFound is returned.
+func (it *RouteIterator) Next() (*route.Route, error) {
+	it.Lock()
+	defer it.Unlock()
+
+	// All paths that are searched for to determine the next route see the
+	// same snapshot of the graph.
+	g := it.graphParams()
+	err := g.withSnapshot(func() error {
+		switch {
+		// On the first call, we search for the best path using the
+		// restrictions as they are.
+		case !it.started:
+			it.started = true
+
+			path, err := it.findPath(
+				g, &it.restrictions, it.source,
+			)
+			if err != nil {
+				return err
+			}
+			it.addCandidate(path)
+
+		// Otherwise, we'll derive the alternatives of the path that
+		// was returned last. This is done lazily, so that no work is
+		// spent on routes that are never requested.
+		case it.expanded < len(it.found):
+			err := it.expand(g, it.found[it.expanded])
+			if err != nil {
+				return err
+			}
+			it.expanded++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(it.candidates) == 0 {
+		return nil, newErrf(ErrNoPathFound, "no more routes to %x",
+			it.target)
+	}
+
+	next := it.candidates[0]
+	it.candidates = it.candidates[1:]
+	it.found = append(it.found, next.path)
+
+	go log.Tracef("Route iterator yields route %v to %x: %v",
+		len(it.found), it.target, newLogClosure(func() string {
+			return spew.Sdump(next.route)
+		}),
+	)
+
+	return next.route, nil
+}
+
+// expand discovers the alternatives of the given path. For every node of the
+// path, a spur path to the target is searched for that deviates from all
+// previously returned paths that share the same root up to that node.
+func (it *RouteIterator) expand(g *graphParams,
+	prev []*channeldb.ChannelEdgePolicy) error {
+
+	for i := range prev {
+		root := prev[:i]
+
+		spurNode := it.source
+		if i > 0 {
+			spurNode = root[i-1].Node.PubKeyBytes
+		}
+
+		// The edges leaving the spur node that were taken by any of the
+		// returned paths with the same root can't be taken again.
+		excludedEdges := make(map[EdgeLocator]struct{})
+		for _, p := range it.found {
+			if len(p) > i && samePrefix(p, root) {
+				excludedEdges[*newEdgeLocator(p[i])] = struct{}{}
+			}
+		}
+
+		// To prevent loops, the nodes of the root path can't be
+		// traversed by the spur path.
+		excludedNodes := map[route.Vertex]struct{}{
+			it.source: {},
+		}
+		for _, edge := range root {
+			excludedNodes[edge.Node.PubKeyBytes] = struct{}{}
+		}
+		delete(excludedNodes, spurNode)
+
+		restrictions := it.restrictions
+		probabilitySource := it.restrictions.ProbabilitySource
+		restrictions.ProbabilitySource = func(fromNode route.Vertex,
+			edge EdgeLocator, amt lnwire.MilliSatoshi,
+			capacity btcutil.Amount) float64 {
+
+			if _, ok := excludedNodes[fromNode]; ok {
+				return 0
+			}
+			if fromNode == spurNode {
+				if _, ok := excludedEdges[edge]; ok {
+					return 0
+				}
+			}
+			if probabilitySource == nil {
+				return 1
+			}
+
+			return probabilitySource(fromNode, edge, amt, capacity)
+		}
+
+		// The outgoing channel restriction only applies to the edge
+		// leaving the source, which is part of the root path for all
+		// but the first spur node.
+		if i > 0 {
+			restrictions.OutgoingChannelIDs = nil
+		}
+
+		spur, err := it.findPath(g, &restrictions, spurNode)
+		switch {
+		case IsError(err, ErrNoPathFound):
+			continue
+
+		case err != nil:
+			return err
+		}
+
+		// Path finding treats the edges of the spur node as our own
+		// channels, which may be used even if they are disabled.
+		if i > 0 && spur[0].ChannelFlags&lnwire.ChanUpdateDisabled != 0 {
+			continue
+		}
+
+		path := make(
+			[]*channeldb.ChannelEdgePolicy, 0, len(root)+len(spur),
+		)
+		path = append(path, root...)
+		path = append(path, spur...)
+
+		it.addCandidate(path)
+	}
+
+	return nil
+}
+
+// graphParams returns the graph parameters for the path finding of the
+// iterator.
+func (it *RouteIterator) graphParams() *graphParams {
+	selfNode := route.Vertex(it.router.selfNode.PubKeyBytes)
+
+	return &graphParams{
+		graph:          it.router.cfg.Graph,
+		bandwidthHints: it.bandwidthHints,
+		selfNode:       &selfNode,
+	}
+}
+
+// findPath searches for a path from the given node to the target of the
+// iterator.
+func (it *RouteIterator) findPath(g *graphParams, restrictions *RestrictParams,
+	from route.Vertex) ([]*channeldb.ChannelEdgePolicy, error) {
+
+	return findPath(g, restrictions, from, it.target, it.amt)
+}
+
+// addCandidate adds the path to the set of candidates, unless it was already
+// discovered before or violates the restrictions of the iterator.
+func (it *RouteIterator) addCandidate(path []*channeldb.ChannelEdgePolicy) {
+	key := pathKey(path)
+	if _, ok := it.seen[key]; ok {
+		return
+	}
+
+	rt, err := newRoute(
+		it.amt, it.source, path, it.height, it.finalCLTVDelta,
+	)
+	if err != nil {
+		log.Debugf("Unable to create route from path: %v", err)
+		return
+	}
+
+	// The fee, cltv and hop limits are checked by path finding for the
+	// spur path only, so we need to check them again for the full route.
+	if rt.TotalFees() > it.restrictions.FeeLimit {
+		return
+	}
+	if it.restrictions.MaxHops != 0 &&
+		len(path) > int(it.restrictions.MaxHops) {
+
+		return
+	}
+	if it.restrictions.CltvLimit != nil {
+		cltvDelta := rt.TotalTimeLock - it.height -
+			uint32(it.finalCLTVDelta)
+		if cltvDelta > *it.restrictions.CltvLimit {
+			return
+		}
+	}
+
+	it.seen[key] = struct{}{}
+
+	candidate := &routeCandidate{
+		path:  path,
+		route: rt,
+	}
+	idx := sort.Search(len(it.candidates), func(i int) bool {
+		return cheaperRoute(candidate.route, it.candidates[i].route)
+	})
+
+	it.candidates = append(it.candidates, nil)
+	copy(it.candidates[idx+1:], it.candidates[idx:])
+	it.candidates[idx] = candidate
+}
+
+// cheaperRoute returns true if route a is cheaper than route b. Routes are
+// compared by fee first, then by time lock.
+func cheaperRoute(a, b *route.Route) bool {
+	if a.TotalFees() != b.TotalFees() {
+		return a.TotalFees() < b.TotalFees()
+	}
+
+	return a.TotalTimeLock < b.TotalTimeLock
+}
+
+// samePrefix returns true if the path starts with the given prefix.
+func samePrefix(path, prefix []*channeldb.ChannelEdgePolicy) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+
+	for i, edge := range prefix {
+		if *newEdgeLocator(path[i]) != *newEdgeLocator(edge) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pathKey returns a key that uniquely identifies the path.
+func pathKey(path []*channeldb.ChannelEdgePolicy) string {
+	var b strings.Builder
+	for i, edge := range path {
+		if i > 0 {
+			b.WriteString("->")
+		}
+		b.WriteString(newEdgeLocator(edge).String())
+	}
+
+	return b.String()
+}
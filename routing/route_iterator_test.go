@@ -0,0 +1,158 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// TestRouteIterator asserts that the route iterator first returns the route
+// that FindRoute would return, then yields distinct alternatives and finally
+// reports that no more routes are available.
+func TestRouteIterator(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	source := ctx.router.selfNode.PubKeyBytes
+	target := ctx.aliases["sophon"]
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+
+	bestRoute, err := ctx.router.FindRoute(
+		source, target, paymentAmt, noRestrictions,
+		zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to find route: %v", err)
+	}
+
+	iter, err := ctx.router.NewRouteIterator(
+		source, target, paymentAmt, noRestrictions,
+		zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create route iterator: %v", err)
+	}
+
+	seen := make(map[string]struct{})
+	for i := 0; ; i++ {
+		// The basic graph is small, so the iterator should be
+		// exhausted well before this limit.
+		if i == 50 {
+			t.Fatalf("route iterator not exhausted")
+		}
+
+		rt, err := iter.Next()
+		if IsError(err, ErrNoPathFound) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unable to get next route: %v", err)
+		}
+
+		if i == 0 && !reflect.DeepEqual(rt, bestRoute) {
+			t.Fatalf("expected first route %v, got %v",
+				bestRoute, rt)
+		}
+
+		if rt.Hops[len(rt.Hops)-1].PubKeyBytes != target {
+			t.Fatalf("route doesn't end at target")
+		}
+
+		key := routeKey(rt)
+		if _, ok := seen[key]; ok {
+			t.Fatalf("route %v returned twice", key)
+		}
+		seen[key] = struct{}{}
+	}
+
+	// There are at least two routes from roasbeef to sophon, over songoku
+	// and over phamnuwen.
+	if len(seen) < 2 {
+		t.Fatalf("expected at least 2 routes, got %v", len(seen))
+	}
+
+	// Once exhausted, the iterator keeps reporting that no route is left.
+	if _, err := iter.Next(); !IsError(err, ErrNoPathFound) {
+		t.Fatalf("expected ErrNoPathFound, got %v", err)
+	}
+}
+
+// TestFindRoutes asserts that FindRoutes returns the requested number of
+// distinct routes, starting with the route that FindRoute would return, and
+// fewer routes if no more alternatives exist.
+func TestFindRoutes(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	source := ctx.router.selfNode.PubKeyBytes
+	target := ctx.aliases["sophon"]
+	paymentAmt := lnwire.NewMSatFromSatoshis(100)
+
+	bestRoute, err := ctx.router.FindRoute(
+		source, target, paymentAmt, noRestrictions,
+		zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to find route: %v", err)
+	}
+
+	routes, err := ctx.router.FindRoutes(
+		source, target, paymentAmt, noRestrictions, 2,
+		zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to find routes: %v", err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %v", len(routes))
+	}
+	if !reflect.DeepEqual(routes[0], bestRoute) {
+		t.Fatalf("expected first route %v, got %v", bestRoute,
+			routes[0])
+	}
+	if routeKey(routes[0]) == routeKey(routes[1]) {
+		t.Fatalf("expected distinct routes")
+	}
+	if routes[1].TotalFees() < routes[0].TotalFees() {
+		t.Fatalf("expected routes ordered by fee")
+	}
+
+	// Requesting more routes than exist returns all of them.
+	allRoutes, err := ctx.router.FindRoutes(
+		source, target, paymentAmt, noRestrictions, 50,
+		zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to find routes: %v", err)
+	}
+	if len(allRoutes) < 2 || len(allRoutes) == 50 {
+		t.Fatalf("expected all routes to be returned, got %v",
+			len(allRoutes))
+	}
+
+	_, err = ctx.router.FindRoutes(
+		source, target, paymentAmt, noRestrictions, 0,
+		zpay32.DefaultFinalCLTVDelta,
+	)
+	if err == nil {
+		t.Fatalf("expected error for zero routes")
+	}
+}
@@ -0,0 +1,151 @@
+package routing
+
+import (
+	"math/bits"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// RouteJournal persists the last route that successfully settled a payment
+// for every destination and amount band. If configured, payments first try
+// the journaled route to their destination before falling back to path
+// finding.
+type RouteJournal interface {
+	// PutRoute stores the given route as the last route that settled a
+	// payment to its destination within the given amount band.
+	PutRoute(band uint8, rt *route.Route, settled time.Time) error
+
+	// FetchRoute returns the last route that settled a payment to the
+	// given destination within the given amount band. If there is none,
+	// channeldb.ErrJournalRouteNotFound is returned.
+	FetchRoute(target route.Vertex,
+		band uint8) (*channeldb.JournalRoute, error)
+
+	// DeleteRoute removes the route for the given destination and amount
+	// band from the journal.
+	DeleteRoute(target route.Vertex, band uint8) error
+}
+
+// amountBand returns the band of the given amount. Bands are powers of two, so
+// that a journaled route is only reused for amounts of the same order of
+// magnitude as the payment that settled over it.
+func amountBand(amt lnwire.MilliSatoshi) uint8 {
+	return uint8(bits.Len64(uint64(amt)))
+}
+
+// recordJournalRoute stores the route over which a payment settled in the
+// route journal, if one is configured.
+func (r *ChannelRouter) recordJournalRoute(rt *route.Route) {
+	if r.cfg.RouteJournal == nil || len(rt.Hops) == 0 {
+		return
+	}
+
+	band := amountBand(rt.Hops[len(rt.Hops)-1].AmtToForward)
+	err := r.cfg.RouteJournal.PutRoute(band, rt, time.Now())
+	if err != nil {
+		log.Errorf("Unable to store route in journal: %v", err)
+	}
+}
+
+// invalidateJournalRoute removes the journaled route for the given destination
+// and amount band.
+func (r *ChannelRouter) invalidateJournalRoute(target route.Vertex,
+	band uint8, reason string) {
+
+	log.Debugf("Invalidating journaled route to %x in band %v: %v",
+		target, band, reason)
+
+	if err := r.cfg.RouteJournal.DeleteRoute(target, band); err != nil {
+		log.Errorf("Unable to delete route from journal: %v", err)
+	}
+}
+
+// journalRoute returns the journaled route to the destination of the given
+// payment, rebuilt for the amount of the payment using the current policies of
+// its channels. The journaled route is invalidated if one of its channels no
+// longer exists or has updated its policy since the route was journaled. If
+// there is no journaled route, nil is returned. The route isn't checked
+// against the restrictions of the payment, which is left to the payment
+// session.
+func (r *ChannelRouter) journalRoute(payment *LightningPayment,
+	height uint32, finalCLTVDelta uint16) *route.Route {
+
+	// Payments that are resumed after a restart don't carry their
+	// destination and amount, so there is nothing to look up for them.
+	if r.cfg.RouteJournal == nil || payment.Amount == 0 {
+		return nil
+	}
+
+	band := amountBand(payment.Amount)
+	entry, err := r.cfg.RouteJournal.FetchRoute(payment.Target, band)
+	switch {
+	case err == channeldb.ErrJournalRouteNotFound:
+		return nil
+
+	case err != nil:
+		log.Errorf("Unable to fetch route from journal: %v", err)
+		return nil
+	}
+
+	hops := entry.Route.Hops
+	if len(hops) == 0 {
+		return nil
+	}
+
+	source := route.Vertex(r.selfNode.PubKeyBytes)
+	pathEdges := make([]*channeldb.ChannelEdgePolicy, 0, len(hops))
+
+	prev := source
+	for _, hop := range hops {
+		info, policy1, policy2, err := r.cfg.Graph.FetchChannelEdgesByID(
+			hop.ChannelID,
+		)
+		if err != nil {
+			r.invalidateJournalRoute(
+				payment.Target, band, "channel not found",
+			)
+			return nil
+		}
+
+		// Select the policy of the channel in the direction of the
+		// route.
+		policy := policy2
+		if info.NodeKey1Bytes == prev {
+			policy = policy1
+		}
+		if policy == nil || policy.Node == nil ||
+			policy.Node.PubKeyBytes != hop.PubKeyBytes {
+
+			r.invalidateJournalRoute(
+				payment.Target, band, "policy not found",
+			)
+			return nil
+		}
+
+		if policy.LastUpdate.After(entry.Settled) {
+			r.invalidateJournalRoute(
+				payment.Target, band, "policy updated",
+			)
+			return nil
+		}
+
+		pathEdges = append(pathEdges, policy)
+		prev = hop.PubKeyBytes
+	}
+
+	rt, err := newRoute(
+		payment.Amount, source, pathEdges, height, finalCLTVDelta,
+	)
+	if err != nil {
+		log.Debugf("Unable to rebuild journaled route: %v", err)
+		return nil
+	}
+
+	log.Debugf("Found journaled route to %x over %v hops",
+		payment.Target, len(rt.Hops))
+
+	return rt
+}
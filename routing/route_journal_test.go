@@ -0,0 +1,54 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// failingRouteJournal is a route journal that fails the test when it is
+// queried.
+type failingRouteJournal struct {
+	t *testing.T
+}
+
+func (j *failingRouteJournal) PutRoute(band uint8, rt *route.Route,
+	settled time.Time) error {
+
+	j.t.Fatal("unexpected PutRoute")
+	return nil
+}
+
+func (j *failingRouteJournal) FetchRoute(target route.Vertex,
+	band uint8) (*channeldb.JournalRoute, error) {
+
+	j.t.Fatal("unexpected FetchRoute")
+	return nil, nil
+}
+
+func (j *failingRouteJournal) DeleteRoute(target route.Vertex,
+	band uint8) error {
+
+	j.t.Fatal("unexpected DeleteRoute")
+	return nil
+}
+
+// TestJournalRouteResumedPayment asserts that the journal isn't consulted for
+// payments that are resumed after a restart, as they don't carry their
+// destination and amount.
+func TestJournalRouteResumedPayment(t *testing.T) {
+	t.Parallel()
+
+	router := &ChannelRouter{
+		cfg: &Config{
+			RouteJournal: &failingRouteJournal{t: t},
+		},
+	}
+
+	rt := router.journalRoute(&LightningPayment{}, 100, 40)
+	if rt != nil {
+		t.Fatalf("expected no journaled route, got %v", rt)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/coreos/bbolt"
@@ -17,7 +18,9 @@ import (
 
 	sphinx "github.com/lightningnetwork/lightning-onion"
 	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/eventbus"
 	"github.com/lightningnetwork/lnd/htlcswitch"
+	"github.com/lightningnetwork/lnd/htlcswitch/failpolicy"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwallet"
@@ -25,6 +28,7 @@ import (
 	"github.com/lightningnetwork/lnd/multimutex"
 	"github.com/lightningnetwork/lnd/routing/chainview"
 	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/subscribe"
 	"github.com/lightningnetwork/lnd/zpay32"
 )
 
@@ -92,6 +96,14 @@ type ChannelGraphSource interface {
 	// live.
 	MarkEdgeLive(chanID lnwire.ShortChannelID) error
 
+	// MarkEdgesLive clears all of the given edges from our zombie index,
+	// deeming them as live.
+	MarkEdgesLive(chanIDs ...lnwire.ShortChannelID) error
+
+	// ZombieEdges returns all edges that are currently marked as zombies,
+	// along with the last update time that caused them to be marked.
+	ZombieEdges() ([]channeldb.ZombieEdge, error)
+
 	// ForAllOutgoingChannels is used to iterate over all channels
 	// emanating from the "source" node which is the center of the
 	// star-graph.
@@ -141,6 +153,15 @@ type PaymentAttemptDispatcher interface {
 	GetPaymentResult(paymentID uint64, paymentHash lntypes.Hash,
 		deobfuscator htlcswitch.ErrorDecrypter) (
 		<-chan *htlcswitch.PaymentResult, error)
+
+	// PaymentAttemptStatus returns what the switch knows about the
+	// payment attempt with the given paymentID.
+	PaymentAttemptStatus(paymentID uint64) (htlcswitch.AttemptStatus,
+		error)
+
+	// LocalPaymentIDs returns the payment IDs of all locally initiated
+	// payment attempts that are currently in flight within the switch.
+	LocalPaymentIDs() []uint64
 }
 
 // PaymentSessionSource is an interface that defines a source for the router to
@@ -258,6 +279,82 @@ type Config struct {
 	// spentness of channel outpoints. For neutrino, this saves long rescans
 	// from blocking initial usage of the daemon.
 	AssumeChannelValid bool
+
+	// DeferGraphSync toggles whether the router becomes available for
+	// payments before the channel graph has been synchronized with the
+	// chain. If set, the graph is synced in the background using the
+	// existing graph in the meantime. Network updates are processed right
+	// away, while new blocks and zombie channels are only pruned once the
+	// sync has completed.
+	DeferGraphSync bool
+
+	// ChainParams are the parameters of the chain the router operates on.
+	// They are used to decode payment requests passed to PayInvoice.
+	ChainParams *chaincfg.Params
+
+	// FailurePolicies is the table that determines how the router reacts
+	// to failures reported while sending payments. If nil, the default
+	// table shared with the switch is used.
+	FailurePolicies *failpolicy.Table
+
+	// LocalChannelUnavailable is an optional callback that is invoked when
+	// our own switch reports that the first hop of a route is unknown,
+	// which indicates that the link with the peer is down. It can be used
+	// to signal that a reconnection to the peer should be attempted.
+	LocalChannelUnavailable func(chanID lnwire.ShortChannelID,
+		peer route.Vertex)
+
+	// CheckLocalPolicy is an optional callback that checks whether the
+	// given htlc can be sent over the first hop of a route, without
+	// actually sending it. It should return an error if the htlc violates
+	// our own outgoing policy, for instance because the channel reserve
+	// would be breached. Routes that fail this check are replaced by a new
+	// route before an attempt is made, so that no attempt is wasted on a
+	// failure that we would cause ourselves.
+	CheckLocalPolicy func(firstHop lnwire.ShortChannelID,
+		htlc *lnwire.UpdateAddHTLC) error
+
+	// RouteJournal is an optional store for the routes that settled
+	// payments. If set, the first attempt of a payment uses the journaled
+	// route to its destination, if it is still usable, before path finding
+	// is performed.
+	RouteJournal RouteJournal
+
+	// HintPolicies is an optional store for the policies of private
+	// channels that are learned when the policy of a route hint turns out
+	// to be wrong. If set, the learned policies replace the ones of the
+	// route hints of subsequent payments.
+	HintPolicies HintPolicyStore
+
+	// EventBus is an optional event bus on which the router publishes
+	// settled and failed payments, and channels that are pruned from the
+	// graph.
+	EventBus *eventbus.EventBus
+
+	// ProbeNode is an optional callback that is used by the liveness
+	// check of payments for nodes that haven't recently updated their
+	// announcements. It should return true if the node is known to be
+	// online, for example because we're connected to it.
+	ProbeNode func(node route.Vertex) bool
+
+	// GraphAuditSize is the number of accepted graph mutations that are
+	// retained in the audit log that is queried through GraphAudit. If
+	// zero, no audit log is kept.
+	GraphAuditSize int
+
+	// ForwardingHistory is an optional source of the forwarding events
+	// of our node, which EstimateFeeRevenue projects the revenue of fee
+	// changes from.
+	ForwardingHistory ForwardingHistory
+
+	// MinAttemptInterval is the minimum time between consecutive
+	// attempts of a payment. If zero, a failed attempt is retried right
+	// away.
+	MinAttemptInterval time.Duration
+
+	// AttemptJitter is the upper bound of a random delay that is added to
+	// the interval between consecutive attempts of a payment.
+	AttemptJitter time.Duration
 }
 
 // routeTuple is an entry within the ChannelRouter's route cache. We cache
@@ -376,11 +473,65 @@ type ChannelRouter struct {
 	// existing client.
 	ntfnClientUpdates chan *topologyClientUpdate
 
+	// topologySeq is the sequence number of the latest topology change,
+	// and topologyHistory holds the most recent topology changes. Both
+	// are guarded by the router's mutex.
+	topologySeq     uint64
+	topologyHistory []*TopologyChange
+
 	// channelEdgeMtx is a mutex we use to make sure we process only one
 	// ChannelEdgePolicy at a time for a given channelID, to ensure
 	// consistency between the various database accesses.
 	channelEdgeMtx *multimutex.Mutex
 
+	// latencyTracker records the end-to-end latency of successful
+	// payments.
+	latencyTracker *latencyTracker
+
+	// failureSampler holds samples of the recent failed payment attempts
+	// across all payments.
+	failureSampler *failureSampler
+
+	// graphAudit records the accepted graph mutations. It is nil if the
+	// audit log is disabled.
+	graphAudit *graphAuditLog
+
+	// chainGuard counts the announcements and payments that were rejected
+	// because they are for a different chain.
+	chainGuard chainGuard
+
+	// activePayments holds the lifecycles of the payments that are
+	// currently being attempted, so that they can be inspected.
+	activePayments *activePayments
+
+	// graphWrites tracks the queue depth of network updates, and reports
+	// graph mutations that stall their processing.
+	graphWrites *graphWriteTracker
+
+	// graphSynced is closed once the channel graph has been synchronized
+	// with the chain after startup.
+	graphSynced chan struct{}
+
+	// graphSyncDone receives the result of a deferred graph sync. The
+	// network handler then prunes the unconnected nodes from the graph
+	// and closes graphSynced.
+	graphSyncDone chan error
+
+	// graphUpdateMtx is held for reading while a network update is
+	// applied to the graph, and for writing while the unconnected nodes
+	// are pruned from it, such that nodes aren't pruned while their
+	// channels are being added.
+	graphUpdateMtx sync.RWMutex
+
+	// nodeAddrNtfns notifies subscribers when the addresses of a node
+	// change.
+	nodeAddrNtfns *subscribe.Server
+
+	// sourceNodes holds the nodes other than our own node on whose
+	// behalf routes are found, keyed by their public key.
+	sourceNodes    map[route.Vertex]*SourceNode
+	sourceNodesMtx sync.RWMutex
+
 	sync.RWMutex
 
 	quit chan struct{}
@@ -409,9 +560,21 @@ func New(cfg Config) (*ChannelRouter, error) {
 		topologyClients:   make(map[uint64]*topologyClient),
 		ntfnClientUpdates: make(chan *topologyClientUpdate),
 		channelEdgeMtx:    multimutex.NewMutex(),
+		latencyTracker:    newLatencyTracker(),
+		failureSampler:    newFailureSampler(maxFailureSamples),
+		activePayments:    newActivePayments(),
 		selfNode:          selfNode,
+		graphSynced:       make(chan struct{}),
+		graphSyncDone:     make(chan error, 1),
+		nodeAddrNtfns:     subscribe.NewServer(),
+		sourceNodes:       make(map[route.Vertex]*SourceNode),
 		quit:              make(chan struct{}),
 	}
+	r.graphWrites = newGraphWriteTracker()
+
+	if cfg.GraphAuditSize > 0 {
+		r.graphAudit = newGraphAuditLog(cfg.GraphAuditSize)
+	}
 
 	return r, nil
 }
@@ -426,6 +589,10 @@ func (r *ChannelRouter) Start() error {
 
 	log.Tracef("Channel Router starting")
 
+	if err := r.nodeAddrNtfns.Start(); err != nil {
+		return err
+	}
+
 	bestHash, bestHeight, err := r.cfg.Chain.GetBestBlock()
 	if err != nil {
 		return err
@@ -452,16 +619,10 @@ func (r *ChannelRouter) Start() error {
 		}
 	}
 
-	// If AssumeChannelValid is present, then we won't rely on pruning
-	// channels from the graph based on their spentness, but whether they
-	// are considered zombies or not.
-	if r.cfg.AssumeChannelValid {
-		if err := r.pruneZombieChans(); err != nil {
-			return err
-		}
-	} else {
-		// Otherwise, we'll use our filtered chain view to prune
-		// channels as soon as they are detected as spent on-chain.
+	// If AssumeChannelValid isn't present, we'll use our filtered chain
+	// view to prune channels as soon as they are detected as spent
+	// on-chain.
+	if !r.cfg.AssumeChannelValid {
 		if err := r.cfg.ChainView.Start(); err != nil {
 			return err
 		}
@@ -492,21 +653,32 @@ func (r *ChannelRouter) Start() error {
 				return err
 			}
 		}
+	}
 
-		// Before we begin normal operation of the router, we first need
-		// to synchronize the channel graph to the latest state of the
-		// UTXO set.
-		if err := r.syncGraphWithChain(); err != nil {
+	// Before we begin normal operation of the router, we first need to
+	// synchronize the channel graph to the latest state of the UTXO set.
+	// If requested, we do this in the background, so that payments can be
+	// made using the existing graph in the meantime. The network handler
+	// is started right away in that case, as callers block on network
+	// updates, but it only prunes the graph once the sync has completed.
+	if r.cfg.DeferGraphSync {
+		log.Infof("Deferring channel graph sync, router available " +
+			"for payments using existing graph")
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+
+			r.graphSyncDone <- r.syncGraph()
+		}()
+	} else {
+		if err := r.syncGraph(); err != nil {
 			return err
 		}
-
-		// Finally, before we proceed, we'll prune any unconnected nodes
-		// from the graph in order to ensure we maintain a tight graph
-		// of "useful" nodes.
-		err = r.cfg.Graph.PruneGraphNodes()
-		if err != nil && err != channeldb.ErrGraphNodesNotFound {
+		if err := r.pruneGraphNodes(); err != nil {
 			return err
 		}
+		close(r.graphSynced)
 	}
 
 	// If any payments are still in flight, we resume, to make sure their
@@ -516,12 +688,38 @@ func (r *ChannelRouter) Start() error {
 		return err
 	}
 
-	for _, payment := range payments {
+	// Before resuming, we reconcile the attempts recorded by the
+	// ControlTower with the state of the switch, as a crash may have left
+	// them inconsistent.
+	reconciliations, err := r.reconcileAttempts(payments)
+	if err != nil {
+		return err
+	}
+
+	for i, payment := range payments {
 		log.Infof("Resuming payment with hash %v", payment.Info.PaymentHash)
+
+		outcome := reconciliations[i].Outcome
+
 		r.wg.Add(1)
 		go func(payment *channeldb.InFlightPayment) {
 			defer r.wg.Done()
 
+			// If the attempt never reached the switch, we send the
+			// exact same attempt again, such that the switch will
+			// be able to deliver its result below.
+			if outcome == AttemptOutcomeNotDispatched {
+				err := r.redispatchAttempt(
+					payment.Info.PaymentHash,
+					payment.Attempt,
+				)
+				if err != nil {
+					log.Errorf("Unable to redispatch "+
+						"attempt for payment %v: %v",
+						payment.Info.PaymentHash, err)
+				}
+			}
+
 			// We create a dummy, empty payment session such that
 			// we won't make another payment attempt when the
 			// result for the in-flight attempt is received.
@@ -532,9 +730,13 @@ func (r *ChannelRouter) Start() error {
 
 			lPayment := &LightningPayment{
 				PaymentHash: payment.Info.PaymentHash,
+				TraceID:     TraceID(payment.Info.TraceID),
+				paused:      payment.Paused,
 			}
 
-			_, _, err = r.sendPayment(payment.Attempt, lPayment, paySession)
+			_, _, err := r.sendPayment(
+				payment.Attempt, lPayment, paySession,
+			)
 			if err != nil {
 				log.Errorf("Resuming payment with hash %v "+
 					"failed: %v.", payment.Info.PaymentHash, err)
@@ -552,6 +754,48 @@ func (r *ChannelRouter) Start() error {
 	return nil
 }
 
+// syncGraph brings the channel graph up to date with the chain. If
+// AssumeChannelValid is present, we won't rely on pruning channels from the
+// graph based on their spentness, but whether they are considered zombies or
+// not. Otherwise, the graph is synchronized to the latest state of the UTXO
+// set.
+func (r *ChannelRouter) syncGraph() error {
+	if r.cfg.AssumeChannelValid {
+		return r.pruneZombieChans()
+	}
+
+	return r.syncGraphWithChain()
+}
+
+// pruneGraphNodes prunes any unconnected nodes from the graph in order to
+// ensure we maintain a tight graph of "useful" nodes. It is called once the
+// graph has been synced, and waits for any network updates that are being
+// applied to the graph in the meantime.
+func (r *ChannelRouter) pruneGraphNodes() error {
+	// If AssumeChannelValid is present, nodes are never pruned, as their
+	// channels aren't pruned based on their spentness either.
+	if r.cfg.AssumeChannelValid {
+		return nil
+	}
+
+	r.graphUpdateMtx.Lock()
+	defer r.graphUpdateMtx.Unlock()
+
+	err := r.cfg.Graph.PruneGraphNodes()
+	if err != nil && err != channeldb.ErrGraphNodesNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// GraphSynced returns a channel that is closed once the channel graph has
+// been synchronized with the chain after startup. Until then, payments are
+// made using the existing graph, and the graph isn't pruned by new blocks.
+func (r *ChannelRouter) GraphSynced() <-chan struct{} {
+	return r.graphSynced
+}
+
 // Stop signals the ChannelRouter to gracefully halt all routines. This method
 // will *block* until all goroutines have excited. If the channel router has
 // already stopped then this method will return immediately.
@@ -573,7 +817,7 @@ func (r *ChannelRouter) Stop() error {
 	close(r.quit)
 	r.wg.Wait()
 
-	return nil
+	return r.nodeAddrNtfns.Stop()
 }
 
 // syncGraphWithChain attempts to synchronize the current channel graph with
@@ -587,7 +831,7 @@ func (r *ChannelRouter) syncGraphWithChain() error {
 	if err != nil {
 		return err
 	}
-	r.bestHeight = uint32(bestHeight)
+	atomic.StoreUint32(&r.bestHeight, uint32(bestHeight))
 
 	pruneHash, pruneHeight, err := r.cfg.Graph.PruneTip()
 	if err != nil {
@@ -707,6 +951,10 @@ func (r *ChannelRouter) syncGraphWithChain() error {
 			return err
 		}
 
+		r.publishPrunedChannels(
+			eventbus.PruneReasonClosed, closedChans...,
+		)
+
 		numClosed := uint32(len(closedChans))
 		log.Infof("Block %v (height=%v) closed %v channels",
 			nextHash, nextHeight, numClosed)
@@ -719,6 +967,27 @@ func (r *ChannelRouter) syncGraphWithChain() error {
 	return nil
 }
 
+// publishPrunedChannels publishes an event for each of the given channels that
+// were removed from the graph, and records their removal in the audit log.
+func (r *ChannelRouter) publishPrunedChannels(reason eventbus.PruneReason,
+	chans ...*channeldb.ChannelEdgeInfo) {
+
+	r.auditPrunedChannels(reason, chans...)
+
+	if r.cfg.EventBus == nil {
+		return
+	}
+
+	for _, info := range chans {
+		r.cfg.EventBus.Publish(eventbus.ChannelPrunedEvent{
+			ChannelID:    info.ChannelID,
+			ChannelPoint: info.ChannelPoint,
+			Capacity:     info.Capacity,
+			Reason:       reason,
+		})
+	}
+}
+
 // pruneZombieChans is a method that will be called periodically to prune out
 // any "zombie" channels. We consider channels zombies if *both* edges haven't
 // been updated since our zombie horizon. If AssumeChannelValid is present,
@@ -726,7 +995,10 @@ func (r *ChannelRouter) syncGraphWithChain() error {
 // usually signals that a channel has been closed on-chain. We do this
 // periodically to keep a healthy, lively routing table.
 func (r *ChannelRouter) pruneZombieChans() error {
-	var chansToPrune []uint64
+	var (
+		chansToPrune []uint64
+		zombieChans  []*channeldb.ChannelEdgeInfo
+	)
 	chanExpiry := r.cfg.ChannelPruneExpiry
 
 	log.Infof("Examining channel graph for zombie channels")
@@ -802,6 +1074,7 @@ func (r *ChannelRouter) pruneZombieChans() error {
 
 		// TODO(roasbeef): add ability to delete single directional edge
 		chansToPrune = append(chansToPrune, info.ChannelID)
+		zombieChans = append(zombieChans, info)
 
 		return nil
 	}
@@ -823,6 +1096,8 @@ func (r *ChannelRouter) pruneZombieChans() error {
 		return fmt.Errorf("unable to delete zombie channels: %v", err)
 	}
 
+	r.publishPrunedChannels(eventbus.PruneReasonZombie, zombieChans...)
+
 	// With the channels pruned, we'll also attempt to prune any nodes that
 	// were a part of them.
 	err = r.cfg.Graph.PruneGraphNodes()
@@ -849,6 +1124,18 @@ func (r *ChannelRouter) networkHandler() {
 	// in the proper order during parallel validation.
 	validationBarrier := NewValidationBarrier(runtime.NumCPU()*4, r.quit)
 
+	// Blocks and zombie channels are only processed once the channel
+	// graph has been synced with the chain, as they would otherwise
+	// interfere with the sync. Until then, the blocks are left queued in
+	// the chain view.
+	var (
+		graphSyncDone = r.graphSyncDone
+		graphSynced   = r.graphSynced
+		newBlocks     <-chan *chainview.FilteredBlock
+		staleBlocks   <-chan *chainview.FilteredBlock
+		pruneTicks    <-chan time.Time
+	)
+
 	for {
 		select {
 		// A new fully validated network update has just arrived. As a
@@ -860,10 +1147,13 @@ func (r *ChannelRouter) networkHandler() {
 			// have thousands of goroutines active.
 			validationBarrier.InitJobDependencies(update.msg)
 
+			r.graphWrites.updateQueued()
+
 			r.wg.Add(1)
 			go func() {
 				defer r.wg.Done()
 				defer validationBarrier.CompleteJob()
+				defer r.graphWrites.updateDone()
 
 				// If this message has an existing dependency,
 				// then we'll wait until that has been fully
@@ -885,7 +1175,9 @@ func (r *ChannelRouter) networkHandler() {
 				// this is either a new update from our PoV or
 				// an update to a prior vertex/edge we
 				// previously accepted.
-				err = r.processUpdate(update.msg)
+				r.graphUpdateMtx.RLock()
+				err = r.processUpdate(update.msg, update.source)
+				r.graphUpdateMtx.RUnlock()
 				update.err <- err
 
 				// If this message had any dependencies, then
@@ -916,7 +1208,33 @@ func (r *ChannelRouter) networkHandler() {
 			// after N blocks pass with no corresponding
 			// announcements.
 
-		case chainUpdate, ok := <-r.staleBlocks:
+		// The deferred graph sync has completed. We prune the
+		// unconnected nodes from here, so that no network updates are
+		// dispatched in the meantime, and then mark the graph as
+		// synced.
+		case err := <-graphSyncDone:
+			graphSyncDone = nil
+
+			if err != nil {
+				log.Errorf("Unable to sync channel graph with "+
+					"chain, graph may contain closed "+
+					"channels: %v", err)
+			} else if err := r.pruneGraphNodes(); err != nil {
+				log.Errorf("Unable to prune graph nodes: %v",
+					err)
+			}
+
+			close(r.graphSynced)
+
+		// The channel graph has been synced with the chain, so we can
+		// start pruning it.
+		case <-graphSynced:
+			graphSynced = nil
+			newBlocks = r.newBlocks
+			staleBlocks = r.staleBlocks
+			pruneTicks = graphPruneTicker.C
+
+		case chainUpdate, ok := <-staleBlocks:
 			// If the channel has been closed, then this indicates
 			// the daemon is shutting down, so we exit ourselves.
 			if !ok {
@@ -941,7 +1259,7 @@ func (r *ChannelRouter) networkHandler() {
 
 		// A new block has arrived, so we can prune the channel graph
 		// of any channels which were closed in the block.
-		case chainUpdate, ok := <-r.newBlocks:
+		case chainUpdate, ok := <-newBlocks:
 			// If the channel has been closed, then this indicates
 			// the daemon is shutting down, so we exit ourselves.
 			if !ok {
@@ -998,6 +1316,10 @@ func (r *ChannelRouter) networkHandler() {
 				continue
 			}
 
+			r.publishPrunedChannels(
+				eventbus.PruneReasonClosed, chansClosed...,
+			)
+
 			// Notify all currently registered clients of the newly
 			// closed channels.
 			closeSummaries := createCloseSummaries(blockHeight, chansClosed...)
@@ -1023,23 +1345,25 @@ func (r *ChannelRouter) networkHandler() {
 					close(client.exit)
 					client.wg.Wait()
 
+					if client.ordered != nil {
+						client.ordered.Stop()
+					}
+
 					close(client.ntfnChan)
 				}
 
 				continue
 			}
 
-			r.Lock()
-			r.topologyClients[ntfnUpdate.clientID] = &topologyClient{
-				ntfnChan: ntfnUpdate.ntfnChan,
-				exit:     make(chan struct{}),
+			err := r.registerTopologyClient(ntfnUpdate)
+			if ntfnUpdate.errChan != nil {
+				ntfnUpdate.errChan <- err
 			}
-			r.Unlock()
 
 		// The graph prune ticker has ticked, so we'll examine the
 		// state of the known graph to filter out any zombie channels
 		// for pruning.
-		case <-graphPruneTicker.C:
+		case <-pruneTicks:
 			if err := r.pruneZombieChans(); err != nil {
 				log.Errorf("Unable to prune zombies: %v", err)
 			}
@@ -1091,8 +1415,10 @@ func (r *ChannelRouter) assertNodeAnnFreshness(node route.Vertex,
 // processUpdate processes a new relate authenticated channel/edge, node or
 // channel/edge update network update. If the update didn't affect the internal
 // state of the draft due to either being out of date, invalid, or redundant,
-// then error is returned.
-func (r *ChannelRouter) processUpdate(msg interface{}) error {
+// then error is returned. Accepted updates are recorded in the audit log with
+// the given source.
+func (r *ChannelRouter) processUpdate(msg interface{},
+	source GraphMutationSource) error {
 	switch msg := msg.(type) {
 	case *channeldb.LightningNode:
 		// Before we add the node to the database, we'll check to see
@@ -1103,14 +1429,34 @@ func (r *ChannelRouter) processUpdate(msg interface{}) error {
 			return err
 		}
 
-		if err := r.cfg.Graph.AddLightningNode(msg); err != nil {
+		prevAddrs := r.announcedAddresses(msg.PubKeyBytes)
+
+		err = r.graphWrites.write("add node", func() error {
+			return r.cfg.Graph.AddLightningNode(msg)
+		})
+		if err != nil {
 			return errors.Errorf("unable to add node %v to the "+
 				"graph: %v", msg.PubKeyBytes, err)
 		}
 
 		log.Infof("Updated vertex data for node=%x", msg.PubKeyBytes)
 
+		r.auditMutation(GraphMutation{
+			Type:   GraphMutationNodeAnn,
+			Source: source,
+			Node:   msg.PubKeyBytes,
+		})
+
+		r.notifyAddressChange(msg.PubKeyBytes, prevAddrs, msg.Addresses)
+
 	case *channeldb.ChannelEdgeInfo:
+		// Reject announcements for channels on other chains, as they
+		// would contaminate our graph.
+		err := r.checkChannelAnnChain(msg.ChannelID, msg.ChainHash)
+		if err != nil {
+			return err
+		}
+
 		// Prior to processing the announcement we first check if we
 		// already know of this channel, if so, then we can exit early.
 		_, _, exists, isZombie, err := r.cfg.Graph.HasChannelEdge(
@@ -1134,13 +1480,18 @@ func (r *ChannelRouter) processUpdate(msg interface{}) error {
 		// short-circuit our path straight to adding the edge to our
 		// graph.
 		if r.cfg.AssumeChannelValid {
-			if err := r.cfg.Graph.AddChannelEdge(msg); err != nil {
+			err := r.graphWrites.write("add edge", func() error {
+				return r.cfg.Graph.AddChannelEdge(msg)
+			})
+			if err != nil {
 				return fmt.Errorf("unable to add edge: %v", err)
 			}
 			log.Infof("New channel discovered! Link "+
 				"connects %x and %x with ChannelID(%v)",
 				msg.NodeKey1Bytes, msg.NodeKey2Bytes,
 				msg.ChannelID)
+
+			r.auditChannelAnn(msg, source)
 			break
 		}
 
@@ -1195,7 +1546,10 @@ func (r *ChannelRouter) processUpdate(msg interface{}) error {
 		// after commitment fees are dynamic.
 		msg.Capacity = btcutil.Amount(chanUtxo.Value)
 		msg.ChannelPoint = *fundingPoint
-		if err := r.cfg.Graph.AddChannelEdge(msg); err != nil {
+		err = r.graphWrites.write("add edge", func() error {
+			return r.cfg.Graph.AddChannelEdge(msg)
+		})
+		if err != nil {
 			return errors.Errorf("unable to add edge: %v", err)
 		}
 
@@ -1205,6 +1559,8 @@ func (r *ChannelRouter) processUpdate(msg interface{}) error {
 			msg.NodeKey1Bytes, msg.NodeKey2Bytes,
 			fundingPoint, msg.ChannelID, msg.Capacity)
 
+		r.auditChannelAnn(msg, source)
+
 		// As a new edge has been added to the channel graph, we'll
 		// update the current UTXO filter within our active
 		// FilteredChainView so we are notified if/when this channel is
@@ -1290,10 +1646,31 @@ func (r *ChannelRouter) processUpdate(msg interface{}) error {
 			}
 		}
 
+		// Retrieve the policy that is about to be replaced, so that
+		// the change can be audited.
+		var oldPolicy *channeldb.ChannelEdgePolicy
+		if r.graphAudit != nil {
+			_, e1, e2, err := r.cfg.Graph.FetchChannelEdgesByID(
+				msg.ChannelID,
+			)
+			if err != nil {
+				return errors.Errorf("unable to fetch edge "+
+					"policies: %v", err)
+			}
+
+			oldPolicy = e1
+			if msg.ChannelFlags&lnwire.ChanUpdateDirection == 1 {
+				oldPolicy = e2
+			}
+		}
+
 		// Now that we know this isn't a stale update, we'll apply the
 		// new edge policy to the proper directional edge within the
 		// channel graph.
-		if err = r.cfg.Graph.UpdateEdgePolicy(msg); err != nil {
+		err = r.graphWrites.write("update edge policy", func() error {
+			return r.cfg.Graph.UpdateEdgePolicy(msg)
+		})
+		if err != nil {
 			err := errors.Errorf("unable to add channel: %v", err)
 			log.Error(err)
 			return err
@@ -1302,6 +1679,10 @@ func (r *ChannelRouter) processUpdate(msg interface{}) error {
 		log.Tracef("New channel update applied: %v",
 			newLogClosure(func() string { return spew.Sdump(msg) }))
 
+		if r.graphAudit != nil {
+			r.auditChannelUpdate(msg, oldPolicy, source)
+		}
+
 	default:
 		return errors.Errorf("wrong routing update message type")
 	}
@@ -1355,8 +1736,9 @@ func (r *ChannelRouter) fetchChanPoint(
 // routingMsg couples a routing related routing topology update to the
 // error channel.
 type routingMsg struct {
-	msg interface{}
-	err chan error
+	msg    interface{}
+	source GraphMutationSource
+	err    chan error
 }
 
 // FindRoute attempts to query the ChannelRouter for the optimum path to a
@@ -1385,20 +1767,22 @@ func (r *ChannelRouter) FindRoute(source, target route.Vertex,
 	}
 
 	// We'll attempt to obtain a set of bandwidth hints that can help us
-	// eliminate certain routes early on in the path finding process.
-	bandwidthHints, err := generateBandwidthHints(
-		r.selfNode, r.cfg.QueryBandwidth,
-	)
+	// eliminate certain routes early on in the path finding process. If
+	// the source is one of the additional source nodes, the hints of its
+	// channels are included.
+	bandwidthHints, err := r.sourceBandwidthHints(source)
 	if err != nil {
 		return nil, err
 	}
 
 	// Now that we know the destination is reachable within the graph, we'll
 	// execute our path finding algorithm.
+	selfNode := route.Vertex(r.selfNode.PubKeyBytes)
 	path, err := findPath(
 		&graphParams{
 			graph:          r.cfg.Graph,
 			bandwidthHints: bandwidthHints,
+			selfNode:       &selfNode,
 		},
 		restrictions, source, target, amt,
 	)
@@ -1550,14 +1934,101 @@ type LightningPayment struct {
 	// destination successfully.
 	RouteHints [][]zpay32.HopHint
 
-	// OutgoingChannelID is the channel that needs to be taken to the first
-	// hop. If nil, any channel may be used.
-	OutgoingChannelID *uint64
+	// HopHintBandwidths is an optional map from the channel ids of route
+	// hint channels to their usable bandwidth. It allows path finding to
+	// skip private channels that are known to be unable to carry the
+	// payment, rather than assuming infinite capacity.
+	HopHintBandwidths map[uint64]lnwire.MilliSatoshi
+
+	// OutgoingChannelIDs is the set of channels of which one needs to be
+	// taken to the first hop. If empty, any channel may be used.
+	OutgoingChannelIDs []uint64
+
+	// LastHop is the node that needs to be the last hop before the
+	// target. If nil, any node may be the last hop. This is useful if the
+	// target is only reachable through a single peer, such as its
+	// liquidity service provider.
+	LastHop *route.Vertex
+
+	// MaxHops is the maximum number of hops of the routes that are
+	// attempted. If zero, HopLimit applies.
+	MaxHops uint32
+
+	// IgnoredNodes is an optional set of nodes that must not be used as
+	// intermediate hops. It allows callers to exclude nodes that are
+	// known to be unreliable, without waiting for mission control to
+	// learn about them.
+	IgnoredNodes map[route.Vertex]struct{}
+
+	// IgnoredPairs is an optional set of directed node pairs. For each
+	// pair, the channels from the first to the second node aren't used.
+	IgnoredPairs map[DirectedNodePair]struct{}
+
+	// AvoidTags is an optional list of annotation tags. Channels and
+	// intermediate nodes that the operator annotated with one of these
+	// tags aren't used to route the payment.
+	AvoidTags []string
+
+	// MinSuccessProbability is an optional minimum success probability,
+	// as estimated by mission control, of the routes that are attempted
+	// for this payment. Routes that are less likely to succeed are
+	// rejected during path finding, so that no attempts are made that
+	// mostly add latency and lock up liquidity. The minimum probability
+	// that is configured for mission control applies as well.
+	MinSuccessProbability float64
+
+	// TraceID is the correlation id under which the router logs its
+	// progress on the payment. If zero, a random id is assigned. It is
+	// stored in the creation info of the payment.
+	TraceID TraceID
 
 	// PaymentRequest is an optional payment request that this payment is
 	// attempting to complete.
 	PaymentRequest []byte
 
+	// InvoiceExpiry is the time at which the invoice this payment is
+	// attempting to pay expires. Once it has passed, no new payment
+	// attempts are made, as the recipient would reject them. A zero value
+	// means the invoice never expires.
+	InvoiceExpiry time.Time
+
+	// MissionControlMode defines whether the penalties of mission control
+	// are taken into account for this payment and whether its failures
+	// are reported back to mission control. Bypassing mission control is
+	// useful to diagnose whether stale penalties cause path finding to
+	// fail.
+	MissionControlMode MissionControlMode
+
+	// Label is an optional external reference that is persisted with the
+	// payment, such as the id of the order that it pays for.
+	Label string
+
+	// GroupID optionally groups related payments, so that they can be
+	// listed together.
+	GroupID string
+
+	// LivenessCheck determines whether the destination is checked for
+	// signs of being offline before the first attempt, which saves
+	// fee-bearing attempts to destinations that can't be reached.
+	LivenessCheck LivenessCheck
+
+	// HubPenalty is an optional penalty for routing through nodes with
+	// many channels. It allows users to reduce their reliance on large
+	// hubs, even if that makes the payment slightly more expensive.
+	HubPenalty *HubPenalty
+
+	// TimePref shifts the trade-off of path finding between cheap and
+	// reliable routes. It ranges from -1, which only minimizes fees and
+	// suits batch payouts, to 1, which strongly prefers routes that are
+	// likely to succeed at the first attempt and suits interactive
+	// payments. Zero applies the configured payment attempt penalty
+	// unchanged.
+	TimePref float64
+
+	// paused indicates that the payment starts out paused. It is set for
+	// in-flight payments that were paused before a restart.
+	paused bool
+
 	// TODO(roasbeef): add e2e message?
 }
 
@@ -1610,16 +2081,41 @@ func (r *ChannelRouter) SendPaymentAsync(payment *LightningPayment) error {
 func (r *ChannelRouter) preparePayment(payment *LightningPayment) (
 	PaymentSession, error) {
 
+	// Ensure that we aren't attempting to pay an invoice for another
+	// chain.
+	if err := r.checkPaymentChain(payment); err != nil {
+		return nil, err
+	}
+
+	if payment.TimePref < -1 || payment.TimePref > 1 {
+		return nil, fmt.Errorf("time preference %v out of range "+
+			"[-1, 1]", payment.TimePref)
+	}
+
+	// Fail fast or warn if the destination looks offline.
+	err := r.checkDestinationLiveness(payment, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	// Before starting the HTLC routing attempt, we'll create a fresh
 	// payment session which will report our errors back to mission
 	// control.
+	routeHints, err := r.applyHintPolicies(payment.RouteHints)
+	if err != nil {
+		return nil, err
+	}
 	paySession, err := r.cfg.MissionControl.NewPaymentSession(
-		payment.RouteHints, payment.Target,
+		routeHints, payment.Target,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if payment.TraceID == 0 {
+		payment.TraceID = newTraceID()
+	}
+
 	// Record this payment hash with the ControlTower, ensuring it is not
 	// already in-flight.
 	info := &channeldb.PaymentCreationInfo{
@@ -1627,6 +2123,9 @@ func (r *ChannelRouter) preparePayment(payment *LightningPayment) (
 		Value:          payment.Amount,
 		CreationDate:   time.Now(),
 		PaymentRequest: payment.PaymentRequest,
+		TraceID:        uint64(payment.TraceID),
+		Label:          payment.Label,
+		GroupID:        payment.GroupID,
 	}
 
 	err = r.cfg.Control.InitPayment(payment.PaymentHash, info)
@@ -1640,8 +2139,17 @@ func (r *ChannelRouter) preparePayment(payment *LightningPayment) (
 // SendToRoute attempts to send a payment with the given hash through the
 // provided route. This function is blocking and will return the obtained
 // preimage if the payment is successful or the full error in case of a failure.
-func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, route *route.Route) (
-	lntypes.Preimage, error) {
+// The fee limit is optional. If set, a route whose total fees exceed it is
+// rejected with ErrFeeLimitExceeded before the payment is initiated, which
+// protects callers that construct routes themselves against fee mistakes.
+func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, route *route.Route,
+	feeLimit ...lnwire.MilliSatoshi) (lntypes.Preimage, error) {
+
+	if len(feeLimit) > 0 && route.TotalFees() > feeLimit[0] {
+		return lntypes.Preimage{}, newErrf(ErrFeeLimitExceeded,
+			"route fee of %v exceeds fee limit of %v",
+			route.TotalFees(), feeLimit[0])
+	}
 
 	// Create a payment session for just this route.
 	paySession := r.cfg.MissionControl.NewPaymentSessionForRoute(route)
@@ -1651,11 +2159,13 @@ func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, route *route.Route) (
 
 	// Record this payment hash with the ControlTower, ensuring it is not
 	// already in-flight.
+	traceID := newTraceID()
 	info := &channeldb.PaymentCreationInfo{
 		PaymentHash:    hash,
 		Value:          amt,
 		CreationDate:   time.Now(),
 		PaymentRequest: nil,
+		TraceID:        uint64(traceID),
 	}
 
 	err := r.cfg.Control.InitPayment(hash, info)
@@ -1672,6 +2182,7 @@ func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, route *route.Route) (
 	// attempt.
 	payment := &LightningPayment{
 		PaymentHash: hash,
+		TraceID:     traceID,
 	}
 
 	// Since this is the first time this payment is being made, we pass nil
@@ -1717,8 +2228,8 @@ func (r *ChannelRouter) sendPayment(
 	payment *LightningPayment, paySession PaymentSession) (
 	[32]byte, *route.Route, error) {
 
-	log.Tracef("Dispatching route for lightning payment: %v",
-		newLogClosure(func() string {
+	log.Tracef("Dispatching route for lightning payment (trace=%v): %v",
+		payment.TraceID, newLogClosure(func() string {
 			for _, routeHint := range payment.RouteHints {
 				for _, hopHint := range routeHint {
 					hopHint.NodeID.Curve = nil
@@ -1746,15 +2257,31 @@ func (r *ChannelRouter) sendPayment(
 		attempt:        existingAttempt,
 		circuit:        nil,
 		lastError:      nil,
+		resumeChan:     make(chan struct{}),
+	}
+	if payment.paused {
+		p.setPaused(true)
 	}
 
 	// If a timeout is specified, create a timeout channel. If no timeout is
 	// specified, the channel is left nil and will never abort the payment
 	// loop.
+	p.started = time.Now()
 	if payment.PayAttemptTimeout != 0 {
 		p.timeoutChan = time.After(payment.PayAttemptTimeout)
+		p.deadline = p.started.Add(payment.PayAttemptTimeout)
+	}
+
+	// A resumed attempt counts as the first attempt of the payment.
+	if existingAttempt != nil {
+		p.recordAttempt(&existingAttempt.Route)
 	}
 
+	// Register the payment for the duration of its execution, so that
+	// its progress can be inspected.
+	r.activePayments.add(p)
+	defer r.activePayments.remove(p)
+
 	return p.resumePayment()
 
 }
@@ -1764,13 +2291,15 @@ func (r *ChannelRouter) sendPayment(
 // error type, this error is either the final outcome of the payment or we need
 // to continue with an alternative route. This is indicated by the boolean
 // return value.
-func (r *ChannelRouter) processSendError(paySession PaymentSession,
-	rt *route.Route, fErr *htlcswitch.ForwardingError) bool {
+func (r *ChannelRouter) processSendError(traceID TraceID,
+	paySession PaymentSession, rt *route.Route,
+	fErr *htlcswitch.ForwardingError) bool {
 
 	errSource := fErr.ErrorSource
 	errVertex := route.NewVertex(errSource)
 
-	log.Tracef("node=%x reported failure when sending htlc", errVertex)
+	log.Tracef("[trace=%v] node=%x reported failure when sending htlc",
+		traceID, errVertex)
 
 	// Always determine chan id ourselves, because a channel
 	// update with id may not be available.
@@ -1781,6 +2310,8 @@ func (r *ChannelRouter) processSendError(paySession PaymentSession,
 		return true
 	}
 
+	r.sampleFailure(rt, errVertex, failedEdge, failedAmt, fErr)
+
 	// processChannelUpdateAndRetry is a closure that
 	// handles a failure message containing a channel
 	// update. This function always tries to apply the
@@ -1796,8 +2327,13 @@ func (r *ChannelRouter) processSendError(paySession PaymentSession,
 		update *lnwire.ChannelUpdate,
 		pubKey *btcec.PublicKey) {
 
-		// Try to apply the channel update.
+		// Try to apply the channel update. If the channel is a
+		// private channel of a route hint, we'll learn its policy
+		// instead.
 		updateOk := r.applyChannelUpdate(update, pubKey)
+		if !updateOk {
+			updateOk = r.learnHintPolicy(update, pubKey, failedEdge)
+		}
 
 		// If the update could not be applied, prune the
 		// edge. There is no reason to continue trying
@@ -1808,175 +2344,107 @@ func (r *ChannelRouter) processSendError(paySession PaymentSession,
 		// update to fail?
 		if !updateOk {
 			paySession.ReportEdgeFailure(
-				failedEdge, 0,
+				failedEdge, 0, FailureClassPermanent,
 			)
 		}
 
 		paySession.ReportEdgePolicyFailure(failedEdge)
 	}
 
-	switch onionErr := fErr.FailureMessage.(type) {
-
-	// If the end destination didn't know the payment
-	// hash or we sent the wrong payment amount to the
-	// destination, then we'll terminate immediately.
-	case *lnwire.FailUnknownPaymentHash:
-		return true
-
-	// If we sent the wrong amount to the destination, then
-	// we'll exit early.
-	case *lnwire.FailIncorrectPaymentAmount:
-		return true
-
-	// If the time-lock that was extended to the final node
-	// was incorrect, then we can't proceed.
-	case *lnwire.FailFinalIncorrectCltvExpiry:
-		return true
-
-	// If we crafted an invalid onion payload for the final
-	// node, then we'll exit early.
-	case *lnwire.FailFinalIncorrectHtlcAmount:
-		return true
-
-	// Similarly, if the HTLC expiry that we extended to
-	// the final hop expires too soon, then will fail the
-	// payment.
-	//
-	// TODO(roasbeef): can happen to to race condition, try
-	// again with recent block height
-	case *lnwire.FailFinalExpiryTooSoon:
-		return true
-
-	// If we erroneously attempted to cross a chain border,
-	// then we'll cancel the payment.
-	case *lnwire.FailInvalidRealm:
-		return true
+	// If our own switch reports that it doesn't know the first hop, the
+	// link with our peer is unavailable. This is a problem with our local
+	// channel rather than with the network, so instead of penalizing the
+	// channel in mission control, we'll refresh our view of the local
+	// channels and retry over one of the others.
+	if errVertex == rt.SourcePubKey &&
+		fErr.FailureMessage != nil &&
+		fErr.FailureMessage.Code() == lnwire.CodeUnknownNextPeer {
 
-	// If we get a notice that the expiry was too soon for
-	// an intermediate node, then we'll prune out the node
-	// that sent us this error, as it doesn't now what the
-	// correct block height is.
-	case *lnwire.FailExpiryTooSoon:
-		r.applyChannelUpdate(&onionErr.Update, errSource)
-		paySession.ReportVertexFailure(errVertex)
+		r.handleLocalUnknownNextPeer(paySession, failedEdge)
 		return false
+	}
 
-	// If we hit an instance of onion payload corruption or
-	// an invalid version, then we'll exit early as this
-	// shouldn't happen in the typical case.
-	case *lnwire.FailInvalidOnionVersion:
-		return true
-	case *lnwire.FailInvalidOnionHmac:
-		return true
-	case *lnwire.FailInvalidOnionKey:
-		return true
+	policies := r.cfg.FailurePolicies
+	if policies == nil {
+		policies = failpolicy.DefaultTable
+	}
 
-	// If we get a failure due to violating the minimum
-	// amount, we'll apply the new minimum amount and retry
-	// routing.
-	case *lnwire.FailAmountBelowMinimum:
-		processChannelUpdateAndRetry(
-			&onionErr.Update, errSource,
-		)
-		return false
+	policy := policies.Lookup(fErr.FailureMessage)
 
-	// If we get a failure due to a fee, we'll apply the
-	// new fee update, and retry our attempt using the
-	// newly updated fees.
-	case *lnwire.FailFeeInsufficient:
-		processChannelUpdateAndRetry(
-			&onionErr.Update, errSource,
-		)
-		return false
+	log.Debugf("[trace=%v] Failure %T from node %x: terminal=%v, "+
+		"permanent=%v, penalty=%v", traceID, fErr.FailureMessage,
+		errVertex, policy.Terminal, policy.Permanent, policy.Penalty)
 
-	// If we get the failure for an intermediate node that
-	// disagrees with our time lock values, then we'll
-	// apply the new delta value and try it once more.
-	case *lnwire.FailIncorrectCltvExpiry:
-		processChannelUpdateAndRetry(
-			&onionErr.Update, errSource,
-		)
-		return false
+	if policy.Terminal {
+		return true
+	}
 
-	// The outgoing channel that this node was meant to
-	// forward one is currently disabled, so we'll apply
-	// the update and continue.
-	case *lnwire.FailChannelDisabled:
-		r.applyChannelUpdate(&onionErr.Update, errSource)
-		paySession.ReportEdgeFailure(failedEdge, 0)
-		return false
+	class := FailureClassTemporary
+	if policy.Permanent {
+		class = FailureClassPermanent
+	}
 
-	// It's likely that the outgoing channel didn't have
-	// sufficient capacity, so we'll prune this edge for
-	// now, and continue onwards with our path finding.
-	case *lnwire.FailTemporaryChannelFailure:
-		r.applyChannelUpdate(onionErr.Update, errSource)
-		paySession.ReportEdgeFailure(failedEdge, failedAmt)
-		return false
+	update := failpolicy.ChannelUpdate(fErr.FailureMessage)
 
-	// If the send fail due to a node not having the
-	// required features, then we'll note this error and
-	// continue.
-	case *lnwire.FailRequiredNodeFeatureMissing:
-		paySession.ReportVertexFailure(errVertex)
-		return false
+	// Policy failures are handled by the closure above, which takes care
+	// of applying the enclosed channel update itself.
+	if policy.Penalty == failpolicy.PenaltyChannelPolicy {
+		if update == nil {
+			paySession.ReportEdgeFailure(failedEdge, 0, class)
+			return false
+		}
 
-	// If the send fail due to a node not having the
-	// required features, then we'll note this error and
-	// continue.
-	case *lnwire.FailRequiredChannelFeatureMissing:
-		paySession.ReportVertexFailure(errVertex)
+		processChannelUpdateAndRetry(update, errSource)
 		return false
+	}
 
-	// If the next hop in the route wasn't known or
-	// offline, we'll only the channel which we attempted
-	// to route over. This is conservative, and it can
-	// handle faulty channels between nodes properly.
-	// Additionally, this guards against routing nodes
-	// returning errors in order to attempt to black list
-	// another node.
-	case *lnwire.FailUnknownNextPeer:
-		paySession.ReportEdgeFailure(failedEdge, 0)
-		return false
+	if policy.ApplyUpdate && update != nil {
+		r.applyChannelUpdate(update, errSource)
+	}
 
-	// If the node wasn't able to forward for which ever
-	// reason, then we'll note this and continue with the
-	// routes.
-	case *lnwire.FailTemporaryNodeFailure:
-		paySession.ReportVertexFailure(errVertex)
-		return false
+	switch policy.Penalty {
+	case failpolicy.PenaltyNode:
+		paySession.ReportVertexFailure(errVertex, class)
 
-	case *lnwire.FailPermanentNodeFailure:
-		paySession.ReportVertexFailure(errVertex)
-		return false
+	case failpolicy.PenaltyOutgoingChannel:
+		paySession.ReportEdgeFailure(failedEdge, 0, class)
 
-	// If we crafted a route that contains a too long time
-	// lock for an intermediate node, we'll prune the node.
-	// As there currently is no way of knowing that node's
-	// maximum acceptable cltv, we cannot take this
-	// constraint into account during routing.
-	//
-	// TODO(joostjager): Record the rejected cltv and use
-	// that as a hint during future path finding through
-	// that node.
-	case *lnwire.FailExpiryTooFar:
-		paySession.ReportVertexFailure(errVertex)
-		return false
+	case failpolicy.PenaltyOutgoingChannelAmt:
+		paySession.ReportEdgeFailure(failedEdge, failedAmt, class)
 
-	// If we get a permanent channel or node failure, then
-	// we'll prune the channel in both directions and
-	// continue with the rest of the routes.
-	case *lnwire.FailPermanentChannelFailure:
-		paySession.ReportEdgeFailure(failedEdge, 0)
+	// We'll prune the channel in both directions and continue with the
+	// rest of the routes.
+	case failpolicy.PenaltyOutgoingChannelBoth:
+		paySession.ReportEdgeFailure(failedEdge, 0, class)
 		paySession.ReportEdgeFailure(edge{
 			from:    failedEdge.to,
 			to:      failedEdge.from,
 			channel: failedEdge.channel,
-		}, 0)
-		return false
+		}, 0, class)
+	}
 
-	default:
-		return true
+	return false
+}
+
+// handleLocalUnknownNextPeer handles a failure of our own switch to find the
+// link of the first hop of a route.
+func (r *ChannelRouter) handleLocalUnknownNextPeer(paySession PaymentSession,
+	failedEdge edge) {
+
+	log.Debugf("Local channel %v to peer %x is unavailable",
+		failedEdge.channel, failedEdge.to[:])
+
+	if err := paySession.ReportLocalChannelFailure(
+		failedEdge.channel,
+	); err != nil {
+		log.Errorf("Unable to refresh bandwidth hints: %v", err)
+	}
+
+	if r.cfg.LocalChannelUnavailable != nil {
+		r.cfg.LocalChannelUnavailable(
+			lnwire.NewShortChanIDFromInt(failedEdge.channel),
+			failedEdge.to,
+		)
 	}
 }
 
@@ -2032,6 +2500,16 @@ func (r *ChannelRouter) applyChannelUpdate(msg *lnwire.ChannelUpdate,
 		return true
 	}
 
+	// Updates for channels on other chains must never be applied to our
+	// graph.
+	err := r.checkChannelUpdateChain(
+		msg.ShortChannelID.ToUint64(), msg.ChainHash,
+	)
+	if err != nil {
+		log.Errorf("Unable to apply channel update: %v", err)
+		return false
+	}
+
 	ch, _, _, err := r.GetChannelByID(msg.ShortChannelID)
 	if err != nil {
 		log.Errorf("Unable to retrieve channel by id: %v", err)
@@ -2043,7 +2521,7 @@ func (r *ChannelRouter) applyChannelUpdate(msg *lnwire.ChannelUpdate,
 		return false
 	}
 
-	err = r.UpdateEdge(&channeldb.ChannelEdgePolicy{
+	err = r.updateEdge(&channeldb.ChannelEdgePolicy{
 		SigBytes:                  msg.Signature.ToSignatureBytes(),
 		ChannelID:                 msg.ShortChannelID.ToUint64(),
 		LastUpdate:                time.Unix(int64(msg.Timestamp), 0),
@@ -2054,7 +2532,8 @@ func (r *ChannelRouter) applyChannelUpdate(msg *lnwire.ChannelUpdate,
 		MaxHTLC:                   msg.HtlcMaximumMsat,
 		FeeBaseMSat:               lnwire.MilliSatoshi(msg.BaseFee),
 		FeeProportionalMillionths: lnwire.MilliSatoshi(msg.FeeRate),
-	})
+		ExtraOpaqueData:           msg.ExtraOpaqueData,
+	}, GraphSourcePaymentFailure)
 	if err != nil && !IsError(err, ErrIgnored, ErrOutdated) {
 		log.Errorf("Unable to apply channel update: %v", err)
 		return false
@@ -2116,9 +2595,18 @@ func (r *ChannelRouter) AddEdge(edge *channeldb.ChannelEdgeInfo) error {
 //
 // NOTE: This method is part of the ChannelGraphSource interface.
 func (r *ChannelRouter) UpdateEdge(update *channeldb.ChannelEdgePolicy) error {
+	return r.updateEdge(update, GraphSourceGossip)
+}
+
+// updateEdge applies the given policy update, recording the source it
+// originates from in the audit log.
+func (r *ChannelRouter) updateEdge(update *channeldb.ChannelEdgePolicy,
+	source GraphMutationSource) error {
+
 	rMsg := &routingMsg{
-		msg: update,
-		err: make(chan error, 1),
+		msg:    update,
+		source: source,
+		err:    make(chan error, 1),
 	}
 
 	select {
@@ -2309,3 +2797,25 @@ func (r *ChannelRouter) IsStaleEdgePolicy(chanID lnwire.ShortChannelID,
 func (r *ChannelRouter) MarkEdgeLive(chanID lnwire.ShortChannelID) error {
 	return r.cfg.Graph.MarkEdgeLive(chanID.ToUint64())
 }
+
+// MarkEdgesLive clears all of the given edges from our zombie index, deeming
+// them as live. This can be used to correct over-aggressive zombification,
+// e.g. after an extended period of downtime.
+//
+// NOTE: This method is part of the ChannelGraphSource interface.
+func (r *ChannelRouter) MarkEdgesLive(chanIDs ...lnwire.ShortChannelID) error {
+	rawChanIDs := make([]uint64, 0, len(chanIDs))
+	for _, chanID := range chanIDs {
+		rawChanIDs = append(rawChanIDs, chanID.ToUint64())
+	}
+
+	return r.cfg.Graph.MarkEdgesLive(rawChanIDs...)
+}
+
+// ZombieEdges returns all edges that are currently marked as zombies, along
+// with the last update time that caused them to be marked.
+//
+// NOTE: This method is part of the ChannelGraphSource interface.
+func (r *ChannelRouter) ZombieEdges() ([]channeldb.ZombieEdge, error) {
+	return r.cfg.Graph.ZombieEdges()
+}
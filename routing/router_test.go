@@ -2,6 +2,8 @@ package routing
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"image/color"
 	"math/rand"
@@ -253,7 +255,9 @@ func TestSendPaymentRouteFailureFallback(t *testing.T) {
 
 	// Craft a LightningPayment struct that'll send a payment from roasbeef
 	// to luo ji for 1000 satoshis, with a maximum of 1000 satoshis in fees.
-	var payHash [32]byte
+	var preImage [32]byte
+	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
+	payHash := sha256.Sum256(preImage[:])
 	paymentAmt := lnwire.NewMSatFromSatoshis(1000)
 	payment := LightningPayment{
 		Target:      ctx.aliases["luoji"],
@@ -262,9 +266,6 @@ func TestSendPaymentRouteFailureFallback(t *testing.T) {
 		PaymentHash: payHash,
 	}
 
-	var preImage [32]byte
-	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
-
 	sourceNode := ctx.router.selfNode
 
 	// We'll modify the SendToSwitch method that's been set within the
@@ -500,7 +501,9 @@ func TestSendPaymentErrorRepeatedFeeInsufficient(t *testing.T) {
 
 	// Craft a LightningPayment struct that'll send a payment from roasbeef
 	// to luo ji for 100 satoshis.
-	var payHash [32]byte
+	var preImage [32]byte
+	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
+	payHash := sha256.Sum256(preImage[:])
 	amt := lnwire.NewMSatFromSatoshis(1000)
 	payment := LightningPayment{
 		Target:      ctx.aliases["sophon"],
@@ -509,9 +512,6 @@ func TestSendPaymentErrorRepeatedFeeInsufficient(t *testing.T) {
 		PaymentHash: payHash,
 	}
 
-	var preImage [32]byte
-	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
-
 	// We'll also fetch the first outgoing channel edge from roasbeef to
 	// son goku. We'll obtain this as we'll need to to generate the
 	// FeeInsufficient error that we'll send back.
@@ -612,7 +612,9 @@ func TestSendPaymentErrorNonFinalTimeLockErrors(t *testing.T) {
 
 	// Craft a LightningPayment struct that'll send a payment from roasbeef
 	// to sophon for 1k satoshis.
-	var payHash [32]byte
+	var preImage [32]byte
+	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
+	payHash := sha256.Sum256(preImage[:])
 	amt := lnwire.NewMSatFromSatoshis(1000)
 	payment := LightningPayment{
 		Target:      ctx.aliases["sophon"],
@@ -621,9 +623,6 @@ func TestSendPaymentErrorNonFinalTimeLockErrors(t *testing.T) {
 		PaymentHash: payHash,
 	}
 
-	var preImage [32]byte
-	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
-
 	// We'll also fetch the first outgoing channel edge from roasbeef to
 	// son goku. This edge will be included in the time lock related expiry
 	// errors that we'll get back due to disagrements in what the current
@@ -749,6 +748,246 @@ func TestSendPaymentErrorNonFinalTimeLockErrors(t *testing.T) {
 	assertExpectedPath(paymentPreImage, rt)
 }
 
+// TestSendPaymentInvoiceExpired tests that the router stops making payment
+// attempts once the invoice being paid has expired.
+func TestSendPaymentInvoiceExpired(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(startingBlockHeight, basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	var payHash [32]byte
+	payment := LightningPayment{
+		Target:        ctx.aliases["sophon"],
+		Amount:        lnwire.NewMSatFromSatoshis(1000),
+		FeeLimit:      noFeeLimit,
+		PaymentHash:   payHash,
+		InvoiceExpiry: time.Now().Add(100 * time.Millisecond),
+	}
+
+	sourceNode := ctx.router.selfNode
+
+	// Every attempt fails with a temporary error, but only after the
+	// invoice has expired. This means that only a single attempt should be
+	// made.
+	var numAttempts int
+	ctx.router.cfg.Payer.(*mockPaymentAttemptDispatcher).setPaymentResult(
+		func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+			numAttempts++
+			time.Sleep(200 * time.Millisecond)
+
+			pub, err := sourceNode.PubKey()
+			if err != nil {
+				return [32]byte{}, err
+			}
+			return [32]byte{}, &htlcswitch.ForwardingError{
+				ErrorSource:    pub,
+				FailureMessage: &lnwire.FailTemporaryChannelFailure{},
+			}
+		})
+
+	_, _, err = ctx.router.SendPayment(&payment)
+	if !IsError(err, ErrInvoiceExpired) {
+		t.Fatalf("expected ErrInvoiceExpired, got: %v", err)
+	}
+	if numAttempts != 1 {
+		t.Fatalf("expected a single attempt, got %v", numAttempts)
+	}
+}
+
+// TestSendPaymentLocalPolicyCheck asserts that a route that violates our own
+// outgoing policy is replaced before an attempt is made, without penalizing
+// the channel in mission control.
+func TestSendPaymentLocalPolicyCheck(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(startingBlockHeight, basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	var preImage [32]byte
+	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
+	payHash := sha256.Sum256(preImage[:])
+	payment := LightningPayment{
+		Target:      ctx.aliases["luoji"],
+		Amount:      lnwire.NewMSatFromSatoshis(1000),
+		FeeLimit:    noFeeLimit,
+		PaymentHash: payHash,
+	}
+
+	// The direct channel to luo ji would be used first, but it violates
+	// our own policy.
+	roasbeefLuoji := lnwire.NewShortChanIDFromInt(689530843)
+	var checked []lnwire.ShortChannelID
+	ctx.router.cfg.CheckLocalPolicy = func(firstHop lnwire.ShortChannelID,
+		htlc *lnwire.UpdateAddHTLC) error {
+
+		checked = append(checked, firstHop)
+		if firstHop == roasbeefLuoji {
+			return errors.New("channel reserve breached")
+		}
+		return nil
+	}
+
+	var dispatched []lnwire.ShortChannelID
+	ctx.router.cfg.Payer.(*mockPaymentAttemptDispatcher).setPaymentResult(
+		func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+			dispatched = append(dispatched, firstHop)
+			return preImage, nil
+		})
+
+	_, rt, err := ctx.router.SendPayment(&payment)
+	if err != nil {
+		t.Fatalf("unable to send payment: %v", err)
+	}
+
+	if len(checked) != 2 || checked[0] != roasbeefLuoji {
+		t.Fatalf("expected direct channel to be checked first, "+
+			"got %v", checked)
+	}
+
+	// Only the route that satisfies our policy should have been
+	// dispatched.
+	if len(dispatched) != 1 || dispatched[0] == roasbeefLuoji {
+		t.Fatalf("unexpected dispatched attempts: %v", dispatched)
+	}
+	if rt.Hops[0].PubKeyBytes != ctx.aliases["satoshi"] {
+		t.Fatalf("route should go through satoshi as first hop, "+
+			"instead passes through: %v",
+			getAliasFromPubKey(rt.Hops[0].PubKeyBytes,
+				ctx.aliases))
+	}
+
+	// The self-inflicted failure must not end up in mission control.
+	mc := ctx.router.cfg.MissionControl.(*MissionControl)
+	for _, node := range mc.GetHistorySnapshot().Nodes {
+		if node.LastFail != nil || len(node.Channels) != 0 {
+			t.Fatalf("unexpected mission control history: %v",
+				node)
+		}
+	}
+}
+
+// TestSendPaymentTraceID asserts that payments are assigned a trace id, which
+// is stored in their creation info, and that a trace id supplied by the caller
+// is kept.
+func TestSendPaymentTraceID(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(startingBlockHeight, basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	init := make(chan initArgs, 1)
+	ctx.router.cfg.Control.(*mockControlTower).init = init
+
+	var preImage [32]byte
+	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
+	ctx.router.cfg.Payer.(*mockPaymentAttemptDispatcher).setPaymentResult(
+		func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+			return preImage, nil
+		})
+
+	sendPayment := func(hashByte byte, traceID TraceID) TraceID {
+		t.Helper()
+
+		preImage[0] = hashByte
+		payHash := sha256.Sum256(preImage[:])
+		payment := LightningPayment{
+			Target:      ctx.aliases["luoji"],
+			Amount:      lnwire.NewMSatFromSatoshis(1000),
+			FeeLimit:    noFeeLimit,
+			PaymentHash: payHash,
+			TraceID:     traceID,
+		}
+		if _, _, err := ctx.router.SendPayment(&payment); err != nil {
+			t.Fatalf("unable to send payment: %v", err)
+		}
+
+		args := <-init
+		if TraceID(args.c.TraceID) != payment.TraceID {
+			t.Fatalf("expected trace id %v in creation info, got "+
+				"%v", payment.TraceID, TraceID(args.c.TraceID))
+		}
+
+		return payment.TraceID
+	}
+
+	if sendPayment(1, 0) == 0 {
+		t.Fatalf("expected trace id to be assigned")
+	}
+	if traceID := sendPayment(2, 0xabcd); traceID != 0xabcd {
+		t.Fatalf("expected trace id %v, got %v", TraceID(0xabcd),
+			traceID)
+	}
+}
+
+// TestSendPaymentInvalidPreimage asserts that a payment that is settled with a
+// preimage that doesn't match the payment hash isn't marked as succeeded.
+func TestSendPaymentInvalidPreimage(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	control := ctx.router.cfg.Control.(*mockControlTower)
+	control.success = make(chan successArgs, 1)
+	control.fail = make(chan failArgs, 1)
+
+	var bogusPreImage [32]byte
+	copy(bogusPreImage[:], bytes.Repeat([]byte{9}, 32))
+	ctx.router.cfg.Payer.(*mockPaymentAttemptDispatcher).setPaymentResult(
+		func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+			return bogusPreImage, nil
+		})
+
+	var payHash [32]byte
+	payment := LightningPayment{
+		Target:      ctx.aliases["luoji"],
+		Amount:      lnwire.NewMSatFromSatoshis(1000),
+		FeeLimit:    noFeeLimit,
+		PaymentHash: payHash,
+	}
+
+	_, _, err = ctx.router.SendPayment(&payment)
+	if !IsError(err, ErrInvalidPreimage) {
+		t.Fatalf("expected ErrInvalidPreimage, got %v", err)
+	}
+
+	select {
+	case <-control.success:
+		t.Fatalf("payment marked as succeeded")
+	default:
+	}
+
+	select {
+	case args := <-control.fail:
+		if args.reason != channeldb.FailureReasonInvalidPreimage {
+			t.Fatalf("expected failure reason %v, got %v",
+				channeldb.FailureReasonInvalidPreimage,
+				args.reason)
+		}
+	default:
+		t.Fatalf("payment not marked as failed")
+	}
+}
+
 // TestSendPaymentErrorPathPruning tests that the send of candidate routes
 // properly gets pruned in response to ForwardingError response from the
 // underlying SendToSwitch function.
@@ -764,7 +1003,9 @@ func TestSendPaymentErrorPathPruning(t *testing.T) {
 
 	// Craft a LightningPayment struct that'll send a payment from roasbeef
 	// to luo ji for 1000 satoshis, with a maximum of 1000 satoshis in fees.
-	var payHash [32]byte
+	var preImage [32]byte
+	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
+	payHash := sha256.Sum256(preImage[:])
 	paymentAmt := lnwire.NewMSatFromSatoshis(1000)
 	payment := LightningPayment{
 		Target:      ctx.aliases["luoji"],
@@ -773,9 +1014,6 @@ func TestSendPaymentErrorPathPruning(t *testing.T) {
 		PaymentHash: payHash,
 	}
 
-	var preImage [32]byte
-	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
-
 	sourceNode, err := ctx.graph.SourceNode()
 	if err != nil {
 		t.Fatalf("unable to fetch source node: %v", err)
@@ -862,6 +1100,19 @@ func TestSendPaymentErrorPathPruning(t *testing.T) {
 			return preImage, nil
 		})
 
+	// As the failure is reported by our own switch, the router should
+	// signal that the local channel is unavailable.
+	var (
+		unavailableChan lnwire.ShortChannelID
+		unavailablePeer route.Vertex
+	)
+	ctx.router.cfg.LocalChannelUnavailable = func(
+		chanID lnwire.ShortChannelID, peer route.Vertex) {
+
+		unavailableChan = chanID
+		unavailablePeer = peer
+	}
+
 	// This shouldn't return an error, as we'll make a payment attempt via
 	// the satoshi channel based on the assumption that there might be an
 	// intermittent issue with the roasbeef <-> lioji channel.
@@ -870,6 +1121,15 @@ func TestSendPaymentErrorPathPruning(t *testing.T) {
 		t.Fatalf("unable send payment: %v", err)
 	}
 
+	if unavailableChan != roasbeefLuoji {
+		t.Fatalf("expected channel %v to be unavailable, got %v",
+			roasbeefLuoji, unavailableChan)
+	}
+	if unavailablePeer != ctx.aliases["luoji"] {
+		t.Fatalf("expected luoji to be signaled, got %v",
+			getAliasFromPubKey(unavailablePeer, ctx.aliases))
+	}
+
 	// This path should go: roasbeef -> satoshi -> luoji
 	if len(rt.Hops) != 2 {
 		t.Fatalf("incorrect route length: expected %v got %v", 2,
@@ -2943,8 +3203,6 @@ func TestRouterPaymentStateMachine(t *testing.T) {
 			t.Fatalf("unable to fetch source node pub: %v", err)
 		}
 
-		copy(preImage[:], bytes.Repeat([]byte{9}, 32))
-
 		router.cfg.MissionControl = &mockPaymentSessionSource{
 			routes: test.routes,
 		}
@@ -3259,3 +3517,277 @@ func TestSendToRouteStructuredError(t *testing.T) {
 		t.Fatalf("initPayment not called")
 	}
 }
+
+// TestSendToRouteFeeLimit asserts that SendToRoute rejects a route whose fees
+// exceed the fee limit before initiating the payment.
+func TestSendToRouteFeeLimit(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	init := make(chan initArgs, 1)
+	ctx.router.cfg.Control.(*mockControlTower).init = init
+
+	// Setup a route from roasbeef to sophon over phamnuwen that pays a
+	// fee of 100 msat.
+	const payAmt = lnwire.MilliSatoshi(10000)
+	hops := []*route.Hop{
+		{
+			ChannelID:    999991,
+			PubKeyBytes:  ctx.aliases["phamnuwen"],
+			AmtToForward: payAmt,
+		},
+		{
+			ChannelID:    99999,
+			PubKeyBytes:  ctx.aliases["sophon"],
+			AmtToForward: payAmt,
+		},
+	}
+	rt, err := route.NewRouteFromHops(
+		payAmt+100, 100, ctx.aliases["roasbeef"], hops,
+	)
+	if err != nil {
+		t.Fatalf("unable to create route: %v", err)
+	}
+
+	var preImage [32]byte
+	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
+	ctx.router.cfg.Payer.(*mockPaymentAttemptDispatcher).setPaymentResult(
+		func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+			return preImage, nil
+		},
+	)
+
+	payment := sha256.Sum256(preImage[:])
+
+	// A fee limit below the fee of the route should fail the payment
+	// without initiating it.
+	_, err = ctx.router.SendToRoute(payment, rt, 99)
+	if !IsError(err, ErrFeeLimitExceeded) {
+		t.Fatalf("expected ErrFeeLimitExceeded, got %v", err)
+	}
+
+	select {
+	case <-init:
+		t.Fatalf("payment initiated despite exceeded fee limit")
+	default:
+	}
+
+	// With a sufficient fee limit, the payment should succeed.
+	preimage, err := ctx.router.SendToRoute(payment, rt, 100)
+	if err != nil {
+		t.Fatalf("unable to send payment: %v", err)
+	}
+	if preimage != preImage {
+		t.Fatalf("unexpected preimage")
+	}
+}
+
+// blockingChain is a mock chain that blocks the second call to GetBestBlock,
+// which is made by the graph sync of the router, until it is released.
+type blockingChain struct {
+	*mockChain
+
+	calls   int32
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingChain) GetBestBlock() (*chainhash.Hash, int32, error) {
+	if atomic.AddInt32(&b.calls, 1) == 2 {
+		close(b.blocked)
+		<-b.release
+	}
+
+	return b.mockChain.GetBestBlock()
+}
+
+// startDeferredSyncRouter replaces the router of the test context with one
+// that defers the graph sync, and returns it once the sync has started. The
+// sync is held up until the release channel of the returned chain is closed.
+// The returned function releases the sync if needed, and stops the router.
+func startDeferredSyncRouter(t *testing.T, ctx *testCtx) (*ChannelRouter,
+	*blockingChain, func()) {
+
+	if err := ctx.router.Stop(); err != nil {
+		t.Fatalf("unable to stop router: %v", err)
+	}
+
+	chain := &blockingChain{
+		mockChain: ctx.chain,
+		blocked:   make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+
+	cfg := *ctx.router.cfg
+	cfg.Chain = chain
+	cfg.ChainView = newMockChainView(ctx.chain)
+	cfg.DeferGraphSync = true
+
+	router, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	if err := router.Start(); err != nil {
+		t.Fatalf("unable to start router: %v", err)
+	}
+
+	// Make sure the sync is released before the router is stopped.
+	stop := func() {
+		select {
+		case <-chain.release:
+		default:
+			close(chain.release)
+		}
+
+		router.Stop()
+	}
+
+	select {
+	case <-chain.blocked:
+	case <-time.After(5 * time.Second):
+		stop()
+		t.Fatalf("graph sync not started")
+	}
+
+	return router, chain, stop
+}
+
+// TestDeferGraphSync asserts that a router that defers the graph sync at
+// startup is able to find routes and process network updates while the sync
+// is still in progress.
+func TestDeferGraphSync(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	router, chain, stop := startDeferredSyncRouter(t, ctx)
+	defer stop()
+
+	// Routes should be found using the existing graph.
+	restrictions := &RestrictParams{
+		FeeLimit:          lnwire.NewMSatFromSatoshis(10),
+		ProbabilitySource: noProbabilitySource,
+	}
+	_, err = router.FindRoute(
+		router.selfNode.PubKeyBytes, ctx.aliases["sophon"],
+		lnwire.NewMSatFromSatoshis(100), restrictions,
+		zpay32.DefaultFinalCLTVDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to find route: %v", err)
+	}
+
+	// Network updates should be processed without waiting for the sync.
+	node, err := createTestNode()
+	if err != nil {
+		t.Fatalf("unable to create node: %v", err)
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- router.AddNode(node)
+	}()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatalf("unable to add node: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("network update blocked by graph sync")
+	}
+
+	select {
+	case <-router.GraphSynced():
+		t.Fatalf("graph sync completed before release")
+	default:
+	}
+
+	close(chain.release)
+
+	select {
+	case <-router.GraphSynced():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("graph sync didn't complete")
+	}
+}
+
+// TestDeferGraphSyncPruneNodes asserts that a router that defers the graph
+// sync only prunes the unconnected nodes once the sync has completed, and
+// waits for the network updates that are being applied at that time.
+func TestDeferGraphSyncPruneNodes(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	router, chain, stop := startDeferredSyncRouter(t, ctx)
+	defer stop()
+
+	// Add a node without any channels while the sync is in progress.
+	node, err := createTestNode()
+	if err != nil {
+		t.Fatalf("unable to create node: %v", err)
+	}
+	if err := router.AddNode(node); err != nil {
+		t.Fatalf("unable to add node: %v", err)
+	}
+
+	assertNodeExists := func(pub route.Vertex, expected bool) {
+		t.Helper()
+
+		_, exists, err := ctx.graph.HasLightningNode(pub)
+		if err != nil {
+			t.Fatalf("unable to query graph: %v", err)
+		}
+		if exists != expected {
+			t.Fatalf("expected node %x to exist: %v", pub[:],
+				expected)
+		}
+	}
+
+	// Hold the graph as if a network update is being applied to it when
+	// the sync completes.
+	router.graphUpdateMtx.RLock()
+	close(chain.release)
+
+	select {
+	case <-router.GraphSynced():
+		t.Fatalf("graph synced while an update was being applied")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assertNodeExists(node.PubKeyBytes, true)
+
+	router.graphUpdateMtx.RUnlock()
+
+	select {
+	case <-router.GraphSynced():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("graph sync didn't complete")
+	}
+
+	// Once the update has been applied, the unconnected node is pruned,
+	// while the nodes with channels are kept.
+	assertNodeExists(node.PubKeyBytes, false)
+	assertNodeExists(ctx.aliases["sophon"], true)
+}
@@ -0,0 +1,133 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// ErrUnknownSourceNode is returned when a source node is removed that isn't
+// registered with the router.
+var ErrUnknownSourceNode = errors.New("unknown source node")
+
+// SourceNode describes an additional node on whose behalf the router finds
+// routes, next to our own node. This allows a single router to serve multiple
+// tenants in hosted or multi-account deployments, each with their own set of
+// channels.
+type SourceNode struct {
+	// PubKey is the public key of the node. The node and its channels
+	// must be part of the graph.
+	PubKey route.Vertex
+
+	// QueryBandwidth returns the bandwidth that is currently available
+	// to the node in the given channel of the node. Like the bandwidth
+	// query of our own node, it allows path finding to skip channels
+	// that are unable to carry a payment.
+	QueryBandwidth func(*channeldb.ChannelEdgeInfo) lnwire.MilliSatoshi
+}
+
+// AddSourceNode registers a node on whose behalf routes are found. Once
+// registered, routes that are requested with the node as the source take the
+// bandwidth of its channels into account. Registering a node again replaces
+// its previous registration.
+func (r *ChannelRouter) AddSourceNode(node *SourceNode) error {
+	if node.PubKey == r.selfNode.PubKeyBytes {
+		return errors.New("our own node can't be added as source node")
+	}
+	if node.QueryBandwidth == nil {
+		return errors.New("no bandwidth query given for source node")
+	}
+
+	_, exists, err := r.cfg.Graph.HasLightningNode(node.PubKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("source node %x not found in graph",
+			node.PubKey[:])
+	}
+
+	r.sourceNodesMtx.Lock()
+	r.sourceNodes[node.PubKey] = node
+	r.sourceNodesMtx.Unlock()
+
+	log.Infof("Added source node %x", node.PubKey[:])
+
+	return nil
+}
+
+// RemoveSourceNode unregisters a node that was added with AddSourceNode.
+func (r *ChannelRouter) RemoveSourceNode(pubKey route.Vertex) error {
+	r.sourceNodesMtx.Lock()
+	defer r.sourceNodesMtx.Unlock()
+
+	if _, ok := r.sourceNodes[pubKey]; !ok {
+		return ErrUnknownSourceNode
+	}
+	delete(r.sourceNodes, pubKey)
+
+	log.Infof("Removed source node %x", pubKey[:])
+
+	return nil
+}
+
+// SourceNodes returns the public keys of the nodes that were added with
+// AddSourceNode.
+func (r *ChannelRouter) SourceNodes() []route.Vertex {
+	r.sourceNodesMtx.RLock()
+	defer r.sourceNodesMtx.RUnlock()
+
+	nodes := make([]route.Vertex, 0, len(r.sourceNodes))
+	for pubKey := range r.sourceNodes {
+		nodes = append(nodes, pubKey)
+	}
+
+	return nodes
+}
+
+// sourceBandwidthHints returns the bandwidth hints to use when finding a path
+// from the given source node. The hints of our own channels always apply, as
+// paths from other source nodes may traverse our node. If the source node was
+// added with AddSourceNode, the hints of its channels are added, taking
+// precedence for channels that it shares with our node.
+func (r *ChannelRouter) sourceBandwidthHints(
+	source route.Vertex) (map[uint64]lnwire.MilliSatoshi, error) {
+
+	bandwidthHints, err := generateBandwidthHints(
+		r.selfNode, r.cfg.QueryBandwidth,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if source == r.selfNode.PubKeyBytes {
+		return bandwidthHints, nil
+	}
+
+	r.sourceNodesMtx.RLock()
+	node, ok := r.sourceNodes[source]
+	r.sourceNodesMtx.RUnlock()
+	if !ok {
+		return bandwidthHints, nil
+	}
+
+	sourceNode, err := r.FetchLightningNode(source)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceHints, err := generateBandwidthHints(
+		sourceNode, node.QueryBandwidth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for chanID, bandwidth := range sourceHints {
+		bandwidthHints[chanID] = bandwidth
+	}
+
+	return bandwidthHints, nil
+}
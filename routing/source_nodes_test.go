@@ -0,0 +1,101 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// TestSourceNodeBandwidth asserts that routes from an additional source node
+// take the bandwidth of its channels into account.
+func TestSourceNodeBandwidth(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	source := route.Vertex(ctx.aliases["songoku"])
+	target := route.Vertex(ctx.aliases["sophon"])
+	amt := lnwire.NewMSatFromSatoshis(1000)
+
+	findRoute := func() *route.Route {
+		rt, err := ctx.router.FindRoute(
+			source, target, amt, noRestrictions,
+			zpay32.DefaultFinalCLTVDelta,
+		)
+		if err != nil {
+			t.Fatalf("unable to find route: %v", err)
+		}
+		return rt
+	}
+
+	// Without a registration, the direct channel to sophon is taken.
+	if rt := findRoute(); rt.Hops[0].ChannelID != 3495345 {
+		t.Fatalf("expected direct channel, got %v",
+			rt.Hops[0].ChannelID)
+	}
+
+	// Register songoku with no bandwidth in its direct channel to sophon.
+	err = ctx.router.AddSourceNode(&SourceNode{
+		PubKey: source,
+		QueryBandwidth: func(
+			e *channeldb.ChannelEdgeInfo) lnwire.MilliSatoshi {
+
+			if e.ChannelID == 3495345 {
+				return 0
+			}
+			return lnwire.NewMSatFromSatoshis(e.Capacity)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to add source node: %v", err)
+	}
+
+	sourceNodes := ctx.router.SourceNodes()
+	if len(sourceNodes) != 1 || sourceNodes[0] != source {
+		t.Fatalf("unexpected source nodes: %v", sourceNodes)
+	}
+
+	// The route must now go through roasbeef instead.
+	rt := findRoute()
+	if rt.Hops[0].ChannelID != 12345 {
+		t.Fatalf("expected route via roasbeef, got %v",
+			rt.Hops[0].ChannelID)
+	}
+
+	// After removing the node, the direct channel is taken again.
+	if err := ctx.router.RemoveSourceNode(source); err != nil {
+		t.Fatalf("unable to remove source node: %v", err)
+	}
+	if rt := findRoute(); rt.Hops[0].ChannelID != 3495345 {
+		t.Fatalf("expected direct channel, got %v",
+			rt.Hops[0].ChannelID)
+	}
+
+	err = ctx.router.RemoveSourceNode(source)
+	if err != ErrUnknownSourceNode {
+		t.Fatalf("expected ErrUnknownSourceNode, got %v", err)
+	}
+
+	// Our own node can't be registered.
+	err = ctx.router.AddSourceNode(&SourceNode{
+		PubKey: ctx.router.selfNode.PubKeyBytes,
+		QueryBandwidth: func(
+			*channeldb.ChannelEdgeInfo) lnwire.MilliSatoshi {
+
+			return 0
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected own node to be rejected")
+	}
+}
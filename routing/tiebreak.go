@@ -0,0 +1,140 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+)
+
+// TieBreaker defines how path finding chooses between candidate paths of
+// equal cost.
+type TieBreaker uint8
+
+const (
+	// TieBreakNone keeps the first candidate path that is found. Which
+	// one that is depends on the order in which the graph is traversed.
+	TieBreakNone TieBreaker = iota
+
+	// TieBreakProbability prefers the candidate path with the highest
+	// success probability.
+	TieBreakProbability
+
+	// TieBreakHops prefers the candidate path with the fewest hops.
+	TieBreakHops
+
+	// TieBreakCapacity prefers the candidate path with the largest
+	// minimum channel capacity. Channels of unknown capacity, such as
+	// those of hop hints, don't limit the minimum.
+	TieBreakCapacity
+
+	// TieBreakRandom picks one of the candidate paths uniformly at random,
+	// so that equal cost payments don't always take the same path.
+	TieBreakRandom
+)
+
+// String returns the name of the tie breaker, as accepted by ParseTieBreaker.
+func (b TieBreaker) String() string {
+	switch b {
+	case TieBreakNone:
+		return "none"
+
+	case TieBreakProbability:
+		return "probability"
+
+	case TieBreakHops:
+		return "hops"
+
+	case TieBreakCapacity:
+		return "capacity"
+
+	case TieBreakRandom:
+		return "random"
+
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTieBreaker returns the tie breaker with the given name. An empty name
+// selects TieBreakNone.
+func ParseTieBreaker(name string) (TieBreaker, error) {
+	if name == "" {
+		return TieBreakNone, nil
+	}
+
+	for b := TieBreakNone; b <= TieBreakRandom; b++ {
+		if b.String() == name {
+			return b, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown tie breaker %q", name)
+}
+
+// prefers returns whether the candidate path from a node should replace the
+// current best path of equal cost. The edges are the first edges of both
+// paths. Unless paths are chosen randomly, paths that are equal by the
+// criterion of the tie breaker are ordered by the channel id of their first
+// edge, so that the result doesn't depend on the traversal order.
+//
+// The ties field of current must hold the number of equal cost candidates
+// that were seen before this one.
+func (b TieBreaker) prefers(candidate, current *nodeWithDist,
+	candidateEdge, currentEdge *channeldb.ChannelEdgePolicy) bool {
+
+	switch b {
+	case TieBreakProbability:
+		if candidate.probability != current.probability {
+			return candidate.probability > current.probability
+		}
+
+	case TieBreakHops:
+		if candidate.hops != current.hops {
+			return candidate.hops < current.hops
+		}
+
+	case TieBreakCapacity:
+		candidateCapacity := effectiveCapacity(candidate.minCapacity)
+		currentCapacity := effectiveCapacity(current.minCapacity)
+		if candidateCapacity != currentCapacity {
+			return candidateCapacity > currentCapacity
+		}
+
+	// Replacing the current path with a probability of 1/(n+1) for the
+	// n-th tie selects each of the candidates with equal probability.
+	case TieBreakRandom:
+		return rand.Intn(current.ties+1) == 0
+
+	default:
+		return false
+	}
+
+	return candidateEdge.ChannelID < currentEdge.ChannelID
+}
+
+// minCapacity returns the minimum capacity of a path, given the minimum of the
+// rest of the path and the capacity of the channel that is added to it. A
+// capacity of zero is unknown and doesn't limit the minimum.
+func minCapacity(pathCapacity, capacity btcutil.Amount) btcutil.Amount {
+	if capacity == 0 {
+		return pathCapacity
+	}
+	if pathCapacity == 0 || capacity < pathCapacity {
+		return capacity
+	}
+
+	return pathCapacity
+}
+
+// effectiveCapacity maps the unknown capacity of zero to the largest possible
+// capacity, so that capacities can be compared.
+func effectiveCapacity(capacity btcutil.Amount) btcutil.Amount {
+	if capacity == 0 {
+		return math.MaxInt64
+	}
+
+	return capacity
+}
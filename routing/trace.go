@@ -0,0 +1,35 @@
+package routing
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// TraceID is a correlation id that is attached to all log lines the router
+// writes while working on a payment, so that they can be joined. It is stored
+// in the creation info of the payment.
+type TraceID uint64
+
+// String returns the trace id as a fixed width hex string.
+func (t TraceID) String() string {
+	return fmt.Sprintf("%016x", uint64(t))
+}
+
+// newTraceID returns a random, non-zero trace id.
+func newTraceID() TraceID {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// Trace ids don't need to be unpredictable, so falling back to
+		// the current time is fine.
+		binary.BigEndian.PutUint64(b[:], uint64(time.Now().UnixNano()))
+	}
+
+	id := TraceID(binary.BigEndian.Uint64(b[:]))
+	if id == 0 {
+		id = 1
+	}
+
+	return id
+}
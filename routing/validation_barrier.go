@@ -2,7 +2,9 @@ package routing
 
 import (
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -14,6 +16,51 @@ import (
 // fulfilled.
 var ErrVBarrierShuttingDown = errors.New("validation barrier shutting down")
 
+const (
+	// DefaultVBarrierStallThreshold is the default duration after which a
+	// job that is still waiting on its dependencies is reported as being
+	// stalled.
+	DefaultVBarrierStallThreshold = time.Minute
+
+	// DefaultVBarrierReleaseTimeout is the default duration after which a
+	// job that is still waiting on its dependencies forcibly releases the
+	// dependency chain, unblocking itself and all other dependants.
+	DefaultVBarrierReleaseTimeout = 10 * time.Minute
+)
+
+// PendingDependency describes a ChannelAnnouncement validation job that other
+// jobs depend on, and which hasn't completed yet.
+type PendingDependency struct {
+	// ChannelID is the short channel ID of the announced channel.
+	ChannelID lnwire.ShortChannelID
+
+	// Node1 and Node2 are the nodes of the announced channel. Node
+	// announcements for either node depend on the announcement.
+	Node1, Node2 route.Vertex
+
+	// Age is the time that has passed since the job was started.
+	Age time.Duration
+
+	// Waiters is the number of jobs currently blocked on this job.
+	Waiters int
+}
+
+// String returns a human readable description of the dependency.
+func (p *PendingDependency) String() string {
+	return fmt.Sprintf("chan_ann(chan_id=%v, node1=%x, node2=%x, age=%v, "+
+		"waiters=%v)", p.ChannelID, p.Node1[:], p.Node2[:], p.Age,
+		p.Waiters)
+}
+
+// pendingAnn holds the metadata of a pending ChannelAnnouncement validation
+// job, which is used to describe dependency chains.
+type pendingAnn struct {
+	shortID      lnwire.ShortChannelID
+	node1, node2 route.Vertex
+	started      time.Time
+	waiters      int
+}
+
 // ValidationBarrier is a barrier used to ensure proper validation order while
 // concurrently validating new announcements for channel edges, and the
 // attributes of channel edges.  It uses this set of maps (protected by this
@@ -45,6 +92,21 @@ type ValidationBarrier struct {
 	// ChannelAnnouncement before proceeding.
 	nodeAnnDependencies map[route.Vertex]chan struct{}
 
+	// pendingAnns maps the finish signal of each pending
+	// ChannelAnnouncement job to its metadata. It is used to report stalled
+	// dependency chains.
+	pendingAnns map[chan struct{}]*pendingAnn
+
+	// stallThreshold is the duration after which a job waiting on its
+	// dependencies is reported as stalled. A value of zero disables stall
+	// detection.
+	stallThreshold time.Duration
+
+	// releaseTimeout is the duration after which a job waiting on its
+	// dependencies forcibly releases the dependency chain. A value of
+	// zero disables force-releasing.
+	releaseTimeout time.Duration
+
 	quit chan struct{}
 	sync.Mutex
 }
@@ -59,6 +121,9 @@ func NewValidationBarrier(numActiveReqs int,
 		chanAnnFinSignal:     make(map[lnwire.ShortChannelID]chan struct{}),
 		chanEdgeDependencies: make(map[lnwire.ShortChannelID]chan struct{}),
 		nodeAnnDependencies:  make(map[route.Vertex]chan struct{}),
+		pendingAnns:          make(map[chan struct{}]*pendingAnn),
+		stallThreshold:       DefaultVBarrierStallThreshold,
+		releaseTimeout:       DefaultVBarrierReleaseTimeout,
 		quit:                 quitChan,
 	}
 
@@ -72,6 +137,20 @@ func NewValidationBarrier(numActiveReqs int,
 	return v
 }
 
+// SetStallTimeouts overrides the duration after which a job blocked on its
+// dependencies is reported as stalled, and the duration after which the
+// blocking dependency chain is forcibly released. A zero value disables the
+// respective behavior. This should be called before any jobs are added.
+func (v *ValidationBarrier) SetStallTimeouts(stallThreshold,
+	releaseTimeout time.Duration) {
+
+	v.Lock()
+	defer v.Unlock()
+
+	v.stallThreshold = stallThreshold
+	v.releaseTimeout = releaseTimeout
+}
+
 // InitJobDependencies will wait for a new job slot to become open, and then
 // sets up any dependent signals/trigger for the new job
 func (v *ValidationBarrier) InitJobDependencies(job interface{}) {
@@ -113,6 +192,13 @@ func (v *ValidationBarrier) InitJobDependencies(job interface{}) {
 
 			v.nodeAnnDependencies[route.Vertex(msg.NodeID1)] = annFinCond
 			v.nodeAnnDependencies[route.Vertex(msg.NodeID2)] = annFinCond
+
+			v.pendingAnns[annFinCond] = &pendingAnn{
+				shortID: msg.ShortChannelID,
+				node1:   route.Vertex(msg.NodeID1),
+				node2:   route.Vertex(msg.NodeID2),
+				started: time.Now(),
+			}
 		}
 	case *channeldb.ChannelEdgeInfo:
 
@@ -125,6 +211,13 @@ func (v *ValidationBarrier) InitJobDependencies(job interface{}) {
 
 			v.nodeAnnDependencies[route.Vertex(msg.NodeKey1Bytes)] = annFinCond
 			v.nodeAnnDependencies[route.Vertex(msg.NodeKey2Bytes)] = annFinCond
+
+			v.pendingAnns[annFinCond] = &pendingAnn{
+				shortID: shortID,
+				node1:   route.Vertex(msg.NodeKey1Bytes),
+				node2:   route.Vertex(msg.NodeKey2Bytes),
+				started: time.Now(),
+			}
 		}
 
 	// These other types don't have any dependants, so no further
@@ -196,20 +289,152 @@ func (v *ValidationBarrier) WaitForDependants(job interface{}) error {
 		v.Unlock()
 		return nil
 	}
+
+	// If we don't have an active job to wait on, we can return directly.
+	if !ok {
+		v.Unlock()
+		return nil
+	}
+
+	ann, annPending := v.pendingAnns[signal]
+	if annPending {
+		ann.waiters++
+	}
+	stallThreshold := v.stallThreshold
+	releaseTimeout := v.releaseTimeout
 	v.Unlock()
 
-	// If we do have an active job, then we'll wait until either the signal
-	// is closed, or the set of jobs exits.
-	if ok {
+	defer func() {
+		v.Lock()
+		if annPending {
+			ann.waiters--
+		}
+		v.Unlock()
+	}()
+
+	// Set up the timers that detect stalled dependency chains, if
+	// enabled.
+	var stallChan, releaseChan <-chan time.Time
+	if stallThreshold > 0 {
+		stallTimer := time.NewTimer(stallThreshold)
+		defer stallTimer.Stop()
+		stallChan = stallTimer.C
+	}
+	if releaseTimeout > 0 {
+		releaseTimer := time.NewTimer(releaseTimeout)
+		defer releaseTimer.Stop()
+		releaseChan = releaseTimer.C
+	}
+
+	// We'll wait until either the signal is closed, or the set of jobs
+	// exits.
+	for {
 		select {
 		case <-v.quit:
 			return ErrVBarrierShuttingDown
+
 		case <-signal:
 			return nil
+
+		case <-stallChan:
+			log.Warnf("Validation job %v blocked for more than %v "+
+				"on %v", describeJob(job), stallThreshold,
+				v.describeSignal(signal))
+			stallChan = nil
+
+		case <-releaseChan:
+			log.Errorf("Validation job %v blocked for more than %v "+
+				"on %v, force-releasing dependency chain",
+				describeJob(job), releaseTimeout,
+				v.describeSignal(signal))
+			v.forceRelease(signal)
+			return nil
 		}
 	}
+}
+
+// describeSignal returns a description of the pending job that will close the
+// given signal.
+func (v *ValidationBarrier) describeSignal(signal chan struct{}) string {
+	v.Lock()
+	defer v.Unlock()
+
+	ann, ok := v.pendingAnns[signal]
+	if !ok {
+		return "unknown dependency"
+	}
+
+	dep := ann.toDependency(time.Now())
+	return dep.String()
+}
+
+// forceRelease closes the given signal, unblocking all jobs that depend on it.
+// If the signal has already been closed, this is a noop.
+func (v *ValidationBarrier) forceRelease(signal chan struct{}) {
+	v.Lock()
+	defer v.Unlock()
+
+	ann, ok := v.pendingAnns[signal]
+	if !ok {
+		return
+	}
+
+	// Only close the signal if it is still the active one for this
+	// channel, otherwise it was already closed by SignalDependants.
+	if finSignal, ok := v.chanAnnFinSignal[ann.shortID]; ok &&
+		finSignal == signal {
 
-	return nil
+		close(signal)
+		delete(v.chanAnnFinSignal, ann.shortID)
+	}
+	delete(v.pendingAnns, signal)
+}
+
+// toDependency converts the pending announcement into its exported
+// representation.
+func (p *pendingAnn) toDependency(now time.Time) PendingDependency {
+	return PendingDependency{
+		ChannelID: p.shortID,
+		Node1:     p.node1,
+		Node2:     p.node2,
+		Age:       now.Sub(p.started),
+		Waiters:   p.waiters,
+	}
+}
+
+// PendingDependencies returns all ChannelAnnouncement jobs that haven't
+// completed yet, along with the number of jobs that are blocked on each of
+// them. This can be used to visualize the current dependency graph.
+func (v *ValidationBarrier) PendingDependencies() []PendingDependency {
+	v.Lock()
+	defer v.Unlock()
+
+	now := time.Now()
+	deps := make([]PendingDependency, 0, len(v.pendingAnns))
+	for _, ann := range v.pendingAnns {
+		deps = append(deps, ann.toDependency(now))
+	}
+
+	return deps
+}
+
+// describeJob returns a short human readable description of a validation job.
+func describeJob(job interface{}) string {
+	switch msg := job.(type) {
+	case *channeldb.ChannelEdgePolicy:
+		return fmt.Sprintf("chan_update(chan_id=%v, flags=%v)",
+			lnwire.NewShortChanIDFromInt(msg.ChannelID),
+			msg.ChannelFlags)
+	case *lnwire.ChannelUpdate:
+		return fmt.Sprintf("chan_update(chan_id=%v, flags=%v)",
+			msg.ShortChannelID, msg.ChannelFlags)
+	case *channeldb.LightningNode:
+		return fmt.Sprintf("node_ann(node=%x)", msg.PubKeyBytes)
+	case *lnwire.NodeAnnouncement:
+		return fmt.Sprintf("node_ann(node=%x)", msg.NodeID)
+	default:
+		return fmt.Sprintf("%T", job)
+	}
 }
 
 // SignalDependants will signal any jobs that are dependent on this job that
@@ -230,12 +455,14 @@ func (v *ValidationBarrier) SignalDependants(job interface{}) {
 		if ok {
 			close(finSignal)
 			delete(v.chanAnnFinSignal, shortID)
+			delete(v.pendingAnns, finSignal)
 		}
 	case *lnwire.ChannelAnnouncement:
 		finSignal, ok := v.chanAnnFinSignal[msg.ShortChannelID]
 		if ok {
 			close(finSignal)
 			delete(v.chanAnnFinSignal, msg.ShortChannelID)
+			delete(v.pendingAnns, finSignal)
 		}
 
 		delete(v.chanEdgeDependencies, msg.ShortChannelID)
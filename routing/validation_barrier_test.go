@@ -143,6 +143,72 @@ func TestValidationBarrierQuit(t *testing.T) {
 	}
 }
 
+// TestValidationBarrierForceRelease checks that a job blocked on a stalled
+// dependency is reported, and that the dependency chain is released once the
+// release timeout expires.
+func TestValidationBarrierForceRelease(t *testing.T) {
+	const timeout = time.Second
+
+	quit := make(chan struct{})
+	defer close(quit)
+
+	barrier := routing.NewValidationBarrier(4, quit)
+	barrier.SetStallTimeouts(10*time.Millisecond, 100*time.Millisecond)
+
+	ann := &lnwire.ChannelAnnouncement{
+		ShortChannelID: lnwire.NewShortChanIDFromInt(1),
+		NodeID1:        nodeIDFromInt(1),
+		NodeID2:        nodeIDFromInt(2),
+	}
+	barrier.InitJobDependencies(ann)
+
+	// Queue two dependants of the announcement, which is never signaled.
+	nodeAnn := &lnwire.NodeAnnouncement{NodeID: nodeIDFromInt(1)}
+	chanUpd := &lnwire.ChannelUpdate{
+		ShortChannelID: ann.ShortChannelID,
+	}
+	barrier.InitJobDependencies(nodeAnn)
+	barrier.InitJobDependencies(chanUpd)
+
+	jobErrs := make(chan error, 2)
+	go func() {
+		jobErrs <- barrier.WaitForDependants(nodeAnn)
+	}()
+	go func() {
+		jobErrs <- barrier.WaitForDependants(chanUpd)
+	}()
+
+	// While the jobs are blocked, the pending announcement should be
+	// reported along with both of its waiters.
+	time.Sleep(20 * time.Millisecond)
+	deps := barrier.PendingDependencies()
+	if len(deps) != 1 {
+		t.Fatalf("expected 1 pending dependency, got %v", len(deps))
+	}
+	if deps[0].ChannelID != ann.ShortChannelID || deps[0].Waiters != 2 {
+		t.Fatalf("unexpected pending dependency: %v", deps[0].String())
+	}
+
+	// Once the release timeout expires, both jobs should be unblocked.
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-jobErrs:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case <-time.After(timeout):
+			t.Fatalf("job not released")
+		}
+	}
+
+	if len(barrier.PendingDependencies()) != 0 {
+		t.Fatalf("expected no pending dependencies after release")
+	}
+
+	// Signaling the released announcement afterwards must not panic.
+	barrier.SignalDependants(ann)
+}
+
 // nodeIDFromInt creates a node ID by writing a uint64 to the first 8 bytes.
 func nodeIDFromInt(i uint64) [33]byte {
 	var nodeID [33]byte
@@ -0,0 +1,318 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing"
+)
+
+const (
+	// SignatureHeader is the http header that carries the hex encoded
+	// HMAC-SHA256 of the request body, keyed with the configured secret.
+	SignatureHeader = "X-Lnd-Signature"
+
+	// DefaultBatchSize is the default maximum number of events that are
+	// delivered in a single request.
+	DefaultBatchSize = 100
+
+	// DefaultBatchInterval is the default maximum time that events are
+	// held back before they are delivered.
+	DefaultBatchInterval = 5 * time.Second
+
+	// DefaultMaxRetries is the default number of times a failed delivery
+	// is retried before the batch is dropped.
+	DefaultMaxRetries = 5
+
+	// DefaultRetryBackoff is the default delay before the first retry of a
+	// failed delivery. The delay doubles with every subsequent retry.
+	DefaultRetryBackoff = time.Second
+
+	// defaultRequestTimeout is the timeout of a single delivery attempt if
+	// no http client is configured.
+	defaultRequestTimeout = 30 * time.Second
+)
+
+var (
+	// ErrNoURL is returned when no webhook url is configured.
+	ErrNoURL = errors.New("no webhook url configured")
+
+	// errShuttingDown is returned when a delivery is aborted because the
+	// dispatcher is stopping.
+	errShuttingDown = errors.New("webhook dispatcher shutting down")
+)
+
+// Config houses the parameters of the webhook dispatcher.
+type Config struct {
+	// URL is the endpoint that the events are posted to.
+	URL string
+
+	// Secret, if set, is used to sign the body of every request. The
+	// signature is sent in the SignatureHeader.
+	Secret []byte
+
+	// Filter restricts the events that are delivered. If nil, all events
+	// are delivered.
+	Filter *Filter
+
+	// BatchSize is the maximum number of events that are delivered in a
+	// single request. Zero selects DefaultBatchSize.
+	BatchSize int
+
+	// BatchInterval is the maximum time that events are held back to be
+	// batched with later events. Zero selects DefaultBatchInterval.
+	BatchInterval time.Duration
+
+	// MaxRetries is the number of times a failed delivery is retried
+	// before the batch is dropped. A negative value disables retries.
+	// Zero selects DefaultMaxRetries.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry of a failed
+	// delivery. Zero selects DefaultRetryBackoff.
+	RetryBackoff time.Duration
+
+	// Client is the http client that is used to post the events. If nil,
+	// a client with a default timeout is used.
+	Client *http.Client
+
+	// SubscribeTopology returns a client that receives the topology
+	// changes of the channel graph.
+	SubscribeTopology func() (*routing.TopologyClient, error)
+}
+
+// batch is the body of a webhook request.
+type batch struct {
+	Events []*Event `json:"events"`
+}
+
+// Dispatcher delivers the topology changes of the channel graph to an http
+// endpoint. Events are batched, signed and retried with an exponential
+// backoff, so that external systems can track the graph without holding a
+// streaming connection.
+type Dispatcher struct {
+	started uint32 // To be used atomically.
+	stopped uint32 // To be used atomically.
+
+	cfg    Config
+	filter *eventFilter
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a new webhook dispatcher. Unset parameters of the config are
+// replaced by their defaults.
+func New(cfg Config) (*Dispatcher, error) {
+	if cfg.URL == "" {
+		return nil, ErrNoURL
+	}
+	if cfg.SubscribeTopology == nil {
+		return nil, errors.New("no topology subscription configured")
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchInterval == 0 {
+		cfg.BatchInterval = DefaultBatchInterval
+	}
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = DefaultMaxRetries
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultRequestTimeout}
+	}
+
+	return &Dispatcher{
+		cfg:    cfg,
+		filter: newEventFilter(cfg.Filter),
+		quit:   make(chan struct{}),
+	}, nil
+}
+
+// Start subscribes to topology changes and starts delivering them.
+func (d *Dispatcher) Start() error {
+	if !atomic.CompareAndSwapUint32(&d.started, 0, 1) {
+		return nil
+	}
+
+	log.Infof("Webhook dispatcher starting, delivering to %v", d.cfg.URL)
+
+	client, err := d.cfg.SubscribeTopology()
+	if err != nil {
+		return err
+	}
+
+	d.wg.Add(1)
+	go d.dispatch(client)
+
+	return nil
+}
+
+// Stop stops the delivery of events. Events that are still batched are
+// dropped.
+func (d *Dispatcher) Stop() error {
+	if !atomic.CompareAndSwapUint32(&d.stopped, 0, 1) {
+		return nil
+	}
+
+	log.Info("Webhook dispatcher shutting down")
+
+	close(d.quit)
+	d.wg.Wait()
+
+	return nil
+}
+
+// dispatch is the main loop of the dispatcher. It collects the events of the
+// incoming topology changes and delivers them once the batch is full or the
+// batch interval has expired.
+//
+// NOTE: This MUST be run as a goroutine.
+func (d *Dispatcher) dispatch(client *routing.TopologyClient) {
+	defer d.wg.Done()
+	defer client.Cancel()
+
+	ticker := time.NewTicker(d.cfg.BatchInterval)
+	defer ticker.Stop()
+
+	var pending []*Event
+
+	// flush delivers all pending events, split into batches of at most
+	// the configured size.
+	flush := func() {
+		for len(pending) > 0 {
+			n := len(pending)
+			if n > d.cfg.BatchSize {
+				n = d.cfg.BatchSize
+			}
+
+			err := d.deliver(pending[:n])
+			if err == errShuttingDown {
+				return
+			}
+			if err != nil {
+				log.Errorf("Dropping %v graph events: %v", n,
+					err)
+			}
+
+			pending = pending[n:]
+		}
+	}
+
+	for {
+		select {
+		case change, ok := <-client.TopologyChanges:
+			if !ok {
+				log.Warnf("Topology subscription closed")
+				return
+			}
+
+			for _, event := range eventsFromTopologyChange(change) {
+				if d.filter.matches(event) {
+					pending = append(pending, event)
+				}
+			}
+
+			if len(pending) >= d.cfg.BatchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+// deliver posts the events to the webhook, retrying with an exponential
+// backoff on failure.
+func (d *Dispatcher) deliver(events []*Event) error {
+	body, err := json.Marshal(&batch{Events: events})
+	if err != nil {
+		return err
+	}
+
+	backoff := d.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = d.post(body)
+		if err == nil {
+			log.Debugf("Delivered %v graph events", len(events))
+			return nil
+		}
+
+		if attempt >= d.cfg.MaxRetries {
+			return err
+		}
+
+		log.Debugf("Delivery of graph events failed, retrying in "+
+			"%v: %v", backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-d.quit:
+			return errShuttingDown
+		}
+
+		backoff *= 2
+	}
+}
+
+// post sends a single request with the given body to the webhook.
+func (d *Dispatcher) post(body []byte) error {
+	req, err := http.NewRequest(
+		http.MethodPost, d.cfg.URL, bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(d.cfg.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(d.cfg.Secret, body))
+	}
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	// Drain the body so that the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %v", resp.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the body, keyed with the given
+// secret. Receivers can use it to authenticate the requests of the
+// dispatcher.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
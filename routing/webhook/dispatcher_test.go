@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+var (
+	testSecret = []byte("secret")
+
+	testNode1 = route.Vertex{2, 1}
+	testNode2 = route.Vertex{3, 2}
+)
+
+// webhookServer is a test http server that records the received batches.
+type webhookServer struct {
+	*httptest.Server
+
+	batches  chan []*Event
+	failures int32
+}
+
+// newWebhookServer starts a server that fails the first given number of
+// requests, and verifies the signature of all others.
+func newWebhookServer(t *testing.T, failures int32) *webhookServer {
+	s := &webhookServer{
+		batches:  make(chan []*Event, 10),
+		failures: failures,
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&s.failures, -1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Errorf("unable to read body: %v", err)
+				return
+			}
+
+			sig := r.Header.Get(SignatureHeader)
+			if sig != Sign(testSecret, body) {
+				t.Errorf("invalid signature %v", sig)
+			}
+
+			var b batch
+			if err := json.Unmarshal(body, &b); err != nil {
+				t.Errorf("unable to decode body: %v", err)
+				return
+			}
+
+			s.batches <- b.Events
+		},
+	))
+
+	return s
+}
+
+// expectBatch asserts that the next batch consists of events for the given
+// channels.
+func (s *webhookServer) expectBatch(t *testing.T, chanIDs ...uint64) {
+	t.Helper()
+
+	select {
+	case events := <-s.batches:
+		if len(events) != len(chanIDs) {
+			t.Fatalf("expected %v events, got %v", len(chanIDs),
+				len(events))
+		}
+		for i, event := range events {
+			if event.ChanID != chanIDs[i] {
+				t.Fatalf("expected channel %v, got %v",
+					chanIDs[i], event.ChanID)
+			}
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no batch received")
+	}
+}
+
+// startDispatcher starts a dispatcher with the given config, delivering to
+// the server. The returned channel feeds topology changes to the dispatcher.
+func startDispatcher(t *testing.T, s *webhookServer, cfg Config) (
+	*Dispatcher, chan *routing.TopologyChange) {
+
+	changes := make(chan *routing.TopologyChange)
+
+	cfg.URL = s.URL
+	cfg.Secret = testSecret
+	cfg.SubscribeTopology = func() (*routing.TopologyClient, error) {
+		return &routing.TopologyClient{
+			TopologyChanges: changes,
+			Cancel:          func() {},
+		}, nil
+	}
+
+	d, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unable to create dispatcher: %v", err)
+	}
+	if err := d.Start(); err != nil {
+		t.Fatalf("unable to start dispatcher: %v", err)
+	}
+
+	return d, changes
+}
+
+// TestDispatcherBatchFilter asserts that events are filtered, and delivered
+// once a batch is full.
+func TestDispatcherBatchFilter(t *testing.T) {
+	t.Parallel()
+
+	s := newWebhookServer(t, 0)
+	defer s.Close()
+
+	d, changes := startDispatcher(t, s, Config{
+		Filter: &Filter{
+			Types:    []EventType{EventChannelOpen, EventChannelClose},
+			Channels: []uint64{1, 2, 3},
+		},
+		BatchSize:     2,
+		BatchInterval: time.Hour,
+	})
+	defer d.Stop()
+
+	// The update type and the unknown channel are filtered, so only the
+	// second change completes the batch. The remaining event is delivered
+	// in a batch of its own.
+	changes <- &routing.TopologyChange{
+		NewChannels: []*routing.NewChanSummary{
+			{ChanID: 1, Node1: testNode1, Node2: testNode2},
+			{ChanID: 4, Node1: testNode1, Node2: testNode2},
+		},
+		ChannelEdgeUpdates: []*routing.ChannelEdgeUpdate{
+			{ChanID: 1},
+		},
+	}
+	changes <- &routing.TopologyChange{
+		ClosedChannels: []*routing.ClosedChanSummary{
+			{ChanID: 2}, {ChanID: 3},
+		},
+	}
+
+	s.expectBatch(t, 1, 2)
+	s.expectBatch(t, 3)
+}
+
+// TestDispatcherIntervalRetry asserts that a partial batch is delivered once
+// the batch interval expires, and that failed deliveries are retried.
+func TestDispatcherIntervalRetry(t *testing.T) {
+	t.Parallel()
+
+	s := newWebhookServer(t, 2)
+	defer s.Close()
+
+	d, changes := startDispatcher(t, s, Config{
+		Filter: &Filter{
+			Nodes: []route.Vertex{testNode2},
+		},
+		BatchInterval: 50 * time.Millisecond,
+		MaxRetries:    2,
+		RetryBackoff:  time.Millisecond,
+	})
+	defer d.Stop()
+
+	changes <- &routing.TopologyChange{
+		NewChannels: []*routing.NewChanSummary{
+			{ChanID: 5, Node1: testNode1, Node2: testNode2},
+		},
+		ClosedChannels: []*routing.ClosedChanSummary{
+			{ChanID: 5},
+		},
+	}
+
+	s.expectBatch(t, 5)
+}
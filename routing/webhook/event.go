@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/lightningnetwork/lnd/routing"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// EventType identifies the kind of topology change that an event describes.
+type EventType string
+
+const (
+	// EventChannelOpen is emitted when a new channel is announced.
+	EventChannelOpen EventType = "channel_open"
+
+	// EventChannelUpdate is emitted when the routing policy of one of the
+	// directions of a channel is updated.
+	EventChannelUpdate EventType = "channel_update"
+
+	// EventChannelClose is emitted when the funding output of a channel
+	// is spent.
+	EventChannelClose EventType = "channel_close"
+
+	// EventNodeUpdate is emitted when a node announcement is received.
+	EventNodeUpdate EventType = "node_update"
+)
+
+// Policy is the routing policy of one direction of a channel, as it is
+// delivered to the webhook.
+type Policy struct {
+	MinHTLC       uint64 `json:"min_htlc_msat"`
+	MaxHTLC       uint64 `json:"max_htlc_msat"`
+	BaseFee       uint64 `json:"fee_base_msat"`
+	FeeRate       uint64 `json:"fee_rate_milli_msat"`
+	TimeLockDelta uint16 `json:"time_lock_delta"`
+	Disabled      bool   `json:"disabled"`
+}
+
+// Event is a single topology change, as it is delivered to the webhook.
+// Public keys are hex encoded. Depending on the type of the event, only a
+// subset of the fields is set.
+type Event struct {
+	// Type is the kind of change that this event describes.
+	Type EventType `json:"type"`
+
+	// ChanID is the short channel ID of the channel the event relates to.
+	ChanID uint64 `json:"chan_id,omitempty"`
+
+	// ChanPoint is the funding outpoint of the channel the event relates
+	// to.
+	ChanPoint string `json:"chan_point,omitempty"`
+
+	// Capacity is the capacity of the channel in satoshis.
+	Capacity int64 `json:"capacity,omitempty"`
+
+	// Nodes are the nodes that the event relates to. For channel opens,
+	// these are the two channel parties. For channel updates, the first
+	// node is the advertising node and the second one the connecting
+	// node. For node updates, it is the announcing node. Channel closes
+	// don't carry node information.
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Policy is the updated routing policy of a channel update.
+	Policy *Policy `json:"policy,omitempty"`
+
+	// ClosedHeight is the height at which a channel was closed.
+	ClosedHeight uint32 `json:"closed_height,omitempty"`
+
+	// Alias is the alias of the node of a node update.
+	Alias string `json:"alias,omitempty"`
+
+	// Color is the color of the node of a node update.
+	Color string `json:"color,omitempty"`
+
+	// Addresses are the advertised addresses of the node of a node
+	// update.
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// pubKeyStr returns the hex encoding of the compressed public key.
+func pubKeyStr(key *btcec.PublicKey) string {
+	if key == nil {
+		return ""
+	}
+
+	return hex.EncodeToString(key.SerializeCompressed())
+}
+
+// vertexStr returns the hex encoding of the vertex.
+func vertexStr(v route.Vertex) string {
+	return hex.EncodeToString(v[:])
+}
+
+// eventsFromTopologyChange converts a topology change of the router into the
+// events that are delivered to the webhook.
+func eventsFromTopologyChange(change *routing.TopologyChange) []*Event {
+	var events []*Event
+
+	for _, c := range change.NewChannels {
+		events = append(events, &Event{
+			Type:      EventChannelOpen,
+			ChanID:    c.ChanID,
+			ChanPoint: c.ChanPoint.String(),
+			Capacity:  int64(c.Capacity),
+			Nodes:     []string{vertexStr(c.Node1), vertexStr(c.Node2)},
+		})
+	}
+
+	for _, u := range change.ChannelEdgeUpdates {
+		events = append(events, &Event{
+			Type:      EventChannelUpdate,
+			ChanID:    u.ChanID,
+			ChanPoint: u.ChanPoint.String(),
+			Capacity:  int64(u.Capacity),
+			Nodes: []string{
+				pubKeyStr(u.AdvertisingNode),
+				pubKeyStr(u.ConnectingNode),
+			},
+			Policy: &Policy{
+				MinHTLC:       uint64(u.MinHTLC),
+				MaxHTLC:       uint64(u.MaxHTLC),
+				BaseFee:       uint64(u.BaseFee),
+				FeeRate:       uint64(u.FeeRate),
+				TimeLockDelta: u.TimeLockDelta,
+				Disabled:      u.Disabled,
+			},
+		})
+	}
+
+	for _, c := range change.ClosedChannels {
+		events = append(events, &Event{
+			Type:         EventChannelClose,
+			ChanID:       c.ChanID,
+			ChanPoint:    c.ChanPoint.String(),
+			Capacity:     int64(c.Capacity),
+			ClosedHeight: c.ClosedHeight,
+		})
+	}
+
+	for _, n := range change.NodeUpdates {
+		addrs := make([]string, 0, len(n.Addresses))
+		for _, addr := range n.Addresses {
+			addrs = append(addrs, addr.String())
+		}
+
+		events = append(events, &Event{
+			Type:      EventNodeUpdate,
+			Nodes:     []string{pubKeyStr(n.IdentityKey)},
+			Alias:     n.Alias,
+			Color:     n.Color,
+			Addresses: addrs,
+		})
+	}
+
+	return events
+}
+
+// Filter restricts the events that are delivered to the webhook. An event is
+// delivered if it matches all of the criteria that are set. A nil filter
+// matches all events.
+type Filter struct {
+	// Types, if non-empty, is the set of event types to deliver.
+	Types []EventType
+
+	// Nodes, if non-empty, restricts delivery to events that relate to at
+	// least one of the given nodes. As channel closes don't carry node
+	// information, they are never delivered if this criterion is set.
+	Nodes []route.Vertex
+
+	// Channels, if non-empty, restricts delivery to events that relate to
+	// one of the given short channel IDs.
+	Channels []uint64
+}
+
+// eventFilter is the prepared form of a Filter that allows constant time
+// lookups.
+type eventFilter struct {
+	types    map[EventType]struct{}
+	nodes    map[string]struct{}
+	channels map[uint64]struct{}
+}
+
+// newEventFilter prepares the given filter for matching.
+func newEventFilter(f *Filter) *eventFilter {
+	filter := &eventFilter{}
+	if f == nil {
+		return filter
+	}
+
+	if len(f.Types) > 0 {
+		filter.types = make(map[EventType]struct{})
+		for _, t := range f.Types {
+			filter.types[t] = struct{}{}
+		}
+	}
+
+	if len(f.Nodes) > 0 {
+		filter.nodes = make(map[string]struct{})
+		for _, n := range f.Nodes {
+			filter.nodes[vertexStr(n)] = struct{}{}
+		}
+	}
+
+	if len(f.Channels) > 0 {
+		filter.channels = make(map[uint64]struct{})
+		for _, c := range f.Channels {
+			filter.channels[c] = struct{}{}
+		}
+	}
+
+	return filter
+}
+
+// matches returns true if the event should be delivered.
+func (f *eventFilter) matches(event *Event) bool {
+	if f.types != nil {
+		if _, ok := f.types[event.Type]; !ok {
+			return false
+		}
+	}
+
+	if f.channels != nil {
+		if _, ok := f.channels[event.ChanID]; !ok {
+			return false
+		}
+	}
+
+	if f.nodes != nil {
+		var found bool
+		for _, node := range event.Nodes {
+			if _, ok := f.nodes[node]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -643,6 +643,11 @@ func (r *rpcServer) Start() error {
 		if err != nil {
 			return err
 		}
+
+		err = registerMissionControlMetrics(r.server.missionControl)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Finally, start the REST proxy for our gRPC server above. We'll ensure
@@ -988,13 +993,21 @@ func (r *rpcServer) SendCoins(ctx context.Context,
 			return nil, err
 		}
 
+		// Outputs paying to an address outside of our wallet may be
+		// subject to a different minimum amount.
+		destType := sweep.DestinationExternal
+		if wallet.IsOurAddress(targetAddr) {
+			destType = sweep.DestinationWallet
+		}
+		minOutputAmt := r.server.sweeper.MinOutputAmount(destType)
+
 		// With the sweeper instance created, we can now generate a
 		// transaction that will sweep ALL outputs from the wallet in a
 		// single transaction. This will be generated in a concurrent
 		// safe manner, so no need to worry about locking.
 		sweepTxPkg, err := sweep.CraftSweepAllTx(
-			feePerKw, uint32(bestHeight), targetAddr, wallet,
-			wallet.WalletController, wallet.WalletController,
+			feePerKw, uint32(bestHeight), targetAddr, minOutputAmt,
+			wallet, wallet.WalletController, wallet.WalletController,
 			r.server.cc.feeEstimator, r.server.cc.signer,
 		)
 		if err != nil {
@@ -2885,15 +2898,16 @@ func (r *rpcServer) unmarshallSendToRouteRequest(
 // hints), or we'll get a fully populated route from the user that we'll pass
 // directly to the channel router for dispatching.
 type rpcPaymentIntent struct {
-	msat              lnwire.MilliSatoshi
-	feeLimit          lnwire.MilliSatoshi
-	cltvLimit         *uint32
-	dest              route.Vertex
-	rHash             [32]byte
-	cltvDelta         uint16
-	routeHints        [][]zpay32.HopHint
-	outgoingChannelID *uint64
-	payReq            []byte
+	msat               lnwire.MilliSatoshi
+	feeLimit           lnwire.MilliSatoshi
+	cltvLimit          *uint32
+	dest               route.Vertex
+	rHash              [32]byte
+	cltvDelta          uint16
+	routeHints         [][]zpay32.HopHint
+	outgoingChannelIDs []uint64
+	payReq             []byte
+	invoiceExpiry      time.Time
 
 	route *route.Route
 }
@@ -2901,8 +2915,12 @@ type rpcPaymentIntent struct {
 // extractPaymentIntent attempts to parse the complete details required to
 // dispatch a client from the information presented by an RPC client. There are
 // three ways a client can specify their payment details: a payment request,
-// via manual details, or via a complete route.
-func extractPaymentIntent(rpcPayReq *rpcPaymentRequest) (rpcPaymentIntent, error) {
+// via manual details, or via a complete route. The resolveNode closure is used
+// to map a destination string that isn't a full pubkey to a node.
+func extractPaymentIntent(rpcPayReq *rpcPaymentRequest,
+	resolveNode func(string) (route.Vertex, error)) (rpcPaymentIntent,
+	error) {
+
 	payIntent := rpcPaymentIntent{}
 
 	// If a route was specified, then we can use that directly.
@@ -2929,7 +2947,9 @@ func extractPaymentIntent(rpcPayReq *rpcPaymentRequest) (rpcPaymentIntent, error
 	// If there are no routes specified, pass along a outgoing channel
 	// restriction if specified.
 	if rpcPayReq.OutgoingChanId != 0 {
-		payIntent.outgoingChannelID = &rpcPayReq.OutgoingChanId
+		payIntent.outgoingChannelIDs = []uint64{
+			rpcPayReq.OutgoingChanId,
+		}
 	}
 
 	// Take cltv limit from request if set.
@@ -2954,6 +2974,13 @@ func extractPaymentIntent(rpcPayReq *rpcPaymentRequest) (rpcPaymentIntent, error
 			return payIntent, err
 		}
 
+		// We'll also make sure that we support all features that the
+		// payreq requires.
+		err = routing.ValidateInvoiceFeatures(payReq)
+		if err != nil {
+			return payIntent, err
+		}
+
 		// If the amount was not included in the invoice, then we let
 		// the payee specify the amount of satoshis they wish to send.
 		// We override the amount to pay with the amount provided from
@@ -2983,22 +3010,24 @@ func extractPaymentIntent(rpcPayReq *rpcPaymentRequest) (rpcPaymentIntent, error
 		payIntent.cltvDelta = uint16(payReq.MinFinalCLTVExpiry())
 		payIntent.routeHints = payReq.RouteHints
 		payIntent.payReq = []byte(rpcPayReq.PaymentRequest)
+		payIntent.invoiceExpiry = payReq.Timestamp.Add(payReq.Expiry())
 
 		return payIntent, nil
 	}
 
 	// At this point, a destination MUST be specified, so we'll convert it
 	// into the proper representation now. The destination will either be
-	// encoded as raw bytes, or via a hex string.
+	// encoded as raw bytes, or via a string that is either hex encoded or
+	// the alias or pubkey prefix of a node in the graph.
 	var pubBytes []byte
 	if len(rpcPayReq.Dest) != 0 {
 		pubBytes = rpcPayReq.Dest
 	} else {
-		var err error
-		pubBytes, err = hex.DecodeString(rpcPayReq.DestString)
+		dest, err := resolveNode(rpcPayReq.DestString)
 		if err != nil {
 			return payIntent, err
 		}
+		pubBytes = dest[:]
 	}
 	if len(pubBytes) != 33 {
 		return payIntent, errors.New("invalid key length")
@@ -3090,16 +3119,17 @@ func (r *rpcServer) dispatchPaymentIntent(
 	// router, otherwise we'll create a payment session to execute it.
 	if payIntent.route == nil {
 		payment := &routing.LightningPayment{
-			Target:            payIntent.dest,
-			Amount:            payIntent.msat,
-			FinalCLTVDelta:    payIntent.cltvDelta,
-			FeeLimit:          payIntent.feeLimit,
-			CltvLimit:         payIntent.cltvLimit,
-			PaymentHash:       payIntent.rHash,
-			RouteHints:        payIntent.routeHints,
-			OutgoingChannelID: payIntent.outgoingChannelID,
-			PaymentRequest:    payIntent.payReq,
-			PayAttemptTimeout: routing.DefaultPayAttemptTimeout,
+			Target:             payIntent.dest,
+			Amount:             payIntent.msat,
+			FinalCLTVDelta:     payIntent.cltvDelta,
+			FeeLimit:           payIntent.feeLimit,
+			CltvLimit:          payIntent.cltvLimit,
+			PaymentHash:        payIntent.rHash,
+			RouteHints:         payIntent.routeHints,
+			OutgoingChannelIDs: payIntent.outgoingChannelIDs,
+			PaymentRequest:     payIntent.payReq,
+			PayAttemptTimeout:  routing.DefaultPayAttemptTimeout,
+			InvoiceExpiry:      payIntent.invoiceExpiry,
 		}
 
 		preImage, route, routerErr = r.server.chanRouter.SendPayment(
@@ -3197,7 +3227,10 @@ func (r *rpcServer) sendPayment(stream *paymentStream) error {
 				// fields. If the payment proto wasn't well
 				// formed, then we'll send an error reply and
 				// wait for the next payment.
-				payIntent, err := extractPaymentIntent(nextPayment)
+				payIntent, err := extractPaymentIntent(
+					nextPayment,
+					r.server.chanRouter.ResolveNode,
+				)
 				if err != nil {
 					if err := stream.send(&lnrpc.SendResponse{
 						PaymentError: err.Error(),
@@ -3331,7 +3364,9 @@ func (r *rpcServer) sendPaymentSync(ctx context.Context,
 
 	// First we'll attempt to map the proto describing the next payment to
 	// an intent that we can pass to local sub-systems.
-	payIntent, err := extractPaymentIntent(nextPayment)
+	payIntent, err := extractPaymentIntent(
+		nextPayment, r.server.chanRouter.ResolveNode,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -4039,6 +4074,16 @@ func (r *rpcServer) SubscribeChannelGraph(req *lnrpc.GraphTopologySubscription,
 				return errors.New("server shutting down")
 			}
 
+			// Newly announced channels have no representation in
+			// the gRPC service, so we'll skip changes that only
+			// consist of those.
+			if len(topChange.NodeUpdates) == 0 &&
+				len(topChange.ChannelEdgeUpdates) == 0 &&
+				len(topChange.ClosedChannels) == 0 {
+
+				continue
+			}
+
 			// Convert the struct from the channel router into the
 			// form expected by the gRPC service then send it off
 			// to the client.
@@ -32,6 +32,7 @@ import (
 	"github.com/lightningnetwork/lnd/channelnotifier"
 	"github.com/lightningnetwork/lnd/contractcourt"
 	"github.com/lightningnetwork/lnd/discovery"
+	"github.com/lightningnetwork/lnd/eventbus"
 	"github.com/lightningnetwork/lnd/htlcswitch"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/invoices"
@@ -184,6 +185,10 @@ type server struct {
 
 	channelNotifier *channelnotifier.ChannelNotifier
 
+	// eventBus is the bus on which subsystems publish significant events,
+	// such as settled payments and confirmed sweeps.
+	eventBus *eventbus.EventBus
+
 	witnessBeacon contractcourt.WitnessBeacon
 
 	breachArbiter *breachArbiter
@@ -353,6 +358,7 @@ func newServer(listenAddrs []net.Addr, chanDB *channeldb.DB, cc *chainControl,
 		),
 
 		channelNotifier: channelnotifier.New(chanDB),
+		eventBus:        eventbus.New(),
 
 		identityPriv: privKey,
 		nodeSigner:   netann.NewNodeSigner(privKey),
@@ -646,15 +652,26 @@ func newServer(listenAddrs []net.Addr, chanDB *channeldb.DB, cc *chainControl,
 	//
 	// TODO(joostjager): When we are further in the process of moving to sub
 	// servers, the mission control instance itself can be moved there too.
+	mcCfg, err := routerrpc.GetMissionControlConfig(
+		cfg.SubRPCServers.RouterRPC,
+	)
+	if err != nil {
+		return nil, err
+	}
+	mcCfg.Annotations = channeldb.NewAnnotationStore(chanDB)
 	s.missionControl = routing.NewMissionControl(
-		chanGraph, selfNode, queryBandwidth,
-		routerrpc.GetMissionControlConfig(cfg.SubRPCServers.RouterRPC),
+		chanGraph, selfNode, queryBandwidth, mcCfg,
 	)
 
 	paymentControl := channeldb.NewPaymentControl(chanDB)
 
 	s.controlTower = routing.NewControlTower(paymentControl)
 
+	var routeJournal routing.RouteJournal
+	if cfg.Routing.UseRouteJournal() {
+		routeJournal = channeldb.NewRouteJournal(chanDB)
+	}
+
 	s.chanRouter, err = routing.New(routing.Config{
 		Graph:              chanGraph,
 		Chain:              cc.chainIO,
@@ -666,7 +683,21 @@ func newServer(listenAddrs []net.Addr, chanDB *channeldb.DB, cc *chainControl,
 		GraphPruneInterval: time.Duration(time.Hour),
 		QueryBandwidth:     queryBandwidth,
 		AssumeChannelValid: cfg.Routing.UseAssumeChannelValid(),
+		DeferGraphSync:     cfg.Routing.UseDeferGraphSync(),
 		NextPaymentID:      sequencer.NextID,
+		ChainParams:        activeNetParams.Params,
+		CheckLocalPolicy:   s.htlcSwitch.CheckLocalHtlc,
+		RouteJournal:       routeJournal,
+		HintPolicies:       channeldb.NewHintPolicyStore(chanDB),
+		EventBus:           s.eventBus,
+		ProbeNode: func(node route.Vertex) bool {
+			_, err := s.FindPeerByPubStr(string(node[:]))
+			return err == nil
+		},
+		GraphAuditSize:     routing.DefaultGraphAuditSize,
+		ForwardingHistory:  chanDB.ForwardingLog(),
+		MinAttemptInterval: cfg.Routing.MinAttemptInterval,
+		AttemptJitter:      cfg.Routing.AttemptIntervalJitter,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("can't create router: %v", err)
@@ -722,6 +753,13 @@ func newServer(listenAddrs []net.Addr, chanDB *channeldb.DB, cc *chainControl,
 		return nil, err
 	}
 
+	// If the backend can report the minimum fee rate of its mempool, the
+	// sweeper uses it to avoid broadcasting sweeps that would be rejected.
+	var mempoolMinFeeRate func() (lnwallet.SatPerKWeight, error)
+	if src, ok := cc.feeEstimator.(lnwallet.MempoolFeeSource); ok {
+		mempoolMinFeeRate = src.MempoolMinFeePerKW
+	}
+
 	s.sweeper = sweep.New(&sweep.UtxoSweeperConfig{
 		FeeEstimator: cc.feeEstimator,
 		GenSweepScript: func() ([]byte, error) {
@@ -740,6 +778,11 @@ func newServer(listenAddrs []net.Addr, chanDB *channeldb.DB, cc *chainControl,
 		NextAttemptDeltaFunc: sweep.DefaultNextAttemptDeltaFunc,
 		MaxFeeRate:           sweep.DefaultMaxFeeRate,
 		FeeRateBucketSize:    sweep.DefaultFeeRateBucketSize,
+		OutpointLocker:       cc.wallet,
+		LanePolicies:         sweep.DefaultLanePolicies(),
+		KeyRing:              cc.keyRing,
+		EventBus:             s.eventBus,
+		MempoolMinFeeRate:    mempoolMinFeeRate,
 	})
 
 	s.utxoNursery = newUtxoNursery(&NurseryConfig{
@@ -750,7 +793,7 @@ func newServer(listenAddrs []net.Addr, chanDB *channeldb.DB, cc *chainControl,
 		Notifier:            cc.chainNotifier,
 		PublishTransaction:  cc.wallet.PublishTransaction,
 		Store:               utxnStore,
-		SweepInput:          s.sweeper.SweepInput,
+		SweepInput:          s.sweeper.SweepInputWithMetadata,
 	})
 
 	// Construct a closure that wraps the htlcswitch's CloseLink method.
@@ -1124,6 +1167,10 @@ func (s *server) Start() error {
 			startErr = err
 			return
 		}
+		if err := s.eventBus.Start(); err != nil {
+			startErr = err
+			return
+		}
 		if err := s.sphinx.Start(); err != nil {
 			startErr = err
 			return
@@ -1276,6 +1323,7 @@ func (s *server) Stop() error {
 		s.chainArb.Stop()
 		s.sweeper.Stop()
 		s.channelNotifier.Stop()
+		s.eventBus.Stop()
 		s.cc.wallet.Shutdown()
 		s.cc.chainView.Stop()
 		s.connMgr.Stop()
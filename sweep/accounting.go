@@ -0,0 +1,242 @@
+package sweep
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+// sweepOutcome is the state of an input with respect to the sweeper's
+// accounting.
+type sweepOutcome uint8
+
+const (
+	// outcomePending indicates that the sweeper is still attempting to
+	// sweep the input.
+	outcomePending sweepOutcome = iota
+
+	// outcomeRecovered indicates that the input was swept to the wallet
+	// by a sweep transaction of ours.
+	outcomeRecovered
+
+	// outcomeAbandoned indicates that the sweeper gave up on the input.
+	outcomeAbandoned
+)
+
+// SweepTotals are the summed values of inputs of the sweeper, bucketed by
+// their outcome. Values are those of the inputs themselves, so the fees paid
+// by sweep transactions are included in Recovered.
+type SweepTotals struct {
+	// Recovered is the total value of the inputs that were swept to the
+	// wallet by a sweep transaction of ours.
+	Recovered btcutil.Amount
+
+	// Pending is the total value of the inputs that the sweeper is still
+	// attempting to sweep.
+	Pending btcutil.Amount
+
+	// Abandoned is the total value of the inputs that the sweeper gave up
+	// on, either because another party spent them or because sweeping
+	// them failed.
+	Abandoned btcutil.Amount
+}
+
+// add adds the amount to the total of the given outcome.
+func (t *SweepTotals) add(outcome sweepOutcome, amt btcutil.Amount) {
+	switch outcome {
+	case outcomePending:
+		t.Pending += amt
+
+	case outcomeRecovered:
+		t.Recovered += amt
+
+	case outcomeAbandoned:
+		t.Abandoned += amt
+	}
+}
+
+// SweepAccounting reports how much value passed through the sweeper since it
+// was started, and how much of it is still in flight.
+type SweepAccounting struct {
+	// Totals are the totals over all inputs.
+	Totals SweepTotals
+
+	// ByWitnessType are the totals per witness type of the inputs.
+	ByWitnessType map[input.WitnessType]SweepTotals
+
+	// ByChannel are the totals per channel point of the channel that the
+	// inputs originate from. Inputs without metadata aren't included.
+	ByChannel map[wire.OutPoint]SweepTotals
+
+	// Fees is the total fee paid by our sweep transactions that
+	// confirmed.
+	Fees btcutil.Amount
+
+	// FeesByChannel is the fee paid by our confirmed sweep transactions
+	// per channel point of the channel that the inputs originate from.
+	// The fee of a sweep transaction is attributed to its inputs in
+	// proportion to their values. The share of inputs without metadata
+	// is only included in Fees.
+	FeesByChannel map[wire.OutPoint]btcutil.Amount
+}
+
+// newSweepAccounting returns an empty accounting.
+func newSweepAccounting() *SweepAccounting {
+	return &SweepAccounting{
+		ByWitnessType: make(map[input.WitnessType]SweepTotals),
+		ByChannel:     make(map[wire.OutPoint]SweepTotals),
+		FeesByChannel: make(map[wire.OutPoint]btcutil.Amount),
+	}
+}
+
+// add accounts the value of the pending input to the given outcome.
+func (a *SweepAccounting) add(pi *pendingInput, outcome sweepOutcome) {
+	amt := btcutil.Amount(pi.input.SignDesc().Output.Value)
+
+	a.Totals.add(outcome, amt)
+
+	witnessType := pi.input.WitnessType()
+	totals := a.ByWitnessType[witnessType]
+	totals.add(outcome, amt)
+	a.ByWitnessType[witnessType] = totals
+
+	if pi.metadata == nil {
+		return
+	}
+
+	totals = a.ByChannel[pi.metadata.ChanPoint]
+	totals.add(outcome, amt)
+	a.ByChannel[pi.metadata.ChanPoint] = totals
+}
+
+// addFee accounts the share of the fee of a confirmed sweep that is
+// attributed to the pending input.
+func (a *SweepAccounting) addFee(pi *pendingInput, fee btcutil.Amount) {
+	a.Fees += fee
+
+	if pi.metadata == nil {
+		return
+	}
+
+	a.FeesByChannel[pi.metadata.ChanPoint] += fee
+}
+
+// copy returns a deep copy of the accounting.
+func (a *SweepAccounting) copy() *SweepAccounting {
+	c := newSweepAccounting()
+	c.Totals = a.Totals
+	c.Fees = a.Fees
+
+	for witnessType, totals := range a.ByWitnessType {
+		c.ByWitnessType[witnessType] = totals
+	}
+	for chanPoint, totals := range a.ByChannel {
+		c.ByChannel[chanPoint] = totals
+	}
+	for chanPoint, fee := range a.FeesByChannel {
+		c.FeesByChannel[chanPoint] = fee
+	}
+
+	return c
+}
+
+// accountingReq is a request to retrieve the accounting of the sweeper.
+type accountingReq struct {
+	respChan chan *SweepAccounting
+}
+
+// recordOutcome accounts the final outcome of an input that is no longer
+// pending.
+func (s *UtxoSweeper) recordOutcome(pi *pendingInput, result Result) {
+	outcome := outcomeRecovered
+	if result.Err != nil {
+		outcome = outcomeAbandoned
+	}
+
+	s.accounting.add(pi, outcome)
+}
+
+// attributeSweepFee attributes the fee of our confirmed sweep tx to the pending
+// inputs that it spends, in proportion to their values. It must be called
+// before the inputs are removed. The spend of every input of the tx is
+// notified separately, but the inputs are removed on the first notification,
+// so the fee is only attributed once.
+func (s *UtxoSweeper) attributeSweepFee(tx *wire.MsgTx) {
+	var (
+		inputs     = make([]*pendingInput, 0, len(tx.TxIn))
+		inputTotal btcutil.Amount
+	)
+	for _, txIn := range tx.TxIn {
+		pi, ok := s.pendingInputs[txIn.PreviousOutPoint]
+		if !ok {
+			// Without the value of every input, the fee is
+			// unknown.
+			return
+		}
+
+		inputs = append(inputs, pi)
+		inputTotal += btcutil.Amount(pi.input.SignDesc().Output.Value)
+	}
+
+	var outputTotal btcutil.Amount
+	for _, txOut := range tx.TxOut {
+		outputTotal += btcutil.Amount(txOut.Value)
+	}
+
+	fee := inputTotal - outputTotal
+	if inputTotal == 0 || fee <= 0 {
+		return
+	}
+
+	log.Debugf("Attributing fee %v of sweep tx %v to %v inputs", fee,
+		tx.TxHash(), len(inputs))
+
+	// Attribute the rounding remainder to the first input, so that the
+	// shares add up to the fee.
+	remainder := fee
+	shares := make([]btcutil.Amount, len(inputs))
+	for i, pi := range inputs {
+		value := btcutil.Amount(pi.input.SignDesc().Output.Value)
+		shares[i] = fee * value / inputTotal
+		remainder -= shares[i]
+	}
+	shares[0] += remainder
+
+	for i, pi := range inputs {
+		s.accounting.addFee(pi, shares[i])
+	}
+}
+
+// handleAccountingReq returns the accounting of the inputs that are no longer
+// pending, completed with the inputs that are currently pending.
+func (s *UtxoSweeper) handleAccountingReq() *SweepAccounting {
+	accounting := s.accounting.copy()
+	for _, pi := range s.pendingInputs {
+		accounting.add(pi, outcomePending)
+	}
+
+	return accounting
+}
+
+// Accounting returns the totals of the values of the inputs that were swept to
+// the wallet, that are pending and that were abandoned since the sweeper was
+// started, bucketed by witness type and by channel. It also reports the fees
+// paid by our confirmed sweeps, attributed to the channels that the swept
+// inputs originate from.
+func (s *UtxoSweeper) Accounting() (*SweepAccounting, error) {
+	respChan := make(chan *SweepAccounting, 1)
+	select {
+	case s.accountingReqs <- &accountingReq{
+		respChan: respChan,
+	}:
+	case <-s.quit:
+		return nil, ErrSweeperShuttingDown
+	}
+
+	select {
+	case accounting := <-respChan:
+		return accounting, nil
+	case <-s.quit:
+		return nil, ErrSweeperShuttingDown
+	}
+}
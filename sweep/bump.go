@@ -0,0 +1,293 @@
+package sweep
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+var (
+	// ErrCannotBump is returned when a transaction can't be replaced by
+	// the sweeper, and no outputs were offered to bump it through a child
+	// transaction.
+	ErrCannotBump = errors.New("no means available to bump transaction")
+)
+
+// BumpStrategy describes the means by which the sweeper bumps the fee rate of
+// a transaction.
+type BumpStrategy uint8
+
+const (
+	// BumpStrategyNone indicates that the transaction already pays the
+	// target fee rate, so no bump was needed.
+	BumpStrategyNone BumpStrategy = iota
+
+	// BumpStrategyRBF indicates that the transaction is a sweep of our
+	// own that is replaced by a sweep of the same inputs at a higher fee
+	// rate.
+	BumpStrategyRBF
+
+	// BumpStrategyCPFP indicates that outputs of the transaction are swept
+	// by a child transaction that pays for its parent.
+	BumpStrategyCPFP
+)
+
+// String returns a human readable representation of the bump strategy.
+func (b BumpStrategy) String() string {
+	switch b {
+	case BumpStrategyNone:
+		return "none"
+
+	case BumpStrategyRBF:
+		return "rbf"
+
+	case BumpStrategyCPFP:
+		return "cpfp"
+
+	default:
+		return "unknown"
+	}
+}
+
+// BumpRequest describes an unconfirmed transaction of ours that needs to
+// reach a target fee rate.
+type BumpRequest struct {
+	// Tx is the unconfirmed transaction to bump.
+	Tx *wire.MsgTx
+
+	// Fee is the absolute fee that is paid by Tx. It is used to determine
+	// the fee rate of Tx, and the fee that a child transaction needs to
+	// pay to lift Tx to the target fee rate.
+	Fee btcutil.Amount
+
+	// FeePreference expresses the target fee rate of the transaction.
+	FeePreference FeePreference
+
+	// Outputs are the outputs of Tx that we are able to spend. They are
+	// used to bump Tx through a child transaction if Tx can't be replaced.
+	Outputs []input.Input
+}
+
+// BumpResponse describes how the sweeper is bumping a transaction.
+type BumpResponse struct {
+	// Strategy is the means by which the transaction is bumped.
+	Strategy BumpStrategy
+
+	// Results contains a channel for each input of the replacement
+	// transaction in case of RBF, or for each of the requested outputs in
+	// case of CPFP. The outcome of the sweep is sent over these channels.
+	Results []chan Result
+}
+
+// bumpTxReq is a request to replace one of our own sweep transactions.
+type bumpTxReq struct {
+	req      *BumpRequest
+	feeRate  lnwallet.SatPerKWeight
+	respChan chan []chan Result
+}
+
+// BumpTx ensures that the given unconfirmed transaction of ours reaches the
+// target fee rate. If the transaction is a replaceable sweep of which all
+// inputs are still pending, the inputs are resweeped at the target fee rate,
+// replacing the transaction. Otherwise, the passed outputs of the transaction
+// are swept by a child transaction that pays for the fee deficit of its
+// parent.
+func (s *UtxoSweeper) BumpTx(req *BumpRequest) (*BumpResponse, error) {
+	if req == nil || req.Tx == nil {
+		return nil, errors.New("nil transaction received")
+	}
+	if req.FeePreference.Urgency >= numUrgencyLanes {
+		return nil, fmt.Errorf("unknown urgency %v",
+			req.FeePreference.Urgency)
+	}
+
+	feeRate, err := s.feeRateForPreference(req.FeePreference)
+	if err != nil {
+		return nil, err
+	}
+
+	txid := req.Tx.TxHash()
+	parentWeight := blockchain.GetTransactionWeight(btcutil.NewTx(req.Tx))
+	parentFeeRate := lnwallet.SatPerKWeight(
+		int64(req.Fee) * 1000 / parentWeight,
+	)
+
+	log.Infof("Bump request received: txid=%v, fee_rate=%v, "+
+		"target_fee_rate=%v", txid, parentFeeRate, feeRate)
+
+	if parentFeeRate >= feeRate {
+		return &BumpResponse{Strategy: BumpStrategyNone}, nil
+	}
+
+	// First, attempt to replace the transaction. This is only possible if
+	// it is one of our own sweeps.
+	bumpReq := &bumpTxReq{
+		req:      req,
+		feeRate:  feeRate,
+		respChan: make(chan []chan Result, 1),
+	}
+	select {
+	case s.bumpTxReqs <- bumpReq:
+	case <-s.quit:
+		return nil, ErrSweeperShuttingDown
+	}
+
+	var results []chan Result
+	select {
+	case results = <-bumpReq.respChan:
+	case <-s.quit:
+		return nil, ErrSweeperShuttingDown
+	}
+
+	if results != nil {
+		return &BumpResponse{
+			Strategy: BumpStrategyRBF,
+			Results:  results,
+		}, nil
+	}
+
+	// The transaction can't be replaced, so we'll fall back to sweeping
+	// its outputs with a child that pays for the parent.
+	if len(req.Outputs) == 0 {
+		return nil, ErrCannotBump
+	}
+
+	for _, output := range req.Outputs {
+		if output == nil || output.OutPoint() == nil {
+			return nil, errors.New("nil output received")
+		}
+		if output.OutPoint().Hash != txid {
+			return nil, fmt.Errorf("output %v doesn't belong to "+
+				"tx %v", output.OutPoint(), txid)
+		}
+	}
+
+	resp := &BumpResponse{
+		Strategy: BumpStrategyCPFP,
+	}
+	for _, output := range req.Outputs {
+		childFeeRate := s.cpfpFeeRate(
+			output, parentWeight, req.Fee, feeRate,
+		)
+
+		resultChan, err := s.SweepInput(output, FeePreference{
+			FeeRate: childFeeRate,
+			Urgency: req.FeePreference.Urgency,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Results = append(resp.Results, resultChan)
+	}
+
+	return resp, nil
+}
+
+// cpfpFeeRate returns the fee rate at which the given output needs to be swept
+// so that the child transaction and its parent together reach the target fee
+// rate. The child is assumed to spend only this output, so if it is batched
+// with other inputs the package will pay more than strictly necessary.
+func (s *UtxoSweeper) cpfpFeeRate(output input.Input, parentWeight int64,
+	parentFee btcutil.Amount,
+	feeRate lnwallet.SatPerKWeight) lnwallet.SatPerKWeight {
+
+	_, childWeight, _, _ := getWeightEstimate([]input.Input{output})
+	if childWeight == 0 {
+		return feeRate
+	}
+
+	packageFee := feeRate.FeeForWeight(parentWeight + childWeight)
+	childFeeRate := lnwallet.SatPerKWeight(
+		int64(packageFee-parentFee) * 1000 / childWeight,
+	)
+
+	if childFeeRate < feeRate {
+		childFeeRate = feeRate
+	}
+	if childFeeRate > s.cfg.MaxFeeRate {
+		log.Warnf("Child fee rate %v to bump %v exceeds maximum, "+
+			"using %v", childFeeRate, output.OutPoint().Hash,
+			s.cfg.MaxFeeRate)
+
+		childFeeRate = s.cfg.MaxFeeRate
+	}
+
+	return childFeeRate
+}
+
+// handleBumpTxReq attempts to replace the transaction of the given request by
+// resweeping its inputs at the requested fee rate. This is only possible if
+// the transaction is a sweep of ours that signals replaceability, and all of
+// its inputs are still pending. If the transaction can't be replaced, nil is
+// returned.
+func (s *UtxoSweeper) handleBumpTxReq(req *bumpTxReq,
+	bestHeight int32) []chan Result {
+
+	tx := req.req.Tx
+	txid := tx.TxHash()
+
+	if !signalsReplacement(tx) {
+		log.Debugf("Tx %v doesn't signal replaceability", txid)
+		return nil
+	}
+
+	isOurTx, err := s.cfg.Store.IsOurTx(txid)
+	if err != nil {
+		log.Errorf("Cannot determine if tx %v is ours: %v", txid, err)
+		return nil
+	}
+	if !isOurTx {
+		return nil
+	}
+
+	for _, txIn := range tx.TxIn {
+		if _, ok := s.pendingInputs[txIn.PreviousOutPoint]; !ok {
+			log.Debugf("Input %v of tx %v no longer pending",
+				txIn.PreviousOutPoint, txid)
+			return nil
+		}
+	}
+
+	// All inputs are still ours to sweep. Raise their fee preference and
+	// make them eligible for immediate publication, so that the next sweep
+	// replaces the transaction.
+	results := make([]chan Result, 0, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		pendInput := s.pendingInputs[txIn.PreviousOutPoint]
+
+		pendInput.feePreference = FeePreference{
+			FeeRate: req.feeRate,
+			Urgency: req.req.FeePreference.Urgency,
+		}
+		pendInput.minPublishHeight = bestHeight
+
+		resultChan := make(chan Result, 1)
+		pendInput.listeners = append(pendInput.listeners, resultChan)
+		results = append(results, resultChan)
+	}
+
+	log.Infof("Replacing sweep tx %v at fee rate %v", txid, req.feeRate)
+
+	if err := s.scheduleSweep(bestHeight); err != nil {
+		log.Errorf("schedule sweep: %v", err)
+	}
+
+	return results
+}
+
+// signalsReplacement returns true if the transaction signals replaceability as
+// defined in BIP 125.
+func signalsReplacement(tx *wire.MsgTx) bool {
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,164 @@
+package sweep
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// ProjectedSweepTx describes a sweep transaction that the sweeper would
+// publish for a cluster if the batch timer of its lane expired now.
+type ProjectedSweepTx struct {
+	// Inputs are the outpoints that the transaction would spend.
+	Inputs []wire.OutPoint
+
+	// Weight is the estimated weight of the transaction.
+	Weight int64
+
+	// Fee is the fee that the transaction would pay at the fee rate of the
+	// cluster.
+	Fee btcutil.Amount
+}
+
+// ClusterReport describes a cluster of pending inputs that are swept together
+// at a common fee rate.
+type ClusterReport struct {
+	// Urgency is the urgency lane of the inputs in the cluster.
+	Urgency Urgency
+
+	// FeeRate is the fee rate at which the cluster is swept. This is the
+	// average of the fee rates of its inputs.
+	FeeRate lnwallet.SatPerKWeight
+
+	// Inputs are the outpoints of all inputs in the cluster, including the
+	// inputs that can't be published yet.
+	Inputs []wire.OutPoint
+
+	// ProjectedTxs are the sweep transactions that would be published for
+	// the cluster at the current height. Inputs that can't be published
+	// yet, or that are held back because they don't yield a sweep above
+	// the dust limit, aren't part of any projected transaction.
+	ProjectedTxs []ProjectedSweepTx
+
+	// NextPublishHeight is the lowest height at which an input of the
+	// cluster can be published. It is never below the current height.
+	NextPublishHeight int32
+
+	// TimerActive indicates whether the batch timer of the lane is
+	// running. If it is, the projected transactions are published once it
+	// expires.
+	TimerActive bool
+}
+
+// listClustersReq is a request to retrieve the current clusters of pending
+// inputs.
+type listClustersReq struct {
+	respChan chan []ClusterReport
+}
+
+// sortOutPoints sorts the given outpoints by hash and index, so that reports
+// are deterministic.
+func sortOutPoints(ops []wire.OutPoint) {
+	sort.Slice(ops, func(i, j int) bool {
+		cmp := bytes.Compare(ops[i].Hash[:], ops[j].Hash[:])
+		if cmp != 0 {
+			return cmp < 0
+		}
+		return ops[i].Index < ops[j].Index
+	})
+}
+
+// handleListClustersReq builds a report for every cluster of pending inputs,
+// ordered by urgency lane and then by descending fee rate, which is the order
+// in which the sweeper processes them.
+func (s *UtxoSweeper) handleListClustersReq(
+	bestHeight int32) []ClusterReport {
+
+	var reports []ClusterReport
+	for lane := Urgency(0); lane < numUrgencyLanes; lane++ {
+		clusters := s.clusterBySweepFeeRate(lane)
+		sort.Slice(clusters, func(i, j int) bool {
+			return clusters[i].sweepFeeRate >
+				clusters[j].sweepFeeRate
+		})
+
+		for _, cluster := range clusters {
+			report := ClusterReport{
+				Urgency:     lane,
+				FeeRate:     cluster.sweepFeeRate,
+				TimerActive: s.timers[lane] != nil,
+			}
+
+			for op, input := range cluster.inputs {
+				report.Inputs = append(report.Inputs, op)
+
+				height := input.minPublishHeight
+				if height < bestHeight {
+					height = bestHeight
+				}
+				if report.NextPublishHeight == 0 ||
+					height < report.NextPublishHeight {
+
+					report.NextPublishHeight = height
+				}
+			}
+			sortOutPoints(report.Inputs)
+
+			inputLists, err := s.getInputLists(cluster, bestHeight)
+			if err != nil {
+				log.Errorf("Unable to examine pending inputs: %v",
+					err)
+			}
+
+			for _, inputs := range inputLists {
+				sweepInputs, weight, _, _ := getWeightEstimate(
+					inputs,
+				)
+
+				feeRate := cluster.sweepFeeRate
+				tx := ProjectedSweepTx{
+					Weight: weight,
+					Fee:    feeRate.FeeForWeight(weight),
+				}
+				for _, inp := range sweepInputs {
+					tx.Inputs = append(
+						tx.Inputs, *inp.OutPoint(),
+					)
+				}
+				sortOutPoints(tx.Inputs)
+
+				report.ProjectedTxs = append(
+					report.ProjectedTxs, tx,
+				)
+			}
+
+			reports = append(reports, report)
+		}
+	}
+
+	return reports
+}
+
+// ListClusters returns a report of the current clusters of pending inputs,
+// including the sweep transactions that would be published for them at the
+// next expiry of the batch timer of their lane.
+func (s *UtxoSweeper) ListClusters() ([]ClusterReport, error) {
+	respChan := make(chan []ClusterReport, 1)
+	select {
+	case s.listClustersReqs <- &listClustersReq{
+		respChan: respChan,
+	}:
+	case <-s.quit:
+		return nil, ErrSweeperShuttingDown
+	}
+
+	select {
+	case reports := <-respChan:
+		return reports, nil
+	case <-s.quit:
+		return nil, ErrSweeperShuttingDown
+	}
+}
@@ -11,4 +11,14 @@ var (
 	// window. The sweep is held back during the batch window to allow more
 	// inputs to be added and thereby lower the fee per input.
 	DefaultBatchWindowDuration = 30 * time.Second
+
+	// DefaultCriticalBatchWindowDuration specifies the duration of the
+	// batch window of the critical urgency lane. It is kept short, so that
+	// critical inputs are swept without delay.
+	DefaultCriticalBatchWindowDuration = time.Second
+
+	// DefaultEconomicBatchWindowDuration specifies the duration of the
+	// batch window of the economic urgency lane. It is kept long, in order
+	// to batch as many inputs as possible.
+	DefaultEconomicBatchWindowDuration = 10 * time.Minute
 )
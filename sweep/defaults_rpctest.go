@@ -14,4 +14,14 @@ var (
 	// To speed up integration tests waiting for a sweep to happen, the
 	// batch window is shortened.
 	DefaultBatchWindowDuration = 2 * time.Second
+
+	// DefaultCriticalBatchWindowDuration specifies the duration of the
+	// batch window of the critical urgency lane. It is kept short, so that
+	// critical inputs are swept without delay.
+	DefaultCriticalBatchWindowDuration = time.Second
+
+	// DefaultEconomicBatchWindowDuration specifies the duration of the
+	// batch window of the economic urgency lane. It is kept long, in order
+	// to batch as many inputs as possible.
+	DefaultEconomicBatchWindowDuration = 4 * time.Second
 )
@@ -0,0 +1,49 @@
+package sweep
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcutil"
+)
+
+var (
+	// ErrOutputBelowMinimum is returned when a sweep would create an
+	// output with a value below the minimum that is configured for its
+	// destination type.
+	ErrOutputBelowMinimum = errors.New("sweep output below minimum amount")
+)
+
+// DestinationType describes the kind of script that a sweep transaction pays
+// to.
+type DestinationType uint8
+
+const (
+	// DestinationWallet indicates that the sweep output pays to a script
+	// that belongs to our own wallet.
+	DestinationWallet DestinationType = iota
+
+	// DestinationExternal indicates that the sweep output pays to an
+	// address that doesn't belong to our wallet.
+	DestinationExternal
+)
+
+// String returns a human readable representation of the destination type.
+func (d DestinationType) String() string {
+	switch d {
+	case DestinationWallet:
+		return "wallet"
+
+	case DestinationExternal:
+		return "external"
+
+	default:
+		return "unknown"
+	}
+}
+
+// MinOutputAmount returns the minimum value of a sweep output that pays to the
+// given destination type. Zero is returned if no minimum is configured, in
+// which case only the dust limit applies.
+func (s *UtxoSweeper) MinOutputAmount(dest DestinationType) btcutil.Amount {
+	return s.cfg.MinOutputAmounts[dest]
+}
@@ -0,0 +1,207 @@
+package sweep
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+// ExpectedOutput describes an output that is expected to appear on chain in
+// the future, but whose outpoint isn't known in advance. An example is the
+// output of a remote close that confirms late. Once an output with the script
+// and a value in the given range confirms, it is swept like any other input.
+type ExpectedOutput struct {
+	// PkScript is the script of the expected output.
+	PkScript []byte
+
+	// MinValue is the minimum value of the expected output.
+	MinValue btcutil.Amount
+
+	// MaxValue is the maximum value of the expected output. If zero, the
+	// value isn't bounded.
+	MaxValue btcutil.Amount
+
+	// WitnessType is the witness type that the output is swept with.
+	WitnessType input.WitnessType
+
+	// SignDesc describes how to sign for the output. Its Output field is
+	// set to the output that is found on chain.
+	SignDesc input.SignDescriptor
+
+	// HeightHint is the height at or before which the output can't have
+	// been created.
+	HeightHint uint32
+
+	// FeePreference is the fee preference that the output is swept with.
+	FeePreference FeePreference
+
+	// Metadata optionally describes where the output originates from.
+	Metadata *InputMetadata
+}
+
+// matches returns the index of the first output of the tx that matches the
+// expected output.
+func (e *ExpectedOutput) matches(tx *wire.MsgTx) (uint32, bool) {
+	for i, txOut := range tx.TxOut {
+		if !bytes.Equal(txOut.PkScript, e.PkScript) {
+			continue
+		}
+
+		value := btcutil.Amount(txOut.Value)
+		if value < e.MinValue ||
+			(e.MaxValue != 0 && value > e.MaxValue) {
+
+			continue
+		}
+
+		return uint32(i), true
+	}
+
+	return 0, false
+}
+
+// WatchForOutput registers an expected output with the sweeper. The sweeper
+// watches the chain for the first confirmed output that matches it, and
+// sweeps that output. The returned channel receives the result of the sweep.
+// Calling the returned cancel closure stops watching, unless the output was
+// already found.
+func (s *UtxoSweeper) WatchForOutput(out *ExpectedOutput) (chan Result,
+	func(), error) {
+
+	if len(out.PkScript) == 0 {
+		return nil, nil, errors.New("no script given for expected " +
+			"output")
+	}
+	if out.MaxValue != 0 && out.MaxValue < out.MinValue {
+		return nil, nil, fmt.Errorf("invalid value range %v-%v for "+
+			"expected output", out.MinValue, out.MaxValue)
+	}
+	if _, err := s.feeRateForPreference(out.FeePreference); err != nil {
+		return nil, nil, err
+	}
+
+	// Without a txid, the notifier dispatches the confirmation of the
+	// first tx that pays to the script.
+	confEvent, err := s.cfg.Notifier.RegisterConfirmationsNtfn(
+		nil, out.PkScript, 1, out.HeightHint,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("register conf ntfn: %v", err)
+	}
+
+	log.Infof("Watching for expected output with script %x and value "+
+		"%v-%v", out.PkScript, out.MinValue, out.MaxValue)
+
+	resultChan := make(chan Result, 1)
+	cancelChan := make(chan struct{})
+
+	s.wg.Add(1)
+	go s.watchExpectedOutput(out, confEvent, resultChan, cancelChan)
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(cancelChan)
+		})
+	}
+
+	return resultChan, cancel, nil
+}
+
+// watchExpectedOutput waits for the expected output to confirm and offers it
+// to the sweeper once it does. If the script confirms in a tx without a
+// matching output, watching continues from the next block.
+//
+// NOTE: This MUST be run as a goroutine.
+func (s *UtxoSweeper) watchExpectedOutput(out *ExpectedOutput,
+	confEvent *chainntnfs.ConfirmationEvent, resultChan chan Result,
+	cancelChan <-chan struct{}) {
+
+	defer s.wg.Done()
+
+	for {
+		var conf *chainntnfs.TxConfirmation
+		select {
+		case c, ok := <-confEvent.Confirmed:
+			if !ok {
+				log.Debugf("Conf ntfn for expected output %x "+
+					"canceled", out.PkScript)
+				return
+			}
+			conf = c
+
+		case <-cancelChan:
+			confEvent.Cancel()
+			return
+
+		case <-s.quit:
+			return
+		}
+
+		index, ok := out.matches(conf.Tx)
+		if ok {
+			s.sweepExpectedOutput(out, conf, index, resultChan)
+			return
+		}
+
+		log.Debugf("Tx %v at height %v pays to expected output script "+
+			"%x, but no output matches", conf.Tx.TxHash(),
+			conf.BlockHeight, out.PkScript)
+
+		confEvent.Cancel()
+
+		var err error
+		confEvent, err = s.cfg.Notifier.RegisterConfirmationsNtfn(
+			nil, out.PkScript, 1, conf.BlockHeight+1,
+		)
+		if err != nil {
+			resultChan <- Result{
+				Err: fmt.Errorf("register conf ntfn: %v", err),
+			}
+			return
+		}
+	}
+}
+
+// sweepExpectedOutput offers the output of the tx at the given index to the
+// sweeper and forwards the result of the sweep.
+func (s *UtxoSweeper) sweepExpectedOutput(out *ExpectedOutput,
+	conf *chainntnfs.TxConfirmation, index uint32,
+	resultChan chan Result) {
+
+	outpoint := wire.OutPoint{
+		Hash:  conf.Tx.TxHash(),
+		Index: index,
+	}
+
+	log.Infof("Expected output %v of %v confirmed at height %v", outpoint,
+		btcutil.Amount(conf.Tx.TxOut[index].Value), conf.BlockHeight)
+
+	signDesc := out.SignDesc
+	signDesc.Output = conf.Tx.TxOut[index]
+
+	inp := input.NewBaseInput(
+		&outpoint, out.WitnessType, &signDesc, conf.BlockHeight,
+	)
+
+	sweepResult, err := s.SweepInputWithMetadata(
+		inp, out.FeePreference, out.Metadata,
+	)
+	if err != nil {
+		resultChan <- Result{Err: err}
+		return
+	}
+
+	select {
+	case result := <-sweepResult:
+		resultChan <- result
+
+	case <-s.quit:
+	}
+}
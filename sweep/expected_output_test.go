@@ -0,0 +1,209 @@
+package sweep
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+var expectedOutputScript = []byte{0x00, 0x14, 0x01, 0x02, 0x03}
+
+// newTestExpectedOutput returns an expected output with a minimum value of
+// 5000 sat.
+func newTestExpectedOutput() *ExpectedOutput {
+	return &ExpectedOutput{
+		PkScript:    expectedOutputScript,
+		MinValue:    5000,
+		WitnessType: input.CommitmentTimeLock,
+		SignDesc: input.SignDescriptor{
+			KeyDesc: keychain.KeyDescriptor{
+				PubKey: testPubKey,
+			},
+		},
+		FeePreference: defaultFeePref,
+	}
+}
+
+// newExpectedOutputTx returns a tx that pays the given value to the expected
+// output script in its second output.
+func newExpectedOutputTx(value int64) *wire.MsgTx {
+	prevOut := wire.OutPoint{Index: uint32(value)}
+
+	return &wire.MsgTx{
+		TxIn: []*wire.TxIn{
+			{
+				PreviousOutPoint: prevOut,
+			},
+		},
+		TxOut: []*wire.TxOut{
+			{Value: 1000, PkScript: []byte{0x01}},
+			{Value: value, PkScript: expectedOutputScript},
+		},
+	}
+}
+
+// waitForScriptRegistrations waits until the given number of confirmation
+// registrations for the expected output script are pending.
+func waitForScriptRegistrations(t *testing.T, notifier *MockNotifier,
+	num int) {
+
+	t.Helper()
+
+	timeout := time.After(defaultTestTimeout)
+	for notifier.NumScriptRegistrations(expectedOutputScript) != num {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("expected %v registrations, got %v", num,
+				notifier.NumScriptRegistrations(
+					expectedOutputScript,
+				))
+		}
+	}
+}
+
+// assertExpectedOutputSweep asserts that the sweeper sweeps the output of the
+// given tx that pays to the expected output script, and that the result is
+// forwarded on the result channel.
+func assertExpectedOutputSweep(ctx *sweeperTestContext, tx *wire.MsgTx,
+	resultChan chan Result) {
+
+	ctx.t.Helper()
+
+	ctx.tick()
+	sweepTx := ctx.receiveTx()
+
+	expected := wire.OutPoint{Hash: tx.TxHash(), Index: 1}
+	if len(sweepTx.TxIn) != 1 ||
+		sweepTx.TxIn[0].PreviousOutPoint != expected {
+
+		ctx.t.Fatalf("expected sweep of %v, got %v", expected,
+			sweepTx.TxIn)
+	}
+
+	ctx.backend.mine()
+	ctx.expectResult(resultChan, nil)
+}
+
+// TestWatchForOutputMatch asserts that an expected output is swept once it
+// confirms.
+func TestWatchForOutputMatch(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	resultChan, _, err := ctx.sweeper.WatchForOutput(
+		newTestExpectedOutput(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := newExpectedOutputTx(10000)
+	if err := ctx.notifier.ConfirmScript(
+		expectedOutputScript, tx, 101,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExpectedOutputSweep(ctx, tx, resultChan)
+
+	ctx.finish(1)
+}
+
+// TestWatchForOutputValueMismatch asserts that a tx paying to the expected
+// script with a value out of range is skipped, and that watching continues
+// with a new registration.
+func TestWatchForOutputValueMismatch(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	resultChan, _, err := ctx.sweeper.WatchForOutput(
+		newTestExpectedOutput(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctx.notifier.ConfirmScript(
+		expectedOutputScript, newExpectedOutputTx(1000), 101,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// The output is too small, so nothing is swept and the script is
+	// registered again.
+	waitForScriptRegistrations(t, ctx.notifier, 1)
+	ctx.assertNoTx()
+
+	tx := newExpectedOutputTx(6000)
+	if err := ctx.notifier.ConfirmScript(
+		expectedOutputScript, tx, 102,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	assertExpectedOutputSweep(ctx, tx, resultChan)
+
+	ctx.finish(1)
+}
+
+// TestWatchForOutputCancel asserts that canceling the watch removes the
+// confirmation registration without a result.
+func TestWatchForOutputCancel(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	resultChan, cancel, err := ctx.sweeper.WatchForOutput(
+		newTestExpectedOutput(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForScriptRegistrations(t, ctx.notifier, 1)
+
+	cancel()
+
+	// Canceling twice is harmless.
+	cancel()
+
+	waitForScriptRegistrations(t, ctx.notifier, 0)
+
+	select {
+	case result := <-resultChan:
+		t.Fatalf("unexpected result: %v", result)
+	default:
+	}
+
+	ctx.finish(1)
+}
+
+// TestWatchForOutputQuit asserts that a pending watch doesn't prevent the
+// sweeper from stopping.
+func TestWatchForOutputQuit(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	resultChan, _, err := ctx.sweeper.WatchForOutput(
+		newTestExpectedOutput(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		ctx.sweeper.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(defaultTestTimeout):
+		t.Fatal("sweeper not stopped")
+	}
+
+	select {
+	case result := <-resultChan:
+		t.Fatalf("unexpected result: %v", result)
+	default:
+	}
+}
@@ -0,0 +1,180 @@
+package sweep
+
+import (
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// maxFeeCalibrationRecords is the maximum number of published sweeps that we
+// keep fee records for. Once this limit is reached, the oldest record is
+// dropped.
+const maxFeeCalibrationRecords = 100
+
+// SweepFeeRecord compares the fee rate that was projected for a published
+// sweep transaction with the fee rate that it actually achieved.
+type SweepFeeRecord struct {
+	// TxID is the hash of the sweep transaction.
+	TxID chainhash.Hash
+
+	// ProjectedFeeRate is the fee rate that was returned by the fee
+	// estimator and targeted when creating the sweep.
+	ProjectedFeeRate lnwallet.SatPerKWeight
+
+	// ActualFeeRate is the fee rate that the signed transaction pays,
+	// based on its actual weight.
+	ActualFeeRate lnwallet.SatPerKWeight
+
+	// EstimatedWeight is the weight of the transaction as estimated before
+	// signing. Witness sizes are estimated using an upper bound, so this
+	// is expected to be greater than or equal to the actual weight.
+	EstimatedWeight int64
+
+	// ActualWeight is the weight of the signed transaction.
+	ActualWeight int64
+
+	// Fee is the absolute fee paid by the transaction.
+	Fee btcutil.Amount
+
+	// InputMetadata holds the metadata of the inputs of the transaction
+	// for which it is known, so that the sweep can be correlated with the
+	// channels that the inputs originate from.
+	InputMetadata []InputMetadata
+}
+
+// FeeCalibrationReport summarizes the fee records of recently published
+// sweeps, so that a consistently biased fee estimate can be detected.
+type FeeCalibrationReport struct {
+	// Records contains the fee records of recent sweeps, ordered from
+	// oldest to newest.
+	Records []SweepFeeRecord
+
+	// MeanRatio is the average ratio of the actual fee rate to the
+	// projected fee rate. A value that is consistently above one indicates
+	// that we are overpaying relative to the estimate.
+	MeanRatio float64
+
+	// MinRatio is the lowest observed ratio of actual to projected fee
+	// rate.
+	MinRatio float64
+
+	// MaxRatio is the highest observed ratio of actual to projected fee
+	// rate.
+	MaxRatio float64
+}
+
+// feeCalibrationReq is a request to retrieve the fee calibration report.
+type feeCalibrationReq struct {
+	respChan chan *FeeCalibrationReport
+}
+
+// newSweepFeeRecord creates a fee record for the given signed sweep
+// transaction, which spends the passed inputs.
+func newSweepFeeRecord(tx *wire.MsgTx, inputs inputSet,
+	projectedFeeRate lnwallet.SatPerKWeight) SweepFeeRecord {
+
+	var inputTotal btcutil.Amount
+	for _, inp := range inputs {
+		inputTotal += btcutil.Amount(inp.SignDesc().Output.Value)
+	}
+
+	var outputTotal btcutil.Amount
+	for _, txOut := range tx.TxOut {
+		outputTotal += btcutil.Amount(txOut.Value)
+	}
+
+	_, estimatedWeight, _, _ := getWeightEstimate(inputs)
+	actualWeight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+
+	fee := inputTotal - outputTotal
+
+	var actualFeeRate lnwallet.SatPerKWeight
+	if actualWeight > 0 {
+		actualFeeRate = lnwallet.SatPerKWeight(
+			int64(fee) * 1000 / actualWeight,
+		)
+	}
+
+	return SweepFeeRecord{
+		TxID:             tx.TxHash(),
+		ProjectedFeeRate: projectedFeeRate,
+		ActualFeeRate:    actualFeeRate,
+		EstimatedWeight:  estimatedWeight,
+		ActualWeight:     actualWeight,
+		Fee:              fee,
+	}
+}
+
+// recordSweepFee adds a fee record for a published sweep, dropping the oldest
+// record if the limit is reached.
+func (s *UtxoSweeper) recordSweepFee(record SweepFeeRecord) {
+	log.Debugf("Sweep tx %v projected fee rate %v, actual fee rate %v "+
+		"(estimated weight %v, actual weight %v)", record.TxID,
+		record.ProjectedFeeRate, record.ActualFeeRate,
+		record.EstimatedWeight, record.ActualWeight)
+
+	if len(s.feeRecords) >= maxFeeCalibrationRecords {
+		s.feeRecords = s.feeRecords[1:]
+	}
+	s.feeRecords = append(s.feeRecords, record)
+}
+
+// handleFeeCalibrationReq builds the fee calibration report from the current
+// set of fee records.
+func (s *UtxoSweeper) handleFeeCalibrationReq() *FeeCalibrationReport {
+	report := &FeeCalibrationReport{
+		Records: make([]SweepFeeRecord, len(s.feeRecords)),
+	}
+	copy(report.Records, s.feeRecords)
+
+	var (
+		totalRatio float64
+		count      int
+	)
+	for _, record := range s.feeRecords {
+		if record.ProjectedFeeRate == 0 {
+			continue
+		}
+
+		ratio := float64(record.ActualFeeRate) /
+			float64(record.ProjectedFeeRate)
+
+		if count == 0 || ratio < report.MinRatio {
+			report.MinRatio = ratio
+		}
+		if count == 0 || ratio > report.MaxRatio {
+			report.MaxRatio = ratio
+		}
+
+		totalRatio += ratio
+		count++
+	}
+
+	if count > 0 {
+		report.MeanRatio = totalRatio / float64(count)
+	}
+
+	return report
+}
+
+// FeeCalibrationReport returns a report comparing the projected and actual
+// fee rates of recently published sweep transactions.
+func (s *UtxoSweeper) FeeCalibrationReport() (*FeeCalibrationReport, error) {
+	respChan := make(chan *FeeCalibrationReport, 1)
+	select {
+	case s.feeCalibrationReqs <- &feeCalibrationReq{
+		respChan: respChan,
+	}:
+	case <-s.quit:
+		return nil, ErrSweeperShuttingDown
+	}
+
+	select {
+	case report := <-respChan:
+		return report, nil
+	case <-s.quit:
+		return nil, ErrSweeperShuttingDown
+	}
+}
@@ -0,0 +1,227 @@
+package sweep
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// TestHarness runs a UtxoSweeper against a mock chain backend, notifier, fee
+// estimator and store. Block height and batch timers only advance when the
+// test calls MineBlock and FireBatchTimer, so that the timing and fee
+// escalation of sweeps can be tested deterministically. This type is exported
+// so that packages that embed the sweeper can use it in their tests.
+type TestHarness struct {
+	// Sweeper is the sweeper under test. It is started by NewTestHarness.
+	Sweeper *UtxoSweeper
+
+	// Notifier delivers block epochs and spends to the sweeper.
+	Notifier *MockNotifier
+
+	// Store is the store of the sweeper.
+	Store *MockSweeperStore
+
+	t         *testing.T
+	estimator *mockFeeEstimator
+	backend   *mockBackend
+	height    int32
+
+	timers    chan chan time.Time
+	published chan wire.MsgTx
+}
+
+// NewTestHarness creates and starts a sweeper on a mock chain at height
+// mockChainIOHeight. The fee estimator initially returns a fee rate of 10000
+// sat/kw for all confirmation targets. Retries are scheduled without random
+// delay, after 2^(attempts-1) blocks. The optional modify callback may adjust
+// the sweeper config before the sweeper is created.
+func NewTestHarness(t *testing.T,
+	modify func(*UtxoSweeperConfig)) *TestHarness {
+
+	notifier := NewMockNotifier(t)
+	h := &TestHarness{
+		Notifier:  notifier,
+		Store:     NewMockSweeperStore(),
+		t:         t,
+		estimator: newMockFeeEstimator(10000, lnwallet.FeePerKwFloor),
+		backend:   newMockBackend(notifier),
+		height:    mockChainIOHeight,
+		timers:    make(chan chan time.Time, 1),
+		published: make(chan wire.MsgTx, 10),
+	}
+
+	var outputScriptCount byte
+	cfg := &UtxoSweeperConfig{
+		Notifier: notifier,
+		PublishTransaction: func(tx *wire.MsgTx) error {
+			err := h.backend.publishTransaction(tx)
+			select {
+			case h.published <- *tx:
+			case <-time.After(defaultTestTimeout):
+				t.Fatalf("published tx not consumed")
+			}
+			return err
+		},
+		NewBatchTimer: func() <-chan time.Time {
+			c := make(chan time.Time, 1)
+			h.timers <- c
+			return c
+		},
+		Store:   h.Store,
+		Signer:  &mockSigner{},
+		ChainIO: &mockChainIO{},
+		GenSweepScript: func() ([]byte, error) {
+			script := []byte{outputScriptCount}
+			outputScriptCount++
+			return script, nil
+		},
+		FeeEstimator:     h.estimator,
+		MaxInputsPerTx:   DefaultMaxInputsPerTx,
+		MaxSweepAttempts: DefaultMaxSweepAttempts,
+		NextAttemptDeltaFunc: func(attempts int) int32 {
+			return 1 << uint(attempts-1)
+		},
+		MaxFeeRate:        DefaultMaxFeeRate,
+		FeeRateBucketSize: DefaultFeeRateBucketSize,
+	}
+	if modify != nil {
+		modify(cfg)
+	}
+
+	h.Sweeper = New(cfg)
+	if err := h.Sweeper.Start(); err != nil {
+		t.Fatalf("unable to start sweeper: %v", err)
+	}
+
+	return h
+}
+
+// Stop stops the sweeper.
+func (h *TestHarness) Stop() {
+	if err := h.Sweeper.Stop(); err != nil {
+		h.t.Fatalf("unable to stop sweeper: %v", err)
+	}
+}
+
+// Height returns the current height of the mock chain.
+func (h *TestHarness) Height() int32 {
+	return h.height
+}
+
+// SetFeeRate sets the fee rate that the fee estimator returns for all
+// confirmation targets without a specific fee rate.
+func (h *TestHarness) SetFeeRate(feeRate lnwallet.SatPerKWeight) {
+	h.estimator.updateFees(feeRate, lnwallet.FeePerKwFloor)
+}
+
+// SetFeeRateForTarget sets the fee rate that the fee estimator returns for
+// the given confirmation target.
+func (h *TestHarness) SetFeeRateForTarget(confTarget uint32,
+	feeRate lnwallet.SatPerKWeight) {
+
+	h.estimator.lock.Lock()
+	defer h.estimator.lock.Unlock()
+
+	h.estimator.blocksToFee[confTarget] = feeRate
+}
+
+// FireBatchTimer expires the batch timer that the sweeper started most
+// recently, which makes it sweep the pending inputs that are ready to be
+// published.
+func (h *TestHarness) FireBatchTimer() {
+	h.t.Helper()
+
+	select {
+	case c := <-h.timers:
+		select {
+		case c <- time.Time{}:
+		case <-time.After(defaultTestTimeout):
+			h.t.Fatalf("batch timer not consumed")
+		}
+
+	case <-time.After(defaultTestTimeout):
+		h.t.Fatalf("no batch timer started")
+	}
+}
+
+// AssertNoBatchTimer asserts that the sweeper didn't start a batch timer.
+func (h *TestHarness) AssertNoBatchTimer() {
+	h.t.Helper()
+
+	select {
+	case <-h.timers:
+		h.t.Fatalf("unexpected batch timer started")
+	default:
+	}
+}
+
+// ReceiveTx returns the next tx that the sweeper published.
+func (h *TestHarness) ReceiveTx() *wire.MsgTx {
+	h.t.Helper()
+
+	select {
+	case tx := <-h.published:
+		return &tx
+
+	case <-time.After(defaultTestTimeout):
+		h.t.Fatalf("no tx published")
+		return nil
+	}
+}
+
+// AssertNoTx asserts that the sweeper didn't publish a tx.
+func (h *TestHarness) AssertNoTx() {
+	h.t.Helper()
+
+	select {
+	case tx := <-h.published:
+		h.t.Fatalf("unexpected tx %v published", tx.TxHash())
+	default:
+	}
+}
+
+// PublishTx publishes a tx that wasn't created by the sweeper, such as a spend
+// of one of the inputs by a remote party. It fails if the tx conflicts with
+// an unconfirmed or confirmed tx.
+func (h *TestHarness) PublishTx(tx *wire.MsgTx) error {
+	return h.backend.publishTransaction(tx)
+}
+
+// EvictTx removes the unconfirmed tx with the given hash from the mempool of
+// the mock chain, for example to simulate that it was replaced or that it
+// expired.
+func (h *TestHarness) EvictTx(hash chainhash.Hash) {
+	h.backend.deleteUnconfirmed(hash)
+}
+
+// MineBlock confirms all unconfirmed txes and notifies the sweeper of the
+// spends and the new block.
+func (h *TestHarness) MineBlock() {
+	h.t.Helper()
+
+	h.backend.mine()
+	h.height++
+	h.Notifier.NotifyEpoch(h.height)
+}
+
+// ExpectResult waits for the sweep result of an input and asserts that it
+// carries the expected error.
+func (h *TestHarness) ExpectResult(c chan Result, expected error) Result {
+	h.t.Helper()
+
+	select {
+	case result := <-c:
+		if result.Err != expected {
+			h.t.Fatalf("expected result error %v, got %v",
+				expected, result.Err)
+		}
+		return result
+
+	case <-time.After(defaultTestTimeout):
+		h.t.Fatalf("no result received")
+		return Result{}
+	}
+}
@@ -0,0 +1,53 @@
+package sweep
+
+import (
+	"testing"
+)
+
+// TestHarnessRetry asserts that the test harness lets callers control the
+// block height and batch timers of the sweeper, by walking an input through a
+// failed and a successful sweep attempt.
+func TestHarnessRetry(t *testing.T) {
+	h := NewTestHarness(t, nil)
+	defer h.Stop()
+
+	input := spendableInputs[0]
+	resultChan, err := h.Sweeper.SweepInput(input, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.FireBatchTimer()
+	sweepTx := h.ReceiveTx()
+
+	// The sweep tx drops out of the mempool. No new attempt is made
+	// before the next block.
+	h.EvictTx(sweepTx.TxHash())
+	h.AssertNoBatchTimer()
+	h.AssertNoTx()
+
+	h.MineBlock()
+	if h.Height() != mockChainIOHeight+1 {
+		t.Fatalf("expected height %v, got %v", mockChainIOHeight+1,
+			h.Height())
+	}
+
+	h.FireBatchTimer()
+	h.ReceiveTx()
+
+	pendingInputs, err := h.Sweeper.PendingInputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingInput, ok := pendingInputs[*input.OutPoint()]
+	if !ok {
+		t.Fatalf("input %v not pending", input.OutPoint())
+	}
+	if pendingInput.BroadcastAttempts != 2 {
+		t.Fatalf("expected 2 broadcast attempts, got %v",
+			pendingInput.BroadcastAttempts)
+	}
+
+	h.MineBlock()
+	h.ExpectResult(resultChan, nil)
+}
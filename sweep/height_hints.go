@@ -0,0 +1,62 @@
+package sweep
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+// heightHintSafetyDepth is the number of blocks by which the persisted spend
+// height hints trail the best height. This makes sure that a spend that was
+// reorged out, or whose notification wasn't processed before a shutdown, is
+// still found when the spend notification is registered again after a
+// restart.
+const heightHintSafetyDepth = 6
+
+// spendHeightHint returns the height hint with which the spend notification of
+// the input is registered. If a height hint was persisted for the input before,
+// for example prior to a restart, and it is tighter than the hint of the input
+// itself, the persisted hint is used. This avoids a long rescan of the chain
+// backend for inputs that have been pending for a long time.
+func (s *UtxoSweeper) spendHeightHint(inp input.Input) uint32 {
+	heightHint := inp.HeightHint()
+
+	stored, err := s.cfg.Store.FetchHeightHint(*inp.OutPoint())
+	if err != nil {
+		log.Errorf("Unable to fetch height hint of input %v: %v",
+			inp.OutPoint(), err)
+
+		return heightHint
+	}
+
+	if stored > heightHint {
+		log.Debugf("Using stored height hint %v for input %v "+
+			"(input hint=%v)", stored, inp.OutPoint(), heightHint)
+
+		return stored
+	}
+
+	return heightHint
+}
+
+// updateHeightHints persists a spend height hint for all pending inputs, based
+// on the new best height. The spend notifications of the inputs are active, so
+// none of them can have been spent in the blocks below the hint.
+func (s *UtxoSweeper) updateHeightHints(bestHeight int32) {
+	if bestHeight <= heightHintSafetyDepth || len(s.pendingInputs) == 0 {
+		return
+	}
+
+	ops := make([]wire.OutPoint, 0, len(s.pendingInputs))
+	for op, pendInput := range s.pendingInputs {
+		if pendInput.ntfnRegCancel == nil {
+			continue
+		}
+
+		ops = append(ops, op)
+	}
+
+	heightHint := uint32(bestHeight - heightHintSafetyDepth)
+	if err := s.cfg.Store.UpdateHeightHints(ops, heightHint); err != nil {
+		log.Errorf("Unable to update height hints: %v", err)
+	}
+}
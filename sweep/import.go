@@ -0,0 +1,128 @@
+package sweep
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+var (
+	// ErrUnsupportedImport is returned when an external output is imported
+	// with a witness type that can't be swept with only a key descriptor.
+	ErrUnsupportedImport = errors.New("witness type not supported for " +
+		"import")
+
+	// ErrImportScriptMismatch is returned when the pkScript of an imported
+	// output doesn't match the key that it is supposed to be swept with.
+	ErrImportScriptMismatch = errors.New("pkScript doesn't match key " +
+		"of imported output")
+)
+
+// ExternalOutput describes an output that was discovered outside of the
+// regular channel life cycle, for example an output of a force closed channel
+// that is recovered from a static channel backup, and that should be swept
+// back into the wallet.
+type ExternalOutput struct {
+	// OutPoint is the outpoint of the output.
+	OutPoint wire.OutPoint
+
+	// PkScript is the script of the output.
+	PkScript []byte
+
+	// Value is the value of the output. It must match the value on chain,
+	// as it is committed to by the signature of the sweep transaction.
+	Value btcutil.Amount
+
+	// KeyDesc describes the key that the output pays to. If the public key
+	// isn't set, it is derived from the key locator.
+	KeyDesc keychain.KeyDescriptor
+
+	// SingleTweak is the tweak that is applied to the key, if any.
+	SingleTweak []byte
+
+	// WitnessType is the witness type of the output. Only
+	// input.CommitmentNoDelay is currently supported.
+	WitnessType input.WitnessType
+
+	// HeightHint is the height at or before which the output was created.
+	HeightHint uint32
+
+	// ChanPoint optionally identifies the channel that the output
+	// originates from.
+	ChanPoint *wire.OutPoint
+}
+
+// ImportExternalOutput registers an externally discovered output with the
+// sweeper. The output is swept in the same way as inputs that are offered by
+// the other subsystems, batched with them and subject to the same fee
+// machinery. The returned channel receives the result of the sweep.
+func (s *UtxoSweeper) ImportExternalOutput(out *ExternalOutput,
+	feePreference FeePreference) (chan Result, error) {
+
+	if out.WitnessType != input.CommitmentNoDelay {
+		return nil, ErrUnsupportedImport
+	}
+
+	keyDesc := out.KeyDesc
+	if keyDesc.PubKey == nil {
+		if s.cfg.KeyRing == nil {
+			return nil, errors.New("no public key given and no " +
+				"key ring to derive it")
+		}
+
+		var err error
+		keyDesc, err = s.cfg.KeyRing.DeriveKey(keyDesc.KeyLocator)
+		if err != nil {
+			return nil, fmt.Errorf("unable to derive key %v/%v: %v",
+				keyDesc.Family, keyDesc.Index, err)
+		}
+	}
+
+	// Make sure that the key actually controls the output before handing
+	// it to the sweeper, as a sweep transaction spending it would
+	// otherwise be rejected over and over.
+	pubKey := keyDesc.PubKey
+	if len(out.SingleTweak) > 0 {
+		pubKey = input.TweakPubKeyWithTweak(pubKey, out.SingleTweak)
+	}
+	expectedScript, err := input.CommitScriptUnencumbered(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(expectedScript, out.PkScript) {
+		return nil, ErrImportScriptMismatch
+	}
+
+	signDesc := &input.SignDescriptor{
+		KeyDesc:     keyDesc,
+		SingleTweak: out.SingleTweak,
+		Output: &wire.TxOut{
+			Value:    int64(out.Value),
+			PkScript: out.PkScript,
+		},
+		HashType: txscript.SigHashAll,
+	}
+
+	outpoint := out.OutPoint
+	inp := input.NewBaseInput(
+		&outpoint, out.WitnessType, signDesc, out.HeightHint,
+	)
+
+	metadata := &InputMetadata{
+		Reason: SweepReasonForWitnessType(out.WitnessType),
+	}
+	if out.ChanPoint != nil {
+		metadata.ChanPoint = *out.ChanPoint
+	}
+
+	log.Infof("Importing external output %v of %v for sweeping",
+		outpoint, out.Value)
+
+	return s.SweepInputWithMetadata(inp, feePreference, metadata)
+}
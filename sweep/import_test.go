@@ -0,0 +1,121 @@
+package sweep
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+// mockKeyRing derives testPubKey for every key locator.
+type mockKeyRing struct{}
+
+var _ keychain.KeyRing = (*mockKeyRing)(nil)
+
+func (m *mockKeyRing) DeriveNextKey(
+	keyFam keychain.KeyFamily) (keychain.KeyDescriptor, error) {
+
+	return keychain.KeyDescriptor{}, errors.New("not implemented")
+}
+
+func (m *mockKeyRing) DeriveKey(
+	keyLoc keychain.KeyLocator) (keychain.KeyDescriptor, error) {
+
+	return keychain.KeyDescriptor{
+		KeyLocator: keyLoc,
+		PubKey:     testPubKey,
+	}, nil
+}
+
+// TestImportExternalOutput asserts that externally discovered outputs can be
+// imported into the sweeper, and that outputs that can't be swept with the
+// given key are rejected.
+func TestImportExternalOutput(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+	ctx.sweeper.cfg.KeyRing = &mockKeyRing{}
+
+	pkScript, err := input.CommitScriptUnencumbered(testPubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chanPoint := wire.OutPoint{Index: 1}
+	out := &ExternalOutput{
+		OutPoint: wire.OutPoint{
+			Hash: chainhash.Hash{1},
+		},
+		PkScript: pkScript,
+		Value:    50000,
+		KeyDesc: keychain.KeyDescriptor{
+			KeyLocator: keychain.KeyLocator{
+				Family: keychain.KeyFamilyPaymentBase,
+				Index:  7,
+			},
+		},
+		WitnessType: input.CommitmentNoDelay,
+		ChanPoint:   &chanPoint,
+	}
+
+	// Outputs that require more than a key to be swept can't be imported.
+	unsupported := *out
+	unsupported.WitnessType = input.CommitmentTimeLock
+	_, err = ctx.sweeper.ImportExternalOutput(&unsupported, defaultFeePref)
+	if err != ErrUnsupportedImport {
+		t.Fatalf("expected ErrUnsupportedImport, got %v", err)
+	}
+
+	// An output that doesn't pay to the derived key is rejected.
+	mismatch := *out
+	mismatch.SingleTweak = []byte{1}
+	_, err = ctx.sweeper.ImportExternalOutput(&mismatch, defaultFeePref)
+	if err != ErrImportScriptMismatch {
+		t.Fatalf("expected ErrImportScriptMismatch, got %v", err)
+	}
+
+	resultChan, err := ctx.sweeper.ImportExternalOutput(out, defaultFeePref)
+	if err != nil {
+		t.Fatalf("unable to import output: %v", err)
+	}
+
+	pendingInputs, err := ctx.sweeper.PendingInputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingInput, ok := pendingInputs[out.OutPoint]
+	if !ok {
+		t.Fatalf("imported output not pending")
+	}
+	if pendingInput.Metadata == nil ||
+		pendingInput.Metadata.ChanPoint != chanPoint ||
+		pendingInput.Metadata.Reason != SweepReasonCommitment {
+
+		t.Fatalf("unexpected metadata %v", pendingInput.Metadata)
+	}
+
+	ctx.tick()
+
+	sweepTx := ctx.receiveTx()
+	if len(sweepTx.TxIn) != 1 ||
+		sweepTx.TxIn[0].PreviousOutPoint != out.OutPoint {
+
+		t.Fatalf("expected sweep of imported output")
+	}
+
+	ctx.backend.mine()
+
+	select {
+	case result := <-resultChan:
+		if result.Err != nil {
+			t.Fatalf("expected successful spend, but received "+
+				"error %v instead", result.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no result received")
+	}
+
+	ctx.finish(1)
+}
@@ -0,0 +1,179 @@
+package sweep
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// justiceHoldBlocks is the number of blocks during which the sweeper
+	// refrains from sweeping an input that a registered justice tx
+	// spends, counted from the registration of the tx or the arrival of
+	// the input, whichever is later. If the justice tx doesn't confirm in
+	// time, possibly because it was never published, the input is swept
+	// as usual.
+	justiceHoldBlocks = 6
+
+	// maxJusticeTxs is the maximum number of justice transactions that
+	// are registered at the same time. Once reached, the oldest
+	// registration is dropped.
+	maxJusticeTxs = 10000
+)
+
+// JusticeRegistry is implemented by the sweeper to let a watchtower client
+// inform it about the justice transactions that towers may publish on our
+// behalf. A spend of a pending input by a registered justice tx is treated as
+// a sweep by proxy, rather than as ErrRemoteSpend, and the sweeper holds back
+// its own sweeps of the inputs for a while instead of competing with the
+// tower.
+//
+// The registrations are only held in memory, and are lost on restart. Justice
+// txs of backups that were sent before the restart aren't recognized anymore,
+// so a spend by one of them is reported as ErrRemoteSpend, and the sweeper
+// sweeps the inputs without holding back. This is safe, as either the sweep or
+// the justice tx confirms, and the funds end up with us in both cases.
+type JusticeRegistry interface {
+	// RegisterJusticeTx registers a justice tx that may be published for
+	// the inputs that it spends. The tx doesn't need to be signed, as its
+	// hash doesn't commit to the witnesses.
+	RegisterJusticeTx(tx *wire.MsgTx) error
+}
+
+// A compile-time assertion to ensure that UtxoSweeper implements the
+// JusticeRegistry interface.
+var _ JusticeRegistry = (*UtxoSweeper)(nil)
+
+// justiceTx is the registration of a justice tx.
+type justiceTx struct {
+	hash     chainhash.Hash
+	inputs   []wire.OutPoint
+	register chan error
+}
+
+// justiceTxs holds the registered justice transactions, indexed by the
+// inputs that they spend.
+type justiceTxs struct {
+	txs     map[chainhash.Hash]*justiceTx
+	byInput map[wire.OutPoint]*justiceTx
+	order   []chainhash.Hash
+}
+
+// newJusticeTxs returns an empty set of justice transactions.
+func newJusticeTxs() *justiceTxs {
+	return &justiceTxs{
+		txs:     make(map[chainhash.Hash]*justiceTx),
+		byInput: make(map[wire.OutPoint]*justiceTx),
+	}
+}
+
+// add registers the justice tx, dropping the oldest registration if the limit
+// is reached.
+func (j *justiceTxs) add(tx *justiceTx) {
+	if _, ok := j.txs[tx.hash]; ok {
+		return
+	}
+
+	if len(j.order) >= maxJusticeTxs {
+		j.remove(j.order[0])
+	}
+
+	j.txs[tx.hash] = tx
+	j.order = append(j.order, tx.hash)
+	for _, op := range tx.inputs {
+		j.byInput[op] = tx
+	}
+}
+
+// remove drops the registration of the justice tx with the given hash.
+func (j *justiceTxs) remove(hash chainhash.Hash) {
+	tx, ok := j.txs[hash]
+	if !ok {
+		return
+	}
+
+	delete(j.txs, hash)
+	for _, op := range tx.inputs {
+		if j.byInput[op] == tx {
+			delete(j.byInput, op)
+		}
+	}
+	for i, h := range j.order {
+		if h == hash {
+			j.order = append(j.order[:i], j.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// RegisterJusticeTx registers a justice tx that a watchtower may publish on
+// our behalf. Spends of pending inputs by the tx are signaled as successful
+// sweeps with the JusticeTx flag of the result set.
+//
+// NOTE: This is part of the JusticeRegistry interface.
+func (s *UtxoSweeper) RegisterJusticeTx(tx *wire.MsgTx) error {
+	req := &justiceTx{
+		hash:     tx.TxHash(),
+		inputs:   make([]wire.OutPoint, 0, len(tx.TxIn)),
+		register: make(chan error, 1),
+	}
+	for _, txIn := range tx.TxIn {
+		req.inputs = append(req.inputs, txIn.PreviousOutPoint)
+	}
+
+	select {
+	case s.justiceReqs <- req:
+	case <-s.quit:
+		return ErrSweeperShuttingDown
+	}
+
+	select {
+	case err := <-req.register:
+		return err
+	case <-s.quit:
+		return ErrSweeperShuttingDown
+	}
+}
+
+// handleJusticeReq registers the justice tx and holds back the sweeps of the
+// pending inputs that it spends.
+func (s *UtxoSweeper) handleJusticeReq(tx *justiceTx, bestHeight int32) error {
+	s.justiceTxs.add(tx)
+
+	log.Debugf("Registered justice tx %v spending %v inputs", tx.hash,
+		len(tx.inputs))
+
+	for _, op := range tx.inputs {
+		if pi, ok := s.pendingInputs[op]; ok {
+			s.holdForJustice(op, pi, bestHeight)
+		}
+	}
+
+	return nil
+}
+
+// holdForJustice defers the next sweep of the pending input if it is spent by
+// a registered justice tx, so that the sweeper doesn't compete with the tower
+// that may publish it.
+func (s *UtxoSweeper) holdForJustice(op wire.OutPoint, pi *pendingInput,
+	bestHeight int32) {
+
+	tx, ok := s.justiceTxs.byInput[op]
+	if !ok {
+		return
+	}
+
+	holdHeight := bestHeight + justiceHoldBlocks
+	if pi.minPublishHeight < holdHeight {
+		log.Debugf("Holding back sweep of input %v until height %v "+
+			"for justice tx %v", op, holdHeight, tx.hash)
+
+		pi.minPublishHeight = holdHeight
+	}
+}
+
+// isJusticeTx returns true if the tx with the given hash is a registered
+// justice tx.
+func (s *UtxoSweeper) isJusticeTx(hash chainhash.Hash) bool {
+	_, ok := s.justiceTxs.txs[hash]
+	return ok
+}
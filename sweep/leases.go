@@ -0,0 +1,51 @@
+package sweep
+
+import (
+	"github.com/btcsuite/btcd/wire"
+)
+
+// leaseInputs locks the outpoints of the given inputs with the wallet, so that
+// the wallet's coin selection won't attempt to spend them while the sweeper is
+// about to. Outpoints that are already leased are skipped. It returns the
+// outpoints that were newly leased.
+func (s *UtxoSweeper) leaseInputs(inputs inputSet) []wire.OutPoint {
+	if s.cfg.OutpointLocker == nil {
+		return nil
+	}
+
+	var leased []wire.OutPoint
+	for _, inp := range inputs {
+		op := *inp.OutPoint()
+		if _, ok := s.leasedOutpoints[op]; ok {
+			continue
+		}
+
+		log.Tracef("Leasing outpoint %v for sweep", op)
+
+		s.cfg.OutpointLocker.LockOutpoint(op)
+		s.leasedOutpoints[op] = struct{}{}
+		leased = append(leased, op)
+	}
+
+	return leased
+}
+
+// releaseLeases unlocks the given outpoints with the wallet, making them
+// available for coin selection once again. Outpoints that aren't leased are
+// skipped.
+func (s *UtxoSweeper) releaseLeases(outpoints ...wire.OutPoint) {
+	if s.cfg.OutpointLocker == nil {
+		return
+	}
+
+	for _, op := range outpoints {
+		if _, ok := s.leasedOutpoints[op]; !ok {
+			continue
+		}
+
+		log.Tracef("Releasing lease of outpoint %v", op)
+
+		s.cfg.OutpointLocker.UnlockOutpoint(op)
+		delete(s.leasedOutpoints, op)
+	}
+}
@@ -0,0 +1,79 @@
+package sweep
+
+import (
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// MempoolFeeSkip describes a broadcast of an input that was skipped, because
+// the fee rate of the sweep tx was below the minimum fee rate that the backend
+// accepted into its mempool at the time. Skipped broadcasts don't count as
+// publish attempts.
+type MempoolFeeSkip struct {
+	// FeeRate is the fee rate of the sweep tx that wasn't broadcast.
+	FeeRate lnwallet.SatPerKWeight
+
+	// MempoolMinFeeRate is the minimum fee rate that the backend required
+	// for mempool acceptance.
+	MempoolMinFeeRate lnwallet.SatPerKWeight
+
+	// Height is the height at which the broadcast was skipped.
+	Height int32
+}
+
+// String returns a human readable description of the skipped broadcast.
+func (m *MempoolFeeSkip) String() string {
+	return fmt.Sprintf("broadcast skipped at height %v: fee rate %v below "+
+		"mempool minimum of %v", m.Height, m.FeeRate,
+		m.MempoolMinFeeRate)
+}
+
+// skipBelowMempoolFee checks whether a sweep tx of the given inputs at the
+// given fee rate would be rejected by the backend because of the fee rate
+// being too low for its mempool. If so, the inputs are rescheduled for the
+// next block without recording a publish attempt, and true is returned.
+func (s *UtxoSweeper) skipBelowMempoolFee(inputs inputSet,
+	feeRate lnwallet.SatPerKWeight, currentHeight int32) bool {
+
+	if s.cfg.MempoolMinFeeRate == nil {
+		return false
+	}
+
+	// If the backend can't be queried, the tx is published as usual, so
+	// that the sweep doesn't stall.
+	minFeeRate, err := s.cfg.MempoolMinFeeRate()
+	if err != nil {
+		log.Warnf("Unable to query mempool minimum fee rate: %v", err)
+		return false
+	}
+
+	// The minimum is cleared for inputs that are published again.
+	skipped := feeRate < minFeeRate
+	for _, inp := range inputs {
+		pi, ok := s.pendingInputs[*inp.OutPoint()]
+		if !ok {
+			continue
+		}
+
+		if !skipped {
+			pi.lastFeeSkip = nil
+			continue
+		}
+
+		pi.lastFeeSkip = &MempoolFeeSkip{
+			FeeRate:           feeRate,
+			MempoolMinFeeRate: minFeeRate,
+			Height:            currentHeight,
+		}
+		pi.minPublishHeight = currentHeight + 1
+	}
+
+	if skipped {
+		log.Infof("Skipping sweep of %v inputs at fee rate %v, below "+
+			"the mempool minimum of %v", len(inputs), feeRate,
+			minFeeRate)
+	}
+
+	return skipped
+}
@@ -0,0 +1,194 @@
+package sweep
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+// SweepReason describes why an input is being swept.
+type SweepReason uint8
+
+const (
+	// SweepReasonUnknown indicates that the reason for the sweep wasn't
+	// specified.
+	SweepReasonUnknown SweepReason = iota
+
+	// SweepReasonCommitment indicates that the input is our output on a
+	// commitment transaction.
+	SweepReasonCommitment
+
+	// SweepReasonHtlcTimeout indicates that the input is an outgoing htlc
+	// that is swept after its timeout.
+	SweepReasonHtlcTimeout
+
+	// SweepReasonHtlcSuccess indicates that the input is an incoming htlc
+	// that is swept using its preimage.
+	SweepReasonHtlcSuccess
+
+	// SweepReasonBreach indicates that the input is swept as part of the
+	// justice transaction of a breached channel.
+	SweepReasonBreach
+
+	// SweepReasonAnchor indicates that the input is an anchor output.
+	SweepReasonAnchor
+
+	// SweepReasonWallet indicates that the input is a regular output of
+	// our wallet.
+	SweepReasonWallet
+)
+
+// String returns a human readable representation of the sweep reason.
+func (r SweepReason) String() string {
+	switch r {
+	case SweepReasonUnknown:
+		return "unknown"
+
+	case SweepReasonCommitment:
+		return "commitment"
+
+	case SweepReasonHtlcTimeout:
+		return "htlc_timeout"
+
+	case SweepReasonHtlcSuccess:
+		return "htlc_success"
+
+	case SweepReasonBreach:
+		return "breach"
+
+	case SweepReasonAnchor:
+		return "anchor"
+
+	case SweepReasonWallet:
+		return "wallet"
+
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(r))
+	}
+}
+
+// SweepReasonForWitnessType returns the sweep reason that is implied by the
+// witness type of an input.
+func SweepReasonForWitnessType(witnessType input.WitnessType) SweepReason {
+	switch witnessType {
+	case input.CommitmentTimeLock, input.CommitmentNoDelay:
+		return SweepReasonCommitment
+
+	case input.HtlcOfferedTimeoutSecondLevel,
+		input.HtlcOfferedRemoteTimeout:
+
+		return SweepReasonHtlcTimeout
+
+	case input.HtlcAcceptedSuccessSecondLevel,
+		input.HtlcAcceptedRemoteSuccess:
+
+		return SweepReasonHtlcSuccess
+
+	case input.CommitmentRevoke, input.HtlcOfferedRevoke,
+		input.HtlcAcceptedRevoke, input.HtlcSecondLevelRevoke:
+
+		return SweepReasonBreach
+
+	case input.WitnessKeyHash, input.NestedWitnessKeyHash:
+		return SweepReasonWallet
+
+	default:
+		return SweepReasonUnknown
+	}
+}
+
+// InputMetadata describes the origin of an input, so that its sweep can be
+// correlated with channel events.
+type InputMetadata struct {
+	// ChanPoint is the channel point of the channel that the input
+	// originates from. It is zero if the input isn't related to a
+	// channel.
+	ChanPoint wire.OutPoint
+
+	// Reason describes why the input is being swept.
+	Reason SweepReason
+}
+
+// String returns a human readable representation of the metadata.
+func (m *InputMetadata) String() string {
+	return fmt.Sprintf("chan_point=%v, reason=%v", m.ChanPoint, m.Reason)
+}
+
+// encode serializes the metadata to the given writer.
+func (m *InputMetadata) encode(w io.Writer) error {
+	if _, err := w.Write(m.ChanPoint.Hash[:]); err != nil {
+		return err
+	}
+
+	var scratch [5]byte
+	byteOrder.PutUint32(scratch[:4], m.ChanPoint.Index)
+	scratch[4] = byte(m.Reason)
+
+	_, err := w.Write(scratch[:])
+	return err
+}
+
+// decode deserializes the metadata from the given reader.
+func (m *InputMetadata) decode(r io.Reader) error {
+	var scratch [chainhash.HashSize + 5]byte
+	if _, err := io.ReadFull(r, scratch[:]); err != nil {
+		return err
+	}
+
+	copy(m.ChanPoint.Hash[:], scratch[:chainhash.HashSize])
+	m.ChanPoint.Index = byteOrder.Uint32(
+		scratch[chainhash.HashSize : chainhash.HashSize+4],
+	)
+	m.Reason = SweepReason(scratch[chainhash.HashSize+4])
+
+	return nil
+}
+
+// outpointKey returns the key under which data related to the given outpoint
+// is stored.
+func outpointKey(op wire.OutPoint) ([]byte, error) {
+	var b bytes.Buffer
+	if _, err := b.Write(op.Hash[:]); err != nil {
+		return nil, err
+	}
+
+	var index [4]byte
+	byteOrder.PutUint32(index[:], op.Index)
+	if _, err := b.Write(index[:]); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// setInputMetadata sets the metadata of a pending input. If the given metadata
+// is nil, the metadata that was persisted for the input before, for example
+// prior to a restart, is restored. Otherwise the metadata is persisted.
+func (s *UtxoSweeper) setInputMetadata(pendInput *pendingInput,
+	metadata *InputMetadata) {
+
+	outpoint := *pendInput.input.OutPoint()
+
+	if metadata == nil {
+		stored, err := s.cfg.Store.FetchInputMetadata(outpoint)
+		if err != nil {
+			log.Errorf("Unable to fetch metadata of input %v: %v",
+				outpoint, err)
+			return
+		}
+
+		pendInput.metadata = stored
+		return
+	}
+
+	pendInput.metadata = metadata
+
+	if err := s.cfg.Store.AddInputMetadata(outpoint, metadata); err != nil {
+		log.Errorf("Unable to store metadata of input %v: %v",
+			outpoint, err)
+	}
+}
@@ -0,0 +1,232 @@
+package sweep
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// maxSplitOutputs is the maximum number of outputs that the value of a sweep
+// is split into.
+const maxSplitOutputs = 100
+
+// SplitOutput is one of the outputs that the value of a sweep is split into.
+type SplitOutput struct {
+	// PkScript is the script that the output pays to. If nil, the output
+	// pays to the sweep script.
+	PkScript []byte
+
+	// Weight is the share of the swept value that the output receives,
+	// relative to the weights of the other outputs.
+	Weight uint32
+}
+
+// SplitPolicy describes how the value of a sweep is split over multiple
+// outputs, instead of being swept to a single output. Either Outputs or
+// ChunkSize must be set.
+type SplitPolicy struct {
+	// Outputs splits the value proportionally to the weights of the
+	// outputs. For example, outputs with weights 80 and 20 split the value
+	// between cold storage and the hot wallet. Outputs that would be dust
+	// are dropped, and their share goes to the remaining outputs.
+	Outputs []SplitOutput
+
+	// ChunkSize splits the value into outputs of the given size that pay
+	// to the sweep script, for example to keep the coins of the wallet at
+	// a manageable size. The remaining value is added to the last output.
+	ChunkSize btcutil.Amount
+}
+
+// validate checks that the split policy is well formed.
+func (p *SplitPolicy) validate() error {
+	switch {
+	case len(p.Outputs) > 0 && p.ChunkSize != 0:
+		return errors.New("split policy can't combine outputs and " +
+			"chunk size")
+
+	case len(p.Outputs) > maxSplitOutputs:
+		return errors.New("too many split outputs")
+
+	case len(p.Outputs) > 0:
+		for _, out := range p.Outputs {
+			if out.Weight == 0 {
+				return errors.New("split output without weight")
+			}
+		}
+		return nil
+
+	case p.ChunkSize < 0:
+		return errors.New("negative chunk size")
+
+	case p.ChunkSize > 0:
+		if p.ChunkSize < splitDustLimit(input.P2WPKHSize) {
+			return errors.New("chunk size below dust limit")
+		}
+		return nil
+
+	default:
+		return errors.New("split policy without outputs or chunk size")
+	}
+}
+
+// splitDustLimit returns the dust limit of an output with a script of the
+// given size. The relay fee isn't known during tx creation, so the dust limit
+// is based on the fee rate floor.
+func splitDustLimit(scriptSize int) btcutil.Amount {
+	return txrules.GetDustThreshold(
+		scriptSize,
+		btcutil.Amount(lnwallet.FeePerKwFloor.FeePerKVByte()),
+	)
+}
+
+// splitSweepValue returns the outputs of a sweep tx that spends inputs with
+// the given total value, split according to the policy. The tx weight is the
+// weight of the tx with a single P2WKH output. The fee is increased to
+// account for the additional outputs.
+func splitSweepValue(totalSum btcutil.Amount, txWeight int64,
+	feePerKw lnwallet.SatPerKWeight, sweepScript []byte,
+	policy *SplitPolicy) ([]*wire.TxOut, error) {
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	// outputsFee returns the fee of the tx if it pays to the given
+	// scripts.
+	outputsFee := func(scripts [][]byte) btcutil.Amount {
+		weight := txWeight -
+			input.P2WKHOutputSize*blockchain.WitnessScaleFactor
+		for _, script := range scripts {
+			weight += int64(8+1+len(script)) *
+				blockchain.WitnessScaleFactor
+		}
+
+		return feePerKw.FeeForWeight(weight)
+	}
+
+	if policy.ChunkSize != 0 {
+		return splitChunks(
+			totalSum, policy.ChunkSize, sweepScript, outputsFee,
+		), nil
+	}
+
+	outputs := make([]SplitOutput, 0, len(policy.Outputs))
+	for _, out := range policy.Outputs {
+		if out.PkScript == nil {
+			out.PkScript = sweepScript
+		}
+		outputs = append(outputs, out)
+	}
+
+	// Drop the outputs that would be dust until all remaining outputs are
+	// above the dust limit. Each dropped output lowers the fee and raises
+	// the share of the others.
+	for {
+		scripts := make([][]byte, 0, len(outputs))
+		var totalWeight uint64
+		for _, out := range outputs {
+			scripts = append(scripts, out.PkScript)
+			totalWeight += uint64(out.Weight)
+		}
+
+		value := totalSum - outputsFee(scripts)
+
+		// Attribute the rounding remainder to the first output, so
+		// that no value is lost.
+		txOuts := make([]*wire.TxOut, 0, len(outputs))
+		kept := make([]SplitOutput, 0, len(outputs))
+		remainder := value
+		for _, out := range outputs {
+			amt := btcutil.Amount(
+				uint64(value) * uint64(out.Weight) / totalWeight,
+			)
+			remainder -= amt
+
+			txOuts = append(txOuts, &wire.TxOut{
+				PkScript: out.PkScript,
+				Value:    int64(amt),
+			})
+		}
+		if len(txOuts) > 0 {
+			txOuts[0].Value += int64(remainder)
+		}
+
+		for i, txOut := range txOuts {
+			dustLimit := splitDustLimit(len(txOut.PkScript))
+			if btcutil.Amount(txOut.Value) >= dustLimit {
+				kept = append(kept, outputs[i])
+			}
+		}
+
+		if len(kept) == len(outputs) {
+			return txOuts, nil
+		}
+
+		log.Debugf("Dropping %v split outputs below the dust limit",
+			len(outputs)-len(kept))
+
+		// If all outputs are dust, everything is swept to the sweep
+		// script as usual.
+		if len(kept) == 0 {
+			scripts := [][]byte{sweepScript}
+			return []*wire.TxOut{{
+				PkScript: sweepScript,
+				Value:    int64(totalSum - outputsFee(scripts)),
+			}}, nil
+		}
+
+		outputs = kept
+	}
+}
+
+// splitChunks splits the swept value into outputs of the chunk size that pay
+// to the sweep script. The value that remains after creating as many chunks as
+// possible is added to the last output.
+func splitChunks(totalSum, chunkSize btcutil.Amount, sweepScript []byte,
+	outputsFee func([][]byte) btcutil.Amount) []*wire.TxOut {
+
+	scripts := func(n int) [][]byte {
+		s := make([][]byte, n)
+		for i := range s {
+			s[i] = sweepScript
+		}
+		return s
+	}
+
+	// Estimate the number of chunks based on the fee of a single output,
+	// and lower it until the value after the fee of all outputs covers
+	// the chunks.
+	n := int((totalSum - outputsFee(scripts(1))) / chunkSize)
+	if n > maxSplitOutputs {
+		n = maxSplitOutputs
+	}
+	if n < 1 {
+		n = 1
+	}
+	for n > 1 &&
+		totalSum-outputsFee(scripts(n)) < btcutil.Amount(n)*chunkSize {
+
+		n--
+	}
+
+	value := totalSum - outputsFee(scripts(n))
+
+	txOuts := make([]*wire.TxOut, 0, n)
+	for i := 0; i < n-1; i++ {
+		txOuts = append(txOuts, &wire.TxOut{
+			PkScript: sweepScript,
+			Value:    int64(chunkSize),
+		})
+	}
+	txOuts = append(txOuts, &wire.TxOut{
+		PkScript: sweepScript,
+		Value:    int64(value - btcutil.Amount(n-1)*chunkSize),
+	})
+
+	return txOuts
+}
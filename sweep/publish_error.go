@@ -0,0 +1,141 @@
+package sweep
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// PublishErrorClass classifies the reason why a sweep transaction was rejected
+// by the backend.
+type PublishErrorClass uint8
+
+const (
+	// PublishErrorUnknown indicates that the reason of the rejection isn't
+	// known.
+	PublishErrorUnknown PublishErrorClass = iota
+
+	// PublishErrorMempoolFull indicates that the mempool of the backend is
+	// full, and the transaction doesn't pay enough to evict other
+	// transactions.
+	PublishErrorMempoolFull
+
+	// PublishErrorFeeTooLow indicates that the transaction doesn't pay the
+	// minimum relay fee.
+	PublishErrorFeeTooLow
+
+	// PublishErrorConflict indicates that an input of the transaction is
+	// already spent, or spent by a transaction in the mempool that can't be
+	// replaced.
+	PublishErrorConflict
+
+	// PublishErrorInvalid indicates that the transaction violates the
+	// consensus or standardness rules, so that it won't be accepted when
+	// published again.
+	PublishErrorInvalid
+)
+
+// String returns a human readable representation of the error class.
+func (c PublishErrorClass) String() string {
+	switch c {
+	case PublishErrorMempoolFull:
+		return "mempool full"
+
+	case PublishErrorFeeTooLow:
+		return "fee too low"
+
+	case PublishErrorConflict:
+		return "conflict"
+
+	case PublishErrorInvalid:
+		return "invalid"
+
+	default:
+		return "unknown"
+	}
+}
+
+// publishErrorPatterns maps the reject reasons of the supported backends to
+// the error class they belong to. They are matched in order, as some reasons
+// are contained in others.
+var publishErrorPatterns = []struct {
+	pattern string
+	class   PublishErrorClass
+}{
+	// bitcoind rejects transactions below the dynamic minimum fee of a
+	// full mempool with "mempool min fee not met", so it must be matched
+	// before the generic fee reasons.
+	{"mempool full", PublishErrorMempoolFull},
+	{"mempool min fee not met", PublishErrorMempoolFull},
+
+	{"min relay fee not met", PublishErrorFeeTooLow},
+	{"insufficient priority", PublishErrorFeeTooLow},
+	{"insufficient fee", PublishErrorFeeTooLow},
+	{"fee not met", PublishErrorFeeTooLow},
+
+	{"already spent", PublishErrorConflict},
+	{"already been spent", PublishErrorConflict},
+	{"txn-mempool-conflict", PublishErrorConflict},
+	{"missing inputs", PublishErrorConflict},
+	{"orphan transaction", PublishErrorConflict},
+
+	{"script-verify-flag", PublishErrorInvalid},
+	{"bad-txns", PublishErrorInvalid},
+	{"non-final", PublishErrorInvalid},
+	{"dust", PublishErrorInvalid},
+	{"tx-size", PublishErrorInvalid},
+	{"non-bip68-final", PublishErrorInvalid},
+}
+
+// ClassifyPublishError returns the class of the given error that was returned
+// when publishing a transaction.
+func ClassifyPublishError(err error) PublishErrorClass {
+	if err == lnwallet.ErrDoubleSpend {
+		return PublishErrorConflict
+	}
+
+	reason := strings.ToLower(err.Error())
+	for _, p := range publishErrorPatterns {
+		if strings.Contains(reason, p.pattern) {
+			return p.class
+		}
+	}
+
+	return PublishErrorUnknown
+}
+
+// PublishError is returned when a sweep transaction is rejected by the
+// backend.
+type PublishError struct {
+	// Class is the class of the rejection reason.
+	Class PublishErrorClass
+
+	// TxID is the hash of the rejected transaction.
+	TxID chainhash.Hash
+
+	// Height is the height at which the transaction was published.
+	Height int32
+
+	// Err is the error that was returned by the backend.
+	Err error
+}
+
+// newPublishError classifies the given error that was returned when publishing
+// the sweep transaction with the given hash.
+func newPublishError(txid chainhash.Hash, height int32,
+	err error) *PublishError {
+
+	return &PublishError{
+		Class:  ClassifyPublishError(err),
+		TxID:   txid,
+		Height: height,
+		Err:    err,
+	}
+}
+
+// Error returns a human readable description of the error.
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("publish tx %v (%v): %v", e.TxID, e.Class, e.Err)
+}
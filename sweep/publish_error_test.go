@@ -0,0 +1,44 @@
+package sweep
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// TestClassifyPublishError asserts that reject reasons of the backends are
+// mapped to the expected error class.
+func TestClassifyPublishError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err   error
+		class PublishErrorClass
+	}{
+		{lnwallet.ErrDoubleSpend, PublishErrorConflict},
+		{errors.New("-26: mempool min fee not met"), PublishErrorMempoolFull},
+		{errors.New("mempool full"), PublishErrorMempoolFull},
+		{errors.New("-26: min relay fee not met"), PublishErrorFeeTooLow},
+		{
+			errors.New("transaction has insufficient priority"),
+			PublishErrorFeeTooLow,
+		},
+		{errors.New("txn-mempool-conflict"), PublishErrorConflict},
+		{errors.New("-25: Missing inputs"), PublishErrorConflict},
+		{
+			errors.New("mandatory-script-verify-flag-failed"),
+			PublishErrorInvalid,
+		},
+		{errors.New("-26: dust"), PublishErrorInvalid},
+		{errors.New("connection refused"), PublishErrorUnknown},
+	}
+
+	for _, test := range tests {
+		class := ClassifyPublishError(test.err)
+		if class != test.class {
+			t.Fatalf("%v: expected class %v, got %v", test.err,
+				test.class, class)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package sweep
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// sweptInputExpiry is the number of blocks after which an input that was
+// found to be swept by a tx that was published before we restarted is
+// forgotten if it isn't re-offered. This is safe, because if it is re-offered
+// later, its spend notification still identifies the spending tx as ours.
+const sweptInputExpiry = 144
+
+// sweptInput is an input that was found to be swept by one of our txes that
+// was published before we restarted.
+type sweptInput struct {
+	// tx is the sweep tx that spends the input.
+	tx *wire.MsgTx
+
+	// height is the height at which the sweep was reconciled.
+	height int32
+}
+
+// reconcileSweepTxs watches the stored sweep txes for their confirmation, to
+// find out which of them confirmed while we were down. It runs in its own
+// goroutine, so that startup doesn't wait for the chain backend.
+func (s *UtxoSweeper) reconcileSweepTxs(sweepTxs []*wire.MsgTx) {
+	defer s.wg.Done()
+
+	for _, tx := range sweepTxs {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+
+		s.watchSweepTx(tx)
+	}
+}
+
+// watchSweepTx registers for the confirmation of the given sweep tx, and hands
+// the tx to the main loop once it confirms. The tx is identified by its hash,
+// so it doesn't matter which of its outputs pays to our wallet, or whether
+// that output has been spent since. Any output script of the tx serves as the
+// script that light clients match the tx by.
+//
+// The lowest height hint of the inputs of the tx bounds the height at which it
+// can have confirmed. The hints are only deleted once the spend of an input
+// has been processed. If no hint is known, the tx isn't watched. This is safe,
+// because its inputs are then swept again when they are re-offered, and their
+// spend notifications signal the outcome.
+func (s *UtxoSweeper) watchSweepTx(sweepTx *wire.MsgTx) {
+	if len(sweepTx.TxOut) == 0 {
+		return
+	}
+
+	var heightHint uint32
+	for _, txIn := range sweepTx.TxIn {
+		hint, err := s.cfg.Store.FetchHeightHint(txIn.PreviousOutPoint)
+		if err != nil {
+			log.Errorf("Unable to fetch height hint of input %v: %v",
+				txIn.PreviousOutPoint, err)
+			return
+		}
+		if hint != 0 && (heightHint == 0 || hint < heightHint) {
+			heightHint = hint
+		}
+	}
+	if heightHint == 0 {
+		return
+	}
+
+	txHash := sweepTx.TxHash()
+	confEvent, err := s.cfg.Notifier.RegisterConfirmationsNtfn(
+		&txHash, sweepTx.TxOut[0].PkScript, 1, heightHint,
+	)
+	if err != nil {
+		log.Errorf("Unable to register confirmation of sweep tx %v: %v",
+			txHash, err)
+		return
+	}
+
+	// The watch is stopped when the tx is resolved otherwise, for example
+	// because a conflicting tx confirmed.
+	stop := make(chan struct{})
+	s.sweepTxWatchesMtx.Lock()
+	s.sweepTxWatches[txHash] = stop
+	s.sweepTxWatchesMtx.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer confEvent.Cancel()
+
+		select {
+		case _, ok := <-confEvent.Confirmed:
+			if !ok {
+				return
+			}
+
+		case <-stop:
+			return
+
+		case <-s.quit:
+			return
+		}
+
+		select {
+		case s.confirmedSweepTxs <- sweepTx:
+		case <-s.quit:
+		}
+	}()
+}
+
+// stopSweepTxWatch stops watching the given stored sweep tx for its
+// confirmation.
+func (s *UtxoSweeper) stopSweepTxWatch(txHash chainhash.Hash) {
+	s.sweepTxWatchesMtx.Lock()
+	defer s.sweepTxWatchesMtx.Unlock()
+
+	stop, ok := s.sweepTxWatches[txHash]
+	if !ok {
+		return
+	}
+
+	close(stop)
+	delete(s.sweepTxWatches, txHash)
+}
+
+// handleConfirmedSweepTx processes the confirmation of a stored sweep tx that
+// was published before we restarted. The inputs that it spends and that
+// haven't been re-offered yet are remembered, so that their listeners are
+// signaled as soon as they are re-offered. Inputs that are pending already
+// are signaled by their spend notification. The tx, and the txes that
+// conflict with it, are removed from the store.
+func (s *UtxoSweeper) handleConfirmedSweepTx(sweepTx *wire.MsgTx,
+	bestHeight int32) {
+
+	log.Infof("Stored sweep tx %v confirmed", sweepTx.TxHash())
+
+	for _, txIn := range sweepTx.TxIn {
+		op := txIn.PreviousOutPoint
+		if _, ok := s.pendingInputs[op]; ok {
+			continue
+		}
+
+		s.sweptInputs[op] = &sweptInput{
+			tx:     sweepTx,
+			height: bestHeight,
+		}
+	}
+
+	s.pruneSweepTxs(sweepTx)
+}
+
+// pruneSweptInputs forgets the inputs that were found to be swept by a tx that
+// was published before we restarted, but haven't been re-offered within
+// sweptInputExpiry blocks.
+func (s *UtxoSweeper) pruneSweptInputs(bestHeight int32) {
+	for op, swept := range s.sweptInputs {
+		if bestHeight-swept.height < sweptInputExpiry {
+			continue
+		}
+
+		log.Debugf("Forgetting swept input %v of tx %v", op,
+			swept.tx.TxHash())
+
+		delete(s.sweptInputs, op)
+	}
+}
+
+// pruneSweepTxs removes the stored sweep txes that spend any of the inputs of
+// the given spending tx, as they are resolved. Either they are the spending tx
+// itself, or they conflict with it and can no longer confirm.
+func (s *UtxoSweeper) pruneSweepTxs(spendingTx *wire.MsgTx) {
+	spent := make(map[wire.OutPoint]struct{}, len(spendingTx.TxIn))
+	for _, txIn := range spendingTx.TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+
+	sweepTxs, err := s.cfg.Store.FetchSweepTxs()
+	if err != nil {
+		log.Errorf("Unable to fetch sweep txes: %v", err)
+		return
+	}
+
+	for _, tx := range sweepTxs {
+		for _, txIn := range tx.TxIn {
+			if _, ok := spent[txIn.PreviousOutPoint]; !ok {
+				continue
+			}
+
+			s.stopSweepTxWatch(tx.TxHash())
+
+			err := s.cfg.Store.DeleteSweepTx(tx.TxHash())
+			if err != nil {
+				log.Errorf("Unable to delete sweep tx %v: %v",
+					tx.TxHash(), err)
+			}
+			break
+		}
+	}
+}
+
+// signalSweptInput signals the listener of a newly offered input if the input
+// was found to be swept by us while we were down. It returns true if the input
+// was handled.
+func (s *UtxoSweeper) signalSweptInput(msg *sweepInputMessage) bool {
+	outpoint := *msg.input.OutPoint()
+
+	swept, ok := s.sweptInputs[outpoint]
+	if !ok {
+		return false
+	}
+	delete(s.sweptInputs, outpoint)
+
+	log.Debugf("Input %v was swept by tx %v before it was re-offered",
+		outpoint, swept.tx.TxHash())
+
+	pendInput := &pendingInput{
+		listeners:     []chan Result{msg.resultChan},
+		input:         msg.input,
+		feePreference: msg.feePreference,
+	}
+	s.pendingInputs[outpoint] = pendInput
+	s.setInputMetadata(pendInput, msg.metadata)
+
+	s.signalAndRemove(&outpoint, Result{
+		Tx: swept.tx,
+	})
+
+	return true
+}
@@ -28,6 +28,24 @@ var (
 	// maps: txHash -> empty slice
 	txHashesBucketKey = []byte("sweeper-tx-hashes")
 
+	// sweepTxBucketKey is the key that points to a bucket containing the
+	// published sweep txes that aren't known to be resolved yet.
+	//
+	// maps: txHash -> serialized_tx
+	sweepTxBucketKey = []byte("sweeper-sweep-txes")
+
+	// inputMetadataBucketKey is the key that points to a bucket containing
+	// the metadata of inputs that are being swept.
+	//
+	// maps: outpoint -> serialized_metadata
+	inputMetadataBucketKey = []byte("sweeper-input-metadata")
+
+	// heightHintBucketKey is the key that points to a bucket containing
+	// the spend height hints of inputs that are being swept.
+	//
+	// maps: outpoint -> height_hint
+	heightHintBucketKey = []byte("sweeper-height-hints")
+
 	// utxnChainPrefix is the bucket prefix for nursery buckets.
 	utxnChainPrefix = []byte("utxn")
 
@@ -54,6 +72,39 @@ type SweeperStore interface {
 	// GetLastPublishedTx returns the last tx that we called NotifyPublishTx
 	// for.
 	GetLastPublishedTx() (*wire.MsgTx, error)
+
+	// FetchSweepTxs returns all published txes that haven't been deleted
+	// with DeleteSweepTx.
+	FetchSweepTxs() ([]*wire.MsgTx, error)
+
+	// DeleteSweepTx removes a published tx that is resolved, because it
+	// either confirmed or one of its inputs was spent by another tx. The
+	// tx is still recognized as ours by IsOurTx.
+	DeleteSweepTx(hash chainhash.Hash) error
+
+	// AddInputMetadata stores the metadata of an input that is being
+	// swept.
+	AddInputMetadata(op wire.OutPoint, meta *InputMetadata) error
+
+	// FetchInputMetadata returns the stored metadata of an input. If no
+	// metadata is stored, nil is returned.
+	FetchInputMetadata(op wire.OutPoint) (*InputMetadata, error)
+
+	// DeleteInputMetadata removes the metadata of an input that is no
+	// longer being swept.
+	DeleteInputMetadata(op wire.OutPoint) error
+
+	// UpdateHeightHints stores the given spend height hint for all of the
+	// inputs.
+	UpdateHeightHints(ops []wire.OutPoint, heightHint uint32) error
+
+	// FetchHeightHint returns the stored spend height hint of an input. If
+	// no height hint is stored, zero is returned.
+	FetchHeightHint(op wire.OutPoint) (uint32, error)
+
+	// DeleteHeightHint removes the spend height hint of an input that is
+	// no longer being swept.
+	DeleteHeightHint(op wire.OutPoint) error
 }
 
 type sweeperStore struct {
@@ -72,6 +123,21 @@ func NewSweeperStore(db *channeldb.DB, chainHash *chainhash.Hash) (
 			return err
 		}
 
+		_, err = tx.CreateBucketIfNotExists(inputMetadataBucketKey)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(heightHintBucketKey)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(sweepTxBucketKey)
+		if err != nil {
+			return err
+		}
+
 		if tx.Bucket(txHashesBucketKey) != nil {
 			return nil
 		}
@@ -175,6 +241,11 @@ func (s *sweeperStore) NotifyPublishTx(sweepTx *wire.MsgTx) error {
 			return errors.New("tx hashes bucket does not exist")
 		}
 
+		sweepTxBucket := tx.Bucket(sweepTxBucketKey)
+		if sweepTxBucket == nil {
+			return errors.New("sweep tx bucket does not exist")
+		}
+
 		var b bytes.Buffer
 		if err := sweepTx.Serialize(&b); err != nil {
 			return err
@@ -186,10 +257,57 @@ func (s *sweeperStore) NotifyPublishTx(sweepTx *wire.MsgTx) error {
 
 		hash := sweepTx.TxHash()
 
+		if err := sweepTxBucket.Put(hash[:], b.Bytes()); err != nil {
+			return err
+		}
+
 		return txHashesBucket.Put(hash[:], []byte{})
 	})
 }
 
+// FetchSweepTxs returns all published txes that haven't been deleted with
+// DeleteSweepTx.
+func (s *sweeperStore) FetchSweepTxs() ([]*wire.MsgTx, error) {
+	var sweepTxs []*wire.MsgTx
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		sweepTxBucket := tx.Bucket(sweepTxBucketKey)
+		if sweepTxBucket == nil {
+			return errors.New("sweep tx bucket does not exist")
+		}
+
+		return sweepTxBucket.ForEach(func(_, v []byte) error {
+			sweepTx := &wire.MsgTx{}
+			err := sweepTx.Deserialize(bytes.NewReader(v))
+			if err != nil {
+				return fmt.Errorf("tx deserialize: %v", err)
+			}
+			sweepTxs = append(sweepTxs, sweepTx)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sweepTxs, nil
+}
+
+// DeleteSweepTx removes a published tx that is resolved, because it either
+// confirmed or one of its inputs was spent by another tx. The tx is still
+// recognized as ours by IsOurTx.
+func (s *sweeperStore) DeleteSweepTx(hash chainhash.Hash) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		sweepTxBucket := tx.Bucket(sweepTxBucketKey)
+		if sweepTxBucket == nil {
+			return errors.New("sweep tx bucket does not exist")
+		}
+
+		return sweepTxBucket.Delete(hash[:])
+	})
+}
+
 // GetLastPublishedTx returns the last tx that we called NotifyPublishTx
 // for.
 func (s *sweeperStore) GetLastPublishedTx() (*wire.MsgTx, error) {
@@ -243,5 +361,159 @@ func (s *sweeperStore) IsOurTx(hash chainhash.Hash) (bool, error) {
 	return ours, nil
 }
 
+// AddInputMetadata stores the metadata of an input that is being swept.
+func (s *sweeperStore) AddInputMetadata(op wire.OutPoint,
+	meta *InputMetadata) error {
+
+	key, err := outpointKey(op)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := meta.encode(&b); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		metadataBucket := tx.Bucket(inputMetadataBucketKey)
+		if metadataBucket == nil {
+			return errors.New("input metadata bucket does not " +
+				"exist")
+		}
+
+		return metadataBucket.Put(key, b.Bytes())
+	})
+}
+
+// FetchInputMetadata returns the stored metadata of an input. If no metadata
+// is stored, nil is returned.
+func (s *sweeperStore) FetchInputMetadata(op wire.OutPoint) (*InputMetadata,
+	error) {
+
+	key, err := outpointKey(op)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta *InputMetadata
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		metadataBucket := tx.Bucket(inputMetadataBucketKey)
+		if metadataBucket == nil {
+			return errors.New("input metadata bucket does not " +
+				"exist")
+		}
+
+		metaBytes := metadataBucket.Get(key)
+		if metaBytes == nil {
+			return nil
+		}
+
+		meta = &InputMetadata{}
+		return meta.decode(bytes.NewReader(metaBytes))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// DeleteInputMetadata removes the metadata of an input that is no longer
+// being swept.
+func (s *sweeperStore) DeleteInputMetadata(op wire.OutPoint) error {
+	key, err := outpointKey(op)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		metadataBucket := tx.Bucket(inputMetadataBucketKey)
+		if metadataBucket == nil {
+			return errors.New("input metadata bucket does not " +
+				"exist")
+		}
+
+		return metadataBucket.Delete(key)
+	})
+}
+
+// UpdateHeightHints stores the given spend height hint for all of the inputs.
+func (s *sweeperStore) UpdateHeightHints(ops []wire.OutPoint,
+	heightHint uint32) error {
+
+	var hint [4]byte
+	byteOrder.PutUint32(hint[:], heightHint)
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		heightHintBucket := tx.Bucket(heightHintBucketKey)
+		if heightHintBucket == nil {
+			return errors.New("height hint bucket does not exist")
+		}
+
+		for _, op := range ops {
+			key, err := outpointKey(op)
+			if err != nil {
+				return err
+			}
+
+			if err := heightHintBucket.Put(key, hint[:]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// FetchHeightHint returns the stored spend height hint of an input. If no
+// height hint is stored, zero is returned.
+func (s *sweeperStore) FetchHeightHint(op wire.OutPoint) (uint32, error) {
+	key, err := outpointKey(op)
+	if err != nil {
+		return 0, err
+	}
+
+	var heightHint uint32
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		heightHintBucket := tx.Bucket(heightHintBucketKey)
+		if heightHintBucket == nil {
+			return errors.New("height hint bucket does not exist")
+		}
+
+		hint := heightHintBucket.Get(key)
+		if len(hint) != 4 {
+			return nil
+		}
+
+		heightHint = byteOrder.Uint32(hint)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return heightHint, nil
+}
+
+// DeleteHeightHint removes the spend height hint of an input that is no longer
+// being swept.
+func (s *sweeperStore) DeleteHeightHint(op wire.OutPoint) error {
+	key, err := outpointKey(op)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		heightHintBucket := tx.Bucket(heightHintBucketKey)
+		if heightHintBucket == nil {
+			return errors.New("height hint bucket does not exist")
+		}
+
+		return heightHintBucket.Delete(key)
+	})
+}
+
 // Compile-time constraint to ensure sweeperStore implements SweeperStore.
 var _ SweeperStore = (*sweeperStore)(nil)
@@ -8,14 +8,20 @@ import (
 // MockSweeperStore is a mock implementation of sweeper store. This type is
 // exported, because it is currently used in nursery tests too.
 type MockSweeperStore struct {
-	lastTx  *wire.MsgTx
-	ourTxes map[chainhash.Hash]struct{}
+	lastTx   *wire.MsgTx
+	ourTxes  map[chainhash.Hash]struct{}
+	sweepTxs map[chainhash.Hash]*wire.MsgTx
+	metadata map[wire.OutPoint]InputMetadata
+	hints    map[wire.OutPoint]uint32
 }
 
 // NewMockSweeperStore returns a new instance.
 func NewMockSweeperStore() *MockSweeperStore {
 	return &MockSweeperStore{
-		ourTxes: make(map[chainhash.Hash]struct{}),
+		ourTxes:  make(map[chainhash.Hash]struct{}),
+		sweepTxs: make(map[chainhash.Hash]*wire.MsgTx),
+		metadata: make(map[wire.OutPoint]InputMetadata),
+		hints:    make(map[wire.OutPoint]uint32),
 	}
 }
 
@@ -30,6 +36,7 @@ func (s *MockSweeperStore) IsOurTx(hash chainhash.Hash) (bool, error) {
 func (s *MockSweeperStore) NotifyPublishTx(tx *wire.MsgTx) error {
 	txHash := tx.TxHash()
 	s.ourTxes[txHash] = struct{}{}
+	s.sweepTxs[txHash] = tx
 	s.lastTx = tx
 
 	return nil
@@ -41,5 +48,78 @@ func (s *MockSweeperStore) GetLastPublishedTx() (*wire.MsgTx, error) {
 	return s.lastTx, nil
 }
 
+// FetchSweepTxs returns all published txes that haven't been deleted with
+// DeleteSweepTx.
+func (s *MockSweeperStore) FetchSweepTxs() ([]*wire.MsgTx, error) {
+	sweepTxs := make([]*wire.MsgTx, 0, len(s.sweepTxs))
+	for _, tx := range s.sweepTxs {
+		sweepTxs = append(sweepTxs, tx)
+	}
+
+	return sweepTxs, nil
+}
+
+// DeleteSweepTx removes a published tx that is resolved.
+func (s *MockSweeperStore) DeleteSweepTx(hash chainhash.Hash) error {
+	delete(s.sweepTxs, hash)
+
+	return nil
+}
+
+// AddInputMetadata stores the metadata of an input that is being swept.
+func (s *MockSweeperStore) AddInputMetadata(op wire.OutPoint,
+	meta *InputMetadata) error {
+
+	s.metadata[op] = *meta
+
+	return nil
+}
+
+// FetchInputMetadata returns the stored metadata of an input. If no metadata
+// is stored, nil is returned.
+func (s *MockSweeperStore) FetchInputMetadata(op wire.OutPoint) (
+	*InputMetadata, error) {
+
+	meta, ok := s.metadata[op]
+	if !ok {
+		return nil, nil
+	}
+
+	return &meta, nil
+}
+
+// DeleteInputMetadata removes the metadata of an input that is no longer
+// being swept.
+func (s *MockSweeperStore) DeleteInputMetadata(op wire.OutPoint) error {
+	delete(s.metadata, op)
+
+	return nil
+}
+
+// UpdateHeightHints stores the given spend height hint for all of the inputs.
+func (s *MockSweeperStore) UpdateHeightHints(ops []wire.OutPoint,
+	heightHint uint32) error {
+
+	for _, op := range ops {
+		s.hints[op] = heightHint
+	}
+
+	return nil
+}
+
+// FetchHeightHint returns the stored spend height hint of an input. If no
+// height hint is stored, zero is returned.
+func (s *MockSweeperStore) FetchHeightHint(op wire.OutPoint) (uint32, error) {
+	return s.hints[op], nil
+}
+
+// DeleteHeightHint removes the spend height hint of an input that is no longer
+// being swept.
+func (s *MockSweeperStore) DeleteHeightHint(op wire.OutPoint) error {
+	delete(s.hints, op)
+
+	return nil
+}
+
 // Compile-time constraint to ensure MockSweeperStore implements SweeperStore.
 var _ SweeperStore = (*MockSweeperStore)(nil)
@@ -141,6 +141,35 @@ func testStore(t *testing.T, createStore func() (SweeperStore, error)) {
 		t.Fatal("expected tx to be ours")
 	}
 
+	// Both txes should be stored until they are deleted. A deleted tx is
+	// still recognized as ours.
+	sweepTxs, err := store.FetchSweepTxs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sweepTxs) != 2 {
+		t.Fatalf("expected 2 sweep txes, got %v", len(sweepTxs))
+	}
+
+	if err := store.DeleteSweepTx(tx1.TxHash()); err != nil {
+		t.Fatal(err)
+	}
+	sweepTxs, err = store.FetchSweepTxs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sweepTxs) != 1 || sweepTxs[0].TxHash() != tx2.TxHash() {
+		t.Fatalf("expected only tx2 to be stored, got %v", sweepTxs)
+	}
+
+	ours, err = store.IsOurTx(tx1.TxHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ours {
+		t.Fatal("expected deleted tx to be ours")
+	}
+
 	// An different hash should be reported on as not being ours.
 	var unknownHash chainhash.Hash
 	ours, err = store.IsOurTx(unknownHash)
@@ -150,4 +179,85 @@ func testStore(t *testing.T, createStore func() (SweeperStore, error)) {
 	if ours {
 		t.Fatal("expected tx to be not ours")
 	}
+
+	// Store metadata for an input and assert that it survives recreation
+	// of the store.
+	op := wire.OutPoint{Index: 7}
+	metadata := &InputMetadata{
+		ChanPoint: wire.OutPoint{Hash: chainhash.Hash{1}, Index: 2},
+		Reason:    SweepReasonHtlcSuccess,
+	}
+	if err := store.AddInputMetadata(op, metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = createStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retrievedMetadata, err := store.FetchInputMetadata(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retrievedMetadata == nil || *retrievedMetadata != *metadata {
+		t.Fatalf("expected metadata %v, got %v", metadata,
+			retrievedMetadata)
+	}
+
+	// After deletion, no metadata should be returned.
+	if err := store.DeleteInputMetadata(op); err != nil {
+		t.Fatal(err)
+	}
+	retrievedMetadata, err = store.FetchInputMetadata(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retrievedMetadata != nil {
+		t.Fatalf("expected no metadata, got %v", retrievedMetadata)
+	}
+
+	// Store height hints and assert that they survive recreation of the
+	// store.
+	op2 := wire.OutPoint{Index: 8}
+	err = store.UpdateHeightHints([]wire.OutPoint{op, op2}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.UpdateHeightHints([]wire.OutPoint{op2}, 110)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = createStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hint, err := store.FetchHeightHint(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint != 100 {
+		t.Fatalf("expected height hint 100, got %v", hint)
+	}
+	hint, err = store.FetchHeightHint(op2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint != 110 {
+		t.Fatalf("expected height hint 110, got %v", hint)
+	}
+
+	// After deletion, no height hint should be returned.
+	if err := store.DeleteHeightHint(op); err != nil {
+		t.Fatal(err)
+	}
+	hint, err = store.FetchHeightHint(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint != 0 {
+		t.Fatalf("expected no height hint, got %v", hint)
+	}
 }
@@ -10,11 +10,14 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/lightningnetwork/lnd/eventbus"
 	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/lightningnetwork/lnd/lnwallet"
 )
 
@@ -89,6 +92,19 @@ type pendingInput struct {
 	// lastFeeRate is the most recent fee rate used for this input within a
 	// transaction broadcast to the network.
 	lastFeeRate lnwallet.SatPerKWeight
+
+	// metadata describes the origin of the input. It is nil if the client
+	// didn't provide any.
+	metadata *InputMetadata
+
+	// lastPublishErr is the error of the most recent publish attempt of a
+	// transaction sweeping this input. It is nil if that attempt succeeded.
+	lastPublishErr *PublishError
+
+	// lastFeeSkip describes the most recent broadcast of this input that
+	// was skipped because of the mempool minimum fee rate. It is nil if
+	// the input was broadcast since.
+	lastFeeSkip *MempoolFeeSkip
 }
 
 // pendingInputs is a type alias for a set of pending inputs.
@@ -131,6 +147,38 @@ type PendingInput struct {
 	// NextBroadcastHeight is the next height of the chain at which we'll
 	// attempt to broadcast a transaction sweeping the input.
 	NextBroadcastHeight uint32
+
+	// BlocksUntilNextBroadcast is the number of blocks that remain until
+	// NextBroadcastHeight is reached. It is zero if the input can be
+	// broadcast at the current height, in which case it is swept once the
+	// batch timer of its lane expires.
+	BlocksUntilNextBroadcast uint32
+
+	// RetrySchedule holds, for each of the remaining broadcast attempts
+	// after the next one, the number of blocks that are waited after the
+	// preceding attempt before the input is broadcast again. It is derived
+	// from the configured NextAttemptDeltaFunc, so it is only an estimate
+	// if that function is randomized. After the last attempt, the sweeper
+	// gives up on the input.
+	RetrySchedule []int32
+
+	// Urgency is the urgency lane in which the input is scheduled.
+	Urgency Urgency
+
+	// Metadata describes the channel the input originates from and the
+	// reason it is being swept. It is nil if unknown.
+	Metadata *InputMetadata
+
+	// LastPublishError is the classified error of the most recent
+	// broadcast attempt of the input. It is nil if that attempt succeeded
+	// or no attempt has been made yet.
+	LastPublishError *PublishError
+
+	// MempoolFeeSkip describes the most recent broadcast of the input
+	// that was skipped, because its fee rate was below the minimum fee
+	// rate of the backend's mempool. The input is retried at the next
+	// block. It is nil if the input was broadcast since.
+	MempoolFeeSkip *MempoolFeeSkip
 }
 
 // UtxoSweeper is responsible for sweeping outputs back into the wallet
@@ -148,15 +196,79 @@ type UtxoSweeper struct {
 	// UtxoSweeper is attempting to sweep.
 	pendingSweepsReqs chan *pendingSweepsReq
 
+	// feeCalibrationReqs is a channel that will be sent requests by
+	// external callers in order to retrieve the fee calibration report.
+	feeCalibrationReqs chan *feeCalibrationReq
+
+	// bumpTxReqs is a channel that will be sent requests by external
+	// callers in order to replace one of our sweep transactions at a
+	// higher fee rate.
+	bumpTxReqs chan *bumpTxReq
+
+	// listClustersReqs is a channel that will be sent requests by external
+	// callers in order to retrieve the current clusters of pending inputs.
+	listClustersReqs chan *listClustersReq
+
+	// accountingReqs is a channel that will be sent requests by external
+	// callers in order to retrieve the accounting of swept inputs.
+	accountingReqs chan *accountingReq
+
+	// justiceReqs is a channel that will be sent the justice transactions
+	// that watchtower clients register.
+	justiceReqs chan *justiceTx
+
+	// justiceTxs holds the registered justice transactions.
+	justiceTxs *justiceTxs
+
+	// accounting holds the totals of the inputs that are no longer
+	// pending.
+	accounting *SweepAccounting
+
+	// sweptInputs holds the inputs of the sweep txes that were published
+	// before we restarted and were found to have confirmed. They are
+	// signaled as soon as they are re-offered, or forgotten after
+	// sweptInputExpiry blocks.
+	sweptInputs map[wire.OutPoint]*sweptInput
+
+	// confirmedSweepTxs receives the stored sweep txes that were
+	// published before we restarted, once they confirm.
+	confirmedSweepTxs chan *wire.MsgTx
+
+	// sweepTxWatches holds the channels that stop watching the stored
+	// sweep txes for their confirmation. It is guarded by
+	// sweepTxWatchesMtx.
+	sweepTxWatches    map[chainhash.Hash]chan struct{}
+	sweepTxWatchesMtx sync.Mutex
+
+	// unconfirmedSweeps maps the pending inputs that are spent by a
+	// published, unconfirmed sweep tx to the hash of that tx.
+	unconfirmedSweeps map[wire.OutPoint]chainhash.Hash
+
+	// witnessCache holds the input scripts of recently signed sweep
+	// transactions, so that identical transactions aren't signed again.
+	witnessCache *witnessCache
+
+	// feeRecords holds the projected and actual fee rates of recently
+	// published sweep transactions.
+	feeRecords []SweepFeeRecord
+
 	// pendingInputs is the total set of inputs the UtxoSweeper has been
 	// requested to sweep.
 	pendingInputs pendingInputs
 
-	// timer is the channel that signals expiry of the sweep batch timer.
-	timer <-chan time.Time
+	// leasedOutpoints is the set of outpoints that are currently locked
+	// with the wallet on behalf of the sweeper.
+	leasedOutpoints map[wire.OutPoint]struct{}
+
+	// timers holds the channels that signal expiry of the sweep batch
+	// timer of each urgency lane. A nil channel indicates that no timer is
+	// running for the lane.
+	timers [numUrgencyLanes]<-chan time.Time
 
 	testSpendChan chan wire.OutPoint
 
+	testConfirmedSweepTxChan chan chainhash.Hash
+
 	currentOutputScript []byte
 
 	relayFeeRate lnwallet.SatPerKWeight
@@ -209,6 +321,14 @@ type UtxoSweeperConfig struct {
 	// to the caller.
 	MaxSweepAttempts int
 
+	// MaxUnconfirmedSweeps is the maximum number of sweep transactions
+	// that may be unconfirmed at the same time. Clusters that would
+	// exceed it are deferred until a prior sweep confirms, so that the
+	// sweeper stays clear of the mempool's ancestor and descendant
+	// limits. Replacements of outstanding sweeps are always allowed. A
+	// value of zero disables the limit.
+	MaxUnconfirmedSweeps int
+
 	// NextAttemptDeltaFunc returns given the number of already attempted
 	// sweeps, how many blocks to wait before retrying to sweep.
 	NextAttemptDeltaFunc func(int) int32
@@ -228,6 +348,47 @@ type UtxoSweeperConfig struct {
 	//   #1: min = 1 sat/vbyte, max = 10 sat/vbyte
 	//   #2: min = 11 sat/vbyte, max = 20 sat/vbyte...
 	FeeRateBucketSize int
+
+	// OutpointLocker is used to lease the outpoints of the inputs that are
+	// about to be swept with the wallet, such that the wallet's coin
+	// selection never attempts to double spend them. If nil, no leases are
+	// taken.
+	OutpointLocker OutpointLocker
+
+	// LanePolicies optionally overrides the batch timer and fee policy of
+	// the urgency lanes. Lanes without a policy use NewBatchTimer and the
+	// fee preference of their inputs as is.
+	LanePolicies map[Urgency]LanePolicy
+
+	// MinOutputAmounts optionally sets the minimum value of a sweep output
+	// per destination type. Sweeps that would create a smaller output,
+	// even if above the dust limit, aren't published and their inputs are
+	// carried over to the next batch. This avoids creating utxos that
+	// cost more to spend than they are worth.
+	MinOutputAmounts map[DestinationType]btcutil.Amount
+
+	// KeyRing is used to derive the keys of imported external outputs
+	// that are only described by their key locator. If nil, such outputs
+	// must be imported with their public key.
+	KeyRing keychain.KeyRing
+
+	// TxOrdering determines how the inputs and outputs of sweep
+	// transactions are ordered. The zero value shuffles them randomly.
+	TxOrdering TxOrdering
+
+	// TxOptions controls the locktime and the sequence numbers of sweep
+	// transactions. It can be overridden per call to CreateSweepTx.
+	TxOptions SweepTxOptions
+
+	// EventBus is an optional event bus on which the sweeper publishes
+	// the sweep transactions it broadcasts, and their confirmation.
+	EventBus *eventbus.EventBus
+
+	// MempoolMinFeeRate optionally returns the minimum fee rate that the
+	// backend currently requires for mempool acceptance. Sweeps below it
+	// aren't broadcast, as they would be rejected anyway, and their inputs
+	// are retried at the next block without using up a publish attempt.
+	MempoolMinFeeRate func() (lnwallet.SatPerKWeight, error)
 }
 
 // Result is the struct that is pushed through the result channel. Callers can
@@ -241,6 +402,16 @@ type Result struct {
 
 	// Tx is the transaction that spent the input.
 	Tx *wire.MsgTx
+
+	// LastPublishError is the classified error of the last broadcast
+	// attempt of the input. It is only set if Err is ErrTooManyAttempts
+	// and the last attempt was rejected by the backend.
+	LastPublishError *PublishError
+
+	// JusticeTx indicates that the input was spent by a justice tx that
+	// was registered with RegisterJusticeTx, and that a watchtower
+	// published on our behalf.
+	JusticeTx bool
 }
 
 // sweepInputMessage structs are used in the internal channel between the
@@ -248,18 +419,32 @@ type Result struct {
 type sweepInputMessage struct {
 	input         input.Input
 	feePreference FeePreference
+	metadata      *InputMetadata
 	resultChan    chan Result
 }
 
 // New returns a new Sweeper instance.
 func New(cfg *UtxoSweeperConfig) *UtxoSweeper {
 	return &UtxoSweeper{
-		cfg:               cfg,
-		newInputs:         make(chan *sweepInputMessage),
-		spendChan:         make(chan *chainntnfs.SpendDetail),
-		pendingSweepsReqs: make(chan *pendingSweepsReq),
-		quit:              make(chan struct{}),
-		pendingInputs:     make(pendingInputs),
+		cfg:                cfg,
+		newInputs:          make(chan *sweepInputMessage),
+		spendChan:          make(chan *chainntnfs.SpendDetail),
+		pendingSweepsReqs:  make(chan *pendingSweepsReq),
+		feeCalibrationReqs: make(chan *feeCalibrationReq),
+		bumpTxReqs:         make(chan *bumpTxReq),
+		listClustersReqs:   make(chan *listClustersReq),
+		accountingReqs:     make(chan *accountingReq),
+		justiceReqs:        make(chan *justiceTx),
+		justiceTxs:         newJusticeTxs(),
+		accounting:         newSweepAccounting(),
+		sweptInputs:        make(map[wire.OutPoint]*sweptInput),
+		confirmedSweepTxs:  make(chan *wire.MsgTx),
+		sweepTxWatches:     make(map[chainhash.Hash]chan struct{}),
+		unconfirmedSweeps:  make(map[wire.OutPoint]chainhash.Hash),
+		witnessCache:       newWitnessCache(),
+		quit:               make(chan struct{}),
+		pendingInputs:      make(pendingInputs),
+		leasedOutpoints:    make(map[wire.OutPoint]struct{}),
 	}
 }
 
@@ -271,12 +456,38 @@ func (s *UtxoSweeper) Start() error {
 
 	log.Tracef("Sweeper starting")
 
+	bestHash, bestHeight, err := s.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		return fmt.Errorf("get best block: %v", err)
+	}
+
+	log.Debugf("Best height: %v", bestHeight)
+
 	// Retrieve last published tx from database.
 	lastTx, err := s.cfg.Store.GetLastPublishedTx()
 	if err != nil {
 		return fmt.Errorf("get last published tx: %v", err)
 	}
 
+	// Collect our stored sweep txes, to find out which of them confirmed
+	// while we were down. The last published tx is included, as it may
+	// have been published before the store kept all sweep txes.
+	sweepTxs, err := s.cfg.Store.FetchSweepTxs()
+	if err != nil {
+		return fmt.Errorf("fetch sweep txes: %v", err)
+	}
+	if lastTx != nil {
+		stored := false
+		for _, tx := range sweepTxs {
+			if tx.TxHash() == lastTx.TxHash() {
+				stored = true
+				break
+			}
+		}
+		if !stored {
+			sweepTxs = append(sweepTxs, lastTx)
+		}
+	}
 	// Republish in case the previous call crashed lnd. We don't care about
 	// the return value, because inputs will be re-offered and retried
 	// anyway. The only reason we republish here is to prevent the corner
@@ -290,8 +501,11 @@ func (s *UtxoSweeper) Start() error {
 		// Error can be ignored. Because we are starting up, there are
 		// no pending inputs to update based on the publish result.
 		err := s.cfg.PublishTransaction(lastTx)
-		if err != nil && err != lnwallet.ErrDoubleSpend {
-			log.Errorf("last tx publish: %v", err)
+		if err != nil {
+			publishErr := newPublishError(lastTx.TxHash(), 0, err)
+			if publishErr.Class != PublishErrorConflict {
+				log.Errorf("last tx publish: %v", publishErr)
+			}
 		}
 	}
 
@@ -300,13 +514,6 @@ func (s *UtxoSweeper) Start() error {
 	s.relayFeeRate = s.cfg.FeeEstimator.RelayFeePerKW()
 
 	// Register for block epochs to retry sweeping every block.
-	bestHash, bestHeight, err := s.cfg.ChainIO.GetBestBlock()
-	if err != nil {
-		return fmt.Errorf("get best block: %v", err)
-	}
-
-	log.Debugf("Best height: %v", bestHeight)
-
 	blockEpochs, err := s.cfg.Notifier.RegisterBlockEpochNtfn(
 		&chainntnfs.BlockEpoch{
 			Height: bestHeight,
@@ -329,6 +536,11 @@ func (s *UtxoSweeper) Start() error {
 		}
 	}()
 
+	// Watch the stored sweep txes for their confirmation in the
+	// background, as the registrations may involve the chain backend.
+	s.wg.Add(1)
+	go s.reconcileSweepTxs(sweepTxs)
+
 	return nil
 }
 
@@ -359,6 +571,18 @@ func (s *UtxoSweeper) Stop() error {
 func (s *UtxoSweeper) SweepInput(input input.Input,
 	feePreference FeePreference) (chan Result, error) {
 
+	return s.SweepInputWithMetadata(input, feePreference, nil)
+}
+
+// SweepInputWithMetadata sweeps an input like SweepInput, additionally
+// recording the channel it originates from and the reason it is swept. The
+// metadata is persisted until the input is no longer pending, and exposed
+// through PendingInputs and the fee calibration report. If metadata is nil,
+// any metadata that was persisted for the input before is used.
+func (s *UtxoSweeper) SweepInputWithMetadata(input input.Input,
+	feePreference FeePreference, metadata *InputMetadata) (chan Result,
+	error) {
+
 	if input == nil || input.OutPoint() == nil || input.SignDesc() == nil {
 		return nil, errors.New("nil input received")
 	}
@@ -367,15 +591,26 @@ func (s *UtxoSweeper) SweepInput(input input.Input,
 	if _, err := s.feeRateForPreference(feePreference); err != nil {
 		return nil, err
 	}
+	if feePreference.Urgency >= numUrgencyLanes {
+		return nil, fmt.Errorf("unknown urgency %v",
+			feePreference.Urgency)
+	}
 
 	log.Infof("Sweep request received: out_point=%v, witness_type=%v, "+
-		"time_lock=%v, amount=%v, fee_preference=%v", input.OutPoint(),
-		input.WitnessType(), input.BlocksToMaturity(),
-		btcutil.Amount(input.SignDesc().Output.Value), feePreference)
+		"time_lock=%v, amount=%v, fee_preference=%v, urgency=%v",
+		input.OutPoint(), input.WitnessType(), input.BlocksToMaturity(),
+		btcutil.Amount(input.SignDesc().Output.Value), feePreference,
+		feePreference.Urgency)
+
+	if metadata != nil {
+		log.Infof("Sweep input %v metadata: %v", input.OutPoint(),
+			metadata)
+	}
 
 	sweeperInput := &sweepInputMessage{
 		input:         input,
 		feePreference: feePreference,
+		metadata:      metadata,
 		resultChan:    make(chan Result, 1),
 	}
 
@@ -432,6 +667,18 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch,
 				pendInput.listeners = append(
 					pendInput.listeners, input.resultChan,
 				)
+
+				if input.metadata != nil {
+					s.setInputMetadata(
+						pendInput, input.metadata,
+					)
+				}
+				continue
+			}
+
+			// If the input was swept while we were down, its
+			// listener can be signaled right away.
+			if s.signalSweptInput(input) {
 				continue
 			}
 
@@ -447,12 +694,18 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch,
 			}
 			s.pendingInputs[outpoint] = pendInput
 
+			s.setInputMetadata(pendInput, input.metadata)
+
+			// Don't compete with a justice tx that may have been
+			// published for the input.
+			s.holdForJustice(outpoint, pendInput, bestHeight)
+
 			// Start watching for spend of this input, either by us
 			// or the remote party.
 			cancel, err := s.waitForSpend(
 				outpoint,
 				input.input.SignDesc().Output.PkScript,
-				input.input.HeightHint(),
+				s.spendHeightHint(input.input),
 			)
 			if err != nil {
 				err := fmt.Errorf("wait for spend: %v", err)
@@ -486,15 +739,27 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch,
 				continue
 			}
 
+			// A justice tx that a watchtower published on our
+			// behalf sweeps the inputs by proxy.
+			isJusticeTx := s.isJusticeTx(spendHash)
+
 			log.Debugf("Detected spend related to in flight inputs "+
-				"(is_ours=%v): %v",
+				"(is_ours=%v, is_justice=%v): %v",
+				isOurTx, isJusticeTx,
 				newLogClosure(func() string {
 					return spew.Sdump(spend.SpendingTx)
-				}), isOurTx,
+				}),
 			)
 
+			// Attribute the fee of our sweep to the inputs that it
+			// spends, before they are removed.
+			if isOurTx {
+				s.attributeSweepFee(spend.SpendingTx)
+			}
+
 			// Signal sweep results for inputs in this confirmed
 			// tx.
+			var signaled bool
 			for _, txIn := range spend.SpendingTx.TxIn {
 				outpoint := txIn.PreviousOutPoint
 
@@ -510,15 +775,41 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch,
 
 				// Return either a nil or a remote spend result.
 				var err error
-				if !isOurTx {
+				if !isOurTx && !isJusticeTx {
 					err = ErrRemoteSpend
 				}
 
 				// Signal result channels.
 				s.signalAndRemove(&outpoint, Result{
-					Tx:  spend.SpendingTx,
-					Err: err,
+					Tx:        spend.SpendingTx,
+					Err:       err,
+					JusticeTx: isJusticeTx,
 				})
+				signaled = true
+			}
+
+			// The inputs of the tx are spent, so our stored sweep
+			// txes and the justice txs spending them are resolved.
+			s.pruneSweepTxs(spend.SpendingTx)
+
+			for _, txIn := range spend.SpendingTx.TxIn {
+				op := txIn.PreviousOutPoint
+				if tx, ok := s.justiceTxs.byInput[op]; ok {
+					s.justiceTxs.remove(tx.hash)
+				}
+			}
+
+			// The spend of every input of the tx is notified
+			// separately, but all inputs are signaled on the first
+			// notification. So the confirmation is only published
+			// once.
+			if isOurTx && signaled {
+				s.cfg.EventBus.Publish(
+					eventbus.SweepConfirmedEvent{
+						Tx:     spend.SpendingTx,
+						Height: spend.SpendingHeight,
+					},
+				)
 			}
 
 			// Now that an input of ours is spent, we can try to
@@ -530,52 +821,54 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch,
 		// A new external request has been received to retrieve all of
 		// the inputs we're currently attempting to sweep.
 		case req := <-s.pendingSweepsReqs:
-			req.respChan <- s.handlePendingSweepsReq(req)
-
-		// The timer expires and we are going to (re)sweep.
-		case <-s.timer:
-			log.Debugf("Sweep timer expired")
-
-			// Set timer to nil so we know that a new timer needs to
-			// be started when new inputs arrive.
-			s.timer = nil
-
-			// We'll attempt to cluster all of our inputs with
-			// similar fee rates. Before attempting to sweep them,
-			// we'll sort them in descending fee rate order. We do
-			// this to ensure any inputs which have had their fee
-			// rate bumped are broadcast first in order enforce the
-			// RBF policy.
-			inputClusters := s.clusterBySweepFeeRate()
-			sort.Slice(inputClusters, func(i, j int) bool {
-				return inputClusters[i].sweepFeeRate >
-					inputClusters[j].sweepFeeRate
-			})
-			for _, cluster := range inputClusters {
-				// Examine pending inputs and try to construct
-				// lists of inputs.
-				inputLists, err := s.getInputLists(
-					cluster, bestHeight,
-				)
-				if err != nil {
-					log.Errorf("Unable to examine pending "+
-						"inputs: %v", err)
-					continue
-				}
+			req.respChan <- s.handlePendingSweepsReq(req, bestHeight)
+
+		// A new external request has been received to retrieve the fee
+		// calibration report.
+		case req := <-s.feeCalibrationReqs:
+			req.respChan <- s.handleFeeCalibrationReq()
+
+		// A new external request has been received to bump the fee
+		// rate of a transaction by replacing it.
+		case req := <-s.bumpTxReqs:
+			req.respChan <- s.handleBumpTxReq(req, bestHeight)
+
+		// A new external request has been received to retrieve the
+		// current clusters of pending inputs.
+		case req := <-s.listClustersReqs:
+			req.respChan <- s.handleListClustersReq(bestHeight)
+
+		// A new external request has been received to retrieve the
+		// accounting of swept inputs.
+		case req := <-s.accountingReqs:
+			req.respChan <- s.handleAccountingReq()
+
+		// A watchtower client registers a justice tx that may be
+		// published on our behalf.
+		case req := <-s.justiceReqs:
+			req.register <- s.handleJusticeReq(req, bestHeight)
+
+		// A sweep tx that was published before we restarted
+		// confirmed.
+		case tx := <-s.confirmedSweepTxs:
+			s.handleConfirmedSweepTx(tx, bestHeight)
 
-				// Sweep selected inputs.
-				for _, inputs := range inputLists {
-					err := s.sweep(
-						inputs, cluster.sweepFeeRate,
-						bestHeight,
-					)
-					if err != nil {
-						log.Errorf("Unable to sweep "+
-							"inputs: %v", err)
-					}
-				}
+			// For testing purposes.
+			if s.testConfirmedSweepTxChan != nil {
+				s.testConfirmedSweepTxChan <- tx.TxHash()
 			}
 
+		// The timer of one of the urgency lanes expires and we are
+		// going to (re)sweep the inputs in that lane.
+		case <-s.timers[UrgencyCritical]:
+			s.sweepLane(UrgencyCritical, bestHeight)
+
+		case <-s.timers[UrgencyNormal]:
+			s.sweepLane(UrgencyNormal, bestHeight)
+
+		case <-s.timers[UrgencyEconomic]:
+			s.sweepLane(UrgencyEconomic, bestHeight)
+
 		// A new block comes in. Things may have changed, so we retry a
 		// sweep.
 		case epoch, ok := <-blockEpochs:
@@ -588,6 +881,9 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch,
 			log.Debugf("New block: height=%v, sha=%v",
 				epoch.Height, epoch.Hash)
 
+			s.updateHeightHints(bestHeight)
+			s.pruneSweptInputs(bestHeight)
+
 			if err := s.scheduleSweep(bestHeight); err != nil {
 				log.Errorf("schedule sweep: %v", err)
 			}
@@ -598,6 +894,49 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch,
 	}
 }
 
+// sweepLane is called when the batch timer of the given lane expires. It
+// attempts to sweep all pending inputs in the lane.
+func (s *UtxoSweeper) sweepLane(lane Urgency, bestHeight int32) {
+	log.Debugf("Sweep timer expired for %v lane", lane)
+
+	// Set timer to nil so we know that a new timer needs to be started
+	// when new inputs arrive.
+	s.timers[lane] = nil
+
+	// We'll attempt to cluster all of our inputs with similar fee rates.
+	// Before attempting to sweep them, we'll sort them in descending fee
+	// rate order. We do this to ensure any inputs which have had their fee
+	// rate bumped are broadcast first in order enforce the RBF policy.
+	inputClusters := s.clusterBySweepFeeRate(lane)
+	sort.Slice(inputClusters, func(i, j int) bool {
+		return inputClusters[i].sweepFeeRate >
+			inputClusters[j].sweepFeeRate
+	})
+	for _, cluster := range inputClusters {
+		// Examine pending inputs and try to construct lists of inputs.
+		inputLists, err := s.getInputLists(cluster, bestHeight)
+		if err != nil {
+			log.Errorf("Unable to examine pending inputs: %v", err)
+			continue
+		}
+
+		// Sweep selected inputs.
+		for _, inputs := range inputLists {
+			if !s.canPublishSweep(inputs) {
+				log.Debugf("Deferring sweep of %v inputs, %v "+
+					"sweeps unconfirmed", len(inputs),
+					s.numUnconfirmedSweeps())
+				continue
+			}
+
+			err := s.sweep(inputs, cluster.sweepFeeRate, bestHeight)
+			if err != nil {
+				log.Errorf("Unable to sweep inputs: %v", err)
+			}
+		}
+	}
+}
+
 // bucketForFeeReate determines the proper bucket for a fee rate. This is done
 // in order to batch inputs with similar fee rates together.
 func (s *UtxoSweeper) bucketForFeeRate(
@@ -609,18 +948,23 @@ func (s *UtxoSweeper) bucketForFeeRate(
 	)
 }
 
-// clusterBySweepFeeRate takes the set of pending inputs within the UtxoSweeper
-// and clusters those together with similar fee rates. Each cluster contains a
-// sweep fee rate, which is determined by calculating the average fee rate of
-// all inputs within that cluster.
-func (s *UtxoSweeper) clusterBySweepFeeRate() []inputCluster {
+// clusterBySweepFeeRate takes the set of pending inputs of the given urgency
+// lane within the UtxoSweeper and clusters those together with similar fee
+// rates. Each cluster contains a sweep fee rate, which is determined by
+// calculating the average fee rate of all inputs within that cluster.
+func (s *UtxoSweeper) clusterBySweepFeeRate(lane Urgency) []inputCluster {
 	bucketInputs := make(map[lnwallet.SatPerKWeight]pendingInputs)
 	inputFeeRates := make(map[wire.OutPoint]lnwallet.SatPerKWeight)
 
 	// First, we'll group together all inputs with similar fee rates. This
 	// is done by determining the fee rate bucket they should belong in.
 	for op, input := range s.pendingInputs {
-		feeRate, err := s.feeRateForPreference(input.feePreference)
+		// Inputs are never clustered with inputs of another lane.
+		if input.feePreference.Urgency != lane {
+			continue
+		}
+
+		feeRate, err := s.feeRateForInput(input)
 		if err != nil {
 			log.Warnf("Skipping input %v: %v", op, err)
 			continue
@@ -656,28 +1000,42 @@ func (s *UtxoSweeper) clusterBySweepFeeRate() []inputCluster {
 	return inputClusters
 }
 
-// scheduleSweep starts the sweep timer to create an opportunity for more inputs
-// to be added.
+// scheduleSweep starts the sweep timer of every urgency lane that has inputs
+// ready to be swept, to create an opportunity for more inputs to be added.
 func (s *UtxoSweeper) scheduleSweep(currentHeight int32) error {
+	for lane := Urgency(0); lane < numUrgencyLanes; lane++ {
+		if err := s.scheduleLaneSweep(lane, currentHeight); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scheduleLaneSweep starts the sweep timer of the given lane to create an
+// opportunity for more inputs to be added.
+func (s *UtxoSweeper) scheduleLaneSweep(lane Urgency,
+	currentHeight int32) error {
+
 	// The timer is already ticking, no action needed for the sweep to
 	// happen.
-	if s.timer != nil {
-		log.Debugf("Timer still ticking")
+	if s.timers[lane] != nil {
+		log.Debugf("Timer still ticking for %v lane", lane)
 		return nil
 	}
 
 	// We'll only start our timer once we have inputs we're able to sweep.
 	startTimer := false
-	for _, cluster := range s.clusterBySweepFeeRate() {
+	for _, cluster := range s.clusterBySweepFeeRate(lane) {
 		// Examine pending inputs and try to construct lists of inputs.
 		inputLists, err := s.getInputLists(cluster, currentHeight)
 		if err != nil {
 			return fmt.Errorf("get input lists: %v", err)
 		}
 
-		log.Infof("Sweep candidates at height=%v with fee_rate=%v, "+
-			"yield %v distinct txns", currentHeight,
-			cluster.sweepFeeRate, len(inputLists))
+		log.Infof("Sweep candidates in %v lane at height=%v with "+
+			"fee_rate=%v, yield %v distinct txns", lane,
+			currentHeight, cluster.sweepFeeRate, len(inputLists))
 
 		if len(inputLists) != 0 {
 			startTimer = true
@@ -690,9 +1048,9 @@ func (s *UtxoSweeper) scheduleSweep(currentHeight int32) error {
 
 	// Start sweep timer to create opportunity for more inputs to be added
 	// before a tx is constructed.
-	s.timer = s.cfg.NewBatchTimer()
+	s.timers[lane] = s.newLaneTimer(lane)
 
-	log.Debugf("Sweep timer started")
+	log.Debugf("Sweep timer started for %v lane", lane)
 
 	return nil
 }
@@ -715,6 +1073,8 @@ func (s *UtxoSweeper) signalAndRemove(outpoint *wire.OutPoint, result Result) {
 		)
 	}
 
+	s.recordOutcome(pendInput, result)
+
 	// Signal all listeners. Channel is buffered. Because we only send once
 	// on every channel, it should never block.
 	for _, resultChan := range listeners {
@@ -729,14 +1089,33 @@ func (s *UtxoSweeper) signalAndRemove(outpoint *wire.OutPoint, result Result) {
 		pendInput.ntfnRegCancel()
 	}
 
+	// Release the lease of the input, as the sweeper no longer attempts to
+	// spend it.
+	s.releaseLeases(*outpoint)
+
+	if pendInput.metadata != nil {
+		err := s.cfg.Store.DeleteInputMetadata(*outpoint)
+		if err != nil {
+			log.Errorf("Unable to delete metadata of input %v: %v",
+				outpoint, err)
+		}
+	}
+
+	if err := s.cfg.Store.DeleteHeightHint(*outpoint); err != nil {
+		log.Errorf("Unable to delete height hint of input %v: %v",
+			outpoint, err)
+	}
+
 	// Inputs are no longer pending after result has been sent.
 	delete(s.pendingInputs, *outpoint)
+	delete(s.unconfirmedSweeps, *outpoint)
 }
 
 // getInputLists goes through the given inputs and constructs multiple distinct
 // sweep lists with the given fee rate, each up to the configured maximum number
 // of inputs. Negative yield inputs are skipped. Transactions with an output
-// below the dust limit are not published. Those inputs remain pending and will
+// below the dust limit or the configured minimum output amount are not
+// published. Those inputs remain pending and will
 // be bundled with future inputs if possible.
 func (s *UtxoSweeper) getInputLists(cluster inputCluster,
 	currentHeight int32) ([]inputSet, error) {
@@ -775,6 +1154,7 @@ func (s *UtxoSweeper) getInputLists(cluster inputCluster,
 		allSets, err = generateInputPartitionings(
 			append(retryInputs, newInputs...), s.relayFeeRate,
 			cluster.sweepFeeRate, s.cfg.MaxInputsPerTx,
+			s.MinOutputAmount(DestinationWallet),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("input partitionings: %v", err)
@@ -784,7 +1164,7 @@ func (s *UtxoSweeper) getInputLists(cluster inputCluster,
 	// Create sets for just the new inputs.
 	newSets, err := generateInputPartitionings(
 		newInputs, s.relayFeeRate, cluster.sweepFeeRate,
-		s.cfg.MaxInputsPerTx,
+		s.cfg.MaxInputsPerTx, s.MinOutputAmount(DestinationWallet),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("input partitionings: %v", err)
@@ -803,6 +1183,12 @@ func (s *UtxoSweeper) getInputLists(cluster inputCluster,
 func (s *UtxoSweeper) sweep(inputs inputSet, feeRate lnwallet.SatPerKWeight,
 	currentHeight int32) error {
 
+	// Don't waste a publish attempt on a tx that the backend's mempool
+	// would reject.
+	if s.skipBelowMempoolFee(inputs, feeRate, currentHeight) {
+		return nil
+	}
+
 	// Generate an output script if there isn't an unused script available.
 	if s.currentOutputScript == nil {
 		pkScript, err := s.cfg.GenSweepScript()
@@ -812,12 +1198,20 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate lnwallet.SatPerKWeight,
 		s.currentOutputScript = pkScript
 	}
 
+	// Lease the inputs with the wallet before we use them, so that the
+	// wallet's coin selection won't attempt to spend them concurrently.
+	// The newly taken leases are released again if the sweep tx can't be
+	// published.
+	leased := s.leaseInputs(inputs)
+
 	// Create sweep tx.
 	tx, err := createSweepTx(
 		inputs, s.currentOutputScript, uint32(currentHeight), feeRate,
+		s.cfg.TxOrdering, s.cfg.TxOptions, s.witnessCache,
 		s.cfg.Signer,
 	)
 	if err != nil {
+		s.releaseLeases(leased...)
 		return fmt.Errorf("create sweep tx: %v", err)
 	}
 
@@ -828,6 +1222,7 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate lnwallet.SatPerKWeight,
 	// then and would also not add the hash to the store.
 	err = s.cfg.Store.NotifyPublishTx(tx)
 	if err != nil {
+		s.releaseLeases(leased...)
 		return fmt.Errorf("notify publish tx: %v", err)
 	}
 
@@ -843,15 +1238,51 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate lnwallet.SatPerKWeight,
 
 	err = s.cfg.PublishTransaction(tx)
 
-	// In case of an unexpected error, don't try to recover.
-	if err != nil && err != lnwallet.ErrDoubleSpend {
-		return fmt.Errorf("publish tx: %v", err)
+	// If the tx wasn't published, release the leases taken for it, as its
+	// inputs aren't spent by us.
+	if err != nil {
+		s.releaseLeases(leased...)
+	}
+
+	// Classify the rejection, so that it can be reported to the clients of
+	// the inputs. In case of an unknown error, don't try to recover. All
+	// other errors are retried according to the attempt schedule, because
+	// the next transaction sweeping the inputs may differ in its fee rate
+	// and input set.
+	var publishErr *PublishError
+	if err != nil {
+		publishErr = newPublishError(tx.TxHash(), currentHeight, err)
+		if publishErr.Class == PublishErrorUnknown {
+			return publishErr
+		}
+
+		log.Warnf("Sweep tx rejected: %v", publishErr)
 	}
 
 	// Keep the output script in case of an error, so that it can be reused
-	// for the next transaction and causes no address inflation.
+	// for the next transaction and causes no address inflation. If the tx
+	// was published, record its fee rate for calibration purposes.
 	if err == nil {
 		s.currentOutputScript = nil
+		s.trackUnconfirmedSweep(tx)
+
+		s.cfg.EventBus.Publish(eventbus.SweepBroadcastEvent{
+			Tx:      tx,
+			FeeRate: feeRate,
+			Height:  currentHeight,
+		})
+
+		record := newSweepFeeRecord(tx, inputs, feeRate)
+		for _, inp := range inputs {
+			pi, ok := s.pendingInputs[*inp.OutPoint()]
+			if !ok || pi.metadata == nil {
+				continue
+			}
+			record.InputMetadata = append(
+				record.InputMetadata, *pi.metadata,
+			)
+		}
+		s.recordSweepFee(record)
 	}
 
 	// Reschedule sweep.
@@ -866,6 +1297,7 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate lnwallet.SatPerKWeight,
 
 		// Record another publish attempt.
 		pi.publishAttempts++
+		pi.lastPublishErr = publishErr
 
 		// We don't care what the result of the publish call was. Even
 		// if it is published successfully, it can still be that it
@@ -885,7 +1317,8 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate lnwallet.SatPerKWeight,
 		if pi.publishAttempts >= s.cfg.MaxSweepAttempts {
 			// Signal result channels sweep result.
 			s.signalAndRemove(&input.PreviousOutPoint, Result{
-				Err: ErrTooManyAttempts,
+				Err:              ErrTooManyAttempts,
+				LastPublishError: publishErr,
 			})
 		}
 	}
@@ -956,14 +1389,23 @@ func (s *UtxoSweeper) PendingInputs() (map[wire.OutPoint]*PendingInput, error) {
 
 // handlePendingSweepsReq handles a request to retrieve all pending inputs the
 // UtxoSweeper is attempting to sweep.
-func (s *UtxoSweeper) handlePendingSweepsReq(
-	req *pendingSweepsReq) map[wire.OutPoint]*PendingInput {
+func (s *UtxoSweeper) handlePendingSweepsReq(req *pendingSweepsReq,
+	bestHeight int32) map[wire.OutPoint]*PendingInput {
 
 	pendingInputs := make(map[wire.OutPoint]*PendingInput, len(s.pendingInputs))
 	for _, pendingInput := range s.pendingInputs {
 		// Only the exported fields are set, as we expect the response
 		// to only be consumed externally.
 		op := *pendingInput.input.OutPoint()
+
+		var blocksUntilBroadcast uint32
+		if pendingInput.minPublishHeight > bestHeight {
+			blocksUntilBroadcast = uint32(
+				pendingInput.minPublishHeight - bestHeight,
+			)
+		}
+		retrySchedule := s.retrySchedule(pendingInput.publishAttempts)
+
 		pendingInputs[op] = &PendingInput{
 			OutPoint:    op,
 			WitnessType: pendingInput.input.WitnessType(),
@@ -973,12 +1415,31 @@ func (s *UtxoSweeper) handlePendingSweepsReq(
 			LastFeeRate:         pendingInput.lastFeeRate,
 			BroadcastAttempts:   pendingInput.publishAttempts,
 			NextBroadcastHeight: uint32(pendingInput.minPublishHeight),
+			Urgency:             pendingInput.feePreference.Urgency,
+			Metadata:            pendingInput.metadata,
+
+			BlocksUntilNextBroadcast: blocksUntilBroadcast,
+			RetrySchedule:            retrySchedule,
+			LastPublishError:         pendingInput.lastPublishErr,
+			MempoolFeeSkip:           pendingInput.lastFeeSkip,
 		}
 	}
 
 	return pendingInputs
 }
 
+// retrySchedule returns the number of blocks that are waited after each of the
+// broadcast attempts that follow the given number of attempts. The final
+// attempt isn't followed by a retry, so it isn't part of the schedule.
+func (s *UtxoSweeper) retrySchedule(attempts int) []int32 {
+	var schedule []int32
+	for i := attempts + 1; i < s.cfg.MaxSweepAttempts; i++ {
+		schedule = append(schedule, s.cfg.NextAttemptDeltaFunc(i))
+	}
+
+	return schedule
+}
+
 // CreateSweepTx accepts a list of inputs and signs and generates a txn that
 // spends from them. This method also makes an accurate fee estimate before
 // generating the required witnesses.
@@ -986,16 +1447,19 @@ func (s *UtxoSweeper) handlePendingSweepsReq(
 // The created transaction has a single output sending all the funds back to
 // the source wallet, after accounting for the fee estimate.
 //
-// The value of currentBlockHeight argument will be set as the tx locktime.
-// This function assumes that all CLTV inputs will be unlocked after
-// currentBlockHeight. Reasons not to use the maximum of all actual CLTV expiry
-// values of the inputs:
+// The value of currentBlockHeight argument will be set as the tx locktime,
+// unless the tx options randomize it. This function assumes that all CLTV
+// inputs will be unlocked after currentBlockHeight. Reasons not to use the
+// maximum of all actual CLTV expiry values of the inputs:
 //
 // - Make handling re-orgs easier.
 // - Thwart future possible fee sniping attempts.
 // - Make us blend in with the bitcoind wallet.
+//
+// The tx options are optional. If not set, the options of the sweeper config
+// are used.
 func (s *UtxoSweeper) CreateSweepTx(inputs []input.Input, feePref FeePreference,
-	currentBlockHeight uint32) (*wire.MsgTx, error) {
+	currentBlockHeight uint32, opts ...SweepTxOptions) (*wire.MsgTx, error) {
 
 	feePerKw, err := DetermineFeePerKw(s.cfg.FeeEstimator, feePref)
 	if err != nil {
@@ -1008,8 +1472,14 @@ func (s *UtxoSweeper) CreateSweepTx(inputs []input.Input, feePref FeePreference,
 		return nil, err
 	}
 
+	txOptions := s.cfg.TxOptions
+	if len(opts) > 0 {
+		txOptions = opts[0]
+	}
+
 	return createSweepTx(
-		inputs, pkScript, currentBlockHeight, feePerKw, s.cfg.Signer,
+		inputs, pkScript, currentBlockHeight, feePerKw,
+		s.cfg.TxOrdering, txOptions, s.witnessCache, s.cfg.Signer,
 	)
 }
 
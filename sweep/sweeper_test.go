@@ -2,11 +2,14 @@ package sweep
 
 import (
 	"os"
+	"reflect"
 	"runtime/debug"
 	"runtime/pprof"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
@@ -162,6 +165,31 @@ func (ctx *sweeperTestContext) restartSweeper() {
 	ctx.sweeper.Start()
 }
 
+// confirmSweepTx confirms a stored sweep tx that the sweeper watches after a
+// restart, and waits for the sweeper to process the confirmation.
+func (ctx *sweeperTestContext) confirmSweepTx(tx *wire.MsgTx) {
+	ctx.t.Helper()
+
+	confirmed := make(chan chainhash.Hash, 1)
+	ctx.sweeper.testConfirmedSweepTxChan = confirmed
+
+	txHash := tx.TxHash()
+	if err := ctx.notifier.ConfirmTx(&txHash, 95); err != nil {
+		ctx.t.Fatal(err)
+	}
+
+	select {
+	case hash := <-confirmed:
+		if hash != txHash {
+			ctx.t.Fatalf("expected confirmation of %v, got %v",
+				txHash, hash)
+		}
+
+	case <-time.After(defaultTestTimeout):
+		ctx.t.Fatalf("confirmation of %v not processed", txHash)
+	}
+}
+
 func (ctx *sweeperTestContext) tick() {
 	testLog.Trace("Waiting for tick to be consumed")
 	select {
@@ -411,6 +439,373 @@ func TestSuccess(t *testing.T) {
 	}
 }
 
+// TestFeeCalibrationReport asserts that the projected and actual fee rate of a
+// published sweep are recorded.
+func TestFeeCalibrationReport(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	resultChan, err := ctx.sweeper.SweepInput(
+		spendableInputs[0], defaultFeePref,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+
+	sweepTx := ctx.receiveTx()
+
+	report, err := ctx.sweeper.FeeCalibrationReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Records) != 1 {
+		t.Fatalf("expected 1 fee record, got %v", len(report.Records))
+	}
+
+	record := report.Records[0]
+	if record.TxID != sweepTx.TxHash() {
+		t.Fatalf("expected record for tx %v, got %v",
+			sweepTx.TxHash(), record.TxID)
+	}
+	if record.ProjectedFeeRate == 0 || record.ActualFeeRate == 0 {
+		t.Fatalf("expected non-zero fee rates: %v", record)
+	}
+	if record.Fee <= 0 {
+		t.Fatalf("expected positive fee, got %v", record.Fee)
+	}
+	if report.MeanRatio <= 0 || report.MinRatio != report.MaxRatio {
+		t.Fatalf("unexpected ratios: mean=%v, min=%v, max=%v",
+			report.MeanRatio, report.MinRatio, report.MaxRatio)
+	}
+
+	ctx.backend.mine()
+
+	ctx.expectResult(resultChan, nil)
+
+	ctx.finish(1)
+}
+
+// TestListClusters asserts that the clusters of pending inputs are reported
+// together with the sweep transactions that would be published for them.
+func TestListClusters(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	var resultChans []chan Result
+	for _, inp := range spendableInputs[:2] {
+		resultChan, err := ctx.sweeper.SweepInput(inp, defaultFeePref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resultChans = append(resultChans, resultChan)
+	}
+
+	reports, err := ctx.sweeper.ListClusters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 cluster, got %v", len(reports))
+	}
+
+	report := reports[0]
+	if len(report.Inputs) != 2 {
+		t.Fatalf("expected 2 inputs, got %v", len(report.Inputs))
+	}
+	if report.FeeRate == 0 {
+		t.Fatalf("expected non-zero fee rate")
+	}
+	if !report.TimerActive {
+		t.Fatalf("expected timer to be active")
+	}
+	if report.NextPublishHeight != mockChainIOHeight {
+		t.Fatalf("expected next publish height %v, got %v",
+			mockChainIOHeight, report.NextPublishHeight)
+	}
+	if len(report.ProjectedTxs) != 1 {
+		t.Fatalf("expected 1 projected tx, got %v",
+			len(report.ProjectedTxs))
+	}
+
+	projected := report.ProjectedTxs[0]
+	if len(projected.Inputs) != 2 {
+		t.Fatalf("expected projected tx with 2 inputs, got %v",
+			len(projected.Inputs))
+	}
+	if projected.Weight <= 0 || projected.Fee <= 0 {
+		t.Fatalf("unexpected projected tx: %v", projected)
+	}
+
+	ctx.tick()
+
+	sweepTx := ctx.receiveTx()
+	if len(sweepTx.TxIn) != len(projected.Inputs) {
+		t.Fatalf("expected tx with %v inputs, got %v",
+			len(projected.Inputs), len(sweepTx.TxIn))
+	}
+
+	ctx.backend.mine()
+
+	for _, resultChan := range resultChans {
+		ctx.expectResult(resultChan, nil)
+	}
+
+	ctx.finish(1)
+}
+
+// TestInputMetadata asserts that the metadata of an input is exposed while the
+// input is pending, restored after a restart and removed once the input is
+// swept.
+func TestInputMetadata(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	metadata := &InputMetadata{
+		ChanPoint: wire.OutPoint{Index: 3},
+		Reason:    SweepReasonHtlcTimeout,
+	}
+
+	assertMetadata := func(op wire.OutPoint) {
+		t.Helper()
+
+		pendingInputs, err := ctx.sweeper.PendingInputs()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pendingInput, ok := pendingInputs[op]
+		if !ok {
+			t.Fatalf("input %v not pending", op)
+		}
+		if pendingInput.Metadata == nil ||
+			*pendingInput.Metadata != *metadata {
+
+			t.Fatalf("expected metadata %v, got %v", metadata,
+				pendingInput.Metadata)
+		}
+	}
+
+	input1 := spendableInputs[0]
+	_, err := ctx.sweeper.SweepInputWithMetadata(
+		input1, defaultFeePref, metadata,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertMetadata(*input1.OutPoint())
+
+	ctx.tick()
+
+	ctx.receiveTx()
+
+	// The sweep should be reported along with the metadata of its input.
+	report, err := ctx.sweeper.FeeCalibrationReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Records) != 1 ||
+		len(report.Records[0].InputMetadata) != 1 ||
+		report.Records[0].InputMetadata[0] != *metadata {
+
+		t.Fatalf("expected metadata in fee report: %v", report)
+	}
+
+	// After a restart, the input is re-offered without metadata. The
+	// persisted metadata should be restored.
+	ctx.restartSweeper()
+
+	ctx.receiveTx()
+
+	resultChan, err := ctx.sweeper.SweepInput(input1, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertMetadata(*input1.OutPoint())
+
+	ctx.backend.mine()
+
+	ctx.expectResult(resultChan, nil)
+
+	// Synchronize with the sweeper to make sure that the input has been
+	// fully processed, before asserting that its metadata is removed.
+	if _, err := ctx.sweeper.PendingInputs(); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := ctx.store.FetchInputMetadata(*input1.OutPoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != nil {
+		t.Fatalf("expected metadata to be removed, got %v", stored)
+	}
+
+	ctx.finish(1)
+}
+
+// TestAccounting asserts that the values of inputs are accounted as pending,
+// recovered or abandoned, and bucketed by witness type and channel.
+func TestAccounting(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	assertTotals := func(recovered, pending, abandoned btcutil.Amount) {
+		t.Helper()
+
+		accounting, err := ctx.sweeper.Accounting()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := SweepTotals{
+			Recovered: recovered,
+			Pending:   pending,
+			Abandoned: abandoned,
+		}
+		if accounting.Totals != expected {
+			t.Fatalf("expected totals %v, got %v", expected,
+				accounting.Totals)
+		}
+
+		byType := accounting.ByWitnessType[input.CommitmentTimeLock]
+		if byType != expected {
+			t.Fatalf("expected witness type totals %v, got %v",
+				expected, byType)
+		}
+	}
+
+	chanPoint := wire.OutPoint{Index: 5}
+	input1 := spendableInputs[0]
+	resultChan1, err := ctx.sweeper.SweepInputWithMetadata(
+		input1, defaultFeePref, &InputMetadata{
+			ChanPoint: chanPoint,
+			Reason:    SweepReasonCommitment,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input2 := spendableInputs[1]
+	resultChan2, err := ctx.sweeper.SweepInput(input2, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value1 := btcutil.Amount(input1.SignDesc().Output.Value)
+	value2 := btcutil.Amount(input2.SignDesc().Output.Value)
+	assertTotals(0, value1+value2, 0)
+
+	// The second input is spent by a remote party, so it is abandoned.
+	remoteTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{
+			{
+				PreviousOutPoint: *input2.OutPoint(),
+			},
+		},
+	}
+	if err := ctx.backend.publishTransaction(remoteTx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.backend.mine()
+
+	ctx.expectResult(resultChan2, ErrRemoteSpend)
+
+	assertTotals(0, value1, value2)
+
+	// The first input is swept by us, so it is recovered.
+	ctx.tick()
+	ctx.receiveTx()
+	ctx.backend.mine()
+
+	ctx.expectResult(resultChan1, nil)
+
+	assertTotals(value1, 0, value2)
+
+	accounting, err := ctx.sweeper.Accounting()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounting.ByChannel) != 1 ||
+		accounting.ByChannel[chanPoint].Recovered != value1 {
+
+		t.Fatalf("unexpected channel totals: %v",
+			accounting.ByChannel)
+	}
+
+	ctx.finish(1)
+}
+
+// TestSweepFeeAttribution asserts that the fee of a confirmed sweep is
+// attributed to the channels of its inputs in proportion to their values.
+func TestSweepFeeAttribution(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	chanPoint1 := wire.OutPoint{Index: 1}
+	input1 := spendableInputs[0]
+	resultChan1, err := ctx.sweeper.SweepInputWithMetadata(
+		input1, defaultFeePref, &InputMetadata{
+			ChanPoint: chanPoint1,
+			Reason:    SweepReasonCommitment,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chanPoint2 := wire.OutPoint{Index: 2}
+	input2 := spendableInputs[1]
+	resultChan2, err := ctx.sweeper.SweepInputWithMetadata(
+		input2, defaultFeePref, &InputMetadata{
+			ChanPoint: chanPoint2,
+			Reason:    SweepReasonCommitment,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+
+	sweepTx := ctx.receiveTx()
+	if len(sweepTx.TxIn) != 2 {
+		t.Fatalf("expected 2 inputs, got %v", len(sweepTx.TxIn))
+	}
+
+	ctx.backend.mine()
+
+	ctx.expectResult(resultChan1, nil)
+	ctx.expectResult(resultChan2, nil)
+
+	value1 := btcutil.Amount(input1.SignDesc().Output.Value)
+	value2 := btcutil.Amount(input2.SignDesc().Output.Value)
+	fee := value1 + value2 - btcutil.Amount(sweepTx.TxOut[0].Value)
+
+	accounting, err := ctx.sweeper.Accounting()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accounting.Fees != fee {
+		t.Fatalf("expected fees %v, got %v", fee, accounting.Fees)
+	}
+
+	fee1 := accounting.FeesByChannel[chanPoint1]
+	fee2 := accounting.FeesByChannel[chanPoint2]
+	if fee1+fee2 != fee {
+		t.Fatalf("expected channel fees to add up to %v, got %v+%v",
+			fee, fee1, fee2)
+	}
+
+	// The second input has the higher value, so it is attributed the
+	// larger share of the fee.
+	expectedFee2 := fee * value2 / (value1 + value2)
+	if fee2 != expectedFee2 || fee1 >= fee2 {
+		t.Fatalf("unexpected channel fees %v and %v", fee1, fee2)
+	}
+
+	ctx.finish(1)
+}
+
 // TestDust asserts that inputs that are not big enough to raise above the dust
 // limit, are held back until the total set does surpass the limit.
 func TestDust(t *testing.T) {
@@ -456,6 +851,51 @@ func TestDust(t *testing.T) {
 	ctx.finish(1)
 }
 
+// TestMinOutputAmount asserts that inputs that would create a sweep output
+// below the configured minimum are carried over until the output is large
+// enough.
+func TestMinOutputAmount(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	ctx.sweeper.cfg.MinOutputAmounts = map[DestinationType]btcutil.Amount{
+		DestinationWallet: 150000,
+	}
+
+	// Sweeping a single input of this size results in an output that is
+	// well above the dust limit, but below the minimum output amount.
+	smallInput := createTestInput(100000, input.CommitmentTimeLock)
+
+	_, err := ctx.sweeper.SweepInput(&smallInput, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No sweep transaction is expected now. Sweep another input that
+	// brings the tx output above the minimum.
+	otherInput := createTestInput(100000, input.CommitmentTimeLock)
+
+	_, err = ctx.sweeper.SweepInput(&otherInput, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+
+	sweepTx := ctx.receiveTx()
+	if len(sweepTx.TxIn) != 2 {
+		t.Fatalf("Expected tx to sweep 2 inputs, but contains %v "+
+			"inputs instead", len(sweepTx.TxIn))
+	}
+	if sweepTx.TxOut[0].Value < 150000 {
+		t.Fatalf("Expected output of at least 150000 sat, got %v",
+			sweepTx.TxOut[0].Value)
+	}
+
+	ctx.backend.mine()
+
+	ctx.finish(1)
+}
+
 // TestNegativeInput asserts that no inputs with a negative yield are swept.
 // Negative yield means that the value minus the added fee is negative.
 func TestNegativeInput(t *testing.T) {
@@ -886,7 +1326,207 @@ func TestRestartConfirmed(t *testing.T) {
 	ctx.finish(1)
 }
 
-// TestRestartRepublish asserts that sweeper republishes the last published
+// TestRestartReconcile asserts that the sweeper finds out that its last tx
+// confirmed while it was down, and signals the re-offered inputs right away.
+func TestRestartReconcile(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	input := spendableInputs[0]
+	if _, err := ctx.sweeper.SweepInput(input, defaultFeePref); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+
+	sweepTx := ctx.receiveTx()
+
+	// Without a height hint, the last tx isn't watched and is only
+	// republished.
+	ctx.restartSweeper()
+	ctx.receiveTx()
+
+	// Simulate a block epoch that stored a height hint for the input and
+	// the confirmation of the sweep tx while the sweeper is down.
+	err := ctx.store.UpdateHeightHints(
+		[]wire.OutPoint{*input.OutPoint()}, 90,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.backend.mine()
+
+	// The last tx is republished on restart, which the backend rejects.
+	// Its confirmation is found in the background.
+	ctx.restartSweeper()
+	ctx.receiveTx()
+	ctx.confirmSweepTx(&sweepTx)
+
+	// Simulate other subsystem (eg contract resolver) re-offering input 0.
+	// It is signaled without waiting for a spend ntfn or a new sweep.
+	spendChan, err := ctx.sweeper.SweepInput(input, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.expectResult(spendChan, nil)
+
+	ctx.finish(1)
+}
+
+// TestRestartReconcileAll asserts that the sweeper reconciles all of its
+// stored sweep txes on restart, not only the last published one, and that the
+// confirmed txes are removed from the store.
+func TestRestartReconcileAll(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	input0 := spendableInputs[0]
+	if _, err := ctx.sweeper.SweepInput(input0, defaultFeePref); err != nil {
+		t.Fatal(err)
+	}
+	ctx.tick()
+	sweepTx0 := ctx.receiveTx()
+
+	// The second input is swept in a separate tx, which becomes the last
+	// published tx.
+	input1 := spendableInputs[1]
+	if _, err := ctx.sweeper.SweepInput(input1, defaultFeePref); err != nil {
+		t.Fatal(err)
+	}
+	ctx.tick()
+	sweepTx1 := ctx.receiveTx()
+	if len(sweepTx1.TxIn) != 1 ||
+		sweepTx1.TxIn[0].PreviousOutPoint != *input1.OutPoint() {
+
+		t.Fatalf("expected separate sweep of input 1")
+	}
+
+	err := ctx.store.UpdateHeightHints(
+		[]wire.OutPoint{*input0.OutPoint(), *input1.OutPoint()}, 90,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the last tx is republished. The first tx is found to have
+	// confirmed while the sweeper was down.
+	ctx.restartSweeper()
+	republishedTx := ctx.receiveTx()
+	if republishedTx.TxHash() != sweepTx1.TxHash() {
+		t.Fatalf("expected republication of %v, got %v",
+			sweepTx1.TxHash(), republishedTx.TxHash())
+	}
+	ctx.confirmSweepTx(&sweepTx0)
+
+	// The input of the confirmed tx is signaled right away when it is
+	// re-offered.
+	spendChan, err := ctx.sweeper.SweepInput(input0, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.expectResult(spendChan, nil)
+
+	sweepTxs, err := ctx.store.FetchSweepTxs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sweepTxs) != 1 || sweepTxs[0].TxHash() != sweepTx1.TxHash() {
+		t.Fatalf("expected only the unconfirmed tx to be stored, "+
+			"got %v", sweepTxs)
+	}
+
+	// Mine and confirm the last tx to conclude the test properly.
+	ctx.backend.mine()
+	ctx.confirmSweepTx(&sweepTx1)
+
+	ctx.finish(1)
+}
+
+// TestRestartReconcileOutputOrder asserts that a stored sweep tx is
+// reconciled regardless of the position of the output that pays to our
+// wallet, as is the case for sweeps with a random output order or with
+// multiple outputs.
+func TestRestartReconcileOutputOrder(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	// Store a sweep tx whose first output doesn't pay to our wallet.
+	input := spendableInputs[0]
+	sweepTx := &wire.MsgTx{
+		Version: 2,
+		TxIn: []*wire.TxIn{
+			{PreviousOutPoint: *input.OutPoint()},
+		},
+		TxOut: []*wire.TxOut{
+			{Value: 3000, PkScript: []byte{0xaa}},
+			{Value: 7000, PkScript: []byte{0xbb}},
+		},
+	}
+	if err := ctx.store.NotifyPublishTx(sweepTx); err != nil {
+		t.Fatal(err)
+	}
+	err := ctx.store.UpdateHeightHints(
+		[]wire.OutPoint{*input.OutPoint()}, 90,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.restartSweeper()
+	ctx.receiveTx()
+	ctx.confirmSweepTx(sweepTx)
+
+	// The re-offered input is signaled with the confirmed tx.
+	spendChan, err := ctx.sweeper.SweepInput(input, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case result := <-spendChan:
+		if result.Err != nil {
+			t.Fatalf("unexpected error: %v", result.Err)
+		}
+		if result.Tx.TxHash() != sweepTx.TxHash() {
+			t.Fatalf("expected tx %v, got %v", sweepTx.TxHash(),
+				result.Tx.TxHash())
+		}
+
+	case <-time.After(defaultTestTimeout):
+		t.Fatalf("no result received")
+	}
+
+	// Mine the republished tx to conclude the test properly.
+	ctx.backend.mine()
+
+	ctx.finish(1)
+}
+
+// TestPruneSweptInputs asserts that inputs that were found to be swept while
+// the sweeper was down are forgotten if they aren't re-offered in time.
+func TestPruneSweptInputs(t *testing.T) {
+	t.Parallel()
+
+	s := &UtxoSweeper{
+		sweptInputs: make(map[wire.OutPoint]*sweptInput),
+	}
+
+	oldInput := wire.OutPoint{Index: 1}
+	newInput := wire.OutPoint{Index: 2}
+	s.sweptInputs[oldInput] = &sweptInput{tx: &wire.MsgTx{}, height: 100}
+	s.sweptInputs[newInput] = &sweptInput{tx: &wire.MsgTx{}, height: 110}
+
+	s.pruneSweptInputs(100 + sweptInputExpiry - 1)
+	if len(s.sweptInputs) != 2 {
+		t.Fatalf("expected no inputs to be pruned")
+	}
+
+	s.pruneSweptInputs(100 + sweptInputExpiry)
+	if _, ok := s.sweptInputs[oldInput]; ok {
+		t.Fatalf("expected old input to be pruned")
+	}
+	if _, ok := s.sweptInputs[newInput]; !ok {
+		t.Fatalf("expected new input to be kept")
+	}
+}
+
+// TestRestartRepublish asserts that sweeper republishes the last published
 // tx on restart.
 func TestRestartRepublish(t *testing.T) {
 	ctx := createSweeperTestContext(t)
@@ -1056,6 +1696,57 @@ func TestDifferentFeePreferences(t *testing.T) {
 	ctx.finish(1)
 }
 
+// TestMaxUnconfirmedSweeps asserts that the sweeper defers the publication of
+// new clusters while the maximum number of unconfirmed sweeps is outstanding,
+// and resumes once a prior sweep confirms.
+func TestMaxUnconfirmedSweeps(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	ctx.sweeper.cfg.MaxUnconfirmedSweeps = 1
+
+	lowFeePref := FeePreference{
+		ConfTarget: 12,
+	}
+	ctx.estimator.blocksToFee[lowFeePref.ConfTarget] = 5000
+	highFeePref := FeePreference{
+		ConfTarget: 6,
+	}
+	ctx.estimator.blocksToFee[highFeePref.ConfTarget] = 10000
+
+	input1 := spendableInputs[0]
+	resultChan1, err := ctx.sweeper.SweepInput(input1, highFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input2 := spendableInputs[1]
+	resultChan2, err := ctx.sweeper.SweepInput(input2, lowFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+
+	// Only the high fee rate cluster is expected to be swept, as the low
+	// fee rate one would exceed the limit.
+	sweepTx1 := ctx.receiveTx()
+	assertTxSweepsInputs(t, &sweepTx1, input1)
+	ctx.assertNoTx()
+
+	// Once the first sweep confirms, the deferred cluster is swept.
+	ctx.backend.mine()
+	ctx.expectResult(resultChan1, nil)
+
+	ctx.tick()
+
+	sweepTx2 := ctx.receiveTx()
+	assertTxSweepsInputs(t, &sweepTx2, input2)
+
+	ctx.backend.mine()
+	ctx.expectResult(resultChan2, nil)
+
+	ctx.finish(1)
+}
+
 // TestPendingInputs ensures that the sweeper correctly determines the inputs
 // pending to be swept.
 func TestPendingInputs(t *testing.T) {
@@ -1123,3 +1814,499 @@ func TestPendingInputs(t *testing.T) {
 
 	ctx.finish(1)
 }
+
+// TestPendingInputRetrySchedule asserts that pending inputs expose the number
+// of blocks until their next broadcast and the schedule of their retries.
+func TestPendingInputRetrySchedule(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	input := spendableInputs[0]
+	resultChan, err := ctx.sweeper.SweepInput(input, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertSchedule := func(attempts int, blocksUntil uint32,
+		schedule []int32) {
+
+		t.Helper()
+
+		pendingInputs, err := ctx.sweeper.PendingInputs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pendingInput, ok := pendingInputs[*input.OutPoint()]
+		if !ok {
+			t.Fatalf("input %v not pending", input.OutPoint())
+		}
+
+		if pendingInput.BroadcastAttempts != attempts {
+			t.Fatalf("expected %v attempts, got %v", attempts,
+				pendingInput.BroadcastAttempts)
+		}
+		if pendingInput.BlocksUntilNextBroadcast != blocksUntil {
+			t.Fatalf("expected %v blocks until next broadcast, "+
+				"got %v", blocksUntil,
+				pendingInput.BlocksUntilNextBroadcast)
+		}
+		if !reflect.DeepEqual(pendingInput.RetrySchedule, schedule) {
+			t.Fatalf("expected retry schedule %v, got %v",
+				schedule, pendingInput.RetrySchedule)
+		}
+	}
+
+	// Before the first broadcast, the input can be swept right away. The
+	// test delta function doubles the delay with every attempt, and the
+	// last attempt isn't retried.
+	assertSchedule(0, 0, []int32{1, 2})
+
+	ctx.tick()
+	ctx.receiveTx()
+
+	// After the first broadcast, the input is retried in the next block.
+	assertSchedule(1, 1, []int32{2})
+
+	ctx.backend.mine()
+	ctx.expectResult(resultChan, nil)
+
+	ctx.finish(1)
+}
+
+// TestMempoolFeeSkip asserts that sweeps below the minimum fee rate of the
+// backend's mempool aren't broadcast, and that skipped broadcasts don't count
+// as publish attempts.
+func TestMempoolFeeSkip(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	// The default fee preference maps to a fee rate of 10000 sat/kw.
+	var mempoolMinFeeRate int64 = 20000
+	ctx.sweeper.cfg.MempoolMinFeeRate = func() (lnwallet.SatPerKWeight,
+		error) {
+
+		return lnwallet.SatPerKWeight(
+			atomic.LoadInt64(&mempoolMinFeeRate),
+		), nil
+	}
+
+	input := spendableInputs[0]
+	resultChan, err := ctx.sweeper.SweepInput(input, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+	ctx.assertNoTx()
+
+	pendingInputs, err := ctx.sweeper.PendingInputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingInput, ok := pendingInputs[*input.OutPoint()]
+	if !ok {
+		t.Fatalf("input %v not pending", input.OutPoint())
+	}
+	if pendingInput.BroadcastAttempts != 0 {
+		t.Fatalf("expected no broadcast attempts, got %v",
+			pendingInput.BroadcastAttempts)
+	}
+	skip := pendingInput.MempoolFeeSkip
+	if skip == nil || skip.FeeRate != 10000 ||
+		skip.MempoolMinFeeRate != 20000 {
+
+		t.Fatalf("unexpected mempool fee skip: %v", skip)
+	}
+
+	// Once the mempool minimum drops, the input is swept at the next
+	// block.
+	atomic.StoreInt64(&mempoolMinFeeRate, 1000)
+	ctx.notifier.NotifyEpoch(101)
+	ctx.tick()
+	ctx.receiveTx()
+
+	pendingInputs, err = ctx.sweeper.PendingInputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingInput = pendingInputs[*input.OutPoint()]
+	if pendingInput.BroadcastAttempts != 1 ||
+		pendingInput.MempoolFeeSkip != nil {
+
+		t.Fatalf("unexpected pending input after broadcast: %v",
+			pendingInput)
+	}
+
+	ctx.backend.mine()
+	ctx.expectResult(resultChan, nil)
+
+	ctx.finish(1)
+}
+
+// TestJusticeTxSpend asserts that the sweeper holds back its sweep of an input
+// that a registered justice tx spends, and that the spend by the justice tx is
+// signaled as a sweep by proxy rather than as a remote spend.
+func TestJusticeTxSpend(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	input := spendableInputs[0]
+	justiceTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{
+			{
+				PreviousOutPoint: *input.OutPoint(),
+			},
+		},
+	}
+	if err := ctx.sweeper.RegisterJusticeTx(justiceTx); err != nil {
+		t.Fatal(err)
+	}
+
+	resultChan, err := ctx.sweeper.SweepInput(input, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sweeper must not compete with the tower that may publish the
+	// justice tx.
+	ctx.tick()
+	ctx.assertNoTx()
+
+	if err := ctx.backend.publishTransaction(justiceTx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.backend.mine()
+
+	select {
+	case result := <-resultChan:
+		if result.Err != nil {
+			t.Fatalf("expected sweep by proxy, got %v", result.Err)
+		}
+		if !result.JusticeTx {
+			t.Fatal("expected spend by justice tx")
+		}
+	case <-time.After(defaultTestTimeout):
+		t.Fatal("no result received")
+	}
+
+	ctx.finish(1)
+}
+
+// TestUrgencyLanes asserts that inputs in different urgency lanes are swept
+// independently, so that a critical input isn't held back by the batch timer
+// of a less urgent lane.
+func TestUrgencyLanes(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	// Give the critical lane its own batch timer, so that it can be
+	// controlled independently of the other lanes.
+	criticalTimers := make(chan chan time.Time, 1)
+	ctx.sweeper.cfg.LanePolicies = map[Urgency]LanePolicy{
+		UrgencyCritical: {
+			NewBatchTimer: func() <-chan time.Time {
+				c := make(chan time.Time, 1)
+				criticalTimers <- c
+				return c
+			},
+		},
+	}
+	ctx.restartSweeper()
+
+	economicPref := defaultFeePref
+	economicPref.Urgency = UrgencyEconomic
+	economicResult, err := ctx.sweeper.SweepInput(
+		spendableInputs[0], economicPref,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	criticalPref := defaultFeePref
+	criticalPref.Urgency = UrgencyCritical
+	criticalResult, err := ctx.sweeper.SweepInput(
+		spendableInputs[1], criticalPref,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expire the critical lane's timer while the economic lane's timer is
+	// still running. Only the critical input should be swept, even though
+	// both inputs share the same fee rate.
+	select {
+	case c := <-criticalTimers:
+		c <- time.Time{}
+	case <-time.After(defaultTestTimeout):
+		t.Fatal("no critical lane timer created")
+	}
+
+	sweepTx := ctx.receiveTx()
+	if len(sweepTx.TxIn) != 1 ||
+		sweepTx.TxIn[0].PreviousOutPoint != *spendableInputs[1].OutPoint() {
+
+		t.Fatalf("expected only the critical input to be swept")
+	}
+
+	ctx.backend.mine()
+	ctx.expectResult(criticalResult, nil)
+
+	// The economic input should only be swept once its own timer expires.
+	ctx.tick()
+
+	sweepTx = ctx.receiveTx()
+	if len(sweepTx.TxIn) != 1 ||
+		sweepTx.TxIn[0].PreviousOutPoint != *spendableInputs[0].OutPoint() {
+
+		t.Fatalf("expected only the economic input to be swept")
+	}
+
+	ctx.backend.mine()
+	ctx.expectResult(economicResult, nil)
+
+	ctx.finish(1)
+}
+
+// TestOutpointLeases asserts that the inputs of a sweep are leased with the
+// wallet, and that the leases are released if the sweep tx can't be published
+// or once the input is no longer pending.
+func TestOutpointLeases(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	locker := newMockOutpointLocker()
+	ctx.sweeper.cfg.OutpointLocker = locker
+	ctx.restartSweeper()
+
+	// assertLease waits for the sweeper to process all prior events, and
+	// then asserts the lease state of the outpoint.
+	assertLease := func(op wire.OutPoint, locked, unlocked bool) {
+		t.Helper()
+
+		if _, err := ctx.sweeper.PendingInputs(); err != nil {
+			t.Fatalf("unable to query pending inputs: %v", err)
+		}
+
+		locker.Lock()
+		defer locker.Unlock()
+
+		if _, ok := locker.lockedOutpoints[op]; ok != locked {
+			t.Fatalf("expected %v locked=%v", op, locked)
+		}
+		if _, ok := locker.unlockedOutpoints[op]; ok != unlocked {
+			t.Fatalf("expected %v unlocked=%v", op, unlocked)
+		}
+	}
+
+	// Spend the first input with an unknown tx, so that the sweep tx will
+	// fail to publish.
+	remoteOp := *spendableInputs[0].OutPoint()
+	remoteTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{{PreviousOutPoint: remoteOp}},
+	}
+	if err := ctx.backend.publishTransaction(remoteTx); err != nil {
+		t.Fatal(err)
+	}
+
+	remoteResult, err := ctx.sweeper.SweepInput(
+		spendableInputs[0], defaultFeePref,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+	ctx.receiveTx()
+
+	// The lease should have been released, as the tx wasn't published.
+	assertLease(remoteOp, true, true)
+
+	ctx.backend.mine()
+	ctx.expectResult(remoteResult, ErrRemoteSpend)
+
+	// Now sweep the second input, which should succeed. The lease should
+	// be held until the input is no longer pending.
+	op := *spendableInputs[1].OutPoint()
+	resultChan, err := ctx.sweeper.SweepInput(
+		spendableInputs[1], defaultFeePref,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+	ctx.receiveTx()
+
+	assertLease(op, true, false)
+
+	ctx.backend.mine()
+	ctx.expectResult(resultChan, nil)
+
+	assertLease(op, true, true)
+
+	ctx.finish(1)
+}
+
+// TestBumpTx asserts that the sweeper bumps its own sweeps by replacing them,
+// and other transactions by sweeping their outputs with a child transaction.
+func TestBumpTx(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	targetFeeRate := lnwallet.SatPerKWeight(20000)
+	targetPref := FeePreference{FeeRate: targetFeeRate}
+
+	feeRate := func(tx *wire.MsgTx, inputValue int64) (btcutil.Amount,
+		lnwallet.SatPerKWeight) {
+
+		fee := btcutil.Amount(inputValue - tx.TxOut[0].Value)
+		weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+		return fee, lnwallet.SatPerKWeight(int64(fee) * 1000 / weight)
+	}
+
+	// Publish a sweep of our own, and request it to be bumped. As all of
+	// its inputs are still pending, it should be replaced.
+	inp := spendableInputs[0]
+	inputValue := inp.SignDesc().Output.Value
+	resultChan, err := ctx.sweeper.SweepInput(inp, defaultFeePref)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+	sweepTx := ctx.receiveTx()
+	sweepFee, sweepFeeRate := feeRate(&sweepTx, inputValue)
+
+	resp, err := ctx.sweeper.BumpTx(&BumpRequest{
+		Tx:            &sweepTx,
+		Fee:           sweepFee,
+		FeePreference: targetPref,
+	})
+	if err != nil {
+		t.Fatalf("unable to bump tx: %v", err)
+	}
+	if resp.Strategy != BumpStrategyRBF || len(resp.Results) != 1 {
+		t.Fatalf("expected rbf with 1 result, got %v with %v results",
+			resp.Strategy, len(resp.Results))
+	}
+
+	// The backend would accept the replacement, evicting the original.
+	ctx.backend.deleteUnconfirmed(sweepTx.TxHash())
+
+	ctx.tick()
+	replacementTx := ctx.receiveTx()
+	_, replacementFeeRate := feeRate(&replacementTx, inputValue)
+	if replacementFeeRate <= sweepFeeRate {
+		t.Fatalf("expected replacement fee rate above %v, got %v",
+			sweepFeeRate, replacementFeeRate)
+	}
+
+	ctx.backend.mine()
+	ctx.expectResult(resultChan, nil)
+	ctx.expectResult(resp.Results[0], nil)
+
+	// A transaction that isn't ours can only be bumped through its
+	// outputs.
+	parentTx := &wire.MsgTx{
+		TxIn: []*wire.TxIn{{
+			PreviousOutPoint: *spendableInputs[1].OutPoint(),
+		}},
+		TxOut: []*wire.TxOut{{Value: 50000}},
+	}
+	parentReq := &BumpRequest{
+		Tx:            parentTx,
+		Fee:           100,
+		FeePreference: targetPref,
+	}
+	if _, err := ctx.sweeper.BumpTx(parentReq); err != ErrCannotBump {
+		t.Fatalf("expected ErrCannotBump, got %v", err)
+	}
+
+	output := input.MakeBaseInput(
+		&wire.OutPoint{Hash: parentTx.TxHash()},
+		input.CommitmentTimeLock,
+		&input.SignDescriptor{
+			Output: parentTx.TxOut[0],
+			KeyDesc: keychain.KeyDescriptor{
+				PubKey: testPubKey,
+			},
+		},
+		0,
+	)
+	parentReq.Outputs = []input.Input{&output}
+
+	resp, err = ctx.sweeper.BumpTx(parentReq)
+	if err != nil {
+		t.Fatalf("unable to bump tx: %v", err)
+	}
+	if resp.Strategy != BumpStrategyCPFP || len(resp.Results) != 1 {
+		t.Fatalf("expected cpfp with 1 result, got %v with %v results",
+			resp.Strategy, len(resp.Results))
+	}
+
+	// The child should pay for the fee deficit of its parent.
+	ctx.tick()
+	childTx := ctx.receiveTx()
+	_, childFeeRate := feeRate(&childTx, parentTx.TxOut[0].Value)
+	if childFeeRate <= targetFeeRate {
+		t.Fatalf("expected child fee rate above %v, got %v",
+			targetFeeRate, childFeeRate)
+	}
+
+	ctx.backend.mine()
+	ctx.expectResult(resp.Results[0], nil)
+
+	// A transaction that already pays the target fee rate doesn't need to
+	// be bumped.
+	parentReq.Fee = 50000
+	resp, err = ctx.sweeper.BumpTx(parentReq)
+	if err != nil {
+		t.Fatalf("unable to bump tx: %v", err)
+	}
+	if resp.Strategy != BumpStrategyNone {
+		t.Fatalf("expected no bump, got %v", resp.Strategy)
+	}
+
+	ctx.finish(1)
+}
+
+// TestRepublishReusesWitnesses asserts that an unchanged sweep tx that is
+// republished in a later block isn't signed again, even though the tx is
+// ordered randomly and created at a different height.
+func TestRepublishReusesWitnesses(t *testing.T) {
+	signer := &mockSigner{}
+	h := NewTestHarness(t, func(cfg *UtxoSweeperConfig) {
+		cfg.Signer = signer
+		cfg.TxOrdering = TxOrderingRandom
+	})
+	defer h.Stop()
+
+	var resultChans []chan Result
+	for _, inp := range spendableInputs[:2] {
+		resultChan, err := h.Sweeper.SweepInput(inp, defaultFeePref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resultChans = append(resultChans, resultChan)
+	}
+
+	h.FireBatchTimer()
+	sweepTx := h.ReceiveTx()
+	if len(sweepTx.TxIn) != 2 {
+		t.Fatalf("expected 2 inputs, got %v", len(sweepTx.TxIn))
+	}
+
+	// The sweep tx drops out of the mempool and is swept again in the
+	// next block.
+	h.EvictTx(sweepTx.TxHash())
+	h.MineBlock()
+	h.FireBatchTimer()
+	republishedTx := h.ReceiveTx()
+
+	if republishedTx.TxHash() != sweepTx.TxHash() {
+		t.Fatalf("expected republication of %v, got %v",
+			sweepTx.TxHash(), republishedTx.TxHash())
+	}
+	if signer.numSigned != 2 {
+		t.Fatalf("expected 2 signatures, got %v", signer.numSigned)
+	}
+
+	h.MineBlock()
+	for _, resultChan := range resultChans {
+		h.ExpectResult(resultChan, nil)
+	}
+}
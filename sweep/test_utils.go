@@ -19,11 +19,15 @@ var (
 )
 
 type mockSigner struct {
+	// numSigned is the number of signatures that have been requested.
+	numSigned int
 }
 
 func (m *mockSigner) SignOutputRaw(tx *wire.MsgTx,
 	signDesc *input.SignDescriptor) ([]byte, error) {
 
+	m.numSigned++
+
 	return []byte{}, nil
 }
 
@@ -37,6 +41,7 @@ func (m *mockSigner) ComputeInputScript(tx *wire.MsgTx,
 // exported because it is used in nursery tests.
 type MockNotifier struct {
 	confChannel map[chainhash.Hash]chan *chainntnfs.TxConfirmation
+	scriptConfs map[string][]chan *chainntnfs.TxConfirmation
 	epochChan   map[chan *chainntnfs.BlockEpoch]int32
 	spendChan   map[wire.OutPoint][]chan *chainntnfs.SpendDetail
 	spends      map[wire.OutPoint]*wire.MsgTx
@@ -48,6 +53,7 @@ type MockNotifier struct {
 func NewMockNotifier(t *testing.T) *MockNotifier {
 	return &MockNotifier{
 		confChannel: make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+		scriptConfs: make(map[string][]chan *chainntnfs.TxConfirmation),
 		epochChan:   make(map[chan *chainntnfs.BlockEpoch]int32),
 		spendChan:   make(map[wire.OutPoint][]chan *chainntnfs.SpendDetail),
 		spends:      make(map[wire.OutPoint]*wire.MsgTx),
@@ -91,6 +97,50 @@ func (m *MockNotifier) ConfirmTx(txid *chainhash.Hash, height uint32) error {
 	return nil
 }
 
+// ConfirmScript simulates the confirmation of a tx that pays to the given
+// script. The confirmation is dispatched to the pending script-only
+// registrations of the script, which are then removed. It waits for at least
+// one registration to exist.
+func (m *MockNotifier) ConfirmScript(pkScript []byte, tx *wire.MsgTx,
+	height uint32) error {
+
+	confirm := &chainntnfs.TxConfirmation{
+		BlockHeight: height,
+		Tx:          tx,
+	}
+
+	timeout := time.After(defaultTestTimeout)
+	for {
+		m.mutex.Lock()
+		channels := m.scriptConfs[string(pkScript)]
+		delete(m.scriptConfs, string(pkScript))
+		m.mutex.Unlock()
+
+		for _, channel := range channels {
+			channel <- confirm
+		}
+		if len(channels) > 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			return fmt.Errorf("no registration for script %x",
+				pkScript)
+		}
+	}
+}
+
+// NumScriptRegistrations returns the number of pending script-only
+// confirmation registrations of the given script.
+func (m *MockNotifier) NumScriptRegistrations(pkScript []byte) int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return len(m.scriptConfs[string(pkScript)])
+}
+
 // SpendOutpoint simulates a utxo being spent.
 func (m *MockNotifier) SpendOutpoint(outpoint wire.OutPoint,
 	spendingTx wire.MsgTx) {
@@ -123,12 +173,44 @@ func (m *MockNotifier) sendSpend(channel chan *chainntnfs.SpendDetail,
 }
 
 // RegisterConfirmationsNtfn registers for tx confirm notifications.
+// Registrations without a txid are dispatched by ConfirmScript.
 func (m *MockNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
-	_ []byte, numConfs, heightHint uint32) (*chainntnfs.ConfirmationEvent,
-	error) {
+	pkScript []byte, numConfs, heightHint uint32) (
+	*chainntnfs.ConfirmationEvent, error) {
+
+	if txid != nil {
+		return &chainntnfs.ConfirmationEvent{
+			Confirmed: m.getConfChannel(txid),
+			Cancel:    func() {},
+		}, nil
+	}
+
+	channel := make(chan *chainntnfs.TxConfirmation, 1)
+
+	m.mutex.Lock()
+	script := string(pkScript)
+	m.scriptConfs[script] = append(m.scriptConfs[script], channel)
+	m.mutex.Unlock()
 
 	return &chainntnfs.ConfirmationEvent{
-		Confirmed: m.getConfChannel(txid),
+		Confirmed: channel,
+		Cancel: func() {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+
+			channels := m.scriptConfs[script]
+			for i, c := range channels {
+				if c == channel {
+					m.scriptConfs[script] = append(
+						channels[:i], channels[i+1:]...,
+					)
+					break
+				}
+			}
+			if len(m.scriptConfs[script]) == 0 {
+				delete(m.scriptConfs, script)
+			}
+		},
 	}, nil
 }
 
@@ -2,12 +2,14 @@ package sweep
 
 import (
 	"fmt"
+	"math/rand"
 	"sort"
 
 	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/txsort"
 	"github.com/btcsuite/btcwallet/wallet/txrules"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/lnwallet"
@@ -20,6 +22,92 @@ var (
 	DefaultMaxInputsPerTx = 100
 )
 
+// TxOrdering determines how the inputs and outputs of a sweep transaction are
+// ordered.
+type TxOrdering uint8
+
+const (
+	// TxOrderingRandom shuffles the inputs and outputs, so that the order
+	// of a sweep transaction doesn't reveal how it was constructed.
+	TxOrderingRandom TxOrdering = iota
+
+	// TxOrderingBIP69 sorts the inputs and outputs according to BIP69,
+	// which makes the order deterministic.
+	TxOrderingBIP69
+)
+
+// String returns a human readable representation of the ordering.
+func (o TxOrdering) String() string {
+	switch o {
+	case TxOrderingRandom:
+		return "random"
+
+	case TxOrderingBIP69:
+		return "bip69"
+
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// lockTimeRandomizeChance is the inverse of the probability with which
+	// a randomized locktime is moved back from the current height.
+	lockTimeRandomizeChance = 10
+
+	// maxLockTimeOffset is the maximum number of blocks by which a
+	// randomized locktime is moved back from the current height.
+	maxLockTimeOffset = 100
+
+	// rbfSequence is the sequence number that signals replaceability of
+	// an input that doesn't require a relative time lock, as defined in
+	// BIP125.
+	rbfSequence = wire.MaxTxInSequenceNum - 2
+)
+
+// SweepTxOptions controls the locktime and the sequence numbers of sweep
+// transactions.
+type SweepTxOptions struct {
+	// RandomizeLockTime occasionally sets the locktime to a randomly
+	// chosen recent height rather than to the current height, matching
+	// the anti fee sniping behavior of common wallets, so that sweeps
+	// aren't distinguishable by their locktime. It has no effect on txes
+	// that spend inputs with an absolute time lock.
+	RandomizeLockTime bool
+
+	// SignalRBF sets the sequence number of the inputs that don't require
+	// a relative time lock to the explicit BIP125 replaceability signal.
+	// Otherwise their sequence number is zero.
+	SignalRBF bool
+
+	// Split optionally splits the swept value over multiple outputs. If
+	// nil, the value is swept to a single output.
+	Split *SplitPolicy
+}
+
+// sweepLockTime returns the locktime of a sweep tx that is created at the
+// given height.
+func sweepLockTime(currentBlockHeight uint32, cltvCount int,
+	opts SweepTxOptions) uint32 {
+
+	// Inputs with an absolute time lock require the locktime to be at
+	// least their expiry, which is only guaranteed by the current height.
+	if !opts.RandomizeLockTime || cltvCount > 0 {
+		return currentBlockHeight
+	}
+
+	if rand.Intn(lockTimeRandomizeChance) != 0 {
+		return currentBlockHeight
+	}
+
+	offset := uint32(rand.Intn(maxLockTimeOffset))
+	if offset > currentBlockHeight {
+		return currentBlockHeight
+	}
+
+	return currentBlockHeight - offset
+}
+
 // inputSet is a set of inputs that can be used as the basis to generate a tx
 // on.
 type inputSet []input.Input
@@ -28,10 +116,10 @@ type inputSet []input.Input
 // of inputs that can be used to generate a sensible transaction. Each set
 // contains up to the configured maximum number of inputs. Negative yield
 // inputs are skipped. No input sets with a total value after fees below the
-// dust limit are returned.
+// dust limit or the given minimum output amount are returned.
 func generateInputPartitionings(sweepableInputs []input.Input,
 	relayFeePerKW, feePerKW lnwallet.SatPerKWeight,
-	maxInputsPerTx int) ([]inputSet, error) {
+	maxInputsPerTx int, minOutputAmt btcutil.Amount) ([]inputSet, error) {
 
 	// Calculate dust limit based on the P2WPKH output script of the sweep
 	// txes.
@@ -40,6 +128,11 @@ func generateInputPartitionings(sweepableInputs []input.Input,
 		btcutil.Amount(relayFeePerKW.FeePerKVByte()),
 	)
 
+	// A configured minimum output amount only tightens the dust limit.
+	if minOutputAmt > dustLimit {
+		dustLimit = minOutputAmt
+	}
+
 	// Sort input by yield. We will start constructing input sets starting
 	// with the highest yield inputs. This is to prevent the construction
 	// of a set with an output below the dust limit, causing the sweep
@@ -87,12 +180,12 @@ func generateInputPartitionings(sweepableInputs []input.Input,
 		}
 
 		// If the output value of this block of inputs does not reach
-		// the dust limit, stop sweeping. Because of the sorting,
+		// the dust limit or minimum output amount, stop sweeping. Because of the sorting,
 		// continuing with the remaining inputs will only lead to sets
 		// with a even lower output value.
 		if outputValue < dustLimit {
-			log.Debugf("Set value %v below dust limit of %v",
-				outputValue, dustLimit)
+			log.Debugf("Set value %v below minimum output amount "+
+				"of %v", outputValue, dustLimit)
 			return sets, nil
 		}
 
@@ -167,8 +260,11 @@ func getPositiveYieldInputs(sweepableInputs []input.Input, maxInputs int,
 }
 
 // createSweepTx builds a signed tx spending the inputs to a the output script.
+// If a witness cache is passed, the input scripts of an identical tx that was
+// signed before are reused rather than signing the tx again.
 func createSweepTx(inputs []input.Input, outputPkScript []byte,
 	currentBlockHeight uint32, feePerKw lnwallet.SatPerKWeight,
+	ordering TxOrdering, opts SweepTxOptions, cache *witnessCache,
 	signer input.Signer) (*wire.MsgTx, error) {
 
 	inputs, txWeight, csvCount, cltvCount := getWeightEstimate(inputs)
@@ -190,24 +286,56 @@ func createSweepTx(inputs []input.Input, outputPkScript []byte,
 
 	// Create the sweep transaction that we will be building. We use
 	// version 2 as it is required for CSV. The txn will sweep the amount
-	// after fees to the pkscript generated above.
+	// after fees to the pkscript generated above, unless a split over
+	// multiple outputs was requested.
 	sweepTx := wire.NewMsgTx(2)
-	sweepTx.AddTxOut(&wire.TxOut{
-		PkScript: outputPkScript,
-		Value:    sweepAmt,
-	})
+	if opts.Split == nil {
+		sweepTx.AddTxOut(&wire.TxOut{
+			PkScript: outputPkScript,
+			Value:    sweepAmt,
+		})
+	} else {
+		txOuts, err := splitSweepValue(
+			totalSum, txWeight, feePerKw, outputPkScript,
+			opts.Split,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, txOut := range txOuts {
+			sweepTx.AddTxOut(txOut)
+		}
+	}
 
-	sweepTx.LockTime = currentBlockHeight
+	// Republishing a sweep of the same inputs reuses the locktime and
+	// ordering of its first tx, so that an unchanged tx doesn't need to be
+	// signed again.
+	setKey := inputSetKey(inputs)
+	lockTime, shuffleSeed, ok := cache.params(setKey)
+	if !ok {
+		lockTime = sweepLockTime(currentBlockHeight, cltvCount, opts)
+		shuffleSeed = rand.Int63()
+	}
+	sweepTx.LockTime = lockTime
 
 	// Add all inputs to the sweep transaction. Ensure that for each
 	// csvInput, we set the sequence number properly.
 	for _, input := range inputs {
+		sequence := input.BlocksToMaturity()
+		if sequence == 0 && opts.SignalRBF {
+			sequence = rbfSequence
+		}
+
 		sweepTx.AddTxIn(&wire.TxIn{
 			PreviousOutPoint: *input.OutPoint(),
-			Sequence:         input.BlocksToMaturity(),
+			Sequence:         sequence,
 		})
 	}
 
+	// Order the transaction as configured. The inputs are reordered along
+	// with it, so that each input is signed at its final index.
+	inputs = orderSweepTx(sweepTx, inputs, ordering, shuffleSeed)
+
 	// Before signing the transaction, check to ensure that it meets some
 	// basic validity requirements.
 	//
@@ -221,15 +349,32 @@ func createSweepTx(inputs []input.Input, outputPkScript []byte,
 
 	hashCache := txscript.NewTxSigHashes(sweepTx)
 
+	// The hash of the unsigned tx identifies the input scripts in the
+	// witness cache.
+	unsignedHash := sweepTx.TxHash()
+	cachedScripts := cache.fetch(setKey, unsignedHash)
+	if cachedScripts != nil {
+		log.Debugf("Reusing cached input scripts for sweep tx %v",
+			unsignedHash)
+	}
+	inputScripts := make([]*input.Script, len(inputs))
+
 	// With all the inputs in place, use each output's unique input script
 	// function to generate the final witness required for spending.
 	addInputScript := func(idx int, tso input.Input) error {
-		inputScript, err := tso.CraftInputScript(
-			signer, sweepTx, hashCache, idx,
-		)
-		if err != nil {
-			return err
+		var inputScript *input.Script
+		if cachedScripts != nil {
+			inputScript = cachedScripts[idx]
+		} else {
+			var err error
+			inputScript, err = tso.CraftInputScript(
+				signer, sweepTx, hashCache, idx,
+			)
+			if err != nil {
+				return err
+			}
 		}
+		inputScripts[idx] = inputScript
 
 		sweepTx.TxIn[idx].Witness = inputScript.Witness
 
@@ -248,9 +393,45 @@ func createSweepTx(inputs []input.Input, outputPkScript []byte,
 		}
 	}
 
+	cache.add(setKey, lockTime, shuffleSeed, unsignedHash, inputScripts)
+
 	return sweepTx, nil
 }
 
+// orderSweepTx orders the inputs and outputs of the given transaction
+// according to the given ordering, and returns the inputs in the same order as
+// they appear in the transaction. A random ordering is derived from the given
+// seed.
+func orderSweepTx(tx *wire.MsgTx, inputs []input.Input,
+	ordering TxOrdering, shuffleSeed int64) []input.Input {
+
+	switch ordering {
+	case TxOrderingBIP69:
+		txsort.InPlaceSort(tx)
+
+	default:
+		rng := rand.New(rand.NewSource(shuffleSeed))
+		rng.Shuffle(len(tx.TxIn), func(i, j int) {
+			tx.TxIn[i], tx.TxIn[j] = tx.TxIn[j], tx.TxIn[i]
+		})
+		rng.Shuffle(len(tx.TxOut), func(i, j int) {
+			tx.TxOut[i], tx.TxOut[j] = tx.TxOut[j], tx.TxOut[i]
+		})
+	}
+
+	inputsByOutpoint := make(map[wire.OutPoint]input.Input, len(inputs))
+	for _, inp := range inputs {
+		inputsByOutpoint[*inp.OutPoint()] = inp
+	}
+
+	ordered := make([]input.Input, 0, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		ordered = append(ordered, inputsByOutpoint[txIn.PreviousOutPoint])
+	}
+
+	return ordered
+}
+
 // getInputWitnessSizeUpperBound returns the maximum length of the witness for
 // the given input if it would be included in a tx. We also return if the
 // output itself is a nested p2sh output, if so then we need to take into
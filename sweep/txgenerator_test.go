@@ -0,0 +1,292 @@
+package sweep
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/txsort"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// TestCreateSweepTxOrdering asserts that sweep transactions are ordered as
+// configured, and that the BIP69 ordering is independent of the order in
+// which the inputs are offered.
+func TestCreateSweepTxOrdering(t *testing.T) {
+	t.Parallel()
+
+	var inputs, reversed []input.Input
+	for i := 0; i < 3; i++ {
+		inputs = append(inputs, spendableInputs[i])
+		reversed = append(reversed, spendableInputs[2-i])
+	}
+
+	pkScript := []byte{1}
+
+	createTx := func(inputs []input.Input,
+		ordering TxOrdering) *wire.MsgTx {
+
+		tx, err := createSweepTx(
+			inputs, pkScript, 100, 10000, ordering,
+			SweepTxOptions{}, nil, &mockSigner{},
+		)
+		if err != nil {
+			t.Fatalf("unable to create sweep tx: %v", err)
+		}
+		return tx
+	}
+
+	tx1 := createTx(inputs, TxOrderingBIP69)
+	tx2 := createTx(reversed, TxOrderingBIP69)
+	if !txsort.IsSorted(tx1) {
+		t.Fatalf("expected tx to be sorted according to bip69")
+	}
+	if tx1.TxHash() != tx2.TxHash() {
+		t.Fatalf("expected identical txes, got %v and %v",
+			tx1.TxHash(), tx2.TxHash())
+	}
+
+	// A randomly ordered tx must still spend all inputs.
+	tx := createTx(inputs, TxOrderingRandom)
+	spent := make(map[wire.OutPoint]struct{})
+	for _, txIn := range tx.TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+	for _, inp := range inputs {
+		if _, ok := spent[*inp.OutPoint()]; !ok {
+			t.Fatalf("input %v not spent", inp.OutPoint())
+		}
+	}
+	if len(tx.TxIn) != len(inputs) {
+		t.Fatalf("expected %v inputs, got %v", len(inputs),
+			len(tx.TxIn))
+	}
+}
+
+// TestCreateSweepTxOptions asserts that sweep transactions signal
+// replaceability and randomize their locktime if configured.
+func TestCreateSweepTxOptions(t *testing.T) {
+	t.Parallel()
+
+	const height = 1000
+
+	inputs := []input.Input{spendableInputs[0], spendableInputs[1]}
+	pkScript := []byte{1}
+
+	createTx := func(opts SweepTxOptions) *wire.MsgTx {
+		tx, err := createSweepTx(
+			inputs, pkScript, height, 10000, TxOrderingBIP69,
+			opts, nil, &mockSigner{},
+		)
+		if err != nil {
+			t.Fatalf("unable to create sweep tx: %v", err)
+		}
+		return tx
+	}
+
+	// Without options, the locktime is the current height and the inputs
+	// don't signal replaceability explicitly.
+	tx := createTx(SweepTxOptions{})
+	if tx.LockTime != height {
+		t.Fatalf("expected locktime %v, got %v", height, tx.LockTime)
+	}
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence != 0 {
+			t.Fatalf("expected sequence 0, got %v", txIn.Sequence)
+		}
+	}
+
+	tx = createTx(SweepTxOptions{SignalRBF: true})
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence != rbfSequence {
+			t.Fatalf("expected sequence %v, got %v", rbfSequence,
+				txIn.Sequence)
+		}
+	}
+
+	// A randomized locktime stays within the recent blocks, and is moved
+	// back occasionally.
+	randomized := false
+	for i := 0; i < 500; i++ {
+		tx = createTx(SweepTxOptions{RandomizeLockTime: true})
+		if tx.LockTime > height ||
+			tx.LockTime <= height-maxLockTimeOffset {
+
+			t.Fatalf("locktime %v out of range", tx.LockTime)
+		}
+		if tx.LockTime != height {
+			randomized = true
+		}
+	}
+	if !randomized {
+		t.Fatalf("expected locktime to be randomized")
+	}
+}
+
+// TestCreateSweepTxWitnessCache asserts that the input scripts of an identical
+// sweep tx are taken from the witness cache, and that any change to the tx
+// results in signing it again.
+func TestCreateSweepTxWitnessCache(t *testing.T) {
+	t.Parallel()
+
+	inputs := []input.Input{spendableInputs[0], spendableInputs[1]}
+	pkScript := []byte{1}
+
+	cache := newWitnessCache()
+	signer := &mockSigner{}
+
+	createTx := func(feeRate lnwallet.SatPerKWeight) *wire.MsgTx {
+		tx, err := createSweepTx(
+			inputs, pkScript, 100, feeRate, TxOrderingBIP69,
+			SweepTxOptions{}, cache, signer,
+		)
+		if err != nil {
+			t.Fatalf("unable to create sweep tx: %v", err)
+		}
+		return tx
+	}
+
+	assertSigned := func(expected int) {
+		t.Helper()
+
+		if signer.numSigned != expected {
+			t.Fatalf("expected %v signatures, got %v", expected,
+				signer.numSigned)
+		}
+	}
+
+	tx1 := createTx(10000)
+	assertSigned(len(inputs))
+
+	// Creating the same tx again reuses the cached input scripts.
+	tx2 := createTx(10000)
+	assertSigned(len(inputs))
+	if tx1.TxHash() != tx2.TxHash() {
+		t.Fatalf("expected identical txes, got %v and %v",
+			tx1.TxHash(), tx2.TxHash())
+	}
+	for i := range tx1.TxIn {
+		if !reflect.DeepEqual(tx1.TxIn[i].Witness, tx2.TxIn[i].Witness) {
+			t.Fatalf("witness mismatch for input %v", i)
+		}
+	}
+
+	// A different fee rate changes the tx, which needs to be signed again.
+	createTx(20000)
+	assertSigned(2 * len(inputs))
+}
+
+// TestSplitSweepValue asserts that the value of a sweep is split according to
+// the split policy, with the fee covering the additional outputs and without
+// creating dust outputs.
+func TestSplitSweepValue(t *testing.T) {
+	t.Parallel()
+
+	const (
+		totalSum = btcutil.Amount(1000000)
+		txWeight = 1000
+		feePerKw = lnwallet.SatPerKWeight(10000)
+	)
+
+	sweepScript := make([]byte, input.P2WPKHSize)
+	coldScript := make([]byte, input.P2WSHSize)
+	coldScript[0] = 1
+
+	sumOutputs := func(txOuts []*wire.TxOut) btcutil.Amount {
+		var sum btcutil.Amount
+		for _, txOut := range txOuts {
+			sum += btcutil.Amount(txOut.Value)
+		}
+		return sum
+	}
+
+	// A proportional split pays 80% to cold storage and 20% to the sweep
+	// script. The fee must cover the weight of the extra output.
+	txOuts, err := splitSweepValue(
+		totalSum, txWeight, feePerKw, sweepScript, &SplitPolicy{
+			Outputs: []SplitOutput{
+				{PkScript: coldScript, Weight: 80},
+				{Weight: 20},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to split: %v", err)
+	}
+	if len(txOuts) != 2 {
+		t.Fatalf("expected 2 outputs, got %v", len(txOuts))
+	}
+	singleFee := feePerKw.FeeForWeight(txWeight)
+	fee := totalSum - sumOutputs(txOuts)
+	if fee <= singleFee {
+		t.Fatalf("expected fee above %v, got %v", singleFee, fee)
+	}
+	value := totalSum - fee
+	if !reflect.DeepEqual(txOuts[0].PkScript, coldScript) ||
+		btcutil.Amount(txOuts[0].Value) != value-value*20/100 {
+
+		t.Fatalf("unexpected cold output: %v", txOuts[0])
+	}
+	if !reflect.DeepEqual(txOuts[1].PkScript, sweepScript) ||
+		btcutil.Amount(txOuts[1].Value) != value*20/100 {
+
+		t.Fatalf("unexpected sweep output: %v", txOuts[1])
+	}
+
+	// An output that would be dust is dropped, leaving the value to the
+	// other output.
+	txOuts, err = splitSweepValue(
+		totalSum, txWeight, feePerKw, sweepScript, &SplitPolicy{
+			Outputs: []SplitOutput{
+				{PkScript: coldScript, Weight: 100000},
+				{Weight: 1},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to split: %v", err)
+	}
+	if len(txOuts) != 1 ||
+		!reflect.DeepEqual(txOuts[0].PkScript, coldScript) {
+
+		t.Fatalf("expected single cold output, got %v", txOuts)
+	}
+
+	// Chunking creates outputs of the chunk size, with the remainder
+	// added to the last one.
+	const chunkSize = 300000
+	txOuts, err = splitSweepValue(
+		totalSum, txWeight, feePerKw, sweepScript, &SplitPolicy{
+			ChunkSize: chunkSize,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to split: %v", err)
+	}
+	if len(txOuts) != 3 {
+		t.Fatalf("expected 3 outputs, got %v", len(txOuts))
+	}
+	for _, txOut := range txOuts[:2] {
+		if txOut.Value != chunkSize {
+			t.Fatalf("expected chunk of %v, got %v", chunkSize,
+				txOut.Value)
+		}
+	}
+	if txOuts[2].Value < chunkSize {
+		t.Fatalf("expected last output of at least %v, got %v",
+			chunkSize, txOuts[2].Value)
+	}
+	if totalSum-sumOutputs(txOuts) <= singleFee {
+		t.Fatalf("expected fee to cover the extra outputs")
+	}
+
+	// An invalid policy is rejected.
+	_, err = splitSweepValue(
+		totalSum, txWeight, feePerKw, sweepScript, &SplitPolicy{},
+	)
+	if err == nil {
+		t.Fatalf("expected empty policy to be rejected")
+	}
+}
@@ -0,0 +1,46 @@
+package sweep
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// trackUnconfirmedSweep records the published sweep tx as unconfirmed. Inputs
+// that were spent by an earlier sweep tx are moved over to this one, as the
+// new tx replaces it.
+func (s *UtxoSweeper) trackUnconfirmedSweep(tx *wire.MsgTx) {
+	hash := tx.TxHash()
+	for _, txIn := range tx.TxIn {
+		s.unconfirmedSweeps[txIn.PreviousOutPoint] = hash
+	}
+}
+
+// numUnconfirmedSweeps returns the number of distinct sweep transactions that
+// spend inputs which are still pending.
+func (s *UtxoSweeper) numUnconfirmedSweeps() int {
+	sweeps := make(map[chainhash.Hash]struct{})
+	for _, hash := range s.unconfirmedSweeps {
+		sweeps[hash] = struct{}{}
+	}
+
+	return len(sweeps)
+}
+
+// canPublishSweep returns whether a sweep tx spending the given inputs may be
+// published without exceeding the configured maximum number of unconfirmed
+// sweeps. A tx that spends an input of an outstanding sweep replaces that
+// sweep rather than adding to the chain of unconfirmed transactions, so it is
+// always allowed.
+func (s *UtxoSweeper) canPublishSweep(inputs inputSet) bool {
+	if s.cfg.MaxUnconfirmedSweeps <= 0 {
+		return true
+	}
+
+	for _, inp := range inputs {
+		if _, ok := s.unconfirmedSweeps[*inp.OutPoint()]; ok {
+			return true
+		}
+	}
+
+	return s.numUnconfirmedSweeps() < s.cfg.MaxUnconfirmedSweeps
+}
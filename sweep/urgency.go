@@ -0,0 +1,159 @@
+package sweep
+
+import (
+	"time"
+
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+)
+
+// DefaultCriticalMaxConfTarget is the maximum confirmation target used for
+// inputs in the critical urgency lane.
+const DefaultCriticalMaxConfTarget = 2
+
+// Urgency denotes the scheduling lane of an input within the UtxoSweeper.
+// Inputs are only ever batched with inputs of the same lane, and every lane
+// runs its own batch timer. This ensures that time critical inputs are never
+// held back by the long batching window of less urgent inputs.
+type Urgency uint8
+
+const (
+	// UrgencyNormal is the default lane, used for inputs that don't specify
+	// an urgency.
+	UrgencyNormal Urgency = iota
+
+	// UrgencyCritical is the lane for inputs that must be swept as soon as
+	// possible, such as outputs that would otherwise be claimable by the
+	// remote party.
+	UrgencyCritical
+
+	// UrgencyEconomic is the lane for inputs that aren't time sensitive.
+	// Inputs in this lane can be held back for longer in order to be
+	// batched with more inputs, which lowers the fee per input.
+	UrgencyEconomic
+
+	// numUrgencyLanes is the total number of urgency lanes.
+	numUrgencyLanes
+)
+
+// String returns a human-readable string of the urgency.
+func (u Urgency) String() string {
+	switch u {
+	case UrgencyNormal:
+		return "normal"
+	case UrgencyCritical:
+		return "critical"
+	case UrgencyEconomic:
+		return "economic"
+	default:
+		return "unknown"
+	}
+}
+
+// UrgencyForWitnessType returns the urgency lane for an input of the given
+// witness type. Inputs that the remote party is able to claim as well, because
+// they are contested by a hash or time lock or are the subject of a breach,
+// are critical. Inputs that only we can spend are swept economically.
+func UrgencyForWitnessType(witnessType input.WitnessType) Urgency {
+	switch witnessType {
+	case input.HtlcOfferedRemoteTimeout, input.HtlcAcceptedRemoteSuccess,
+		input.CommitmentRevoke, input.HtlcOfferedRevoke,
+		input.HtlcAcceptedRevoke, input.HtlcSecondLevelRevoke:
+
+		return UrgencyCritical
+
+	case input.CommitmentTimeLock, input.CommitmentNoDelay,
+		input.HtlcOfferedTimeoutSecondLevel,
+		input.HtlcAcceptedSuccessSecondLevel:
+
+		return UrgencyEconomic
+
+	default:
+		return UrgencyNormal
+	}
+}
+
+// LanePolicy describes the batching and fee policy of an urgency lane.
+type LanePolicy struct {
+	// NewBatchTimer creates the batch timer of the lane. If nil, the
+	// NewBatchTimer of the sweeper config is used.
+	NewBatchTimer func() <-chan time.Time
+
+	// MaxConfTarget is the maximum confirmation target that is used for
+	// inputs in this lane. Inputs with a higher confirmation target are
+	// swept using this target instead. If zero, no limit is applied.
+	MaxConfTarget uint32
+
+	// MinFeeRate is the minimum fee rate that is used for inputs in this
+	// lane. If zero, no minimum is applied.
+	MinFeeRate lnwallet.SatPerKWeight
+}
+
+// DefaultLanePolicies returns the default policies of the urgency lanes. The
+// normal lane isn't included, which means that it falls back to the sweeper's
+// configured batch timer.
+func DefaultLanePolicies() map[Urgency]LanePolicy {
+	return map[Urgency]LanePolicy{
+		UrgencyCritical: {
+			NewBatchTimer: func() <-chan time.Time {
+				return time.NewTimer(
+					DefaultCriticalBatchWindowDuration,
+				).C
+			},
+			MaxConfTarget: DefaultCriticalMaxConfTarget,
+		},
+		UrgencyEconomic: {
+			NewBatchTimer: func() <-chan time.Time {
+				return time.NewTimer(
+					DefaultEconomicBatchWindowDuration,
+				).C
+			},
+		},
+	}
+}
+
+// lanePolicy returns the policy of the given lane, or an empty policy if none
+// is configured.
+func (s *UtxoSweeper) lanePolicy(lane Urgency) LanePolicy {
+	return s.cfg.LanePolicies[lane]
+}
+
+// newLaneTimer creates a new batch timer for the given lane.
+func (s *UtxoSweeper) newLaneTimer(lane Urgency) <-chan time.Time {
+	if policy := s.lanePolicy(lane); policy.NewBatchTimer != nil {
+		return policy.NewBatchTimer()
+	}
+
+	return s.cfg.NewBatchTimer()
+}
+
+// feeRateForInput returns the fee rate at which the given input should be
+// swept, taking into account the fee policy of the input's lane.
+func (s *UtxoSweeper) feeRateForInput(
+	input *pendingInput) (lnwallet.SatPerKWeight, error) {
+
+	feePref := input.feePreference
+	policy := s.lanePolicy(feePref.Urgency)
+
+	// Tighten the confirmation target if it exceeds the maximum of the
+	// lane.
+	if policy.MaxConfTarget != 0 && feePref.ConfTarget > policy.MaxConfTarget {
+		feePref.ConfTarget = policy.MaxConfTarget
+	}
+
+	feeRate, err := s.feeRateForPreference(feePref)
+	if err != nil {
+		return 0, err
+	}
+
+	// Raise the fee rate to the lane's minimum, but never beyond the
+	// maximum fee rate of the sweeper.
+	if feeRate < policy.MinFeeRate {
+		feeRate = policy.MinFeeRate
+		if feeRate > s.cfg.MaxFeeRate {
+			feeRate = s.cfg.MaxFeeRate
+		}
+	}
+
+	return feeRate, nil
+}
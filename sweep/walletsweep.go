@@ -28,6 +28,10 @@ type FeePreference struct {
 	// FeeRate if non-zero, signals a fee pre fence expressed in the fee
 	// rate expressed in sat/kw for a particular transaction.
 	FeeRate lnwallet.SatPerKWeight
+
+	// Urgency is the urgency lane in which the UtxoSweeper schedules the
+	// input. It is ignored outside of the UtxoSweeper.
+	Urgency Urgency
 }
 
 // String returns a human-readable string of the fee preference.
@@ -156,9 +160,11 @@ type WalletSweepPackage struct {
 // caller to sweep ALL outputs within the wallet to a single UTXO, as specified
 // by the delivery address. The sweep transaction will be crafted with the
 // target fee rate, and will use the utxoSource and outpointLocker as sources
-// for wallet funds.
+// for wallet funds. If the value of the sweep output would be below
+// minOutputAmt, ErrOutputBelowMinimum is returned.
 func CraftSweepAllTx(feeRate lnwallet.SatPerKWeight, blockHeight uint32,
-	deliveryAddr btcutil.Address, coinSelectLocker CoinSelectionLocker,
+	deliveryAddr btcutil.Address, minOutputAmt btcutil.Amount,
+	coinSelectLocker CoinSelectionLocker,
 	utxoSource UtxoSource, outpointLocker OutpointLocker,
 	feeEstimator lnwallet.FeeEstimator,
 	signer input.Signer) (*WalletSweepPackage, error) {
@@ -281,8 +287,11 @@ func CraftSweepAllTx(feeRate lnwallet.SatPerKWeight, blockHeight uint32,
 
 	// Finally, we'll ask the sweeper to craft a sweep transaction which
 	// respects our fee preference and targets all the UTXOs of the wallet.
+	// As the transaction is handed back to the caller rather than being
+	// published by the sweeper, we keep its order deterministic.
 	sweepTx, err := createSweepTx(
-		inputsToSweep, deliveryPkScript, blockHeight, feeRate, signer,
+		inputsToSweep, deliveryPkScript, blockHeight, feeRate,
+		TxOrderingBIP69, SweepTxOptions{}, nil, signer,
 	)
 	if err != nil {
 		unlockOutputs()
@@ -290,6 +299,14 @@ func CraftSweepAllTx(feeRate lnwallet.SatPerKWeight, blockHeight uint32,
 		return nil, err
 	}
 
+	// As there is no later batch to carry the outputs over to, we'll fail
+	// if the sweep would create an output below the minimum.
+	if btcutil.Amount(sweepTx.TxOut[0].Value) < minOutputAmt {
+		unlockOutputs()
+
+		return nil, ErrOutputBelowMinimum
+	}
+
 	return &WalletSweepPackage{
 		SweepTx:            sweepTx,
 		CancelSweepAttempt: unlockOutputs,
@@ -3,6 +3,7 @@ package sweep
 import (
 	"bytes"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/btcsuite/btcd/chaincfg"
@@ -165,6 +166,8 @@ type mockOutpointLocker struct {
 	lockedOutpoints map[wire.OutPoint]struct{}
 
 	unlockedOutpoints map[wire.OutPoint]struct{}
+
+	sync.Mutex
 }
 
 func newMockOutpointLocker() *mockOutpointLocker {
@@ -176,9 +179,15 @@ func newMockOutpointLocker() *mockOutpointLocker {
 }
 
 func (m *mockOutpointLocker) LockOutpoint(o wire.OutPoint) {
+	m.Lock()
+	defer m.Unlock()
+
 	m.lockedOutpoints[o] = struct{}{}
 }
 func (m *mockOutpointLocker) UnlockOutpoint(o wire.OutPoint) {
+	m.Lock()
+	defer m.Unlock()
+
 	m.unlockedOutpoints[o] = struct{}{}
 }
 
@@ -304,7 +313,8 @@ func TestCraftSweepAllTxCoinSelectFail(t *testing.T) {
 	utxoLocker := newMockOutpointLocker()
 
 	_, err := CraftSweepAllTx(
-		0, 100, nil, coinSelectLocker, utxoSource, utxoLocker, nil, nil,
+		0, 100, nil, 0, coinSelectLocker, utxoSource, utxoLocker, nil,
+		nil,
 	)
 
 	// Since we instructed the coin select locker to fail above, we should
@@ -329,7 +339,8 @@ func TestCraftSweepAllTxUnknownWitnessType(t *testing.T) {
 	utxoLocker := newMockOutpointLocker()
 
 	_, err := CraftSweepAllTx(
-		0, 100, nil, coinSelectLocker, utxoSource, utxoLocker, nil, nil,
+		0, 100, nil, 0, coinSelectLocker, utxoSource, utxoLocker, nil,
+		nil,
 	)
 
 	// Since passed in a p2wsh output, which is unknown, we should fail to
@@ -363,8 +374,8 @@ func TestCraftSweepAllTx(t *testing.T) {
 	utxoLocker := newMockOutpointLocker()
 
 	sweepPkg, err := CraftSweepAllTx(
-		0, 100, deliveryAddr, coinSelectLocker, utxoSource, utxoLocker,
-		feeEstimator, signer,
+		0, 100, deliveryAddr, 0, coinSelectLocker, utxoSource,
+		utxoLocker, feeEstimator, signer,
 	)
 	if err != nil {
 		t.Fatalf("unable to make sweep tx: %v", err)
@@ -407,3 +418,30 @@ func TestCraftSweepAllTx(t *testing.T) {
 	sweepPkg.CancelSweepAttempt()
 	assertUtxosUnlocked(t, utxoLocker, testUtxos[:2])
 }
+
+// TestCraftSweepAllTxMinOutputAmount tests that no sweep transaction is crafted
+// if its output would be below the minimum amount, and that all outputs are
+// unlocked again in that case.
+func TestCraftSweepAllTxMinOutputAmount(t *testing.T) {
+	t.Parallel()
+
+	signer := &mockSigner{}
+	feeEstimator := newMockFeeEstimator(0, 0)
+
+	targetUTXOs := testUtxos[:2]
+	utxoSource := newMockUtxoSource(targetUTXOs)
+	coinSelectLocker := &mockCoinSelectionLocker{}
+	utxoLocker := newMockOutpointLocker()
+
+	// The inputs add up to 3000 sat, so a minimum just above that should
+	// cause the sweep to fail.
+	_, err := CraftSweepAllTx(
+		0, 100, deliveryAddr, 3001, coinSelectLocker, utxoSource,
+		utxoLocker, feeEstimator, signer,
+	)
+	if err != ErrOutputBelowMinimum {
+		t.Fatalf("expected ErrOutputBelowMinimum, got %v", err)
+	}
+
+	assertUtxosLockedAndUnlocked(t, utxoLocker, targetUTXOs)
+}
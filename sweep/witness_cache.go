@@ -0,0 +1,168 @@
+package sweep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+// maxCachedSweepTxs is the number of sweep transactions whose input scripts
+// are kept in the witness cache.
+const maxCachedSweepTxs = 20
+
+// cachedSweep holds the parameters and input scripts of the most recent sweep
+// tx that was created for a set of inputs.
+type cachedSweep struct {
+	// lockTime is the locktime of the first sweep tx of the input set. It
+	// is reused for later txes of the set, so that republishing in a
+	// later block results in the same tx.
+	lockTime uint32
+
+	// shuffleSeed seeds the random ordering of the inputs and outputs, so
+	// that the order is the same for every tx of the input set.
+	shuffleSeed int64
+
+	// unsignedHash is the hash of the unsigned tx the scripts belong to.
+	unsignedHash chainhash.Hash
+
+	// scripts are the input scripts of the tx, by input index.
+	scripts []*input.Script
+}
+
+// witnessCache holds the input scripts of recently signed sweep transactions,
+// so that republishing an identical tx doesn't invoke the signer again. This
+// matters for remote signers, for which every signature adds latency or cost.
+//
+// The cache is keyed by the set of outpoints that is swept. Besides the input
+// scripts, it remembers the locktime and the ordering of the first tx of the
+// set, because both would otherwise change between attempts and with them the
+// signature hash of every input. The scripts are only reused if the hash of
+// the unsigned tx matches. It commits to the inputs, their sequence numbers,
+// the outputs and the locktime, and thereby also to the fee rate, so that the
+// cached scripts are never used for a tx with a different structure. The
+// oldest input set is evicted once the cache is full.
+type witnessCache struct {
+	mu sync.Mutex
+
+	// sweeps maps the key of an input set to its most recent sweep tx.
+	sweeps map[chainhash.Hash]*cachedSweep
+
+	// order holds the keys of the cached input sets, from oldest to
+	// newest.
+	order []chainhash.Hash
+}
+
+// newWitnessCache returns an empty witness cache.
+func newWitnessCache() *witnessCache {
+	return &witnessCache{
+		sweeps: make(map[chainhash.Hash]*cachedSweep),
+	}
+}
+
+// inputSetKey returns the key of the set of outpoints spent by the given
+// inputs, independent of their order.
+func inputSetKey(inputs []input.Input) chainhash.Hash {
+	outpoints := make([]wire.OutPoint, 0, len(inputs))
+	for _, inp := range inputs {
+		outpoints = append(outpoints, *inp.OutPoint())
+	}
+	sort.Slice(outpoints, func(i, j int) bool {
+		cmp := bytes.Compare(
+			outpoints[i].Hash[:], outpoints[j].Hash[:],
+		)
+		if cmp != 0 {
+			return cmp < 0
+		}
+		return outpoints[i].Index < outpoints[j].Index
+	})
+
+	var b bytes.Buffer
+	for _, op := range outpoints {
+		b.Write(op.Hash[:])
+
+		var index [4]byte
+		binary.BigEndian.PutUint32(index[:], op.Index)
+		b.Write(index[:])
+	}
+
+	return chainhash.HashH(b.Bytes())
+}
+
+// params returns the locktime and shuffle seed of the first sweep tx of the
+// input set with the given key. False is returned if the set isn't cached. A
+// nil cache never holds any sets.
+func (c *witnessCache) params(setKey chainhash.Hash) (uint32, int64, bool) {
+	if c == nil {
+		return 0, 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sweep, ok := c.sweeps[setKey]
+	if !ok {
+		return 0, 0, false
+	}
+
+	return sweep.lockTime, sweep.shuffleSeed, true
+}
+
+// fetch returns the input scripts of the unsigned tx with the given hash that
+// spends the input set with the given key, or nil if they aren't cached.
+func (c *witnessCache) fetch(setKey,
+	unsignedHash chainhash.Hash) []*input.Script {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sweep, ok := c.sweeps[setKey]
+	if !ok || sweep.unsignedHash != unsignedHash {
+		return nil
+	}
+
+	return sweep.scripts
+}
+
+// add caches the parameters and input scripts of a sweep tx of the input set
+// with the given key. The scripts replace those of a previous tx of the set,
+// while the parameters of the first tx are kept. Adding to a nil cache has no
+// effect.
+func (c *witnessCache) add(setKey chainhash.Hash, lockTime uint32,
+	shuffleSeed int64, unsignedHash chainhash.Hash,
+	scripts []*input.Script) {
+
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sweep, ok := c.sweeps[setKey]; ok {
+		sweep.unsignedHash = unsignedHash
+		sweep.scripts = scripts
+		return
+	}
+
+	if len(c.order) == maxCachedSweepTxs {
+		delete(c.sweeps, c.order[0])
+		c.order = c.order[1:]
+	}
+
+	c.sweeps[setKey] = &cachedSweep{
+		lockTime:     lockTime,
+		shuffleSeed:  shuffleSeed,
+		unsignedHash: unsignedHash,
+		scripts:      scripts,
+	}
+	c.order = append(c.order, setKey)
+}
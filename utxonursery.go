@@ -202,7 +202,8 @@ type NurseryConfig struct {
 	Store NurseryStore
 
 	// Sweep sweeps an input back to the wallet.
-	SweepInput func(input.Input, sweep.FeePreference) (chan sweep.Result, error)
+	SweepInput func(input.Input, sweep.FeePreference,
+		*sweep.InputMetadata) (chan sweep.Result, error)
 }
 
 // utxoNursery is a system dedicated to incubating time-locked outputs created
@@ -810,13 +811,28 @@ func (u *utxoNursery) sweepMatureOutputs(classHeight uint32,
 	utxnLog.Infof("Sweeping %v CSV-delayed outputs with sweep tx for "+
 		"height %v", len(kgtnOutputs), classHeight)
 
-	feePref := sweep.FeePreference{ConfTarget: kgtnOutputConfTarget}
 	for _, output := range kgtnOutputs {
 		// Create local copy to prevent pointer to loop variable to be
 		// passed in with disastrous consequences.
 		local := output
 
-		resultChan, err := u.cfg.SweepInput(&local, feePref)
+		// Outputs that the remote party can claim as well are swept
+		// in the critical lane, all others economically.
+		feePref := sweep.FeePreference{
+			ConfTarget: kgtnOutputConfTarget,
+			Urgency: sweep.UrgencyForWitnessType(
+				local.WitnessType(),
+			),
+		}
+
+		resultChan, err := u.cfg.SweepInput(
+			&local, feePref, &sweep.InputMetadata{
+				ChanPoint: *local.OriginChanPoint(),
+				Reason: sweep.SweepReasonForWitnessType(
+					local.WitnessType(),
+				),
+			},
+		)
 		if err != nil {
 			return err
 		}
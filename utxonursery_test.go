@@ -793,8 +793,9 @@ func testNurseryOutgoingHtlcSuccessOnLocal(t *testing.T,
 	// Notify arrival of block where second level HTLC unlocks.
 	ctx.notifyEpoch(128)
 
-	// Check final sweep into wallet.
-	testSweepHtlc(t, ctx)
+	// Check final sweep into wallet. Only we can spend the second level
+	// output, so it is swept economically.
+	testSweepHtlc(t, ctx, sweep.UrgencyEconomic)
 
 	ctx.finish()
 }
@@ -833,8 +834,9 @@ func testNurseryOutgoingHtlcSuccessOnRemote(t *testing.T,
 	// Notify arrival of block where HTLC CLTV expires.
 	ctx.notifyEpoch(125)
 
-	// Check final sweep into wallet.
-	testSweepHtlc(t, ctx)
+	// Check final sweep into wallet. The remote party can still claim
+	// the HTLC with the preimage, so it is swept in the critical lane.
+	testSweepHtlc(t, ctx, sweep.UrgencyCritical)
 
 	ctx.finish()
 }
@@ -884,7 +886,7 @@ func testNurseryCommitSuccessOnLocal(t *testing.T,
 	ctx.notifyEpoch(126)
 
 	// Check final sweep into wallet.
-	testSweep(t, ctx, func() {
+	testSweep(t, ctx, sweep.UrgencyEconomic, func() {
 		// Check limbo balance after sweep publication
 		assertNurseryReport(t, ctx.nursery, 0, 0, 10000)
 	})
@@ -892,23 +894,25 @@ func testNurseryCommitSuccessOnLocal(t *testing.T,
 	ctx.finish()
 }
 
-func testSweepHtlc(t *testing.T, ctx *nurseryTestContext) {
-	testSweep(t, ctx, func() {
+func testSweepHtlc(t *testing.T, ctx *nurseryTestContext,
+	urgency sweep.Urgency) {
+
+	testSweep(t, ctx, urgency, func() {
 		// Verify stage in nursery report. HTLCs should now both still
 		// be in stage two.
 		assertNurseryReport(t, ctx.nursery, 1, 2, 10000)
 	})
 }
 
-func testSweep(t *testing.T, ctx *nurseryTestContext,
+func testSweep(t *testing.T, ctx *nurseryTestContext, urgency sweep.Urgency,
 	afterPublishAssert func()) {
 
 	// Wait for nursery to publish the sweep tx.
-	ctx.sweeper.expectSweep()
+	ctx.sweeper.expectSweep(urgency)
 
 	if ctx.restart() {
 		// Nursery reoffers its input after a restart.
-		ctx.sweeper.expectSweep()
+		ctx.sweeper.expectSweep(urgency)
 	}
 
 	afterPublishAssert()
@@ -1040,24 +1044,25 @@ type mockSweeper struct {
 	resultChans map[wire.OutPoint]chan sweep.Result
 	t           *testing.T
 
-	sweepChan chan input.Input
+	sweepChan chan sweep.FeePreference
 }
 
 func newMockSweeper(t *testing.T) *mockSweeper {
 	return &mockSweeper{
 		resultChans: make(map[wire.OutPoint]chan sweep.Result),
-		sweepChan:   make(chan input.Input, 1),
+		sweepChan:   make(chan sweep.FeePreference, 1),
 		t:           t,
 	}
 }
 
 func (s *mockSweeper) sweepInput(input input.Input,
-	_ sweep.FeePreference) (chan sweep.Result, error) {
+	feePref sweep.FeePreference, _ *sweep.InputMetadata) (chan sweep.Result,
+	error) {
 
 	utxnLog.Debugf("mockSweeper sweepInput called for %v", *input.OutPoint())
 
 	select {
-	case s.sweepChan <- input:
+	case s.sweepChan <- feePref:
 	case <-time.After(defaultTestTimeout):
 		s.t.Fatal("signal result timeout")
 	}
@@ -1071,11 +1076,17 @@ func (s *mockSweeper) sweepInput(input input.Input,
 	return c, nil
 }
 
-func (s *mockSweeper) expectSweep() {
+// expectSweep waits for an input to be offered to the sweeper, and asserts
+// that it is scheduled in the given urgency lane.
+func (s *mockSweeper) expectSweep(urgency sweep.Urgency) {
 	s.t.Helper()
 
 	select {
-	case <-s.sweepChan:
+	case feePref := <-s.sweepChan:
+		if feePref.Urgency != urgency {
+			s.t.Fatalf("expected urgency %v, got %v", urgency,
+				feePref.Urgency)
+		}
 	case <-time.After(defaultTestTimeout):
 		s.t.Fatal("signal result timeout")
 	}
@@ -48,6 +48,10 @@ type backupTask struct {
 
 	blobType blob.Type
 	outputs  []*wire.TxOut
+
+	// justiceTx is the unsigned justice transaction, available after the
+	// session payload has been crafted.
+	justiceTx *wire.MsgTx
 }
 
 // newBackupTask initializes a new backupTask and populates all state-dependent
@@ -292,6 +296,8 @@ func (t *backupTask) craftSessionPayload(
 	// on the network, it can use the full txid to decyrpt the blob.
 	hint = wtdb.NewBreachHintFromHash(&breachKey)
 
+	t.justiceTx = justiceTxn
+
 	return hint, encBlob, nil
 }
 
@@ -129,6 +129,12 @@ type Config struct {
 	// watchtowers. If the exponential backoff produces a timeout greater
 	// than this value, the backoff will be clamped to MaxBackoff.
 	MaxBackoff time.Duration
+
+	// JusticeRegistry, if non-nil, is notified about the justice
+	// transactions of all backups that are sent to towers. It should be
+	// set to the sweeper of the node, so that the sweeper doesn't compete
+	// with the towers over the outputs of a breached channel.
+	JusticeRegistry JusticeRegistry
 }
 
 // TowerClient is a concrete implementation of the Client interface, offering a
@@ -465,11 +471,11 @@ func (c *TowerClient) RegisterChannel(chanID lnwire.ChannelID) error {
 
 // BackupState initiates a request to back up a particular revoked state. If the
 // method returns nil, the backup is guaranteed to be successful unless the:
-//  - client is force quit,
-//  - justice transaction would create dust outputs when trying to abide by the
-//    negotiated policy, or
-//  - breached outputs contain too little value to sweep at the target sweep fee
-//    rate.
+//   - client is force quit,
+//   - justice transaction would create dust outputs when trying to abide by the
+//     negotiated policy, or
+//   - breached outputs contain too little value to sweep at the target sweep fee
+//     rate.
 func (c *TowerClient) BackupState(chanID *lnwire.ChannelID,
 	breachInfo *lnwallet.BreachRetribution) error {
 
@@ -822,15 +828,16 @@ func (c *TowerClient) sendMessage(peer wtserver.Peer, msg wtwire.Message) error
 // database and supplying it with the resources needed by the client.
 func (c *TowerClient) newSessionQueue(s *wtdb.ClientSession) *sessionQueue {
 	return newSessionQueue(&sessionQueueConfig{
-		ClientSession: s,
-		ChainHash:     c.cfg.ChainHash,
-		Dial:          c.dial,
-		ReadMessage:   c.readMessage,
-		SendMessage:   c.sendMessage,
-		Signer:        c.cfg.Signer,
-		DB:            c.cfg.DB,
-		MinBackoff:    c.cfg.MinBackoff,
-		MaxBackoff:    c.cfg.MaxBackoff,
+		ClientSession:   s,
+		ChainHash:       c.cfg.ChainHash,
+		Dial:            c.dial,
+		ReadMessage:     c.readMessage,
+		SendMessage:     c.sendMessage,
+		Signer:          c.cfg.Signer,
+		DB:              c.cfg.DB,
+		MinBackoff:      c.cfg.MinBackoff,
+		MaxBackoff:      c.cfg.MaxBackoff,
+		JusticeRegistry: c.cfg.JusticeRegistry,
 	})
 }
 
@@ -4,6 +4,7 @@ import (
 	"net"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/brontide"
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/lightningnetwork/lnd/lnwire"
@@ -73,6 +74,14 @@ type DB interface {
 	AckUpdate(id *wtdb.SessionID, seqNum, lastApplied uint16) error
 }
 
+// JusticeRegistry is notified about the justice transactions that are backed
+// up to towers, so that spends by them can be recognized as our own.
+type JusticeRegistry interface {
+	// RegisterJusticeTx registers an unsigned justice transaction that a
+	// tower may publish on our behalf.
+	RegisterJusticeTx(tx *wire.MsgTx) error
+}
+
 // Dial connects to an addr using the specified net and returns the connection
 // object.
 type Dial func(net, addr string) (net.Conn, error)
@@ -74,6 +74,10 @@ type sessionQueueConfig struct {
 	// timeout greater than this value, the backoff duration will be clamped
 	// to MaxBackoff.
 	MaxBackoff time.Duration
+
+	// JusticeRegistry, if non-nil, is notified about the justice
+	// transaction of each update before it is committed.
+	JusticeRegistry JusticeRegistry
 }
 
 // sessionQueue implements a reliable queue that will encrypt and send accepted
@@ -412,6 +416,18 @@ func (q *sessionQueue) nextStateUpdate() (*wtwire.StateUpdate, bool, error) {
 		}
 		// TODO(conner): special case other obscure errors
 
+		if q.cfg.JusticeRegistry != nil {
+			err := q.cfg.JusticeRegistry.RegisterJusticeTx(
+				task.justiceTx,
+			)
+			if err != nil {
+				log.Errorf("SessionQueue %s unable to register "+
+					"justice tx for chanid=%s commit-height=%d: "+
+					"%v", q.ID(), task.id.ChanID,
+					task.id.CommitHeight, err)
+			}
+		}
+
 		update = wtdb.CommittedUpdate{
 			SeqNum: seqNum,
 			CommittedUpdateBody: wtdb.CommittedUpdateBody{
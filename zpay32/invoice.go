@@ -67,6 +67,10 @@ const (
 
 	// fieldTypeC contains an optional requested final CLTV delta.
 	fieldTypeC = 24
+
+	// fieldType9 contains one or more bytes for signaling features
+	// supported or required by the receiver.
+	fieldType9 = 5
 )
 
 // MessageSigner is passed to the Encode method to provide a signature
@@ -146,6 +150,10 @@ type Invoice struct {
 	//
 	// NOTE: This is optional.
 	RouteHints [][]HopHint
+
+	// Features represents an optional field used to signal optional or
+	// required support for features by the receiver.
+	Features *lnwire.FeatureVector
 }
 
 // Amount is a functional option that allows callers of NewInvoice to set the
@@ -219,6 +227,16 @@ func RouteHint(routeHint []HopHint) func(*Invoice) {
 	}
 }
 
+// Features is a functional option that allows callers of NewInvoice to set the
+// desired feature bits that are advertised on the invoice.
+func Features(features *lnwire.RawFeatureVector) func(*Invoice) {
+	return func(i *Invoice) {
+		i.Features = lnwire.NewFeatureVector(
+			features, lnwire.InvoiceFeatures,
+		)
+	}
+}
+
 // NewInvoice creates a new Invoice object. The last parameter is a set of
 // variadic arguments for setting optional fields of the invoice.
 //
@@ -663,6 +681,14 @@ func parseTaggedFields(invoice *Invoice, fields []byte, net *chaincfg.Params) er
 			}
 
 			invoice.RouteHints = append(invoice.RouteHints, routeHint)
+		case fieldType9:
+			if invoice.Features != nil {
+				// We skip the field if we have already seen a
+				// supported one.
+				continue
+			}
+
+			invoice.Features, err = parseFeatures(base32Data)
 		default:
 			// Ignore unknown type.
 		}
@@ -677,6 +703,23 @@ func parseTaggedFields(invoice *Invoice, fields []byte, net *chaincfg.Params) er
 	return nil
 }
 
+// parseFeatures decodes any feature bits directly from the base32
+// representation. The bits are numbered starting from the least significant
+// bit of the last 5-bit group.
+func parseFeatures(data []byte) (*lnwire.FeatureVector, error) {
+	rawFeatures := lnwire.NewRawFeatureVector()
+	for i := 0; i < len(data)*5; i++ {
+		group := data[len(data)-1-i/5]
+		if (group>>uint(i%5))&1 == 1 {
+			rawFeatures.Set(lnwire.FeatureBit(i))
+		}
+	}
+
+	return lnwire.NewFeatureVector(
+		rawFeatures, lnwire.InvoiceFeatures,
+	), nil
+}
+
 // parseFieldDataLength converts the two byte slice into a uint16.
 func parseFieldDataLength(data []byte) (uint16, error) {
 	if len(data) != 2 {
@@ -934,6 +977,16 @@ func writeTaggedFields(bufferBase32 *bytes.Buffer, invoice *Invoice) error {
 		}
 	}
 
+	if invoice.Features != nil {
+		features := featuresToBase32(invoice.Features.RawFeatureVector)
+		if len(features) > 0 {
+			err := writeTaggedField(bufferBase32, fieldType9, features)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	if invoice.expiry != nil {
 		seconds := invoice.expiry.Seconds()
 		expiry := uint64ToBase32(uint64(seconds))
@@ -1060,6 +1113,33 @@ func writeTaggedField(bufferBase32 *bytes.Buffer, dataType byte, data []byte) er
 	return nil
 }
 
+// featuresToBase32 encodes the feature bits using as few 5-bit groups as
+// possible. The bits are numbered starting from the least significant bit of
+// the last group.
+func featuresToBase32(features *lnwire.RawFeatureVector) []byte {
+	maxBits := features.SerializeSize() * 8
+
+	// Find the highest set bit to determine the number of groups.
+	highest := -1
+	for i := 0; i < maxBits; i++ {
+		if features.IsSet(lnwire.FeatureBit(i)) {
+			highest = i
+		}
+	}
+	if highest == -1 {
+		return nil
+	}
+
+	data := make([]byte, highest/5+1)
+	for i := 0; i <= highest; i++ {
+		if features.IsSet(lnwire.FeatureBit(i)) {
+			data[len(data)-1-i/5] |= 1 << uint(i%5)
+		}
+	}
+
+	return data
+}
+
 // base32ToUint64 converts a base32 encoded number to uint64.
 func base32ToUint64(data []byte) (uint64, error) {
 	// Maximum that fits in uint64 is ceil(64 / 5) = 12 groups.
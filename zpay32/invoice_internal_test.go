@@ -1,6 +1,7 @@
 package zpay32
 
 import (
+	"bytes"
 	"encoding/binary"
 	"math"
 	"reflect"
@@ -777,3 +778,56 @@ func TestParseRouteHint(t *testing.T) {
 		}
 	}
 }
+
+// TestParseFeatures checks that the feature field is properly parsed and
+// encoded.
+func TestParseFeatures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		data []byte
+		bits []lnwire.FeatureBit
+	}{
+		{
+			data: []byte{},
+			bits: nil,
+		},
+		{
+			data: []byte{0x01},
+			bits: []lnwire.FeatureBit{0},
+		},
+		{
+			// The feature field of the BOLT-11 test vectors,
+			// "sgq", signaling bits 8 and 14.
+			data: []byte{0x10, 0x08, 0x00},
+			bits: []lnwire.FeatureBit{
+				lnwire.TLVOnionPayloadRequired,
+				lnwire.PaymentAddrRequired,
+			},
+		},
+		{
+			data: []byte{0x01, 0x00, 0x00, 0x00},
+			bits: []lnwire.FeatureBit{15},
+		},
+	}
+
+	for i, test := range tests {
+		features, err := parseFeatures(test.data)
+		if err != nil {
+			t.Fatalf("test %d: unable to parse features: %v", i,
+				err)
+		}
+
+		for _, bit := range test.bits {
+			if !features.IsSet(bit) {
+				t.Fatalf("test %d: expected bit %v to be set",
+					i, bit)
+			}
+		}
+		encoded := featuresToBase32(features.RawFeatureVector)
+		if !bytes.Equal(encoded, test.data) {
+			t.Fatalf("test %d: expected encoding %x, got %x", i,
+				test.data, encoded)
+		}
+	}
+}